@@ -0,0 +1,143 @@
+// Package acl controls which principals may access which resources.
+//
+// A ResourceACL is an ordered list of rules matched against resource URIs.
+// The first rule whose pattern matches decides: access is granted when the
+// rule admits the caller and denied otherwise. URIs no rule matches follow
+// the ACL's default, so sensitive resources can be fenced off on a server
+// whose other resources stay public.
+package acl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/auth"
+)
+
+// Rule grants access to the resources matching a pattern.
+type Rule struct {
+	// Pattern is a glob over resource URIs: "*" matches within a path
+	// segment, "**" across segments and "?" a single character other than
+	// "/". Exactly one of Pattern and Regexp must be set.
+	Pattern string
+	// Regexp is a regular expression matched against the whole URI.
+	Regexp string
+	// Public admits every caller, authenticated or not.
+	Public bool
+	// Subjects admits principals with one of these subjects. "*" admits
+	// any authenticated principal.
+	Subjects []string
+	// Scopes admits principals holding any of these scopes.
+	Scopes []string
+}
+
+// ResourceACL decides access to resources. The zero value allows
+// everything.
+type ResourceACL struct {
+	rules []compiledRule
+	deny  bool
+}
+
+type compiledRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// New compiles rules into an ACL that allows access to resources no rule
+// matches.
+func New(rules ...Rule) (*ResourceACL, error) {
+	a := &ResourceACL{}
+	for i, r := range rules {
+		var expr string
+		switch {
+		case r.Pattern != "" && r.Regexp != "":
+			return nil, fmt.Errorf("acl: rule %d sets both Pattern and Regexp", i)
+		case r.Pattern != "":
+			expr = globToRegexp(r.Pattern)
+		case r.Regexp != "":
+			expr = "^(?:" + r.Regexp + ")$"
+		default:
+			return nil, fmt.Errorf("acl: rule %d has no pattern", i)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("acl: rule %d: %w", i, err)
+		}
+		a.rules = append(a.rules, compiledRule{Rule: r, re: re})
+	}
+	return a, nil
+}
+
+// MustNew is like New but panics on invalid rules.
+func MustNew(rules ...Rule) *ResourceACL {
+	a, err := New(rules...)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// DenyByDefault makes a deny access to resources no rule matches, and
+// returns a.
+func (a *ResourceACL) DenyByDefault() *ResourceACL {
+	a.deny = true
+	return a
+}
+
+// Allowed reports whether p, which is nil for anonymous callers, may
+// access the resource at uri. A nil ACL allows everything.
+func (a *ResourceACL) Allowed(p *auth.Principal, uri string) bool {
+	if a == nil {
+		return true
+	}
+	for _, r := range a.rules {
+		if r.re.MatchString(uri) {
+			return r.admits(p)
+		}
+	}
+	return !a.deny
+}
+
+func (r *compiledRule) admits(p *auth.Principal) bool {
+	if r.Public {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	for _, s := range r.Subjects {
+		if s == "*" || s == p.Subject {
+			return true
+		}
+	}
+	for _, s := range r.Scopes {
+		if p.HasScope(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp translates a URI glob into an anchored regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
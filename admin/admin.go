@@ -0,0 +1,97 @@
+// Package admin serves operational endpoints for a running mcp.Server.
+// Mount the handler on a separate, non-public listener.
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/reload"
+)
+
+// Option configures the admin handler.
+type Option func(*options)
+
+type options struct {
+	reloader *reload.Reloader
+}
+
+// WithReloader adds POST /reload, which applies the configuration loaded
+// by r and answers with the configuration in effect, or 400 with the
+// reason the new configuration was rejected.
+func WithReloader(r *reload.Reloader) Option {
+	return func(o *options) { o.reloader = r }
+}
+
+// NewHandler returns a handler exposing:
+//
+//	GET    /metrics      server metrics in the Prometheus text format
+//	GET    /health       load signals; 503 when the server is degraded
+//	GET    /build        version, commit and Go toolchain of the binary
+//	GET    /connections  live connections with request and byte counts
+//	GET    /transports   accept, handshake, frame error and write statistics
+//	                     of every transport
+//	GET    /deadletters  notifications that could not be delivered
+//	DELETE /deadletters  the same, emptying the dead-letter buffer
+//
+// and the endpoints enabled by opts.
+func NewHandler(s *mcp.Server, opts ...Option) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.Metrics().Handler())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		h := s.Health()
+		if h.Status != mcp.HealthOK {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeJSON(w, h)
+	})
+	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.BuildInfo())
+	})
+	mux.HandleFunc("/connections", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.Connections())
+	})
+	mux.HandleFunc("/transports", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.TransportStats())
+	})
+	mux.HandleFunc("/deadletters", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, s.DeadLetters())
+		case http.MethodDelete:
+			writeJSON(w, s.DrainDeadLetters())
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	if o.reloader != nil {
+		mux.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", "POST")
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			cfg, err := o.reloader.Reload(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, cfg)
+		})
+	}
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
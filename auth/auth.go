@@ -0,0 +1,63 @@
+// Package auth describes authenticated clients. Transports attach the
+// Principal they establish to transport.Peer, where policies such as
+// resource ACLs and tenancy look it up.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthenticated is returned by authenticators for requests without
+// valid credentials.
+var ErrUnauthenticated = errors.New("auth: unauthenticated")
+
+// Principal is an authenticated client identity.
+type Principal struct {
+	// Subject identifies the client, such as a user or service account.
+	Subject string
+	// Scopes are the permissions granted to the credentials.
+	Scopes []string
+	// Claims holds further attributes asserted by the credentials, such as
+	// the claims of a JWT.
+	Claims map[string]interface{}
+}
+
+// HasScope reports whether p was granted scope. A nil principal has no
+// scopes.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Claim returns the claim name as a string, or "" when it is absent or not
+// a string.
+func (p *Principal) Claim(name string) string {
+	if p == nil {
+		return ""
+	}
+	s, _ := p.Claims[name].(string)
+	return s
+}
+
+// Authenticator establishes the principal of an HTTP request. It returns
+// a nil principal for anonymous requests that may proceed, and an error,
+// typically wrapping ErrUnauthenticated, to reject the request.
+type Authenticator func(r *http.Request) (*Principal, error)
+
+// BearerToken returns the token of an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,90 @@
+// Package blob lets clients upload large binary tool arguments out of band.
+// A client uploads the payload to the transport's uploads endpoint, receives
+// a Ref, and passes the Ref as a tool argument; the tool then opens the blob
+// as a stream instead of decoding a giant base64 string.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RefPrefix marks a string argument as a blob reference.
+const RefPrefix = "blob:"
+
+// ErrNotFound is returned for unknown or expired blobs.
+var ErrNotFound = errors.New("blob: not found")
+
+// ErrTooLarge is returned when a blob exceeds the store's size limit.
+var ErrTooLarge = errors.New("blob: too large")
+
+// ErrFull is returned when a store has no room left for a blob. It
+// matches ErrTooLarge with errors.Is.
+var ErrFull = fmt.Errorf("%w: store full", ErrTooLarge)
+
+// Info describes a stored blob.
+type Info struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	MimeType  string    `json:"mimeType,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Ref returns the reference clients pass as a tool argument.
+func (i Info) Ref() Ref { return Ref(RefPrefix + i.ID) }
+
+// Store persists uploaded blobs.
+type Store interface {
+	// Put reads r to completion and stores its contents.
+	Put(ctx context.Context, r io.Reader, mimeType string) (Info, error)
+	// Open returns a reader over the blob with the given ID.
+	Open(ctx context.Context, id string) (io.ReadCloser, Info, error)
+	// Delete removes a blob. Deleting an unknown blob is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// Ref is a tool argument referring to an uploaded blob. It encodes as a
+// string of the form "blob:<id>".
+type Ref string
+
+// ID returns the blob ID, or "" when r is not a blob reference.
+func (r Ref) ID() string {
+	id, ok := strings.CutPrefix(string(r), RefPrefix)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// Valid reports whether r has the blob reference form.
+func (r Ref) Valid() bool { return r.ID() != "" }
+
+// Open opens the referenced blob using the store carried by ctx. Handlers
+// receive such a context when the server is configured with a blob store.
+func (r Ref) Open(ctx context.Context) (io.ReadCloser, Info, error) {
+	store, ok := FromContext(ctx)
+	if !ok {
+		return nil, Info{}, errors.New("blob: no store configured")
+	}
+	if !r.Valid() {
+		return nil, Info{}, errors.New("blob: invalid reference " + string(r))
+	}
+	return store.Open(ctx, r.ID())
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying store.
+func NewContext(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, contextKey{}, store)
+}
+
+// FromContext returns the store carried by ctx.
+func FromContext(ctx context.Context) (Store, bool) {
+	s, ok := ctx.Value(contextKey{}).(Store)
+	return s, ok
+}
@@ -0,0 +1,187 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+)
+
+// DefaultMaxSize bounds a single blob in a MemoryStore.
+const DefaultMaxSize = 64 << 20
+
+// DefaultMaxTotal bounds the bytes a MemoryStore holds across all its
+// blobs.
+const DefaultMaxTotal = 256 << 20
+
+// DefaultMaxBlobs bounds the number of blobs a MemoryStore holds.
+const DefaultMaxBlobs = 1024
+
+// DefaultTTL is how long a MemoryStore keeps a blob.
+const DefaultTTL = 15 * time.Minute
+
+// MemoryOptions configures a MemoryStore.
+type MemoryOptions struct {
+	// MaxSize bounds a single blob. Defaults to DefaultMaxSize.
+	MaxSize int64
+	// MaxTotal bounds the bytes of all the blobs held, including those
+	// being uploaded, so that clients cannot exhaust memory with many
+	// uploads. Defaults to DefaultMaxTotal.
+	MaxTotal int64
+	// MaxBlobs bounds the number of blobs held. Defaults to
+	// DefaultMaxBlobs.
+	MaxBlobs int
+	// TTL is how long blobs are kept after upload. Defaults to DefaultTTL.
+	TTL time.Duration
+	// Clock expires blobs. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// MemoryStore keeps blobs in memory until they expire.
+type MemoryStore struct {
+	opts MemoryOptions
+
+	mu    sync.Mutex
+	blobs map[string]*memoryBlob
+	// size is the bytes of the blobs held and of the uploads in
+	// progress.
+	size int64
+}
+
+type memoryBlob struct {
+	info    Info
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore(opts MemoryOptions) *MemoryStore {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxTotal <= 0 {
+		opts.MaxTotal = DefaultMaxTotal
+	}
+	if opts.MaxBlobs <= 0 {
+		opts.MaxBlobs = DefaultMaxBlobs
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	opts.Clock = clock.Or(opts.Clock)
+	return &MemoryStore{opts: opts, blobs: make(map[string]*memoryBlob)}
+}
+
+// Put implements Store. It fails with ErrFull when the blob would take
+// the store past MaxTotal or MaxBlobs.
+func (s *MemoryStore) Put(ctx context.Context, r io.Reader, mimeType string) (Info, error) {
+	s.mu.Lock()
+	s.sweep(s.opts.Clock.Now())
+	full := len(s.blobs) >= s.opts.MaxBlobs
+	s.mu.Unlock()
+	if full {
+		return Info{}, ErrFull
+	}
+	w := &chargedBuffer{s: s}
+	n, err := io.Copy(w, io.LimitReader(r, s.opts.MaxSize+1))
+	var id string
+	if err == nil {
+		id, err = newID()
+	}
+	if err != nil {
+		s.release(w.charged)
+		return Info{}, err
+	}
+	now := s.opts.Clock.Now()
+	b := &memoryBlob{
+		info:    Info{ID: id, Size: n, MimeType: mimeType, CreatedAt: now},
+		data:    w.buf.Bytes(),
+		expires: now.Add(s.opts.TTL),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	if len(s.blobs) >= s.opts.MaxBlobs {
+		s.size -= w.charged
+		return Info{}, ErrFull
+	}
+	s.blobs[id] = b
+	return b.info, nil
+}
+
+// chargedBuffer buffers an upload, counting its bytes against the
+// store's MaxTotal as they arrive.
+type chargedBuffer struct {
+	s       *MemoryStore
+	buf     bytes.Buffer
+	charged int64
+}
+
+func (w *chargedBuffer) Write(p []byte) (int, error) {
+	n := int64(len(p))
+	if w.charged+n > w.s.opts.MaxSize {
+		// Too large for any store, not only this full one.
+		return 0, ErrTooLarge
+	}
+	w.s.mu.Lock()
+	if w.s.size+n > w.s.opts.MaxTotal {
+		w.s.mu.Unlock()
+		return 0, ErrFull
+	}
+	w.s.size += n
+	w.s.mu.Unlock()
+	w.charged += n
+	return w.buf.Write(p)
+}
+
+// release returns n bytes to the store's budget.
+func (s *MemoryStore) release(n int64) {
+	s.mu.Lock()
+	s.size -= n
+	s.mu.Unlock()
+}
+
+// Open implements Store.
+func (s *MemoryStore) Open(ctx context.Context, id string) (io.ReadCloser, Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blobs[id]
+	if !ok || s.opts.Clock.Now().After(b.expires) {
+		return nil, Info{}, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b.data)), b.info, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	if b, ok := s.blobs[id]; ok {
+		s.size -= int64(len(b.data))
+		delete(s.blobs, id)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// sweep drops expired blobs. s.mu must be held.
+func (s *MemoryStore) sweep(now time.Time) {
+	for id, b := range s.blobs {
+		if now.After(b.expires) {
+			s.size -= int64(len(b.data))
+			delete(s.blobs, id)
+		}
+	}
+}
+
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,73 @@
+package blob_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/blob"
+	"github.com/hyperleex/zenmcp/clock"
+)
+
+func put(s *blob.MemoryStore, n int) (blob.Info, error) {
+	return s.Put(context.Background(), strings.NewReader(strings.Repeat("x", n)), "text/plain")
+}
+
+func TestMemoryStoreMaxSize(t *testing.T) {
+	s := blob.NewMemoryStore(blob.MemoryOptions{MaxSize: 10})
+	if _, err := put(s, 10); err != nil {
+		t.Fatal(err)
+	}
+	_, err := put(s, 11)
+	if !errors.Is(err, blob.ErrTooLarge) || errors.Is(err, blob.ErrFull) {
+		t.Errorf("Put of 11 bytes: %v, want ErrTooLarge", err)
+	}
+}
+
+func TestMemoryStoreMaxTotal(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	s := blob.NewMemoryStore(blob.MemoryOptions{MaxSize: 10, MaxTotal: 25, TTL: time.Minute, Clock: fake})
+	first, err := put(s, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := put(s, 10); err != nil {
+		t.Fatal(err)
+	}
+	_, err = put(s, 10)
+	if !errors.Is(err, blob.ErrFull) || !errors.Is(err, blob.ErrTooLarge) {
+		t.Fatalf("Put past MaxTotal: %v, want ErrFull", err)
+	}
+	// The rejected upload gave its bytes back: five still fit.
+	if _, err := put(s, 5); err != nil {
+		t.Fatalf("Put of the remaining 5 bytes: %v", err)
+	}
+
+	// Deleting and expiring blobs frees room.
+	if err := s.Delete(context.Background(), first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := put(s, 10); err != nil {
+		t.Fatalf("Put after Delete: %v", err)
+	}
+	fake.Advance(2 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := put(s, 10); err != nil {
+			t.Fatalf("Put %d after expiry: %v", i, err)
+		}
+	}
+}
+
+func TestMemoryStoreMaxBlobs(t *testing.T) {
+	s := blob.NewMemoryStore(blob.MemoryOptions{MaxBlobs: 2})
+	for i := 0; i < 2; i++ {
+		if _, err := put(s, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := put(s, 1); !errors.Is(err, blob.ErrFull) {
+		t.Errorf("Put past MaxBlobs: %v, want ErrFull", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package budget caps the tool calls a session may make over its whole
+// life, to stop runaway agent loops from hammering backends.
+//
+// Unlike package quota, whose limits renew every window, a budget is spent
+// once: a session that made its allowed number of calls, or ran up its
+// allowed cost, gets a BudgetExhausted error for every further call until
+// its budget is reset. Costs are declared by tools in
+// registry.ToolDescriptor.Cost. Callers can read what they have left from
+// the zenmcp://budget resource.
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// ResourceURI is the meta-resource reporting the caller's budget.
+const ResourceURI = "zenmcp://budget"
+
+// Limits bounds what a session may spend. Zero fields are unlimited.
+type Limits struct {
+	Calls int64   `json:"calls,omitempty"`
+	Cost  float64 `json:"cost,omitempty"`
+}
+
+// Config configures budget enforcement.
+type Config struct {
+	// Limits applies to every key.
+	Limits Limits
+	// Costs overrides the cost tools declare, by tool name.
+	Costs map[string]float64
+	// Key returns what budgets are kept per. The default is the session
+	// ID, qualified by the tenant if any. Calls for which Key returns ""
+	// are not budgeted.
+	Key func(ctx *runtime.Context) string
+	// IdleTimeout is how long the budget of a key that makes no calls is
+	// kept. Defaults to 24 hours.
+	IdleTimeout time.Duration
+	// Clock times idleness. Defaults to the server's clock.
+	Clock clock.Clock
+}
+
+// Usage is what a key has spent and has left.
+type Usage struct {
+	Key       string    `json:"key"`
+	Calls     int64     `json:"calls"`
+	Cost      float64   `json:"cost"`
+	Limits    Limits    `json:"limits"`
+	Remaining Remaining `json:"remaining"`
+}
+
+// Remaining is what is left of a budget. Nil fields are unlimited.
+type Remaining struct {
+	Calls *int64   `json:"calls,omitempty"`
+	Cost  *float64 `json:"cost,omitempty"`
+}
+
+// ExhaustedData is the data of the BudgetExhausted error returned for
+// calls over budget.
+type ExhaustedData struct {
+	Resource  string    `json:"resource"`
+	Used      float64   `json:"used"`
+	Limit     float64   `json:"limit"`
+	Remaining Remaining `json:"remaining"`
+}
+
+// Budget enforces a Config on a server.
+type Budget struct {
+	cfg    Config
+	limits atomic.Pointer[Limits]
+
+	mu    sync.Mutex
+	spent map[string]*spent
+	swept time.Time
+}
+
+type spent struct {
+	calls int64
+	cost  float64
+	last  time.Time
+}
+
+// Install enforces cfg on the tool calls of s and registers the
+// zenmcp://budget resource. It must be called before serving starts.
+func Install(s *mcp.Server, cfg Config) (*Budget, error) {
+	if cfg.Limits.Calls < 0 || cfg.Limits.Cost < 0 {
+		return nil, errors.New("budget: limits must not be negative")
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 24 * time.Hour
+	}
+	if cfg.Key == nil {
+		cfg.Key = defaultKey
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = s.Clock()
+	}
+	b := &Budget{cfg: cfg, spent: make(map[string]*spent)}
+	b.limits.Store(&cfg.Limits)
+	s.Router().InterceptToolCalls(b.intercept)
+	err := mcp.RegisterResourceTyped(s, registry.ResourceDescriptor{
+		URI:         ResourceURI,
+		Name:        "budget",
+		Description: "The tool calls and cost your session has spent and has left.",
+	}, func(ctx *runtime.Context, uri string) (*Usage, error) {
+		return b.Usage(cfg.Key(ctx)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func defaultKey(ctx *runtime.Context) string {
+	s := ctx.Session()
+	if s == nil {
+		return ""
+	}
+	if t := ctx.Tenant(); t != "" {
+		return "tenant:" + t + "/session:" + s.ID()
+	}
+	return "session:" + s.ID()
+}
+
+// Limits returns the limits in effect.
+func (b *Budget) Limits() Limits { return *b.limits.Load() }
+
+// SetLimits replaces the limits applied to every key. What keys have
+// spent is kept.
+func (b *Budget) SetLimits(l Limits) { b.limits.Store(&l) }
+
+// Usage returns what key has spent and has left.
+func (b *Budget) Usage(key string) *Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	u := &Usage{Key: key, Limits: b.Limits()}
+	if sp, ok := b.spent[key]; ok {
+		u.Calls, u.Cost = sp.calls, sp.cost
+	}
+	u.Remaining = remaining(u.Limits, u.Calls, u.Cost)
+	return u
+}
+
+// Reset restores the full budget of key.
+func (b *Budget) Reset(key string) {
+	b.mu.Lock()
+	delete(b.spent, key)
+	b.mu.Unlock()
+}
+
+func (b *Budget) intercept(ctx *runtime.Context, tool *registry.ToolDescriptor, args json.RawMessage, next registry.ToolHandler) (*protocol.ToolCallResult, error) {
+	key := b.cfg.Key(ctx)
+	if key == "" {
+		return next(ctx, args)
+	}
+	if err := b.charge(key, b.cost(tool)); err != nil {
+		return nil, err
+	}
+	return next(ctx, args)
+}
+
+// cost returns what a call of tool costs.
+func (b *Budget) cost(tool *registry.ToolDescriptor) float64 {
+	if c, ok := b.cfg.Costs[tool.Name]; ok {
+		return c
+	}
+	if tool.Cost > 0 {
+		return tool.Cost
+	}
+	return 1
+}
+
+// charge spends one call of the given cost from the budget of key, or
+// returns a BudgetExhausted error when that would exceed a limit. Calls
+// are charged before they run, so failed calls count too.
+func (b *Budget) charge(key string, cost float64) error {
+	now := b.cfg.Clock.Now()
+	l := b.Limits()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweep(now)
+	sp, ok := b.spent[key]
+	if !ok {
+		sp = &spent{}
+		b.spent[key] = sp
+	}
+	sp.last = now
+	exhausted := func(resource string, used, limit float64) error {
+		return protocol.NewError(protocol.BudgetExhausted,
+			fmt.Sprintf("budget exhausted: %s limit of %g reached", resource, limit),
+			ExhaustedData{Resource: resource, Used: used, Limit: limit, Remaining: remaining(l, sp.calls, sp.cost)})
+	}
+	switch {
+	case l.Calls > 0 && sp.calls+1 > l.Calls:
+		return exhausted("calls", float64(sp.calls), float64(l.Calls))
+	case l.Cost > 0 && sp.cost+cost > l.Cost:
+		return exhausted("cost", sp.cost, l.Cost)
+	}
+	sp.calls++
+	sp.cost += cost
+	return nil
+}
+
+// sweep forgets the budgets of keys idle for longer than the idle
+// timeout, at most once per timeout. b.mu is held.
+func (b *Budget) sweep(now time.Time) {
+	if now.Sub(b.swept) < b.cfg.IdleTimeout {
+		return
+	}
+	b.swept = now
+	for key, sp := range b.spent {
+		if now.Sub(sp.last) >= b.cfg.IdleTimeout {
+			delete(b.spent, key)
+		}
+	}
+}
+
+// remaining returns what is left of l after spending calls and cost.
+func remaining(l Limits, calls int64, cost float64) Remaining {
+	var r Remaining
+	if l.Calls > 0 {
+		n := max(l.Calls-calls, 0)
+		r.Calls = &n
+	}
+	if l.Cost > 0 {
+		c := max(l.Cost-cost, 0)
+		r.Cost = &c
+	}
+	return r
+}
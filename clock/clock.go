@@ -0,0 +1,81 @@
+// Package clock abstracts the passage of time so that timeouts, expiry and
+// periodic work can be tested without sleeping.
+//
+// Components that read the time or schedule timers take a Clock in their
+// configuration and fall back to Real when none is given. Tests pass a
+// Fake instead and move it forward with Advance.
+package clock
+
+import "time"
+
+// Clock tells the time and schedules timers.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer sending the time on its channel after d.
+	NewTimer(d time.Duration) Timer
+	// NewTicker returns a ticker sending the time on its channel every d.
+	// It panics if d is not positive.
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc calls f in its own goroutine after d.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event, as time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on. It is nil for timers
+	// created with AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing and reports whether it was
+	// active.
+	Stop() bool
+	// Reset changes the timer to fire after d and reports whether it was
+	// active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals, as time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real is the clock of the system, backed by the time package.
+var Real Clock = realClock{}
+
+// Or returns c, or Real when c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+// Since returns the time elapsed on c since t.
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time   { return t.t.C }
+func (t realTicker) Stop()                 { t.t.Stop() }
+func (t realTicker) Reset(d time.Duration) { t.t.Reset(d) }
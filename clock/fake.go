@@ -0,0 +1,160 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. Timers and tickers fire
+// during Advance, in deadline order, with Now reporting their deadline.
+// Functions scheduled with AfterFunc run synchronously within Advance, so
+// their effects are visible once it returns.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  map[*fakeTimer]bool
+}
+
+// NewFake returns a fake clock reading start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start, timers: make(map[*fakeTimer]bool)}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now implements Clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer implements Clock.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker implements Clock.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{clock: f, ch: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// AfterFunc implements Clock.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that
+// fall due on the way.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+	for {
+		var next *fakeTimer
+		for t := range f.timers {
+			if !t.when.After(target) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		if next.when.After(f.now) {
+			f.now = next.when
+		}
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			delete(f.timers, next)
+		}
+		if next.fn != nil {
+			f.mu.Unlock()
+			next.fn()
+			f.mu.Lock()
+			continue
+		}
+		select {
+		case next.ch <- f.now:
+		default: // the previous tick has not been received; drop this one
+		}
+	}
+	if target.After(f.now) {
+		f.now = target
+	}
+	f.mu.Unlock()
+}
+
+// Set moves the clock forward to t, as Advance. Times before the current
+// one are ignored.
+func (f *Fake) Set(t time.Time) {
+	f.Advance(t.Sub(f.Now()))
+}
+
+// BlockUntil waits until at least n timers and tickers are active. Tests
+// use it to let the code under test schedule its timers before calling
+// Advance.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock  *Fake
+	ch     chan time.Time
+	fn     func()
+	period time.Duration
+	when   time.Time // guarded by clock.mu
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	active := f.timers[t]
+	delete(f.timers, t)
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	active := f.timers[t]
+	t.when = f.now.Add(d)
+	f.timers[t] = true
+	f.changed.Broadcast()
+	f.mu.Unlock()
+	if d <= 0 {
+		f.Advance(0)
+	}
+	return active
+}
+
+type fakeTicker struct{ t *fakeTimer }
+
+func (t fakeTicker) C() <-chan time.Time { return t.t.ch }
+func (t fakeTicker) Stop()               { t.t.Stop() }
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.t.clock.mu.Lock()
+	t.t.period = d
+	t.t.clock.mu.Unlock()
+	t.t.Reset(d)
+}
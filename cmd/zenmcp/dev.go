@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+const devUsage = `usage: zenmcp dev [flags] [package] [-- server arguments]
+
+Builds the server in package (default .) and serves it on stdio. When a Go
+file under the watched directory changes, the server is rebuilt and
+restarted behind the same client connection: the client's initialize
+request is replayed to the new process and the client is told that the
+tool, resource and prompt lists changed. Requests in flight during a
+restart fail and can be retried. A build that fails leaves the running
+server in place.
+
+flags:
+`
+
+// runDev runs the dev command and returns the exit status.
+func runDev(args []string) int {
+	fset := flag.NewFlagSet("dev", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(os.Stderr, devUsage)
+		fset.PrintDefaults()
+	}
+	watch := fset.String("watch", ".", "directory to watch for changes")
+	interval := fset.Duration("interval", 500*time.Millisecond, "how often to look for changes")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	pkg, serverArgs := ".", []string(nil)
+	rest := fset.Args()
+	if len(rest) > 0 && rest[0] != "--" {
+		pkg, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == "--" {
+		serverArgs = rest[1:]
+	} else if len(rest) > 0 {
+		fset.Usage()
+		return 2
+	}
+	dir, err := os.MkdirTemp("", "zenmcp-dev-")
+	if err != nil {
+		devLog("%v", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	p := &devProxy{
+		pkg:     pkg,
+		args:    serverArgs,
+		binDir:  dir,
+		client:  codec.NewContentLength(os.Stdin, os.Stdout),
+		pending: make(map[protocol.ID]bool),
+	}
+	if err := p.rebuild(ctx); err != nil {
+		devLog("%v; waiting for changes", err)
+	}
+	go p.watch(ctx, *watch, *interval)
+	err = p.serveClient(ctx)
+	p.stopChild()
+	if err != nil && !errors.Is(err, io.EOF) && ctx.Err() == nil {
+		devLog("%v", err)
+		return 1
+	}
+	return 0
+}
+
+func devLog(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "zenmcp dev: "+format+"\n", args...)
+}
+
+// devProxy sits between the client, on stdio, and the current server
+// process.
+type devProxy struct {
+	pkg    string
+	args   []string
+	binDir string
+	client *codec.ContentLength
+
+	// clientMu serializes writes to the client.
+	clientMu sync.Mutex
+
+	// mu guards the fields below. It is held while restarting so that
+	// client messages wait for the new process.
+	mu          sync.Mutex
+	builds      int
+	child       *devChild
+	initialize  *protocol.Message
+	initialized bool
+
+	// pending holds the IDs of client requests the current process has
+	// not answered.
+	pendingMu sync.Mutex
+	pending   map[protocol.ID]bool
+}
+
+// devChild is one server process.
+type devChild struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	codec  *codec.ContentLength
+	replay protocol.ID
+	exited chan struct{}
+}
+
+func (p *devProxy) toClient(msg *protocol.Message) {
+	p.clientMu.Lock()
+	defer p.clientMu.Unlock()
+	if err := p.client.Encode(msg); err != nil {
+		devLog("writing to client: %v", err)
+	}
+}
+
+// serveClient forwards client messages to the current process until the
+// client goes away.
+func (p *devProxy) serveClient(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			var msg protocol.Message
+			if err := p.client.Decode(&msg); err != nil {
+				var perr *protocol.Error
+				if errors.As(err, &perr) {
+					p.toClient(protocol.NewErrorResponse(nil, perr))
+					continue
+				}
+				errc <- err
+				return
+			}
+			p.forward(&msg)
+		}
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *devProxy) forward(msg *protocol.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch msg.Method {
+	case protocol.MethodInitialize:
+		msg = withoutCompressionOffer(msg)
+		p.initialize = msg
+	case protocol.MethodInitialized:
+		p.initialized = true
+	}
+	if p.child == nil {
+		if msg.IsRequest() {
+			p.toClient(protocol.NewErrorResponse(msg.ID, protocol.NewError(protocol.InternalError, "zenmcp dev: the server is not running; fix the build and save", nil)))
+		}
+		return
+	}
+	if msg.IsRequest() && msg.ID != nil {
+		p.pendingMu.Lock()
+		p.pending[*msg.ID] = true
+		p.pendingMu.Unlock()
+	}
+	if err := p.child.codec.Encode(msg); err != nil {
+		devLog("writing to server: %v", err)
+	}
+}
+
+// withoutCompressionOffer returns the initialize request msg without the
+// client's zenmcp/compression offer. The proxy relays plain frames only, so
+// the server must not agree to compress them.
+func withoutCompressionOffer(msg *protocol.Message) *protocol.Message {
+	var params map[string]interface{}
+	if json.Unmarshal(msg.Params, &params) != nil {
+		return msg
+	}
+	caps, _ := params["capabilities"].(map[string]interface{})
+	experimental, _ := caps["experimental"].(map[string]interface{})
+	if _, ok := experimental[runtime.ExperimentalCompression]; !ok {
+		return msg
+	}
+	delete(experimental, runtime.ExperimentalCompression)
+	data, err := json.Marshal(params)
+	if err != nil {
+		return msg
+	}
+	stripped := *msg
+	stripped.Params = data
+	return &stripped
+}
+
+// readChild forwards the messages of c to the client, dropping the
+// response to a replayed initialize.
+func (p *devProxy) readChild(c *devChild) {
+	for {
+		var msg protocol.Message
+		if err := c.codec.Decode(&msg); err != nil {
+			var perr *protocol.Error
+			if errors.As(err, &perr) {
+				devLog("server wrote an invalid frame: %v", perr.Message)
+				continue
+			}
+			break
+		}
+		if msg.IsResponse() && msg.ID != nil {
+			if *msg.ID == c.replay {
+				if msg.Error != nil {
+					devLog("replayed initialize failed: %s", msg.Error.Message)
+				}
+				continue
+			}
+			p.pendingMu.Lock()
+			delete(p.pending, *msg.ID)
+			p.pendingMu.Unlock()
+		}
+		p.toClient(&msg)
+	}
+	err := c.cmd.Wait()
+	close(c.exited)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.child != c {
+		return // stopped for a restart
+	}
+	devLog("server exited (%v); waiting for changes", err)
+	p.child = nil
+	p.failPending("the server exited during the request")
+}
+
+// failPending answers the requests the current process will never answer.
+func (p *devProxy) failPending(reason string) {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+	for id := range p.pending {
+		id := id
+		p.toClient(protocol.NewErrorResponse(&id, protocol.NewError(protocol.InternalError, "zenmcp dev: "+reason, nil)))
+	}
+	clear(p.pending)
+}
+
+// rebuild builds the server and, when that succeeds, replaces the running
+// process with the new binary.
+func (p *devProxy) rebuild(ctx context.Context) error {
+	p.builds++
+	bin := filepath.Join(p.binDir, fmt.Sprintf("server-%d", p.builds))
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, p.pkg)
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	start := time.Now()
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	restarting := p.child != nil
+	p.stopChildLocked()
+	p.failPending("the server restarted during the request; retry it")
+	if err := p.startChildLocked(bin); err != nil {
+		return err
+	}
+	if restarting {
+		devLog("rebuilt and restarted in %s", time.Since(start).Round(time.Millisecond))
+	}
+	if restarting && p.initialize != nil {
+		for _, method := range []string{protocol.MethodToolsListChanged, protocol.MethodResourcesListChanged, protocol.MethodPromptsListChanged} {
+			msg, _ := protocol.NewNotification(method, nil)
+			p.toClient(msg)
+		}
+	}
+	return nil
+}
+
+func (p *devProxy) startChildLocked(bin string) error {
+	cmd := exec.Command(bin, p.args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting server: %w", err)
+	}
+	c := &devChild{
+		cmd:    cmd,
+		stdin:  stdin,
+		codec:  codec.NewContentLength(stdout, stdin),
+		replay: protocol.NewStringID(fmt.Sprintf("zenmcp-dev-initialize-%d", p.builds)),
+		exited: make(chan struct{}),
+	}
+	p.child = c
+	go p.readChild(c)
+	if p.initialize != nil {
+		replay := *p.initialize
+		replay.ID = &c.replay
+		if err := c.codec.Encode(&replay); err != nil {
+			return fmt.Errorf("replaying initialize: %w", err)
+		}
+		if p.initialized {
+			msg, _ := protocol.NewNotification(protocol.MethodInitialized, nil)
+			c.codec.Encode(msg)
+		}
+	}
+	return nil
+}
+
+func (p *devProxy) stopChild() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopChildLocked()
+}
+
+// stopChildLocked closes the server's input, as a client would, and kills
+// it if it has not exited shortly after.
+func (p *devProxy) stopChildLocked() {
+	c := p.child
+	if c == nil {
+		return
+	}
+	p.child = nil
+	c.stdin.Close()
+	select {
+	case <-c.exited:
+	case <-time.After(2 * time.Second):
+		c.cmd.Process.Kill()
+		<-c.exited
+	}
+}
+
+// watch rebuilds the server whenever the Go sources under dir change and
+// then stay unchanged for one interval.
+func (p *devProxy) watch(ctx context.Context, dir string, interval time.Duration) {
+	last := snapshotSources(dir)
+	changed := false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := snapshotSources(dir)
+		if cur != last {
+			last, changed = cur, true
+			continue
+		}
+		if changed {
+			changed = false
+			if err := p.rebuild(ctx); err != nil && ctx.Err() == nil {
+				devLog("%v; keeping the running server", err)
+			}
+		}
+	}
+}
+
+// snapshotSources fingerprints the Go sources and module files under dir
+// by their names, sizes and modification times.
+func snapshotSources(dir string) string {
+	var b strings.Builder
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(&b, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return b.String()
+}
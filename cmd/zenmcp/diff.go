@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hyperleex/zenmcp/manifest"
+)
+
+const diffUsage = `usage: zenmcp diff [flags] old.json new.json
+
+Compares two tool manifests, written by manifest.Manifest.Write or saved
+from a tools/list response, and prints the changes from old to new.
+Breaking changes, which may make calls that worked against old fail
+against new, are marked BREAKING. The exit status is 1 when there are
+any, so the command can gate releases.
+
+flags:
+`
+
+// runDiff runs the diff command and returns the exit status.
+func runDiff(args []string) int {
+	fset := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(os.Stderr, diffUsage)
+		fset.PrintDefaults()
+	}
+	breakingOnly := fset.Bool("breaking", false, "only print breaking changes")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 2 {
+		fset.Usage()
+		return 2
+	}
+	old, err := manifest.Load(fset.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp diff:", err)
+		return 2
+	}
+	new, err := manifest.Load(fset.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp diff:", err)
+		return 2
+	}
+	changes := manifest.Diff(old, new)
+	breaking := manifest.Breaking(changes)
+	if *breakingOnly {
+		changes = breaking
+	}
+	for _, c := range changes {
+		mark := "         "
+		if c.Breaking {
+			mark = "BREAKING "
+		}
+		fmt.Println(mark + c.String())
+	}
+	if len(breaking) > 0 {
+		fmt.Fprintf(os.Stderr, "zenmcp diff: %d breaking changes\n", len(breaking))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+// Command zenmcp is the zenmcp developer tool.
+//
+// Usage:
+//
+//	zenmcp vet [dir ...]        check tool registrations and argument types
+//	zenmcp dev [package]        serve a server on stdio, rebuilding it on change
+//	zenmcp trace view file ...  print trace files written by transport/trace
+//	zenmcp diff old new         report breaking changes between tool manifests
+//	zenmcp version [-check]     print the version, optionally checking for updates
+//	zenmcp self-update          replace the binary with the latest release
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/vet"
+)
+
+const usage = `usage: zenmcp <command> [arguments]
+
+commands:
+  vet [dir ...]  check tool registrations and argument types (default ./...)
+  dev [package]  serve the server in package (default .) on stdio and
+                 rebuild and restart it when its sources change
+  trace view file ...
+                 print trace files written by transport/trace
+  diff old.json new.json
+                 report the changes, breaking or not, between two tool
+                 manifests
+  version [-check]
+                 print the version and, with -check, whether a newer
+                 release is available
+  self-update    replace this binary with the latest release
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "vet":
+		os.Exit(runVet(args))
+	case "dev":
+		os.Exit(runDev(args))
+	case "trace":
+		os.Exit(runTrace(args))
+	case "diff":
+		os.Exit(runDiff(args))
+	case "version", "-version", "--version":
+		os.Exit(runVersion(args))
+	case "self-update":
+		os.Exit(runSelfUpdate(args))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "zenmcp: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+// runVet prints the problems found in dirs and returns the exit status:
+// 0 when there are none, 1 when there are some and 2 on failure.
+func runVet(dirs []string) int {
+	if len(dirs) == 0 {
+		dirs = []string{"./..."}
+	}
+	diags, err := vet.Dirs(dirs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp vet:", err)
+		return 2
+	}
+	wd, _ := os.Getwd()
+	for _, d := range diags {
+		if rel, err := filepath.Rel(wd, d.Pos.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			d.Pos.Filename = rel
+		}
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
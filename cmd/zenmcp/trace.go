@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport/trace"
+)
+
+const traceUsage = `usage: zenmcp trace view [flags] file ...
+
+Prints trace files written by transport/trace, one line per message:
+-> for messages from the client and <- for messages from the server.
+Responses show the method of their request and how long it took.
+
+flags:
+`
+
+// runTrace runs the trace command and returns the exit status.
+func runTrace(args []string) int {
+	if len(args) == 0 || args[0] != "view" {
+		fmt.Fprint(os.Stderr, traceUsage)
+		return 2
+	}
+	fset := flag.NewFlagSet("trace view", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(os.Stderr, traceUsage)
+		fset.PrintDefaults()
+	}
+	var f traceFilter
+	fset.StringVar(&f.method, "method", "", "only show messages whose method matches this pattern, such as tools/*")
+	fset.StringVar(&f.tool, "tool", "", "only show calls of this tool and their responses")
+	fset.BoolVar(&f.errors, "errors", false, "only show failed exchanges")
+	full := fset.Bool("full", false, "print params and results in full, indented")
+	if err := fset.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return 2
+	}
+	status := 0
+	for _, name := range fset.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "zenmcp trace:", err)
+			status = 1
+			continue
+		}
+		entries, err := trace.ReadAll(file)
+		file.Close()
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			fmt.Fprintf(os.Stderr, "zenmcp trace: %s: last entry is incomplete\n", name)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "zenmcp trace: %s: %v\n", name, err)
+			status = 1
+		}
+		if fset.NArg() > 1 {
+			fmt.Printf("== %s\n", name)
+		}
+		viewTrace(os.Stdout, entries, f, *full)
+	}
+	return status
+}
+
+// traceFilter selects the exchanges to print.
+type traceFilter struct {
+	method string
+	tool   string
+	errors bool
+}
+
+// exchange is a request and its response, or a lone notification.
+type exchange struct {
+	method string
+	tool   string
+	start  time.Time
+	failed bool
+}
+
+func (f traceFilter) match(x *exchange) bool {
+	if f.method != "" {
+		if ok, _ := path.Match(f.method, x.method); !ok {
+			return false
+		}
+	}
+	if f.tool != "" && x.tool != f.tool {
+		return false
+	}
+	return !f.errors || x.failed
+}
+
+// viewTrace prints entries to w. Responses are matched to their requests
+// by ID and direction, so filters apply to whole exchanges.
+func viewTrace(w io.Writer, entries []trace.Entry, f traceFilter, full bool) {
+	// Exchanges are keyed by the direction of the request and its ID.
+	open := make(map[string]*exchange)
+	of := make([]*exchange, len(entries))
+	for i, e := range entries {
+		m := e.Message
+		switch {
+		case m == nil:
+			// The server answers unreadable frames with a null ID.
+			x := &exchange{failed: e.Error != ""}
+			open[e.Event+"null"] = x
+			of[i] = x
+		case m.Method == "":
+			x := open[reverse(e.Event)+idString(m)]
+			if x == nil {
+				x = &exchange{}
+			}
+			if m.Error != nil || isToolError(m.Result) || e.Error != "" {
+				x.failed = true
+			}
+			of[i] = x
+		default:
+			x := &exchange{method: m.Method, start: e.Time, failed: e.Error != ""}
+			if m.Method == protocol.MethodToolsCall {
+				var p struct {
+					Name string `json:"name"`
+				}
+				json.Unmarshal(m.Params, &p)
+				x.tool = p.Name
+			}
+			if m.IsRequest() {
+				open[e.Event+idString(m)] = x
+			}
+			of[i] = x
+		}
+	}
+	for i, e := range entries {
+		if e.Event == trace.EventOpen || e.Event == trace.EventClose {
+			if f == (traceFilter{}) {
+				fmt.Fprintln(w, formatEntry(e, nil, full))
+			}
+			continue
+		}
+		if f.match(of[i]) {
+			fmt.Fprintln(w, formatEntry(e, of[i], full))
+		}
+	}
+}
+
+// idString returns the ID of m as it appears on the wire. A null ID
+// decodes as nil.
+func idString(m *protocol.Message) string {
+	if m.ID == nil {
+		return "null"
+	}
+	return m.ID.String()
+}
+
+func reverse(event string) string {
+	if event == trace.EventIn {
+		return trace.EventOut
+	}
+	return trace.EventIn
+}
+
+func isToolError(result json.RawMessage) bool {
+	var r struct {
+		IsError bool `json:"isError"`
+	}
+	json.Unmarshal(result, &r)
+	return r.IsError
+}
+
+func formatEntry(e trace.Entry, x *exchange, full bool) string {
+	var b strings.Builder
+	b.WriteString(e.Time.Local().Format("15:04:05.000 "))
+	switch e.Event {
+	case trace.EventOpen:
+		b.WriteString("open")
+		if p := e.Peer; p != nil {
+			for _, kv := range [][2]string{{"transport", p.Transport}, {"addr", p.RemoteAddr}, {"agent", p.UserAgent}, {"subject", p.Subject}, {"tenant", p.Tenant}} {
+				if kv[1] != "" {
+					fmt.Fprintf(&b, " %s=%s", kv[0], kv[1])
+				}
+			}
+		}
+		return b.String()
+	case trace.EventClose:
+		b.WriteString("close")
+		return b.String()
+	case trace.EventIn:
+		b.WriteString("-> ")
+	default:
+		b.WriteString("<- ")
+	}
+	m := e.Message
+	if m == nil {
+		b.WriteString("unreadable frame: " + e.Error)
+		return b.String()
+	}
+	if m.ID != nil || m.Method == "" {
+		fmt.Fprintf(&b, "#%s ", idString(m))
+	}
+	var body json.RawMessage
+	if m.Method == "" {
+		if x.method != "" {
+			fmt.Fprintf(&b, "%s ", x.method)
+		}
+		switch {
+		case m.Error != nil:
+			fmt.Fprintf(&b, "error %d %s", m.Error.Code, m.Error.Message)
+			if m.Error.Data != nil {
+				body, _ = json.Marshal(m.Error.Data)
+			}
+		case isToolError(m.Result):
+			b.WriteString("tool error")
+			body = m.Result
+		default:
+			b.WriteString("ok")
+			body = m.Result
+		}
+		if !x.start.IsZero() {
+			fmt.Fprintf(&b, " (%s)", e.Time.Sub(x.start).Round(time.Microsecond))
+		}
+	} else {
+		b.WriteString(m.Method)
+		body = m.Params
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, " [write failed: %s]", e.Error)
+	}
+	if len(body) > 0 {
+		b.WriteString(formatBody(body, full))
+	}
+	return b.String()
+}
+
+// formatBody returns JSON indented on the following lines, or compacted
+// and shortened to fit on the current one.
+func formatBody(body json.RawMessage, full bool) string {
+	var buf bytes.Buffer
+	if full {
+		if json.Indent(&buf, body, "    ", "  ") != nil {
+			return "\n    " + string(body)
+		}
+		return "\n    " + buf.String()
+	}
+	if json.Compact(&buf, body) != nil {
+		buf.Reset()
+		buf.Write(body)
+	}
+	s := buf.String()
+	if len(s) > 120 {
+		n := 117
+		for !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n] + "..."
+	}
+	return " " + s
+}
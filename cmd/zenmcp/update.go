@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+)
+
+// version is the release version, set by release builds with
+// -ldflags "-X main.version=v1.2.3". Builds from source report the module
+// version or commit instead.
+var version string
+
+// currentVersion returns the version of the running binary.
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	return mcp.ReadBuildInfo().Version()
+}
+
+// releasesEnv names the environment variable overriding releasesURL, for
+// mirrors and private forks. Like every URL the update fetches, it must be
+// HTTPS unless it points at the loopback interface, as test servers do.
+const releasesEnv = "ZENMCP_RELEASES_URL"
+
+// releasesURL is the GitHub API endpoint for the latest release.
+const releasesURL = "https://api.github.com/repos/hyperleex/zenmcp/releases/latest"
+
+// checksumsAsset is the release asset listing the SHA-256 sums of the
+// others, in sha256sum format.
+const checksumsAsset = "checksums.txt"
+
+const versionUsage = `usage: zenmcp version [flags]
+
+Prints the version of zenmcp. With -check, also looks up the latest
+release and says whether it is newer; the exit status is then 1 when an
+update is available.
+
+flags:
+`
+
+const selfUpdateUsage = `usage: zenmcp self-update [flags]
+
+Replaces the running zenmcp binary with the latest release for this
+platform, after checking it against the release's SHA-256 checksums.
+Builds from source are left alone unless -force is given; update those
+with go install instead.
+
+The checksums come from the same release as the binary and are not
+signed: they catch a damaged download, not a tampered release. The
+update is only as trustworthy as the HTTPS connection to the release
+host, which is why plain HTTP is refused for everything but loopback
+addresses.
+
+flags:
+`
+
+type release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []asset `json:"assets"`
+}
+
+type asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// asset returns the download URL of the asset named name.
+func (r *release) asset(name string) (string, error) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no asset %s", r.Tag, name)
+}
+
+// binaryAsset is the name of the release asset holding the binary for
+// this platform.
+func binaryAsset() string {
+	name := "zenmcp_" + goruntime.GOOS + "_" + goruntime.GOARCH
+	if goruntime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// runVersion runs the version command and returns the exit status.
+func runVersion(args []string) int {
+	fset := flag.NewFlagSet("version", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(os.Stderr, versionUsage)
+		fset.PrintDefaults()
+	}
+	check := fset.Bool("check", false, "look up the latest release")
+	timeout := fset.Duration("timeout", 30*time.Second, "time allowed for the lookup")
+	if err := fset.Parse(args); err != nil || fset.NArg() > 0 {
+		return 2
+	}
+	cur := currentVersion()
+	fmt.Printf("zenmcp %s %s/%s\n", cur, goruntime.GOOS, goruntime.GOARCH)
+	if !*check {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	rel, err := latestRelease(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp version:", err)
+		return 2
+	}
+	switch {
+	case !isRelease(cur):
+		fmt.Printf("development build; the latest release is %s\n", rel.Tag)
+	case compareSemver(rel.Tag, cur) > 0:
+		fmt.Printf("%s is available (%s); run zenmcp self-update\n", rel.Tag, rel.URL)
+		return 1
+	default:
+		fmt.Println("up to date")
+	}
+	return 0
+}
+
+// runSelfUpdate runs the self-update command and returns the exit status.
+func runSelfUpdate(args []string) int {
+	fset := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(os.Stderr, selfUpdateUsage)
+		fset.PrintDefaults()
+	}
+	force := fset.Bool("force", false, "install the latest release even over a newer or development build")
+	timeout := fset.Duration("timeout", 5*time.Minute, "time allowed for the download")
+	if err := fset.Parse(args); err != nil || fset.NArg() > 0 {
+		return 2
+	}
+	cur := currentVersion()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	rel, err := latestRelease(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp self-update:", err)
+		return 1
+	}
+	if !*force {
+		if !isRelease(cur) {
+			fmt.Fprintf(os.Stderr, "zenmcp self-update: %s is a development build; use -force to replace it with %s\n", cur, rel.Tag)
+			return 1
+		}
+		if compareSemver(rel.Tag, cur) <= 0 {
+			fmt.Printf("zenmcp %s is up to date\n", cur)
+			return 0
+		}
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp self-update: locating the binary:", err)
+		return 1
+	}
+	if err := install(ctx, rel, exe); err != nil {
+		fmt.Fprintln(os.Stderr, "zenmcp self-update:", err)
+		return 1
+	}
+	fmt.Printf("updated zenmcp from %s to %s\n", cur, rel.Tag)
+	return 0
+}
+
+// latestRelease looks up the latest release.
+func latestRelease(ctx context.Context) (*release, error) {
+	endpoint := releasesURL
+	if u := os.Getenv(releasesEnv); u != "" {
+		endpoint = u
+	}
+	body, err := fetch(ctx, endpoint, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("looking up the latest release: %w", err)
+	}
+	var rel release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("looking up the latest release: %w", err)
+	}
+	if rel.Tag == "" {
+		return nil, errors.New("looking up the latest release: response names no release")
+	}
+	return &rel, nil
+}
+
+// install downloads the binary of rel for this platform, checks it against
+// the release checksums and puts it in place of exe.
+func install(ctx context.Context, rel *release, exe string) error {
+	name := binaryAsset()
+	binURL, err := rel.asset(name)
+	if err != nil {
+		return err
+	}
+	sumsURL, err := rel.asset(checksumsAsset)
+	if err != nil {
+		return err
+	}
+	sums, err := fetch(ctx, sumsURL, 1<<20)
+	if err != nil {
+		return fmt.Errorf("downloading checksums: %w", err)
+	}
+	want, err := checksum(sums, name)
+	if err != nil {
+		return err
+	}
+	bin, err := fetch(ctx, binURL, 1<<30)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", name, err)
+	}
+	if got := sha256.Sum256(bin); hex.EncodeToString(got[:]) != want {
+		return fmt.Errorf("%s does not match its checksum", name)
+	}
+	return replaceFile(exe, bin)
+}
+
+// checksum returns the SHA-256 sum listed for name in sums, the contents
+// of a sha256sum output file.
+func checksum(sums []byte, name string) (string, error) {
+	sc := bufio.NewScanner(strings.NewReader(string(sums)))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", checksumsAsset, name)
+}
+
+// replaceFile atomically replaces the executable at path with data, keeping
+// its permissions. The new file is written next to it and renamed into
+// place, so a failed update leaves the old binary intact. Windows cannot
+// replace a running executable, so the old one is moved aside first.
+func replaceFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".zenmcp-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if goruntime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Rename(old, path)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// updateClient fetches releases, refusing redirects to insecure URLs.
+var updateClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return checkSecure(req.URL)
+	},
+}
+
+// checkSecure reports an error unless u is an HTTPS URL or an HTTP URL on
+// a loopback address. Release metadata, checksums and binaries all come
+// over such URLs, since nothing else vouches for them.
+func checkSecure(u *url.URL) error {
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+			return nil
+		}
+	}
+	return fmt.Errorf("refusing insecure URL %s: releases must be fetched over HTTPS", u.Redacted())
+}
+
+// fetch returns the body of a GET request to rawURL, failing for bodies
+// over limit bytes and for URLs checkSecure rejects.
+func fetch(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSecure(u); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "zenmcp/"+currentVersion())
+	if u.Host == "api.github.com" {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	resp, err := updateClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", rawURL, limit)
+	}
+	return body, nil
+}
+
+// pseudoVersion matches the pre-release suffix of the pseudo-versions the
+// go command stamps on builds from a checkout, such as
+// "v0.0.0-20240102150405-0123456789ab".
+var pseudoVersion = regexp.MustCompile(`(^|\.)\d{14}-[0-9a-f]{12}$`)
+
+// isRelease reports whether v is a release version such as "v1.2.3" or
+// "v1.3.0-rc.1", rather than a commit, a pseudo-version or "dev".
+func isRelease(v string) bool {
+	_, pre, ok := parseSemver(v)
+	return ok && !strings.Contains(v, "+") && !pseudoVersion.MatchString(pre)
+}
+
+// parseSemver splits v, with or without its leading "v", into its numeric
+// components and its pre-release suffix.
+func parseSemver(v string) (nums [3]int, pre string, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return nums, "", false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nums, "", false
+		}
+		nums[i] = n
+	}
+	return nums, pre, true
+}
+
+// compareSemver orders release versions. A pre-release sorts before the
+// release it precedes; pre-releases of the same version compare by their
+// dot-separated identifiers. Versions that do not parse sort first.
+func compareSemver(a, b string) int {
+	an, apre, aok := parseSemver(a)
+	bn, bpre, bok := parseSemver(b)
+	switch {
+	case !aok || !bok:
+		if aok == bok {
+			return 0
+		}
+		if aok {
+			return 1
+		}
+		return -1
+	}
+	for i := range an {
+		if an[i] != bn[i] {
+			if an[i] < bn[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case apre == bpre:
+		return 0
+	case apre == "":
+		return 1
+	case bpre == "":
+		return -1
+	}
+	as, bs := strings.Split(apre, "."), strings.Split(bpre, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, xerr := strconv.Atoi(as[i])
+		y, yerr := strconv.Atoi(bs[i])
+		switch {
+		case xerr == nil && yerr == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case xerr == nil:
+			return -1
+		case yerr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+package codec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Canonical returns the canonical JSON encoding of v: the encoding
+// json.Marshal produces, rewritten so that equal values always encode to
+// the same bytes. It follows RFC 8785 (JCS) closely:
+//
+//   - object members are sorted by the UTF-16 code units of their names;
+//   - there is no insignificant whitespace;
+//   - strings are not HTML-escaped;
+//   - non-integer numbers are written in their shortest form, 1e21 and up
+//     or below 1e-6 in exponent notation, like JavaScript does.
+//
+// Unlike JCS, integer literals keep every digit instead of being rounded
+// through float64, so int64 IDs hash and sign as the client sent them.
+//
+// Canonical output is meant for comparisons, hashes and signatures; the
+// codecs still encode messages in the order their fields are declared
+// unless SetCanonical is used.
+func Canonical(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := writeCanonical(&out, tree); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Digest returns the hex-encoded SHA-256 of the canonical encoding of v,
+// suitable as a cache or idempotency key.
+func Digest(v interface{}) (string, error) {
+	data, err := Canonical(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		s, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case string:
+		writeString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeString(buf, k)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("codec: unexpected %T in canonical encoding", v)
+	}
+	return nil
+}
+
+// canonicalNumber formats a number literal. Integer literals are kept
+// digit for digit, minus a negative zero's sign; everything else goes
+// through float64.
+func canonicalNumber(n json.Number) (string, error) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if s == "-0" {
+			return "0", nil
+		}
+		return s, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return "", fmt.Errorf("codec: number %s: %w", s, err)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e21 || abs < 1e-6 {
+		out := strconv.FormatFloat(f, 'e', -1, 64)
+		// Go writes e-07 where JavaScript writes e-7.
+		if i := strings.Index(out, "e"); i >= 0 && len(out) > i+3 && out[i+2] == '0' {
+			out = out[:i+2] + out[i+3:]
+		}
+		return out, nil
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// writeString writes s as a JSON string without HTML escaping.
+func writeString(buf *bytes.Buffer, s string) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string cannot fail
+	buf.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+}
+
+// lessUTF16 orders strings by their UTF-16 code units, as JCS requires.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,179 @@
+// Package codec implements the wire framings used by stream-oriented
+// transports to carry JSON-RPC messages.
+package codec
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// DefaultMaxFrameSize bounds the size of a single decoded frame.
+const DefaultMaxFrameSize = 16 << 20
+
+// Codec reads and writes JSON-RPC messages on a byte stream.
+//
+// Decode returns a *transport.FrameError, which unwraps to a *protocol.Error
+// with code ParseError, when a frame was read successfully but did not
+// contain valid JSON; the stream is still usable afterwards. Any other error
+// means the stream is broken.
+//
+// Bytes reports the total number of bytes read and written on the stream,
+// framing included.
+type Codec interface {
+	Decode(msg *protocol.Message) error
+	Encode(msg *protocol.Message) error
+	Bytes() (read, written int64)
+}
+
+// ContentLength implements LSP-style framing: every message is preceded by
+// a Content-Length header and a blank line. Compressed frames add a
+// Content-Encoding header; see SetCompressions.
+type ContentLength struct {
+	r            *bufio.Reader
+	w            io.Writer
+	maxFrameSize int
+	canonical    bool
+	compressions []Compression
+	encoder      atomic.Pointer[Compression] // set by EnableCompression
+
+	received atomic.Int64 // bytes pulled from the reader, read-ahead included
+	read     atomic.Int64 // bytes consumed by decoded frames
+	written  atomic.Int64
+}
+
+// NewContentLength returns a codec reading from r and writing to w.
+func NewContentLength(r io.Reader, w io.Writer) *ContentLength {
+	c := &ContentLength{w: w, maxFrameSize: DefaultMaxFrameSize}
+	c.r = bufio.NewReader(countingReader{r: r, n: &c.received})
+	return c
+}
+
+// Decode reads the next frame into msg.
+func (c *ContentLength) Decode(msg *protocol.Message) error {
+	defer func() { c.read.Store(c.received.Load() - int64(c.r.Buffered())) }()
+	tp := textproto.NewReader(c.r)
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF {
+			return io.EOF
+		}
+		return fmt.Errorf("read frame header: %w", err)
+	}
+	value := header.Get("Content-Length")
+	if value == "" {
+		return fmt.Errorf("frame header missing Content-Length")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid Content-Length %q", value)
+	}
+	if n > c.maxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds limit of %d", n, c.maxFrameSize)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return fmt.Errorf("read frame body: %w", err)
+	}
+	if encoding := header.Get("Content-Encoding"); encoding != "" {
+		if body, err = c.decompress(encoding, body); err != nil {
+			return &transport.FrameError{
+				Frame: body,
+				Size:  int64(n),
+				Err:   protocol.NewError(protocol.ParseError, "parse error: "+err.Error(), nil),
+			}
+		}
+	}
+	return unmarshal(body, msg)
+}
+
+// decompress decodes a body sent with the given Content-Encoding. On
+// failure it returns body unchanged along with the error.
+func (c *ContentLength) decompress(encoding string, body []byte) ([]byte, error) {
+	cmp := c.compression(encoding)
+	if cmp == nil {
+		return body, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+	out, err := cmp.Decompress(body, c.maxFrameSize)
+	if err != nil {
+		return body, fmt.Errorf("%s frame: %w", encoding, err)
+	}
+	return out, nil
+}
+
+// SetCanonical makes Encode write the Canonical encoding of messages, so
+// that identical messages are byte for byte identical on the wire. It must
+// be called before the codec is used.
+func (c *ContentLength) SetCanonical(on bool) { c.canonical = on }
+
+// Encode writes msg as a single frame, compressed when compression is
+// enabled and the frame is large enough to benefit.
+func (c *ContentLength) Encode(msg *protocol.Message) error {
+	marshal := json.Marshal
+	if c.canonical {
+		marshal = Canonical
+	}
+	body, err := marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal frame: %w", err)
+	}
+	var encoding string
+	if cmp := c.encoder.Load(); cmp != nil && len(body) >= DefaultCompressThreshold {
+		compressed, err := (*cmp).Compress(body)
+		if err != nil {
+			return fmt.Errorf("compress frame: %w", err)
+		}
+		if len(compressed) < len(body) {
+			body, encoding = compressed, (*cmp).Name()
+		}
+	}
+	frame := make([]byte, 0, len(body)+64)
+	frame = append(frame, "Content-Length: "...)
+	frame = strconv.AppendInt(frame, int64(len(body)), 10)
+	if encoding != "" {
+		frame = append(frame, "\r\nContent-Encoding: "...)
+		frame = append(frame, encoding...)
+	}
+	frame = append(frame, "\r\n\r\n"...)
+	frame = append(frame, body...)
+	n, err := c.w.Write(frame)
+	c.written.Add(int64(n))
+	return err
+}
+
+// Bytes implements Codec.
+func (c *ContentLength) Bytes() (read, written int64) {
+	return c.read.Load(), c.written.Load()
+}
+
+// countingReader adds the number of bytes read from r to n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func unmarshal(body []byte, msg *protocol.Message) error {
+	*msg = protocol.Message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return &transport.FrameError{
+			Frame: body,
+			Size:  int64(len(body)),
+			Err:   protocol.NewError(protocol.ParseError, "parse error: "+err.Error(), nil),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// DefaultCompressThreshold is the body size from which ContentLength
+// compresses frames once compression is enabled. Smaller frames rarely
+// shrink enough to pay for the work.
+const DefaultCompressThreshold = 1 << 10
+
+// Compression is a per-frame compression algorithm. Frames compressed with
+// it carry its Name in a Content-Encoding header, next to Content-Length.
+//
+// The standard library has no zstd implementation, so the "zstd" algorithm
+// is supplied by the application, typically wrapping an encoder and
+// decoder from a third-party package.
+type Compression interface {
+	// Name identifies the algorithm on the wire and during negotiation.
+	Name() string
+	// Compress returns the compressed form of p.
+	Compress(p []byte) ([]byte, error)
+	// Decompress returns the data p was compressed from, failing when it
+	// is longer than limit bytes.
+	Decompress(p []byte, limit int) ([]byte, error)
+}
+
+// Deflate is the Compression named "deflate", using compress/flate at the
+// default level.
+var Deflate Compression = deflate{}
+
+type deflate struct{}
+
+func (deflate) Name() string { return "deflate" }
+
+func (deflate) Compress(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (deflate) Decompress(p []byte, limit int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(p))
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > limit {
+		return nil, fmt.Errorf("decompressed frame exceeds limit of %d bytes", limit)
+	}
+	return out, nil
+}
+
+// SetCompressions sets the algorithms the codec can decode, in order of
+// preference, and thereby offers during negotiation. It must be called
+// before the codec is used.
+func (c *ContentLength) SetCompressions(cs ...Compression) {
+	c.compressions = cs
+}
+
+// Compressions returns the names of the algorithms set with
+// SetCompressions.
+func (c *ContentLength) Compressions() []string {
+	names := make([]string, len(c.compressions))
+	for i, cmp := range c.compressions {
+		names[i] = cmp.Name()
+	}
+	return names
+}
+
+// EnableCompression makes Encode compress frames of at least
+// DefaultCompressThreshold bytes with the named algorithm, which must be
+// one set with SetCompressions. Call it once the peer has agreed to the
+// algorithm; frames it does not compress stay readable by any peer.
+func (c *ContentLength) EnableCompression(name string) error {
+	cmp := c.compression(name)
+	if cmp == nil {
+		return fmt.Errorf("codec: compression %q not supported", name)
+	}
+	c.encoder.Store(&cmp)
+	return nil
+}
+
+func (c *ContentLength) compression(name string) Compression {
+	for _, cmp := range c.compressions {
+		if cmp.Name() == name {
+			return cmp
+		}
+	}
+	return nil
+}
+
+// Negotiate returns the first of offered, the algorithms a peer supports
+// in its order of preference, that is also in supported, or "" when they
+// have none in common.
+func Negotiate(offered, supported []string) string {
+	for _, name := range offered {
+		for _, s := range supported {
+			if name == s {
+				return name
+			}
+		}
+	}
+	return ""
+}
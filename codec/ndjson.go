@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// NDJSON implements newline-delimited framing: every message is a single
+// line of JSON. Blank lines between messages are skipped. It has no room
+// for headers, so frames are never compressed.
+type NDJSON struct {
+	r            *bufio.Reader
+	w            io.Writer
+	maxFrameSize int
+	canonical    bool
+
+	received atomic.Int64 // bytes pulled from the reader, read-ahead included
+	read     atomic.Int64 // bytes consumed by decoded frames
+	written  atomic.Int64
+}
+
+// NewNDJSON returns a codec reading from r and writing to w.
+func NewNDJSON(r io.Reader, w io.Writer) *NDJSON {
+	c := &NDJSON{w: w, maxFrameSize: DefaultMaxFrameSize}
+	c.r = bufio.NewReader(countingReader{r: r, n: &c.received})
+	return c
+}
+
+// Decode reads the next line into msg.
+func (c *NDJSON) Decode(msg *protocol.Message) error {
+	defer func() { c.read.Store(c.received.Load() - int64(c.r.Buffered())) }()
+	for {
+		line, err := c.readLine()
+		if len(bytes.TrimSpace(line)) > 0 {
+			return unmarshal(line, msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLine returns the next line without its line ending. A last line
+// lacking one is returned with a nil error; io.EOF follows.
+func (c *NDJSON) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := c.r.ReadSlice('\n')
+		if len(line)+len(chunk) > c.maxFrameSize+2 {
+			return nil, fmt.Errorf("frame exceeds limit of %d bytes", c.maxFrameSize)
+		}
+		line = append(line, chunk...)
+		switch {
+		case err == nil:
+			line = bytes.TrimSuffix(line[:len(line)-1], []byte("\r"))
+			return line, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err == io.EOF && len(line) > 0:
+			return line, nil
+		case err == io.EOF:
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("read frame: %w", err)
+	}
+}
+
+// SetCanonical makes Encode write the Canonical encoding of messages, as
+// ContentLength.SetCanonical does.
+func (c *NDJSON) SetCanonical(on bool) { c.canonical = on }
+
+// Encode writes msg as a single line.
+func (c *NDJSON) Encode(msg *protocol.Message) error {
+	marshal := json.Marshal
+	if c.canonical {
+		marshal = Canonical
+	}
+	body, err := marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal frame: %w", err)
+	}
+	n, err := c.w.Write(append(body, '\n'))
+	c.written.Add(int64(n))
+	return err
+}
+
+// Bytes implements Codec.
+func (c *NDJSON) Bytes() (read, written int64) {
+	return c.read.Load(), c.written.Load()
+}
+
+// IsNDJSON reports whether a stream starting with prefix, the first bytes
+// a peer sent, is framed as NDJSON rather than with Content-Length
+// headers: whether its first character other than white space opens a
+// JSON value. It returns ok false when prefix holds only white space.
+func IsNDJSON(prefix []byte) (ndjson, ok bool) {
+	trimmed := bytes.TrimLeft(prefix, " \t\r\n")
+	if len(trimmed) == 0 {
+		return false, false
+	}
+	return trimmed[0] == '{' || trimmed[0] == '[', true
+}
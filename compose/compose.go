@@ -0,0 +1,291 @@
+// Package compose defines tools as declarative pipelines of other tools.
+//
+// Every step calls a registered tool. Its arguments are built from a
+// template in which strings starting with "$" are paths into the pipeline
+// state:
+//
+//	$.input.query       the pipeline's own arguments
+//	$.prev.items[0].id  the output of the previous step
+//	$.steps.search.url  the output of the step named "search"
+//	$.steps[1]          the output of the second step
+//
+// A step's output is the JSON value of its single text content item when
+// that parses as JSON, or the concatenated text otherwise. Any other
+// template value is used literally; maps and slices are walked.
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Step is one tool invocation of a pipeline.
+type Step struct {
+	// Name identifies the step's output as $.steps.<name>. Optional.
+	Name string
+	// Tool is the tool to call, optionally as "name@version".
+	Tool string
+	// Args is the argument template.
+	Args map[string]interface{}
+}
+
+// Pipeline describes a composite tool.
+type Pipeline struct {
+	Name        string
+	Description string
+	// InputSchema describes the pipeline's arguments. Defaults to an
+	// unconstrained object.
+	InputSchema map[string]interface{}
+	Tags        []string
+	Steps       []Step
+	// Output is a template selecting the pipeline's result. When nil, the
+	// last step's result is returned unchanged.
+	Output interface{}
+}
+
+// Register validates p and registers it on s as a tool named p.Name.
+func Register(s *mcp.Server, p Pipeline) error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("compose: pipeline %q has no steps", p.Name)
+	}
+	seen := make(map[string]bool)
+	for i, st := range p.Steps {
+		if st.Tool == "" {
+			return fmt.Errorf("compose: pipeline %q: step %d has no tool", p.Name, i)
+		}
+		if base, _, _ := strings.Cut(st.Tool, "@"); base == p.Name {
+			return fmt.Errorf("compose: pipeline %q calls itself", p.Name)
+		}
+		if st.Name != "" {
+			if seen[st.Name] {
+				return fmt.Errorf("compose: pipeline %q: duplicate step name %q", p.Name, st.Name)
+			}
+			seen[st.Name] = true
+		}
+	}
+	router := s.Router()
+	return s.Registry().RegisterTool(p.Name, registry.ToolDescriptor{
+		Description: p.Description,
+		InputSchema: p.InputSchema,
+		Tags:        p.Tags,
+		Handler: func(ctx context.Context, args json.RawMessage) (*protocol.ToolCallResult, error) {
+			return p.run(ctx, router, args)
+		},
+	})
+}
+
+func (p *Pipeline) run(ctx context.Context, router *runtime.Router, args json.RawMessage) (*protocol.ToolCallResult, error) {
+	state, err := NewState(args)
+	if err != nil {
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+	}
+	var last *protocol.ToolCallResult
+	for i, st := range p.Steps {
+		stepArgs, err := state.Expand(st.Args)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i, st.Tool, err)
+		}
+		raw, err := json.Marshal(stepArgs)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): encode arguments: %w", i, st.Tool, err)
+		}
+		res, err := router.CallTool(ctx, st.Tool, raw)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i, st.Tool, err)
+		}
+		if res.IsError {
+			return StepFailed(i, st.Tool, res), nil
+		}
+		state.Record(st.Name, StepOutput(res))
+		last = res
+	}
+	if p.Output == nil {
+		return last, nil
+	}
+	return state.Result(p.Output)
+}
+
+// StepFailed wraps the error result of step i so that the caller can tell
+// which step failed.
+func StepFailed(i int, tool string, res *protocol.ToolCallResult) *protocol.ToolCallResult {
+	content := append([]protocol.Content{protocol.TextContent(fmt.Sprintf("step %d (%s) failed:", i, tool))}, res.Content...)
+	return &protocol.ToolCallResult{Content: content, IsError: true}
+}
+
+// StepOutput converts a step result into a value paths can address.
+func StepOutput(res *protocol.ToolCallResult) interface{} {
+	var texts []string
+	for _, c := range res.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if len(texts) == 1 {
+		var v interface{}
+		if err := json.Unmarshal([]byte(text), &v); err == nil {
+			return v
+		}
+	}
+	return text
+}
+
+// State is the data step templates select from. It encodes to JSON so
+// that long-running executions can persist it between steps.
+type State struct {
+	Input   interface{}            `json:"input"`
+	Outputs []interface{}          `json:"outputs"`
+	Named   map[string]interface{} `json:"named"`
+}
+
+// NewState returns the state of an execution started with args.
+func NewState(args json.RawMessage) (*State, error) {
+	s := &State{Named: make(map[string]interface{})}
+	if err := json.Unmarshal(args, &s.Input); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Record appends the output of a step, also making it addressable by name
+// when name is not empty.
+func (s *State) Record(name string, out interface{}) {
+	s.Outputs = append(s.Outputs, out)
+	if name != "" {
+		if s.Named == nil {
+			s.Named = make(map[string]interface{})
+		}
+		s.Named[name] = out
+	}
+}
+
+// Result evaluates an output template into a tool result: strings are
+// returned as text, anything else as JSON text.
+func (s *State) Result(template interface{}) (*protocol.ToolCallResult, error) {
+	v, err := s.Expand(template)
+	if err != nil {
+		return nil, fmt.Errorf("output: %w", err)
+	}
+	if str, ok := v.(string); ok {
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(str)}}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("output: %w", err)
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+// Expand evaluates the paths in template t.
+func (s *State) Expand(t interface{}) (interface{}, error) {
+	switch t := t.(type) {
+	case string:
+		if !strings.HasPrefix(t, "$") {
+			return t, nil
+		}
+		return s.lookup(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			ev, err := s.Expand(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = ev
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, v := range t {
+			ev, err := s.Expand(v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = ev
+		}
+		return out, nil
+	}
+	return t, nil
+}
+
+// lookup evaluates a path such as "$.steps.search.items[0]['id']".
+func (s *State) lookup(path string) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var prev interface{}
+	if len(s.Outputs) > 0 {
+		prev = s.Outputs[len(s.Outputs)-1]
+	}
+	var cur interface{} = map[string]interface{}{"input": s.Input, "prev": prev, "steps": s.Named}
+	for i, seg := range segs {
+		// $.steps[n] addresses steps by position.
+		if i == 1 && segs[0] == "steps" {
+			if n, err := strconv.Atoi(seg); err == nil {
+				if n < 0 || n >= len(s.Outputs) {
+					return nil, fmt.Errorf("%s: step %d has not run", path, n)
+				}
+				cur = s.Outputs[n]
+				continue
+			}
+		}
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			v, ok := c[seg]
+			if !ok {
+				return nil, fmt.Errorf("%s: no field %q", path, seg)
+			}
+			cur = v
+		case []interface{}:
+			n, err := strconv.Atoi(seg)
+			if err != nil || n < 0 || n >= len(c) {
+				return nil, fmt.Errorf("%s: index %q out of range", path, seg)
+			}
+			cur = c[n]
+		default:
+			return nil, fmt.Errorf("%s: cannot select %q from %T", path, seg, cur)
+		}
+	}
+	return cur, nil
+}
+
+// parsePath splits "$.a.b[0]['c d']" into ["a", "b", "0", "c d"].
+func parsePath(path string) ([]string, error) {
+	rest := strings.TrimPrefix(path, "$")
+	var segs []string
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%s: empty path segment", path)
+			}
+			segs = append(segs, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%s: unterminated [", path)
+			}
+			segs = append(segs, strings.Trim(rest[1:end], `'"`))
+			rest = rest[end+1:]
+		default:
+			return nil, errors.New(path + ": path must start with $. or $[")
+		}
+	}
+	return segs, nil
+}
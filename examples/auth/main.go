@@ -0,0 +1,100 @@
+// Command auth serves tools over HTTP to clients presenting a bearer
+// token, and shows how handlers see who is calling.
+//
+// Tokens map to principals with scopes. The whoami tool reports the
+// caller; the admin.reset tool refuses callers without the "admin" scope.
+//
+//	go run ./examples/auth -addr 127.0.0.1:8080
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/auth"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport/http"
+)
+
+// tokens would come from a secret store in a real deployment.
+var tokens = map[string]*auth.Principal{
+	"alice-token": {Subject: "alice", Scopes: []string{"read", "admin"}},
+	"bob-token":   {Subject: "bob", Scopes: []string{"read"}},
+}
+
+// bearer authenticates requests by their Authorization header.
+func bearer(r *nethttp.Request) (*auth.Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, errors.New("missing bearer token")
+	}
+	for t, p := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return p, nil
+		}
+	}
+	return nil, errors.New("unknown token")
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("auth-example")}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "whoami",
+		Description: "Report the authenticated caller and their scopes.",
+	}, func(ctx *runtime.Context, _ struct{}) (*protocol.ToolCallResult, error) {
+		p := ctx.Principal()
+		if p == nil {
+			return nil, errors.New("anonymous caller")
+		}
+		text := fmt.Sprintf("%s (scopes: %s)", p.Subject, strings.Join(p.Scopes, ", "))
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(text)}}, nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "admin.reset",
+		Description: "Reset the server state. Requires the admin scope.",
+	}, func(ctx *runtime.Context, _ struct{}) (*protocol.ToolCallResult, error) {
+		if p := ctx.Principal(); p == nil || !p.HasScope("admin") {
+			return &protocol.ToolCallResult{
+				Content: []protocol.Content{protocol.TextContent("the admin scope is required")},
+				IsError: true,
+			}, nil
+		}
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent("reset")}}, nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "address to listen on")
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := newServer(mcp.WithTransport(http.New(l.Addr().String(), http.WithListener(l), http.WithAuthenticator(bearer))))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving on http://%s/mcp", l.Addr())
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+func TestBearer(t *testing.T) {
+	tests := []struct {
+		header  string
+		subject string
+	}{
+		{"", ""},
+		{"alice-token", ""},
+		{"Bearer wrong", ""},
+		{"Bearer alice-token", "alice"},
+		{"Bearer bob-token", "bob"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(nethttp.MethodPost, "/mcp", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		p, err := bearer(r)
+		switch {
+		case tt.subject == "" && err == nil:
+			t.Errorf("bearer(%q) = %s, want an error", tt.header, p.Subject)
+		case tt.subject != "" && err != nil:
+			t.Errorf("bearer(%q): %v", tt.header, err)
+		case tt.subject != "" && p.Subject != tt.subject:
+			t.Errorf("bearer(%q) = %s, want %s", tt.header, p.Subject, tt.subject)
+		}
+	}
+}
+
+func TestScopes(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exampletest.Serve(t, s)
+	alice := exampletest.Dial(t, tr, transport.Peer{Principal: tokens["alice-token"]})
+	bob := exampletest.Dial(t, tr, transport.Peer{Principal: tokens["bob-token"]})
+
+	if got := exampletest.CallTool(t, alice, "whoami", nil); got != "alice (scopes: read, admin)" {
+		t.Errorf("whoami as alice = %q", got)
+	}
+	if got := exampletest.CallTool(t, alice, "admin.reset", nil); got != "reset" {
+		t.Errorf("admin.reset as alice = %q", got)
+	}
+	res, err := bob.CallTool(exampletest.Context(t), "admin.reset", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError || !strings.Contains(exampletest.Text(res), "admin scope") {
+		t.Errorf("admin.reset as bob = %+v, want a scope error", res)
+	}
+
+	anonymous := exampletest.Dial(t, tr, transport.Peer{})
+	if res, err := anonymous.CallTool(exampletest.Context(t), "whoami", nil); err == nil && !res.IsError {
+		t.Errorf("whoami as anonymous = %q, want an error", exampletest.Text(res))
+	}
+}
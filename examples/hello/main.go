@@ -0,0 +1,75 @@
+// Command hello is the smallest useful zenmcp server: one typed tool and
+// one resource, served over stdio.
+//
+// Run it from an MCP client configuration:
+//
+//	{"command": "go", "args": ["run", "./examples/hello"]}
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+type greetArgs struct {
+	Name     string `json:"name" description:"Who to greet." validate:"nonzero"`
+	Shouting bool   `json:"shouting,omitempty" description:"Greet in capitals."`
+}
+
+func greet(ctx *runtime.Context, args greetArgs) (*protocol.ToolCallResult, error) {
+	msg := fmt.Sprintf("Hello, %s!", args.Name)
+	if args.Shouting {
+		msg = strings.ToUpper(msg)
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(msg)}}, nil
+}
+
+type serverTime struct {
+	Now  time.Time `json:"now"`
+	Zone string    `json:"zone"`
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("hello"), mcp.WithVersion("1.0.0")}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "greet",
+		Description: "Greet someone by name.",
+	}, greet); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterResourceTyped(s, registry.ResourceDescriptor{
+		URI:         "hello://time",
+		Name:        "Server time",
+		Description: "The current time on the server.",
+	}, func(ctx *runtime.Context, uri string) (serverTime, error) {
+		now := time.Now()
+		zone, _ := now.Zone()
+		return serverTime{Now: now, Zone: zone}, nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+func TestHello(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exampletest.Connect(t, s, tr)
+
+	if got := exampletest.CallTool(t, c, "greet", map[string]interface{}{"name": "Ada"}); got != "Hello, Ada!" {
+		t.Errorf("greet = %q", got)
+	}
+	if got := exampletest.CallTool(t, c, "greet", map[string]interface{}{"name": "Ada", "shouting": true}); got != "HELLO, ADA!" {
+		t.Errorf("greet shouting = %q", got)
+	}
+	if _, err := c.CallTool(exampletest.Context(t), "greet", map[string]interface{}{}); err == nil {
+		t.Error("greet accepted a call without a name")
+	}
+
+	res, err := c.ReadResource(exampletest.Context(t), "hello://time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var now serverTime
+	if len(res.Contents) != 1 || json.Unmarshal([]byte(res.Contents[0].Text), &now) != nil || now.Now.IsZero() {
+		t.Errorf("hello://time = %+v", res.Contents)
+	}
+}
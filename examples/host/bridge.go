@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport/http"
+)
+
+// serveEnv names the stdio server a subprocess of the host should be.
+const serveEnv = "ZENMCP_HOST_EXAMPLE_SERVE"
+
+// functionSep joins a server's name to the name of one of its tools in
+// the functions offered to the model, so that servers may offer tools of
+// the same name.
+const functionSep = "__"
+
+// function is a tool as the model sees it.
+type function struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// connection is the host's session with one server.
+type connection struct {
+	name   string
+	client *mcp.Client
+}
+
+// route is where the calls of one function go.
+type route struct {
+	conn *connection
+	tool string
+}
+
+// host connects to MCP servers and offers their tools to a model as
+// functions, running the calls the model makes.
+type host struct {
+	// timeout bounds each tool call. When it expires, the call is
+	// cancelled on the server and the model is told so.
+	timeout time.Duration
+	// out receives the progress of tool calls and the stderr of the
+	// stdio servers.
+	out io.Writer
+
+	mu        sync.Mutex // serializes writes to out
+	conns     []*connection
+	functions []function
+	routes    map[string]route
+	// tokens maps the progress token of each call in flight to the name
+	// of the function called.
+	tokens    sync.Map
+	nextToken atomic.Int64
+	stop      []func()
+}
+
+// newHost returns a host connected to no server yet.
+func newHost(out io.Writer) *host {
+	return &host{timeout: 10 * time.Second, out: out, routes: make(map[string]route)}
+}
+
+// startStdio runs the named server of this example as a subprocess, by
+// running the host's own executable with serveEnv set, and connects to it
+// over its stdin and stdout.
+func (h *host) startStdio(ctx context.Context, name string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self)
+	cmd.Env = append(os.Environ(), serveEnv+"="+name)
+	cmd.Stderr = h.writer()
+	conn, err := mcp.NewCommandConn(cmd)
+	if err != nil {
+		return err
+	}
+	return h.connect(ctx, name, conn)
+}
+
+// startHTTP serves the weather server on a loopback port until Close and
+// connects to it over HTTP, as a host would to a remote server.
+func (h *host) startHTTP(ctx context.Context, name string) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	s, err := newWeatherServer(mcp.WithTransport(http.New(l.Addr().String(), http.WithListener(l))))
+	if err != nil {
+		l.Close()
+		return err
+	}
+	sctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(sctx)
+	}()
+	// Stopped after the connections close, in Close.
+	h.stop = append(h.stop, func() { cancel(); <-done })
+	return h.connect(ctx, name, http.Dial("http://"+l.Addr().String()+"/mcp", http.ClientOptions{}))
+}
+
+// connect initializes a session with a server over conn and adds its
+// tools to the functions.
+func (h *host) connect(ctx context.Context, name string, conn mcp.ClientConn) error {
+	c := mcp.NewClient(conn,
+		mcp.WithClientInfo("zenmcp-host-example", "1.0.0"),
+		mcp.WithNotificationHandler(h.notification),
+	)
+	if _, err := c.Initialize(ctx); err != nil {
+		c.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	tools, err := c.ListTools(ctx, nil)
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	cn := &connection{name: name, client: c}
+	h.conns = append(h.conns, cn)
+	for _, t := range tools.Tools {
+		fn := name + functionSep + t.Name
+		h.functions = append(h.functions, function{Name: fn, Description: t.Description, Parameters: t.InputSchema})
+		h.routes[fn] = route{conn: cn, tool: t.Name}
+	}
+	return nil
+}
+
+// call runs the tool behind a function the model called and returns what
+// to tell the model: the tool's text, or why it failed. It fails itself
+// only when ctx is done, which ends the conversation.
+func (h *host) call(ctx context.Context, call toolCall) (string, error) {
+	r, ok := h.routes[call.Name]
+	if !ok {
+		return fmt.Sprintf("error: no function %s", call.Name), nil
+	}
+	token := fmt.Sprintf("call-%d", h.nextToken.Add(1))
+	h.tokens.Store(token, call.Name)
+	defer h.tokens.Delete(token)
+
+	cctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	var res protocol.ToolCallResult
+	// Call rather than CallTool, to ask for progress in the request's
+	// _meta. If cctx ends first, the client sends
+	// notifications/cancelled and the server stops the tool.
+	err := r.conn.client.Call(cctx, protocol.MethodToolsCall, protocol.ToolCallParams{
+		Name:      r.tool,
+		Arguments: call.Arguments,
+		Meta:      &protocol.RequestMeta{ProgressToken: token},
+	}, &res)
+	switch {
+	case ctx.Err() != nil:
+		return "", ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		h.printf("[%s] cancelled after %s\n", call.Name, h.timeout)
+		return fmt.Sprintf("cancelled: %s did not finish within %s", call.Name, h.timeout), nil
+	case err != nil:
+		return "error: " + err.Error(), nil
+	}
+	var parts []string
+	for _, c := range res.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		}
+	}
+	out := strings.Join(parts, "\n")
+	if res.IsError {
+		out = "error: " + out
+	}
+	return out, nil
+}
+
+// notification prints the progress of the calls in flight.
+func (h *host) notification(method string, params json.RawMessage) {
+	if method != protocol.MethodProgress {
+		return
+	}
+	var p protocol.ProgressParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return
+	}
+	fn, ok := h.tokens.Load(p.ProgressToken)
+	if !ok {
+		return
+	}
+	h.printf("[%s] %g/%g %s\n", fn, p.Progress, p.Total, p.Message)
+}
+
+// Close ends the sessions, which stops the stdio servers, and then the
+// HTTP server.
+func (h *host) Close() error {
+	var errs []error
+	for _, c := range h.conns {
+		if err := c.client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	for _, stop := range h.stop {
+		stop()
+	}
+	return errors.Join(errs...)
+}
+
+func (h *host) printf(format string, args ...interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(h.out, format, args...)
+}
+
+// writer returns a writer to out that is safe to share with the
+// subprocesses' stderr.
+func (h *host) writer() io.Writer { return lockedWriter{h} }
+
+type lockedWriter struct{ h *host }
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.h.mu.Lock()
+	defer w.h.mu.Unlock()
+	return w.h.out.Write(p)
+}
@@ -0,0 +1,81 @@
+// Command host is the other side of the examples: an application that
+// connects to several MCP servers and lets a language model use their
+// tools.
+//
+// It starts two servers as subprocesses speaking MCP over stdio, with
+// mcp.NewCommandConn, and reaches a third over HTTP. The tools they list
+// become the functions offered to the model, named after their server so
+// that names cannot clash, and the calls the model makes are routed back
+// to the right server. Each call asks for progress, printed as it
+// arrives, and is bounded by -timeout: when that expires, or on Ctrl-C,
+// the client sends notifications/cancelled and the server stops the tool.
+//
+// The model is scripted so that the example needs no API key; it
+// deliberately asks for a reindex too long for the timeout, then retries
+// with less work once told it was cancelled.
+//
+//	go run ./examples/host
+//	go run ./examples/host -timeout 30s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+// serveStdio serves the named stdio server until ctx is done or the host
+// closes its stdin.
+func serveStdio(ctx context.Context, name string) error {
+	newServer, ok := stdioServers[name]
+	if !ok {
+		return fmt.Errorf("no server %q", name)
+	}
+	s, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx)
+}
+
+// start connects h to the example's servers.
+func start(ctx context.Context, h *host) error {
+	for _, name := range []string{"notes", "indexer"} {
+		if err := h.startStdio(ctx, name); err != nil {
+			return err
+		}
+	}
+	return h.startHTTP(ctx, "weather")
+}
+
+func main() {
+	timeout := flag.Duration("timeout", 2*time.Second, "how long a tool call may take before it is cancelled")
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if name := os.Getenv(serveEnv); name != "" {
+		if err := serveStdio(ctx, name); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		return
+	}
+
+	h := newHost(os.Stdout)
+	h.timeout = *timeout
+	defer h.Close()
+	if err := start(ctx, h); err != nil {
+		log.Fatal(err)
+	}
+	answer, err := converse(ctx, h, scriptedModel{}, "Check tomorrow's weather in Lyon and note it for my trip.")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(answer)
+}
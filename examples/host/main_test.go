@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+)
+
+// TestMain makes the test binary serve the stdio servers when the host
+// under test runs it as a subprocess, as the command does.
+func TestMain(m *testing.M) {
+	if name := os.Getenv(serveEnv); name != "" {
+		if err := serveStdio(context.Background(), name); err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// syncBuffer is written by the host and the subprocesses' stderr copiers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func startHost(t *testing.T, out *syncBuffer) *host {
+	t.Helper()
+	h := newHost(out)
+	h.timeout = time.Second
+	if err := start(exampletest.Context(t), h); err != nil {
+		h.Close()
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestConverse(t *testing.T) {
+	var out syncBuffer
+	h := startHost(t, &out)
+	var names []string
+	for _, f := range h.functions {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, " "); got != "notes__save_note notes__search_notes indexer__build_index weather__forecast" {
+		t.Errorf("functions = %s", got)
+	}
+
+	answer, err := converse(exampletest.Context(t), h, scriptedModel{}, "Note the weather in Lyon.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Saved the Lyon forecast to your notes: Lyon tomorrow: sunny, 24°C." {
+		t.Errorf("answer = %q", answer)
+	}
+	// The note reached the notes server.
+	found, err := h.call(exampletest.Context(t), toolCall{Name: "notes__search_notes", Arguments: json.RawMessage(`{"query":"sunny"}`)})
+	if err != nil || found != "Lyon trip" {
+		t.Errorf("search_notes = %q, %v", found, err)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	log := out.String()
+	for _, want := range []string{
+		"[indexer__build_index] 1/100 indexed shard 1 of 100\n",
+		"indexer: stopped after ",
+		"[indexer__build_index] cancelled after 1s\n",
+		"[indexer__build_index] 4/4 indexed shard 4 of 4\n",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("output lacks %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	var out syncBuffer
+	h := startHost(t, &out)
+	h.timeout = time.Minute
+
+	// Cancelling the conversation, as Ctrl-C does, cancels the call in
+	// progress on the server.
+	ctx, cancel := context.WithCancel(exampletest.Context(t))
+	time.AfterFunc(300*time.Millisecond, cancel)
+	_, err := h.call(ctx, toolCall{Name: "indexer__build_index", Arguments: json.RawMessage(`{"shards":100,"millis":100}`)})
+	if err != context.Canceled {
+		t.Fatalf("call = %v, want context.Canceled", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if log := out.String(); !strings.Contains(log, "indexer: stopped after ") {
+		t.Errorf("the indexer did not stop:\n%s", log)
+	}
+}
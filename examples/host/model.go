@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxTurns bounds the model's replies in one conversation, in case it
+// keeps calling functions.
+const maxTurns = 10
+
+// message is one message of a function-calling conversation, shaped like
+// those of the usual chat completion APIs.
+type message struct {
+	Role    string `json:"role"` // "user", "assistant" or "tool"
+	Content string `json:"content,omitempty"`
+	// ToolCalls are the functions an assistant message asks to call.
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// toolCall is a call of a function the model asks for.
+type toolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// model is a language model able to call functions. Given the
+// conversation so far and the functions it may call, it returns its next
+// message: either calls to make, whose results are added to the
+// conversation before asking it again, or its answer.
+type model interface {
+	Next(ctx context.Context, messages []message, functions []function) (message, error)
+}
+
+// converse has m answer prompt, calling the host's tools as it asks, and
+// returns its answer.
+func converse(ctx context.Context, h *host, m model, prompt string) (string, error) {
+	messages := []message{{Role: "user", Content: prompt}}
+	for turn := 0; turn < maxTurns; turn++ {
+		reply, err := m.Next(ctx, messages, h.functions)
+		if err != nil {
+			return "", err
+		}
+		messages = append(messages, reply)
+		if len(reply.ToolCalls) == 0 {
+			return reply.Content, nil
+		}
+		for _, call := range reply.ToolCalls {
+			h.printf("[%s] %s\n", call.Name, call.Arguments)
+			out, err := h.call(ctx, call)
+			if err != nil {
+				return "", err
+			}
+			messages = append(messages, message{Role: "tool", ToolCallID: call.ID, Content: out})
+		}
+	}
+	return "", errors.New("the model did not answer within the turn limit")
+}
+
+// scriptedModel stands in for a real model, so that the example runs
+// without an API key: it plans a trip note by following a fixed script,
+// reading the tool results the way a model would. A host would instead
+// send the messages and functions to a chat completion API.
+type scriptedModel struct{}
+
+func (scriptedModel) Next(ctx context.Context, messages []message, functions []function) (message, error) {
+	for _, name := range []string{"weather__forecast", "indexer__build_index", "notes__save_note"} {
+		if !hasFunction(functions, name) {
+			return message{}, fmt.Errorf("scripted model: no function %s", name)
+		}
+	}
+	results := make(map[string]string)
+	for _, m := range messages {
+		if m.Role == "tool" {
+			results[m.ToolCallID] = m.Content
+		}
+	}
+	_, retried := results["3"]
+	_, saved := results["4"]
+	switch {
+	case len(results) == 0:
+		// Check the weather and, in the same turn, try a full reindex,
+		// which takes longer than the host allows.
+		return calls(
+			call("1", "weather__forecast", `{"city":"Lyon"}`),
+			call("2", "indexer__build_index", `{"shards":100,"millis":100}`),
+		), nil
+	case strings.HasPrefix(results["2"], "cancelled") && !retried:
+		// The full reindex was cancelled: rebuild fewer shards.
+		return calls(call("3", "indexer__build_index", `{"shards":4,"millis":100}`)), nil
+	case !saved:
+		return calls(call("4", "notes__save_note", note(results["1"]))), nil
+	default:
+		return message{Role: "assistant", Content: "Saved the Lyon forecast to your notes: " + results["1"] + "."}, nil
+	}
+}
+
+func hasFunction(functions []function, name string) bool {
+	for _, f := range functions {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func calls(cs ...toolCall) message { return message{Role: "assistant", ToolCalls: cs} }
+
+func call(id, name, args string) toolCall {
+	return toolCall{ID: id, Name: name, Arguments: json.RawMessage(args)}
+}
+
+func note(forecast string) string {
+	args, _ := json.Marshal(saveNoteArgs{Title: "Lyon trip", Body: forecast})
+	return string(args)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// The servers the host connects to. They live in this command so that the
+// example is self-contained: the host runs itself again as each stdio
+// server, and serves the HTTP one in process on a loopback port.
+
+// serverLogger keeps the servers' connection logs out of the host's
+// output; warnings still reach stderr.
+var serverLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+func text(s string) *protocol.ToolCallResult {
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(s)}}
+}
+
+type saveNoteArgs struct {
+	Title string `json:"title" description:"Title of the note." validate:"nonzero"`
+	Body  string `json:"body" description:"Text of the note."`
+}
+
+type searchNotesArgs struct {
+	Query string `json:"query" description:"Text to look for in titles and bodies."`
+}
+
+// newNotesServer keeps notes in memory for as long as it runs.
+func newNotesServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("notes"), mcp.WithLogger(serverLogger)}, opts...)...)
+	var (
+		mu    sync.Mutex
+		notes = make(map[string]string)
+	)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "save_note",
+		Description: "Save a note under a title, replacing any note of that title.",
+	}, func(ctx *runtime.Context, args saveNoteArgs) (*protocol.ToolCallResult, error) {
+		mu.Lock()
+		notes[args.Title] = args.Body
+		mu.Unlock()
+		return text(fmt.Sprintf("saved %q", args.Title)), nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "search_notes",
+		Description: "List the titles of the notes mentioning a text.",
+	}, func(ctx *runtime.Context, args searchNotesArgs) (*protocol.ToolCallResult, error) {
+		mu.Lock()
+		var titles []string
+		for title, body := range notes {
+			if strings.Contains(title, args.Query) || strings.Contains(body, args.Query) {
+				titles = append(titles, title)
+			}
+		}
+		mu.Unlock()
+		sort.Strings(titles)
+		if len(titles) == 0 {
+			return text("no notes found"), nil
+		}
+		return text(strings.Join(titles, "\n")), nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type buildIndexArgs struct {
+	Shards int `json:"shards" description:"Number of shards to index." validate:"nonzero"`
+	// Millis stands in for real work.
+	Millis int `json:"millis,omitempty" description:"How long each shard takes, in milliseconds." default:"50"`
+}
+
+// newIndexServer builds a search index slowly, reporting progress after
+// every shard and stopping as soon as the call is cancelled.
+func newIndexServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("indexer"), mcp.WithLogger(serverLogger)}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "build_index",
+		Description: "Rebuild the search index. Takes a while for many shards.",
+	}, func(ctx *runtime.Context, args buildIndexArgs) (*protocol.ToolCallResult, error) {
+		if args.Millis == 0 {
+			args.Millis = 50
+		}
+		for i := 1; i <= args.Shards; i++ {
+			select {
+			case <-time.After(time.Duration(args.Millis) * time.Millisecond):
+			case <-ctx.Done():
+				// Printed to the indexer's stderr, which the host shares,
+				// to show that the cancellation reached the server.
+				fmt.Fprintf(os.Stderr, "indexer: stopped after %d of %d shards: %v\n", i-1, args.Shards, ctx.Err())
+				return nil, ctx.Err()
+			}
+			msg := fmt.Sprintf("indexed shard %d of %d", i, args.Shards)
+			if err := ctx.ReportProgressMessage(float64(i), float64(args.Shards), msg); err != nil {
+				return nil, err
+			}
+		}
+		return text(fmt.Sprintf("index rebuilt from %d shards", args.Shards)), nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type forecastArgs struct {
+	City string `json:"city" description:"City to forecast." validate:"nonzero"`
+}
+
+// newWeatherServer answers forecasts; the host reaches it over HTTP.
+func newWeatherServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("weather"), mcp.WithLogger(serverLogger)}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "forecast",
+		Description: "Tomorrow's weather in a city.",
+	}, func(ctx *runtime.Context, args forecastArgs) (*protocol.ToolCallResult, error) {
+		return text(fmt.Sprintf("%s tomorrow: sunny, 24°C", args.City)), nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// stdioServers are the servers the host starts as subprocesses, by the
+// name passed to them in serveEnv.
+var stdioServers = map[string]func(opts ...mcp.Option) (*mcp.Server, error){
+	"notes":   newNotesServer,
+	"indexer": newIndexServer,
+}
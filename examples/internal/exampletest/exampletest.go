@@ -0,0 +1,94 @@
+// Package exampletest runs example servers in process for their tests.
+// The server is served on an inproc transport and the test drives it
+// through an mcp.Client, as a host application would, so that each
+// example proves it still builds and behaves as its comments say.
+package exampletest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+// Timeout bounds each request of an example test.
+const Timeout = 10 * time.Second
+
+// Serve serves s until the test ends.
+func Serve(t testing.TB, s *mcp.Server) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(Timeout):
+			t.Error("exampletest: server did not stop")
+		}
+	})
+}
+
+// Connect serves s, created with tr as its transport, until the test ends
+// and returns an initialized client connected to it.
+func Connect(t testing.TB, s *mcp.Server, tr *inproc.Transport, opts ...mcp.ClientOption) *mcp.Client {
+	t.Helper()
+	Serve(t, s)
+	return Dial(t, tr, transport.Peer{}, opts...)
+}
+
+// Dial returns an initialized client connected to the server serving tr,
+// which sees it as peer. The client is closed when the test ends.
+func Dial(t testing.TB, tr *inproc.Transport, peer transport.Peer, opts ...mcp.ClientOption) *mcp.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	conn, err := tr.DialPeer(ctx, peer)
+	if err != nil {
+		t.Fatalf("exampletest: dial: %v", err)
+	}
+	c := mcp.NewClient(conn, append([]mcp.ClientOption{mcp.WithClientInfo("example-test", "1")}, opts...)...)
+	t.Cleanup(func() { c.Close() })
+	if _, err := c.Initialize(ctx); err != nil {
+		t.Fatalf("exampletest: initialize: %v", err)
+	}
+	return c
+}
+
+// Context returns a context bounding a request by Timeout.
+func Context(t testing.TB) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// CallTool calls tool name with args and returns the text of its result,
+// failing the test when the call or the tool fails.
+func CallTool(t testing.TB, c *mcp.Client, name string, args interface{}) string {
+	t.Helper()
+	res, err := c.CallTool(Context(t), name, args)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if res.IsError {
+		t.Fatalf("%s failed: %s", name, Text(res))
+	}
+	return Text(res)
+}
+
+// Text returns the text content of a tool call result, one item per line.
+func Text(res *protocol.ToolCallResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,74 @@
+// Command jobs runs a slow tool as a background job so that clients do
+// not time out waiting for it.
+//
+// The report tool is registered with Async set. Once a jobs.Manager is
+// installed, calling it returns a job ID at once; the client polls
+// jobs.status, or reads the job's resource, until the report is ready.
+//
+//	go run ./examples/jobs
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperleex/zenmcp/jobs"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+type reportArgs struct {
+	Topic string `json:"topic" description:"Subject of the report." validate:"nonzero"`
+	// Seconds stands in for real work.
+	Seconds int `json:"seconds,omitempty" description:"How long the report takes to compile." default:"2"`
+}
+
+func report(ctx *runtime.Context, args reportArgs) (*protocol.ToolCallResult, error) {
+	if args.Seconds == 0 {
+		args.Seconds = 2
+	}
+	select {
+	case <-time.After(time.Duration(args.Seconds) * time.Second):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	text := fmt.Sprintf("Report on %s: all systems nominal.", args.Topic)
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(text)}}, nil
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, *jobs.Manager, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("jobs-example")}, opts...)...)
+	m, err := jobs.Install(s, jobs.Config{Store: store.NewMemoryStore(), Workers: 2})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "report",
+		Description: "Compile a report on a topic. Runs in the background; poll jobs.status with the returned job ID.",
+		Async:       true,
+	}, report); err != nil {
+		return nil, nil, err
+	}
+	return s, m, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s, m, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	go m.Run(ctx)
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
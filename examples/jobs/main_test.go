@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/jobs"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+func TestJobs(t *testing.T) {
+	tr := inproc.New()
+	s, m, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+	c := exampletest.Connect(t, s, tr)
+
+	// The call returns at once; the second content item holds the job.
+	text := exampletest.CallTool(t, c, "report", map[string]interface{}{"topic": "uptime", "seconds": 1})
+	var started struct {
+		JobID string `json:"jobId"`
+	}
+	if i := strings.Index(text, "\n{"); i >= 0 {
+		json.Unmarshal([]byte(text[i+1:]), &started)
+	}
+	if started.JobID == "" {
+		t.Fatalf("no job ID in %q", text)
+	}
+
+	deadline := time.Now().Add(exampletest.Timeout)
+	for {
+		var job jobs.Job
+		status := exampletest.CallTool(t, c, jobs.StatusToolName, map[string]string{"jobId": started.JobID})
+		if err := json.Unmarshal([]byte(status), &job); err != nil {
+			t.Fatal(err)
+		}
+		switch job.Status {
+		case jobs.StatusQueued, jobs.StatusRunning:
+		case jobs.StatusSucceeded:
+			if got := job.Result.Content[0].Text; !strings.Contains(got, "Report on uptime") {
+				t.Errorf("job result = %q", got)
+			}
+			return
+		default:
+			t.Fatalf("job ended %s", job.Status)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job did not finish in time")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,78 @@
+// Command progress reports how far a long tool call has come, and stops
+// it when the client gives up.
+//
+// A client that wants progress sends a progress token in the _meta of its
+// tools/call request. The index tool then calls ReportProgressMessage
+// after each step, which sends notifications/progress with that token;
+// without a token the calls do nothing. A client that stops waiting sends
+// notifications/cancelled, which cancels the handler's context: the tool
+// checks it between steps and returns early.
+//
+//	go run ./examples/progress
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+type indexArgs struct {
+	Shards int `json:"shards,omitempty" description:"Number of shards to index." default:"5"`
+	// Millis stands in for real work.
+	Millis int `json:"millis,omitempty" description:"How long each shard takes, in milliseconds." default:"200"`
+}
+
+func index(ctx *runtime.Context, args indexArgs) (*protocol.ToolCallResult, error) {
+	if args.Shards == 0 {
+		args.Shards = 5
+	}
+	if args.Millis == 0 {
+		args.Millis = 200
+	}
+	total := float64(args.Shards)
+	for i := 1; i <= args.Shards; i++ {
+		select {
+		case <-time.After(time.Duration(args.Millis) * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if err := ctx.ReportProgressMessage(float64(i), total, fmt.Sprintf("indexed shard %d of %d", i, args.Shards)); err != nil {
+			return nil, err
+		}
+	}
+	text := fmt.Sprintf("indexed %d shards", args.Shards)
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(text)}}, nil
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("progress-example")}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "index",
+		Description: "Index the shards one by one, reporting progress after each.",
+	}, index); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+// connect returns a client of a fresh server and the channel its
+// progress notifications arrive on.
+func connect(t *testing.T) (*mcp.Client, <-chan protocol.ProgressParams) {
+	t.Helper()
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := make(chan protocol.ProgressParams, 16)
+	c := exampletest.Connect(t, s, tr, mcp.WithNotificationHandler(func(method string, params json.RawMessage) {
+		var p protocol.ProgressParams
+		if method == protocol.MethodProgress && json.Unmarshal(params, &p) == nil {
+			progress <- p
+		}
+	}))
+	return c, progress
+}
+
+// callIndex calls the index tool asking for progress under token.
+func callIndex(ctx context.Context, c *mcp.Client, token string, args indexArgs) (*protocol.ToolCallResult, error) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	var res protocol.ToolCallResult
+	err = c.Call(ctx, protocol.MethodToolsCall, protocol.ToolCallParams{
+		Name:      "index",
+		Arguments: raw,
+		Meta:      &protocol.RequestMeta{ProgressToken: token},
+	}, &res)
+	return &res, err
+}
+
+func TestProgress(t *testing.T) {
+	c, progress := connect(t)
+	res, err := callIndex(exampletest.Context(t), c, "run-1", indexArgs{Shards: 3, Millis: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exampletest.Text(res); got != "indexed 3 shards" {
+		t.Errorf("index = %q", got)
+	}
+	for i := 1; i <= 3; i++ {
+		select {
+		case p := <-progress:
+			if p.ProgressToken != "run-1" || p.Progress != float64(i) || p.Total != 3 {
+				t.Errorf("progress %d = %+v", i, p)
+			}
+		case <-time.After(exampletest.Timeout):
+			t.Fatalf("progress %d never arrived", i)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	c, progress := connect(t)
+	ctx, cancel := context.WithCancel(exampletest.Context(t))
+	done := make(chan error, 1)
+	go func() {
+		_, err := callIndex(ctx, c, "run-2", indexArgs{Shards: 1000, Millis: 5})
+		done <- err
+	}()
+	// Give up once the tool is under way.
+	select {
+	case <-progress:
+	case <-time.After(exampletest.Timeout):
+		t.Fatal("no progress before cancelling")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("index after cancel: %v", err)
+	}
+	// The tool stops at the next step, so progress stops coming.
+	time.Sleep(50 * time.Millisecond)
+	for len(progress) > 0 {
+		<-progress
+	}
+	select {
+	case p := <-progress:
+		t.Errorf("progress after the call was cancelled: %+v", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,107 @@
+// Command proxying serves the tools of other MCP servers, failing over
+// between them.
+//
+// The proxy mirrors the tools its upstreams list and forwards each call
+// to one of them: to the primary while it answers, to the backup, of a
+// higher priority, once it does not. The _meta of every result names the
+// upstream that served it. To stay self-contained the example runs both
+// upstreams in process; in a real deployment their clients would come
+// from mcp.NewCommandConn or the HTTP transport's Dial.
+//
+//	go run ./examples/proxying
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/proxy"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+type forecastArgs struct {
+	City string `json:"city" description:"City to forecast." validate:"nonzero"`
+}
+
+// newUpstream returns one of the identical servers behind the proxy,
+// which says in its answers which region it runs in.
+func newUpstream(region string, opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("forecast-" + region)}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "forecast",
+		Description: "Tomorrow's weather in a city.",
+	}, func(ctx *runtime.Context, args forecastArgs) (*protocol.ToolCallResult, error) {
+		text := fmt.Sprintf("Sunny in %s (from %s)", args.City, region)
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(text)}}, nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// startUpstream serves a new upstream in process until ctx is done and
+// returns a client connected to it.
+func startUpstream(ctx context.Context, region string) (*mcp.Client, error) {
+	tr := inproc.New()
+	s, err := newUpstream(region, mcp.WithTransport(tr))
+	if err != nil {
+		return nil, err
+	}
+	go s.Serve(ctx)
+	conn, err := tr.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := mcp.NewClient(conn, mcp.WithClientInfo("proxying-example", "1.0.0"))
+	if _, err := c.Initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newServer returns the proxy server, offering the tools of upstreams.
+func newServer(ctx context.Context, upstreams []proxy.Upstream, opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("proxying-example")}, opts...)...)
+	p, err := proxy.New(upstreams, proxy.Options{})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Mirror(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	primary, err := startUpstream(ctx, "eu-west")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer primary.Close()
+	backup, err := startUpstream(ctx, "us-east")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer backup.Close()
+	s, err := newServer(ctx, []proxy.Upstream{
+		{Name: "primary", Client: primary},
+		{Name: "backup", Client: backup, Priority: 1},
+	}, mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/proxy"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+func TestFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	primary, err := startUpstream(ctx, "eu-west")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	backup, err := startUpstream(ctx, "us-east")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+
+	tr := inproc.New()
+	s, err := newServer(exampletest.Context(t), []proxy.Upstream{
+		{Name: "primary", Client: primary},
+		{Name: "backup", Client: backup, Priority: 1},
+	}, mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exampletest.Connect(t, s, tr)
+
+	forecast := func() *protocol.ToolCallResult {
+		t.Helper()
+		res, err := c.CallTool(exampletest.Context(t), "forecast", map[string]string{"city": "Lyon"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.IsError {
+			t.Fatalf("forecast failed: %s", exampletest.Text(res))
+		}
+		return res
+	}
+	res := forecast()
+	if got := exampletest.Text(res); got != "Sunny in Lyon (from eu-west)" {
+		t.Errorf("forecast = %q", got)
+	}
+	if res.Meta == nil || res.Meta.Upstream != "primary" {
+		t.Errorf("served by %+v, want primary", res.Meta)
+	}
+
+	// With the primary gone, the backup serves the call.
+	primary.Close()
+	res = forecast()
+	if got := exampletest.Text(res); got != "Sunny in Lyon (from us-east)" {
+		t.Errorf("forecast after failover = %q", got)
+	}
+	if res.Meta == nil || res.Meta.Upstream != "backup" {
+		t.Errorf("served by %+v after failover, want backup", res.Meta)
+	}
+}
@@ -0,0 +1,91 @@
+// Command sampling has a tool borrow the client's language model.
+//
+// The summarize tool has no model of its own. It sends
+// sampling/createMessage back to the client that called it, which answers
+// with a completion from whatever model the host uses, and returns that
+// completion as its result. Only clients announcing the sampling
+// capability in initialize are asked; the others get a tool error.
+//
+//	go run ./examples/sampling
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+const methodCreateMessage = "sampling/createMessage"
+
+// samplingMessage, createMessageParams and createMessageResult are the
+// parts of the sampling/createMessage request and result this example
+// uses.
+type samplingMessage struct {
+	Role    string           `json:"role"`
+	Content protocol.Content `json:"content"`
+}
+
+type createMessageParams struct {
+	Messages     []samplingMessage `json:"messages"`
+	SystemPrompt string            `json:"systemPrompt,omitempty"`
+	MaxTokens    int               `json:"maxTokens"`
+}
+
+type createMessageResult struct {
+	Role    string           `json:"role"`
+	Content protocol.Content `json:"content"`
+	Model   string           `json:"model"`
+}
+
+type summarizeArgs struct {
+	Text string `json:"text" description:"The text to summarize." validate:"nonzero"`
+}
+
+func summarize(ctx *runtime.Context, args summarizeArgs) (*protocol.ToolCallResult, error) {
+	if sess := ctx.Session(); sess == nil || sess.ClientCapabilities().Sampling == nil {
+		return &protocol.ToolCallResult{
+			Content: []protocol.Content{protocol.TextContent("the client does not support sampling")},
+			IsError: true,
+		}, nil
+	}
+	var res createMessageResult
+	err := ctx.Request(methodCreateMessage, createMessageParams{
+		Messages:     []samplingMessage{{Role: "user", Content: protocol.TextContent(args.Text)}},
+		SystemPrompt: "Summarize the user's text in one sentence.",
+		MaxTokens:    200,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{res.Content}}, nil
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("sampling-example")}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "summarize",
+		Description: "Summarize a text in one sentence, using the client's model.",
+	}, summarize); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+// model stands in for the host's language model: it answers with the
+// first sentence of the text it was given.
+func model(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var p createMessageParams
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	if p.SystemPrompt == "" || len(p.Messages) != 1 {
+		return nil, protocol.Errorf(protocol.InvalidParams, "unexpected request %s", raw)
+	}
+	first, _, _ := strings.Cut(p.Messages[0].Content.Text, ".")
+	return createMessageResult{Role: "assistant", Content: protocol.TextContent(first + "."), Model: "test"}, nil
+}
+
+func TestSummarize(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exampletest.Connect(t, s, tr,
+		mcp.WithClientCapabilities(protocol.ClientCapabilities{Sampling: &struct{}{}}),
+		mcp.WithRequestHandler(methodCreateMessage, model),
+	)
+	got := exampletest.CallTool(t, c, "summarize", map[string]string{"text": "Sampling works. The rest is detail."})
+	if got != "Sampling works." {
+		t.Errorf("summarize = %q", got)
+	}
+}
+
+func TestSummarizeWithoutSampling(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exampletest.Connect(t, s, tr)
+	res, err := c.CallTool(exampletest.Context(t), "summarize", map[string]string{"text": "Anything."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError {
+		t.Errorf("summarize without sampling = %q, want a tool error", exampletest.Text(res))
+	}
+}
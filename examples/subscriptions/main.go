@@ -0,0 +1,91 @@
+// Command subscriptions announces changes of a resource to the clients
+// that subscribed to it.
+//
+// The counter://value resource holds a count that the increment tool
+// bumps. After each change the server calls NotifyResourceUpdated, which
+// sends notifications/resources/updated to every connection subscribed to
+// the resource with resources/subscribe; those clients then read it again.
+// Only resources registered as Subscribable accept subscriptions.
+// With mcp.Client, SubscribeResource returns a channel of the updates.
+//
+//	go run ./examples/subscriptions
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+const counterURI = "counter://value"
+
+type counter struct {
+	mu    sync.Mutex
+	value int
+}
+
+type incrementArgs struct {
+	By int `json:"by,omitempty" description:"How much to add." default:"1"`
+}
+
+type counterValue struct {
+	Value int `json:"value"`
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("subscriptions-example")}, opts...)...)
+	var c counter
+	if err := mcp.RegisterResourceTyped(s, registry.ResourceDescriptor{
+		URI:         counterURI,
+		Name:        "Counter",
+		Description: "The current count. Subscribe to be told when it changes.",
+		// Without Subscribable, resources/subscribe is refused.
+		Subscribable: true,
+	}, func(ctx *runtime.Context, uri string) (counterValue, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return counterValue{Value: c.value}, nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "increment",
+		Description: "Add to the counter.",
+	}, func(ctx *runtime.Context, args incrementArgs) (*protocol.ToolCallResult, error) {
+		if args.By == 0 {
+			args.By = 1
+		}
+		c.mu.Lock()
+		c.value += args.By
+		c.mu.Unlock()
+		// Subscribers are told after the change is visible, so the read
+		// they make in response sees it.
+		if _, err := s.NotifyResourceUpdated(counterURI); err != nil {
+			return nil, err
+		}
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent("ok")}}, nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
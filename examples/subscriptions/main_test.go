@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+func TestSubscribe(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher := exampletest.Connect(t, s, tr)
+	writer := exampletest.Dial(t, tr, transport.Peer{})
+
+	ctx, cancel := context.WithCancel(exampletest.Context(t))
+	defer cancel()
+	updates, err := watcher.SubscribeResource(ctx, counterURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exampletest.CallTool(t, writer, "increment", map[string]int{"by": 2})
+	select {
+	case u := <-updates:
+		if u.URI != counterURI {
+			t.Fatalf("update for %s", u.URI)
+		}
+	case <-time.After(exampletest.Timeout):
+		t.Fatal("no update after increment")
+	}
+	if got := read(t, watcher); got != 2 {
+		t.Errorf("counter = %d after the update, want 2", got)
+	}
+
+	// Once the subscription's context ends, its channel closes.
+	cancel()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Fatal("update after the subscription ended")
+		}
+	case <-time.After(exampletest.Timeout):
+		t.Fatal("channel still open after the subscription ended")
+	}
+}
+
+func read(t *testing.T, c *mcp.Client) int {
+	t.Helper()
+	res, err := c.ReadResource(exampletest.Context(t), counterURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v counterValue
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v.Value
+}
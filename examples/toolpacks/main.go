@@ -0,0 +1,58 @@
+// Command toolpacks assembles a coding assistant's server from the
+// ready-made packs under zentool: a scratchpad memory and read-only
+// access to a git repository.
+//
+//	go run ./examples/toolpacks -repo .
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/store"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+	"github.com/hyperleex/zenmcp/zentool/git"
+	"github.com/hyperleex/zenmcp/zentool/memory"
+)
+
+func newServer(repo string, opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{
+		mcp.WithName("toolpacks-example"),
+		mcp.WithInstructions("Use memory.* to keep notes between turns and git.* to inspect the repository."),
+	}, opts...)...)
+	if _, err := memory.Install(s, memory.Config{Store: store.NewMemoryStore(), Scope: memory.ScopeSession}); err != nil {
+		return nil, err
+	}
+	if repo != "" {
+		if _, err := git.Install(s, git.Config{Repositories: map[string]string{filepath.Base(repo): repo}}); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func main() {
+	repo := flag.String("repo", "", "git repository to expose read-only; none when empty")
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *repo != "" {
+		abs, err := filepath.Abs(*repo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*repo = abs
+	}
+	s, err := newServer(*repo, mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
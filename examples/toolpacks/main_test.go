@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+	"github.com/hyperleex/zenmcp/zentool/memory"
+)
+
+func TestMemory(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer("", mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exampletest.Connect(t, s, tr)
+
+	exampletest.CallTool(t, c, memory.SetToolName, map[string]interface{}{"key": "plan", "value": []string{"read the diff", "write tests"}})
+	if got := exampletest.CallTool(t, c, memory.GetToolName, map[string]string{"key": "plan"}); !strings.Contains(got, "write tests") {
+		t.Errorf("get = %q", got)
+	}
+	if got := exampletest.CallTool(t, c, memory.ListToolName, map[string]string{}); !strings.Contains(got, "plan") {
+		t.Errorf("list = %q", got)
+	}
+
+	// Without a repository, only the memory pack is installed.
+	tools, err := c.ListTools(exampletest.Context(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools.Tools {
+		if !strings.HasPrefix(tool.Name, "memory.") {
+			t.Errorf("unexpected tool %s", tool.Name)
+		}
+	}
+}
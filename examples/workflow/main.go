@@ -0,0 +1,101 @@
+// Command workflow pauses a multi-step tool for human approval.
+//
+// The release workflow drafts release notes, then publishes them. The
+// publish step requires approval: calling release returns a run ID and
+// stops. The client application asks the user and calls workflow/approve,
+// which publishes the release, or rejects it.
+//
+//	go run ./examples/workflow
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+	"github.com/hyperleex/zenmcp/workflow"
+)
+
+type draftArgs struct {
+	Version string `json:"version" validate:"nonzero"`
+}
+
+type publishArgs struct {
+	Version string `json:"version" validate:"nonzero"`
+	Notes   string `json:"notes"`
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+func newServer(opts ...mcp.Option) (*mcp.Server, error) {
+	s := mcp.NewServer(append([]mcp.Option{mcp.WithName("workflow-example")}, opts...)...)
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "release.draft",
+		Description: "Draft the notes of a release.",
+	}, func(ctx *runtime.Context, args draftArgs) (*protocol.ToolCallResult, error) {
+		return jsonResult(map[string]string{"notes": "Version " + args.Version + ": bug fixes and improvements."})
+	}); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        "release.publish",
+		Description: "Publish a release.",
+	}, func(ctx *runtime.Context, args publishArgs) (*protocol.ToolCallResult, error) {
+		return jsonResult(map[string]string{"published": args.Version, "notes": args.Notes})
+	}); err != nil {
+		return nil, err
+	}
+	e, err := workflow.NewEngine(s, store.NewMemoryStore())
+	if err != nil {
+		return nil, err
+	}
+	err = e.Register(workflow.Workflow{
+		Name:        "release",
+		Description: "Draft and publish a release. Publishing waits for the user's approval.",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"version": map[string]interface{}{"type": "string"}},
+			"required":   []string{"version"},
+		},
+		Steps: []workflow.Step{
+			{Name: "draft", Tool: "release.draft", Args: map[string]interface{}{"version": "$.input.version"}},
+			{
+				Name:            "publish",
+				Tool:            "release.publish",
+				Args:            map[string]interface{}{"version": "$.input.version", "notes": "$.steps.draft.notes"},
+				RequireApproval: true,
+				ApprovalMessage: "Publish the release to all users.",
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s, err := newServer(mcp.WithTransport(stdio.New()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Serve(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/examples/internal/exampletest"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+	"github.com/hyperleex/zenmcp/workflow"
+)
+
+func TestRelease(t *testing.T) {
+	tr := inproc.New()
+	s, err := newServer(mcp.WithTransport(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exampletest.Connect(t, s, tr)
+
+	text := exampletest.CallTool(t, c, "release", map[string]string{"version": "1.2.0"})
+	if !strings.Contains(text, "waiting for user approval") {
+		t.Fatalf("run did not pause: %q", text)
+	}
+	var pending struct {
+		RunID string `json:"runId"`
+	}
+	if i := strings.Index(text, "\n{"); i >= 0 {
+		json.Unmarshal([]byte(text[i+1:]), &pending)
+	}
+	if pending.RunID == "" {
+		t.Fatalf("no run ID in %q", text)
+	}
+
+	// Approval may come from another session, as when the user answers
+	// after the client reconnected.
+	other := exampletest.Dial(t, tr, transport.Peer{})
+	var res protocol.ToolCallResult
+	if err := other.Call(exampletest.Context(t), workflow.MethodApprove, map[string]interface{}{"runId": pending.RunID, "approve": true}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if got := exampletest.Text(&res); !strings.Contains(got, `"published":"1.2.0"`) {
+		t.Errorf("release not published: %q", got)
+	}
+	if got := exampletest.CallTool(t, other, workflow.StatusToolName, map[string]string{"runId": pending.RunID}); !strings.Contains(got, `"status":"completed"`) {
+		t.Errorf("status = %q", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package idgen generates the identifiers zenmcp hands out for sessions,
+// jobs, workflow runs and the like.
+//
+// Components take a Generator in their configuration and fall back to
+// Default, which produces UUIDv7 strings. Tests pass NewSequential instead
+// to get predictable IDs.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperleex/zenmcp/clock"
+)
+
+// Generator produces identifiers. Every ID it returns is distinct from the
+// others it returned, and it is safe for concurrent use.
+type Generator interface {
+	NewID() string
+}
+
+// Default is the generator used when none is configured.
+var Default Generator = NewUUIDv7(nil)
+
+// Or returns g, or Default when g is nil.
+func Or(g Generator) Generator {
+	if g == nil {
+		return Default
+	}
+	return g
+}
+
+// NewUUIDv7 returns a generator of RFC 9562 version 7 UUIDs, which sort by
+// creation time. IDs generated within the same millisecond are ordered by
+// a counter, so they stay unique and sortable however fast they are
+// requested. c supplies the time and defaults to clock.Real.
+func NewUUIDv7(c clock.Clock) Generator {
+	return &uuidV7{clock: clock.Or(c)}
+}
+
+type uuidV7 struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	lastMS int64
+	seq    uint16 // 12-bit counter within lastMS
+}
+
+func (g *uuidV7) NewID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		panic("idgen: reading random bytes: " + err.Error())
+	}
+	g.mu.Lock()
+	ms := g.clock.Now().UnixMilli()
+	switch {
+	case ms > g.lastMS:
+		g.lastMS = ms
+		g.seq = uint16(u[6])<<4 | uint16(u[7])>>4 // random start, leaving room to count
+		g.seq &= 0x7ff
+	default:
+		// Same millisecond, or the clock went back: keep counting from the
+		// last ID, moving to the next millisecond when the counter is full.
+		g.seq++
+		if g.seq > 0xfff {
+			g.lastMS++
+			g.seq = 0
+		}
+	}
+	ms, seq := g.lastMS, g.seq
+	g.mu.Unlock()
+
+	u[0], u[1], u[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	u[3], u[4], u[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = 0x80 | u[8]&0x3f
+	return format(u)
+}
+
+func format(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// NewSequential returns a generator of the IDs prefix1, prefix2 and so on,
+// for tests that need to know the IDs in advance.
+func NewSequential(prefix string) Generator {
+	return &sequential{prefix: prefix}
+}
+
+type sequential struct {
+	prefix string
+	n      atomic.Int64
+}
+
+func (g *sequential) NewID() string {
+	return g.prefix + strconv.FormatInt(g.n.Add(1), 10)
+}
@@ -0,0 +1,453 @@
+// Package jobs runs long tool calls in the background so that they do not
+// hit client timeouts.
+//
+// Tools registered with ToolDescriptor.Async are not executed inline once
+// a Manager is installed. The call is recorded as a job in a store.Store
+// and answered at once with the job's ID; a pool of workers executes it.
+// Clients poll the jobs.status tool or read the job's resource,
+// zenmcp://jobs/{id}, for which notifications/resources/updated is sent
+// when the job finishes. The jobs.list, jobs.cancel and jobs.result tools
+// manage a client's jobs. Jobs that were queued or running when the
+// process stopped are executed again by the next Run; finished jobs are
+// deleted once the retention policy lets them go.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/idgen"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+	"github.com/hyperleex/zenmcp/tenant"
+)
+
+// Names of the job management tools.
+const (
+	StatusToolName = "jobs.status"
+	ListToolName   = "jobs.list"
+	CancelToolName = "jobs.cancel"
+	ResultToolName = "jobs.result"
+)
+
+// URIPrefix prefixes the resource URI of every job; URITemplate is the
+// resource template matching them.
+const (
+	URIPrefix   = "zenmcp://jobs/"
+	URITemplate = URIPrefix + "{id}"
+)
+
+// ErrJobNotFound is returned for unknown job IDs.
+var ErrJobNotFound = errors.New("jobs: job not found")
+
+// ErrJobFinished is returned when cancelling a job that has finished.
+var ErrJobFinished = errors.New("jobs: job has already finished")
+
+// keyPrefix is the store prefix holding jobs.
+const keyPrefix = "jobs/"
+
+// Status is the state of a job.
+type Status string
+
+// Job states.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Job is a tool call executed in the background.
+type Job struct {
+	ID        string          `json:"id"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Status    Status          `json:"status"`
+	// Result is the tool's result once the job has finished. Failed jobs
+	// carry the error result the tool returned, if any.
+	Result *protocol.ToolCallResult `json:"result,omitempty"`
+	// Error describes why a failed job failed.
+	Error string `json:"error,omitempty"`
+	// Tenant and Owner restrict access to the tenant and principal subject
+	// that submitted the job.
+	Tenant string `json:"tenant,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+	// Connection is the connection the job was submitted on, notified when
+	// the job finishes.
+	Connection string     `json:"connection,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// URI returns the resource URI of the job.
+func (j *Job) URI() string { return URIPrefix + j.ID }
+
+// Done reports whether the job has finished.
+func (j *Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// Config configures a Manager.
+type Config struct {
+	// Store persists jobs. Required.
+	Store store.Store
+	// Workers is the number of jobs executed concurrently. Defaults to 4.
+	Workers int
+	// QueueSize bounds the jobs waiting for a worker. Calls beyond it fail
+	// with ServerOverloaded. Defaults to 1024.
+	QueueSize int
+	// Tenants lets jobs submitted by tenants be resumed after a restart.
+	// Pass the set given to mcp.WithTenants, if any.
+	Tenants *tenant.Set
+	// Retention is how long finished jobs and their results are kept.
+	// Defaults to 24 hours; a negative value keeps them until
+	// MaxFinished evicts them.
+	Retention time.Duration
+	// MaxFinished bounds the number of finished jobs kept, evicting the
+	// oldest first. Zero means no bound.
+	MaxFinished int
+	// PruneInterval is how often Run applies the retention policy.
+	// Defaults to one minute.
+	PruneInterval time.Duration
+	// Logger receives job diagnostics. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Clock timestamps jobs and schedules pruning. Defaults to the
+	// server's clock.
+	Clock clock.Clock
+	// IDs generates job IDs. Defaults to the server's ID generator.
+	IDs idgen.Generator
+}
+
+// Manager executes the async tools of a server.
+type Manager struct {
+	server *mcp.Server
+	cfg    Config
+	queue  chan *task
+
+	mu        sync.Mutex
+	active    map[string]context.CancelFunc // queued or running; nil until started
+	cancelled map[string]bool               // active jobs cancelled by a client
+}
+
+// task is a job waiting for a worker. Jobs submitted since the process
+// started run through the rest of the interceptor chain; resumed jobs
+// have no next and call the tool directly.
+type task struct {
+	job  *Job
+	ctx  *runtime.Context
+	next registry.ToolHandler
+}
+
+type inJobKey struct{}
+
+// Install makes the async tools of s run as jobs and registers the job
+// management tools and resource template. Call Run to start the workers. Interceptors added
+// after Install run inside the job.
+func Install(s *mcp.Server, cfg Config) (*Manager, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("jobs: a store is required")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.Retention == 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+	if cfg.PruneInterval <= 0 {
+		cfg.PruneInterval = time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = s.Clock()
+	}
+	if cfg.IDs == nil {
+		cfg.IDs = s.IDGenerator()
+	}
+	m := &Manager{
+		server:    s,
+		cfg:       cfg,
+		queue:     make(chan *task, cfg.QueueSize),
+		active:    make(map[string]context.CancelFunc),
+		cancelled: make(map[string]bool),
+	}
+	s.Router().InterceptToolCalls(m.intercept)
+	if err := m.register(s); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run executes jobs until ctx is done, starting with those left queued or
+// running by a previous process, and periodically prunes finished jobs.
+// Jobs interrupted by ctx are queued again for the next Run.
+func (m *Manager) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.pruneLoop(ctx)
+	}()
+	for i := 0; i < m.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+	if err := m.resume(ctx); err != nil && ctx.Err() == nil {
+		m.cfg.Logger.Warn("jobs: resuming interrupted jobs", "error", err)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	data, err := m.cfg.Store.Get(ctx, jobKey(id))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var j Job
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("jobs: job %s: %w", id, err)
+	}
+	return &j, nil
+}
+
+func (m *Manager) intercept(ctx *runtime.Context, tool *registry.ToolDescriptor, args json.RawMessage, next registry.ToolHandler) (*protocol.ToolCallResult, error) {
+	if !tool.Async || ctx.Value(inJobKey{}) != nil {
+		return next(ctx, args)
+	}
+	id := m.cfg.IDs.NewID()
+	job := &Job{
+		ID:         id,
+		Tool:       tool.Key(),
+		Arguments:  args,
+		Status:     StatusQueued,
+		Tenant:     ctx.Tenant(),
+		Connection: mcp.ConnectionID(ctx),
+		CreatedAt:  m.cfg.Clock.Now(),
+	}
+	if p := ctx.Principal(); p != nil {
+		job.Owner = p.Subject
+	}
+	if err := m.save(ctx, job); err != nil {
+		return nil, err
+	}
+	res, err := accepted(job)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.active[id] = nil
+	m.mu.Unlock()
+	select {
+	case m.queue <- &task{job: job, ctx: ctx.Detach(), next: next}:
+	default:
+		m.forget(id)
+		m.cfg.Store.Delete(ctx, jobKey(id))
+		return nil, protocol.NewError(protocol.ServerOverloaded, "job queue is full", nil)
+	}
+	ctx.Logger().Info("job queued", "job", id, "tool", job.Tool)
+	return res, nil
+}
+
+// accepted is the immediate result of an async call.
+func accepted(job *Job) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"jobId":    job.ID,
+		"status":   job.Status,
+		"resource": job.URI(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{
+		protocol.TextContent(fmt.Sprintf("Started job %s. Call %s with this job ID to get its result.", job.ID, StatusToolName)),
+		protocol.TextContent(string(data)),
+	}}, nil
+}
+
+// resume queues the jobs a previous process left unfinished.
+func (m *Manager) resume(ctx context.Context) error {
+	keys, err := m.cfg.Store.List(ctx, keyPrefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		job, err := m.Get(ctx, strings.TrimPrefix(k, keyPrefix))
+		if err != nil {
+			m.cfg.Logger.Warn("jobs: skipping unreadable job", "key", k, "error", err)
+			continue
+		}
+		if job.Done() {
+			continue
+		}
+		m.mu.Lock()
+		_, known := m.active[job.ID]
+		if !known {
+			m.active[job.ID] = nil
+		}
+		m.mu.Unlock()
+		if known {
+			continue
+		}
+		select {
+		case m.queue <- &task{job: job}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-m.queue:
+			m.execute(ctx, t)
+		}
+	}
+}
+
+// execute runs one job and records its outcome.
+func (m *Manager) execute(ctx context.Context, t *task) {
+	job := t.job
+	defer m.forget(job.ID)
+	var jctx context.Context
+	var cancel context.CancelFunc
+	if t.ctx != nil {
+		jctx, cancel = t.ctx, t.ctx.Cancel
+	} else {
+		jctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	jctx = context.WithValue(jctx, inJobKey{}, job.ID)
+	m.mu.Lock()
+	if m.cancelled[job.ID] {
+		m.mu.Unlock()
+		return
+	}
+	m.active[job.ID] = cancel
+	m.mu.Unlock()
+
+	started := m.cfg.Clock.Now()
+	job.Status, job.StartedAt = StatusRunning, &started
+	if err := m.save(ctx, job); err != nil {
+		m.cfg.Logger.Warn("jobs: saving job", "job", job.ID, "error", err)
+	}
+	res, err := m.call(jctx, t, job)
+	m.mu.Lock()
+	cancelled := m.cancelled[job.ID]
+	m.mu.Unlock()
+	if !cancelled && ctx.Err() != nil {
+		// Shutting down: leave the job for the next Run.
+		job.Status, job.StartedAt = StatusQueued, nil
+		if err := m.save(context.WithoutCancel(ctx), job); err != nil {
+			m.cfg.Logger.Warn("jobs: saving interrupted job", "job", job.ID, "error", err)
+		}
+		return
+	}
+
+	finished := m.cfg.Clock.Now()
+	job.FinishedAt = &finished
+	job.Status = StatusSucceeded
+	var rpcErr *protocol.Error
+	switch {
+	case cancelled:
+		job.Status, job.Error = StatusCancelled, "cancelled by the client"
+	case errors.As(err, &rpcErr):
+		job.Status, job.Error = StatusFailed, rpcErr.Message
+	case err != nil:
+		job.Status, job.Error = StatusFailed, err.Error()
+		job.Result = &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(err.Error())}, IsError: true}
+	case res != nil && res.IsError:
+		job.Status, job.Result = StatusFailed, res
+	default:
+		job.Result = res
+	}
+	if err := m.save(ctx, job); err != nil {
+		m.cfg.Logger.Warn("jobs: saving job", "job", job.ID, "error", err)
+		return
+	}
+	m.cfg.Logger.Info("job finished", "job", job.ID, "tool", job.Tool,
+		"status", job.Status, "duration", finished.Sub(started))
+	if job.Connection != "" && m.server.Connected(job.Connection) {
+		m.server.Notify(job.Connection, protocol.MethodResourceUpdated, protocol.ResourceUpdatedParams{URI: job.URI()})
+	}
+}
+
+// call runs the job's tool. Resumed jobs look the tool up in the registry
+// of their tenant; their submission already passed the interceptors. A
+// panicking tool fails the job instead of the worker.
+func (m *Manager) call(ctx context.Context, t *task, job *Job) (res *protocol.ToolCallResult, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			m.cfg.Logger.Error("jobs: tool panicked", "job", job.ID, "tool", job.Tool, "panic", v, "stack", string(debug.Stack()))
+			res, err = nil, fmt.Errorf("tool %q panicked: %v", job.Tool, v)
+		}
+	}()
+	if t.next != nil {
+		return t.next(ctx, job.Arguments)
+	}
+	reg := m.server.Registry()
+	if job.Tenant != "" {
+		var ok bool
+		if m.cfg.Tenants != nil {
+			reg, ok = m.cfg.Tenants.Registry(job.Tenant)
+		}
+		if !ok {
+			return nil, fmt.Errorf("tenant %q is not served", job.Tenant)
+		}
+	}
+	tool, ok := reg.Tool(job.Tool)
+	if !ok {
+		return nil, fmt.Errorf("tool %q is no longer registered", job.Tool)
+	}
+	if err := reg.EnsureToolInit(ctx, tool.Key()); err != nil {
+		return nil, err
+	}
+	return tool.Handler(ctx, job.Arguments)
+}
+
+func (m *Manager) forget(id string) {
+	m.mu.Lock()
+	delete(m.active, id)
+	delete(m.cancelled, id)
+	m.mu.Unlock()
+}
+
+func (m *Manager) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return m.cfg.Store.Put(ctx, jobKey(job.ID), data)
+}
+
+func jobKey(id string) string { return keyPrefix + id }
@@ -0,0 +1,249 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// register adds the job management tools and resource template to s.
+func (m *Manager) register(s *mcp.Server) error {
+	meta := []string{"meta"}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        StatusToolName,
+		Description: "Report the status of a background job and, once it has finished, its result.",
+		Tags:        meta,
+	}, m.statusTool); err != nil {
+		return err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ListToolName,
+		Description: "List your background jobs, newest first, optionally filtered by status.",
+		Tags:        meta,
+	}, m.listTool); err != nil {
+		return err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        CancelToolName,
+		Description: "Cancel a queued or running background job.",
+		Tags:        meta,
+	}, m.cancelTool); err != nil {
+		return err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ResultToolName,
+		Description: "Return the result of a finished background job as if the tool had been called directly.",
+		Tags:        meta,
+	}, m.resultTool); err != nil {
+		return err
+	}
+	return s.Registry().RegisterResourceTemplate(URITemplate, registry.ResourceTemplateDescriptor{
+		Name:        "job",
+		Description: "A background job: its status and, once finished, its result.",
+		MimeType:    "application/json",
+		Handler:     m.readJob,
+	})
+}
+
+// List returns the jobs in the store, newest first.
+func (m *Manager) List(ctx context.Context) ([]*Job, error) {
+	keys, err := m.cfg.Store.List(ctx, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(keys))
+	for _, k := range keys {
+		job, err := m.Get(ctx, strings.TrimPrefix(k, keyPrefix))
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+// Cancel stops the job with the given ID. A queued job is cancelled at
+// once; a running one when its tool returns after its context is
+// cancelled.
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.Done() {
+		return fmt.Errorf("%w: %s is %s", ErrJobFinished, id, job.Status)
+	}
+	m.mu.Lock()
+	cancel, active := m.active[id]
+	if active {
+		m.cancelled[id] = true
+	}
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		return nil
+	}
+	now := m.cfg.Clock.Now()
+	job.Status, job.Error, job.FinishedAt = StatusCancelled, "cancelled by the client", &now
+	return m.save(ctx, job)
+}
+
+// Prune deletes the finished jobs the retention policy no longer keeps
+// and returns how many it deleted.
+func (m *Manager) Prune(ctx context.Context) (int, error) {
+	jobs, err := m.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := m.cfg.Clock.Now().Add(-m.cfg.Retention)
+	kept, n := 0, 0
+	for _, job := range jobs {
+		if !job.Done() {
+			continue
+		}
+		expired := m.cfg.Retention > 0 && job.FinishedAt != nil && job.FinishedAt.Before(cutoff)
+		if !expired && (m.cfg.MaxFinished <= 0 || kept < m.cfg.MaxFinished) {
+			kept++
+			continue
+		}
+		if err := m.cfg.Store.Delete(ctx, jobKey(job.ID)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *Manager) pruneLoop(ctx context.Context) {
+	ticker := m.cfg.Clock.NewTicker(m.cfg.PruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		if n, err := m.Prune(ctx); err != nil && ctx.Err() == nil {
+			m.cfg.Logger.Warn("jobs: pruning finished jobs", "error", err)
+		} else if n > 0 {
+			m.cfg.Logger.Debug("jobs: pruned finished jobs", "count", n)
+		}
+	}
+}
+
+// lookup returns the job with the given ID if the caller may see it.
+// Jobs of other tenants and principals are reported as not found.
+func (m *Manager) lookup(ctx *runtime.Context, id string) (*Job, error) {
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !visible(ctx, job) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job, nil
+}
+
+// visible reports whether the caller submitted job.
+func visible(ctx *runtime.Context, job *Job) bool {
+	owner := ""
+	if p := ctx.Principal(); p != nil {
+		owner = p.Subject
+	}
+	return job.Tenant == ctx.Tenant() && job.Owner == owner
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+type jobArgs struct {
+	JobID string `json:"jobId" description:"ID of the job returned by the async tool call." validate:"nonzero"`
+}
+
+func (m *Manager) statusTool(ctx *runtime.Context, args jobArgs) (*protocol.ToolCallResult, error) {
+	job, err := m.lookup(ctx, args.JobID)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(job)
+}
+
+type listArgs struct {
+	Status Status `json:"status,omitempty" description:"Only list jobs in this status: queued, running, succeeded, failed or cancelled."`
+	Limit  int    `json:"limit" description:"Maximum number of jobs to return." default:"50"`
+}
+
+func (m *Manager) listTool(ctx *runtime.Context, args listArgs) (*protocol.ToolCallResult, error) {
+	jobs, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := []Job{}
+	for _, job := range jobs {
+		if !visible(ctx, job) || args.Status != "" && job.Status != args.Status {
+			continue
+		}
+		if args.Limit > 0 && len(out) == args.Limit {
+			break
+		}
+		summary := *job
+		summary.Arguments, summary.Result = nil, nil
+		out = append(out, summary)
+	}
+	return jsonResult(map[string]interface{}{"jobs": out})
+}
+
+func (m *Manager) cancelTool(ctx *runtime.Context, args jobArgs) (*protocol.ToolCallResult, error) {
+	if _, err := m.lookup(ctx, args.JobID); err != nil {
+		return nil, err
+	}
+	if err := m.Cancel(ctx, args.JobID); err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{
+		protocol.TextContent(fmt.Sprintf("Cancellation of job %s requested.", args.JobID)),
+	}}, nil
+}
+
+func (m *Manager) resultTool(ctx *runtime.Context, args jobArgs) (*protocol.ToolCallResult, error) {
+	job, err := m.lookup(ctx, args.JobID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !job.Done():
+		return nil, fmt.Errorf("job %s is still %s; try again later", job.ID, job.Status)
+	case job.Result != nil:
+		return job.Result, nil
+	case job.Error != "":
+		return nil, fmt.Errorf("job %s %s: %s", job.ID, job.Status, job.Error)
+	}
+	return &protocol.ToolCallResult{}, nil
+}
+
+func (m *Manager) readJob(ctx context.Context, uri string, vars map[string]string) (*protocol.ReadResourceResult, error) {
+	job, err := m.lookup(runtime.FromContext(ctx), vars["id"])
+	if err != nil {
+		return nil, protocol.NewError(protocol.ResourceNotFound, "resource not found", map[string]string{"uri": uri})
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ReadResourceResult{
+		Contents: []protocol.ResourceContents{{URI: uri, MimeType: "application/json", Text: string(data)}},
+	}, nil
+}
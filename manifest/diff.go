@@ -0,0 +1,72 @@
+package manifest
+
+import (
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/schema"
+)
+
+// Change is a difference between two manifests.
+type Change struct {
+	Tool string
+	// Path locates changes to the tool's input schema, as in
+	// schema.Change. It is empty for changes to the tool itself.
+	Path string
+	// Breaking reports whether callers of the old manifest's tools may
+	// fail against the new one.
+	Breaking bool
+	Message  string
+}
+
+func (c Change) String() string {
+	if c.Path == "" {
+		return c.Tool + ": " + c.Message
+	}
+	return c.Tool + ": " + c.Path + ": " + c.Message
+}
+
+// Diff returns the changes from old to new: removed tools, which break
+// their callers, added and newly deprecated tools, and the differences
+// between the input schemas of tools in both, as reported by schema.Diff.
+// Tools are matched by name; the changes of old's tools come first, in
+// their order, followed by the tools new adds.
+func Diff(old, new *Manifest) []Change {
+	var changes []Change
+	for _, ot := range old.Tools {
+		nt, ok := new.Tool(ot.Name)
+		if !ok {
+			changes = append(changes, Change{Tool: ot.Name, Breaking: true, Message: "tool removed"})
+			continue
+		}
+		if deprecated(nt) && !deprecated(ot) {
+			msg := "tool deprecated"
+			if m := nt.Annotations.DeprecationMessage; m != "" {
+				msg += ": " + m
+			}
+			changes = append(changes, Change{Tool: ot.Name, Message: msg})
+		}
+		for _, sc := range schema.Diff(ot.InputSchema, nt.InputSchema) {
+			changes = append(changes, Change{Tool: ot.Name, Path: sc.Path, Breaking: sc.Breaking, Message: sc.Message})
+		}
+	}
+	for _, nt := range new.Tools {
+		if _, ok := old.Tool(nt.Name); !ok {
+			changes = append(changes, Change{Tool: nt.Name, Message: "tool added"})
+		}
+	}
+	return changes
+}
+
+// Breaking returns the breaking changes among changes.
+func Breaking(changes []Change) []Change {
+	var out []Change
+	for _, c := range changes {
+		if c.Breaking {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func deprecated(t protocol.Tool) bool {
+	return t.Annotations != nil && t.Annotations.Deprecated
+}
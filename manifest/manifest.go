@@ -0,0 +1,88 @@
+// Package manifest exports the tools of a server as a manifest file and
+// compares two manifests, reporting the changes that break existing
+// callers. Release checks keep the manifest of the last release and diff
+// the current one against it:
+//
+//	m := manifest.FromRegistry(s.Registry())
+//	m.Write(f)
+//
+//	zenmcp diff released.json current.json
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+// Manifest lists the tools of a server. It has the shape of a tools/list
+// result, so a saved tools/list response can be used as a manifest.
+type Manifest struct {
+	Tools []protocol.Tool `json:"tools"`
+}
+
+// FromRegistry returns the manifest of the tools in r. Versioned tools
+// are listed under "name@version".
+func FromRegistry(r *registry.Registry) *Manifest {
+	m := &Manifest{Tools: []protocol.Tool{}}
+	for _, d := range r.Tools() {
+		t := d.Tool()
+		t.Name = d.Key()
+		m.Tools = append(m.Tools, t)
+	}
+	return m
+}
+
+// Read decodes a manifest from r.
+func Read(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	seen := make(map[string]bool, len(m.Tools))
+	for _, t := range m.Tools {
+		if t.Name == "" {
+			return nil, fmt.Errorf("manifest: tool without a name")
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("manifest: tool %q listed twice", t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return &m, nil
+}
+
+// Load reads the manifest in the named file.
+func Load(name string) (*Manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// Write encodes m to w as indented JSON.
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Tool returns the tool listed under name.
+func (m *Manifest) Tool(name string) (protocol.Tool, bool) {
+	for _, t := range m.Tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return protocol.Tool{}, false
+}
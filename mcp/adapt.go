@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// MapToolHandler handles a tool call with its arguments decoded into a
+// map, for tools whose arguments have no fixed shape.
+type MapToolHandler func(ctx *runtime.Context, args map[string]interface{}) (*protocol.ToolCallResult, error)
+
+// ContextMapToolHandler handles a tool call with its arguments decoded
+// into a map, receiving a plain context.Context.
+//
+// Deprecated: Use MapToolHandler, whose handlers get the
+// *runtime.Context without looking it up.
+type ContextMapToolHandler func(ctx context.Context, args map[string]interface{}) (*protocol.ToolCallResult, error)
+
+// UntypedToolHandler handles a tool call with its arguments decoded into a
+// map and returns an untyped result, converted as AdaptTool describes.
+//
+// Deprecated: Use MapToolHandler, or RegisterToolTyped for arguments with
+// a fixed shape.
+type UntypedToolHandler func(ctx interface{}, args map[string]interface{}) (interface{}, error)
+
+// UntypedRawToolHandler handles a tool call with its raw arguments and
+// returns an untyped result, converted as AdaptTool describes.
+//
+// Deprecated: Use registry.ToolHandler.
+type UntypedRawToolHandler func(ctx interface{}, args json.RawMessage) (interface{}, error)
+
+// AdaptTool converts a tool handler written in one of the older styles
+// into a registry.ToolHandler, the form every tool is registered in.
+// Accepted signatures are:
+//
+//	registry.ToolHandler, or the equivalent func literal
+//	MapToolHandler, or the equivalent func literal
+//	ContextMapToolHandler, or the equivalent func literal (deprecated)
+//	UntypedToolHandler, or the equivalent func literal (deprecated)
+//	UntypedRawToolHandler, or the equivalent func literal (deprecated)
+//
+// Handlers taking ctx as interface{} receive the *runtime.Context. Their
+// results are converted as follows: a *protocol.ToolCallResult is used as
+// is, a string becomes a text item, []protocol.Content becomes the
+// content, nil becomes an empty result and any other value is encoded as
+// JSON text. Typed handlers are converted with AdaptTyped.
+func AdaptTool(h interface{}) (registry.ToolHandler, error) {
+	handler, err := adaptTool(h)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: %w", err)
+	}
+	return handler, nil
+}
+
+func adaptTool(h interface{}) (registry.ToolHandler, error) {
+	switch h := h.(type) {
+	case registry.ToolHandler:
+		return h, nil
+	case func(context.Context, json.RawMessage) (*protocol.ToolCallResult, error):
+		return h, nil
+	case MapToolHandler:
+		return adaptMap(h), nil
+	case func(*runtime.Context, map[string]interface{}) (*protocol.ToolCallResult, error):
+		return adaptMap(h), nil
+	case ContextMapToolHandler:
+		return adaptContextMap(h), nil
+	case func(context.Context, map[string]interface{}) (*protocol.ToolCallResult, error):
+		return adaptContextMap(h), nil
+	case UntypedToolHandler:
+		return adaptUntyped(h), nil
+	case func(interface{}, map[string]interface{}) (interface{}, error):
+		return adaptUntyped(h), nil
+	case UntypedRawToolHandler:
+		return adaptUntypedRaw(h), nil
+	case func(interface{}, json.RawMessage) (interface{}, error):
+		return adaptUntypedRaw(h), nil
+	case nil:
+		return nil, errors.New("nil tool handler")
+	default:
+		return nil, fmt.Errorf("unsupported tool handler type %T", h)
+	}
+}
+
+// RegisterToolFunc registers a tool implemented by a handler in any of the
+// forms accepted by AdaptTool. desc.InputSchema defaults to one accepting
+// any object.
+func RegisterToolFunc(s *Server, desc registry.ToolDescriptor, h interface{}) error {
+	handler, err := adaptTool(h)
+	if err != nil {
+		return fmt.Errorf("mcp: tool %q: %w", desc.Name, err)
+	}
+	if desc.InputSchema == nil {
+		desc.InputSchema = map[string]interface{}{"type": "object"}
+	}
+	desc.Handler = handler
+	return s.registry.RegisterTool(desc.Name, desc)
+}
+
+// RegisterTool registers the tool name on s, as RegisterToolFunc does for
+// desc named name. h is a handler in any of the forms accepted by
+// AdaptTool; it may be nil when desc.Handler is set.
+func (s *Server) RegisterTool(name string, desc registry.ToolDescriptor, h interface{}) error {
+	desc.Name = name
+	if h == nil && desc.Handler != nil {
+		h = desc.Handler
+	}
+	return RegisterToolFunc(s, desc, h)
+}
+
+func adaptMap(h MapToolHandler) registry.ToolHandler {
+	return func(ctx context.Context, raw json.RawMessage) (*protocol.ToolCallResult, error) {
+		rc := runtime.FromContext(ctx)
+		args := map[string]interface{}{}
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := runtime.DecodeJSON(raw, &args, rc.UseNumber()); err != nil {
+				return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+			}
+		}
+		return h(rc, args)
+	}
+}
+
+func adaptContextMap(h ContextMapToolHandler) registry.ToolHandler {
+	return adaptMap(func(ctx *runtime.Context, args map[string]interface{}) (*protocol.ToolCallResult, error) {
+		return h(ctx, args)
+	})
+}
+
+func adaptUntyped(h UntypedToolHandler) registry.ToolHandler {
+	return adaptMap(func(ctx *runtime.Context, args map[string]interface{}) (*protocol.ToolCallResult, error) {
+		v, err := h(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return toolResult(v)
+	})
+}
+
+func adaptUntypedRaw(h UntypedRawToolHandler) registry.ToolHandler {
+	return func(ctx context.Context, args json.RawMessage) (*protocol.ToolCallResult, error) {
+		v, err := h(runtime.FromContext(ctx), args)
+		if err != nil {
+			return nil, err
+		}
+		return toolResult(v)
+	}
+}
+
+// toolResult converts the value returned by an untyped handler into a
+// tool call result.
+func toolResult(v interface{}) (*protocol.ToolCallResult, error) {
+	switch v := v.(type) {
+	case nil:
+		return &protocol.ToolCallResult{Content: []protocol.Content{}}, nil
+	case *protocol.ToolCallResult:
+		return v, nil
+	case protocol.ToolCallResult:
+		return &v, nil
+	case string:
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(v)}}, nil
+	case []protocol.Content:
+		return &protocol.ToolCallResult{Content: v}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode tool result: %w", err)
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
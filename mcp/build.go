@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// BuildResourceURI is the resource describing the server binary, served
+// when the server is created with WithBuildInfoResource.
+const BuildResourceURI = "zenmcp://server/build"
+
+// BuildInfo identifies the binary a server runs in, as recorded by the Go
+// toolchain. Fields the toolchain did not record are empty: VCS details are
+// only stamped into binaries built from a checkout with "go build".
+type BuildInfo struct {
+	// Path is the import path of the main package.
+	Path string `json:"path,omitempty"`
+	// Module and ModuleVersion identify the main module. The version is
+	// "(devel)" for binaries built from a local checkout.
+	Module        string `json:"module,omitempty"`
+	ModuleVersion string `json:"moduleVersion,omitempty"`
+	// Commit, CommitTime and Modified describe the checkout the binary was
+	// built from; Modified reports uncommitted changes.
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"goVersion,omitempty"`
+	// ZenMCPVersion is the version of zenmcp linked into the binary.
+	ZenMCPVersion string `json:"zenmcpVersion,omitempty"`
+}
+
+const zenmcpModule = "github.com/hyperleex/zenmcp"
+
+// ReadBuildInfo returns the build information of the running binary.
+func ReadBuildInfo() BuildInfo { return readBuildInfo() }
+
+var readBuildInfo = sync.OnceValue(func() BuildInfo {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{}
+	}
+	info := BuildInfo{
+		Path:          bi.Path,
+		Module:        bi.Main.Path,
+		ModuleVersion: bi.Main.Version,
+		GoVersion:     bi.GoVersion,
+	}
+	if bi.Main.Path == zenmcpModule {
+		info.ZenMCPVersion = bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == zenmcpModule {
+			info.ZenMCPVersion = dep.Version
+			if dep.Replace != nil {
+				info.ZenMCPVersion = dep.Replace.Version
+			}
+		}
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.CommitTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+})
+
+// Version returns a short version for the binary: the module version for
+// released builds, the abbreviated commit otherwise, suffixed "-dirty" for
+// modified checkouts, or "dev" when neither is known.
+func (b BuildInfo) Version() string {
+	if b.ModuleVersion != "" && b.ModuleVersion != "(devel)" {
+		return b.ModuleVersion
+	}
+	if b.Commit == "" {
+		return "dev"
+	}
+	v := b.Commit
+	if len(v) > 12 {
+		v = v[:12]
+	}
+	if b.Modified {
+		v += "-dirty"
+	}
+	return v
+}
+
+// BuildInfo returns the build information of the binary s runs in.
+func (s *Server) BuildInfo() BuildInfo { return ReadBuildInfo() }
+
+// WithBuildInfoResource serves the server's BuildInfo as the
+// zenmcp://server/build resource.
+func WithBuildInfoResource() Option {
+	return func(s *Server) { s.buildResource = true }
+}
+
+func (s *Server) registerBuildResource() error {
+	return RegisterResourceTyped(s, registry.ResourceDescriptor{
+		URI:         BuildResourceURI,
+		Name:        "server build",
+		Description: "The version, commit and Go toolchain of the running server.",
+	}, func(ctx *runtime.Context, uri string) (BuildInfo, error) {
+		return s.BuildInfo(), nil
+	})
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// readResult is a message read from a connection, with its size in bytes
+// and the time it was read, or the error reading it.
+type readResult struct {
+	msg  *protocol.Message
+	size int64
+	at   time.Time
+	err  error
+}
+
+// readMessages reads from the connection of st in the background, so that
+// notifications/cancelled takes effect while a request is being handled.
+// Cancellations are acted on as they arrive; every other message, and the
+// error that ends reading, is delivered in order on the returned channel.
+// A *protocol.Error does not end reading: the connection stays usable
+// after a malformed frame. Reading stops when stop is closed.
+func (s *Server) readMessages(ctx context.Context, st *connState, stop <-chan struct{}) <-chan readResult {
+	out := make(chan readResult)
+	bytesRead := s.metrics.bytesRead.With(st.peer.Transport)
+	go func() {
+		for {
+			msg, err := st.conn.Read(ctx)
+			var in readResult
+			if err == nil {
+				now := s.clock.Now()
+				st.touch(now)
+				in = readResult{msg: msg, size: st.countRead(), at: now}
+				st.transport.messages.Add(1)
+				bytesRead.Add(float64(in.size))
+				if msg.Method == protocol.MethodCancelled && msg.IsNotification() {
+					s.cancelRequest(st, msg.Params)
+					continue
+				}
+				if msg.IsResponse() && st.answered(msg) {
+					continue
+				}
+			} else {
+				in.err = err
+				if errors.As(err, new(*protocol.Error)) {
+					st.transport.frameError()
+				}
+			}
+			select {
+			case out <- in:
+			case <-stop:
+				return
+			}
+			var rpcErr *protocol.Error
+			if err != nil && !errors.As(err, &rpcErr) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// beginRequest returns the context to handle msg in, registering requests
+// so that a cancellation naming their ID cancels it. The returned function
+// must be called once the request is handled.
+func (st *connState) beginRequest(ctx context.Context, msg *protocol.Message) (context.Context, func()) {
+	if !msg.IsRequest() {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	key := msg.ID.String()
+	st.cancelMu.Lock()
+	if st.cancels == nil {
+		st.cancels = make(map[string]context.CancelFunc)
+	}
+	st.cancels[key] = cancel
+	st.cancelMu.Unlock()
+	return ctx, func() {
+		st.cancelMu.Lock()
+		delete(st.cancels, key)
+		st.cancelMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelRequest cancels the context of the request named by a
+// notifications/cancelled. Cancellations of requests that already
+// finished, or were never made, are ignored as the protocol requires.
+func (s *Server) cancelRequest(st *connState, params json.RawMessage) {
+	var p protocol.CancelledParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		s.logger.Debug("ignoring malformed cancellation", "connection", st.id, "error", err)
+		return
+	}
+	key := p.RequestID.String()
+	st.cancelMu.Lock()
+	cancel := st.cancels[key]
+	st.cancelMu.Unlock()
+	if cancel == nil {
+		return
+	}
+	s.logger.Debug("request cancelled by client", "connection", st.id, "request", key, "reason", p.Reason)
+	s.metrics.cancelledRequests.With(st.peer.Transport).Inc()
+	cancel()
+}
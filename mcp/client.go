@@ -0,0 +1,597 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ErrClientClosed is returned by client calls once the client has been
+// closed or its connection has ended.
+var ErrClientClosed = errors.New("mcp: client closed")
+
+// ErrHandshakeTimeout is matched, with errors.Is, by the error Initialize
+// returns when its context's deadline passes before the handshake
+// completes. The error also matches context.DeadlineExceeded.
+var ErrHandshakeTimeout = errors.New("mcp: handshake timed out")
+
+// GoodbyeError is what Err returns once a server that said goodbye, with
+// notifications/zenmcp/goodbye, closed the connection: the connection was
+// closed on purpose, for instance because the server was shutting down,
+// rather than lost. It matches ErrClientClosed with errors.Is.
+type GoodbyeError struct {
+	Params protocol.GoodbyeParams
+}
+
+func (e *GoodbyeError) Error() string {
+	msg := "mcp: server closed the connection: " + e.Params.Reason
+	if e.Params.Message != "" {
+		msg += ": " + e.Params.Message
+	}
+	return msg
+}
+
+func (e *GoodbyeError) Unwrap() error { return ErrClientClosed }
+
+// ClientConn is the client's end of a message stream to a server.
+// NewStreamConn, NewCommandConn and the HTTP transport's Dial provide ones
+// for the usual ways of reaching a server.
+type ClientConn interface {
+	// Read returns the next message from the server, or io.EOF when the
+	// server has finished sending.
+	Read(ctx context.Context) (*protocol.Message, error)
+	// Write sends a message to the server. It may be called from several
+	// goroutines at once.
+	Write(ctx context.Context, msg *protocol.Message) error
+	// Close releases the connection.
+	Close() error
+}
+
+// NotificationHandler receives the notifications sent by a server. It is
+// called from the client's read loop and must not block.
+type NotificationHandler func(method string, params json.RawMessage)
+
+// ClientRequestHandler answers a request sent by the server, such as
+// roots/list or sampling/createMessage.
+type ClientRequestHandler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithClientInfo sets the client name and version sent in initialize.
+func WithClientInfo(name, version string) ClientOption {
+	return func(c *Client) { c.info = protocol.Implementation{Name: name, Version: version} }
+}
+
+// WithClientCapabilities sets the capabilities announced in initialize.
+func WithClientCapabilities(caps protocol.ClientCapabilities) ClientOption {
+	return func(c *Client) { c.capabilities = caps }
+}
+
+// WithClientLogger sets the logger reporting malformed or unexpected
+// messages. It defaults to slog.Default().
+func WithClientLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithNotificationHandler installs h to receive server notifications.
+// Without one they are dropped.
+func WithNotificationHandler(h NotificationHandler) ClientOption {
+	return func(c *Client) { c.onNotification = h }
+}
+
+// WithRequestHandler answers server requests for method with h. Requests
+// for methods without a handler, other than ping, fail with
+// MethodNotFound.
+func WithRequestHandler(method string, h ClientRequestHandler) ClientOption {
+	return func(c *Client) { c.handlers[method] = h }
+}
+
+// Client is a connection to an MCP server. It correlates responses with
+// the requests that caused them, so its methods may be called from
+// several goroutines at once.
+//
+// A session starts with Initialize and ends with Close:
+//
+//	c := mcp.NewClient(conn, mcp.WithClientInfo("my-agent", "1.0"))
+//	defer c.Close()
+//	if _, err := c.Initialize(ctx); err != nil {
+//		return err
+//	}
+//	res, err := c.CallTool(ctx, "greet", map[string]string{"name": "Ada"})
+//
+// Calls fail with a *protocol.Error when the server answers with one.
+type Client struct {
+	conn           ClientConn
+	info           protocol.Implementation
+	capabilities   protocol.ClientCapabilities
+	logger         *slog.Logger
+	onNotification NotificationHandler
+	handlers       map[string]ClientRequestHandler
+
+	nextID atomic.Int64
+
+	mu        sync.Mutex
+	pending   map[protocol.ID]chan *protocol.Message
+	initState *protocol.InitializeResult
+	goodbye   *protocol.GoodbyeParams
+	// subs holds the channels of the resource subscriptions, by URI.
+	subs      map[string][]chan protocol.ResourceUpdatedParams
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewClient returns a client talking to a server over conn and starts
+// reading its messages. Call Initialize before anything else.
+func NewClient(conn ClientConn, opts ...ClientOption) *Client {
+	c := &Client{
+		conn:     conn,
+		info:     protocol.Implementation{Name: "zenmcp-client", Version: ReadBuildInfo().Version()},
+		handlers: make(map[string]ClientRequestHandler),
+		pending:  make(map[protocol.ID]chan *protocol.Message),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = slog.Default()
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	go c.readLoop()
+	return c
+}
+
+// Initialize performs the handshake: it sends initialize and, once the
+// server has answered, confirms with notifications/initialized. Give ctx
+// a deadline to bound a handshake with a server that does not answer, or
+// does not even read: once it passes, Initialize fails with
+// ErrHandshakeTimeout.
+func (c *Client) Initialize(ctx context.Context) (*protocol.InitializeResult, error) {
+	res, err := c.initialize(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: %w", ErrHandshakeTimeout, context.DeadlineExceeded)
+	}
+	return res, err
+}
+
+func (c *Client) initialize(ctx context.Context) (*protocol.InitializeResult, error) {
+	caps := c.capabilities
+	compressor, _ := c.conn.(transport.Compressor)
+	if compressor != nil && len(compressor.Compressions()) > 0 {
+		experimental := make(map[string]interface{}, len(caps.Experimental)+1)
+		for k, v := range caps.Experimental {
+			experimental[k] = v
+		}
+		experimental[runtime.ExperimentalCompression] = runtime.CompressionOffer{Algorithms: compressor.Compressions()}
+		caps.Experimental = experimental
+	}
+	var res protocol.InitializeResult
+	err := c.Call(ctx, protocol.MethodInitialize, protocol.InitializeParams{
+		ProtocolVersion: protocol.LatestProtocolVersion,
+		Capabilities:    caps,
+		ClientInfo:      c.info,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.ProtocolVersion == "" {
+		return nil, errors.New("mcp: initialize result has no protocol version")
+	}
+	if compressor != nil {
+		choice, _ := res.Capabilities.Experimental[runtime.ExperimentalCompression].(map[string]interface{})
+		if name, _ := choice["algorithm"].(string); name != "" {
+			if err := compressor.EnableCompression(name); err != nil {
+				return nil, fmt.Errorf("mcp: %w", err)
+			}
+		}
+	}
+	if err := c.Notify(ctx, protocol.MethodInitialized, nil); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.initState = &res
+	c.mu.Unlock()
+	return &res, nil
+}
+
+// InitializeResult returns the server's answer to Initialize, or nil
+// before the handshake has completed.
+func (c *Client) InitializeResult() *protocol.InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initState
+}
+
+// Ping checks that the server is responsive.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Call(ctx, protocol.MethodPing, nil, nil)
+}
+
+// ListTools lists the tools offered by the server. params may be nil.
+// Without params.Cursor, it follows the server's cursors and returns the
+// tools of every page; with it, it returns that page only.
+func (c *Client) ListTools(ctx context.Context, params *protocol.ListToolsParams) (*protocol.ListToolsResult, error) {
+	if params != nil && params.Cursor != "" {
+		var res protocol.ListToolsResult
+		if err := c.Call(ctx, protocol.MethodToolsList, params, &res); err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	p := protocol.ListToolsParams{}
+	if params != nil {
+		p = *params
+	}
+	all := &protocol.ListToolsResult{Tools: []protocol.Tool{}}
+	err := paginate(func(cursor string) (string, error) {
+		p.Cursor = cursor
+		var res protocol.ListToolsResult
+		if err := c.Call(ctx, protocol.MethodToolsList, p, &res); err != nil {
+			return "", err
+		}
+		all.Tools = append(all.Tools, res.Tools...)
+		for name, def := range res.Defs {
+			if all.Defs == nil {
+				all.Defs = make(map[string]interface{})
+			}
+			all.Defs[name] = def
+		}
+		return res.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// paginate calls fetch with the cursor of each page of a list, starting
+// with none for the first, until fetch returns no next cursor.
+func paginate(fetch func(cursor string) (next string, err error)) error {
+	cursor := ""
+	for {
+		next, err := fetch(cursor)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		if next == cursor {
+			return errors.New("mcp: server returned the same list cursor twice")
+		}
+		cursor = next
+	}
+}
+
+// CallTool calls the named tool with args, which are encoded as JSON. A
+// result flagged IsError is returned without an error: the tool ran and
+// reported a failure.
+func (c *Client) CallTool(ctx context.Context, name string, args interface{}) (*protocol.ToolCallResult, error) {
+	params := protocol.ToolCallParams{Name: name}
+	if args != nil {
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("mcp: encoding arguments of %s: %w", name, err)
+		}
+		params.Arguments = raw
+	}
+	var res protocol.ToolCallResult
+	if err := c.Call(ctx, protocol.MethodToolsCall, params, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListResources lists the resources offered by the server, following its
+// cursors through every page.
+func (c *Client) ListResources(ctx context.Context) (*protocol.ListResourcesResult, error) {
+	all := &protocol.ListResourcesResult{Resources: []protocol.Resource{}}
+	err := paginate(func(cursor string) (string, error) {
+		var res protocol.ListResourcesResult
+		if err := c.Call(ctx, protocol.MethodResourcesList, protocol.ListResourcesParams{Cursor: cursor}, &res); err != nil {
+			return "", err
+		}
+		all.Resources = append(all.Resources, res.Resources...)
+		return res.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// ListResourceTemplates lists the resource templates offered by the
+// server, following its cursors through every page.
+func (c *Client) ListResourceTemplates(ctx context.Context) (*protocol.ListResourceTemplatesResult, error) {
+	all := &protocol.ListResourceTemplatesResult{ResourceTemplates: []protocol.ResourceTemplate{}}
+	err := paginate(func(cursor string) (string, error) {
+		var res protocol.ListResourceTemplatesResult
+		if err := c.Call(ctx, protocol.MethodResourceTemplatesList, protocol.ListResourceTemplatesParams{Cursor: cursor}, &res); err != nil {
+			return "", err
+		}
+		all.ResourceTemplates = append(all.ResourceTemplates, res.ResourceTemplates...)
+		return res.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// ReadResource reads the resource at uri.
+func (c *Client) ReadResource(ctx context.Context, uri string) (*protocol.ReadResourceResult, error) {
+	var res protocol.ReadResourceResult
+	if err := c.Call(ctx, protocol.MethodResourcesRead, protocol.ReadResourceParams{URI: uri}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SetLogLevel asks the server to send the log messages at level and above,
+// which arrive as notifications/message.
+func (c *Client) SetLogLevel(ctx context.Context, level protocol.LoggingLevel) error {
+	return c.Call(ctx, protocol.MethodLoggingSetLevel, protocol.SetLevelParams{Level: level}, nil)
+}
+
+// ListPrompts lists the prompts offered by the server, following its
+// cursors through every page.
+func (c *Client) ListPrompts(ctx context.Context) (*protocol.ListPromptsResult, error) {
+	all := &protocol.ListPromptsResult{Prompts: []protocol.Prompt{}}
+	err := paginate(func(cursor string) (string, error) {
+		var res protocol.ListPromptsResult
+		if err := c.Call(ctx, protocol.MethodPromptsList, protocol.ListPromptsParams{Cursor: cursor}, &res); err != nil {
+			return "", err
+		}
+		all.Prompts = append(all.Prompts, res.Prompts...)
+		return res.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// GetPrompt renders the named prompt with args.
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*protocol.GetPromptResult, error) {
+	var res protocol.GetPromptResult
+	if err := c.Call(ctx, protocol.MethodPromptsGet, protocol.GetPromptParams{Name: name, Arguments: args}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Call sends a request for method and decodes its result into result,
+// which may be nil to discard it. It is the building block of the typed
+// methods and reaches methods they do not cover, such as zenmcp
+// extensions. If ctx ends first, the server is told with
+// notifications/cancelled and ctx's error is returned.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	id := protocol.NewNumberID(c.nextID.Add(1))
+	req, err := protocol.NewRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	ch := make(chan *protocol.Message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.write(ctx, req); err != nil {
+		c.forget(id)
+		if ctx.Err() != nil {
+			// Connections like HTTP write until the response arrives, so
+			// the request may be in progress on the server.
+			c.cancelRequest(id, ctx.Err())
+		}
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp == nil {
+			return c.closeErr()
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || resp.Result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("mcp: decoding %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		c.forget(id)
+		c.cancelRequest(id, ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification to the server.
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	msg, err := protocol.NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return c.write(ctx, msg)
+}
+
+// Done returns a channel closed when the client stops, either because
+// Close was called or because the connection ended.
+func (c *Client) Done() <-chan struct{} { return c.done }
+
+// Err returns why the client stopped, or nil while it is running.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection. Calls in progress fail with
+// ErrClientClosed.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.shutdown(ErrClientClosed)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *Client) write(ctx context.Context, msg *protocol.Message) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return c.conn.Write(ctx, msg)
+}
+
+func (c *Client) forget(id protocol.ID) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *Client) cancelRequest(id protocol.ID, reason error) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	if err := c.Notify(ctx, protocol.MethodCancelled, protocol.CancelledParams{RequestID: id, Reason: reason.Error()}); err != nil {
+		c.logger.Debug("mcp client: sending cancellation", "id", id.String(), "error", err)
+	}
+}
+
+func (c *Client) closeErr() error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return ErrClientClosed
+}
+
+// shutdown records why the client stopped and fails the pending calls.
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.err = err
+	pending := c.pending
+	c.pending = nil
+	c.closeSubscriptions()
+	c.mu.Unlock()
+	c.cancel()
+	for _, ch := range pending {
+		ch <- nil
+	}
+	close(c.done)
+}
+
+func (c *Client) readLoop() {
+	for {
+		msg, err := c.conn.Read(c.ctx)
+		if err != nil {
+			var rpcErr *protocol.Error
+			if errors.As(err, &rpcErr) && rpcErr.Code == protocol.ParseError {
+				c.logger.Warn("mcp client: malformed message from server", "error", err)
+				continue
+			}
+			c.mu.Lock()
+			goodbye := c.goodbye
+			c.mu.Unlock()
+			switch {
+			case goodbye != nil && c.ctx.Err() == nil:
+				err = &GoodbyeError{Params: *goodbye}
+			case errors.Is(err, io.EOF) || c.ctx.Err() != nil:
+				err = ErrClientClosed
+			default:
+				err = fmt.Errorf("%w: %v", ErrClientClosed, err)
+			}
+			c.shutdown(err)
+			return
+		}
+		switch {
+		case msg.IsResponse():
+			c.deliver(msg)
+		case msg.IsRequest():
+			go c.answer(msg)
+		case msg.IsNotification():
+			switch msg.Method {
+			case protocol.MethodGoodbye:
+				c.recordGoodbye(msg.Params)
+			case protocol.MethodResourceUpdated:
+				c.resourceUpdated(msg.Params)
+			}
+			if c.onNotification != nil {
+				c.onNotification(msg.Method, msg.Params)
+			}
+		default:
+			c.logger.Warn("mcp client: unexpected message from server", "method", msg.Method)
+		}
+	}
+}
+
+// recordGoodbye keeps the goodbye of the server, for Err to report once
+// the connection ends.
+func (c *Client) recordGoodbye(params json.RawMessage) {
+	var p protocol.GoodbyeParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		c.logger.Warn("mcp client: malformed goodbye from server", "error", err)
+		return
+	}
+	c.mu.Lock()
+	c.goodbye = &p
+	c.mu.Unlock()
+}
+
+func (c *Client) deliver(resp *protocol.Message) {
+	c.mu.Lock()
+	ch, ok := c.pending[*resp.ID]
+	delete(c.pending, *resp.ID)
+	c.mu.Unlock()
+	if !ok {
+		c.logger.Debug("mcp client: response to an unknown request", "id", resp.ID.String())
+		return
+	}
+	ch <- resp
+}
+
+// answer runs the handler for a request sent by the server and writes its
+// response.
+func (c *Client) answer(req *protocol.Message) {
+	var resp *protocol.Message
+	h, ok := c.handlers[req.Method]
+	switch {
+	case ok:
+		result, err := h(c.ctx, req.Params)
+		if err == nil {
+			resp, err = protocol.NewResult(req.ID, result)
+		}
+		if err != nil {
+			resp = protocol.NewErrorResponse(req.ID, protocol.AsError(err))
+		}
+	case req.Method == protocol.MethodPing:
+		resp, _ = protocol.NewResult(req.ID, struct{}{})
+	default:
+		resp = protocol.NewErrorResponse(req.ID, protocol.Errorf(protocol.MethodNotFound, "method %q not supported by client", req.Method))
+	}
+	if err := c.write(c.ctx, resp); err != nil {
+		c.logger.Debug("mcp client: answering server request", "method", req.Method, "error", err)
+	}
+}
@@ -0,0 +1,47 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+)
+
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	for _, c := range cs {
+		c.Close()
+	}
+	return nil
+}
+
+// TestInitializeTimeout checks that a deadline aborts the handshake with a
+// server that never answers, whether or not it reads what it is sent.
+func TestInitializeTimeout(t *testing.T) {
+	for _, reads := range []bool{true, false} {
+		fromClient, toServer := io.Pipe()
+		fromServer, toClient := io.Pipe()
+		if reads {
+			go io.Copy(io.Discard, fromClient)
+		}
+		c := mcp.NewClient(mcp.NewStreamConn(fromServer, toServer, closers{toServer, fromServer}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		_, err := c.Initialize(ctx)
+		cancel()
+		if !errors.Is(err, mcp.ErrHandshakeTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("server reads = %v: Initialize: %v, want ErrHandshakeTimeout", reads, err)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("server reads = %v: Initialize took %s", reads, d)
+		}
+		c.Close()
+		toClient.Close()
+		fromClient.Close()
+	}
+}
@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// StreamOption configures the connections made by NewStreamConn and
+// NewCommandConn.
+type StreamOption func(*codec.ContentLength)
+
+// WithStreamCompression offers the server per-frame compression with cs,
+// in order of preference, when the client initializes. Frames stay plain
+// unless the server agrees to one of them.
+func WithStreamCompression(cs ...codec.Compression) StreamOption {
+	return func(c *codec.ContentLength) { c.SetCompressions(cs...) }
+}
+
+// NewStreamConn returns a ClientConn exchanging Content-Length framed
+// messages over r and w, such as the pipes of a server started by the
+// caller or a TCP connection. Closing it closes closer, which may be nil.
+//
+// A write still blocked when its context ends, because the server stopped
+// reading, is aborted by closing closer, or w when closer is nil and w is
+// an io.Closer: a frame cut short leaves the stream unusable anyway.
+func NewStreamConn(r io.Reader, w io.Writer, closer io.Closer, opts ...StreamOption) ClientConn {
+	return newStreamConn(r, w, closer, opts)
+}
+
+func newStreamConn(r io.Reader, w io.Writer, closer io.Closer, opts []StreamOption) *streamConn {
+	cl := codec.NewContentLength(r, w)
+	for _, opt := range opts {
+		opt(cl)
+	}
+	c := &streamConn{codec: cl, closer: closer, abort: closer, sem: make(chan struct{}, 1)}
+	if closer == nil {
+		c.abort, _ = w.(io.Closer)
+	}
+	return c
+}
+
+type streamConn struct {
+	codec  *codec.ContentLength
+	closer io.Closer
+	// abort breaks a blocked write: closer, or else w if it can be
+	// closed.
+	abort io.Closer
+	// sem serializes writes. It is a channel, not a mutex, so that a
+	// writer waiting behind a blocked one can give up when its context
+	// ends.
+	sem chan struct{}
+}
+
+func (c *streamConn) Read(ctx context.Context) (*protocol.Message, error) {
+	var msg protocol.Message
+	if err := c.codec.Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Write sends msg unless ctx ends first, aborting a blocked write as
+// NewStreamConn describes. Without anything to close, it cannot be
+// aborted.
+func (c *streamConn) Write(ctx context.Context, msg *protocol.Message) error {
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-c.sem }()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.abort == nil {
+		return c.codec.Encode(msg)
+	}
+	stop := context.AfterFunc(ctx, func() { c.abort.Close() })
+	err := c.codec.Encode(msg)
+	if !stop() {
+		return fmt.Errorf("mcp: write aborted, connection closed: %w", ctx.Err())
+	}
+	return err
+}
+
+func (c *streamConn) Compressions() []string { return c.codec.Compressions() }
+
+func (c *streamConn) EnableCompression(name string) error { return c.codec.EnableCompression(name) }
+
+func (c *streamConn) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// NewCommandConn starts cmd, a server speaking MCP over stdio, and returns
+// a connection to it. The command's stderr is left as configured. Closing
+// the connection closes the server's stdin and waits for it to exit,
+// killing it if it has not done so after a few seconds.
+func NewCommandConn(cmd *exec.Cmd, opts ...StreamOption) (ClientConn, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp: starting %s: %w", cmd.Path, err)
+	}
+	p := &process{cmd: cmd, stdin: stdin}
+	return newStreamConn(stdout, stdin, p, opts), nil
+}
+
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.Closer
+	once  sync.Once
+	err   error
+}
+
+func (p *process) Close() error {
+	p.once.Do(func() {
+		p.stdin.Close()
+		exited := make(chan error, 1)
+		go func() { exited <- p.cmd.Wait() }()
+		select {
+		case err := <-exited:
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				p.err = err
+			}
+		case <-time.After(3 * time.Second):
+			p.cmd.Process.Kill()
+			<-exited
+		}
+	})
+	return p.err
+}
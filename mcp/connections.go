@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ConnectionInfo is a snapshot of a live connection, as reported by
+// Server.Connections.
+type ConnectionInfo struct {
+	ID           string    `json:"id"`
+	Transport    string    `json:"transport"`
+	RemoteAddr   string    `json:"remoteAddr,omitempty"`
+	UserAgent    string    `json:"userAgent,omitempty"`
+	TLS          bool      `json:"tls,omitempty"`
+	OpenedAt     time.Time `json:"openedAt"`
+	Requests     int64     `json:"requests"`
+	BytesRead    int64     `json:"bytesRead"`
+	BytesWritten int64     `json:"bytesWritten"`
+	// QueuedNotifications is the number of notifications waiting to be
+	// written.
+	QueuedNotifications int `json:"queuedNotifications"`
+	// Disconnected reports that a write found the client gone. Nothing
+	// more is written to the connection while it winds down.
+	Disconnected bool `json:"disconnected,omitempty"`
+}
+
+// connState is the server's bookkeeping for one connection.
+type connState struct {
+	id       string
+	conn     transport.Connection
+	counter  transport.ByteCounter
+	peer     transport.Peer
+	openedAt time.Time
+	// session is the runtime session of the connection, holding its
+	// resource subscriptions.
+	session *runtime.Session
+
+	// writeMu serializes responses and notifications.
+	writeMu sync.Mutex
+
+	queueMu sync.Mutex
+	queue   chan queuedNotification
+	closed  bool
+	done    chan struct{}
+	// queued is signalled when a notification is queued. sendMu is held
+	// while taking notifications off the queue and writing them, so that
+	// they are written in the order they were queued.
+	queued chan struct{}
+	sendMu sync.Mutex
+
+	requests     atomic.Int64
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+
+	// lastActive is the time, in Unix nanoseconds, a message was last
+	// read or written; busy counts the requests being handled.
+	lastActive atomic.Int64
+	busy       atomic.Int32
+	// cancels holds the cancel functions of the requests being handled,
+	// by request ID.
+	cancelMu sync.Mutex
+	cancels  map[string]context.CancelFunc
+	// calls holds the channels awaiting the answers to the requests sent
+	// to the client, by request ID.
+	callMu sync.Mutex
+	calls  map[protocol.ID]chan *protocol.Message
+
+	// reaped is set once the idle reaper has closed the connection.
+	reaped atomic.Bool
+	// saidGoodbye is set once notifications/zenmcp/goodbye was sent.
+	saidGoodbye atomic.Bool
+	// handshaken is set once initialize succeeded.
+	handshaken atomic.Bool
+
+	// transport holds the statistics of the transport that accepted the
+	// connection.
+	transport *transportStats
+
+	// gone is set once a write found the client disconnected; later
+	// writes are suppressed and counted in dropped.
+	gone    atomic.Bool
+	dropped atomic.Int64
+}
+
+func newConnState(seq int64, conn transport.Connection, session *runtime.Session, queueSize int, now time.Time) *connState {
+	counter, _ := conn.(transport.ByteCounter)
+	st := &connState{
+		id:       "c" + strconv.FormatInt(seq, 10),
+		conn:     conn,
+		counter:  counter,
+		peer:     conn.Peer(),
+		openedAt: now,
+		session:  session,
+		queue:    make(chan queuedNotification, queueSize),
+		done:     make(chan struct{}),
+		queued:   make(chan struct{}, 1),
+	}
+	st.touch(now)
+	return st
+}
+
+// touch records activity on the connection at t.
+func (st *connState) touch(t time.Time) {
+	st.lastActive.Store(t.UnixNano())
+}
+
+// write sends msg and returns the number of bytes written since the
+// previous write.
+func (st *connState) write(ctx context.Context, msg *protocol.Message) (int64, error) {
+	st.writeMu.Lock()
+	defer st.writeMu.Unlock()
+	if st.gone.Load() {
+		st.dropped.Add(1)
+		return 0, transport.ErrDisconnected
+	}
+	start := st.transport.clock.Now()
+	err := st.conn.Write(ctx, msg)
+	if err == nil {
+		st.transport.observeWrite(st.transport.clock.Now().Sub(start))
+	}
+	return st.countWritten(), err
+}
+
+// disconnected reports whether err, returned writing msg to st, means the
+// client is gone. The first such error marks st, so that nothing more is
+// written to it, and emits EventDisconnected; the writes dropped after
+// that are only counted.
+func (s *Server) disconnected(st *connState, msg *protocol.Message, err error) bool {
+	if !transport.IsDisconnected(err) {
+		return false
+	}
+	if st.gone.CompareAndSwap(false, true) {
+		attrs := []slog.Attr{
+			slog.String("connection", st.id),
+			slog.String("transport", st.peer.Transport),
+			slog.String("error", err.Error()),
+		}
+		if st.peer.RemoteAddr != "" {
+			attrs = append(attrs, slog.String("remoteAddr", st.peer.RemoteAddr))
+		}
+		if msg != nil && msg.Method != "" {
+			attrs = append(attrs, slog.String("method", msg.Method))
+		}
+		s.emit(slog.LevelInfo, EventDisconnected, "client disconnected", attrs...)
+	}
+	return true
+}
+
+// countRead updates the read total from the connection's counter and
+// returns the bytes read since the previous call.
+func (st *connState) countRead() int64 {
+	if st.counter == nil {
+		return 0
+	}
+	read, _ := st.counter.Bytes()
+	return read - st.bytesRead.Swap(read)
+}
+
+// countWritten updates the written total from the connection's counter and
+// returns the bytes written since the previous call.
+func (st *connState) countWritten() int64 {
+	if st.counter == nil {
+		return 0
+	}
+	_, written := st.counter.Bytes()
+	return written - st.bytesWritten.Swap(written)
+}
+
+func (st *connState) info() ConnectionInfo {
+	return ConnectionInfo{
+		ID:           st.id,
+		Transport:    st.peer.Transport,
+		RemoteAddr:   st.peer.RemoteAddr,
+		UserAgent:    st.peer.UserAgent,
+		TLS:          st.peer.TLS != nil,
+		OpenedAt:     st.openedAt,
+		Requests:     st.requests.Load(),
+		BytesRead:    st.bytesRead.Load(),
+		BytesWritten: st.bytesWritten.Load(),
+
+		QueuedNotifications: len(st.queue),
+		Disconnected:        st.gone.Load(),
+	}
+}
+
+// Connections returns a snapshot of the live connections, oldest first.
+func (s *Server) Connections() []ConnectionInfo {
+	s.mu.Lock()
+	out := make([]ConnectionInfo, 0, len(s.conns))
+	for _, st := range s.conns {
+		out = append(out, st.info())
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].OpenedAt.Before(out[j].OpenedAt) })
+	return out
+}
+
+// Connected reports whether the connection with the given ID is live.
+func (s *Server) Connected(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.conns {
+		if st.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+type connIDKey struct{}
+
+// ConnectionID returns the ID of the connection a request arrived on, as
+// listed by Connections, or "" when ctx does not belong to a connection.
+// Handlers pass it to Notify to reach their client later.
+func ConnectionID(ctx context.Context) string {
+	id, _ := ctx.Value(connIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// Reasons a notification is dead-lettered.
+const (
+	DeadLetterConnectionClosed = "connection_closed"
+	DeadLetterDisconnected     = "disconnected"
+	DeadLetterQueueFull        = "queue_full"
+	DeadLetterWriteFailed      = "write_failed"
+)
+
+// defaultDeadLetterCapacity is the number of dead letters kept when
+// WithDeadLetterCapacity is not given.
+const defaultDeadLetterCapacity = 256
+
+// DeadLetter is a notification that could not be delivered.
+type DeadLetter struct {
+	Connection string          `json:"connection"`
+	Transport  string          `json:"transport,omitempty"`
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Reason     string          `json:"reason"`
+	Error      string          `json:"error,omitempty"`
+	Time       time.Time       `json:"time"`
+}
+
+// WithDeadLetterCapacity sets how many undelivered notifications the
+// server keeps for inspection. The oldest are discarded first.
+func WithDeadLetterCapacity(n int) Option {
+	return func(s *Server) { s.deadLetterCapacity = n }
+}
+
+// DeadLetters returns the buffered dead letters, oldest first.
+func (s *Server) DeadLetters() []DeadLetter {
+	return s.deadLetters.snapshot(false)
+}
+
+// DrainDeadLetters returns the buffered dead letters, oldest first, and
+// empties the buffer.
+func (s *Server) DrainDeadLetters() []DeadLetter {
+	return s.deadLetters.snapshot(true)
+}
+
+func (s *Server) deadLetter(connID, transport string, msg *protocol.Message, reason string, err error) {
+	dl := DeadLetter{
+		Connection: connID,
+		Transport:  transport,
+		Method:     msg.Method,
+		Params:     notificationParams(msg),
+		Reason:     reason,
+		Time:       s.clock.Now(),
+	}
+	if err != nil {
+		dl.Error = err.Error()
+	}
+	s.deadLetters.add(dl)
+	s.logger.Debug("notification dead-lettered", "connection", connID, "method", msg.Method, "reason", reason, "error", err)
+}
+
+// deadLetterBuffer is a ring buffer of the most recent dead letters.
+type deadLetterBuffer struct {
+	mu    sync.Mutex
+	buf   []DeadLetter
+	start int
+	n     int
+
+	total    metrics.CounterVec
+	buffered metrics.Gauge
+}
+
+func newDeadLetterBuffer(capacity int, reg *metrics.Registry) *deadLetterBuffer {
+	if capacity <= 0 {
+		capacity = defaultDeadLetterCapacity
+	}
+	return &deadLetterBuffer{
+		buf: make([]DeadLetter, capacity),
+		total: reg.CounterVec("zenmcp_notifications_dead_lettered_total",
+			"Notifications that could not be delivered, by reason.", "reason"),
+		buffered: reg.Gauge("zenmcp_dead_letters",
+			"Undelivered notifications currently held in the dead-letter buffer."),
+	}
+}
+
+func (b *deadLetterBuffer) add(dl DeadLetter) {
+	b.total.With(dl.Reason).Inc()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf[(b.start+b.n)%len(b.buf)] = dl
+	if b.n < len(b.buf) {
+		b.n++
+	} else {
+		b.start = (b.start + 1) % len(b.buf)
+	}
+	b.buffered.Set(float64(b.n))
+}
+
+func (b *deadLetterBuffer) snapshot(drain bool) []DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]DeadLetter, b.n)
+	for i := range out {
+		out[i] = b.buf[(b.start+i)%len(b.buf)]
+	}
+	if drain {
+		clear(b.buf)
+		b.start, b.n = 0, 0
+		b.buffered.Set(0)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Event kinds reported to an EventHandler.
+const (
+	EventMemoryPressure  = "memory.pressure"
+	EventMemoryRecovered = "memory.recovered"
+	EventHealthDegraded  = "health.degraded"
+	EventHealthRecovered = "health.recovered"
+	// EventDisconnected is emitted once per connection, when a write
+	// finds the client gone.
+	EventDisconnected = "connection.disconnected"
+)
+
+// Event is an operational occurrence worth an operator's attention, such as
+// the server starting to shed load.
+type Event struct {
+	Kind    string
+	Time    time.Time
+	Message string
+	Attrs   []slog.Attr
+}
+
+// EventHandler receives server events. It is called synchronously and must
+// not block.
+type EventHandler func(Event)
+
+// WithEventHandler installs h to receive server events in addition to the
+// log entries written for them.
+func WithEventHandler(h EventHandler) Option {
+	return func(s *Server) { s.onEvent = h }
+}
+
+// emit logs an event at level and passes it to the event handler.
+func (s *Server) emit(level slog.Level, kind, message string, attrs ...slog.Attr) {
+	ev := Event{Kind: kind, Time: s.clock.Now(), Message: message, Attrs: attrs}
+	s.logger.LogAttrs(context.Background(), level, message, append([]slog.Attr{slog.String("event", kind)}, attrs...)...)
+	if s.onEvent != nil {
+		s.onEvent(ev)
+	}
+}
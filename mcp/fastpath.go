@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/schema"
+	"github.com/hyperleex/zenmcp/validate"
+)
+
+// adaptFast returns the handler of a FastPath tool: it decodes the
+// arguments with dec and calls h, and hands the calls dec declines to
+// generic, the handler AdaptTyped built, once it has checked their
+// arguments as the router does for other tools.
+func adaptFast[T any](dec *schema.Decoder[T], inputSchema map[string]interface{}, generic registry.ToolHandler, h TypedToolHandler[T]) registry.ToolHandler {
+	checks := validate.Checks(reflect.TypeOf((*T)(nil)).Elem())
+	return func(ctx context.Context, raw json.RawMessage) (*protocol.ToolCallResult, error) {
+		rc := runtime.FromContext(ctx)
+		var args T
+		if !dec.Decode(raw, &args) {
+			if err := rc.ValidateArguments(raw, inputSchema); err != nil {
+				return nil, err
+			}
+			return generic(ctx, raw)
+		}
+		if checks {
+			if err := validateValue(&args); err != nil {
+				return nil, err
+			}
+		}
+		return h(rc, args)
+	}
+}
@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/schema"
+)
+
+type searchArgs struct {
+	Query  string   `json:"query"`
+	Limit  int      `json:"limit" default:"10" minimum:"1" maximum:"100"`
+	Offset int      `json:"offset,omitempty"`
+	Exact  bool     `json:"exact,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// BenchmarkArguments compares the cost of decoding the arguments of a
+// tool registered with and without ToolDescriptor.FastPath. The generic
+// path includes the argument check the router makes before calling the
+// handler.
+func BenchmarkArguments(b *testing.B) {
+	inputSchema, err := schema.For[searchArgs]()
+	if err != nil {
+		b.Fatal(err)
+	}
+	dec, err := schema.NewDecoder[searchArgs](inputSchema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	args := json.RawMessage(`{"query":"zenmcp transport","offset":20,"tags":["go","mcp"]}`)
+
+	var got searchArgs
+	handler := func(ctx *runtime.Context, args searchArgs) (*protocol.ToolCallResult, error) {
+		got = args
+		return nil, nil
+	}
+	typed := AdaptTyped(inputSchema, false, handler)
+	generic := func(ctx context.Context, raw json.RawMessage) (*protocol.ToolCallResult, error) {
+		if err := schema.Validate(raw, inputSchema); err != nil {
+			return nil, err
+		}
+		return typed(ctx, raw)
+	}
+	fast := adaptFast(dec, inputSchema, typed, handler)
+
+	ctx := context.Background()
+	if _, err := generic(ctx, args); err != nil {
+		b.Fatal(err)
+	}
+	want := got
+	if !dec.Decode(args, &got) {
+		b.Fatal("the fast path declines the arguments")
+	}
+	if _, err := fast(ctx, args); err != nil {
+		b.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		b.Fatalf("fast path decoded %+v, generic path %+v", got, want)
+	}
+
+	for _, bb := range []struct {
+		name string
+		call func(context.Context, json.RawMessage) (*protocol.ToolCallResult, error)
+	}{{"generic", generic}, {"fast", fast}} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bb.call(ctx, args); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package mcp
+
+import (
+	"encoding/json"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// logMalformedFrame logs a preview of a frame that could not be parsed.
+func (s *Server) logMalformedFrame(peer transport.Peer, fe *transport.FrameError) {
+	if s.framePreview <= 0 {
+		return
+	}
+	s.logger.Debug("malformed frame",
+		"peer", peer,
+		"size", fe.Size,
+		"frame", transport.Preview(fe.Frame, s.framePreview),
+		"error", fe.Err.Message)
+}
+
+// logUnroutedFrame logs a preview of msg when the router answered it with
+// InvalidRequest or MethodNotFound: it was not a valid message, named an
+// unknown method or could not be routed to a tenant.
+func (s *Server) logUnroutedFrame(st *connState, msg *protocol.Message, rpcErr *protocol.Error) {
+	if s.framePreview <= 0 {
+		return
+	}
+	if rpcErr.Code != protocol.InvalidRequest && rpcErr.Code != protocol.MethodNotFound {
+		return
+	}
+	frame, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.logger.Debug("frame not routed",
+		"connection", st.id,
+		"peer", st.peer,
+		"size", len(frame),
+		"frame", transport.Preview(frame, s.framePreview),
+		"error", rpcErr.Message)
+}
@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// goodbyeTimeout bounds the time spent writing a goodbye to one client.
+const goodbyeTimeout = time.Second
+
+// sayGoodbye sends notifications/zenmcp/goodbye to the client of st,
+// unless it was sent already. The goodbye is written directly rather than
+// queued behind other notifications; a client that is gone simply misses
+// it.
+func (s *Server) sayGoodbye(ctx context.Context, st *connState, params protocol.GoodbyeParams) {
+	if !st.saidGoodbye.CompareAndSwap(false, true) {
+		return
+	}
+	msg, err := protocol.NewNotification(protocol.MethodGoodbye, params)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, goodbyeTimeout)
+	defer cancel()
+	if _, err := st.write(ctx, msg); err != nil {
+		s.disconnected(st, msg, err)
+	}
+}
+
+// shutdownGoodbye is the goodbye of a server shutting down.
+var shutdownGoodbye = protocol.GoodbyeParams{Reason: protocol.GoodbyeShutdown, Message: "server shutting down"}
+
+// goodbyeAll tells the clients of all open connections that the server is
+// shutting down, so that they can tell the coming close from a crash and
+// reconnect once the server is back. Connections that live for a single
+// request, such as plain HTTP POSTs, have no room for it and are skipped.
+func (s *Server) goodbyeAll(ctx context.Context) {
+	s.mu.Lock()
+	var conns []*connState
+	for conn, st := range s.conns {
+		if rs, ok := conn.(transport.RequestScoped); ok && rs.RequestScoped() {
+			continue
+		}
+		conns = append(conns, st)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, st := range conns {
+		wg.Add(1)
+		go func(st *connState) {
+			defer wg.Done()
+			s.sayGoodbye(ctx, st, shutdownGoodbye)
+		}(st)
+	}
+	wg.Wait()
+}
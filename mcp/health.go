@@ -0,0 +1,191 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	goruntime "runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/metrics"
+)
+
+// Health statuses reported by Server.Health.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// HealthConfig sets the thresholds beyond which the server reports itself
+// degraded. Zero fields take their defaults.
+type HealthConfig struct {
+	// MaxQueueFill is the fraction of a connection's notification queue
+	// that may be in use. Defaults to 0.8.
+	MaxQueueFill float64
+	// MaxDispatchLag is the longest a message may wait between being read
+	// and being dispatched. Defaults to one second.
+	MaxDispatchLag time.Duration
+	// MaxGoroutines bounds the number of goroutines. Zero, the default,
+	// sets no bound.
+	MaxGoroutines int
+	// SampleInterval is how often the signals are sampled into metrics and
+	// checked for degradation events. Defaults to five seconds.
+	SampleInterval time.Duration
+}
+
+// WithHealthConfig sets the health thresholds.
+func WithHealthConfig(cfg HealthConfig) Option {
+	return func(s *Server) { s.healthConfig = cfg }
+}
+
+// Health is a snapshot of the server's internal load signals.
+type Health struct {
+	// Status is HealthOK, or HealthDegraded when a signal is beyond its
+	// threshold; Reasons then names the signals.
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons,omitempty"`
+
+	Connections int `json:"connections"`
+	Goroutines  int `json:"goroutines"`
+	// QueuedNotifications is the number of notifications waiting to be
+	// written across all connections; MaxQueueFill is the largest
+	// fraction of one connection's queue in use.
+	QueuedNotifications int     `json:"queuedNotifications"`
+	MaxQueueFill        float64 `json:"maxQueueFill"`
+	// DispatchLagSeconds is the longest a message waited between being
+	// read and being dispatched over the last sample interval.
+	DispatchLagSeconds float64 `json:"dispatchLagSeconds"`
+}
+
+// healthMonitor tracks the signals behind Server.Health.
+type healthMonitor struct {
+	cfg HealthConfig
+
+	// lag is the longest dispatch lag, in nanoseconds, seen in the current
+	// sample interval; lastLag is that of the previous interval.
+	lag     atomic.Int64
+	lastLag atomic.Int64
+	status  atomic.Value // string
+
+	dispatchLag metrics.Histogram
+	goroutines  metrics.Gauge
+	queueDepth  metrics.GaugeVec
+	queueFill   metrics.Gauge
+}
+
+func newHealthMonitor(cfg HealthConfig, reg *metrics.Registry) *healthMonitor {
+	if cfg.MaxQueueFill <= 0 {
+		cfg.MaxQueueFill = 0.8
+	}
+	if cfg.MaxDispatchLag <= 0 {
+		cfg.MaxDispatchLag = time.Second
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = 5 * time.Second
+	}
+	h := &healthMonitor{
+		cfg: cfg,
+		dispatchLag: reg.Histogram("zenmcp_dispatch_lag_seconds",
+			"Time messages waited between being read and dispatched.",
+			metrics.ExponentialBuckets(0.0001, 4, 9)),
+		goroutines: reg.Gauge("zenmcp_goroutines", "Number of goroutines."),
+		queueDepth: reg.GaugeVec("zenmcp_notification_queue_depth",
+			"Notifications waiting to be written, by transport.", "transport"),
+		queueFill: reg.Gauge("zenmcp_notification_queue_max_fill",
+			"Largest fraction of a connection's notification queue in use."),
+	}
+	h.status.Store(HealthOK)
+	return h
+}
+
+// observeLag records that a message waited d to be dispatched.
+func (h *healthMonitor) observeLag(d time.Duration) {
+	h.dispatchLag.Observe(d.Seconds())
+	for {
+		cur := h.lag.Load()
+		if int64(d) <= cur || h.lag.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// Health reports the server's load signals and whether any is beyond the
+// thresholds set with WithHealthConfig.
+func (s *Server) Health() Health {
+	h := Health{Goroutines: goruntime.NumGoroutine()}
+	s.mu.Lock()
+	h.Connections = len(s.conns)
+	for _, st := range s.conns {
+		n := len(st.queue)
+		h.QueuedNotifications += n
+		if c := cap(st.queue); c > 0 {
+			h.MaxQueueFill = max(h.MaxQueueFill, float64(n)/float64(c))
+		}
+	}
+	s.mu.Unlock()
+	m := s.health
+	lag := time.Duration(max(m.lag.Load(), m.lastLag.Load()))
+	h.DispatchLagSeconds = lag.Seconds()
+
+	if h.MaxQueueFill > m.cfg.MaxQueueFill {
+		h.Reasons = append(h.Reasons, "notification queue filling up")
+	}
+	if lag > m.cfg.MaxDispatchLag {
+		h.Reasons = append(h.Reasons, "dispatch lagging")
+	}
+	if m.cfg.MaxGoroutines > 0 && h.Goroutines > m.cfg.MaxGoroutines {
+		h.Reasons = append(h.Reasons, "too many goroutines")
+	}
+	h.Status = HealthOK
+	if len(h.Reasons) > 0 {
+		h.Status = HealthDegraded
+	}
+	return h
+}
+
+// monitorHealth samples the health signals into metrics every sample
+// interval and emits an event when the status changes.
+func (s *Server) monitorHealth(ctx context.Context) {
+	m := s.health
+	ticker := s.clock.NewTicker(m.cfg.SampleInterval)
+	defer ticker.Stop()
+	transports := make(map[string]bool) // those with a queue depth reported
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		m.lastLag.Store(m.lag.Swap(0))
+		h := s.Health()
+		m.goroutines.Set(float64(h.Goroutines))
+		m.queueFill.Set(h.MaxQueueFill)
+		depths := s.queueDepths()
+		for t := range depths {
+			transports[t] = true
+		}
+		for t := range transports {
+			m.queueDepth.With(t).Set(float64(depths[t]))
+		}
+		prev := m.status.Swap(h.Status).(string)
+		switch {
+		case prev == HealthOK && h.Status == HealthDegraded:
+			s.emit(slog.LevelWarn, EventHealthDegraded, "server health degraded",
+				slog.Any("reasons", h.Reasons), slog.Float64("dispatch_lag_seconds", h.DispatchLagSeconds),
+				slog.Float64("max_queue_fill", h.MaxQueueFill), slog.Int("goroutines", h.Goroutines))
+		case prev == HealthDegraded && h.Status == HealthOK:
+			s.emit(slog.LevelInfo, EventHealthRecovered, "server health recovered")
+		}
+	}
+}
+
+// queueDepths returns the number of queued notifications by transport.
+func (s *Server) queueDepths() map[string]int {
+	depths := make(map[string]int)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.conns {
+		depths[st.peer.Transport] += len(st.queue)
+	}
+	return depths
+}
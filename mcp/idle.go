@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// reapIdle closes the connections that have been idle for longer than the
+// idle timeout, checking a few times per timeout until ctx is done.
+func (s *Server) reapIdle(ctx context.Context) {
+	ticker := s.clock.NewTicker(max(s.idleTimeout/4, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			for _, st := range s.idleConnections() {
+				s.closeIdle(st)
+			}
+		}
+	}
+}
+
+// idleConnections returns the connections due for reaping.
+func (s *Server) idleConnections() []*connState {
+	cutoff := s.clock.Now().Add(-s.idleTimeout).UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var idle []*connState
+	for conn, st := range s.conns {
+		if rs, ok := conn.(transport.RequestScoped); ok && rs.RequestScoped() {
+			continue
+		}
+		if st.busy.Load() == 0 && st.lastActive.Load() <= cutoff && st.reaped.CompareAndSwap(false, true) {
+			idle = append(idle, st)
+		}
+	}
+	return idle
+}
+
+// closeIdle says goodbye to the client of st and closes the connection.
+func (s *Server) closeIdle(st *connState) {
+	s.logger.Info("closing idle connection", "id", st.id, "peer", st.peer, "timeout", s.idleTimeout)
+	s.metrics.idleClosed.With(st.peer.Transport).Inc()
+	s.sayGoodbye(context.Background(), st, protocol.GoodbyeParams{
+		Reason:  protocol.GoodbyeIdle,
+		Message: "closing connection after " + s.idleTimeout.String() + " without activity",
+	})
+	st.conn.Close()
+}
@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	rtdebug "runtime/debug"
+	rtmetrics "runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// MemoryConfig configures memory limits and load shedding.
+type MemoryConfig struct {
+	// SoftLimit, when positive, is installed as the Go runtime soft memory
+	// limit (see runtime/debug.SetMemoryLimit). Otherwise the limit set via
+	// GOMEMLIMIT, if any, is used.
+	SoftLimit int64
+	// GCPercent, when non-zero, is installed with debug.SetGCPercent. Large
+	// deployments running with a soft limit commonly use -1 (off) or a high
+	// value so that the limit drives collection.
+	GCPercent int
+	// RejectAbove is the fraction of the limit beyond which new requests are
+	// rejected. Defaults to 0.9.
+	RejectAbove float64
+	// RequestOverhead estimates the memory a request allocates as a multiple
+	// of its encoded size. Estimates of in-flight requests count towards the
+	// limit until they complete. Defaults to 4.
+	RequestOverhead float64
+	// MinRequestEstimate is the smallest estimate charged per request.
+	// Defaults to 64 KiB.
+	MinRequestEstimate int64
+	// SampleInterval is how often memory usage is sampled. Defaults to one
+	// second.
+	SampleInterval time.Duration
+}
+
+// WithMemoryConfig enables memory limit integration: the runtime soft
+// limit and GC percentage are applied, usage is sampled, and requests are
+// rejected with protocol.ServerOverloaded while usage plus the estimated
+// cost of in-flight requests exceeds the configured share of the limit.
+// Crossing the threshold in either direction emits an event.
+func WithMemoryConfig(cfg MemoryConfig) Option {
+	return func(s *Server) { s.memoryConfig = &cfg }
+}
+
+// memoryGuard admits requests while memory usage is below the threshold.
+type memoryGuard struct {
+	cfg       MemoryConfig
+	threshold int64
+	sample    []rtmetrics.Sample
+
+	used      atomic.Int64
+	reserved  atomic.Int64
+	pressured atomic.Bool
+
+	usage      metrics.Gauge
+	limit      metrics.Gauge
+	rejections metrics.Counter
+}
+
+func newMemoryGuard(cfg MemoryConfig, reg *metrics.Registry) *memoryGuard {
+	if cfg.RejectAbove <= 0 || cfg.RejectAbove > 1 {
+		cfg.RejectAbove = 0.9
+	}
+	if cfg.RequestOverhead <= 0 {
+		cfg.RequestOverhead = 4
+	}
+	if cfg.MinRequestEstimate <= 0 {
+		cfg.MinRequestEstimate = 64 << 10
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = time.Second
+	}
+	if cfg.GCPercent != 0 {
+		rtdebug.SetGCPercent(cfg.GCPercent)
+	}
+	limit := rtdebug.SetMemoryLimit(-1)
+	if cfg.SoftLimit > 0 {
+		rtdebug.SetMemoryLimit(cfg.SoftLimit)
+		limit = cfg.SoftLimit
+	}
+	g := &memoryGuard{
+		cfg:       cfg,
+		threshold: math.MaxInt64,
+		sample: []rtmetrics.Sample{
+			{Name: "/memory/classes/total:bytes"},
+			{Name: "/memory/classes/heap/released:bytes"},
+		},
+		usage:      reg.Gauge("zenmcp_memory_usage_bytes", "Memory mapped by the Go runtime minus memory released to the OS."),
+		limit:      reg.Gauge("zenmcp_memory_limit_bytes", "Soft memory limit in effect."),
+		rejections: reg.Counter("zenmcp_memory_rejections_total", "Requests rejected because memory usage was above the threshold."),
+	}
+	if limit != math.MaxInt64 {
+		g.threshold = int64(float64(limit) * cfg.RejectAbove)
+		g.limit.Set(float64(limit))
+	}
+	return g
+}
+
+// run samples memory usage until ctx is done.
+func (g *memoryGuard) run(ctx context.Context, s *Server) {
+	ticker := s.clock.NewTicker(g.cfg.SampleInterval)
+	defer ticker.Stop()
+	for {
+		g.measure(s)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+func (g *memoryGuard) measure(s *Server) {
+	rtmetrics.Read(g.sample)
+	used := int64(g.sample[0].Value.Uint64() - g.sample[1].Value.Uint64())
+	g.used.Store(used)
+	g.usage.Set(float64(used))
+
+	over := used+g.reserved.Load() > g.threshold
+	if over && !g.pressured.Swap(true) {
+		s.emit(slog.LevelWarn, EventMemoryPressure, "memory usage above threshold, shedding requests",
+			slog.Int64("used_bytes", used), slog.Int64("threshold_bytes", g.threshold))
+	} else if !over && g.pressured.Swap(false) {
+		s.emit(slog.LevelInfo, EventMemoryRecovered, "memory usage back below threshold",
+			slog.Int64("used_bytes", used), slog.Int64("threshold_bytes", g.threshold))
+	}
+}
+
+// admit reserves the estimated cost of a request of size bytes. It returns
+// a function releasing the reservation, or an error when the request must
+// be rejected.
+func (g *memoryGuard) admit(size int64) (func(), *protocol.Error) {
+	estimate := int64(float64(size) * g.cfg.RequestOverhead)
+	if estimate < g.cfg.MinRequestEstimate {
+		estimate = g.cfg.MinRequestEstimate
+	}
+	if g.used.Load()+g.reserved.Add(estimate) > g.threshold {
+		g.reserved.Add(-estimate)
+		g.rejections.Inc()
+		return nil, protocol.NewError(protocol.ServerOverloaded, "server is low on memory, retry later", nil)
+	}
+	return func() { g.reserved.Add(-estimate) }, nil
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"github.com/hyperleex/zenmcp/metrics"
+)
+
+// sizeBuckets span payloads from 64 bytes to 16 MiB.
+var sizeBuckets = metrics.ExponentialBuckets(64, 4, 10)
+
+// serverMetrics are the instruments maintained by a Server.
+type serverMetrics struct {
+	requestBytes  metrics.HistogramVec
+	responseBytes metrics.HistogramVec
+	bytesRead     metrics.CounterVec
+	bytesWritten  metrics.CounterVec
+
+	handshakeTimeouts metrics.CounterVec
+	idleClosed        metrics.CounterVec
+	cancelledRequests metrics.CounterVec
+	deprecatedCalls   metrics.CounterVec
+
+	transportAccepted          metrics.CounterVec
+	transportAcceptErrors      metrics.CounterVec
+	transportHandshakeFailures metrics.CounterVec
+	transportFrameErrors       metrics.CounterVec
+	transportWriteSeconds      metrics.HistogramVec
+	transportSlow              metrics.GaugeVec
+}
+
+func newServerMetrics(reg *metrics.Registry) *serverMetrics {
+	return &serverMetrics{
+		requestBytes: reg.HistogramVec("zenmcp_request_bytes",
+			"Size of incoming requests in bytes, by method and tool or prompt.", sizeBuckets, "method", "target"),
+		responseBytes: reg.HistogramVec("zenmcp_response_bytes",
+			"Size of outgoing responses in bytes, by method and tool or prompt.", sizeBuckets, "method", "target"),
+		bytesRead: reg.CounterVec("zenmcp_bytes_read_total",
+			"Bytes read from clients, by transport.", "transport"),
+		bytesWritten: reg.CounterVec("zenmcp_bytes_written_total",
+			"Bytes written to clients, by transport.", "transport"),
+		handshakeTimeouts: reg.CounterVec("zenmcp_handshake_timeouts_total",
+			"Connections closed for not completing initialize in time, by transport.", "transport"),
+		idleClosed: reg.CounterVec("zenmcp_idle_connections_closed_total",
+			"Connections closed by the idle reaper, by transport.", "transport"),
+		cancelledRequests: reg.CounterVec("zenmcp_cancelled_requests_total",
+			"Requests cancelled by the client while in progress, by transport.", "transport"),
+		deprecatedCalls: reg.CounterVec("zenmcp_deprecated_tool_calls_total",
+			"Calls to deprecated tools, by tool.", "tool"),
+
+		transportAccepted: reg.CounterVec("zenmcp_transport_accepted_total",
+			"Connections accepted, by transport instance.", "transport", "instance"),
+		transportAcceptErrors: reg.CounterVec("zenmcp_transport_accept_errors_total",
+			"Failures to accept a connection, by transport instance.", "transport", "instance"),
+		transportHandshakeFailures: reg.CounterVec("zenmcp_transport_handshake_failures_total",
+			"Connections closed without completing initialize, by transport instance.", "transport", "instance"),
+		transportFrameErrors: reg.CounterVec("zenmcp_transport_frame_errors_total",
+			"Frames that could not be parsed, by transport instance.", "transport", "instance"),
+		transportWriteSeconds: reg.HistogramVec("zenmcp_transport_write_seconds",
+			"Time taken to write a message, by transport instance.", metrics.ExponentialBuckets(0.0001, 4, 10), "transport", "instance"),
+		transportSlow: reg.GaugeVec("zenmcp_transport_slow",
+			"1 while writes on the transport instance are slower than the threshold.", "transport", "instance"),
+	}
+}
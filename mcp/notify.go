@@ -0,0 +1,279 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// defaultNotificationQueue is the number of notifications buffered per
+// connection when WithNotificationQueueSize is not given.
+const defaultNotificationQueue = 64
+
+// ErrNotDelivered is wrapped by the errors Notify returns for
+// notifications that were dead-lettered instead of queued.
+var ErrNotDelivered = errors.New("mcp: notification not delivered")
+
+// WithNotificationQueueSize sets how many notifications may wait to be
+// written to one connection. Notifications beyond that are dead-lettered.
+func WithNotificationQueueSize(n int) Option {
+	return func(s *Server) { s.notificationQueue = n }
+}
+
+// Notify queues a notification for the connection with the given ID, as
+// listed by Connections. It returns once the notification is queued; a
+// failure to write it later is recorded in the dead-letter buffer. If the
+// connection is gone or its queue is full, the notification is
+// dead-lettered and the returned error wraps ErrNotDelivered.
+func (s *Server) Notify(connID, method string, params interface{}) error {
+	msg, err := protocol.NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	var st *connState
+	for _, c := range s.conns {
+		if c.id == connID {
+			st = c
+			break
+		}
+	}
+	s.mu.Unlock()
+	if st == nil {
+		s.deadLetter(connID, "", msg, DeadLetterConnectionClosed, nil)
+		return fmt.Errorf("%w: connection %s is closed", ErrNotDelivered, connID)
+	}
+	return s.enqueue(st, msg, nil)
+}
+
+// Broadcast queues a notification for every live connection. It returns
+// the number of connections the notification was queued for.
+func (s *Server) Broadcast(method string, params interface{}) (int, error) {
+	msg, err := protocol.NewNotification(method, params)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	targets := make([]*connState, 0, len(s.conns))
+	for _, st := range s.conns {
+		targets = append(targets, st)
+	}
+	s.mu.Unlock()
+	n := 0
+	for _, st := range targets {
+		if s.enqueue(st, msg, nil) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// NotifyResourceUpdated queues notifications/resources/updated for every
+// connection whose client subscribed to uri with resources/subscribe. It
+// returns the number of connections the notification was queued for.
+// Subscriptions belong to a connection, so over plain HTTP only sessions
+// keep them beyond the subscribing request.
+func (s *Server) NotifyResourceUpdated(uri string) (int, error) {
+	msg, err := protocol.NewNotification(protocol.MethodResourceUpdated, protocol.ResourceUpdatedParams{URI: uri})
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	var targets []*connState
+	for _, st := range s.conns {
+		if st.session.Subscribed(uri) {
+			targets = append(targets, st)
+		}
+	}
+	s.mu.Unlock()
+	n := 0
+	for _, st := range targets {
+		if s.enqueue(st, msg, nil) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// queuedNotification is a notification waiting to be written, with the ID
+// of the request whose handler sent it, if any.
+type queuedNotification struct {
+	msg     *protocol.Message
+	related *protocol.ID
+}
+
+// enqueue queues msg for st. related names the request msg is sent on
+// behalf of, or is nil.
+func (s *Server) enqueue(st *connState, msg *protocol.Message, related *protocol.ID) error {
+	st.queueMu.Lock()
+	defer st.queueMu.Unlock()
+	if st.closed {
+		s.deadLetter(st.id, st.peer.Transport, msg, DeadLetterConnectionClosed, nil)
+		return fmt.Errorf("%w: connection %s is closed", ErrNotDelivered, st.id)
+	}
+	if st.gone.Load() {
+		s.deadLetter(st.id, st.peer.Transport, msg, DeadLetterDisconnected, nil)
+		return fmt.Errorf("%w: client of connection %s disconnected", ErrNotDelivered, st.id)
+	}
+	select {
+	case st.queue <- queuedNotification{msg: msg, related: related}:
+		select {
+		case st.queued <- struct{}{}:
+		default:
+		}
+		return nil
+	default:
+		s.deadLetter(st.id, st.peer.Transport, msg, DeadLetterQueueFull, nil)
+		return fmt.Errorf("%w: queue of connection %s is full", ErrNotDelivered, st.id)
+	}
+}
+
+// sendNotifications writes queued notifications to the connection until
+// it closes.
+func (s *Server) sendNotifications(ctx context.Context, st *connState) {
+	for {
+		select {
+		case <-st.done:
+			return
+		case <-st.queued:
+			s.flushNotifications(ctx, st)
+		}
+	}
+}
+
+// writeNotification writes a queued notification, dead-lettering it if
+// the write fails.
+func (s *Server) writeNotification(ctx context.Context, st *connState, q queuedNotification) {
+	if q.related != nil {
+		ctx = transport.ContextWithRelatedRequest(ctx, *q.related)
+	}
+	msg := q.msg
+	n, err := st.write(ctx, msg)
+	s.metrics.bytesWritten.With(st.peer.Transport).Add(float64(n))
+	switch {
+	case err == nil:
+		st.touch(s.clock.Now())
+	case s.disconnected(st, msg, err):
+		s.deadLetter(st.id, st.peer.Transport, msg, DeadLetterDisconnected, nil)
+	default:
+		s.deadLetter(st.id, st.peer.Transport, msg, DeadLetterWriteFailed, err)
+	}
+}
+
+// flushNotifications writes the notifications already queued for st, so
+// that those a handler sent, such as progress, precede its response.
+func (s *Server) flushNotifications(ctx context.Context, st *connState) {
+	st.sendMu.Lock()
+	defer st.sendMu.Unlock()
+	for {
+		select {
+		case q := <-st.queue:
+			s.writeNotification(ctx, st, q)
+		default:
+			return
+		}
+	}
+}
+
+// closeQueue stops accepting notifications for st and dead-letters the
+// ones still waiting.
+func (s *Server) closeQueue(st *connState) {
+	st.queueMu.Lock()
+	st.closed = true
+	close(st.done)
+	st.queueMu.Unlock()
+	for {
+		select {
+		case q := <-st.queue:
+			s.deadLetter(st.id, st.peer.Transport, q.msg, DeadLetterConnectionClosed, nil)
+		default:
+			return
+		}
+	}
+}
+
+// notificationParams returns the raw params of msg for a dead letter.
+func notificationParams(msg *protocol.Message) json.RawMessage {
+	if len(msg.Params) == 0 {
+		return nil
+	}
+	return append(json.RawMessage(nil), msg.Params...)
+}
+
+// Notifier sends the notifications of a server. It is obtained from
+// Server.Notifier; handlers notify their own client with
+// runtime.Context.Notify instead.
+type Notifier struct {
+	s *Server
+}
+
+// Notifier returns the notifier of s.
+func (s *Server) Notifier() *Notifier { return &Notifier{s: s} }
+
+// Notify queues a notification for one connection, as Server.Notify.
+func (n *Notifier) Notify(connID, method string, params interface{}) error {
+	return n.s.Notify(connID, method, params)
+}
+
+// Broadcast queues a notification for every connection, as
+// Server.Broadcast.
+func (n *Notifier) Broadcast(method string, params interface{}) (int, error) {
+	return n.s.Broadcast(method, params)
+}
+
+// ToolsListChanged tells every client that the list of tools changed.
+func (n *Notifier) ToolsListChanged() (int, error) {
+	return n.s.Broadcast(protocol.MethodToolsListChanged, nil)
+}
+
+// ResourcesListChanged tells every client that the list of resources
+// changed.
+func (n *Notifier) ResourcesListChanged() (int, error) {
+	return n.s.Broadcast(protocol.MethodResourcesListChanged, nil)
+}
+
+// ResourceUpdated tells the clients subscribed to uri that the resource
+// changed, as Server.NotifyResourceUpdated.
+func (n *Notifier) ResourceUpdated(uri string) (int, error) {
+	return n.s.NotifyResourceUpdated(uri)
+}
+
+// PromptsListChanged tells every client that the list of prompts changed.
+func (n *Notifier) PromptsListChanged() (int, error) {
+	return n.s.Broadcast(protocol.MethodPromptsListChanged, nil)
+}
+
+// announceChange broadcasts the list_changed notification of a change to
+// the registry.
+func (s *Server) announceChange(kind registry.ChangeKind) {
+	n := s.Notifier()
+	switch kind {
+	case registry.ToolsChanged:
+		n.ToolsListChanged()
+	case registry.ResourcesChanged:
+		n.ResourcesListChanged()
+	case registry.PromptsChanged:
+		n.PromptsListChanged()
+	}
+}
+
+// connNotifier is the runtime.Notifier of one connection. Handlers get
+// one naming the request they handle.
+type connNotifier struct {
+	s       *Server
+	st      *connState
+	related *protocol.ID
+}
+
+func (n connNotifier) Notify(method string, params interface{}) error {
+	msg, err := protocol.NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return n.s.enqueue(n.st, msg, n.related)
+}
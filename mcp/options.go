@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/hyperleex/zenmcp/acl"
+	"github.com/hyperleex/zenmcp/blob"
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/idgen"
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/schema"
+	"github.com/hyperleex/zenmcp/tenant"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/http"
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithName sets the server name reported in initialize.
+func WithName(name string) Option {
+	return func(s *Server) { s.info.Name = name }
+}
+
+// WithVersion sets the server version reported in initialize. It
+// defaults to the version of the binary; see BuildInfo.Version.
+func WithVersion(version string) Option {
+	return func(s *Server) { s.info.Version = version }
+}
+
+// WithInstructions sets the usage instructions returned from initialize.
+func WithInstructions(instructions string) Option {
+	return func(s *Server) { s.instructions = instructions }
+}
+
+// WithLogger sets the logger used by the server and its router.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) { s.logger = logger }
+}
+
+// WithClock makes the server take the time from c instead of the system
+// clock, for connection timestamps, the handshake timeout, dead letters,
+// events and memory sampling. Tests pass a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) { s.clock = c }
+}
+
+// WithIDGenerator sets the generator of session IDs, of the IDs of
+// requests sent to clients and of the IDs created by packages installed
+// on the server, such as jobs. It defaults
+// to idgen.Default; tests pass idgen.NewSequential for predictable IDs.
+func WithIDGenerator(g idgen.Generator) Option {
+	return func(s *Server) { s.ids = g }
+}
+
+// WithTransport adds a transport to serve on. It may be given several times.
+func WithTransport(t transport.Transport) Option {
+	return func(s *Server) { s.transports = append(s.transports, t) }
+}
+
+// WithHTTPTransport adds an HTTP transport listening on addr, such as
+// ":8080", configured by opts.
+func WithHTTPTransport(addr string, opts ...http.Option) Option {
+	return WithTransport(http.New(addr, opts...))
+}
+
+// WithRegistry makes the server serve an existing registry instead of
+// creating an empty one.
+func WithRegistry(reg *registry.Registry) Option {
+	return func(s *Server) { s.registry = reg }
+}
+
+// WithBlobStore makes store available to handlers so that blob.Ref
+// arguments can be opened. Pass the same store to the HTTP transport's
+// uploads endpoint.
+func WithBlobStore(store blob.Store) Option {
+	return func(s *Server) { s.blobs = store }
+}
+
+// WithMetrics records server metrics in reg instead of metrics.Default.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *Server) { s.metricsRegistry = reg }
+}
+
+// WithHandshakeTimeout closes connections that have not completed the
+// initialize handshake within d. Zero, the default, disables the timeout.
+func WithHandshakeTimeout(d time.Duration) Option {
+	return func(s *Server) { s.handshakeTimeout = d }
+}
+
+// WithIdleTimeout closes connections on which no message was read or
+// written for d, after telling the client with
+// notifications/zenmcp/goodbye. A connection handling a request is never
+// idle, and neither are request-scoped connections such as plain HTTP
+// requests. Zero, the default, disables the timeout.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) { s.idleTimeout = d }
+}
+
+// WithSlowWriteThreshold reports a transport as slow, in TransportStats,
+// the zenmcp_transport_slow metric and a warning in the log, while writing
+// a message to its clients takes longer than d on average. It defaults to
+// DefaultSlowWriteThreshold; a negative d disables the detection.
+func WithSlowWriteThreshold(d time.Duration) Option {
+	return func(s *Server) { s.slowWriteThreshold = d }
+}
+
+// WithFramePreviews logs, at debug level, the first n bytes of every frame
+// the server could not parse or route, to help diagnose incompatible
+// clients. Binary data is escaped. Frames that fail to route are logged as
+// re-encoded from the decoded message. Zero, the default, logs no frames.
+func WithFramePreviews(n int) Option {
+	return func(s *Server) { s.framePreview = n }
+}
+
+// WithMaxConcurrentRequests lets each connection handle up to n requests
+// at a time, so that one slow tool call does not hold up the others. The
+// default of 1 handles a connection's messages one after the other.
+//
+// Messages are still taken up in the order they arrive, and responses may
+// then complete in any order. Notifications from the client are handled
+// as they arrive, and initialize waits for the requests before it to
+// finish. Writes to the connection are serialized.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(s *Server) { s.maxConcurrentRequests = n }
+}
+
+// WithArgumentLimits bounds the depth, array lengths, object sizes and
+// string lengths of tool call arguments. Schema keywords of registered
+// tools take precedence; unset limits use schema.DefaultLimits.
+func WithArgumentLimits(l schema.Limits) Option {
+	return func(s *Server) { s.argumentLimits = l }
+}
+
+// WithUseNumber decodes the numbers in every tool's arguments as
+// json.Number wherever they land in an interface{}, so large integer IDs
+// survive decoding intact. Tools opt in individually with
+// registry.ToolDescriptor.UseNumber.
+func WithUseNumber() Option {
+	return func(s *Server) { s.useNumber = true }
+}
+
+// WithoutArgumentValidation stops the server from checking tool call
+// arguments against the tools' input schemas before their handlers run.
+// Size limits still apply.
+func WithoutArgumentValidation() Option {
+	return func(s *Server) { s.skipArgumentValidation = true }
+}
+
+// WithoutLogging stops the server from offering logging/setLevel and
+// advertising the logging capability. Messages tools send with
+// runtime.Context.Log are then dropped.
+func WithoutLogging() Option {
+	return func(s *Server) { s.disableLogging = true }
+}
+
+// WithPanicStacks attaches the panic value and stack trace to the
+// InternalError a client receives when a handler panics. Panics are
+// always recovered and logged; by default the client only learns that the
+// handler failed.
+func WithPanicStacks() Option {
+	return func(s *Server) { s.panicStacks = true }
+}
+
+// WithPageSize splits tools/list, resources/list, resources/templates/list
+// and prompts/list responses into pages of at most n items, linked by
+// opaque cursors, so that servers with large catalogs do not send them
+// whole. By default every list is sent in one response.
+func WithPageSize(n int) Option {
+	return func(s *Server) { s.pageSize = n }
+}
+
+// WithToolProfiles configures named tool filters that clients select
+// during initialize through the zenmcp/profile experimental capability.
+// A session restricted to a profile only sees and calls matching tools.
+func WithToolProfiles(profiles map[string]protocol.ToolFilter) Option {
+	return func(s *Server) { s.toolProfiles = profiles }
+}
+
+// WithResourceACL restricts resources to the principals a admits, as
+// established by the transports' authentication.
+func WithResourceACL(a *acl.ResourceACL) Option {
+	return func(s *Server) { s.resourceACL = a }
+}
+
+// WithTenants serves each tenant in set from its own registry. Requests
+// that name no tenant are served from the server's registry, which should
+// then hold only what every client may see. The tenants' tool hooks run
+// alongside the server's own in Serve and Shutdown.
+func WithTenants(set *tenant.Set) Option {
+	return func(s *Server) { s.tenants = set }
+}
+
+// WithSharedSchemaDefs makes RegisterToolTyped define every named struct
+// type of an argument type under $defs. Tools whose arguments share types
+// then define them identically, and tools/list sends each shared
+// definition once to clients announcing the zenmcp/sharedDefs capability.
+func WithSharedSchemaDefs() Option {
+	return func(s *Server) { s.schemaOptions.DefineAll = true }
+}
+
+// WithListChanged announces that the server's tools, resources and prompts
+// may change while it serves, setting listChanged in the capabilities it
+// advertises. The server then broadcasts the matching
+// notifications/*/list_changed whenever an entry is registered or
+// unregistered.
+func WithListChanged() Option {
+	return func(s *Server) { s.listChanged = true }
+}
@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// connRequester is the runtime.Requester of one connection. Handlers get
+// one naming the request they handle, so that transports able to tell
+// requests apart send theirs along with its response.
+type connRequester struct {
+	s       *Server
+	st      *connState
+	related *protocol.ID
+}
+
+// Request sends a request to the client of the connection, with an ID
+// from the server's IDGenerator, and waits for its answer. When ctx is
+// done first, the client is told with notifications/cancelled.
+func (r connRequester) Request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id := protocol.NewStringID(r.s.ids.NewID())
+	msg, err := protocol.NewRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+	answer := r.st.await(id)
+	defer r.st.forget(id)
+	if err := r.s.enqueue(r.st, msg, r.related); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-answer:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		cancelled, err := protocol.NewNotification(protocol.MethodCancelled, protocol.CancelledParams{RequestID: id, Reason: context.Cause(ctx).Error()})
+		if err == nil {
+			r.s.enqueue(r.st, cancelled, r.related)
+		}
+		return nil, ctx.Err()
+	case <-r.st.done:
+		return nil, fmt.Errorf("mcp: connection %s closed awaiting the answer to %s: %w", r.st.id, method, transport.ErrClosed)
+	}
+}
+
+// await registers a request sent to the client of st with the given ID
+// and returns the channel its answer is delivered on.
+func (st *connState) await(id protocol.ID) <-chan *protocol.Message {
+	ch := make(chan *protocol.Message, 1)
+	st.callMu.Lock()
+	if st.calls == nil {
+		st.calls = make(map[protocol.ID]chan *protocol.Message)
+	}
+	st.calls[id] = ch
+	st.callMu.Unlock()
+	return ch
+}
+
+// forget unregisters the request with the given ID.
+func (st *connState) forget(id protocol.ID) {
+	st.callMu.Lock()
+	delete(st.calls, id)
+	st.callMu.Unlock()
+}
+
+// answered delivers msg, a response read from the client of st, to the
+// request it answers, and reports whether there was one.
+func (st *connState) answered(msg *protocol.Message) bool {
+	st.callMu.Lock()
+	ch, ok := st.calls[*msg.ID]
+	delete(st.calls, *msg.ID)
+	st.callMu.Unlock()
+	if ok {
+		ch <- msg
+	}
+	return ok
+}
@@ -0,0 +1,574 @@
+// Package mcp is the entry point for building MCP servers with zenmcp.
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/acl"
+	"github.com/hyperleex/zenmcp/blob"
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/idgen"
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/schema"
+	"github.com/hyperleex/zenmcp/tenant"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown has been called.
+var ErrServerClosed = errors.New("mcp: server closed")
+
+// Server serves a registry of tools, resources and prompts over one or more
+// transports.
+type Server struct {
+	info         protocol.Implementation
+	instructions string
+	logger       *slog.Logger
+	clock        clock.Clock
+	ids          idgen.Generator
+	registry     *registry.Registry
+	transports   []transport.Transport
+	// transportStats holds the statistics of transports[i] at index i.
+	transportStats []*transportStats
+	router         *runtime.Router
+	sessions       *runtime.SessionManager
+	blobs          blob.Store
+
+	metricsRegistry *metrics.Registry
+	metrics         *serverMetrics
+	onEvent         EventHandler
+	memoryConfig    *MemoryConfig
+	memory          *memoryGuard
+	healthConfig    HealthConfig
+	health          *healthMonitor
+
+	handshakeTimeout       time.Duration
+	idleTimeout            time.Duration
+	slowWriteThreshold     time.Duration
+	framePreview           int
+	argumentLimits         schema.Limits
+	useNumber              bool
+	skipArgumentValidation bool
+	maxConcurrentRequests  int
+	panicStacks            bool
+	pageSize               int
+	schemaOptions          schema.Options
+	toolProfiles           map[string]protocol.ToolFilter
+	resourceACL            *acl.ResourceACL
+	tenants                *tenant.Set
+	buildResource          bool
+	listChanged            bool
+	disableLogging         bool
+
+	notificationQueue  int
+	deadLetterCapacity int
+	deadLetters        *deadLetterBuffer
+
+	mu        sync.Mutex
+	listening bool
+	closing   bool
+	connSeq   int64
+	conns     map[transport.Connection]*connState
+	inflight  sync.WaitGroup
+	done      chan struct{}
+	cancel    context.CancelFunc
+}
+
+// NewServer returns a server configured by opts.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		info:  protocol.Implementation{Name: "zenmcp"},
+		conns: make(map[transport.Connection]*connState),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+	if s.info.Version == "" {
+		s.info.Version = ReadBuildInfo().Version()
+	}
+	s.clock = clock.Or(s.clock)
+	s.ids = idgen.Or(s.ids)
+	s.sessions = runtime.NewSessionManager(s.ids)
+	if s.registry == nil {
+		s.registry = registry.New()
+	}
+	if s.metricsRegistry == nil {
+		s.metricsRegistry = metrics.Default
+	}
+	s.metrics = newServerMetrics(s.metricsRegistry)
+	if s.slowWriteThreshold == 0 {
+		s.slowWriteThreshold = DefaultSlowWriteThreshold
+	}
+	for i, t := range s.transports {
+		s.transportStats = append(s.transportStats, newTransportStats(i, t, s))
+	}
+	if s.notificationQueue <= 0 {
+		s.notificationQueue = defaultNotificationQueue
+	}
+	s.deadLetters = newDeadLetterBuffer(s.deadLetterCapacity, s.metricsRegistry)
+	if s.memoryConfig != nil {
+		s.memory = newMemoryGuard(*s.memoryConfig, s.metricsRegistry)
+	}
+	s.health = newHealthMonitor(s.healthConfig, s.metricsRegistry)
+	s.router = runtime.NewRouter(s.registry, runtime.Config{
+		Info:                   s.info,
+		Instructions:           s.instructions,
+		ArgumentLimits:         s.argumentLimits,
+		UseNumber:              s.useNumber,
+		SkipArgumentValidation: s.skipArgumentValidation,
+		ListChanged:            s.listChanged,
+		DisableLogging:         s.disableLogging,
+		PanicStacks:            s.panicStacks,
+		PageSize:               s.pageSize,
+		ToolProfiles:           s.toolProfiles,
+		ResourceACL:            s.resourceACL,
+		Tenants:                s.tenants,
+		Logger:                 s.logger,
+	})
+	if s.listChanged {
+		s.registry.OnChange(s.announceChange)
+	}
+	if s.buildResource {
+		if err := s.registerBuildResource(); err != nil {
+			s.logger.Error("registering the build info resource", "error", err)
+		}
+	}
+	return s
+}
+
+// Registry returns the registry served by s.
+func (s *Server) Registry() *registry.Registry { return s.registry }
+
+// Router returns the router dispatching requests for s.
+func (s *Server) Router() *runtime.Router { return s.router }
+
+// Sessions returns the manager of the sessions of the open connections.
+func (s *Server) Sessions() *runtime.SessionManager { return s.sessions }
+
+// Clock returns the clock the server takes the time from. Packages
+// installed on the server default to it.
+func (s *Server) Clock() clock.Clock { return s.clock }
+
+// IDGenerator returns the generator of session IDs and of the IDs of
+// requests sent to clients. Packages installed on the server use it for
+// the IDs they create too.
+func (s *Server) IDGenerator() idgen.Generator { return s.ids }
+
+// Metrics returns the registry the server records metrics in.
+func (s *Server) Metrics() *metrics.Registry { return s.metricsRegistry }
+
+// Addr returns the address of the first transport listening on a network
+// address, or nil while Serve has not yet started listening. Transports
+// given ":0" or the like listen on a port picked by the system, which Addr
+// tells.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	listening := s.listening
+	s.mu.Unlock()
+	if !listening {
+		return nil
+	}
+	for _, t := range s.transports {
+		if a, ok := t.(interface{ Addr() net.Addr }); ok {
+			if addr := a.Addr(); addr != nil {
+				return addr
+			}
+		}
+	}
+	return nil
+}
+
+// Serve initializes registered tools, starts every transport and handles
+// connections until ctx is cancelled, Shutdown is called or all transports
+// have stopped accepting. Cancelling ctx shuts the server down as if by
+// Shutdown with a background context.
+func (s *Server) Serve(ctx context.Context) error {
+	if len(s.transports) == 0 {
+		return errors.New("mcp: no transports configured")
+	}
+	if err := s.registry.Init(ctx); err != nil {
+		return err
+	}
+	if s.tenants != nil {
+		if err := s.tenants.Init(ctx); err != nil {
+			return err
+		}
+	}
+
+	base, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if s.blobs != nil {
+		base = blob.NewContext(base, s.blobs)
+	}
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	if s.memory != nil {
+		go s.memory.run(base, s)
+	}
+	if s.idleTimeout > 0 {
+		go s.reapIdle(base)
+	}
+	go s.monitorHealth(base)
+
+	for i, t := range s.transports {
+		if fr, ok := t.(transport.FrameReporter); ok {
+			ts := s.transportStats[i]
+			fr.ReportMalformedFrames(max(s.framePreview, 0), func(peer transport.Peer, fe *transport.FrameError) {
+				ts.frameError()
+				s.logMalformedFrame(peer, fe)
+			})
+		}
+		if err := t.Listen(ctx); err != nil {
+			s.closeTransports()
+			cancel()
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.listening = true
+	s.mu.Unlock()
+
+	var accepting sync.WaitGroup
+	for i, t := range s.transports {
+		accepting.Add(1)
+		go func(t transport.Transport, ts *transportStats) {
+			defer accepting.Done()
+			s.acceptLoop(base, t, ts)
+		}(t, s.transportStats[i])
+	}
+	stopped := make(chan struct{})
+	go func() {
+		accepting.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return s.Shutdown(context.Background())
+	case <-s.done:
+		return ErrServerClosed
+	case <-stopped:
+		return s.Shutdown(context.Background())
+	}
+}
+
+// Shutdown stops accepting connections, tells connected clients with
+// notifications/zenmcp/goodbye that the server is going away, waits for
+// in-flight requests to finish or ctx to expire, closes all connections
+// and runs the tools' Shutdown hooks.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closing = true
+	close(s.done)
+	s.mu.Unlock()
+
+	s.closeTransports()
+	s.goodbyeAll(ctx)
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	err = errors.Join(err, s.registry.Shutdown(ctx))
+	if s.tenants != nil {
+		err = errors.Join(err, s.tenants.Shutdown(ctx))
+	}
+	return err
+}
+
+func (s *Server) closeTransports() {
+	for _, t := range s.transports {
+		if err := t.Close(); err != nil {
+			s.logger.Warn("closing transport", "error", err)
+		}
+	}
+}
+
+func (s *Server) acceptLoop(ctx context.Context, t transport.Transport, ts *transportStats) {
+	for {
+		conn, err := t.Accept(ctx)
+		if err != nil {
+			if !s.isClosing() && !errors.Is(err, transport.ErrClosed) && ctx.Err() == nil {
+				ts.acceptError()
+				s.logger.Error("accepting connection", "transport", ts.name, "error", err)
+			}
+			return
+		}
+		st := s.track(conn, ts)
+		if st == nil {
+			conn.Close()
+			return
+		}
+		go s.handleConnection(ctx, conn, st)
+	}
+}
+
+func (s *Server) handleConnection(ctx context.Context, conn transport.Connection, st *connState) {
+	defer s.untrack(conn)
+	defer s.closeQueue(st)
+	defer conn.Close()
+	defer func() {
+		if n := st.dropped.Load(); n > 0 {
+			s.logger.Debug("writes dropped after disconnect", "connection", st.id, "count", n)
+		}
+	}()
+
+	ctx = transport.ContextWithPeer(ctx, st.peer)
+	ctx = context.WithValue(ctx, connIDKey{}, st.id)
+	ctx = runtime.WithSession(ctx, st.session)
+	ctx = runtime.WithNotifier(ctx, connNotifier{s: s, st: st})
+	if c, ok := conn.(transport.Compressor); ok && len(c.Compressions()) > 0 {
+		ctx = runtime.WithCompressor(ctx, c)
+	}
+	s.logger.Debug("connection opened", "id", st.id, "peer", st.peer)
+	bytesWritten := s.metrics.bytesWritten.With(st.peer.Transport)
+	handshakeDone := s.startHandshakeTimer(conn, st)
+	defer handshakeDone()
+	go s.sendNotifications(ctx, st)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	reads := s.readMessages(ctx, st, stop)
+
+	// Requests handled concurrently hold a slot in workers. A failed
+	// write by any of them closes failed, ending the connection once the
+	// others are done.
+	workers := make(chan struct{}, max(s.maxConcurrentRequests, 1))
+	var handling sync.WaitGroup
+	defer handling.Wait()
+	failed := make(chan struct{})
+	var failOnce sync.Once
+
+	for {
+		var in readResult
+		select {
+		case in = <-reads:
+		case <-failed:
+			return
+		}
+		msg, err := in.msg, in.err
+		if err != nil {
+			var rpcErr *protocol.Error
+			if errors.As(err, &rpcErr) {
+				var fe *transport.FrameError
+				if errors.As(err, &fe) {
+					s.logMalformedFrame(st.peer, fe)
+				}
+				resp := protocol.NewErrorResponse(nil, rpcErr)
+				n, werr := st.write(ctx, resp)
+				bytesWritten.Add(float64(n))
+				if werr != nil {
+					if !s.disconnected(st, resp, werr) {
+						s.logger.Warn("writing to connection", "error", werr)
+					}
+					return
+				}
+				continue
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, transport.ErrClosed) && !s.isClosing() {
+				s.logger.Warn("reading from connection", "error", err)
+			}
+			return
+		}
+		if !s.begin() {
+			// The server is shutting down, and the connection closes
+			// with it.
+			if rs, ok := conn.(transport.RequestScoped); !ok || !rs.RequestScoped() {
+				s.sayGoodbye(ctx, st, shutdownGoodbye)
+			}
+			return
+		}
+		st.busy.Add(1)
+		reqCtx, done := st.beginRequest(ctx, msg)
+		if cap(workers) == 1 || !msg.IsRequest() || msg.Method == protocol.MethodInitialize {
+			// Notifications are handled as they arrive, and initialize
+			// once every earlier request is done.
+			if msg.Method == protocol.MethodInitialize {
+				handling.Wait()
+			}
+			if !s.handleMessage(ctx, st, in, reqCtx, done, handshakeDone) {
+				return
+			}
+			continue
+		}
+		select {
+		case workers <- struct{}{}:
+		case <-failed:
+			done()
+			st.busy.Add(-1)
+			s.inflight.Done()
+			return
+		}
+		handling.Add(1)
+		go func() {
+			defer handling.Done()
+			defer func() { <-workers }()
+			if !s.handleMessage(ctx, st, in, reqCtx, done, handshakeDone) {
+				failOnce.Do(func() { close(failed) })
+			}
+		}()
+	}
+}
+
+// handleMessage dispatches the message in, registered with Server.begin
+// and counted in st.busy, in the request context reqCtx, which done ends.
+// It writes the response with the connection context ctx and reports false
+// when that failed and the connection should close.
+func (s *Server) handleMessage(ctx context.Context, st *connState, in readResult, reqCtx context.Context, done, handshakeDone func()) bool {
+	msg, reqBytes := in.msg, in.size
+	info := &runtime.RequestInfo{}
+	s.health.observeLag(s.clock.Now().Sub(in.at))
+	if msg.IsRequest() {
+		reqCtx = runtime.WithNotifier(reqCtx, connNotifier{s: s, st: st, related: msg.ID})
+		reqCtx = runtime.WithRequester(reqCtx, connRequester{s: s, st: st, related: msg.ID})
+	}
+	resp := s.dispatch(runtime.WithRequestInfo(reqCtx, info), msg, reqBytes)
+	done()
+	if resp != nil && resp.Error != nil {
+		s.logUnroutedFrame(st, msg, resp.Error)
+	}
+	if msg.Method == protocol.MethodInitialize && resp != nil && resp.Error == nil {
+		st.handshaken.Store(true)
+		handshakeDone()
+	}
+	var respBytes int64
+	var err error
+	if resp != nil {
+		s.flushNotifications(ctx, st)
+		respBytes, err = st.write(ctx, resp)
+	}
+	st.touch(s.clock.Now())
+	st.busy.Add(-1)
+	s.inflight.Done()
+	s.metrics.bytesWritten.With(st.peer.Transport).Add(float64(respBytes))
+	if msg.IsRequest() {
+		st.requests.Add(1)
+		s.metrics.requestBytes.With(msg.Method, info.Target).Observe(float64(reqBytes))
+		s.metrics.responseBytes.With(msg.Method, info.Target).Observe(float64(respBytes))
+		if info.Deprecated {
+			s.metrics.deprecatedCalls.With(info.Target).Inc()
+		}
+	}
+	if err != nil {
+		if !s.disconnected(st, msg, err) {
+			s.logger.Warn("writing to connection", "error", err)
+		}
+		return false
+	}
+	return true
+}
+
+// startHandshakeTimer arranges for conn to be closed if initialize has not
+// succeeded within the handshake timeout. The returned function disarms
+// the timer. Request-scoped connections are exempt: their handshake, if
+// any, happened on a different connection.
+func (s *Server) startHandshakeTimer(conn transport.Connection, st *connState) func() {
+	d := s.handshakeTimeout
+	if rs, ok := conn.(transport.RequestScoped); d <= 0 || ok && rs.RequestScoped() {
+		return func() {}
+	}
+	timer := s.clock.AfterFunc(d, func() {
+		s.logger.Warn("closing connection: initialize not completed in time",
+			"id", st.id, "peer", st.peer, "timeout", d)
+		s.metrics.handshakeTimeouts.With(st.peer.Transport).Inc()
+		conn.Close()
+	})
+	return func() { timer.Stop() }
+}
+
+// dispatch routes msg unless load shedding rejects it.
+func (s *Server) dispatch(ctx context.Context, msg *protocol.Message, size int64) *protocol.Message {
+	if s.memory != nil && msg.IsRequest() {
+		release, rpcErr := s.memory.admit(size)
+		if rpcErr != nil {
+			return protocol.NewErrorResponse(msg.ID, rpcErr)
+		}
+		defer release()
+	}
+	return s.router.Dispatch(ctx, msg)
+}
+
+// begin registers an in-flight request unless the server is shutting down.
+func (s *Server) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+func (s *Server) track(conn transport.Connection, ts *transportStats) *connState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return nil
+	}
+	s.connSeq++
+	var id string
+	if si, ok := conn.(transport.SessionIdentified); ok {
+		id = si.SessionID()
+	}
+	st := newConnState(s.connSeq, conn, s.sessions.Open(id), s.notificationQueue, s.clock.Now())
+	st.transport = ts
+	s.conns[conn] = st
+	ts.accept()
+	return st
+}
+
+func (s *Server) untrack(conn transport.Connection) {
+	s.mu.Lock()
+	st := s.conns[conn]
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	if st != nil {
+		s.sessions.Close(st.session)
+		st.transport.open.Add(-1)
+		rs, ok := conn.(transport.RequestScoped)
+		if !st.handshaken.Load() && (!ok || !rs.RequestScoped()) {
+			st.transport.handshakeFailed()
+		}
+	}
+}
+
+func (s *Server) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
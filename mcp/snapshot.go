@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+// Snapshot is the catalog state of a server: its registry and the tools
+// turned off with the router's SetDisabledTools.
+type Snapshot struct {
+	Registry      *registry.Snapshot `json:"registry"`
+	DisabledTools []string           `json:"disabledTools,omitempty"`
+}
+
+// Snapshot returns the catalog state of s.
+func (s *Server) Snapshot() *Snapshot {
+	return &Snapshot{
+		Registry:      s.registry.Snapshot(),
+		DisabledTools: s.router.DisabledTools(),
+	}
+}
+
+// Restore brings back the catalog state in snap: it restores the registry
+// as registry.Registry.Restore does, building the entries configured at
+// run time with f, and disables the tools that were disabled. Call it
+// before Serve, after registering the server's built-in tools.
+func (s *Server) Restore(snap *Snapshot, f registry.Factories) error {
+	if snap.Registry == nil {
+		return errors.New("mcp: snapshot has no registry")
+	}
+	err := s.registry.Restore(snap.Registry, f)
+	s.router.SetDisabledTools(snap.DisabledTools)
+	return err
+}
+
+// SaveSnapshot writes the catalog state of s to the file at path. The file
+// is replaced atomically, so a crash while saving leaves the previous
+// snapshot intact.
+func (s *Server) SaveSnapshot(path string) error {
+	data, err := json.MarshalIndent(s.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RestoreSnapshot restores the catalog state saved by SaveSnapshot at
+// path, as Restore does. A missing file is not an error: there is nothing
+// to restore on the first start.
+func (s *Server) RestoreSnapshot(path string, f registry.Factories) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("mcp: snapshot %s: %w", path, err)
+	}
+	return s.Restore(&snap, f)
+}
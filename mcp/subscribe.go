@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// unsubscribeTimeout bounds the resources/unsubscribe request sent once
+// the context of a subscription is done.
+const unsubscribeTimeout = 10 * time.Second
+
+// SubscribeResource subscribes to changes of the resource at uri and
+// returns the channel on which the server's
+// notifications/resources/updated for it arrive. ctx bounds both the
+// subscribe request and the subscription: once it is done, the client
+// sends resources/unsubscribe, unless other subscriptions to uri remain,
+// and closes the channel. The channel is also closed by
+// UnsubscribeResource and when the client closes.
+//
+// The channel holds one update. Updates arriving while it is full are
+// dropped, since the waiting one already tells that the resource changed;
+// read it again to see the latest contents.
+func (c *Client) SubscribeResource(ctx context.Context, uri string) (<-chan protocol.ResourceUpdatedParams, error) {
+	ch := make(chan protocol.ResourceUpdatedParams, 1)
+	// Registered first, so that no update sent right after the server
+	// accepts the subscription is missed.
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	if c.subs == nil {
+		c.subs = make(map[string][]chan protocol.ResourceUpdatedParams)
+	}
+	c.subs[uri] = append(c.subs[uri], ch)
+	c.mu.Unlock()
+
+	if err := c.Call(ctx, protocol.MethodResourcesSubscribe, protocol.SubscribeParams{URI: uri}, nil); err != nil {
+		c.dropSubscription(uri, ch)
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.done:
+			return
+		}
+		if !c.dropSubscription(uri, ch) {
+			return
+		}
+		uctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unsubscribeTimeout)
+		defer cancel()
+		if err := c.Call(uctx, protocol.MethodResourcesUnsubscribe, protocol.SubscribeParams{URI: uri}, nil); err != nil {
+			c.logger.Debug("mcp client: unsubscribing", "uri", uri, "error", err)
+		}
+	}()
+	return ch, nil
+}
+
+// UnsubscribeResource cancels the subscriptions to uri made with
+// SubscribeResource, closing their channels.
+func (c *Client) UnsubscribeResource(ctx context.Context, uri string) error {
+	c.mu.Lock()
+	for _, ch := range c.subs[uri] {
+		close(ch)
+	}
+	delete(c.subs, uri)
+	c.mu.Unlock()
+	return c.Call(ctx, protocol.MethodResourcesUnsubscribe, protocol.SubscribeParams{URI: uri}, nil)
+}
+
+// dropSubscription closes ch and forgets it. It reports whether ch was
+// the last subscription to uri, so that the server should be told to stop
+// announcing its changes; false also when ch was already closed.
+func (c *Client) dropSubscription(uri string, ch chan protocol.ResourceUpdatedParams) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subs := c.subs[uri]
+	for i, s := range subs {
+		if s == ch {
+			close(ch)
+			subs = append(subs[:i], subs[i+1:]...)
+			if len(subs) == 0 {
+				delete(c.subs, uri)
+				return c.err == nil
+			}
+			c.subs[uri] = subs
+			return false
+		}
+	}
+	return false
+}
+
+// resourceUpdated passes a notifications/resources/updated on to the
+// subscriptions to its resource.
+func (c *Client) resourceUpdated(params json.RawMessage) {
+	var p protocol.ResourceUpdatedParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		c.logger.Warn("mcp client: malformed resource update from server", "error", err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.subs[p.URI] {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// closeSubscriptions closes the channels of every subscription. c.mu is
+// held.
+func (c *Client) closeSubscriptions() {
+	for _, subs := range c.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	c.subs = nil
+}
@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// DefaultSlowWriteThreshold is the average write time beyond which a
+// transport is reported slow, unless set with WithSlowWriteThreshold.
+const DefaultSlowWriteThreshold = time.Second
+
+// TransportStats describes the traffic of one of a server's transports.
+// Comparing transports tells problems with the clients of one transport,
+// such as stdio clients framing messages wrongly, from problems with the
+// server itself, which show on every transport alike.
+type TransportStats struct {
+	// Index is the position of the transport among the server's
+	// transports, telling apart several transports of the same kind.
+	Index int `json:"index"`
+	// Name names the kind of transport, such as "stdio" or "http".
+	Name string `json:"name"`
+
+	// Accepted counts the connections accepted, and AcceptRate those of
+	// the last minute, per second.
+	Accepted   int64   `json:"accepted"`
+	AcceptRate float64 `json:"acceptRate"`
+	// AcceptErrors counts failures to accept a connection.
+	AcceptErrors int64 `json:"acceptErrors"`
+	// Open counts the connections currently open.
+	Open int64 `json:"open"`
+	// HandshakeFailures counts the connections that closed without
+	// completing initialize: it timed out, failed or never came.
+	// Request-scoped connections, which need no handshake, are not
+	// counted.
+	HandshakeFailures int64 `json:"handshakeFailures"`
+
+	// Messages counts the messages read, and FrameErrors the frames that
+	// could not be parsed. FrameErrorRate is the share of frames that
+	// could not.
+	Messages       int64   `json:"messages"`
+	FrameErrors    int64   `json:"frameErrors"`
+	FrameErrorRate float64 `json:"frameErrorRate"`
+
+	// WriteSeconds is the moving average of the time taken to write a
+	// message, which grows when clients read slowly. Slow is set while it
+	// exceeds the server's slow write threshold.
+	WriteSeconds float64 `json:"writeSeconds"`
+	Slow         bool    `json:"slow"`
+}
+
+// writeAlpha weighs every write in the moving average of write times.
+const writeAlpha = 0.05
+
+// transportStats accumulates the TransportStats of a transport.
+type transportStats struct {
+	index    int
+	name     string
+	instance string
+	clock    clock.Clock
+	logger   *slog.Logger
+	// slowWrite is the average write time beyond which the transport is
+	// slow, or zero.
+	slowWrite time.Duration
+
+	accepted          atomic.Int64
+	acceptErrors      atomic.Int64
+	open              atomic.Int64
+	handshakeFailures atomic.Int64
+	messages          atomic.Int64
+	frameErrors       atomic.Int64
+	writeSeconds      atomic.Uint64 // math.Float64bits of the moving average
+	slow              atomic.Bool
+
+	accepts rateWindow
+
+	metrics transportMetrics
+}
+
+// transportMetrics are the instruments of one transport.
+type transportMetrics struct {
+	accepted          metrics.Counter
+	acceptErrors      metrics.Counter
+	handshakeFailures metrics.Counter
+	frameErrors       metrics.Counter
+	writeSeconds      metrics.Histogram
+	slow              metrics.Gauge
+}
+
+func newTransportStats(index int, t transport.Transport, s *Server) *transportStats {
+	ts := &transportStats{
+		index:     index,
+		name:      transportName(t),
+		instance:  strconv.Itoa(index),
+		clock:     s.clock,
+		logger:    s.logger,
+		slowWrite: max(s.slowWriteThreshold, 0),
+	}
+	m := s.metrics
+	labels := []string{ts.name, ts.instance}
+	ts.metrics = transportMetrics{
+		accepted:          m.transportAccepted.With(labels...),
+		acceptErrors:      m.transportAcceptErrors.With(labels...),
+		handshakeFailures: m.transportHandshakeFailures.With(labels...),
+		frameErrors:       m.transportFrameErrors.With(labels...),
+		writeSeconds:      m.transportWriteSeconds.With(labels...),
+		slow:              m.transportSlow.With(labels...),
+	}
+	return ts
+}
+
+// transportName derives the kind of t from the package defining it:
+// "stdio" for *stdio.Transport.
+func transportName(t transport.Transport) string {
+	name := strings.TrimLeft(fmt.Sprintf("%T", t), "*")
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (ts *transportStats) accept() {
+	ts.accepted.Add(1)
+	ts.open.Add(1)
+	ts.accepts.add(ts.clock.Now())
+	ts.metrics.accepted.Inc()
+}
+
+func (ts *transportStats) acceptError() {
+	ts.acceptErrors.Add(1)
+	ts.metrics.acceptErrors.Inc()
+}
+
+func (ts *transportStats) handshakeFailed() {
+	ts.handshakeFailures.Add(1)
+	ts.metrics.handshakeFailures.Inc()
+}
+
+func (ts *transportStats) frameError() {
+	ts.frameErrors.Add(1)
+	ts.metrics.frameErrors.Inc()
+}
+
+// observeWrite records a write that took d, logging when it makes the
+// transport turn slow or recover.
+func (ts *transportStats) observeWrite(d time.Duration) {
+	secs := d.Seconds()
+	ts.metrics.writeSeconds.Observe(secs)
+	var avg float64
+	for {
+		old := ts.writeSeconds.Load()
+		avg = secs
+		if old != 0 {
+			avg = math.Float64frombits(old)*(1-writeAlpha) + secs*writeAlpha
+		}
+		if ts.writeSeconds.CompareAndSwap(old, math.Float64bits(avg)) {
+			break
+		}
+	}
+	if ts.slowWrite <= 0 {
+		return
+	}
+	slow := avg > ts.slowWrite.Seconds()
+	if ts.slow.Swap(slow) == slow {
+		return
+	}
+	avgTime := time.Duration(avg * float64(time.Second))
+	if slow {
+		ts.metrics.slow.Set(1)
+		ts.logger.Warn("transport slow: writes to clients take longer than the threshold",
+			"transport", ts.name, "instance", ts.index, "average", avgTime, "threshold", ts.slowWrite)
+	} else {
+		ts.metrics.slow.Set(0)
+		ts.logger.Info("transport no longer slow", "transport", ts.name, "instance", ts.index, "average", avgTime)
+	}
+}
+
+func (ts *transportStats) snapshot() TransportStats {
+	out := TransportStats{
+		Index:             ts.index,
+		Name:              ts.name,
+		Accepted:          ts.accepted.Load(),
+		AcceptRate:        ts.accepts.rate(ts.clock.Now()),
+		AcceptErrors:      ts.acceptErrors.Load(),
+		Open:              ts.open.Load(),
+		HandshakeFailures: ts.handshakeFailures.Load(),
+		Messages:          ts.messages.Load(),
+		FrameErrors:       ts.frameErrors.Load(),
+		WriteSeconds:      math.Float64frombits(ts.writeSeconds.Load()),
+		Slow:              ts.slow.Load(),
+	}
+	if frames := out.Messages + out.FrameErrors; frames > 0 {
+		out.FrameErrorRate = float64(out.FrameErrors) / float64(frames)
+	}
+	return out
+}
+
+// rateWindow counts events over the last minute, by second.
+type rateWindow struct {
+	mu     sync.Mutex
+	counts [60]int64
+	// secs holds the Unix second each slot of counts is counting.
+	secs [60]int64
+}
+
+func (w *rateWindow) add(now time.Time) {
+	sec := now.Unix()
+	i := sec % int64(len(w.counts))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.secs[i] != sec {
+		w.secs[i], w.counts[i] = sec, 0
+	}
+	w.counts[i]++
+}
+
+// rate returns the events per second over the minute up to now.
+func (w *rateWindow) rate(now time.Time) float64 {
+	cutoff := now.Unix() - int64(len(w.counts))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var n int64
+	for i, sec := range w.secs {
+		if sec > cutoff {
+			n += w.counts[i]
+		}
+	}
+	return float64(n) / float64(len(w.counts))
+}
+
+// TransportStats returns the statistics of the server's transports, in the
+// order they were configured.
+func (s *Server) TransportStats() []TransportStats {
+	out := make([]TransportStats, len(s.transportStats))
+	for i, ts := range s.transportStats {
+		out[i] = ts.snapshot()
+	}
+	return out
+}
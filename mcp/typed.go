@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/schema"
+	"github.com/hyperleex/zenmcp/validate"
+)
+
+// TypedToolHandler handles a tool call with arguments decoded into T.
+type TypedToolHandler[T any] func(ctx *runtime.Context, args T) (*protocol.ToolCallResult, error)
+
+// TypedResourceHandler reads a resource whose contents are T, returned to
+// the client as JSON.
+type TypedResourceHandler[T any] func(ctx *runtime.Context, uri string) (T, error)
+
+// RegisterToolTyped registers a tool whose arguments are decoded into T.
+// desc.InputSchema is generated from T unless already set; every other
+// descriptor field, including lifecycle hooks, is used as given.
+//
+// Properties missing from the call arguments are set to the defaults the
+// input schema declares, including those generated from `default` struct
+// tags, before the arguments are decoded.
+//
+// With desc.UseNumber or the server's WithUseNumber, numbers decoded into
+// interface{} fields of T are json.Number values.
+//
+// Decoded arguments are checked with validate.Value before the handler
+// runs, so T may implement validate.Validator or tag fields with named
+// validators. Failures are reported as InvalidParams errors whose data
+// lists the offending fields.
+//
+// With desc.FastPath, arguments of a flat struct type are decoded and
+// checked by a schema.Decoder compiled for T; other types are logged and
+// take the usual path.
+func RegisterToolTyped[T any](s *Server, desc registry.ToolDescriptor, handler TypedToolHandler[T]) error {
+	if desc.InputSchema == nil {
+		sch, err := schema.ForWith[T](s.schemaOptions)
+		if err != nil {
+			return fmt.Errorf("mcp: tool %q: %w", desc.Name, err)
+		}
+		desc.InputSchema = sch
+	}
+	desc.Handler = AdaptTyped(desc.InputSchema, desc.UseNumber, handler)
+	if desc.FastPath {
+		dec, err := schema.NewDecoder[T](desc.InputSchema)
+		if err != nil {
+			s.logger.Warn("tool arguments take the generic path", "tool", desc.Name, "error", err)
+		} else {
+			desc.Handler = adaptFast(dec, desc.InputSchema, desc.Handler, handler)
+			desc.ChecksArguments = true
+		}
+	}
+	return s.registry.RegisterTool(desc.Name, desc)
+}
+
+// AdaptTyped converts a typed handler into a registry.ToolHandler that
+// fills in the defaults declared by inputSchema, decodes and validates the
+// arguments as RegisterToolTyped describes, and calls h. inputSchema may be
+// nil.
+func AdaptTyped[T any](inputSchema map[string]interface{}, useNumber bool, h TypedToolHandler[T]) registry.ToolHandler {
+	withDefaults := schema.HasDefaults(inputSchema)
+	return func(ctx context.Context, raw json.RawMessage) (*protocol.ToolCallResult, error) {
+		if withDefaults {
+			filled, err := schema.ApplyDefaults(inputSchema, raw)
+			if err != nil {
+				return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+			}
+			raw = filled
+		}
+		rc := runtime.FromContext(ctx)
+		var args T
+		if err := runtime.DecodeJSON(raw, &args, useNumber || rc.UseNumber()); err != nil {
+			return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+		}
+		if err := validateValue(&args); err != nil {
+			return nil, err
+		}
+		return h(rc, args)
+	}
+}
+
+// validateValue checks decoded arguments with validate.Value.
+func validateValue(args interface{}) error {
+	err := validate.Value(args)
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		return protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), validationData{errs})
+	}
+	return err
+}
+
+// validationData is the InvalidParams data reported for arguments that
+// fail validation.
+type validationData struct {
+	Errors validate.Errors `json:"errors"`
+}
+
+// RegisterResourceTyped registers a resource whose contents are produced as
+// T and encoded as JSON. desc.MimeType defaults to application/json.
+func RegisterResourceTyped[T any](s *Server, desc registry.ResourceDescriptor, handler TypedResourceHandler[T]) error {
+	if desc.MimeType == "" {
+		desc.MimeType = "application/json"
+	}
+	mimeType := desc.MimeType
+	desc.Handler = func(ctx context.Context, uri string) (*protocol.ReadResourceResult, error) {
+		v, err := handler(runtime.FromContext(ctx), uri)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encode resource %q: %w", uri, err)
+		}
+		return &protocol.ReadResourceResult{
+			Contents: []protocol.ResourceContents{{URI: uri, MimeType: mimeType, Text: string(data)}},
+		}, nil
+	}
+	return s.registry.RegisterResource(desc.URI, desc)
+}
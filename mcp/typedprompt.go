@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/schema"
+	"github.com/hyperleex/zenmcp/validate"
+)
+
+// TypedPromptHandler renders a prompt from arguments decoded into T.
+type TypedPromptHandler[T any] func(ctx *runtime.Context, args T) (*protocol.GetPromptResult, error)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// RegisterPromptFunc registers a prompt whose arguments are decoded into
+// the struct T. Unless desc.Arguments is already set, the prompt's
+// arguments are derived from T's fields the way schema.Generate derives
+// properties: named after their json tag, described by a `description`
+// tag, and required unless the tag contains omitempty, the field is a
+// pointer or it has a `default` tag.
+//
+// Prompt arguments arrive as strings. String fields and fields whose type
+// implements encoding.TextUnmarshaler take them as they are; other fields,
+// such as numbers, booleans or lists, are decoded from the string as JSON.
+// Missing arguments take the value of their `default` tag. Decoded
+// arguments are checked with validate.Value before the handler runs, as
+// for RegisterToolTyped.
+func RegisterPromptFunc[T any](s *Server, desc registry.PromptDescriptor, handler TypedPromptHandler[T]) error {
+	fields, err := promptFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return fmt.Errorf("mcp: prompt %q: %w", desc.Name, err)
+	}
+	if desc.Arguments == nil {
+		for _, f := range fields {
+			desc.Arguments = append(desc.Arguments, registry.Argument{
+				Name:        f.name,
+				Description: f.description,
+				Required:    f.required,
+			})
+		}
+	}
+	desc.Handler = func(ctx context.Context, args map[string]string) (*protocol.GetPromptResult, error) {
+		raw, err := promptArguments(fields, args)
+		if err != nil {
+			return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+		}
+		var v T
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+		}
+		if err := validate.Value(&v); err != nil {
+			var errs validate.Errors
+			if errors.As(err, &errs) {
+				return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), validationData{errs})
+			}
+			return nil, err
+		}
+		return handler(runtime.FromContext(ctx), v)
+	}
+	return s.registry.RegisterPrompt(desc.Name, desc)
+}
+
+// promptField describes a struct field holding a prompt argument.
+type promptField struct {
+	name        string
+	description string
+	required    bool
+	// text marks fields taking the argument string as it is rather than
+	// decoding it as JSON.
+	text bool
+	// def is the value of the default tag, if any.
+	def    string
+	hasDef bool
+}
+
+// promptFields returns the prompt arguments described by the fields of the
+// struct type t, including those of embedded structs without a json name.
+func promptFields(t reflect.Type) ([]promptField, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("prompt arguments must be a struct, not %s", t)
+	}
+	var out []promptField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, skip := schema.FieldName(f)
+		if skip {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded, err := promptFields(ft)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, embedded...)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		text := ft.Kind() == reflect.String || reflect.PointerTo(ft).Implements(textUnmarshalerType)
+		def, hasDef := f.Tag.Lookup("default")
+		if text && strings.HasPrefix(def, `"`) {
+			// As in schemas, string defaults may be written as JSON.
+			json.Unmarshal([]byte(def), &def)
+		}
+		out = append(out, promptField{
+			name:        name,
+			description: f.Tag.Get("description"),
+			required:    !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer && !hasDef,
+			text:        text,
+			def:         def,
+			hasDef:      hasDef,
+		})
+	}
+	return out, nil
+}
+
+// promptArguments encodes args as the JSON object T is decoded from,
+// filling in defaults for the missing ones.
+func promptArguments(fields []promptField, args map[string]string) (json.RawMessage, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	n := 0
+	for _, f := range fields {
+		v, ok := args[f.name]
+		if !ok {
+			if !f.hasDef {
+				continue
+			}
+			v = f.def
+		}
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		n++
+		name, _ := json.Marshal(f.name)
+		b.Write(name)
+		b.WriteByte(':')
+		if f.text {
+			data, _ := json.Marshal(v)
+			b.Write(data)
+			continue
+		}
+		if !json.Valid([]byte(v)) {
+			return nil, fmt.Errorf("argument %q: %q is not a valid value", f.name, v)
+		}
+		b.WriteString(v)
+	}
+	b.WriteByte('}')
+	return json.RawMessage(b.String()), nil
+}
@@ -0,0 +1,246 @@
+// Package metrics provides dependency-free counters, gauges and histograms
+// exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Default is the registry used when no other is configured.
+var Default = NewRegistry()
+
+type kind int
+
+const (
+	kindCounter kind = iota
+	kindGauge
+	kindHistogram
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindCounter:
+		return "counter"
+	case kindGauge:
+		return "gauge"
+	default:
+		return "histogram"
+	}
+}
+
+// Registry holds metric families by name.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       atomicFloat
+	// histogram state
+	counts []atomic.Uint64
+	sum    atomicFloat
+	count  atomic.Uint64
+}
+
+// family returns the family named name, creating it on first use. Asking
+// for an existing name with a different kind or label set panics, as that
+// is a programming error.
+func (r *Registry) family(name, help string, k kind, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.kind != k || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("metrics: %s re-registered with a different kind or labels", name))
+		}
+		return f
+	}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    k,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+func (f *family) with(values ...string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), values...)}
+		if f.kind == kindHistogram {
+			s.counts = make([]atomic.Uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct{ s *series }
+
+// Inc adds one.
+func (c Counter) Inc() { c.s.value.add(1) }
+
+// Add adds v, which must not be negative.
+func (c Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.s.value.add(v)
+}
+
+// Value returns the current value.
+func (c Counter) Value() float64 { return c.s.value.load() }
+
+// Gauge is a value that can go up and down.
+type Gauge struct{ s *series }
+
+// Set sets the value.
+func (g Gauge) Set(v float64) { g.s.value.store(v) }
+
+// Add adds v, which may be negative.
+func (g Gauge) Add(v float64) { g.s.value.add(v) }
+
+// Value returns the current value.
+func (g Gauge) Value() float64 { return g.s.value.load() }
+
+// Histogram counts observations into buckets.
+type Histogram struct {
+	f *family
+	s *series
+}
+
+// Observe records v.
+func (h Histogram) Observe(v float64) {
+	for i, b := range h.f.buckets {
+		if v <= b {
+			h.s.counts[i].Add(1)
+		}
+	}
+	h.s.sum.add(v)
+	h.s.count.Add(1)
+}
+
+// Count returns the number of observations.
+func (h Histogram) Count() uint64 { return h.s.count.Load() }
+
+// Sum returns the sum of all observations.
+func (h Histogram) Sum() float64 { return h.s.sum.load() }
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct{ f *family }
+
+// With returns the counter for the given label values.
+func (v CounterVec) With(values ...string) Counter { return Counter{v.f.with(values...)} }
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ f *family }
+
+// With returns the gauge for the given label values.
+func (v GaugeVec) With(values ...string) Gauge { return Gauge{v.f.with(values...)} }
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct{ f *family }
+
+// With returns the histogram for the given label values.
+func (v HistogramVec) With(values ...string) Histogram {
+	return Histogram{f: v.f, s: v.f.with(values...)}
+}
+
+// Counter returns the unlabelled counter named name.
+func (r *Registry) Counter(name, help string) Counter {
+	return Counter{r.family(name, help, kindCounter, nil, nil).with()}
+}
+
+// CounterVec returns the counter family named name.
+func (r *Registry) CounterVec(name, help string, labels ...string) CounterVec {
+	return CounterVec{r.family(name, help, kindCounter, nil, labels)}
+}
+
+// Gauge returns the unlabelled gauge named name.
+func (r *Registry) Gauge(name, help string) Gauge {
+	return Gauge{r.family(name, help, kindGauge, nil, nil).with()}
+}
+
+// GaugeVec returns the gauge family named name.
+func (r *Registry) GaugeVec(name, help string, labels ...string) GaugeVec {
+	return GaugeVec{r.family(name, help, kindGauge, nil, labels)}
+}
+
+// Histogram returns the unlabelled histogram named name with the given
+// upper bucket bounds.
+func (r *Registry) Histogram(name, help string, buckets []float64) Histogram {
+	f := r.family(name, help, kindHistogram, normalizeBuckets(buckets), nil)
+	return Histogram{f: f, s: f.with()}
+}
+
+// HistogramVec returns the histogram family named name.
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) HistogramVec {
+	return HistogramVec{r.family(name, help, kindHistogram, normalizeBuckets(buckets), labels)}
+}
+
+// ExponentialBuckets returns count bucket bounds starting at start and
+// multiplied by factor each step.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	b := make([]float64, count)
+	for i := range b {
+		b[i] = start
+		start *= factor
+	}
+	return b
+}
+
+// DefBuckets are latency buckets in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func normalizeBuckets(b []float64) []float64 {
+	b = append([]float64(nil), b...)
+	sort.Float64s(b)
+	return b
+}
+
+// atomicFloat is a float64 updated with compare-and-swap.
+type atomicFloat struct{ bits atomic.Uint64 }
+
+func (a *atomicFloat) load() float64   { return math.Float64frombits(a.bits.Load()) }
+func (a *atomicFloat) store(v float64) { a.bits.Store(math.Float64bits(v)) }
+
+func (a *atomicFloat) add(v float64) {
+	for {
+		old := a.bits.Load()
+		if a.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
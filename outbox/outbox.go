@@ -0,0 +1,246 @@
+// Package outbox delivers resource update notifications reliably for
+// servers whose resources live in a database.
+//
+// An application records an update event in the same transaction as the
+// change it describes, using Record with the transaction's store.Store. A
+// Dispatcher then polls the store and sends notifications/resources/updated
+// for every pending event, deleting an event only after it was queued on
+// every live connection. Delivery is therefore at-least-once: a crash
+// between sending and deleting repeats the notification. Each notification
+// carries the event's dedup key in _meta so that clients can drop repeats.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/store"
+)
+
+// pendingPrefix is the store prefix holding undelivered events.
+const pendingPrefix = "outbox/pending/"
+
+// Event is a recorded resource update.
+type Event struct {
+	URI        string    `json:"uri"`
+	DedupKey   string    `json:"dedupKey"`
+	RecordedAt time.Time `json:"recordedAt"`
+	Attempts   int       `json:"attempts,omitempty"`
+}
+
+// Record adds an update event for uri to the outbox. Pass the store.Store
+// of the transaction that changes the resource so that the event is
+// committed if and only if the change is.
+//
+// Events are identified by dedupKey, which defaults to uri: recording a
+// key that is still pending replaces the earlier event, so repeated
+// updates of a resource coalesce into one notification.
+//
+// The event is stamped with the wall clock; use RecordAt to stamp it
+// with the time of a clock.Clock, such as the server's.
+func Record(ctx context.Context, tx store.Store, uri, dedupKey string) error {
+	return RecordAt(ctx, tx, uri, dedupKey, time.Now())
+}
+
+// RecordAt is Record for an event recorded at the given time. Events are
+// delivered in the order of their times.
+func RecordAt(ctx context.Context, tx store.Store, uri, dedupKey string, at time.Time) error {
+	if uri == "" {
+		return errors.New("outbox: uri is required")
+	}
+	if dedupKey == "" {
+		dedupKey = uri
+	}
+	data, err := json.Marshal(Event{URI: uri, DedupKey: dedupKey, RecordedAt: at})
+	if err != nil {
+		return err
+	}
+	return tx.Put(ctx, pendingKey(dedupKey), data)
+}
+
+// Options configures a Dispatcher.
+type Options struct {
+	// Interval is how often the outbox is polled. Defaults to one second.
+	Interval time.Duration
+	// MaxAttempts bounds how often delivery of an event is retried after
+	// some connections could not take it. The event is dropped once the
+	// limit is reached. Defaults to 10.
+	MaxAttempts int
+	// Logger receives delivery diagnostics. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Clock schedules polling. Defaults to the server's clock.
+	Clock clock.Clock
+}
+
+// Dispatcher delivers pending events to the clients of a server.
+type Dispatcher struct {
+	server *mcp.Server
+	store  store.Store
+	opts   Options
+
+	delivered metrics.Counter
+	dropped   metrics.Counter
+	pending   metrics.Gauge
+}
+
+// NewDispatcher returns a dispatcher sending the events pending in st to
+// the connections of s. Metrics are recorded in the server's registry.
+func NewDispatcher(s *mcp.Server, st store.Store, opts Options) *Dispatcher {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Clock == nil {
+		opts.Clock = s.Clock()
+	}
+	reg := s.Metrics()
+	return &Dispatcher{
+		server: s,
+		store:  st,
+		opts:   opts,
+		delivered: reg.Counter("zenmcp_outbox_delivered_total",
+			"Outbox events delivered to all connected clients."),
+		dropped: reg.Counter("zenmcp_outbox_dropped_total",
+			"Outbox events dropped after exhausting their delivery attempts."),
+		pending: reg.Gauge("zenmcp_outbox_pending",
+			"Outbox events awaiting delivery at the last poll."),
+	}
+}
+
+// Run polls the outbox until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := d.opts.Clock.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Flush(ctx); err != nil && ctx.Err() == nil {
+			d.opts.Logger.Warn("outbox: dispatch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// Flush delivers the pending events once, oldest first.
+func (d *Dispatcher) Flush(ctx context.Context) error {
+	keys, err := d.store.List(ctx, pendingPrefix)
+	if err != nil {
+		return err
+	}
+	events := make([]Event, 0, len(keys))
+	for _, k := range keys {
+		data, err := d.store.Get(ctx, k)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var ev Event
+		if err := json.Unmarshal(data, &ev); err != nil {
+			d.opts.Logger.Warn("outbox: dropping malformed event", "key", k, "error", err)
+			if err := d.store.Delete(ctx, k); err != nil {
+				return err
+			}
+			continue
+		}
+		events = append(events, ev)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].RecordedAt.Before(events[j].RecordedAt) })
+	d.pending.Set(float64(len(events)))
+
+	for _, ev := range events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.deliver(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver sends ev and removes it from the outbox, or records a failed
+// attempt when some connection could not take it.
+func (d *Dispatcher) deliver(ctx context.Context, ev Event) error {
+	params := protocol.ResourceUpdatedParams{
+		URI:  ev.URI,
+		Meta: &protocol.NotificationMeta{DedupKey: ev.DedupKey},
+	}
+	live := len(d.server.Connections())
+	sent, err := d.server.Broadcast(protocol.MethodResourceUpdated, params)
+	if err != nil {
+		return fmt.Errorf("outbox: event %s: %w", ev.DedupKey, err)
+	}
+	if sent >= live {
+		d.delivered.Inc()
+		return d.remove(ctx, ev)
+	}
+	ev.Attempts++
+	if ev.Attempts >= d.opts.MaxAttempts {
+		d.dropped.Inc()
+		d.opts.Logger.Warn("outbox: dropping event after repeated delivery failures",
+			"uri", ev.URI, "dedup_key", ev.DedupKey, "attempts", ev.Attempts)
+		return d.remove(ctx, ev)
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return d.replace(ctx, ev, data)
+}
+
+// remove deletes ev unless it was re-recorded since it was read.
+func (d *Dispatcher) remove(ctx context.Context, ev Event) error {
+	return d.replace(ctx, ev, nil)
+}
+
+// replace overwrites ev with data, or deletes it when data is nil. An event
+// recorded again under the same key in the meantime is left alone so that
+// the newer update is not lost. Stores implementing store.Transactional
+// make the check atomic.
+func (d *Dispatcher) replace(ctx context.Context, ev Event, data []byte) error {
+	key := pendingKey(ev.DedupKey)
+	apply := func(tx store.Store) error {
+		cur, err := tx.Get(ctx, key)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var stored Event
+		if json.Unmarshal(cur, &stored) == nil && stored.RecordedAt.After(ev.RecordedAt) {
+			return nil
+		}
+		if data == nil {
+			return tx.Delete(ctx, key)
+		}
+		return tx.Put(ctx, key, data)
+	}
+	if t, ok := d.store.(store.Transactional); ok {
+		return t.Update(ctx, apply)
+	}
+	return apply(d.store)
+}
+
+func pendingKey(dedupKey string) string {
+	return pendingPrefix + url.PathEscape(dedupKey)
+}
@@ -0,0 +1,145 @@
+// Package policy authorizes requests against rules kept outside the code,
+// so that operators can tighten or relax access without rebuilding the
+// server.
+//
+// Every request is described as an Input: its method, the tool, resource
+// or prompt it targets, its arguments, and the caller's principal, session
+// and tenant. An Evaluator decides on the Input before the request is
+// dispatched. Policy, loaded from JSON, is the built-in evaluator; engines
+// such as OPA or CEL plug in by implementing Evaluator over the same
+// Input, which marshals to the document they expect as input.
+//
+//	p, err := policy.Load("policy.json")
+//	if err != nil { ... }
+//	enf := policy.Install(s, p)
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Input describes a request to a policy.
+type Input struct {
+	Method string `json:"method"`
+	// Tool, Resource and Prompt name the target of tools/call,
+	// resources/read and resources/(un)subscribe, and prompts/get.
+	Tool     string `json:"tool,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	// Arguments are the arguments of tools/call and prompts/get.
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	// Principal is the authenticated caller, nil for anonymous ones.
+	Principal *Principal `json:"principal,omitempty"`
+	Session   string     `json:"session,omitempty"`
+	Tenant    string     `json:"tenant,omitempty"`
+}
+
+// Principal is the authenticated caller in an Input.
+type Principal struct {
+	Subject string                 `json:"subject"`
+	Scopes  []string               `json:"scopes,omitempty"`
+	Claims  map[string]interface{} `json:"claims,omitempty"`
+}
+
+// Decision is the verdict of an Evaluator.
+type Decision struct {
+	Allow bool
+	// Reason tells a denied caller why. Optional.
+	Reason string
+}
+
+// Evaluator decides whether requests are allowed.
+type Evaluator interface {
+	Evaluate(ctx context.Context, in *Input) (Decision, error)
+}
+
+// EvaluatorFunc adapts a function to the Evaluator interface.
+type EvaluatorFunc func(ctx context.Context, in *Input) (Decision, error)
+
+// Evaluate calls f.
+func (f EvaluatorFunc) Evaluate(ctx context.Context, in *Input) (Decision, error) {
+	return f(ctx, in)
+}
+
+// DeniedData is the data of the Forbidden error returned for denied
+// requests.
+type DeniedData struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// Enforcer applies an evaluator to the requests of a server.
+type Enforcer struct {
+	ev atomic.Pointer[Evaluator]
+}
+
+// Install makes s check every request with ev before dispatching it.
+// Denied requests fail with a Forbidden error, as do requests ev fails to
+// decide on. The handshake, initialize and ping, is not checked. Install
+// must be called before serving starts; the evaluator may be replaced
+// later with SetEvaluator.
+func Install(s *mcp.Server, ev Evaluator) *Enforcer {
+	e := &Enforcer{}
+	e.SetEvaluator(ev)
+	s.Router().Authorize(e.authorize)
+	return e
+}
+
+// SetEvaluator replaces the evaluator. Requests arriving after it returns
+// are decided by ev.
+func (e *Enforcer) SetEvaluator(ev Evaluator) { e.ev.Store(&ev) }
+
+func (e *Enforcer) authorize(ctx *runtime.Context, params json.RawMessage) error {
+	switch ctx.Method() {
+	case protocol.MethodInitialize, protocol.MethodPing:
+		return nil
+	}
+	in := NewInput(ctx, params)
+	d, err := (*e.ev.Load()).Evaluate(ctx, in)
+	if err != nil {
+		ctx.Logger().Error("policy evaluation failed", "error", err)
+		return protocol.NewError(protocol.Forbidden, "request denied by policy", DeniedData{Reason: "policy evaluation failed"})
+	}
+	if !d.Allow {
+		ctx.Logger().Debug("request denied by policy", "reason", d.Reason)
+		return protocol.NewError(protocol.Forbidden, "request denied by policy", DeniedData{Reason: d.Reason})
+	}
+	return nil
+}
+
+// NewInput describes the request of ctx, whose parameters are params.
+func NewInput(ctx *runtime.Context, params json.RawMessage) *Input {
+	in := &Input{Method: ctx.Method(), Tenant: ctx.Tenant()}
+	if p := ctx.Principal(); p != nil {
+		in.Principal = &Principal{Subject: p.Subject, Scopes: p.Scopes, Claims: p.Claims}
+	}
+	if s := ctx.Session(); s != nil {
+		in.Session = s.ID()
+	}
+	var p struct {
+		Name      string          `json:"name"`
+		URI       string          `json:"uri"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if len(params) == 0 || json.Unmarshal(params, &p) != nil {
+		return in
+	}
+	switch in.Method {
+	case protocol.MethodToolsCall:
+		in.Tool = p.Name
+	case protocol.MethodPromptsGet:
+		in.Prompt = p.Name
+	case protocol.MethodResourcesRead, protocol.MethodResourcesSubscribe, protocol.MethodResourcesUnsubscribe:
+		in.Resource = p.URI
+		return in
+	default:
+		return in
+	}
+	json.Unmarshal(p.Arguments, &in.Arguments)
+	return in
+}
@@ -0,0 +1,389 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Effects of rules.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Policy is an ordered list of rules. The first rule matching a request
+// decides it; requests no rule matches get the default effect. In JSON:
+//
+//	{
+//	  "default": "deny",
+//	  "rules": [
+//	    {"effect": "allow", "methods": ["tools/list", "resources/*"]},
+//	    {"effect": "deny", "tools": ["delete_*"], "unless": [{"field": "principal.scopes", "op": "contains", "value": "admin"}]},
+//	    {"effect": "allow", "tools": ["transfer"], "when": [{"field": "arguments.amount", "op": "le", "value": 1000}]},
+//	    {"effect": "allow", "tools": ["*"], "subjects": ["*"]}
+//	  ]
+//	}
+type Policy struct {
+	// Default is the effect for requests no rule matches. Defaults to
+	// deny.
+	Default string `json:"default,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule matches requests and gives them its effect. Every condition set
+// must hold for the rule to match; unset ones match anything. Name
+// patterns are path.Match globs, so "*" does not cross a "/".
+type Rule struct {
+	// Name identifies the rule in the reasons given for denials.
+	Name   string `json:"name,omitempty"`
+	Effect string `json:"effect"`
+	// Methods, Tools, Resources and Prompts match the request's method
+	// and target. A rule naming targets only matches requests having a
+	// target of that kind.
+	Methods   []string `json:"methods,omitempty"`
+	Tools     []string `json:"tools,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+	Prompts   []string `json:"prompts,omitempty"`
+	// Subjects match the principal's subject; "*" matches any
+	// authenticated caller.
+	Subjects []string `json:"subjects,omitempty"`
+	// Scopes match principals holding any of them.
+	Scopes  []string `json:"scopes,omitempty"`
+	Tenants []string `json:"tenants,omitempty"`
+	// When lists conditions that must all hold, and Unless conditions of
+	// which none may.
+	When   []Condition `json:"when,omitempty"`
+	Unless []Condition `json:"unless,omitempty"`
+}
+
+// Condition tests a field of the Input, addressed by the dotted path of
+// its JSON encoding, such as "arguments.amount" or "principal.claims.org".
+//
+// Op is one of eq, ne, lt, le, gt, ge (numbers and strings), in and notIn
+// (Value is a list), contains (the field is a list holding Value, or a
+// string containing it), prefix, suffix, matches (Value is a regular
+// expression the whole string must match), exists and absent (no Value).
+type Condition struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Load reads a policy from the JSON file at path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and checks a policy written in JSON.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Compile checks p and prepares it for evaluation. Parse and Load compile
+// the policies they return; policies built in code must be compiled
+// before use.
+func (p *Policy) Compile() error {
+	switch p.Default {
+	case "":
+		p.Default = Deny
+	case Allow, Deny:
+	default:
+		return fmt.Errorf("policy: unknown default effect %q", p.Default)
+	}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Effect != Allow && r.Effect != Deny {
+			return fmt.Errorf("policy: rule %s: unknown effect %q", r.label(i), r.Effect)
+		}
+		for _, list := range [][]string{r.Methods, r.Tools, r.Resources, r.Prompts, r.Tenants} {
+			for _, pattern := range list {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("policy: rule %s: bad pattern %q", r.label(i), pattern)
+				}
+			}
+		}
+		for _, conds := range [][]Condition{r.When, r.Unless} {
+			for j := range conds {
+				if err := conds[j].compile(); err != nil {
+					return fmt.Errorf("policy: rule %s: %w", r.label(i), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) label(i int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("%q", r.Name)
+	}
+	return fmt.Sprint(i)
+}
+
+func (c *Condition) compile() error {
+	if c.Field == "" {
+		return fmt.Errorf("condition without a field")
+	}
+	switch c.Op {
+	case "eq", "ne", "lt", "le", "gt", "ge", "contains", "exists", "absent":
+	case "in", "notIn":
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("condition on %s: %s needs a list", c.Field, c.Op)
+		}
+	case "prefix", "suffix", "matches":
+		s, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("condition on %s: %s needs a string", c.Field, c.Op)
+		}
+		if c.Op == "matches" {
+			re, err := regexp.Compile("^(?:" + s + ")$")
+			if err != nil {
+				return fmt.Errorf("condition on %s: %w", c.Field, err)
+			}
+			c.re = re
+		}
+	default:
+		return fmt.Errorf("condition on %s: unknown op %q", c.Field, c.Op)
+	}
+	return nil
+}
+
+// Evaluate decides in by the first matching rule.
+func (p *Policy) Evaluate(ctx context.Context, in *Input) (Decision, error) {
+	var doc map[string]interface{}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !r.matchesTarget(in) {
+			continue
+		}
+		if len(r.When) > 0 || len(r.Unless) > 0 {
+			if doc == nil {
+				data, err := json.Marshal(in)
+				if err != nil {
+					return Decision{}, err
+				}
+				if err := json.Unmarshal(data, &doc); err != nil {
+					return Decision{}, err
+				}
+			}
+			if !r.holds(doc) {
+				continue
+			}
+		}
+		d := Decision{Allow: r.Effect == Allow}
+		if !d.Allow {
+			d.Reason = "denied by rule " + r.label(i)
+		}
+		return d, nil
+	}
+	d := Decision{Allow: p.Default == Allow}
+	if !d.Allow {
+		d.Reason = "no rule allows " + in.Method
+	}
+	return d, nil
+}
+
+// matchesTarget reports whether in matches the rule's patterns and
+// principal conditions.
+func (r *Rule) matchesTarget(in *Input) bool {
+	if !matchAny(r.Methods, in.Method, true) ||
+		!matchAny(r.Tools, in.Tool, in.Tool != "") ||
+		!matchAny(r.Resources, in.Resource, in.Resource != "") ||
+		!matchAny(r.Prompts, in.Prompt, in.Prompt != "") ||
+		!matchAny(r.Tenants, in.Tenant, true) {
+		return false
+	}
+	if len(r.Subjects) == 0 && len(r.Scopes) == 0 {
+		return true
+	}
+	p := in.Principal
+	if p == nil {
+		return false
+	}
+	for _, s := range r.Subjects {
+		if s == "*" || s == p.Subject {
+			return true
+		}
+	}
+	for _, s := range r.Scopes {
+		for _, have := range p.Scopes {
+			if s == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny reports whether value matches one of patterns. An empty list
+// matches anything; otherwise the value must be present.
+func matchAny(patterns []string, value string, present bool) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	if !present {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) holds(doc map[string]interface{}) bool {
+	for i := range r.When {
+		if !r.When[i].holds(doc) {
+			return false
+		}
+	}
+	for i := range r.Unless {
+		if r.Unless[i].holds(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Condition) holds(doc map[string]interface{}) bool {
+	v, ok := lookup(doc, c.Field)
+	switch c.Op {
+	case "exists":
+		return ok
+	case "absent":
+		return !ok
+	}
+	if !ok {
+		return c.Op == "ne" || c.Op == "notIn"
+	}
+	switch c.Op {
+	case "eq":
+		return equal(v, c.Value)
+	case "ne":
+		return !equal(v, c.Value)
+	case "lt", "le", "gt", "ge":
+		n, ok := compare(v, c.Value)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case "lt":
+			return n < 0
+		case "le":
+			return n <= 0
+		case "gt":
+			return n > 0
+		}
+		return n >= 0
+	case "in", "notIn":
+		found := false
+		for _, x := range c.Value.([]interface{}) {
+			if equal(v, x) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == "in")
+	case "contains":
+		switch v := v.(type) {
+		case []interface{}:
+			for _, x := range v {
+				if equal(x, c.Value) {
+					return true
+				}
+			}
+		case string:
+			s, ok := c.Value.(string)
+			return ok && strings.Contains(v, s)
+		}
+		return false
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case "prefix":
+		return strings.HasPrefix(s, c.Value.(string))
+	case "suffix":
+		return strings.HasSuffix(s, c.Value.(string))
+	}
+	return c.re != nil && c.re.MatchString(s)
+}
+
+// lookup returns the value at the dotted path field of doc.
+func lookup(doc map[string]interface{}, field string) (interface{}, bool) {
+	var v interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// equal compares JSON values, treating numbers by value.
+func equal(a, b interface{}) bool {
+	if n, ok := compare(a, b); ok {
+		return n == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two numbers or two strings.
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	x, ok1 := a.(string)
+	y, ok2 := b.(string)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
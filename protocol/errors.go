@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// MCP-specific error codes.
+const (
+	ResourceNotFound = -32002
+)
+
+// zenmcp-specific error codes, in the implementation-defined server error
+// range.
+const (
+	// ServerOverloaded reports that the server shed the request to protect
+	// itself; clients may retry later.
+	ServerOverloaded = -32010
+	// QuotaExceeded reports that the caller used up its quota; the error
+	// data says when it resets.
+	QuotaExceeded = -32011
+	// BudgetExhausted reports that the caller's session used up its call
+	// budget; the error data says what remains.
+	BudgetExhausted = -32012
+	// Forbidden reports that the server's policy does not allow the
+	// request.
+	Forbidden = -32013
+)
+
+// Error is a JSON-RPC error object. It implements the error interface so
+// handlers can return it directly to control the code sent to the client.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// NewError returns a JSON-RPC error with the given code, message and
+// optional structured data.
+func NewError(code int, message string, data interface{}) *Error {
+	return &Error{Code: code, Message: message, Data: data}
+}
+
+// Errorf returns a JSON-RPC error with a formatted message.
+func Errorf(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// AsError converts err into a JSON-RPC error. Errors that already wrap an
+// *Error are returned unchanged; anything else becomes an InternalError.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return NewError(InternalError, err.Error(), nil)
+}
@@ -0,0 +1,163 @@
+// Package protocol defines the JSON-RPC 2.0 envelope and the Model Context
+// Protocol message types exchanged between clients and servers.
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// JSONRPCVersion is the only JSON-RPC version supported by MCP.
+const JSONRPCVersion = "2.0"
+
+// ID is a JSON-RPC request identifier. It holds either a string or an
+// integer; the zero value represents a null ID. IDs are comparable and may
+// be used as map keys.
+type ID struct {
+	raw string
+}
+
+// NewStringID returns a string request ID.
+func NewStringID(s string) ID {
+	b, _ := json.Marshal(s)
+	return ID{raw: string(b)}
+}
+
+// NewNumberID returns a numeric request ID.
+func NewNumberID(n int64) ID {
+	return ID{raw: strconv.FormatInt(n, 10)}
+}
+
+// IsZero reports whether the ID is null.
+func (id ID) IsZero() bool { return id.raw == "" }
+
+// String returns the ID as it appears on the wire.
+func (id ID) String() string {
+	if id.raw == "" {
+		return "null"
+	}
+	return id.raw
+}
+
+// MarshalJSON implements json.Marshaler.
+func (id ID) MarshalJSON() ([]byte, error) {
+	if id.raw == "" {
+		return []byte("null"), nil
+	}
+	return []byte(id.raw), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Only strings, numbers and null
+// are accepted.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		id.raw = ""
+		return nil
+	}
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = NewStringID(s)
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid id %s: must be a string or number", data)
+		}
+		id.raw = n.String()
+	}
+	return nil
+}
+
+// Message is a single JSON-RPC 2.0 frame. Depending on which fields are set
+// it is a request, a notification or a response.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *ID             `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsRequest reports whether m is a request expecting a response.
+func (m *Message) IsRequest() bool { return m.Method != "" && m.ID != nil }
+
+// IsNotification reports whether m is a notification.
+func (m *Message) IsNotification() bool { return m.Method != "" && m.ID == nil }
+
+// IsResponse reports whether m is a response to an earlier request.
+func (m *Message) IsResponse() bool { return m.Method == "" && m.ID != nil }
+
+// Validate checks the envelope for structural errors.
+func (m *Message) Validate() error {
+	if m.JSONRPC != JSONRPCVersion {
+		return NewError(InvalidRequest, fmt.Sprintf("unsupported jsonrpc version %q", m.JSONRPC), nil)
+	}
+	if m.Method == "" && m.ID == nil {
+		return NewError(InvalidRequest, "message has neither method nor id", nil)
+	}
+	if m.Method == "" && m.Result == nil && m.Error == nil {
+		return NewError(InvalidRequest, "response has neither result nor error", nil)
+	}
+	return nil
+}
+
+// NewRequest builds a request frame with params marshaled to JSON.
+func NewRequest(id ID, method string, params interface{}) (*Message, error) {
+	raw, err := marshalParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{JSONRPC: JSONRPCVersion, ID: &id, Method: method, Params: raw}, nil
+}
+
+// NewNotification builds a notification frame with params marshaled to JSON.
+func NewNotification(method string, params interface{}) (*Message, error) {
+	raw, err := marshalParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{JSONRPC: JSONRPCVersion, Method: method, Params: raw}, nil
+}
+
+// NewResult builds a successful response to the request identified by id.
+func NewResult(id *ID, result interface{}) (*Message, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("marshal result: %w", err)
+	}
+	return &Message{JSONRPC: JSONRPCVersion, ID: responseID(id), Result: raw}, nil
+}
+
+// NewErrorResponse builds an error response to the request identified by id.
+// A nil id produces a response with a null ID, as required for parse errors.
+func NewErrorResponse(id *ID, err *Error) *Message {
+	return &Message{JSONRPC: JSONRPCVersion, ID: responseID(id), Error: err}
+}
+
+func responseID(id *ID) *ID {
+	if id == nil {
+		return &ID{}
+	}
+	return id
+}
+
+func marshalParams(params interface{}) (json.RawMessage, error) {
+	if params == nil {
+		return nil, nil
+	}
+	if raw, ok := params.(json.RawMessage); ok {
+		return raw, nil
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("marshal params: %w", err)
+	}
+	return raw, nil
+}
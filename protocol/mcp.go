@@ -0,0 +1,373 @@
+package protocol
+
+import "encoding/json"
+
+// LatestProtocolVersion is the newest MCP revision implemented by zenmcp.
+const LatestProtocolVersion = "2025-03-26"
+
+// MCP method names.
+const (
+	MethodInitialize    = "initialize"
+	MethodInitialized   = "notifications/initialized"
+	MethodPing          = "ping"
+	MethodToolsList     = "tools/list"
+	MethodToolsCall     = "tools/call"
+	MethodResourcesList = "resources/list"
+	MethodResourcesRead = "resources/read"
+	MethodPromptsList   = "prompts/list"
+	MethodPromptsGet    = "prompts/get"
+
+	MethodResourceTemplatesList = "resources/templates/list"
+
+	MethodResourcesSubscribe   = "resources/subscribe"
+	MethodResourcesUnsubscribe = "resources/unsubscribe"
+	MethodResourceUpdated      = "notifications/resources/updated"
+
+	MethodCancelled = "notifications/cancelled"
+	MethodProgress  = "notifications/progress"
+
+	MethodLoggingSetLevel = "logging/setLevel"
+	MethodLoggingMessage  = "notifications/message"
+
+	MethodToolsListChanged     = "notifications/tools/list_changed"
+	MethodResourcesListChanged = "notifications/resources/list_changed"
+	MethodPromptsListChanged   = "notifications/prompts/list_changed"
+
+	// MethodGoodbye is a zenmcp extension: the server sends it just before
+	// closing a connection of its own accord.
+	MethodGoodbye = "notifications/zenmcp/goodbye"
+)
+
+// Implementation identifies a client or server implementation.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ClientCapabilities describes optional features supported by a client.
+type ClientCapabilities struct {
+	Experimental map[string]interface{} `json:"experimental,omitempty"`
+	Roots        *RootsCapability       `json:"roots,omitempty"`
+	Sampling     *struct{}              `json:"sampling,omitempty"`
+}
+
+// RootsCapability describes client support for filesystem roots.
+type RootsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ServerCapabilities describes optional features supported by a server.
+type ServerCapabilities struct {
+	Experimental map[string]interface{} `json:"experimental,omitempty"`
+	Logging      *struct{}              `json:"logging,omitempty"`
+	Prompts      *PromptsCapability     `json:"prompts,omitempty"`
+	Resources    *ResourcesCapability   `json:"resources,omitempty"`
+	Tools        *ToolsCapability       `json:"tools,omitempty"`
+}
+
+// PromptsCapability describes server support for prompts.
+type PromptsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ResourcesCapability describes server support for resources.
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ToolsCapability describes server support for tools.
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// InitializeParams are sent by the client to open a session.
+type InitializeParams struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ClientCapabilities `json:"capabilities"`
+	ClientInfo      Implementation     `json:"clientInfo"`
+}
+
+// InitializeResult is the server's answer to initialize.
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// RequestMeta carries the optional _meta object attached to requests.
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"`
+	// ToolVersion is a zenmcp extension selecting a specific version of
+	// the tool named in tools/call.
+	ToolVersion string `json:"zenmcp/toolVersion,omitempty"`
+}
+
+// Tool describes a tool in tools/list.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations *ToolAnnotations       `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints describing tool behaviour to clients.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+
+	// Deprecated and DeprecationMessage are zenmcp extensions marking
+	// tools scheduled for removal.
+	Deprecated         bool   `json:"deprecated,omitempty"`
+	DeprecationMessage string `json:"deprecationMessage,omitempty"`
+}
+
+// ListToolsParams are the parameters of tools/list.
+type ListToolsParams struct {
+	// Cursor asks for the page after the one whose NextCursor it is.
+	Cursor string `json:"cursor,omitempty"`
+	// Filter is a zenmcp extension restricting the listing to tools with
+	// matching tags.
+	Filter *ToolFilter `json:"zenmcp/filter,omitempty"`
+}
+
+// ToolFilter selects tools by tag. A tool matches when it has at least one
+// of Tags, or Tags is empty, and none of ExcludeTags.
+type ToolFilter struct {
+	Tags        []string `json:"tags,omitempty"`
+	ExcludeTags []string `json:"excludeTags,omitempty"`
+}
+
+// ListToolsResult is the result of tools/list.
+type ListToolsResult struct {
+	Tools []Tool `json:"tools"`
+	// NextCursor, when set, is the Cursor of the next page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Defs is a zenmcp extension holding schema definitions hoisted out of
+	// the tools' input schemas, for clients that opted in. Clients add
+	// them to the $defs of each input schema before resolving it.
+	Defs map[string]interface{} `json:"zenmcp/defs,omitempty"`
+}
+
+// ToolCallParams are the parameters of tools/call.
+type ToolCallParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Meta      *RequestMeta    `json:"_meta,omitempty"`
+}
+
+// ToolCallResult is the result of tools/call.
+type ToolCallResult struct {
+	Content []Content   `json:"content"`
+	IsError bool        `json:"isError,omitempty"`
+	Meta    *ResultMeta `json:"_meta,omitempty"`
+}
+
+// ResultMeta carries the optional _meta object attached to results.
+type ResultMeta struct {
+	// Upstream is a zenmcp extension naming the upstream server a proxy
+	// forwarded the call to.
+	Upstream string `json:"zenmcp/upstream,omitempty"`
+}
+
+// Content is a single item of tool or prompt output.
+type Content struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"`
+	MimeType string            `json:"mimeType,omitempty"`
+	Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+// TextContent returns a text content item.
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// Resource describes a resource in resources/list.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourcesParams are the parameters of resources/list.
+type ListResourcesParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListResourcesResult is the result of resources/list.
+type ListResourcesResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources in
+// resources/templates/list. URITemplate is an RFC 6570 URI template.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourceTemplatesParams are the parameters of
+// resources/templates/list.
+type ListResourceTemplatesParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListResourceTemplatesResult is the result of resources/templates/list.
+type ListResourceTemplatesResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	NextCursor        string             `json:"nextCursor,omitempty"`
+}
+
+// ReadResourceParams are the parameters of resources/read.
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ReadResourceResult is the result of resources/read.
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// SubscribeParams are the parameters of resources/subscribe and
+// resources/unsubscribe.
+type SubscribeParams struct {
+	URI string `json:"uri"`
+}
+
+// ProgressParams are the parameters of notifications/progress, which
+// report the progress of the request that carried ProgressToken in its
+// _meta. Total is zero when unknown.
+type ProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// ResourceUpdatedParams are the parameters of
+// notifications/resources/updated.
+type ResourceUpdatedParams struct {
+	URI  string            `json:"uri"`
+	Meta *NotificationMeta `json:"_meta,omitempty"`
+}
+
+// NotificationMeta carries the optional _meta object attached to
+// notifications.
+type NotificationMeta struct {
+	// DedupKey is a zenmcp extension identifying the event a notification
+	// reports. Notifications delivered at least once may repeat; clients
+	// can drop those whose key they have already seen.
+	DedupKey string `json:"zenmcp/dedupKey,omitempty"`
+}
+
+// CancelledParams are the parameters of notifications/cancelled, sent by
+// either side to abandon a request it issued.
+type CancelledParams struct {
+	RequestID ID     `json:"requestId"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// GoodbyeParams are the parameters of notifications/zenmcp/goodbye.
+type GoodbyeParams struct {
+	// Reason says why the connection is closed, such as GoodbyeIdle.
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// Reasons given in GoodbyeParams.
+const (
+	// GoodbyeIdle closes a connection that saw no activity for too long.
+	GoodbyeIdle = "idle"
+	// GoodbyeShutdown closes every connection of a server shutting down
+	// on purpose; clients may reconnect once it is back.
+	GoodbyeShutdown = "shutdown"
+)
+
+// LoggingLevel is the severity of a log message, as in syslog.
+type LoggingLevel string
+
+// Logging levels, from least to most severe.
+const (
+	LevelDebug     LoggingLevel = "debug"
+	LevelInfo      LoggingLevel = "info"
+	LevelNotice    LoggingLevel = "notice"
+	LevelWarning   LoggingLevel = "warning"
+	LevelError     LoggingLevel = "error"
+	LevelCritical  LoggingLevel = "critical"
+	LevelAlert     LoggingLevel = "alert"
+	LevelEmergency LoggingLevel = "emergency"
+)
+
+// SetLevelParams are the parameters of logging/setLevel, through which a
+// client asks for the log messages at level and above.
+type SetLevelParams struct {
+	Level LoggingLevel `json:"level"`
+}
+
+// LoggingMessageParams are the parameters of notifications/message.
+type LoggingMessageParams struct {
+	Level  LoggingLevel `json:"level"`
+	Logger string       `json:"logger,omitempty"`
+	Data   interface{}  `json:"data"`
+}
+
+// ResourceContents holds the text or base64 blob of a resource.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// Prompt describes a prompt in prompts/list.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes an argument accepted by a prompt.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// ListPromptsParams are the parameters of prompts/list.
+type ListPromptsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListPromptsResult is the result of prompts/list.
+type ListPromptsResult struct {
+	Prompts    []Prompt `json:"prompts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// GetPromptParams are the parameters of prompts/get.
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// GetPromptResult is the result of prompts/get.
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// PromptMessage is a single message produced by a prompt.
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
@@ -0,0 +1,250 @@
+// Package proxy serves the tools of upstream MCP servers, routing each call
+// to one of the upstreams that offer the tool.
+//
+// Identical servers run side by side make a tool highly available: calls
+// go to the upstreams of the lowest priority first, spread among them in
+// proportion to their weights, and fail over to the others when an
+// upstream cannot be reached or fails the call. An upstream that failed is
+// tried last until its cooldown ends. The upstream that served a call is
+// named in the _meta of the result (see protocol.ResultMeta).
+//
+//	p, err := proxy.New([]proxy.Upstream{
+//		{Name: "primary", Client: a, Weight: 3},
+//		{Name: "secondary", Client: b, Weight: 1},
+//		{Name: "backup", Client: c, Priority: 1},
+//	}, proxy.Options{})
+//	if err != nil { ... }
+//	if err := p.Mirror(ctx, s); err != nil { ... }
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+// Client is the connection to an upstream server. *mcp.Client implements
+// it.
+type Client interface {
+	ListTools(ctx context.Context, params *protocol.ListToolsParams) (*protocol.ListToolsResult, error)
+	CallTool(ctx context.Context, name string, args interface{}) (*protocol.ToolCallResult, error)
+}
+
+// Upstream is a server the proxy forwards calls to.
+type Upstream struct {
+	// Name identifies the upstream in results and errors. Required and
+	// unique.
+	Name   string
+	Client Client
+	// Priority orders upstreams: calls go to those of the lowest priority
+	// while any of them is up.
+	Priority int
+	// Weight is the upstream's share of the calls among upstreams of the
+	// same priority. Defaults to 1.
+	Weight int
+}
+
+// Options configures a Proxy.
+type Options struct {
+	// Cooldown is how long an upstream that failed a call is tried only
+	// after the others. Defaults to 30 seconds.
+	Cooldown time.Duration
+	// Clock times cooldowns. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// Proxy routes tool calls among upstream servers.
+type Proxy struct {
+	upstreams []*upstream
+	opts      Options
+}
+
+type upstream struct {
+	Upstream
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// New returns a proxy to the given upstreams.
+func New(upstreams []Upstream, opts Options) (*Proxy, error) {
+	if len(upstreams) == 0 {
+		return nil, errors.New("proxy: no upstreams")
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	opts.Clock = clock.Or(opts.Clock)
+	p := &Proxy{opts: opts}
+	seen := make(map[string]bool)
+	for _, u := range upstreams {
+		switch {
+		case u.Name == "":
+			return nil, errors.New("proxy: upstream has no name")
+		case seen[u.Name]:
+			return nil, fmt.Errorf("proxy: duplicate upstream %q", u.Name)
+		case u.Client == nil:
+			return nil, fmt.Errorf("proxy: upstream %q has no client", u.Name)
+		case u.Weight < 0:
+			return nil, fmt.Errorf("proxy: upstream %q has a negative weight", u.Name)
+		}
+		seen[u.Name] = true
+		if u.Weight == 0 {
+			u.Weight = 1
+		}
+		p.upstreams = append(p.upstreams, &upstream{Upstream: u})
+	}
+	sort.SliceStable(p.upstreams, func(i, j int) bool {
+		return p.upstreams[i].Priority < p.upstreams[j].Priority
+	})
+	return p, nil
+}
+
+// Mirror registers on s the tools the upstreams list. A tool offered by
+// several upstreams is registered once, described as by the upstream of
+// the lowest priority, and its calls are routed among them. Upstreams that
+// cannot be listed are left out; Mirror fails only if none can.
+func (p *Proxy) Mirror(ctx context.Context, s *mcp.Server) error {
+	var (
+		tools  []protocol.Tool
+		routes = make(map[string][]*upstream)
+		errs   []error
+	)
+	for _, u := range p.upstreams {
+		list, err := u.Client.ListTools(ctx, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("proxy: listing the tools of %s: %w", u.Name, err))
+			continue
+		}
+		for _, t := range list.Tools {
+			if _, ok := routes[t.Name]; !ok {
+				tools = append(tools, t)
+			}
+			routes[t.Name] = append(routes[t.Name], u)
+		}
+	}
+	if len(errs) == len(p.upstreams) {
+		return errors.Join(errs...)
+	}
+	for _, t := range tools {
+		name, candidates := t.Name, routes[t.Name]
+		err := s.Registry().RegisterTool(name, registry.ToolDescriptor{
+			Description: t.Description,
+			InputSchema: t.InputSchema,
+			Annotations: t.Annotations,
+			Handler: func(ctx context.Context, args json.RawMessage) (*protocol.ToolCallResult, error) {
+				return p.call(ctx, candidates, name, args)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call forwards a call of the named tool to the first of candidates, in
+// the order route picks, that serves it.
+func (p *Proxy) call(ctx context.Context, candidates []*upstream, name string, args json.RawMessage) (*protocol.ToolCallResult, error) {
+	var a interface{}
+	if len(args) > 0 {
+		a = args
+	}
+	var errs []error
+	for _, u := range p.route(candidates) {
+		res, err := u.Client.CallTool(ctx, name, a)
+		if err == nil {
+			u.up()
+			if res.Meta == nil {
+				res.Meta = &protocol.ResultMeta{}
+			}
+			res.Meta.Upstream = u.Name
+			return res, nil
+		}
+		if ctx.Err() != nil || !failover(err) {
+			return nil, err
+		}
+		u.down(p.opts.Clock.Now().Add(p.opts.Cooldown))
+		errs = append(errs, fmt.Errorf("%s: %w", u.Name, err))
+	}
+	return nil, fmt.Errorf("proxy: every upstream of %s failed: %w", name, errors.Join(errs...))
+}
+
+// failover reports whether another upstream may serve a call that failed
+// with err. Calls the upstream rejected as invalid would be rejected by
+// the others too.
+func failover(err error) bool {
+	var rpcErr *protocol.Error
+	return !errors.As(err, &rpcErr) || rpcErr.Code != protocol.InvalidParams
+}
+
+// route returns candidates in the order to try them: by priority, and
+// within a priority in a random order weighted by the upstreams' weights.
+// Upstreams in their cooldown come last.
+func (p *Proxy) route(candidates []*upstream) []*upstream {
+	now := p.opts.Clock.Now()
+	var up, down []*upstream
+	for _, u := range candidates {
+		if u.isDown(now) {
+			down = append(down, u)
+		} else {
+			up = append(up, u)
+		}
+	}
+	return append(shuffle(up), shuffle(down)...)
+}
+
+// shuffle orders us, which are sorted by priority, for routing.
+func shuffle(us []*upstream) []*upstream {
+	out := make([]*upstream, 0, len(us))
+	for i := 0; i < len(us); {
+		j := i
+		total := 0
+		for j < len(us) && us[j].Priority == us[i].Priority {
+			total += us[j].Weight
+			j++
+		}
+		tier := append([]*upstream(nil), us[i:j]...)
+		for len(tier) > 0 {
+			n := rand.Intn(total)
+			k := 0
+			for n >= tier[k].Weight {
+				n -= tier[k].Weight
+				k++
+			}
+			out = append(out, tier[k])
+			total -= tier[k].Weight
+			tier = append(tier[:k], tier[k+1:]...)
+		}
+		i = j
+	}
+	return out
+}
+
+func (u *upstream) isDown(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return now.Before(u.downUntil)
+}
+
+func (u *upstream) down(until time.Time) {
+	u.mu.Lock()
+	u.downUntil = until
+	u.mu.Unlock()
+}
+
+func (u *upstream) up() {
+	u.mu.Lock()
+	u.downUntil = time.Time{}
+	u.mu.Unlock()
+}
@@ -0,0 +1,249 @@
+// Package quota meters tool calls per session or principal and rejects
+// calls beyond the configured limits.
+//
+// Usage is counted in fixed windows: calls, bytes of arguments and
+// results, and cost units weighted per tool. It is kept in a store.Store
+// so that several server processes sharing a store enforce a common quota.
+// Callers can read their own usage from the zenmcp://quota resource.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+)
+
+// ResourceURI is the meta-resource reporting the caller's usage.
+const ResourceURI = "zenmcp://quota"
+
+// Limits bounds usage within a window. Zero fields are unlimited.
+type Limits struct {
+	Calls int64   `json:"calls,omitempty"`
+	Bytes int64   `json:"bytes,omitempty"`
+	Cost  float64 `json:"cost,omitempty"`
+}
+
+// Config configures quota enforcement.
+type Config struct {
+	// Store holds usage. Required.
+	Store store.Store
+	// Window is the length of the accounting period. Usage resets at
+	// multiples of Window since the Unix epoch. Defaults to one hour.
+	Window time.Duration
+	// Limits applies to every key.
+	Limits Limits
+	// Costs weighs calls by tool name. Tools not listed cost 1.
+	Costs map[string]float64
+	// Key returns the identity usage is accounted to. The default uses
+	// the principal's subject when the caller is authenticated and the
+	// session ID otherwise, qualified by the tenant if any.
+	Key func(ctx *runtime.Context) string
+	// Clock places calls in windows. Defaults to the server's clock.
+	Clock clock.Clock
+}
+
+// Usage is the consumption of one key within the current window.
+type Usage struct {
+	Key         string    `json:"key"`
+	WindowStart time.Time `json:"windowStart"`
+	ResetAt     time.Time `json:"resetAt"`
+	Calls       int64     `json:"calls"`
+	Bytes       int64     `json:"bytes"`
+	Cost        float64   `json:"cost"`
+	Limits      Limits    `json:"limits"`
+}
+
+// ExceededData is the data of the QuotaExceeded error returned for calls
+// over quota.
+type ExceededData struct {
+	Resource string    `json:"resource"`
+	Used     float64   `json:"used"`
+	Limit    float64   `json:"limit"`
+	ResetAt  time.Time `json:"resetAt"`
+}
+
+// Quota enforces a Config on a server.
+type Quota struct {
+	cfg    Config
+	limits atomic.Pointer[Limits]
+	// mu serializes read-modify-write cycles on stores that are not
+	// store.Transactional.
+	mu sync.Mutex
+}
+
+// Install enforces cfg on the tool calls of s and registers the
+// zenmcp://quota resource. It must be called before serving starts.
+func Install(s *mcp.Server, cfg Config) (*Quota, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("quota: a store is required")
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Hour
+	}
+	if cfg.Key == nil {
+		cfg.Key = defaultKey
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = s.Clock()
+	}
+	q := &Quota{cfg: cfg}
+	q.limits.Store(&cfg.Limits)
+	s.Router().InterceptToolCalls(q.intercept)
+	err := mcp.RegisterResourceTyped(s, registry.ResourceDescriptor{
+		URI:         ResourceURI,
+		Name:        "quota",
+		Description: "Your usage and limits in the current quota window.",
+	}, func(ctx *runtime.Context, uri string) (*Usage, error) {
+		return q.Usage(ctx, cfg.Key(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func defaultKey(ctx *runtime.Context) string {
+	if t := ctx.Tenant(); t != "" {
+		return "tenant:" + t + "/" + identity(ctx)
+	}
+	return identity(ctx)
+}
+
+func identity(ctx *runtime.Context) string {
+	if p := ctx.Principal(); p != nil && p.Subject != "" {
+		return "principal:" + p.Subject
+	}
+	if s := ctx.Session(); s != nil {
+		return "session:" + s.ID()
+	}
+	return "anonymous"
+}
+
+// Usage returns the usage of key in the current window.
+func (q *Quota) Usage(ctx context.Context, key string) (*Usage, error) {
+	return q.load(ctx, q.cfg.Store, key, q.cfg.Clock.Now())
+}
+
+// Limits returns the limits in effect.
+func (q *Quota) Limits() Limits { return *q.limits.Load() }
+
+// SetLimits replaces the limits applied to every key. Calls made after it
+// returns are checked against the new limits; usage already counted in
+// the current window is kept.
+func (q *Quota) SetLimits(l Limits) { q.limits.Store(&l) }
+
+// Reset clears the usage of key.
+func (q *Quota) Reset(ctx context.Context, key string) error {
+	return q.cfg.Store.Delete(ctx, storeKey(key))
+}
+
+func (q *Quota) intercept(ctx *runtime.Context, tool *registry.ToolDescriptor, args json.RawMessage, next registry.ToolHandler) (*protocol.ToolCallResult, error) {
+	key := q.cfg.Key(ctx)
+	cost, ok := q.cfg.Costs[tool.Name]
+	if !ok {
+		cost = 1
+	}
+	err := q.update(ctx, key, func(u *Usage) error {
+		if err := q.check(u, 1, int64(len(args)), cost); err != nil {
+			return err
+		}
+		u.Calls++
+		u.Bytes += int64(len(args))
+		u.Cost += cost
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := next(ctx, args)
+	if res != nil {
+		if data, merr := json.Marshal(res); merr == nil {
+			n := int64(len(data))
+			if uerr := q.update(ctx, key, func(u *Usage) error { u.Bytes += n; return nil }); uerr != nil {
+				ctx.Logger().Warn("quota: recording result size", "key", key, "error", uerr)
+			}
+		}
+	}
+	return res, err
+}
+
+// check returns a QuotaExceeded error when adding the given amounts would
+// exceed a limit.
+func (q *Quota) check(u *Usage, calls, bytes int64, cost float64) error {
+	l := q.Limits()
+	exceeded := func(resource string, used, limit float64) error {
+		return protocol.NewError(protocol.QuotaExceeded,
+			fmt.Sprintf("quota exceeded: %s limit of %g per %s reached; resets at %s",
+				resource, limit, q.cfg.Window, u.ResetAt.UTC().Format(time.RFC3339)),
+			ExceededData{Resource: resource, Used: used, Limit: limit, ResetAt: u.ResetAt})
+	}
+	switch {
+	case l.Calls > 0 && u.Calls+calls > l.Calls:
+		return exceeded("calls", float64(u.Calls), float64(l.Calls))
+	case l.Bytes > 0 && u.Bytes+bytes > l.Bytes:
+		return exceeded("bytes", float64(u.Bytes), float64(l.Bytes))
+	case l.Cost > 0 && u.Cost+cost > l.Cost:
+		return exceeded("cost", u.Cost, l.Cost)
+	}
+	return nil
+}
+
+// update applies fn to the usage of key and saves it unless fn fails.
+func (q *Quota) update(ctx context.Context, key string, fn func(u *Usage) error) error {
+	apply := func(tx store.Store) error {
+		u, err := q.load(ctx, tx, key, q.cfg.Clock.Now())
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+		data, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		return tx.Put(ctx, storeKey(key), data)
+	}
+	if t, ok := q.cfg.Store.(store.Transactional); ok {
+		return t.Update(ctx, apply)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return apply(q.cfg.Store)
+}
+
+// load reads the usage of key, starting afresh when the stored usage
+// belongs to an earlier window.
+func (q *Quota) load(ctx context.Context, st store.Store, key string, now time.Time) (*Usage, error) {
+	start := now.Truncate(q.cfg.Window)
+	fresh := &Usage{Key: key, WindowStart: start, ResetAt: start.Add(q.cfg.Window), Limits: q.Limits()}
+	data, err := st.Get(ctx, storeKey(key))
+	if errors.Is(err, store.ErrNotFound) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u Usage
+	if err := json.Unmarshal(data, &u); err != nil || !u.WindowStart.Equal(start) {
+		return fresh, nil
+	}
+	u.Limits = q.Limits()
+	return &u, nil
+}
+
+func storeKey(key string) string {
+	return "quota/usage/" + url.PathEscape(key)
+}
@@ -0,0 +1,253 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// ToolHandler executes a tool call. When invoked by the router, ctx is the
+// request's *runtime.Context.
+type ToolHandler func(ctx context.Context, args json.RawMessage) (*protocol.ToolCallResult, error)
+
+// ResourceHandler reads the resource identified by uri.
+type ResourceHandler func(ctx context.Context, uri string) (*protocol.ReadResourceResult, error)
+
+// PromptHandler renders a prompt from its arguments.
+type PromptHandler func(ctx context.Context, args map[string]string) (*protocol.GetPromptResult, error)
+
+// ArgumentTransform rewrites the arguments of a tool call before they are
+// checked against the tool's input schema, for instance to inject the
+// caller's tenant or to resolve friendly names into internal IDs. A
+// *protocol.Error it returns fails the call with that error; other errors
+// fail it as invalid arguments.
+type ArgumentTransform func(ctx context.Context, args json.RawMessage) (json.RawMessage, error)
+
+// ResultTransform rewrites the result of a tool call before it is sent,
+// for instance to render or reformat its content. It may modify res in
+// place. A *protocol.Error it returns fails the call with that error;
+// other errors become error results, as handler errors do.
+type ResultTransform func(ctx context.Context, res *protocol.ToolCallResult) (*protocol.ToolCallResult, error)
+
+// ToolDescriptor describes a tool and the handler implementing it.
+type ToolDescriptor struct {
+	Name string
+	// Version distinguishes several registrations of the same tool name,
+	// letting a tool's schema change without breaking existing callers.
+	// See Registry.RegisterTool for how versions are resolved.
+	Version     string
+	Description string
+	// Descriptions holds translations of Description keyed by BCP 47
+	// language tag, such as "de" or "pt-BR".
+	Descriptions map[string]string
+	InputSchema  map[string]interface{}
+	Annotations  *protocol.ToolAnnotations
+	Handler      ToolHandler
+	// Transforms rewrite the call's arguments, in order, before they are
+	// validated and reach Handler and the router's interceptors.
+	Transforms []ArgumentTransform
+	// ResultTransforms rewrite the call's result, in order, once Handler
+	// and the interceptors returned it. They see error results too.
+	ResultTransforms []ResultTransform
+	// Tags group tools for discovery; clients and session profiles select
+	// tools by tag.
+	Tags []string
+
+	// Deprecated marks the tool as scheduled for removal. It is flagged in
+	// tools/list and every call result carries a warning.
+	Deprecated bool
+	// DeprecationMessage tells callers what to use instead.
+	DeprecationMessage string
+
+	// Init prepares expensive dependencies such as connection pools or
+	// model clients. It runs once when the server starts, or on the first
+	// call when LazyInit is set. A failed lazy Init is retried by the next
+	// call; concurrent callers share a single in-flight Init.
+	Init func(ctx context.Context) error
+	// Shutdown releases whatever Init acquired. It runs when the server
+	// stops, and only if Init completed successfully.
+	Shutdown func(ctx context.Context) error
+	// LazyInit defers Init until the tool is first called.
+	LazyInit bool
+
+	// UseNumber decodes the numbers in the call's arguments as json.Number
+	// instead of float64 wherever they land in an interface{}, so that
+	// integers beyond 2^53, such as int64 IDs, keep every digit. The
+	// router's Config.UseNumber enables it for every tool.
+	UseNumber bool
+
+	// FastPath has mcp.RegisterToolTyped decode the tool's arguments with
+	// a decoder compiled for their type, which checks them against
+	// InputSchema as it decodes (see schema.Decoder), instead of checking
+	// them generically and decoding them with encoding/json. Argument
+	// types it does not support, and calls it cannot decide, take the
+	// usual path.
+	FastPath bool
+	// ChecksArguments reports that Handler checks the call's arguments
+	// against InputSchema itself, so the router does not. RegisterToolTyped
+	// sets it for the tools it gives a fast path.
+	ChecksArguments bool
+
+	// Async runs calls as background jobs when the server has a job
+	// manager (see package jobs): the call returns a job ID at once and
+	// the client collects the result later. Without one it has no effect.
+	Async bool
+
+	// Cost is the declared cost of one call, in units of the server's
+	// choosing, charged against call budgets (see package budget). Zero
+	// counts as 1.
+	Cost float64
+
+	// Source records how a tool configured at run time was built; see
+	// Registry.Snapshot.
+	Source *Source
+}
+
+// Tool returns the protocol representation used in tools/list.
+func (d *ToolDescriptor) Tool() protocol.Tool {
+	schema := d.InputSchema
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object"}
+	}
+	annotations := d.Annotations
+	if d.Deprecated {
+		a := protocol.ToolAnnotations{}
+		if annotations != nil {
+			a = *annotations
+		}
+		a.Deprecated = true
+		a.DeprecationMessage = d.DeprecationMessage
+		annotations = &a
+	}
+	return protocol.Tool{
+		Name:        d.Name,
+		Description: d.Description,
+		InputSchema: schema,
+		Annotations: annotations,
+	}
+}
+
+// DeprecationWarning returns the warning attached to results of a
+// deprecated tool.
+func (d *ToolDescriptor) DeprecationWarning() string {
+	w := fmt.Sprintf("Warning: tool %q is deprecated and will be removed.", d.Name)
+	if d.DeprecationMessage != "" {
+		w += " " + d.DeprecationMessage
+	}
+	return w
+}
+
+// Matches reports whether the tool passes f. A nil filter matches every
+// tool.
+func (d *ToolDescriptor) Matches(f *protocol.ToolFilter) bool {
+	if f == nil {
+		return true
+	}
+	for _, t := range f.ExcludeTags {
+		if d.hasTag(t) {
+			return false
+		}
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, t := range f.Tags {
+		if d.hasTag(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *ToolDescriptor) hasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the name the tool is registered under: "name@version" for
+// versioned tools, the plain name otherwise.
+func (d *ToolDescriptor) Key() string {
+	if d.Version == "" {
+		return d.Name
+	}
+	return d.Name + "@" + d.Version
+}
+
+// DescriptionFor returns the description best matching locale, or
+// Description when no translation matches.
+func (d *ToolDescriptor) DescriptionFor(locale string) string {
+	return localize(d.Descriptions, d.Description, locale)
+}
+
+// ResourceDescriptor describes a resource and the handler reading it.
+type ResourceDescriptor struct {
+	URI         string
+	Name        string
+	Description string
+	MimeType    string
+	// Subscribable marks resources whose changes the server announces
+	// with notifications/resources/updated, so that clients may subscribe
+	// to them.
+	Subscribable bool
+	Handler      ResourceHandler
+	// Source records how a resource configured at run time was built.
+	Source *Source
+}
+
+// Resource returns the protocol representation used in resources/list.
+func (d *ResourceDescriptor) Resource() protocol.Resource {
+	return protocol.Resource{
+		URI:         d.URI,
+		Name:        d.Name,
+		Description: d.Description,
+		MimeType:    d.MimeType,
+	}
+}
+
+// Argument describes an argument accepted by a prompt.
+type Argument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptDescriptor describes a prompt and the handler rendering it.
+type PromptDescriptor struct {
+	Name        string
+	Description string
+	// Descriptions holds translations of Description keyed by BCP 47
+	// language tag.
+	Descriptions map[string]string
+	Arguments    []Argument
+	Handler      PromptHandler
+	// Cache, when set, caches rendered results by arguments. Use it for
+	// prompts whose assembly is deterministic.
+	Cache *PromptCache
+	// Source records how a prompt configured at run time was built.
+	Source *Source
+}
+
+// DescriptionFor returns the description best matching locale, or
+// Description when no translation matches.
+func (d *PromptDescriptor) DescriptionFor(locale string) string {
+	return localize(d.Descriptions, d.Description, locale)
+}
+
+// Prompt returns the protocol representation used in prompts/list.
+func (d *PromptDescriptor) Prompt() protocol.Prompt {
+	p := protocol.Prompt{Name: d.Name, Description: d.Description}
+	for _, a := range d.Arguments {
+		p.Arguments = append(p.Arguments, protocol.PromptArgument{
+			Name:        a.Name,
+			Description: a.Description,
+			Required:    a.Required,
+		})
+	}
+	return p
+}
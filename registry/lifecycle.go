@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"context"
+	"sync"
+)
+
+// lifecycle tracks the Init/Shutdown state of a single descriptor.
+type lifecycle struct {
+	name     string
+	init     func(ctx context.Context) error
+	shutdown func(ctx context.Context) error
+	lazy     bool
+
+	mu    sync.Mutex
+	ready bool
+	call  *initCall
+}
+
+// initCall is an Init in flight, shared by every caller that arrives while
+// it runs.
+type initCall struct {
+	done chan struct{}
+	err  error
+}
+
+// ensure runs Init unless it already succeeded. Concurrent callers wait for
+// the same attempt; a failed attempt is retried by the next caller.
+func (l *lifecycle) ensure(ctx context.Context) error {
+	if l.init == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if l.ready {
+		l.mu.Unlock()
+		return nil
+	}
+	if c := l.call; c != nil {
+		l.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	c := &initCall{done: make(chan struct{})}
+	l.call = c
+	l.mu.Unlock()
+
+	c.err = l.init(ctx)
+
+	l.mu.Lock()
+	l.call = nil
+	l.ready = c.err == nil
+	l.mu.Unlock()
+	close(c.done)
+	return c.err
+}
+
+// stop runs Shutdown if Init completed (or there is no Init) and marks the
+// descriptor uninitialized again.
+func (l *lifecycle) stop(ctx context.Context) error {
+	l.mu.Lock()
+	ready := l.ready || l.init == nil
+	l.ready = false
+	l.mu.Unlock()
+	if !ready || l.shutdown == nil {
+		return nil
+	}
+	return l.shutdown(ctx)
+}
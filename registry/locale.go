@@ -0,0 +1,29 @@
+package registry
+
+import "strings"
+
+// localize returns the entry of descs best matching locale, falling back
+// from "zh-Hant-TW" to "zh-Hant" to "zh", and finally to def. Tags are
+// matched case-insensitively and "_" is accepted in place of "-".
+func localize(descs map[string]string, def, locale string) string {
+	if len(descs) == 0 || locale == "" {
+		return def
+	}
+	want := normalizeTag(locale)
+	for {
+		for tag, d := range descs {
+			if normalizeTag(tag) == want {
+				return d
+			}
+		}
+		i := strings.LastIndexByte(want, '-')
+		if i < 0 {
+			return def
+		}
+		want = want[:i]
+	}
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+}
@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// defaultPromptCacheEntries bounds a prompt cache when MaxEntries is unset.
+const defaultPromptCacheEntries = 1000
+
+// PromptCache enables reuse of a prompt's rendered results for calls with
+// the same arguments. Failed renders are not cached.
+type PromptCache struct {
+	// TTL is how long a result is reused. A prompt with a non-positive TTL
+	// is not cached.
+	TTL time.Duration
+	// MaxEntries bounds the number of cached argument sets. Defaults to
+	// 1000.
+	MaxEntries int
+	// Vary, when set, returns a string added to the cache key, for
+	// prompts whose output depends on the caller, such as their locale.
+	Vary func(ctx context.Context) string
+	// Clock expires entries. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// promptCache holds the cached results of one prompt.
+type promptCache struct {
+	cfg PromptCache
+
+	mu      sync.Mutex
+	entries map[string]promptCacheEntry
+}
+
+type promptCacheEntry struct {
+	result  *protocol.GetPromptResult
+	expires time.Time
+}
+
+func newPromptCache(cfg PromptCache) *promptCache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultPromptCacheEntries
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &promptCache{cfg: cfg, entries: make(map[string]promptCacheEntry)}
+}
+
+// wrap returns h with results cached.
+func (c *promptCache) wrap(h PromptHandler) PromptHandler {
+	return func(ctx context.Context, args map[string]string) (*protocol.GetPromptResult, error) {
+		key := c.key(ctx, args)
+		if res, ok := c.get(key); ok {
+			return res, nil
+		}
+		res, err := h(ctx, args)
+		if err != nil || res == nil {
+			return res, err
+		}
+		c.put(key, res)
+		return copyPromptResult(res), nil
+	}
+}
+
+// key encodes the arguments in a canonical order.
+func (c *promptCache) key(ctx context.Context, args map[string]string) string {
+	names := make([]string, 0, len(args))
+	for k := range args {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	if c.cfg.Vary != nil {
+		b.WriteString(c.cfg.Vary(ctx))
+	}
+	for _, k := range names {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(args[k])
+	}
+	return b.String()
+}
+
+func (c *promptCache) get(key string) (*protocol.GetPromptResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.cfg.Clock.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return copyPromptResult(e.result), true
+}
+
+func (c *promptCache) put(key string, res *protocol.GetPromptResult) {
+	now := c.cfg.Clock.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.cfg.MaxEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: drop an arbitrary entry.
+		for k := range c.entries {
+			if len(c.entries) < c.cfg.MaxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = promptCacheEntry{result: copyPromptResult(res), expires: now.Add(c.cfg.TTL)}
+}
+
+func (c *promptCache) clear() {
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+// copyPromptResult copies res so that callers modifying a result do not
+// alter the cached one.
+func copyPromptResult(res *protocol.GetPromptResult) *protocol.GetPromptResult {
+	out := *res
+	out.Messages = append([]protocol.PromptMessage(nil), res.Messages...)
+	return &out
+}
+
+// InvalidatePrompt drops the cached results of the named prompt, for
+// example after the data it is assembled from has changed.
+func (r *Registry) InvalidatePrompt(name string) {
+	r.mu.RLock()
+	c, ok := r.promptCaches[name]
+	r.mu.RUnlock()
+	if ok {
+		c.clear()
+	}
+}
+
+// InvalidatePrompts drops the cached results of every prompt.
+func (r *Registry) InvalidatePrompts() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.promptCaches {
+		c.clear()
+	}
+}
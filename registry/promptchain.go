@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// PromptRef references another prompt from a chained prompt.
+type PromptRef struct {
+	// Name is the referenced prompt.
+	Name string
+	// Args sets the referenced prompt's arguments. A value of the form
+	// "{arg}" forwards the including prompt's argument named arg, or is
+	// dropped when that argument was not given.
+	Args map[string]string
+	// ForwardAll passes every argument of the including prompt that Args
+	// does not set.
+	ForwardAll bool
+}
+
+// args returns the arguments of the referenced prompt given those of the
+// including one.
+func (ref PromptRef) args(outer map[string]string) map[string]string {
+	out := make(map[string]string, len(ref.Args))
+	if ref.ForwardAll {
+		for k, v := range outer {
+			out[k] = v
+		}
+	}
+	for k, v := range ref.Args {
+		if name, ok := strings.CutPrefix(v, "{"); ok && strings.HasSuffix(name, "}") {
+			name = strings.TrimSuffix(name, "}")
+			if fv, ok := outer[name]; ok {
+				out[k] = fv
+			} else {
+				delete(out, k)
+			}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+type renderingKey struct{}
+
+// RenderPrompt renders the named prompt after checking that its required
+// arguments are present. Prompts rendered from within it through Include
+// or Chain are checked for cycles.
+func (r *Registry) RenderPrompt(ctx context.Context, name string, args map[string]string) (*protocol.GetPromptResult, error) {
+	d, ok := r.Prompt(name)
+	if !ok {
+		return nil, fmt.Errorf("registry: unknown prompt %q", name)
+	}
+	for _, a := range d.Arguments {
+		if _, ok := args[a.Name]; a.Required && !ok {
+			return nil, fmt.Errorf("registry: prompt %q: missing required argument %q", name, a.Name)
+		}
+	}
+	stack, _ := ctx.Value(renderingKey{}).([]string)
+	for i, n := range stack {
+		if n == name {
+			return nil, fmt.Errorf("registry: prompt include cycle: %s -> %s", strings.Join(stack[i:], " -> "), name)
+		}
+	}
+	stack = append(stack[:len(stack):len(stack)], name)
+	res, err := d.Handler(context.WithValue(ctx, renderingKey{}, stack), args)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		res = &protocol.GetPromptResult{}
+	}
+	return res, nil
+}
+
+// Include renders the prompt ref points to, forwarding arguments from
+// args, and returns its messages. Prompt handlers use it to embed other
+// prompts in their own message list.
+func (r *Registry) Include(ctx context.Context, ref PromptRef, args map[string]string) ([]protocol.PromptMessage, error) {
+	res, err := r.RenderPrompt(ctx, ref.Name, ref.args(args))
+	if err != nil {
+		return nil, err
+	}
+	return res.Messages, nil
+}
+
+// Chain returns a prompt handler whose messages are those of the
+// referenced prompts, in order. The prompts are resolved when the handler
+// runs, so they may be registered after the chaining prompt.
+func (r *Registry) Chain(refs ...PromptRef) PromptHandler {
+	return func(ctx context.Context, args map[string]string) (*protocol.GetPromptResult, error) {
+		res := &protocol.GetPromptResult{Messages: []protocol.PromptMessage{}}
+		for _, ref := range refs {
+			msgs, err := r.Include(ctx, ref, args)
+			if err != nil {
+				return nil, err
+			}
+			res.Messages = append(res.Messages, msgs...)
+		}
+		return res, nil
+	}
+}
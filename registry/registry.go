@@ -0,0 +1,468 @@
+// Package registry stores the tools, resources and prompts exposed by a
+// server.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds registered descriptors keyed by name (tools, prompts) or
+// URI (resources). It is safe for concurrent use: entries may be added and
+// removed while the server is serving, and the functions registered with
+// OnChange learn of every change.
+type Registry struct {
+	mu sync.RWMutex
+
+	tools     map[string]*ToolDescriptor // by Key
+	versions  map[string][]string        // tool name -> versions, ascending
+	pinned    map[string]string          // tool name -> default version
+	resources map[string]*ResourceDescriptor
+	templates []*ResourceTemplateDescriptor
+	prompts   map[string]*PromptDescriptor
+
+	promptCaches map[string]*promptCache
+
+	hooks     []*lifecycle
+	toolHooks map[string]*lifecycle
+
+	listeners []func(ChangeKind)
+}
+
+// ChangeKind tells which of a registry's lists a change affected.
+type ChangeKind int
+
+const (
+	// ToolsChanged reports added or removed tools and changed default
+	// tool versions.
+	ToolsChanged ChangeKind = iota + 1
+	// ResourcesChanged reports added or removed resources and resource
+	// templates.
+	ResourcesChanged
+	// PromptsChanged reports added or removed prompts.
+	PromptsChanged
+)
+
+// OnChange calls fn after every change to r, with the list it affected.
+// fn runs in the goroutine making the change, once r is unlocked, so it
+// may read r but should not block.
+func (r *Registry) OnChange(fn func(ChangeKind)) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, fn)
+	r.mu.Unlock()
+}
+
+// update runs f with r locked and, when f succeeds, tells the listeners
+// about the change.
+func (r *Registry) update(kind ChangeKind, f func() error) error {
+	r.mu.Lock()
+	err := f()
+	listeners := r.listeners
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, fn := range listeners {
+		fn(kind)
+	}
+	return nil
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{
+		tools:     make(map[string]*ToolDescriptor),
+		versions:  make(map[string][]string),
+		pinned:    make(map[string]string),
+		resources: make(map[string]*ResourceDescriptor),
+		prompts:   make(map[string]*PromptDescriptor),
+		toolHooks: make(map[string]*lifecycle),
+
+		promptCaches: make(map[string]*promptCache),
+	}
+}
+
+// RegisterTool adds a tool under name. desc.Name defaults to name and must
+// match it when set.
+//
+// Several versions of a tool may be registered by setting desc.Version or
+// by passing name as "name@version". Calls addressing the plain name go to
+// the default version: the highest one unless another was chosen with
+// SetDefaultToolVersion. Other versions stay reachable as "name@version".
+// A tool name is either versioned or not; the two cannot be mixed.
+func (r *Registry) RegisterTool(name string, desc ToolDescriptor) error {
+	if name == "" {
+		return errors.New("registry: tool name is required")
+	}
+	if base, version, ok := strings.Cut(name, "@"); ok {
+		if desc.Version != "" && desc.Version != version {
+			return fmt.Errorf("registry: tool descriptor version %q does not match %q", desc.Version, name)
+		}
+		name, desc.Version = base, version
+	}
+	if desc.Name == "" {
+		desc.Name = name
+	}
+	if desc.Name != name {
+		return fmt.Errorf("registry: tool descriptor name %q does not match %q", desc.Name, name)
+	}
+	if desc.Handler == nil {
+		return fmt.Errorf("registry: tool %q has no handler", name)
+	}
+	key := desc.Key()
+	return r.update(ToolsChanged, func() error {
+		if _, ok := r.tools[key]; ok {
+			return fmt.Errorf("registry: tool %q already registered", key)
+		}
+		_, unversioned := r.tools[name]
+		if desc.Version != "" && unversioned || desc.Version == "" && len(r.versions[name]) > 0 {
+			return fmt.Errorf("registry: tool %q cannot be registered both with and without a version", name)
+		}
+		r.tools[key] = &desc
+		if desc.Version != "" {
+			vs := append(r.versions[name][:len(r.versions[name]):len(r.versions[name])], desc.Version)
+			sort.Slice(vs, func(i, j int) bool { return compareVersions(vs[i], vs[j]) < 0 })
+			r.versions[name] = vs
+		}
+		if desc.Init != nil || desc.Shutdown != nil {
+			l := &lifecycle{name: key, init: desc.Init, shutdown: desc.Shutdown, lazy: desc.LazyInit}
+			r.hooks = append(r.hooks, l)
+			r.toolHooks[key] = l
+		}
+		return nil
+	})
+}
+
+// UnregisterTool removes the tool registered under name: the given
+// version for "name@version", and every version of a versioned tool for
+// its plain name. Calls already running complete. The tool's Shutdown
+// hook is not run; callers that need it run it once those calls are done.
+func (r *Registry) UnregisterTool(name string) error {
+	return r.update(ToolsChanged, func() error {
+		var keys []string
+		base, version, versioned := strings.Cut(name, "@")
+		switch {
+		case versioned:
+			if _, ok := r.tools[name]; !ok {
+				return fmt.Errorf("registry: tool %q not registered", name)
+			}
+			keys = []string{name}
+			var vs []string
+			for _, v := range r.versions[base] {
+				if v != version {
+					vs = append(vs, v)
+				}
+			}
+			if len(vs) > 0 {
+				r.versions[base] = vs
+			} else {
+				delete(r.versions, base)
+			}
+			if r.pinned[base] == version {
+				delete(r.pinned, base)
+			}
+		case len(r.versions[name]) > 0:
+			for _, v := range r.versions[name] {
+				keys = append(keys, name+"@"+v)
+			}
+			delete(r.versions, name)
+			delete(r.pinned, name)
+		default:
+			if _, ok := r.tools[name]; !ok {
+				return fmt.Errorf("registry: tool %q not registered", name)
+			}
+			keys = []string{name}
+		}
+		for _, key := range keys {
+			delete(r.tools, key)
+			if l, ok := r.toolHooks[key]; ok {
+				delete(r.toolHooks, key)
+				for i, h := range r.hooks {
+					if h == l {
+						r.hooks = append(r.hooks[:i:i], r.hooks[i+1:]...)
+						break
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// SetDefaultToolVersion makes version the one plain-name calls to the tool
+// resolve to, for example to keep an older version as the default while a
+// new one is rolled out.
+func (r *Registry) SetDefaultToolVersion(name, version string) error {
+	return r.update(ToolsChanged, func() error {
+		if _, ok := r.tools[name+"@"+version]; !ok {
+			return fmt.Errorf("registry: tool %q has no version %q", name, version)
+		}
+		r.pinned[name] = version
+		return nil
+	})
+}
+
+// DefaultToolVersion returns the version plain-name calls to the tool
+// resolve to, or "" for unversioned tools.
+func (r *Registry) DefaultToolVersion(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.defaultToolVersion(name)
+}
+
+func (r *Registry) defaultToolVersion(name string) string {
+	if v, ok := r.pinned[name]; ok {
+		return v
+	}
+	vs := r.versions[name]
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[len(vs)-1]
+}
+
+// RegisterResource adds a resource under uri. desc.URI defaults to uri and
+// must match it when set.
+func (r *Registry) RegisterResource(uri string, desc ResourceDescriptor) error {
+	if uri == "" {
+		return errors.New("registry: resource uri is required")
+	}
+	if desc.URI == "" {
+		desc.URI = uri
+	}
+	if desc.URI != uri {
+		return fmt.Errorf("registry: resource descriptor uri %q does not match %q", desc.URI, uri)
+	}
+	if desc.Handler == nil {
+		return fmt.Errorf("registry: resource %q has no handler", uri)
+	}
+	if desc.Name == "" {
+		desc.Name = uri
+	}
+	return r.update(ResourcesChanged, func() error {
+		if _, ok := r.resources[uri]; ok {
+			return fmt.Errorf("registry: resource %q already registered", uri)
+		}
+		r.resources[uri] = &desc
+		return nil
+	})
+}
+
+// UnregisterResource removes the resource registered under uri.
+func (r *Registry) UnregisterResource(uri string) error {
+	return r.update(ResourcesChanged, func() error {
+		if _, ok := r.resources[uri]; !ok {
+			return fmt.Errorf("registry: resource %q not registered", uri)
+		}
+		delete(r.resources, uri)
+		return nil
+	})
+}
+
+// RegisterPrompt adds a prompt under name. desc.Name defaults to name and
+// must match it when set. When desc.Cache is set, desc.Handler is wrapped
+// so that results are reused until they expire or InvalidatePrompt is
+// called.
+func (r *Registry) RegisterPrompt(name string, desc PromptDescriptor) error {
+	if name == "" {
+		return errors.New("registry: prompt name is required")
+	}
+	if desc.Name == "" {
+		desc.Name = name
+	}
+	if desc.Name != name {
+		return fmt.Errorf("registry: prompt descriptor name %q does not match %q", desc.Name, name)
+	}
+	if desc.Handler == nil {
+		return fmt.Errorf("registry: prompt %q has no handler", name)
+	}
+	return r.update(PromptsChanged, func() error {
+		if _, ok := r.prompts[name]; ok {
+			return fmt.Errorf("registry: prompt %q already registered", name)
+		}
+		if desc.Cache != nil && desc.Cache.TTL > 0 {
+			c := newPromptCache(*desc.Cache)
+			r.promptCaches[name] = c
+			desc.Handler = c.wrap(desc.Handler)
+		}
+		r.prompts[name] = &desc
+		return nil
+	})
+}
+
+// UnregisterPrompt removes the prompt registered under name, along with
+// its cached results.
+func (r *Registry) UnregisterPrompt(name string) error {
+	return r.update(PromptsChanged, func() error {
+		if _, ok := r.prompts[name]; !ok {
+			return fmt.Errorf("registry: prompt %q not registered", name)
+		}
+		delete(r.prompts, name)
+		delete(r.promptCaches, name)
+		return nil
+	})
+}
+
+// Tool returns the tool registered under name, which is either a plain
+// tool name, resolved to its default version, or "name@version".
+func (r *Registry) Tool(name string) (*ToolDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if d, ok := r.tools[name]; ok {
+		return d, true
+	}
+	if v := r.defaultToolVersion(name); v != "" {
+		d, ok := r.tools[name+"@"+v]
+		return d, ok
+	}
+	return nil, false
+}
+
+// ToolVersion returns the given version of the named tool.
+func (r *Registry) ToolVersion(name, version string) (*ToolDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.tools[name+"@"+version]
+	return d, ok
+}
+
+// Resource returns the resource registered under uri.
+func (r *Registry) Resource(uri string) (*ResourceDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.resources[uri]
+	return d, ok
+}
+
+// Prompt returns the prompt registered under name.
+func (r *Registry) Prompt(name string) (*PromptDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.prompts[name]
+	return d, ok
+}
+
+// Tools returns all tools sorted by name, versions of a tool in ascending
+// order.
+func (r *Registry) Tools() []*ToolDescriptor {
+	r.mu.RLock()
+	out := make([]*ToolDescriptor, 0, len(r.tools))
+	for _, d := range r.tools {
+		out = append(out, d)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return compareVersions(out[i].Version, out[j].Version) < 0
+	})
+	return out
+}
+
+// Resources returns all resources sorted by URI.
+func (r *Registry) Resources() []*ResourceDescriptor {
+	r.mu.RLock()
+	out := make([]*ResourceDescriptor, 0, len(r.resources))
+	for _, d := range r.resources {
+		out = append(out, d)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].URI < out[j].URI })
+	return out
+}
+
+// Prompts returns all prompts sorted by name.
+func (r *Registry) Prompts() []*PromptDescriptor {
+	r.mu.RLock()
+	out := make([]*PromptDescriptor, 0, len(r.prompts))
+	for _, d := range r.prompts {
+		out = append(out, d)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Init runs the Init hook of every tool not marked LazyInit, in
+// registration order. It stops at the first failure.
+func (r *Registry) Init(ctx context.Context) error {
+	r.mu.RLock()
+	hooks := r.hooks
+	r.mu.RUnlock()
+	for _, l := range hooks {
+		if l.lazy {
+			continue
+		}
+		if err := l.ensure(ctx); err != nil {
+			return fmt.Errorf("registry: init tool %q: %w", l.name, err)
+		}
+	}
+	return nil
+}
+
+// EnsureToolInit runs the Init hook of the tool registered under key (see
+// ToolDescriptor.Key) if it has not completed yet. The router calls it
+// before every invocation so that lazy tools are initialized on first use.
+func (r *Registry) EnsureToolInit(ctx context.Context, key string) error {
+	r.mu.RLock()
+	l, ok := r.toolHooks[key]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	if err := l.ensure(ctx); err != nil {
+		return fmt.Errorf("init tool %q: %w", key, err)
+	}
+	return nil
+}
+
+// Shutdown runs the Shutdown hook of every initialized tool in reverse
+// registration order. All hooks run; their errors are joined.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.RLock()
+	hooks := r.hooks
+	r.mu.RUnlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		l := hooks[i]
+		if err := l.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("registry: shutdown tool %q: %w", l.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compareVersions orders version strings such as "1", "1.2" and "v2.0.1"
+// by their dot-separated components, numerically where both components
+// are numbers.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil && xn != yn:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case (xerr != nil || yerr != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,240 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// Snapshot.
+const SnapshotVersion = 1
+
+// Source records how a tool, resource or prompt configured at run time
+// was built, so that Restore can build it again: the name of a factory in
+// Factories and the configuration it was built from. Entries registered in
+// code need none, as the code registers them again on every start.
+type Source struct {
+	Factory string          `json:"factory"`
+	Config  json.RawMessage `json:"config,omitempty"`
+}
+
+// Factories build the handlers of restored entries from their Source.
+// Each factory receives the restored descriptor, with Source set and
+// Handler nil, and fills in Handler and whatever else, such as lifecycle
+// hooks or a prompt cache, cannot be serialized.
+type Factories struct {
+	Tools     map[string]func(d *ToolDescriptor) error
+	Resources map[string]func(d *ResourceDescriptor) error
+	Prompts   map[string]func(d *PromptDescriptor) error
+}
+
+// Snapshot is the serializable state of a registry: the descriptors of its
+// tools, resources and prompts without their handlers, and the default
+// tool versions chosen with SetDefaultToolVersion. Resource templates are
+// not included.
+type Snapshot struct {
+	Version         int               `json:"version"`
+	Tools           []ToolState       `json:"tools"`
+	Resources       []ResourceState   `json:"resources,omitempty"`
+	Prompts         []PromptState     `json:"prompts,omitempty"`
+	DefaultVersions map[string]string `json:"defaultVersions,omitempty"`
+}
+
+// ToolState is a ToolDescriptor in a Snapshot.
+type ToolState struct {
+	Name               string                    `json:"name"`
+	Version            string                    `json:"version,omitempty"`
+	Description        string                    `json:"description,omitempty"`
+	Descriptions       map[string]string         `json:"descriptions,omitempty"`
+	InputSchema        map[string]interface{}    `json:"inputSchema,omitempty"`
+	Annotations        *protocol.ToolAnnotations `json:"annotations,omitempty"`
+	Tags               []string                  `json:"tags,omitempty"`
+	Deprecated         bool                      `json:"deprecated,omitempty"`
+	DeprecationMessage string                    `json:"deprecationMessage,omitempty"`
+	LazyInit           bool                      `json:"lazyInit,omitempty"`
+	UseNumber          bool                      `json:"useNumber,omitempty"`
+	Async              bool                      `json:"async,omitempty"`
+	Cost               float64                   `json:"cost,omitempty"`
+	Source             *Source                   `json:"source,omitempty"`
+}
+
+// ResourceState is a ResourceDescriptor in a Snapshot.
+type ResourceState struct {
+	URI          string  `json:"uri"`
+	Name         string  `json:"name,omitempty"`
+	Description  string  `json:"description,omitempty"`
+	MimeType     string  `json:"mimeType,omitempty"`
+	Subscribable bool    `json:"subscribable,omitempty"`
+	Source       *Source `json:"source,omitempty"`
+}
+
+// PromptState is a PromptDescriptor in a Snapshot.
+type PromptState struct {
+	Name         string            `json:"name"`
+	Description  string            `json:"description,omitempty"`
+	Descriptions map[string]string `json:"descriptions,omitempty"`
+	Arguments    []Argument        `json:"arguments,omitempty"`
+	Source       *Source           `json:"source,omitempty"`
+}
+
+// Snapshot returns the state of r.
+func (r *Registry) Snapshot() *Snapshot {
+	snap := &Snapshot{Version: SnapshotVersion, Tools: []ToolState{}}
+	for _, d := range r.Tools() {
+		snap.Tools = append(snap.Tools, ToolState{
+			Name:               d.Name,
+			Version:            d.Version,
+			Description:        d.Description,
+			Descriptions:       d.Descriptions,
+			InputSchema:        d.InputSchema,
+			Annotations:        d.Annotations,
+			Tags:               d.Tags,
+			Deprecated:         d.Deprecated,
+			DeprecationMessage: d.DeprecationMessage,
+			LazyInit:           d.LazyInit,
+			UseNumber:          d.UseNumber,
+			Async:              d.Async,
+			Cost:               d.Cost,
+			Source:             d.Source,
+		})
+	}
+	for _, d := range r.Resources() {
+		snap.Resources = append(snap.Resources, ResourceState{
+			URI:          d.URI,
+			Name:         d.Name,
+			Description:  d.Description,
+			MimeType:     d.MimeType,
+			Subscribable: d.Subscribable,
+			Source:       d.Source,
+		})
+	}
+	for _, d := range r.Prompts() {
+		snap.Prompts = append(snap.Prompts, PromptState{
+			Name:         d.Name,
+			Description:  d.Description,
+			Descriptions: d.Descriptions,
+			Arguments:    d.Arguments,
+			Source:       d.Source,
+		})
+	}
+	r.mu.RLock()
+	if len(r.pinned) > 0 {
+		snap.DefaultVersions = make(map[string]string, len(r.pinned))
+		for name, v := range r.pinned {
+			snap.DefaultVersions[name] = v
+		}
+	}
+	r.mu.RUnlock()
+	return snap
+}
+
+// Restore registers the entries of snap that r lacks, building their
+// handlers with f, and then applies the snapshot's default tool versions.
+// Entries already registered, typically by code run before Restore, are
+// kept as they are. Restore carries on past entries it cannot register,
+// such as those without a Source whose code no longer registers them, and
+// returns the problems joined.
+func (r *Registry) Restore(snap *Snapshot, f Factories) error {
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("registry: unsupported snapshot version %d", snap.Version)
+	}
+	var errs []error
+	for _, s := range snap.Tools {
+		d := ToolDescriptor{
+			Name:               s.Name,
+			Version:            s.Version,
+			Description:        s.Description,
+			Descriptions:       s.Descriptions,
+			InputSchema:        s.InputSchema,
+			Annotations:        s.Annotations,
+			Tags:               s.Tags,
+			Deprecated:         s.Deprecated,
+			DeprecationMessage: s.DeprecationMessage,
+			LazyInit:           s.LazyInit,
+			UseNumber:          s.UseNumber,
+			Async:              s.Async,
+			Cost:               s.Cost,
+			Source:             s.Source,
+		}
+		key := d.Key()
+		if _, ok := r.Tool(key); ok {
+			continue
+		}
+		if err := build("tool", key, s.Source, f.Tools, &d); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := r.RegisterTool(key, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, s := range snap.Resources {
+		if _, ok := r.Resource(s.URI); ok {
+			continue
+		}
+		d := ResourceDescriptor{
+			URI:          s.URI,
+			Name:         s.Name,
+			Description:  s.Description,
+			MimeType:     s.MimeType,
+			Subscribable: s.Subscribable,
+			Source:       s.Source,
+		}
+		if err := build("resource", s.URI, s.Source, f.Resources, &d); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := r.RegisterResource(s.URI, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, s := range snap.Prompts {
+		if _, ok := r.Prompt(s.Name); ok {
+			continue
+		}
+		d := PromptDescriptor{
+			Name:         s.Name,
+			Description:  s.Description,
+			Descriptions: s.Descriptions,
+			Arguments:    s.Arguments,
+			Source:       s.Source,
+		}
+		if err := build("prompt", s.Name, s.Source, f.Prompts, &d); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := r.RegisterPrompt(s.Name, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	names := make([]string, 0, len(snap.DefaultVersions))
+	for name := range snap.DefaultVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := r.SetDefaultToolVersion(name, snap.DefaultVersions[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// build runs the factory named by src on d.
+func build[D any](kind, name string, src *Source, factories map[string]func(d *D) error, d *D) error {
+	if src == nil {
+		return fmt.Errorf("registry: %s %q is not registered and has no source to restore it from", kind, name)
+	}
+	factory, ok := factories[src.Factory]
+	if !ok {
+		return fmt.Errorf("registry: %s %q: unknown factory %q", kind, name, src.Factory)
+	}
+	if err := factory(d); err != nil {
+		return fmt.Errorf("registry: %s %q: %w", kind, name, err)
+	}
+	return nil
+}
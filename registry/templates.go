@@ -0,0 +1,165 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// ResourceTemplateHandler reads a resource matched by a template. vars
+// holds the values of the template's variables.
+type ResourceTemplateHandler func(ctx context.Context, uri string, vars map[string]string) (*protocol.ReadResourceResult, error)
+
+// ResourceTemplateDescriptor describes a family of resources whose URIs
+// match a URI template, such as "zenmcp://jobs/{id}". Simple expressions
+// ({name}) match one path segment; reserved expressions ({+name}) also
+// match "/".
+type ResourceTemplateDescriptor struct {
+	URITemplate string
+	Name        string
+	Description string
+	MimeType    string
+	// Subscribable marks templates whose resources the server announces
+	// changes of, as ResourceDescriptor.Subscribable does.
+	Subscribable bool
+	Handler      ResourceTemplateHandler
+
+	pattern *regexp.Regexp
+	vars    []string
+}
+
+// ResourceTemplate returns the protocol representation used in
+// resources/templates/list.
+func (d *ResourceTemplateDescriptor) ResourceTemplate() protocol.ResourceTemplate {
+	return protocol.ResourceTemplate{
+		URITemplate: d.URITemplate,
+		Name:        d.Name,
+		Description: d.Description,
+		MimeType:    d.MimeType,
+	}
+}
+
+// Match reports whether uri matches the template and returns the values
+// of its variables.
+func (d *ResourceTemplateDescriptor) Match(uri string) (map[string]string, bool) {
+	m := d.pattern.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+	vars := make(map[string]string, len(d.vars))
+	for i, name := range d.vars {
+		vars[name] = m[i+1]
+	}
+	return vars, true
+}
+
+// compileTemplate turns a URI template into an anchored regular
+// expression and the names of its variables.
+func compileTemplate(tmpl string) (*regexp.Regexp, []string, error) {
+	var sb strings.Builder
+	var vars []string
+	sb.WriteString("^")
+	rest := tmpl
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, nil, fmt.Errorf("unterminated expression in %q", tmpl)
+		}
+		sb.WriteString(regexp.QuoteMeta(rest[:open]))
+		expr := rest[open+1 : open+end]
+		class := "[^/]+"
+		if name, ok := strings.CutPrefix(expr, "+"); ok {
+			expr, class = name, ".+"
+		}
+		if expr == "" || strings.ContainsAny(expr, "{},*#./;?&=") {
+			return nil, nil, fmt.Errorf("unsupported expression {%s} in %q", rest[open+1:open+end], tmpl)
+		}
+		vars = append(vars, expr)
+		sb.WriteString("(" + class + ")")
+		rest = rest[open+end+1:]
+	}
+	if strings.IndexByte(rest, '}') >= 0 {
+		return nil, nil, fmt.Errorf("unbalanced '}' in %q", tmpl)
+	}
+	sb.WriteString(regexp.QuoteMeta(rest) + "$")
+	re, err := regexp.Compile(sb.String())
+	return re, vars, err
+}
+
+// RegisterResourceTemplate adds a resource template. desc.URITemplate
+// defaults to tmpl and must match it when set. resources/read falls back
+// to templates, in registration order, for URIs that name no registered
+// resource.
+func (r *Registry) RegisterResourceTemplate(tmpl string, desc ResourceTemplateDescriptor) error {
+	if tmpl == "" {
+		return errors.New("registry: resource template is required")
+	}
+	if desc.URITemplate == "" {
+		desc.URITemplate = tmpl
+	}
+	if desc.URITemplate != tmpl {
+		return fmt.Errorf("registry: resource template descriptor %q does not match %q", desc.URITemplate, tmpl)
+	}
+	if desc.Handler == nil {
+		return fmt.Errorf("registry: resource template %q has no handler", tmpl)
+	}
+	re, vars, err := compileTemplate(tmpl)
+	if err != nil {
+		return fmt.Errorf("registry: resource template: %w", err)
+	}
+	if desc.Name == "" {
+		desc.Name = tmpl
+	}
+	desc.pattern, desc.vars = re, vars
+	return r.update(ResourcesChanged, func() error {
+		for _, d := range r.templates {
+			if d.URITemplate == tmpl {
+				return fmt.Errorf("registry: resource template %q already registered", tmpl)
+			}
+		}
+		r.templates = append(r.templates[:len(r.templates):len(r.templates)], &desc)
+		return nil
+	})
+}
+
+// UnregisterResourceTemplate removes the resource template tmpl.
+func (r *Registry) UnregisterResourceTemplate(tmpl string) error {
+	return r.update(ResourcesChanged, func() error {
+		for i, d := range r.templates {
+			if d.URITemplate == tmpl {
+				r.templates = append(r.templates[:i:i], r.templates[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("registry: resource template %q not registered", tmpl)
+	})
+}
+
+// ResourceTemplates returns all resource templates in registration order.
+func (r *Registry) ResourceTemplates() []*ResourceTemplateDescriptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*ResourceTemplateDescriptor(nil), r.templates...)
+}
+
+// MatchResourceTemplate returns the first template matching uri and the
+// values of its variables.
+func (r *Registry) MatchResourceTemplate(uri string) (*ResourceTemplateDescriptor, map[string]string, bool) {
+	r.mu.RLock()
+	templates := r.templates
+	r.mu.RUnlock()
+	for _, d := range templates {
+		if vars, ok := d.Match(uri); ok {
+			return d, vars, true
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,231 @@
+// Package reload applies configuration changes to a running server without
+// dropping its connections.
+//
+// Only settings that can change safely while serving are covered: the log
+// level, quota limits, which tools are disabled, and the origins accepted
+// by HTTP transports. A reload is triggered by SIGHUP (see WatchSignals) or
+// through the admin endpoint (see admin.WithReloader). The new
+// configuration is validated as a whole before anything is applied, so a
+// bad file leaves the server as it was.
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/quota"
+	"github.com/hyperleex/zenmcp/transport/http"
+)
+
+// Config is the reloadable configuration. Nil and empty fields leave the
+// corresponding setting unchanged, except DisabledTools, which is applied
+// as given so that tools can be enabled again.
+type Config struct {
+	// LogLevel is "debug", "info", "warn" or "error".
+	LogLevel string `json:"logLevel,omitempty"`
+	// QuotaLimits replaces the limits of the quota.
+	QuotaLimits *quota.Limits `json:"quotaLimits,omitempty"`
+	// DisabledTools names the tools to hide and refuse.
+	DisabledTools []string `json:"disabledTools,omitempty"`
+	// AllowedOrigins replaces the origins accepted by the HTTP transports.
+	// An empty, non-nil list restores their defaults.
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+}
+
+// Options configures a Reloader.
+type Options struct {
+	// Load returns the configuration to apply. Required; see File.
+	Load func(ctx context.Context) (*Config, error)
+	// Level is the level variable of the server's log handler. Required to
+	// reload LogLevel.
+	Level *slog.LevelVar
+	// Quota is required to reload QuotaLimits.
+	Quota *quota.Quota
+	// HTTP lists the transports AllowedOrigins applies to.
+	HTTP []*http.Transport
+	// Logger records reloads. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Reloader applies configuration loaded by Options.Load to a server.
+type Reloader struct {
+	server *mcp.Server
+	opts   Options
+
+	mu      sync.Mutex
+	current Config
+}
+
+// New returns a reloader for s.
+func New(s *mcp.Server, opts Options) (*Reloader, error) {
+	if opts.Load == nil {
+		return nil, errors.New("reload: a Load function is required")
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Reloader{server: s, opts: opts}, nil
+}
+
+// File returns a Load function reading a JSON Config from path. Unknown
+// fields are rejected so that a misspelt setting is not silently ignored.
+func File(path string) func(ctx context.Context) (*Config, error) {
+	return func(ctx context.Context) (*Config, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		var cfg Config
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &cfg, nil
+	}
+}
+
+// Reload loads the configuration and applies it. If loading or validation
+// fails nothing is changed. It returns the configuration now in effect.
+func (r *Reloader) Reload(ctx context.Context) (*Config, error) {
+	cfg, err := r.opts.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reload: loading configuration: %w", err)
+	}
+	level, err := r.validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("reload: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cfg.LogLevel != "" {
+		r.opts.Level.Set(level)
+		r.current.LogLevel = cfg.LogLevel
+	}
+	if cfg.QuotaLimits != nil {
+		r.opts.Quota.SetLimits(*cfg.QuotaLimits)
+		l := *cfg.QuotaLimits
+		r.current.QuotaLimits = &l
+	}
+	router := r.server.Router()
+	if !sameSet(router.DisabledTools(), cfg.DisabledTools) {
+		router.SetDisabledTools(cfg.DisabledTools)
+		if _, err := r.server.Notifier().ToolsListChanged(); err != nil {
+			r.opts.Logger.Warn("reload: announcing tool changes", "error", err)
+		}
+	}
+	r.current.DisabledTools = router.DisabledTools()
+	if cfg.AllowedOrigins != nil {
+		for _, t := range r.opts.HTTP {
+			t.SetAllowedOrigins(cfg.AllowedOrigins...)
+		}
+		r.current.AllowedOrigins = append([]string{}, cfg.AllowedOrigins...)
+	}
+	cur := r.snapshot()
+	r.opts.Logger.Info("configuration reloaded",
+		"logLevel", cur.LogLevel, "disabledTools", cur.DisabledTools, "allowedOrigins", cur.AllowedOrigins)
+	return cur, nil
+}
+
+// Current returns the configuration applied by the last successful
+// reload.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot()
+}
+
+func (r *Reloader) snapshot() *Config {
+	cfg := r.current
+	if cfg.QuotaLimits != nil {
+		l := *cfg.QuotaLimits
+		cfg.QuotaLimits = &l
+	}
+	cfg.DisabledTools = append([]string(nil), cfg.DisabledTools...)
+	if cfg.AllowedOrigins != nil {
+		cfg.AllowedOrigins = append([]string{}, cfg.AllowedOrigins...)
+	}
+	return &cfg
+}
+
+// WatchSignals reloads on every SIGHUP until ctx is done. Failed reloads
+// are logged and the previous configuration stays in effect.
+func (r *Reloader) WatchSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			if _, err := r.Reload(ctx); err != nil {
+				r.opts.Logger.Error("configuration reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// validate checks cfg against the server and returns the parsed log level.
+func (r *Reloader) validate(cfg *Config) (slog.Level, error) {
+	var level slog.Level
+	if cfg.LogLevel != "" {
+		if r.opts.Level == nil {
+			return 0, errors.New("logLevel given but no level variable is configured")
+		}
+		if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			return 0, fmt.Errorf("logLevel: %w", err)
+		}
+	}
+	if l := cfg.QuotaLimits; l != nil {
+		if r.opts.Quota == nil {
+			return 0, errors.New("quotaLimits given but no quota is installed")
+		}
+		if l.Calls < 0 || l.Bytes < 0 || l.Cost < 0 {
+			return 0, errors.New("quotaLimits: limits must not be negative")
+		}
+	}
+	for _, name := range cfg.DisabledTools {
+		if _, ok := r.server.Registry().Tool(name); !ok {
+			return 0, fmt.Errorf("disabledTools: unknown tool %q", name)
+		}
+	}
+	if cfg.AllowedOrigins != nil {
+		if len(r.opts.HTTP) == 0 {
+			return 0, errors.New("allowedOrigins given but no HTTP transport is configured")
+		}
+		for _, o := range cfg.AllowedOrigins {
+			if o == "" || strings.ContainsAny(o, "/ ") {
+				return 0, fmt.Errorf("allowedOrigins: %q is not a host[:port]", o)
+			}
+		}
+	}
+	return level, nil
+}
+
+func sameSet(sorted, names []string) bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	if len(set) != len(sorted) {
+		return false
+	}
+	for _, n := range sorted {
+		if !set[n] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,308 @@
+// Package replica serves the resources of an upstream MCP server from a
+// local read-through cache, so that hot resources are not fetched from a
+// slow upstream on every read.
+//
+// Cached contents expire after a TTL. When the upstream supports
+// subscriptions, the replica subscribes to every resource it caches and
+// drops it as soon as the upstream announces a change, so the TTL only
+// bounds staleness for upstreams that cannot announce changes:
+//
+//	rep := replica.New(client, replica.Options{TTL: time.Minute})
+//	defer rep.Close()
+//	if err := rep.Mirror(ctx, s); err != nil { ... }
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+// Upstream is the server a replica reads from. *mcp.Client implements it.
+type Upstream interface {
+	ListResources(ctx context.Context) (*protocol.ListResourcesResult, error)
+	ReadResource(ctx context.Context, uri string) (*protocol.ReadResourceResult, error)
+	SubscribeResource(ctx context.Context, uri string) (<-chan protocol.ResourceUpdatedParams, error)
+}
+
+// Options configures a Replica.
+type Options struct {
+	// TTL is how long read contents are served from the cache. Defaults
+	// to one minute.
+	TTL time.Duration
+	// MaxEntries bounds the number of cached resources. Defaults to 1000.
+	MaxEntries int
+	// Timeout bounds each read from the upstream. Defaults to 30 seconds.
+	Timeout time.Duration
+	// Clock expires entries and times the requests to the upstream.
+	// Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// Replica caches the resources read from an upstream server.
+type Replica struct {
+	up   Upstream
+	opts Options
+	// ctx bounds the subscriptions to the upstream; Close cancels it.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	entries  map[string]entry
+	inflight map[string]*fetch
+	// gens counts the invalidations of each URI, so that a read that
+	// started before a change does not cache what it read.
+	gens map[string]uint64
+	// subscribed holds the URIs the upstream announces changes of.
+	subscribed map[string]bool
+	// noSubscribe is set once the upstream refused a subscription for
+	// lack of support, after which the TTL alone expires entries.
+	noSubscribe bool
+	onUpdate    []func(uri string)
+}
+
+type entry struct {
+	result  *protocol.ReadResourceResult
+	expires time.Time
+}
+
+// fetch is a read from the upstream shared by the callers asking for the
+// same URI meanwhile.
+type fetch struct {
+	done   chan struct{}
+	result *protocol.ReadResourceResult
+	err    error
+}
+
+// New returns a replica of the resources of up.
+func New(up Upstream, opts Options) *Replica {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	opts.Clock = clock.Or(opts.Clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Replica{
+		up:         up,
+		opts:       opts,
+		ctx:        ctx,
+		cancel:     cancel,
+		entries:    make(map[string]entry),
+		inflight:   make(map[string]*fetch),
+		gens:       make(map[string]uint64),
+		subscribed: make(map[string]bool),
+	}
+}
+
+// Read returns the contents of the resource at uri, from the cache while
+// they are fresh and from the upstream otherwise. Concurrent reads of a
+// URI missing from the cache share one upstream read.
+func (r *Replica) Read(ctx context.Context, uri string) (*protocol.ReadResourceResult, error) {
+	r.mu.Lock()
+	if e, ok := r.entries[uri]; ok {
+		if r.opts.Clock.Now().Before(e.expires) {
+			r.mu.Unlock()
+			return copyResult(e.result), nil
+		}
+		delete(r.entries, uri)
+	}
+	f, ok := r.inflight[uri]
+	if !ok {
+		f = &fetch{done: make(chan struct{})}
+		r.inflight[uri] = f
+		go r.fetch(uri, f, r.gens[uri])
+	}
+	r.mu.Unlock()
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, f.err
+		}
+		return copyResult(f.result), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch reads uri from the upstream for f. It runs detached from the
+// readers, so that one giving up does not fail the others.
+func (r *Replica) fetch(uri string, f *fetch, gen uint64) {
+	defer close(f.done)
+	r.subscribe(uri)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := r.opts.Clock.AfterFunc(r.opts.Timeout, cancel)
+	f.result, f.err = r.up.ReadResource(ctx, uri)
+	if !timer.Stop() && f.err != nil {
+		f.err = errors.Join(f.err, context.DeadlineExceeded)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.inflight, uri)
+	if f.err != nil || r.gens[uri] != gen {
+		return
+	}
+	r.store(uri, f.result)
+}
+
+// subscribe asks the upstream to announce changes of uri, once, and
+// drops uri from the cache on every announcement until the subscription
+// ends. The subscription lasts until Close; the subscribe request itself
+// is bounded by the read timeout.
+func (r *Replica) subscribe(uri string) {
+	r.mu.Lock()
+	skip := r.noSubscribe || r.subscribed[uri]
+	r.mu.Unlock()
+	if skip {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	timer := r.opts.Clock.AfterFunc(r.opts.Timeout, cancel)
+	updates, err := r.up.SubscribeResource(ctx, uri)
+	if !timer.Stop() {
+		err = errors.Join(err, context.DeadlineExceeded)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rpcErr *protocol.Error
+	switch {
+	case err == nil:
+		r.subscribed[uri] = true
+		go func() {
+			defer cancel()
+			for p := range updates {
+				r.updated(p.URI)
+			}
+			// The subscription ended with the upstream connection or
+			// Close; a later read subscribes again if it can.
+			r.mu.Lock()
+			delete(r.subscribed, uri)
+			r.mu.Unlock()
+		}()
+		return
+	case errors.As(err, &rpcErr) && rpcErr.Code == protocol.MethodNotFound:
+		r.noSubscribe = true
+	}
+	cancel()
+}
+
+// Close ends the replica's subscriptions to the upstream. The cache keeps
+// serving, with the TTL alone expiring entries.
+func (r *Replica) Close() {
+	r.mu.Lock()
+	r.noSubscribe = true
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// store caches res, making room when the cache is full. r.mu is held.
+func (r *Replica) store(uri string, res *protocol.ReadResourceResult) {
+	now := r.opts.Clock.Now()
+	if len(r.entries) >= r.opts.MaxEntries {
+		for k, e := range r.entries {
+			if !now.Before(e.expires) {
+				delete(r.entries, k)
+			}
+		}
+		// Still full: drop an arbitrary entry.
+		for k := range r.entries {
+			if len(r.entries) < r.opts.MaxEntries {
+				break
+			}
+			delete(r.entries, k)
+		}
+	}
+	r.entries[uri] = entry{result: copyResult(res), expires: now.Add(r.opts.TTL)}
+}
+
+// Invalidate drops the cached contents of uri.
+func (r *Replica) Invalidate(uri string) {
+	r.mu.Lock()
+	delete(r.entries, uri)
+	r.gens[uri]++
+	r.mu.Unlock()
+}
+
+// InvalidateAll drops every cached resource.
+func (r *Replica) InvalidateAll() {
+	r.mu.Lock()
+	for uri := range r.entries {
+		r.gens[uri]++
+	}
+	for uri := range r.inflight {
+		r.gens[uri]++
+	}
+	clear(r.entries)
+	r.mu.Unlock()
+}
+
+// HandleNotification used to take the notifications of the upstream.
+//
+// Deprecated: Updates arrive on the channels returned by the upstream's
+// SubscribeResource, so HandleNotification does nothing. Passing the
+// notifications on as well would report every update twice.
+func (r *Replica) HandleNotification(method string, params json.RawMessage) {}
+
+// updated drops uri, announced changed by the upstream, from the cache and
+// passes it on to the functions registered with OnUpdate.
+func (r *Replica) updated(uri string) {
+	r.Invalidate(uri)
+	r.mu.Lock()
+	fns := append([]func(uri string){}, r.onUpdate...)
+	r.mu.Unlock()
+	for _, fn := range fns {
+		fn(uri)
+	}
+}
+
+// OnUpdate calls fn with the URI of every resource the upstream announces
+// a change of.
+func (r *Replica) OnUpdate(fn func(uri string)) {
+	r.mu.Lock()
+	r.onUpdate = append(r.onUpdate, fn)
+	r.mu.Unlock()
+}
+
+// Mirror registers the resources the upstream lists on s, read through
+// the replica, and forwards the upstream's change announcements to the
+// clients of s subscribed to them.
+func (r *Replica) Mirror(ctx context.Context, s *mcp.Server) error {
+	list, err := r.up.ListResources(ctx)
+	if err != nil {
+		return err
+	}
+	for _, res := range list.Resources {
+		err := s.Registry().RegisterResource(res.URI, registry.ResourceDescriptor{
+			Name:         res.Name,
+			Description:  res.Description,
+			MimeType:     res.MimeType,
+			Subscribable: true,
+			Handler:      r.Read,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	r.OnUpdate(func(uri string) { s.NotifyResourceUpdated(uri) })
+	return nil
+}
+
+// copyResult copies res so that callers modifying a result do not alter
+// the cached one.
+func copyResult(res *protocol.ReadResourceResult) *protocol.ReadResourceResult {
+	out := *res
+	out.Contents = append([]protocol.ResourceContents(nil), res.Contents...)
+	return &out
+}
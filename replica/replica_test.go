@@ -0,0 +1,53 @@
+package replica_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/replica"
+)
+
+// stalled is an upstream that cannot subscribe and never answers reads.
+// It sends on reading when a read starts.
+type stalled struct{ reading chan struct{} }
+
+func (stalled) ListResources(ctx context.Context) (*protocol.ListResourcesResult, error) {
+	return &protocol.ListResourcesResult{}, nil
+}
+
+func (u stalled) ReadResource(ctx context.Context, uri string) (*protocol.ReadResourceResult, error) {
+	u.reading <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (stalled) SubscribeResource(ctx context.Context, uri string) (<-chan protocol.ResourceUpdatedParams, error) {
+	return nil, protocol.NewError(protocol.MethodNotFound, "method not found", nil)
+}
+
+func TestTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	up := stalled{reading: make(chan struct{})}
+	rep := replica.New(up, replica.Options{Timeout: time.Hour, Clock: fake})
+	defer rep.Close()
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := rep.Read(context.Background(), "file:///slow")
+		read <- err
+	}()
+	<-up.reading
+	fake.Advance(time.Hour)
+	select {
+	case err := <-read:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Read = %v, want a deadline error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("read did not time out")
+	}
+}
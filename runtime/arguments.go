@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Arguments returns the raw arguments of the tool call being handled, or
+// nil outside a tool call.
+func (c *Context) Arguments() json.RawMessage { return c.args }
+
+// UseNumber reports whether the arguments of the tool call being handled
+// are to be decoded with json.Number, as configured by Config.UseNumber or
+// the tool's descriptor.
+func (c *Context) UseNumber() bool { return c.useNumber }
+
+// ValidateArguments checks args against inputSchema as the router checks
+// the arguments of tools before calling them, for the handlers of tools
+// whose descriptor sets ChecksArguments. It returns the InvalidParams
+// error the router would, or nil when the router is configured to skip
+// the check or there is no tool call being handled.
+func (c *Context) ValidateArguments(args json.RawMessage, inputSchema map[string]interface{}) error {
+	if !c.validateArgs {
+		return nil
+	}
+	return validateArguments(args, inputSchema)
+}
+
+// DecodeArguments decodes the arguments of the tool call being handled
+// into v, using json.Number for numbers stored in an interface{} when
+// UseNumber is set.
+func (c *Context) DecodeArguments(v interface{}) error {
+	return DecodeJSON(c.args, v, c.useNumber)
+}
+
+// NumberArgument returns the top-level argument name exactly as the client
+// sent it, whatever the decoding configuration. It reports false when the
+// argument is absent or not a number.
+func (c *Context) NumberArgument(name string) (json.Number, bool) {
+	var args map[string]interface{}
+	if err := DecodeJSON(c.args, &args, true); err != nil {
+		return "", false
+	}
+	n, ok := args[name].(json.Number)
+	return n, ok
+}
+
+// Int64Argument returns the top-level integer argument name without the
+// loss of precision a float64 would incur. It fails when the argument is
+// absent or not an integer in the range of int64.
+func (c *Context) Int64Argument(name string) (int64, error) {
+	n, ok := c.NumberArgument(name)
+	if !ok {
+		return 0, fmt.Errorf("argument %q is not a number", name)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("argument %q is not an int64: %s", name, n)
+	}
+	return i, nil
+}
+
+// DecodeJSON decodes the JSON value data into v, using json.Number rather
+// than float64 for numbers stored in an interface{} when useNumber is set.
+// Like json.Unmarshal, it rejects data holding more than one value.
+func DecodeJSON(data []byte, v interface{}, useNumber bool) error {
+	if !useNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("runtime: unexpected data after top-level JSON value")
+	}
+	return nil
+}
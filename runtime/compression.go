@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"context"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ExperimentalCompression is the experimental capability through which a
+// client offers per-frame compression during initialize, listing the
+// algorithms it can decode in order of preference:
+//
+//	"capabilities": {"experimental": {"zenmcp/compression": {"algorithms": ["zstd", "deflate"]}}}
+//
+// When the connection supports one of them, the server answers with the
+// algorithm it picked, under the same key, and compresses large frames from
+// then on:
+//
+//	"capabilities": {"experimental": {"zenmcp/compression": {"algorithm": "zstd"}}}
+//
+// Either side may still send plain frames, so peers without a common
+// algorithm simply keep the plain framing.
+const ExperimentalCompression = "zenmcp/compression"
+
+// CompressionOffer is the client's zenmcp/compression capability.
+type CompressionOffer struct {
+	Algorithms []string `json:"algorithms"`
+}
+
+// CompressionChoice is the server's zenmcp/compression capability.
+type CompressionChoice struct {
+	Algorithm string `json:"algorithm"`
+}
+
+type compressorKey struct{}
+
+// WithCompressor returns a copy of ctx whose initialize requests negotiate
+// compression for c. Servers attach the connections that support it.
+func WithCompressor(ctx context.Context, c transport.Compressor) context.Context {
+	return context.WithValue(ctx, compressorKey{}, c)
+}
+
+// negotiateCompression enables on the request's connection the first
+// algorithm offered in the experimental capability v that the connection
+// supports, and returns its name, or "" when there is none.
+func negotiateCompression(ctx *Context, v interface{}) (string, error) {
+	c, ok := ctx.Value(compressorKey{}).(transport.Compressor)
+	if !ok {
+		return "", nil
+	}
+	offer, _ := v.(map[string]interface{})
+	var offered []string
+	list, _ := offer["algorithms"].([]interface{})
+	for _, a := range list {
+		if name, ok := a.(string); ok {
+			offered = append(offered, name)
+		}
+	}
+	name := codec.Negotiate(offered, c.Compressions())
+	if name == "" {
+		return "", nil
+	}
+	if err := c.EnableCompression(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,130 @@
+// Package runtime dispatches JSON-RPC messages to MCP method handlers and
+// provides the per-request Context passed to them.
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+type contextKey struct{}
+
+// Context is the per-request context handed to handlers. It embeds the
+// request's context.Context, which is cancelled when the request finishes,
+// the client sends notifications/cancelled for it or Cancel is called.
+type Context struct {
+	context.Context
+	cancel context.CancelFunc
+
+	requestID *protocol.ID
+	method    string
+	logger    *slog.Logger
+
+	tenant   string
+	registry *registry.Registry
+
+	args         json.RawMessage
+	useNumber    bool
+	validateArgs bool
+
+	// progressToken is the token the client sent in the request's _meta
+	// to ask for progress notifications, or nil.
+	progressToken interface{}
+}
+
+func newContext(parent context.Context, msg *protocol.Message, logger *slog.Logger) *Context {
+	ctx, cancel := context.WithCancel(parent)
+	return &Context{
+		Context:       ctx,
+		cancel:        cancel,
+		requestID:     msg.ID,
+		method:        msg.Method,
+		logger:        logger,
+		progressToken: progressToken(msg),
+	}
+}
+
+// FromContext returns the *Context carried by ctx. Handlers registered with
+// the registry receive a plain context.Context and use this to recover the
+// request context. When ctx carries none, for example when a handler is
+// invoked directly in a test, a detached Context wrapping ctx is returned.
+func FromContext(ctx context.Context) *Context {
+	if c, ok := ctx.(*Context); ok {
+		return c
+	}
+	if c, ok := ctx.Value(contextKey{}).(*Context); ok {
+		return c
+	}
+	return &Context{Context: ctx, cancel: func() {}, logger: slog.Default()}
+}
+
+// Value implements context.Context, additionally exposing the Context
+// itself so that FromContext works through derived contexts.
+func (c *Context) Value(key interface{}) interface{} {
+	if key == (contextKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// RequestID returns the ID of the request being handled. It is nil for
+// notifications.
+func (c *Context) RequestID() *protocol.ID { return c.requestID }
+
+// Method returns the JSON-RPC method being handled.
+func (c *Context) Method() string { return c.method }
+
+// Tenant returns the tenant the request was routed to, or "" when the
+// server is not partitioned or the request names no tenant.
+func (c *Context) Tenant() string { return c.tenant }
+
+// Logger returns the logger for this request.
+func (c *Context) Logger() *slog.Logger { return c.logger }
+
+// Cancel cancels the request context.
+func (c *Context) Cancel() { c.cancel() }
+
+// Detach returns a copy of c for work that outlives the request, such as
+// a background job. It carries the same values, session and tenant but is
+// cancelled only by its own Cancel, and does not report progress.
+func (c *Context) Detach() *Context {
+	d := *c
+	d.Context, d.cancel = context.WithCancel(context.WithoutCancel(c))
+	d.progressToken = nil // progress cannot be reported once the request is answered
+	return &d
+}
+
+type requestInfoKey struct{}
+
+// RequestInfo is filled in by the router while it dispatches a request.
+// Servers attach one to the dispatch context with WithRequestInfo to learn
+// how the request was resolved, for example to label metrics.
+type RequestInfo struct {
+	// Target is the name of the tool or prompt the request addresses.
+	Target string
+	// Deprecated is set when the addressed tool is deprecated.
+	Deprecated bool
+}
+
+// WithRequestInfo returns a copy of ctx carrying info.
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// setDeprecated records that the addressed tool is deprecated.
+func (c *Context) setDeprecated() {
+	if info, ok := c.Value(requestInfoKey{}).(*RequestInfo); ok {
+		info.Deprecated = true
+	}
+}
+
+// setTarget records the tool or prompt addressed by the request.
+func (c *Context) setTarget(name string) {
+	if info, ok := c.Value(requestInfoKey{}).(*RequestInfo); ok {
+		info.Target = name
+	}
+}
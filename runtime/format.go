@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numberFormat holds the conventions of a locale for human-facing text.
+type numberFormat struct {
+	decimal string // decimal separator
+	group   string // thousands separator
+	layout  string // date and time, as a time.Format layout
+}
+
+// formats maps lower-case BCP 47 tags to their conventions. Tags fall back
+// from "de-ch" to "de"; locales missing here get machine-readable output.
+var formats = map[string]numberFormat{
+	"en":    {".", ",", "02/01/2006, 15:04 MST"},
+	"en-us": {".", ",", "1/2/2006, 3:04 PM MST"},
+	"en-ca": {".", ",", "2006-01-02, 3:04 PM MST"},
+	"de":    {",", ".", "02.01.2006, 15:04 MST"},
+	"de-ch": {".", "’", "02.01.2006, 15:04 MST"},
+	"fr":    {",", "\u202f", "02/01/2006 15:04 MST"},
+	"fr-ch": {",", "\u202f", "02.01.2006 15:04 MST"},
+	"es":    {",", ".", "2/1/2006, 15:04 MST"},
+	"it":    {",", ".", "02/01/2006, 15:04 MST"},
+	"pt":    {",", ".", "02/01/2006, 15:04 MST"},
+	"pt-pt": {",", "\u00a0", "02/01/2006, 15:04 MST"},
+	"nl":    {",", ".", "02-01-2006 15:04 MST"},
+	"da":    {",", ".", "02.01.2006 15.04 MST"},
+	"sv":    {",", "\u00a0", "2006-01-02 15:04 MST"},
+	"nb":    {",", "\u00a0", "02.01.2006, 15:04 MST"},
+	"fi":    {",", "\u00a0", "2.1.2006 15.04 MST"},
+	"pl":    {",", "\u00a0", "02.01.2006, 15:04 MST"},
+	"cs":    {",", "\u00a0", "02.01.2006 15:04 MST"},
+	"ru":    {",", "\u00a0", "02.01.2006, 15:04 MST"},
+	"uk":    {",", "\u00a0", "02.01.2006, 15:04 MST"},
+	"tr":    {",", ".", "02.01.2006 15:04 MST"},
+	"ja":    {".", ",", "2006/01/02 15:04 MST"},
+	"zh":    {".", ",", "2006/01/02 15:04 MST"},
+	"ko":    {".", ",", "2006. 1. 2. 15:04 MST"},
+	"hi":    {".", ",", "2/1/2006, 3:04 PM MST"},
+}
+
+// lookupFormat returns the conventions best matching locale.
+func lookupFormat(locale string) (numberFormat, bool) {
+	tag := strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	for tag != "" {
+		if f, ok := formats[tag]; ok {
+			return f, true
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return numberFormat{}, false
+}
+
+// FormatNumber formats v for text read by people speaking locale, with
+// prec digits after the decimal point, or as few as needed when prec is
+// negative: 1234567.5 reads "1,234,567.5" in "en" and "1.234.567,5" in
+// "de". For an empty or unknown locale it returns what strconv.FormatFloat
+// does with format 'f'.
+func FormatNumber(locale string, v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	f, ok := lookupFormat(locale)
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return s
+	}
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	for i := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(f.group)
+		}
+		b.WriteByte(intPart[i])
+	}
+	if frac != "" {
+		b.WriteString(f.decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// FormatTime formats t, in its own location, for text read by people
+// speaking locale: "1/2/2006, 3:04 PM UTC" in "en-US" and "02.01.2006,
+// 15:04 UTC" in "de". For an empty or unknown locale it returns t in RFC
+// 3339 form.
+func FormatTime(locale string, t time.Time) string {
+	f, ok := lookupFormat(locale)
+	if !ok {
+		return t.Format(time.RFC3339)
+	}
+	return t.Format(f.layout)
+}
+
+// FormatNumber formats v for the client's locale. See the function
+// FormatNumber.
+func (c *Context) FormatNumber(v float64, prec int) string {
+	return FormatNumber(c.Locale(), v, prec)
+}
+
+// FormatTime formats t for the client's locale. See the function
+// FormatTime.
+func (c *Context) FormatTime(t time.Time) string {
+	return FormatTime(c.Locale(), t)
+}
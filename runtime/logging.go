@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"encoding/json"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// levelSeverity ranks the logging levels, from least to most severe.
+var levelSeverity = map[protocol.LoggingLevel]int{
+	protocol.LevelDebug:     0,
+	protocol.LevelInfo:      1,
+	protocol.LevelNotice:    2,
+	protocol.LevelWarning:   3,
+	protocol.LevelError:     4,
+	protocol.LevelCritical:  5,
+	protocol.LevelAlert:     6,
+	protocol.LevelEmergency: 7,
+}
+
+func (r *Router) handleLoggingSetLevel(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.SetLevelParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := levelSeverity[p.Level]; !ok {
+		return nil, protocol.Errorf(protocol.InvalidParams, "unknown logging level %q", p.Level)
+	}
+	sess := ctx.Session()
+	if sess == nil {
+		return nil, protocol.NewError(protocol.InvalidRequest, "logging requires a session", nil)
+	}
+	sess.SetLogLevel(p.Level)
+	return struct{}{}, nil
+}
+
+// Log sends the client a log message with notifications/message, when the
+// client asked for messages at level or a less severe one with
+// logging/setLevel. Clients that did not ask get none. logger names the
+// component logging, or is empty; data is any value encoding to JSON,
+// typically a string or an object.
+func (c *Context) Log(level protocol.LoggingLevel, logger string, data interface{}) error {
+	sess := c.Session()
+	if sess == nil {
+		return nil
+	}
+	want, ok := levelSeverity[sess.LogLevel()]
+	if !ok || levelSeverity[level] < want {
+		return nil
+	}
+	return c.Notify(protocol.MethodLoggingMessage, protocol.LoggingMessageParams{
+		Level:  level,
+		Logger: logger,
+		Data:   data,
+	})
+}
@@ -0,0 +1,35 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoNotifier is returned by Context.Notify for requests that did not
+// arrive over a connection, such as those dispatched directly in tests.
+var ErrNoNotifier = errors.New("runtime: request has no connection to notify")
+
+// Notifier sends notifications to the client on the other end of one
+// connection.
+type Notifier interface {
+	Notify(method string, params interface{}) error
+}
+
+type notifierKey struct{}
+
+// WithNotifier returns a copy of ctx whose requests send notifications
+// through n. The server attaches one to every connection.
+func WithNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+// Notify sends a notification to the client that made the request, on the
+// same connection. It returns once the notification is queued; the server
+// writes notifications queued by a handler before the handler's response.
+func (c *Context) Notify(method string, params interface{}) error {
+	n, ok := c.Value(notifierKey{}).(Notifier)
+	if !ok {
+		return ErrNoNotifier
+	}
+	return n.Notify(method, params)
+}
@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// cursorPrefix marks the cursors the router issues, so that cursors from
+// elsewhere are rejected rather than misread.
+const cursorPrefix = "zenmcp:"
+
+// page returns the bounds of the page of a list of n items that cursor
+// selects, and the cursor of the page after it, which is empty for the
+// last page. Without a page size the whole list is one page.
+//
+// Cursors hold the offset of the page's first item. A list that changes
+// between pages may thus repeat or skip items; servers whose lists change
+// announce it with list_changed notifications, after which clients list
+// again from the start.
+func (r *Router) page(cursor string, n int) (start, end int, next string, err error) {
+	if cursor != "" {
+		start, err = decodeCursor(cursor)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		start = min(start, n)
+	}
+	size := r.config.PageSize
+	if size <= 0 {
+		return start, n, "", nil
+	}
+	end = min(start+size, n)
+	if end < n {
+		next = encodeCursor(end)
+	}
+	return start, end, next, nil
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if s, ok := strings.CutPrefix(string(data), cursorPrefix); ok {
+			if offset, err := strconv.Atoi(s); err == nil && offset >= 0 {
+				return offset, nil
+			}
+		}
+	}
+	return 0, protocol.NewError(protocol.InvalidParams, "invalid cursor", nil)
+}
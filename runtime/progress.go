@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// progressToken returns the progress token in the _meta of a request's
+// params, or nil. Params are only decoded when they mention one.
+func progressToken(msg *protocol.Message) interface{} {
+	if !msg.IsRequest() || !bytes.Contains(msg.Params, []byte(`"progressToken"`)) {
+		return nil
+	}
+	var p struct {
+		Meta *protocol.RequestMeta `json:"_meta"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(msg.Params))
+	dec.UseNumber() // numeric tokens must be echoed exactly
+	if dec.Decode(&p) != nil || p.Meta == nil {
+		return nil
+	}
+	return p.Meta.ProgressToken
+}
+
+// ProgressToken returns the token the client sent to ask for progress
+// notifications about the request, or nil when it asked for none.
+func (c *Context) ProgressToken() interface{} { return c.progressToken }
+
+// ReportProgress tells the client how far the request has come by sending
+// notifications/progress with the request's progress token. progress
+// should increase with every call; total is zero when unknown. It does
+// nothing when the client did not ask for progress.
+func (c *Context) ReportProgress(progress, total float64) error {
+	return c.ReportProgressMessage(progress, total, "")
+}
+
+// ReportProgressMessage is ReportProgress with a human-readable message
+// describing the current step.
+func (c *Context) ReportProgressMessage(progress, total float64, message string) error {
+	if c.progressToken == nil {
+		return nil
+	}
+	return c.Notify(protocol.MethodProgress, protocol.ProgressParams{
+		ProgressToken: c.progressToken,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNoRequester is returned by Context.Request for requests that did not
+// arrive over a connection, such as those dispatched directly in tests.
+var ErrNoRequester = errors.New("runtime: request has no connection to send requests on")
+
+// Requester sends requests to the client on the other end of one
+// connection and waits for their answers.
+type Requester interface {
+	// Request sends a request for method and returns the result the
+	// client answered with. An error answer is returned as a
+	// *protocol.Error.
+	Request(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+}
+
+type requesterKey struct{}
+
+// WithRequester returns a copy of ctx whose requests send requests to the
+// client through r. The server attaches one to every request it handles.
+func WithRequester(ctx context.Context, r Requester) context.Context {
+	return context.WithValue(ctx, requesterKey{}, r)
+}
+
+// Request sends a request for method to the client that made the request
+// being handled, such as sampling/createMessage or roots/list, and waits
+// for the answer, decoding its result into result unless that is nil.
+// Waiting ends early, with the context's error, when the request being
+// handled is cancelled.
+func (c *Context) Request(method string, params, result interface{}) error {
+	r, ok := c.Value(requesterKey{}).(Requester)
+	if !ok {
+		return ErrNoRequester
+	}
+	raw, err := r.Request(c, method, params)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
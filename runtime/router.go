@@ -0,0 +1,810 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hyperleex/zenmcp/acl"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/schema"
+	"github.com/hyperleex/zenmcp/tenant"
+)
+
+// HandlerFunc handles a single JSON-RPC method. The returned value is
+// marshaled as the response result; returning a *protocol.Error controls the
+// error code sent to the client.
+type HandlerFunc func(ctx *Context, params json.RawMessage) (interface{}, error)
+
+// supportedVersions lists the protocol revisions the router accepts, newest
+// first.
+var supportedVersions = []string{protocol.LatestProtocolVersion, "2024-11-05"}
+
+// Config configures a Router.
+type Config struct {
+	// Info identifies the server in the initialize result.
+	Info protocol.Implementation
+	// Instructions is optional guidance returned from initialize.
+	Instructions string
+	// Logger receives dispatch diagnostics. Defaults to slog.Default().
+	Logger *slog.Logger
+	// ToolProfiles are named tool filters a client can select during
+	// initialize through the zenmcp/profile experimental capability. Tools
+	// outside a session's profile are neither listed nor callable.
+	ToolProfiles map[string]protocol.ToolFilter
+	// ArgumentLimits bounds the tool call arguments accepted before the
+	// tool's handler runs. The zero value applies schema.DefaultLimits.
+	ArgumentLimits schema.Limits
+	// ResourceACL restricts resources to the principals it admits. Denied
+	// resources are left out of resources/list and reported as not found
+	// by resources/read, so their existence is not disclosed.
+	ResourceACL *acl.ResourceACL
+	// Tenants partitions the server: requests resolved to a tenant are
+	// served from that tenant's registry, and requests naming no tenant
+	// from the router's own. Custom methods registered with Handle are
+	// shared by all tenants.
+	Tenants *tenant.Set
+	// UseNumber enables registry.ToolDescriptor.UseNumber for every tool.
+	UseNumber bool
+	// SkipArgumentValidation turns off checking tool call arguments
+	// against the tool's input schema, for tools whose schemas describe
+	// their arguments only loosely.
+	SkipArgumentValidation bool
+	// ListChanged advertises listChanged for tools, resources and prompts:
+	// the registry may change while serving and clients are notified when
+	// it does.
+	ListChanged bool
+	// DisableLogging turns off logging/setLevel and the logging
+	// capability, so that Context.Log sends clients nothing.
+	DisableLogging bool
+	// PanicStacks attaches the panic value and stack trace to the
+	// InternalError answering a request whose handler panicked. Leave it
+	// off when clients are not trusted with server internals.
+	PanicStacks bool
+	// PageSize bounds the number of items in each page of tools/list,
+	// resources/list, resources/templates/list and prompts/list; clients
+	// fetch the rest with the nextCursor of each page. Zero lists
+	// everything at once.
+	PageSize int
+}
+
+// Router dispatches incoming messages to MCP method handlers backed by a
+// registry.
+type Router struct {
+	registry *registry.Registry
+	config   Config
+	logger   *slog.Logger
+	handlers map[string]HandlerFunc
+	custom   []string
+
+	interceptors []ToolInterceptor
+	authorizers  []Authorizer
+	// disabled holds the names of the tools turned off with
+	// SetDisabledTools.
+	disabled atomic.Pointer[map[string]bool]
+}
+
+// ToolInterceptor wraps the execution of tool calls, after the tool has
+// been resolved and its arguments checked. It runs the tool by calling
+// next, and may instead return without calling it. Errors returned by the
+// tool reach the interceptor unchanged; a *protocol.Error returned by an
+// interceptor fails the call with that error.
+type ToolInterceptor func(ctx *Context, tool *registry.ToolDescriptor, args json.RawMessage, next registry.ToolHandler) (*protocol.ToolCallResult, error)
+
+// Authorizer decides whether a request may be dispatched, after its tenant
+// is resolved and before its handler runs. A non-nil error rejects the
+// request; a *protocol.Error controls the code sent to the client.
+type Authorizer func(ctx *Context, params json.RawMessage) error
+
+// NewRouter returns a router serving the contents of reg.
+func NewRouter(reg *registry.Registry, cfg Config) *Router {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := &Router{
+		registry: reg,
+		config:   cfg,
+		logger:   logger,
+		handlers: make(map[string]HandlerFunc),
+	}
+	r.handlers[protocol.MethodInitialize] = r.handleInitialize
+	r.handlers[protocol.MethodInitialized] = r.handleInitialized
+	r.handlers[protocol.MethodPing] = r.handlePing
+	r.handlers[protocol.MethodToolsList] = r.handleToolsList
+	r.handlers[protocol.MethodToolsCall] = r.handleToolsCall
+	r.handlers[protocol.MethodResourcesList] = r.handleResourcesList
+	r.handlers[protocol.MethodResourcesRead] = r.handleResourcesRead
+	r.handlers[protocol.MethodResourceTemplatesList] = r.handleResourceTemplatesList
+	r.handlers[protocol.MethodResourcesSubscribe] = r.handleResourcesSubscribe
+	r.handlers[protocol.MethodResourcesUnsubscribe] = r.handleResourcesUnsubscribe
+	r.handlers[protocol.MethodPromptsList] = r.handlePromptsList
+	r.handlers[protocol.MethodPromptsGet] = r.handlePromptsGet
+	if !cfg.DisableLogging {
+		r.handlers[protocol.MethodLoggingSetLevel] = r.handleLoggingSetLevel
+	}
+	return r
+}
+
+// ExperimentalMethods is the experimental capability key under which custom
+// methods registered with Handle are advertised.
+const ExperimentalMethods = "zenmcp/methods"
+
+// Handle registers h for a custom JSON-RPC method, letting applications
+// expose small RPCs on the same connection without modelling them as tools.
+// Custom methods are advertised to clients under
+// capabilities.experimental["zenmcp/methods"]. Built-in methods cannot be
+// replaced, and registration must complete before serving starts.
+func (r *Router) Handle(method string, h HandlerFunc) error {
+	if method == "" {
+		return errors.New("runtime: method name is required")
+	}
+	if h == nil {
+		return fmt.Errorf("runtime: method %q has no handler", method)
+	}
+	if _, ok := r.handlers[method]; ok {
+		return fmt.Errorf("runtime: method %q already registered", method)
+	}
+	r.handlers[method] = h
+	r.custom = append(r.custom, method)
+	sort.Strings(r.custom)
+	return nil
+}
+
+// InterceptToolCalls adds i around every tool call, including nested calls
+// made with CallTool. Interceptors run in the order they were added, the
+// first outermost. Like Handle, it must be called before serving starts.
+func (r *Router) InterceptToolCalls(i ToolInterceptor) {
+	r.interceptors = append(r.interceptors, i)
+}
+
+// Authorize adds a to the checks every request passes before dispatch.
+// Authorizers run in the order they were added; the first to fail rejects
+// the request. Notifications are not checked. Like Handle, it must be
+// called before serving starts.
+func (r *Router) Authorize(a Authorizer) {
+	r.authorizers = append(r.authorizers, a)
+}
+
+// authorize runs the authorizers on a request.
+func (r *Router) authorize(ctx *Context, params json.RawMessage) error {
+	if ctx.RequestID() == nil {
+		return nil
+	}
+	for _, a := range r.authorizers {
+		if err := a(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetDisabledTools turns off the named tools, in every tenant, replacing
+// the previous set. Disabled tools are neither listed nor callable. Unlike
+// the other router settings it may be changed while serving.
+func (r *Router) SetDisabledTools(names []string) {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	r.disabled.Store(&m)
+}
+
+// DisabledTools returns the names of the disabled tools, sorted.
+func (r *Router) DisabledTools() []string {
+	m := r.disabled.Load()
+	if m == nil {
+		return nil
+	}
+	names := make([]string, 0, len(*m))
+	for n := range *m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Router) toolEnabled(tool *registry.ToolDescriptor) bool {
+	m := r.disabled.Load()
+	return m == nil || !(*m)[tool.Name]
+}
+
+// runTool calls tool through the interceptors.
+func (r *Router) runTool(ctx *Context, tool *registry.ToolDescriptor, args json.RawMessage) (*protocol.ToolCallResult, error) {
+	next := tool.Handler
+	for i := len(r.interceptors) - 1; i >= 0; i-- {
+		intercept, inner := r.interceptors[i], next
+		next = func(c context.Context, args json.RawMessage) (*protocol.ToolCallResult, error) {
+			rc, ok := c.Value(contextKey{}).(*Context)
+			if !ok {
+				rc = ctx
+			}
+			return intercept(rc, tool, args, inner)
+		}
+	}
+	return next(ctx, args)
+}
+
+// Dispatch handles msg and returns the response to send, or nil when no
+// response is due (notifications and stray responses).
+func (r *Router) Dispatch(ctx context.Context, msg *protocol.Message) *protocol.Message {
+	if err := msg.Validate(); err != nil {
+		return protocol.NewErrorResponse(msg.ID, protocol.AsError(err))
+	}
+	if msg.IsResponse() {
+		r.logger.Debug("ignoring unsolicited response", "id", msg.ID)
+		return nil
+	}
+
+	h, ok := r.handlers[msg.Method]
+	if !ok {
+		if msg.IsNotification() {
+			r.logger.Debug("ignoring unknown notification", "method", msg.Method)
+			return nil
+		}
+		return protocol.NewErrorResponse(msg.ID, protocol.Errorf(protocol.MethodNotFound, "method %q not found", msg.Method))
+	}
+
+	rc := newContext(ctx, msg, r.logger.With("method", msg.Method))
+	defer rc.cancel()
+	var result interface{}
+	err := r.resolveTenant(rc)
+	if err == nil {
+		err = r.authorize(rc, msg.Params)
+	}
+	if err == nil {
+		result, err = r.call(rc, h, msg.Params)
+	}
+	if msg.IsNotification() {
+		if err != nil {
+			r.logger.Warn("notification handler failed", "method", msg.Method, "error", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return protocol.NewErrorResponse(msg.ID, protocol.AsError(err))
+	}
+	resp, err := protocol.NewResult(msg.ID, result)
+	if err != nil {
+		return protocol.NewErrorResponse(msg.ID, protocol.AsError(err))
+	}
+	return resp
+}
+
+// call runs h, recovering from a panic in it: the panic is logged with its
+// stack and the request fails with an InternalError.
+func (r *Router) call(ctx *Context, h HandlerFunc, params json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		stack := debug.Stack()
+		ctx.Logger().Error("handler panicked", "panic", v, "stack", string(stack))
+		var data interface{}
+		if r.config.PanicStacks {
+			data = panicData{Panic: fmt.Sprint(v), Stack: string(stack)}
+		}
+		result, err = nil, protocol.NewError(protocol.InternalError, "internal error: handler panicked", data)
+	}()
+	return h(ctx, params)
+}
+
+// panicData is the InternalError data reported for a panicking handler
+// when Config.PanicStacks is set.
+type panicData struct {
+	Panic string `json:"panic"`
+	Stack string `json:"stack"`
+}
+
+// resolveTenant routes ctx to the registry of its tenant. A session stays
+// with the tenant of its first request; requests resolving to another
+// tenant are rejected.
+func (r *Router) resolveTenant(ctx *Context) error {
+	if r.config.Tenants == nil {
+		return nil
+	}
+	name, reg, err := r.config.Tenants.Resolve(ctx)
+	if err != nil {
+		ctx.Logger().Debug("tenant not resolved", "error", err)
+		return protocol.NewError(protocol.InvalidRequest, err.Error(), nil)
+	}
+	if s := ctx.Session(); s != nil && !s.bindTenant(name) {
+		return protocol.NewError(protocol.InvalidRequest, "session belongs to another tenant", nil)
+	}
+	ctx.tenant, ctx.registry = name, reg
+	if name != "" {
+		ctx.logger = ctx.logger.With("tenant", name)
+	}
+	return nil
+}
+
+// registryFor returns the registry serving ctx.
+func (r *Router) registryFor(ctx *Context) *registry.Registry {
+	if ctx.registry != nil {
+		return ctx.registry
+	}
+	return r.registry
+}
+
+// CallTool invokes a tool exactly as tools/call would, for handlers that
+// compose other tools. The nested call inherits the session, peer and
+// cancellation of ctx but not its RequestInfo, so metrics keep describing
+// the outer request. Protocol-level failures such as an unknown tool are
+// returned as *protocol.Error.
+func (r *Router) CallTool(ctx context.Context, name string, args json.RawMessage) (*protocol.ToolCallResult, error) {
+	params, err := json.Marshal(protocol.ToolCallParams{Name: name, Arguments: args})
+	if err != nil {
+		return nil, err
+	}
+	msg := &protocol.Message{ID: FromContext(ctx).RequestID(), Method: protocol.MethodToolsCall}
+	outer := FromContext(ctx)
+	rc := newContext(WithRequestInfo(ctx, &RequestInfo{}), msg, r.logger.With("method", msg.Method, "tool", name))
+	defer rc.cancel()
+	rc.tenant, rc.registry = outer.tenant, outer.registry
+	rc.progressToken = outer.progressToken
+	result, err := r.handleToolsCall(rc, params)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*protocol.ToolCallResult), nil
+}
+
+func (r *Router) handleInitialize(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.InitializeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	version := protocol.LatestProtocolVersion
+	for _, v := range supportedVersions {
+		if v == p.ProtocolVersion {
+			version = v
+			break
+		}
+	}
+	if s := ctx.Session(); s != nil {
+		s.initialize(version, &p)
+	}
+	if locale, ok := p.Capabilities.Experimental[ExperimentalLocale].(string); ok {
+		if s := ctx.Session(); s != nil {
+			s.SetLocale(locale)
+		}
+	}
+	if name, ok := p.Capabilities.Experimental[ExperimentalProfile].(string); ok {
+		profile, ok := r.config.ToolProfiles[name]
+		if !ok {
+			return nil, protocol.Errorf(protocol.InvalidParams, "unknown tool profile %q", name)
+		}
+		if s := ctx.Session(); s != nil {
+			s.SetToolFilter(&profile)
+		}
+	}
+	if shared, ok := p.Capabilities.Experimental[ExperimentalSharedDefs].(bool); ok {
+		if s := ctx.Session(); s != nil {
+			s.SetSharedDefs(shared)
+		}
+	}
+	compression, err := negotiateCompression(ctx, p.Capabilities.Experimental[ExperimentalCompression])
+	if err != nil {
+		return nil, err
+	}
+	caps := r.capabilities(r.registryFor(ctx))
+	experimental := map[string]interface{}{}
+	if compression != "" {
+		experimental[ExperimentalCompression] = CompressionChoice{Algorithm: compression}
+	}
+	if caps.Tools != nil {
+		experimental[ExperimentalToolFilter] = map[string]interface{}{}
+		experimental[ExperimentalSharedDefs] = map[string]interface{}{}
+	}
+	if len(r.custom) > 0 {
+		experimental[ExperimentalMethods] = map[string]interface{}{"methods": r.custom}
+	}
+	if len(experimental) > 0 {
+		caps.Experimental = experimental
+	}
+	return &protocol.InitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    caps,
+		ServerInfo:      r.config.Info,
+		Instructions:    r.config.Instructions,
+	}, nil
+}
+
+// capabilities advertises the features backed by the contents of reg:
+// tools, resources and prompts only when some are registered, or may be
+// with ListChanged, and resource subscriptions only when a resource is
+// subscribable. The tool-related experimental capabilities follow the
+// tools capability. Logging is advertised unless DisableLogging is set.
+func (r *Router) capabilities(reg *registry.Registry) protocol.ServerCapabilities {
+	var caps protocol.ServerCapabilities
+	if !r.config.DisableLogging {
+		caps.Logging = &struct{}{}
+	}
+	// A registry that may change while serving can gain entries of a kind
+	// it holds none of yet, which clients only list if it is advertised.
+	listChanged := r.config.ListChanged
+	if listChanged || len(reg.Tools()) > 0 {
+		caps.Tools = &protocol.ToolsCapability{ListChanged: listChanged}
+	}
+	resources := reg.Resources()
+	if listChanged || len(resources) > 0 || len(reg.ResourceTemplates()) > 0 {
+		caps.Resources = &protocol.ResourcesCapability{ListChanged: listChanged}
+		for _, d := range resources {
+			if d.Subscribable {
+				caps.Resources.Subscribe = true
+				break
+			}
+		}
+		for _, d := range reg.ResourceTemplates() {
+			if d.Subscribable {
+				caps.Resources.Subscribe = true
+				break
+			}
+		}
+	}
+	if listChanged || len(reg.Prompts()) > 0 {
+		caps.Prompts = &protocol.PromptsCapability{ListChanged: listChanged}
+	}
+	return caps
+}
+
+func (r *Router) handleInitialized(ctx *Context, params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (r *Router) handlePing(ctx *Context, params json.RawMessage) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+func (r *Router) handleToolsList(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.ListToolsParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	reg := r.registryFor(ctx)
+	tools := reg.Tools()
+	result := &protocol.ListToolsResult{Tools: make([]protocol.Tool, 0, len(tools))}
+	locale := ctx.Locale()
+	for _, d := range tools {
+		if !ctx.toolVisible(d) || !r.toolEnabled(d) || !d.Matches(p.Filter) {
+			continue
+		}
+		t := d.Tool()
+		t.Description = d.DescriptionFor(locale)
+		if d.Version != "" && d.Version != reg.DefaultToolVersion(d.Name) {
+			t.Name = d.Key()
+		}
+		result.Tools = append(result.Tools, t)
+	}
+	start, end, next, err := r.page(p.Cursor, len(result.Tools))
+	if err != nil {
+		return nil, err
+	}
+	result.Tools, result.NextCursor = result.Tools[start:end], next
+	if s := ctx.Session(); s != nil && s.SharedDefs() {
+		shareDefs(result)
+	}
+	return result, nil
+}
+
+// shareDefs moves the schema definitions the listed tools have in common
+// into result.Defs.
+func shareDefs(result *protocol.ListToolsResult) {
+	schemas := make([]map[string]interface{}, len(result.Tools))
+	for i, t := range result.Tools {
+		schemas[i] = t.InputSchema
+	}
+	defs, schemas := schema.ShareDefs(schemas)
+	if len(defs) == 0 {
+		return
+	}
+	for i := range result.Tools {
+		result.Tools[i].InputSchema = schemas[i]
+	}
+	result.Defs = defs
+}
+
+func (r *Router) handleToolsCall(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.ToolCallParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	ctx.setTarget(p.Name)
+	reg := r.registryFor(ctx)
+	var tool *registry.ToolDescriptor
+	var ok bool
+	if p.Meta != nil && p.Meta.ToolVersion != "" {
+		tool, ok = reg.ToolVersion(p.Name, p.Meta.ToolVersion)
+	} else {
+		tool, ok = reg.Tool(p.Name)
+	}
+	if !ok || !ctx.toolVisible(tool) || !r.toolEnabled(tool) {
+		return nil, protocol.Errorf(protocol.InvalidParams, "unknown tool %q", p.Name)
+	}
+	ctx.setTarget(tool.Key())
+	if tool.Deprecated {
+		ctx.setDeprecated()
+	}
+	if err := reg.EnsureToolInit(ctx, tool.Key()); err != nil {
+		return nil, protocol.NewError(protocol.InternalError, err.Error(), nil)
+	}
+	args := p.Arguments
+	if len(bytes.TrimSpace(args)) == 0 {
+		args = json.RawMessage("{}")
+	}
+	if err := schema.CheckLimits(args, tool.InputSchema, r.config.ArgumentLimits); err != nil {
+		var limitErr *schema.LimitError
+		if errors.As(err, &limitErr) {
+			return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), limitErr)
+		}
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+	}
+	args, err := transformArguments(ctx, tool, args)
+	if err != nil {
+		return nil, err
+	}
+	if !r.config.SkipArgumentValidation && !tool.ChecksArguments {
+		if err := validateArguments(args, tool.InputSchema); err != nil {
+			return nil, err
+		}
+	}
+	ctx.args, ctx.useNumber = args, r.config.UseNumber || tool.UseNumber
+	ctx.validateArgs = !r.config.SkipArgumentValidation
+	result, err := r.runTool(ctx, tool, args)
+	if err == nil {
+		result, err = transformResult(ctx, tool, result)
+	}
+	if err != nil {
+		var rpcErr *protocol.Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return &protocol.ToolCallResult{
+			Content: []protocol.Content{protocol.TextContent(err.Error())},
+			IsError: true,
+		}, nil
+	}
+	if result.Content == nil {
+		result.Content = []protocol.Content{}
+	}
+	if tool.Deprecated {
+		result.Content = append(result.Content, protocol.TextContent(tool.DeprecationWarning()))
+	}
+	return result, nil
+}
+
+// validateArguments checks the arguments of a tool call against the
+// tool's input schema.
+func validateArguments(args json.RawMessage, inputSchema map[string]interface{}) error {
+	err := schema.Validate(args, inputSchema)
+	if err == nil {
+		return nil
+	}
+	var errs schema.ValidationErrors
+	if errors.As(err, &errs) {
+		return protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), argumentErrorsData{errs})
+	}
+	return protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+}
+
+// argumentErrorsData is the InvalidParams data reported for tool call
+// arguments that do not match the tool's input schema.
+type argumentErrorsData struct {
+	Errors schema.ValidationErrors `json:"errors"`
+}
+
+func (r *Router) handleResourcesList(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.ListResourcesParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	resources := r.registryFor(ctx).Resources()
+	result := &protocol.ListResourcesResult{Resources: make([]protocol.Resource, 0, len(resources))}
+	principal := ctx.Principal()
+	for _, d := range resources {
+		if !r.config.ResourceACL.Allowed(principal, d.URI) {
+			continue
+		}
+		result.Resources = append(result.Resources, d.Resource())
+	}
+	start, end, next, err := r.page(p.Cursor, len(result.Resources))
+	if err != nil {
+		return nil, err
+	}
+	result.Resources, result.NextCursor = result.Resources[start:end], next
+	return result, nil
+}
+
+func (r *Router) handleResourcesRead(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.ReadResourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	reg := r.registryFor(ctx)
+	var read func() (*protocol.ReadResourceResult, error)
+	if res, ok := reg.Resource(p.URI); ok {
+		read = func() (*protocol.ReadResourceResult, error) { return res.Handler(ctx, p.URI) }
+	} else if tmpl, vars, ok := reg.MatchResourceTemplate(p.URI); ok {
+		read = func() (*protocol.ReadResourceResult, error) { return tmpl.Handler(ctx, p.URI, vars) }
+	}
+	if read != nil && !r.config.ResourceACL.Allowed(ctx.Principal(), p.URI) {
+		ctx.Logger().Debug("resource access denied", "uri", p.URI)
+		read = nil
+	}
+	if read == nil {
+		return nil, protocol.NewError(protocol.ResourceNotFound, "resource not found", map[string]string{"uri": p.URI})
+	}
+	result, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &protocol.ReadResourceResult{}
+	}
+	if result.Contents == nil {
+		result.Contents = []protocol.ResourceContents{}
+	}
+	return result, nil
+}
+
+// handleResourcesSubscribe records a subscription to a resource, static
+// or matched by a template, that is marked subscribable. The subscription
+// lasts as long as the connection's session.
+func (r *Router) handleResourcesSubscribe(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.SubscribeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.URI == "" {
+		return nil, protocol.NewError(protocol.InvalidParams, "missing uri", nil)
+	}
+	reg := r.registryFor(ctx)
+	found, subscribable := false, false
+	if res, ok := reg.Resource(p.URI); ok {
+		found, subscribable = true, res.Subscribable
+	} else if tmpl, _, ok := reg.MatchResourceTemplate(p.URI); ok {
+		found, subscribable = true, tmpl.Subscribable
+	}
+	if !found || !r.config.ResourceACL.Allowed(ctx.Principal(), p.URI) {
+		return nil, protocol.NewError(protocol.ResourceNotFound, "resource not found", map[string]string{"uri": p.URI})
+	}
+	if !subscribable {
+		return nil, protocol.NewError(protocol.InvalidParams, "resource does not support subscriptions", map[string]string{"uri": p.URI})
+	}
+	sess := ctx.Session()
+	if sess == nil {
+		return nil, protocol.NewError(protocol.InvalidRequest, "subscriptions require a session", nil)
+	}
+	sess.Subscribe(p.URI)
+	return struct{}{}, nil
+}
+
+// handleResourcesUnsubscribe removes a subscription. Unsubscribing from a
+// resource that was not subscribed to is not an error.
+func (r *Router) handleResourcesUnsubscribe(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.SubscribeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.URI == "" {
+		return nil, protocol.NewError(protocol.InvalidParams, "missing uri", nil)
+	}
+	if sess := ctx.Session(); sess != nil {
+		sess.Unsubscribe(p.URI)
+	}
+	return struct{}{}, nil
+}
+
+func (r *Router) handleResourceTemplatesList(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.ListResourceTemplatesParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	templates := r.registryFor(ctx).ResourceTemplates()
+	start, end, next, err := r.page(p.Cursor, len(templates))
+	if err != nil {
+		return nil, err
+	}
+	result := &protocol.ListResourceTemplatesResult{ResourceTemplates: make([]protocol.ResourceTemplate, 0, end-start), NextCursor: next}
+	for _, d := range templates[start:end] {
+		result.ResourceTemplates = append(result.ResourceTemplates, d.ResourceTemplate())
+	}
+	return result, nil
+}
+
+func (r *Router) handlePromptsList(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.ListPromptsParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	prompts := r.registryFor(ctx).Prompts()
+	start, end, next, err := r.page(p.Cursor, len(prompts))
+	if err != nil {
+		return nil, err
+	}
+	result := &protocol.ListPromptsResult{Prompts: make([]protocol.Prompt, 0, end-start), NextCursor: next}
+	locale := ctx.Locale()
+	for _, d := range prompts[start:end] {
+		p := d.Prompt()
+		p.Description = d.DescriptionFor(locale)
+		result.Prompts = append(result.Prompts, p)
+	}
+	return result, nil
+}
+
+// promptArgumentsData is the InvalidParams data reported for prompts/get
+// requests whose arguments do not match the prompt's declaration.
+type promptArgumentsData struct {
+	Missing []string `json:"missing,omitempty"`
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+func (r *Router) handlePromptsGet(ctx *Context, params json.RawMessage) (interface{}, error) {
+	var p protocol.GetPromptParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	ctx.setTarget(p.Name)
+	reg := r.registryFor(ctx)
+	d, ok := reg.Prompt(p.Name)
+	if !ok {
+		return nil, protocol.Errorf(protocol.InvalidParams, "unknown prompt %q", p.Name)
+	}
+	var bad promptArgumentsData
+	declared := make(map[string]bool, len(d.Arguments))
+	for _, a := range d.Arguments {
+		declared[a.Name] = true
+		if _, ok := p.Arguments[a.Name]; a.Required && !ok {
+			bad.Missing = append(bad.Missing, a.Name)
+		}
+	}
+	for name := range p.Arguments {
+		if !declared[name] {
+			bad.Unknown = append(bad.Unknown, name)
+		}
+	}
+	if len(bad.Missing) > 0 || len(bad.Unknown) > 0 {
+		sort.Strings(bad.Unknown)
+		var problems []string
+		if len(bad.Missing) > 0 {
+			problems = append(problems, "missing required "+strings.Join(bad.Missing, ", "))
+		}
+		if len(bad.Unknown) > 0 {
+			problems = append(problems, "unknown "+strings.Join(bad.Unknown, ", "))
+		}
+		return nil, protocol.NewError(protocol.InvalidParams,
+			fmt.Sprintf("invalid arguments for prompt %q: %s", p.Name, strings.Join(problems, "; ")), bad)
+	}
+	rendered, err := reg.RenderPrompt(ctx, p.Name, p.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	result := *rendered // may be shared with the prompt cache
+	if result.Description == "" {
+		result.Description = d.DescriptionFor(ctx.Locale())
+	}
+	if result.Messages == nil {
+		result.Messages = []protocol.PromptMessage{}
+	}
+	return &result, nil
+}
+
+// decodeParams unmarshals params into v, treating absent params as empty.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(bytes.TrimSpace(params)) == 0 || bytes.Equal(bytes.TrimSpace(params), []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return protocol.NewError(protocol.InvalidParams, "invalid params: "+err.Error(), nil)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package runtime
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/auth"
+	"github.com/hyperleex/zenmcp/idgen"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ExperimentalLocale is the experimental client capability through which a
+// client announces its preferred locale during initialize, as a BCP 47 tag:
+//
+//	"capabilities": {"experimental": {"zenmcp/locale": "de-DE"}}
+const ExperimentalLocale = "zenmcp/locale"
+
+// ExperimentalProfile is the experimental client capability through which
+// a client selects one of the tool profiles configured on the server:
+//
+//	"capabilities": {"experimental": {"zenmcp/profile": "readonly"}}
+const ExperimentalProfile = "zenmcp/profile"
+
+// ExperimentalToolFilter is the experimental server capability advertising
+// support for the zenmcp/filter parameter of tools/list.
+const ExperimentalToolFilter = "zenmcp/toolFilter"
+
+// ExperimentalSharedDefs is the experimental capability through which a
+// client asks for schema definitions shared between tools to be sent once
+// per tools/list response, in its zenmcp/defs member:
+//
+//	"capabilities": {"experimental": {"zenmcp/sharedDefs": true}}
+const ExperimentalSharedDefs = "zenmcp/sharedDefs"
+
+// Session holds state a client established on its connection, such as
+// what it announced during initialize and the resources it subscribed to.
+type Session struct {
+	id string
+
+	mu              sync.Mutex
+	protocolVersion string
+	clientInfo      protocol.Implementation
+	capabilities    protocol.ClientCapabilities
+	logLevel        protocol.LoggingLevel
+	locale          string
+	profile         *protocol.ToolFilter
+	tenant          *string
+
+	sharedDefs bool
+	// subscriptions holds the URIs of the resources the client
+	// subscribed to.
+	subscriptions map[string]bool
+}
+
+// NewSession returns an empty session with an ID from idgen.Default.
+func NewSession() *Session {
+	return NewSessionWithID(idgen.Default.NewID())
+}
+
+// NewSessionWithID returns an empty session identified by id.
+func NewSessionWithID(id string) *Session {
+	return &Session{id: id}
+}
+
+// ID returns the session's identifier, unique within the process.
+func (s *Session) ID() string { return s.id }
+
+// initialize records what the client announced in initialize and the
+// protocol version negotiated.
+func (s *Session) initialize(version string, p *protocol.InitializeParams) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = version
+	s.clientInfo = p.ClientInfo
+	s.capabilities = p.Capabilities
+}
+
+// ProtocolVersion returns the protocol version negotiated in initialize,
+// or "" before it.
+func (s *Session) ProtocolVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protocolVersion
+}
+
+// ClientInfo returns the name and version the client gave in initialize.
+func (s *Session) ClientInfo() protocol.Implementation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clientInfo
+}
+
+// ClientCapabilities returns the capabilities the client announced in
+// initialize.
+func (s *Session) ClientCapabilities() protocol.ClientCapabilities {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.capabilities
+}
+
+// LogLevel returns the level the client asked for with logging/setLevel,
+// or "" when it asked for no log messages.
+func (s *Session) LogLevel() protocol.LoggingLevel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logLevel
+}
+
+// SetLogLevel records the least severe level of the log messages the
+// client wants.
+func (s *Session) SetLogLevel(level protocol.LoggingLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logLevel = level
+}
+
+// Locale returns the locale announced by the client, or "".
+func (s *Session) Locale() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locale
+}
+
+// SetLocale records the client's preferred locale.
+func (s *Session) SetLocale(locale string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locale = locale
+}
+
+// ToolFilter returns the filter of the tool profile selected for the
+// session, or nil when every tool is visible.
+func (s *Session) ToolFilter() *protocol.ToolFilter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.profile
+}
+
+// SetToolFilter restricts the tools visible to the session.
+func (s *Session) SetToolFilter(f *protocol.ToolFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profile = f
+}
+
+// SharedDefs reports whether the client accepts schema definitions hoisted
+// out of the tools' input schemas.
+func (s *Session) SharedDefs() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sharedDefs
+}
+
+// SetSharedDefs records whether the client accepts hoisted schema
+// definitions.
+func (s *Session) SetSharedDefs(shared bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sharedDefs = shared
+}
+
+// Subscribe records the client's subscription to updates of the resource
+// at uri.
+func (s *Session) Subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string]bool)
+	}
+	s.subscriptions[uri] = true
+}
+
+// Unsubscribe removes the subscription to uri and reports whether there
+// was one.
+func (s *Session) Unsubscribe(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscriptions[uri] {
+		return false
+	}
+	delete(s.subscriptions, uri)
+	return true
+}
+
+// Subscribed reports whether the client subscribed to uri.
+func (s *Session) Subscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscriptions[uri]
+}
+
+// Subscriptions returns the URIs the client subscribed to, sorted.
+func (s *Session) Subscriptions() []string {
+	s.mu.Lock()
+	uris := make([]string, 0, len(s.subscriptions))
+	for uri := range s.subscriptions {
+		uris = append(uris, uri)
+	}
+	s.mu.Unlock()
+	sort.Strings(uris)
+	return uris
+}
+
+// bindTenant binds the session to tenant on first use and reports whether
+// it is bound to tenant.
+func (s *Session) bindTenant(tenant string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tenant == nil {
+		s.tenant = &tenant
+	}
+	return *s.tenant == tenant
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx carrying s. Servers attach one session
+// per connection to the contexts they dispatch.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// Session returns the session of the connection the request arrived on, or
+// nil when there is none.
+func (c *Context) Session() *Session {
+	s, _ := c.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// Locale returns the client's preferred locale: the one announced in
+// initialize if any, otherwise the one reported by the transport.
+func (c *Context) Locale() string {
+	if s := c.Session(); s != nil {
+		if l := s.Locale(); l != "" {
+			return l
+		}
+	}
+	p, _ := transport.PeerFromContext(c)
+	return p.Locale
+}
+
+// Principal returns the authenticated client, or nil for anonymous
+// connections.
+func (c *Context) Principal() *auth.Principal {
+	p, _ := transport.PeerFromContext(c)
+	return p.Principal
+}
+
+// toolVisible reports whether the session's profile lets it see tool.
+func (c *Context) toolVisible(tool *registry.ToolDescriptor) bool {
+	s := c.Session()
+	return s == nil || tool.Matches(s.ToolFilter())
+}
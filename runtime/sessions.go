@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/idgen"
+)
+
+// SessionManager keeps the sessions of the open connections of a server,
+// by ID. The server opens a session for every connection it accepts and
+// closes it with the connection, whether the client went away or the
+// server ended it, as it does with idle connections.
+type SessionManager struct {
+	ids idgen.Generator
+
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewSessionManager returns a manager whose sessions get IDs from ids, or
+// from idgen.Default when ids is nil.
+func NewSessionManager(ids idgen.Generator) *SessionManager {
+	return &SessionManager{ids: idgen.Or(ids), sessions: make(map[string]*Session)}
+}
+
+// Open starts a session identified by id, or by a new ID when id is "",
+// as for transports that do not assign session IDs of their own.
+func (m *SessionManager) Open(id string) *Session {
+	if id == "" {
+		id = m.ids.NewID()
+	}
+	s := NewSessionWithID(id)
+	m.mu.Lock()
+	m.sessions[id] = s
+	m.mu.Unlock()
+	return s
+}
+
+// Close forgets s.
+func (m *SessionManager) Close(s *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[s.id] == s {
+		delete(m.sessions, s.id)
+	}
+}
+
+// Get returns the open session identified by id.
+func (m *SessionManager) Get(id string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	return s, ok
+}
+
+// Len returns the number of open sessions.
+func (m *SessionManager) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sessions)
+}
+
+// Sessions returns the open sessions, ordered by ID.
+func (m *SessionManager) Sessions() []*Session {
+	m.mu.RLock()
+	list := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, s)
+	}
+	m.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+// transformArguments runs the argument transforms of tool on args, in
+// order.
+func transformArguments(ctx *Context, tool *registry.ToolDescriptor, args json.RawMessage) (json.RawMessage, error) {
+	for i, t := range tool.Transforms {
+		out, err := t(ctx, args)
+		if err != nil {
+			var rpcErr *protocol.Error
+			if errors.As(err, &rpcErr) {
+				return nil, rpcErr
+			}
+			return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+		}
+		if !json.Valid(out) {
+			return nil, protocol.NewError(protocol.InternalError, fmt.Sprintf("argument transform %d of tool %q returned invalid JSON", i, tool.Key()), nil)
+		}
+		args = out
+	}
+	return args, nil
+}
+
+// transformResult runs the result transforms of tool on res, in order. A
+// nil result is replaced by an empty one first.
+func transformResult(ctx *Context, tool *registry.ToolDescriptor, res *protocol.ToolCallResult) (*protocol.ToolCallResult, error) {
+	if res == nil {
+		res = &protocol.ToolCallResult{}
+	}
+	for _, t := range tool.ResultTransforms {
+		out, err := t(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+		if out != nil {
+			res = out
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// parseDefault decodes the `default` tag of a field of type t.
+func parseDefault(t reflect.Type, tag string) (interface{}, error) {
+	v := reflect.New(t)
+	err := json.Unmarshal([]byte(tag), v.Interface())
+	if err != nil {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.String {
+			return nil, fmt.Errorf("schema: invalid default %q: %w", tag, err)
+		}
+		return tag, nil
+	}
+	v = v.Elem()
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Interface(), nil
+}
+
+// HasDefaults reports whether s, or any schema nested in its properties,
+// items or definitions, declares a default.
+func HasDefaults(s map[string]interface{}) bool {
+	if hasDefaults(s) {
+		return true
+	}
+	defs, _ := s["$defs"].(map[string]interface{})
+	for _, d := range defs {
+		if ds, ok := d.(map[string]interface{}); ok && hasDefaults(ds) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDefaults is HasDefaults without following references.
+func hasDefaults(s map[string]interface{}) bool {
+	if _, ok := s["default"]; ok {
+		return true
+	}
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		for _, p := range props {
+			if ps, ok := p.(map[string]interface{}); ok && hasDefaults(ps) {
+				return true
+			}
+		}
+	}
+	if items, ok := s["items"].(map[string]interface{}); ok && hasDefaults(items) {
+		return true
+	}
+	return false
+}
+
+// ApplyDefaults returns the JSON object raw with every property missing
+// from it, and declared with a default in s, set to that default. Nested
+// objects and arrays of objects are filled in the same way. Values that are
+// not JSON objects are returned unchanged.
+func ApplyDefaults(s map[string]interface{}, raw json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return raw, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if !fill(s, s, v) {
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
+
+// fill applies the defaults of s to v and reports whether it changed v.
+// References in s are resolved against root.
+func fill(root, s map[string]interface{}, v interface{}) bool {
+	s = deref(root, s)
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		for name, p := range props {
+			ps, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if cur, present := v[name]; present {
+				changed = fill(root, ps, cur) || changed
+			} else if def, ok := ps["default"]; ok {
+				v[name] = def
+				changed = true
+			}
+		}
+	case []interface{}:
+		items, _ := s["items"].(map[string]interface{})
+		if items == nil {
+			break
+		}
+		for _, e := range v {
+			changed = fill(root, items, e) || changed
+		}
+	}
+	return changed
+}
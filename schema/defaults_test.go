@@ -0,0 +1,225 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/schema"
+)
+
+type filterArgs struct {
+	Field string `json:"field"`
+	// Value and pointer fields with defaults.
+	Limit   int      `json:"limit" default:"10"`
+	Ratio   *float64 `json:"ratio" default:"0.5"`
+	Verbose *bool    `json:"verbose" default:"true"`
+	Mode    string   `json:"mode" default:"fast"`
+	Tag     *string  `json:"tag" default:"\"all\""`
+	// Optional values without defaults stay unset.
+	Note *string `json:"note,omitempty"`
+}
+
+type pageArgs struct {
+	Size   int  `json:"size" default:"20"`
+	Cursor *int `json:"cursor" default:"0"`
+}
+
+type searchArgs struct {
+	Query   string       `json:"query"`
+	Page    pageArgs     `json:"page,omitempty"`
+	Next    *pageArgs    `json:"next,omitempty"`
+	Filters []filterArgs `json:"filters,omitempty"`
+	Sorts   []*sortArgs  `json:"sorts,omitempty"`
+}
+
+type sortArgs struct {
+	By   string `json:"by"`
+	Desc bool   `json:"desc" default:"false"`
+	// Both pointer and value uses of pageArgs above make it a shared
+	// definition, so defaults are found through $ref too.
+	Window pageArgs `json:"window,omitempty"`
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// decodeWithDefaults applies the defaults of the schema of T to raw and
+// decodes the result, as typed tool handlers get their arguments.
+func decodeWithDefaults[T any](t *testing.T, raw string) T {
+	t.Helper()
+	s, err := schema.For[T]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled, err := schema.ApplyDefaults(s, json.RawMessage(raw))
+	if err != nil {
+		t.Fatalf("ApplyDefaults(%s): %v", raw, err)
+	}
+	var v T
+	if err := json.Unmarshal(filled, &v); err != nil {
+		t.Fatalf("decoding %s: %v", filled, err)
+	}
+	return v
+}
+
+func TestApplyDefaultsFields(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want filterArgs
+	}{
+		{
+			name: "all missing",
+			raw:  `{"field":"size"}`,
+			want: filterArgs{Field: "size", Limit: 10, Ratio: ptr(0.5), Verbose: ptr(true), Mode: "fast", Tag: ptr("all")},
+		},
+		{
+			name: "zero values are kept",
+			raw:  `{"field":"size","limit":0,"ratio":0,"verbose":false,"mode":"","tag":""}`,
+			want: filterArgs{Field: "size", Limit: 0, Ratio: ptr(0.0), Verbose: ptr(false), Mode: "", Tag: ptr("")},
+		},
+		{
+			name: "null leaves a pointer nil",
+			raw:  `{"field":"size","ratio":null,"verbose":null}`,
+			want: filterArgs{Field: "size", Limit: 10, Mode: "fast", Tag: ptr("all")},
+		},
+		{
+			name: "given values win",
+			raw:  `{"field":"size","limit":3,"ratio":2.5,"verbose":false,"mode":"exact","tag":"x","note":"n"}`,
+			want: filterArgs{Field: "size", Limit: 3, Ratio: ptr(2.5), Verbose: ptr(false), Mode: "exact", Tag: ptr("x"), Note: ptr("n")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeWithDefaults[filterArgs](t, tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s, want %s", dump(got), dump(tt.want))
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsNested(t *testing.T) {
+	got := decodeWithDefaults[searchArgs](t, `{
+		"query": "q",
+		"page": {"size": 5},
+		"next": {},
+		"filters": [{"field": "a"}, {"field": "b", "limit": 1, "verbose": false}],
+		"sorts": [{"by": "date", "window": {}}, {"by": "name", "desc": true}]
+	}`)
+	want := searchArgs{
+		Query: "q",
+		Page:  pageArgs{Size: 5, Cursor: ptr(0)},
+		Next:  &pageArgs{Size: 20, Cursor: ptr(0)},
+		Filters: []filterArgs{
+			{Field: "a", Limit: 10, Ratio: ptr(0.5), Verbose: ptr(true), Mode: "fast", Tag: ptr("all")},
+			{Field: "b", Limit: 1, Ratio: ptr(0.5), Verbose: ptr(false), Mode: "fast", Tag: ptr("all")},
+		},
+		Sorts: []*sortArgs{
+			{By: "date", Window: pageArgs{Size: 20, Cursor: ptr(0)}},
+			{By: "name", Desc: true},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s\nwant %s", dump(got), dump(want))
+	}
+
+	// Defaults fill objects that are present; absent objects without a
+	// default of their own stay absent.
+	got = decodeWithDefaults[searchArgs](t, `{"query":"q"}`)
+	if !reflect.DeepEqual(got, searchArgs{Query: "q"}) {
+		t.Errorf("got %s, want only the query", dump(got))
+	}
+}
+
+func TestApplyDefaultsSharedDefinitions(t *testing.T) {
+	s, err := schema.For[searchArgs]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, _ := s["$defs"].(map[string]interface{})
+	if len(defs) == 0 {
+		t.Fatalf("expected pageArgs to be defined in $defs, got %s", dump(s))
+	}
+	if !schema.HasDefaults(s) {
+		t.Error("HasDefaults = false for a schema whose definitions declare defaults")
+	}
+}
+
+func TestApplyDefaultsDeclaredSchema(t *testing.T) {
+	s := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"units": map[string]interface{}{"type": "string", "default": "metric"},
+			"days":  map[string]interface{}{"type": "integer", "default": 3},
+			"where": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"country": map[string]interface{}{"type": "string", "default": "NZ"},
+				},
+			},
+		},
+	}
+	got, err := schema.ApplyDefaults(s, json.RawMessage(`{"days":7,"where":{}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"days":7,"units":"metric","where":{"country":"NZ"}}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestApplyDefaultsUnchanged(t *testing.T) {
+	s, err := schema.For[filterArgs]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []string{
+		`{"field":"f","limit":1,"ratio":1,"verbose":true,"mode":"m","tag":"t"}`,
+		`[1,2]`,
+		`"text"`,
+		`null`,
+		``,
+	} {
+		got, err := schema.ApplyDefaults(s, json.RawMessage(raw))
+		if err != nil {
+			t.Errorf("ApplyDefaults(%s): %v", raw, err)
+			continue
+		}
+		if string(got) != raw {
+			t.Errorf("ApplyDefaults(%s) = %s, want it unchanged", raw, got)
+		}
+	}
+	if _, err := schema.ApplyDefaults(s, json.RawMessage(`{"field":`)); err == nil {
+		t.Error("ApplyDefaults accepted a truncated object")
+	}
+}
+
+func TestHasDefaults(t *testing.T) {
+	type plain struct {
+		A string `json:"a"`
+		B []int  `json:"b"`
+	}
+	s, err := schema.For[plain]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.HasDefaults(s) {
+		t.Error("HasDefaults = true for a schema without defaults")
+	}
+	s, err = schema.For[[]filterArgs]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schema.HasDefaults(s) {
+		t.Error("HasDefaults = false for an array of objects with defaults")
+	}
+}
+
+func dump(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
@@ -0,0 +1,287 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change is a difference between two versions of an input schema.
+type Change struct {
+	// Path locates the change like ValidationError.Path, with "[]" standing
+	// for the items of an array and "*" for additional properties. It is
+	// empty for the schema as a whole.
+	Path string
+	// Breaking reports whether the new schema may reject values the old
+	// one accepted.
+	Breaking bool
+	Message  string
+}
+
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "arguments"
+	}
+	return path + ": " + c.Message
+}
+
+// lowerBounds and upperBounds are the keywords whose raising, respectively
+// lowering, narrows the values a schema accepts.
+var (
+	lowerBounds = []string{"minimum", "exclusiveMinimum", "minLength", "minItems", "minProperties"}
+	upperBounds = []string{"maximum", "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"}
+)
+
+// Diff compares two versions of the schema of the same values, such as a
+// tool's arguments, and returns their differences, breaking ones being
+// those that may reject values old accepted: a removed type, enum value or
+// alternative, a new or tightened bound or pattern, a newly required or
+// no longer allowed property. It compares the keywords Validate checks,
+// following references into $defs, and reports other differences, such as
+// to descriptions, not at all. A nil schema accepts any value.
+func Diff(old, new map[string]interface{}) []Change {
+	d := differ{oldRoot: old, newRoot: new, seen: make(map[[2]uintptr]bool)}
+	d.compare("", old, new)
+	return d.changes
+}
+
+type differ struct {
+	oldRoot, newRoot map[string]interface{}
+	// seen holds the pairs of schemas compared so far, which ends the
+	// comparison of recursive definitions.
+	seen    map[[2]uintptr]bool
+	changes []Change
+}
+
+func (d *differ) add(path string, breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Path: path, Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) compare(path string, old, new map[string]interface{}) {
+	old, new = deref(d.oldRoot, old), deref(d.newRoot, new)
+	if old != nil && new != nil {
+		pair := [2]uintptr{reflect.ValueOf(old).Pointer(), reflect.ValueOf(new).Pointer()}
+		if d.seen[pair] {
+			return
+		}
+		d.seen[pair] = true
+	}
+	d.compareTypes(path, old["type"], new["type"])
+	d.compareEnums(path, old["enum"], new["enum"])
+	if c, ok := new["const"]; ok && !equalJSON(old["const"], c) {
+		d.add(path, true, "now must be %s", encode(c))
+	}
+	for _, kw := range lowerBounds {
+		d.compareBound(path, kw, old[kw], new[kw], 1)
+	}
+	for _, kw := range upperBounds {
+		d.compareBound(path, kw, old[kw], new[kw], -1)
+	}
+	op, _ := old["pattern"].(string)
+	np, _ := new["pattern"].(string)
+	switch {
+	case np != "" && op == "":
+		d.add(path, true, "now must match %q", np)
+	case np != op && op != "" && np != "":
+		d.add(path, true, "pattern changed from %q to %q", op, np)
+	case np == "" && op != "":
+		d.add(path, false, "no longer must match %q", op)
+	}
+	d.compareObjects(path, old, new)
+	oi, _ := old["items"].(map[string]interface{})
+	ni, _ := new["items"].(map[string]interface{})
+	if oi != nil || ni != nil {
+		d.compare(path+"[]", oi, ni)
+	}
+	d.compareAlternatives(path, "anyOf", old["anyOf"], new["anyOf"])
+	d.compareAlternatives(path, "oneOf", old["oneOf"], new["oneOf"])
+	if oa, na := schemas(old["allOf"]), schemas(new["allOf"]); !equalJSON(oa, na) {
+		d.add(path, true, "allOf changed")
+	}
+	if on, nn := old["not"], new["not"]; !equalJSON(on, nn) {
+		d.add(path, nn != nil, "excluded schema changed")
+	}
+}
+
+func (d *differ) compareTypes(path string, ot, nt interface{}) {
+	olds, news := stringList(ot), stringList(nt)
+	if len(news) == 0 {
+		if len(olds) > 0 {
+			d.add(path, false, "no longer restricted to %s", typeList(ot))
+		}
+		return
+	}
+	if len(olds) == 0 {
+		d.add(path, true, "now must be %s", typeList(nt))
+		return
+	}
+	for _, t := range olds {
+		// A number schema accepts the integers an integer schema did.
+		if !contains(news, t) && !(t == "integer" && contains(news, "number")) {
+			d.add(path, true, "type changed from %s to %s", typeList(ot), typeList(nt))
+			return
+		}
+	}
+	if len(news) > len(olds) || !contains(olds, "number") && contains(news, "number") {
+		d.add(path, false, "type widened from %s to %s", typeList(ot), typeList(nt))
+	}
+}
+
+func (d *differ) compareEnums(path string, oe, ne interface{}) {
+	if ne == nil {
+		if oe != nil {
+			d.add(path, false, "no longer limited to %s", encode(oe))
+		}
+		return
+	}
+	if oe == nil {
+		d.add(path, true, "now must be one of %s", encode(ne))
+		return
+	}
+	olds, news := toSlice(oe), toSlice(ne)
+	for _, v := range olds {
+		if !containsJSON(news, v) {
+			d.add(path, true, "value %s no longer allowed", encode(v))
+		}
+	}
+	for _, v := range news {
+		if !containsJSON(olds, v) {
+			d.add(path, false, "value %s now allowed", encode(v))
+		}
+	}
+}
+
+// compareBound compares the bound kw, which narrows the accepted values
+// when moved in direction dir: 1 for lower bounds, -1 for upper ones.
+func (d *differ) compareBound(path, kw string, ov, nv interface{}, dir float64) {
+	o, oldSet := number(ov)
+	n, newSet := number(nv)
+	switch {
+	case newSet && !oldSet:
+		d.add(path, true, "%s %v added", kw, n)
+	case oldSet && !newSet:
+		d.add(path, false, "%s %v removed", kw, o)
+	case oldSet && (n-o)*dir > 0:
+		d.add(path, true, "%s tightened from %v to %v", kw, o, n)
+	case oldSet && (n-o)*dir < 0:
+		d.add(path, false, "%s relaxed from %v to %v", kw, o, n)
+	}
+}
+
+func (d *differ) compareObjects(path string, old, new map[string]interface{}) {
+	oldProps, _ := old["properties"].(map[string]interface{})
+	newProps, _ := new["properties"].(map[string]interface{})
+	oldReq, newReq := stringList(old["required"]), stringList(new["required"])
+	closed := false
+	if ap, ok := new["additionalProperties"].(bool); ok && !ap {
+		closed = true
+	}
+
+	names := make([]string, 0, len(oldProps)+len(newProps))
+	for name := range oldProps {
+		names = append(names, name)
+	}
+	for name := range newProps {
+		if _, ok := oldProps[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range newReq {
+		_, inOld := oldProps[name]
+		_, inNew := newProps[name]
+		if !inOld && !inNew {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := joinPath(path, name)
+		op, inOld := oldProps[name]
+		np, inNew := newProps[name]
+		wasReq, isReq := contains(oldReq, name), contains(newReq, name)
+		switch {
+		case isReq && !wasReq && !inOld:
+			d.add(p, true, "new required property")
+		case isReq && !wasReq:
+			d.add(p, true, "now required")
+		case wasReq && !isReq:
+			d.add(p, false, "no longer required")
+		}
+		switch {
+		case inOld && !inNew:
+			d.add(p, closed, "property removed")
+		case inNew && !inOld && !isReq:
+			d.add(p, false, "property added")
+		case inOld && inNew:
+			om, _ := op.(map[string]interface{})
+			nm, _ := np.(map[string]interface{})
+			d.compare(p, om, nm)
+		}
+	}
+
+	switch oap, nap := old["additionalProperties"], new["additionalProperties"]; {
+	case closed:
+		if ap, ok := oap.(bool); !ok || ap {
+			d.add(path, true, "additional properties no longer allowed")
+		}
+	case oap == false:
+		d.add(path, false, "additional properties now allowed")
+	default:
+		om, _ := oap.(map[string]interface{})
+		nm, _ := nap.(map[string]interface{})
+		if nm != nil {
+			d.compare(joinPath(path, "*"), om, nm)
+		}
+	}
+}
+
+// compareAlternatives reports the alternatives of anyOf or oneOf that were
+// removed or changed, which is breaking, and those that were added.
+func (d *differ) compareAlternatives(path, kw string, ov, nv interface{}) {
+	olds, news := schemas(ov), schemas(nv)
+	if len(olds) == 0 && len(news) == 0 {
+		return
+	}
+	if len(news) == 0 {
+		d.add(path, false, "%s removed", kw)
+		return
+	}
+	if len(olds) == 0 {
+		d.add(path, true, "%s added", kw)
+		return
+	}
+	var removed, added int
+	for _, s := range olds {
+		if !containsJSON(toSlice(news), s) {
+			removed++
+		}
+	}
+	for _, s := range news {
+		if !containsJSON(toSlice(olds), s) {
+			added++
+		}
+	}
+	if removed > 0 {
+		d.add(path, true, "%s: %d %s removed or changed", kw, removed, plural(removed, "alternative"))
+	}
+	if added > 0 {
+		d.add(path, false, "%s: %d %s added", kw, added, plural(added, "alternative"))
+	}
+}
+
+func containsJSON(list []interface{}, v interface{}) bool {
+	for _, x := range list {
+		if equalJSON(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,807 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxDecoderFields bounds the fields of the types a Decoder supports.
+const maxDecoderFields = 64
+
+// Decoder decodes tool arguments into a struct type T and checks them
+// against an input schema in a single pass over the JSON, without the
+// generic decoding of Validate and encoding/json. NewDecoder works out
+// once which field each property goes to; Decode then sets the fields
+// with the setters of package reflect.
+//
+// It supports flat structs: every field is a bool, a string, an integer,
+// a floating-point number, or a slice of one of those, and maps to a
+// property of the schema that constrains it with the keywords of scalars
+// and arrays only. NewDecoder reports other types and schemas.
+//
+// Decoding never guesses: arguments the decoder cannot accept with
+// certainty, because they do not match the schema or take a form it does
+// not handle, such as property names differing in case only, are
+// declined, and the caller falls back to Validate and encoding/json, which
+// report the problem.
+type Decoder[T any] struct {
+	fields []fastField
+	byName map[string]int
+	// required has bit i set when fields[i] is required.
+	required uint64
+	// closed is set when the schema forbids additional properties.
+	closed bool
+}
+
+// fastField decodes a field of the struct type.
+type fastField struct {
+	name string
+	// index is the index of the field in the struct.
+	index int
+	value fastScalar
+	// slice is set for slices of value, with the array keywords.
+	slice              bool
+	minItems, maxItems bound
+	uniqueItems        bool
+	// def is the JSON encoding of the property's default, or nil.
+	def []byte
+}
+
+// fastScalar decodes a scalar value and checks it against the keywords
+// of its schema.
+type fastScalar struct {
+	kind reflect.Kind
+	// enum lists the values allowed by enum or const, normalized as
+	// encoding/json decodes them into an interface{}; nil allows any.
+	enum                               []interface{}
+	minimum, maximum                   bound
+	exclusiveMinimum, exclusiveMaximum bound
+	multipleOf                         bound
+	minLength, maxLength               bound
+	pattern                            *regexp.Regexp
+}
+
+type bound struct {
+	v  float64
+	ok bool
+}
+
+func boundOf(s map[string]interface{}, keyword string) bound {
+	v, ok := number(s[keyword])
+	return bound{v, ok}
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Keywords a Decoder understands, besides the annotations, which have no
+// bearing on validation.
+var (
+	objectKeywords = keywordSet("type", "properties", "required", "additionalProperties", "$defs")
+	scalarKeywords = keywordSet("type", "enum", "const", "minimum", "maximum", "exclusiveMinimum",
+		"exclusiveMaximum", "multipleOf", "minLength", "maxLength", "pattern", "default")
+	arrayKeywords = keywordSet("type", "items", "minItems", "maxItems", "uniqueItems", "default")
+	annotations   = keywordSet("$schema", "$id", "title", "description", "examples", "format",
+		"deprecated", "readOnly", "writeOnly")
+)
+
+func keywordSet(keywords ...string) map[string]bool {
+	set := make(map[string]bool, len(keywords))
+	for _, k := range keywords {
+		set[k] = true
+	}
+	return set
+}
+
+// checkKeywords fails on the first keyword of s outside allowed and the
+// annotations.
+func checkKeywords(s map[string]interface{}, allowed map[string]bool) error {
+	for k := range s {
+		if !allowed[k] && !annotations[k] {
+			return fmt.Errorf("unsupported keyword %q", k)
+		}
+	}
+	return nil
+}
+
+// NewDecoder returns a decoder of arguments of type T checked against s.
+// It fails when T or s are outside what a Decoder supports.
+func NewDecoder[T any](s map[string]interface{}) (*Decoder[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: fast path: %s is not a struct", t)
+	}
+	if customDecoding(t) {
+		return nil, fmt.Errorf("schema: fast path: %s decodes itself", t)
+	}
+	if err := checkKeywords(s, objectKeywords); err != nil {
+		return nil, fmt.Errorf("schema: fast path: %w", err)
+	}
+	if typ, _ := s["type"].(string); typ != "object" {
+		return nil, errors.New(`schema: fast path: schema is not of type "object"`)
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	d := &Decoder[T]{byName: make(map[string]int)}
+	switch ap := s["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		d.closed = !ap
+	default:
+		return nil, errors.New("schema: fast path: additionalProperties is a schema")
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, skip := FieldName(f)
+		if skip {
+			continue
+		}
+		if f.Anonymous {
+			return nil, fmt.Errorf("schema: fast path: embedded field %s", f.Name)
+		}
+		if strings.Contains(opts, "string") {
+			return nil, fmt.Errorf("schema: fast path: field %s is encoded as a string", f.Name)
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, dup := d.byName[name]; dup {
+			return nil, fmt.Errorf("schema: fast path: several fields named %q", name)
+		}
+		prop, ok := props[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema: fast path: field %s has no property in the schema", f.Name)
+		}
+		field, err := compileField(f.Type, prop)
+		if err != nil {
+			return nil, fmt.Errorf("schema: fast path: field %s: %w", f.Name, err)
+		}
+		field.name, field.index = name, i
+		d.byName[name] = len(d.fields)
+		d.fields = append(d.fields, field)
+	}
+	if len(d.fields) != len(props) {
+		return nil, errors.New("schema: fast path: schema has properties without a field")
+	}
+	if len(d.fields) > maxDecoderFields {
+		return nil, fmt.Errorf("schema: fast path: more than %d fields", maxDecoderFields)
+	}
+	for _, name := range stringList(s["required"]) {
+		i, ok := d.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("schema: fast path: required property %q has no field", name)
+		}
+		d.required |= 1 << i
+	}
+	return d, nil
+}
+
+// customDecoding reports whether encoding/json decodes values of type t
+// with methods of their own.
+func customDecoding(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(unmarshalerType) || pt.Implements(unmarshalerType) ||
+		t.Implements(textUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+func compileField(t reflect.Type, prop map[string]interface{}) (fastField, error) {
+	var f fastField
+	if customDecoding(t) {
+		return f, fmt.Errorf("%s decodes itself", t)
+	}
+	if def, ok := prop["default"]; ok && def != nil {
+		data, err := json.Marshal(def)
+		if err != nil {
+			return f, fmt.Errorf("default: %w", err)
+		}
+		f.def = data
+	}
+	if t.Kind() != reflect.Slice {
+		var err error
+		f.value, err = compileScalar(t, prop)
+		return f, err
+	}
+	if err := checkKeywords(prop, arrayKeywords); err != nil {
+		return f, err
+	}
+	if typ, _ := prop["type"].(string); typ != "array" {
+		return f, fmt.Errorf("slice %s is not of type \"array\"", t)
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Uint8 || customDecoding(elem) {
+		return f, fmt.Errorf("unsupported slice %s", t)
+	}
+	items, ok := prop["items"].(map[string]interface{})
+	if !ok {
+		return f, errors.New("array without an items schema")
+	}
+	if _, ok := items["default"]; ok {
+		return f, errors.New("items with a default")
+	}
+	value, err := compileScalar(elem, items)
+	if err != nil {
+		return f, fmt.Errorf("items: %w", err)
+	}
+	f.slice, f.value = true, value
+	f.minItems, f.maxItems = boundOf(prop, "minItems"), boundOf(prop, "maxItems")
+	f.uniqueItems, _ = prop["uniqueItems"].(bool)
+	if f.uniqueItems && value.kind == reflect.Float32 {
+		// Validate compares the numbers the client sent, which float32
+		// items do not keep.
+		return f, errors.New("uniqueItems of float32 items")
+	}
+	return f, nil
+}
+
+// scalarTypes lists the schema types accepted for each kind. Integer
+// fields accept "number" too: the generic path rejects fractions when
+// decoding, and the fast path declines them.
+var scalarTypes = map[reflect.Kind][]string{
+	reflect.Bool:    {"boolean"},
+	reflect.String:  {"string"},
+	reflect.Int:     {"integer", "number"},
+	reflect.Int8:    {"integer", "number"},
+	reflect.Int16:   {"integer", "number"},
+	reflect.Int32:   {"integer", "number"},
+	reflect.Int64:   {"integer", "number"},
+	reflect.Uint:    {"integer", "number"},
+	reflect.Uint8:   {"integer", "number"},
+	reflect.Uint16:  {"integer", "number"},
+	reflect.Uint32:  {"integer", "number"},
+	reflect.Uint64:  {"integer", "number"},
+	reflect.Float32: {"number"},
+	reflect.Float64: {"number"},
+}
+
+func compileScalar(t reflect.Type, s map[string]interface{}) (fastScalar, error) {
+	c := fastScalar{kind: t.Kind()}
+	types, ok := scalarTypes[c.kind]
+	if !ok || customDecoding(t) {
+		return c, fmt.Errorf("unsupported type %s", t)
+	}
+	if err := checkKeywords(s, scalarKeywords); err != nil {
+		return c, err
+	}
+	typ, _ := s["type"].(string)
+	if !contains(types, typ) {
+		return c, fmt.Errorf("%s is not of type %q", t, typ)
+	}
+	if enum, ok := s["enum"]; ok {
+		for _, e := range toSlice(enum) {
+			c.enum = append(c.enum, normalize(e))
+		}
+		if c.enum == nil {
+			c.enum = []interface{}{}
+		}
+	}
+	if v, ok := s["const"]; ok {
+		if _, hasEnum := s["enum"]; hasEnum {
+			return c, errors.New("both enum and const")
+		}
+		c.enum = []interface{}{normalize(v)}
+	}
+	c.minimum, c.maximum = boundOf(s, "minimum"), boundOf(s, "maximum")
+	c.exclusiveMinimum, c.exclusiveMaximum = boundOf(s, "exclusiveMinimum"), boundOf(s, "exclusiveMaximum")
+	c.multipleOf = boundOf(s, "multipleOf")
+	c.minLength, c.maxLength = boundOf(s, "minLength"), boundOf(s, "maxLength")
+	if p, ok := s["pattern"].(string); ok {
+		// Validate ignores patterns it cannot compile, and so does the
+		// decoder.
+		c.pattern, _ = compilePattern(p)
+	}
+	return c, nil
+}
+
+// Decode decodes the JSON object data into v, filling in the defaults of
+// the properties it lacks, and reports whether data matches the schema. It
+// returns false, leaving v partly written, when data does not match or
+// takes a form the decoder does not handle; the caller then decodes data
+// the generic way into a fresh value.
+func (d *Decoder[T]) Decode(data []byte, v *T) bool {
+	rv := reflect.ValueOf(v).Elem()
+	sc := scanner{data: data}
+	if !sc.consume('{') {
+		return false
+	}
+	var seen uint64
+	if !sc.consume('}') {
+		for {
+			key, ok := sc.str()
+			if !ok || !sc.consume(':') {
+				return false
+			}
+			i, known := d.byName[key]
+			if !known {
+				if d.closed || d.folds(key) || !sc.skipValue() {
+					return false
+				}
+			} else {
+				if seen&(1<<i) != 0 {
+					return false
+				}
+				seen |= 1 << i
+				if sc.literal("null") {
+					// encoding/json leaves the field alone, and Validate
+					// accepts null for optional properties.
+					if d.required&(1<<i) != 0 {
+						return false
+					}
+				} else if !d.fields[i].decode(&sc, rv, true) {
+					return false
+				}
+			}
+			if sc.consume('}') {
+				break
+			}
+			if !sc.consume(',') {
+				return false
+			}
+		}
+	}
+	if sc.skipSpace(); sc.pos != len(sc.data) {
+		return false
+	}
+	if seen&d.required != d.required {
+		return false
+	}
+	for i := range d.fields {
+		f := &d.fields[i]
+		if seen&(1<<i) == 0 && f.def != nil {
+			// Defaults are not checked against the schema, as with
+			// ApplyDefaults.
+			if !f.decode(&scanner{data: f.def}, rv, false) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// folds reports whether key names a field in another case, which
+// encoding/json decodes into the field although Validate treats it as an
+// additional property.
+func (d *Decoder[T]) folds(key string) bool {
+	for i := range d.fields {
+		if strings.EqualFold(d.fields[i].name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// decode decodes the next value of sc into the field of the struct rv,
+// checking it against the schema when check is set.
+func (f *fastField) decode(sc *scanner, rv reflect.Value, check bool) bool {
+	fv := rv.Field(f.index)
+	if !f.slice {
+		return f.value.decode(sc, fv, check)
+	}
+	n, ok := f.decodeSlice(sc, fv, check)
+	if !ok || !check {
+		return ok
+	}
+	if f.minItems.ok && float64(n) < f.minItems.v || f.maxItems.ok && float64(n) > f.maxItems.v {
+		return false
+	}
+	return !f.uniqueItems || f.unique(fv)
+}
+
+// decodeSlice decodes a JSON array into the slice fv and returns its
+// length. The slice is set only once the whole array has decoded.
+func (f *fastField) decodeSlice(sc *scanner, fv reflect.Value, check bool) (int, bool) {
+	if !sc.consume('[') {
+		return 0, false
+	}
+	// encoding/json decodes an empty array into an empty, non-nil slice.
+	out := reflect.MakeSlice(fv.Type(), 0, 4)
+	if !sc.consume(']') {
+		for i := 0; ; i++ {
+			out = reflect.Append(out, reflect.Zero(out.Type().Elem()))
+			if !f.value.decode(sc, out.Index(i), check) {
+				return 0, false
+			}
+			if sc.consume(']') {
+				break
+			}
+			if !sc.consume(',') {
+				return 0, false
+			}
+		}
+	}
+	fv.Set(out)
+	return out.Len(), true
+}
+
+// unique reports whether the items of the slice fv differ, comparing
+// numbers by value as Validate does.
+func (f *fastField) unique(fv reflect.Value) bool {
+	items := make([]interface{}, fv.Len())
+	for i := range items {
+		items[i] = f.value.item(fv.Index(i))
+	}
+	for i := range items {
+		for j := 0; j < i; j++ {
+			if items[i] == items[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// item returns the slice element v as Validate compares it: numbers as
+// float64.
+func (c *fastScalar) item(v reflect.Value) interface{} {
+	switch c.kind {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// decode decodes the next value of sc into v, of the kind of c, checking
+// it when check is set.
+func (c *fastScalar) decode(sc *scanner, v reflect.Value, check bool) bool {
+	switch c.kind {
+	case reflect.Bool:
+		var b bool
+		switch {
+		case sc.literal("true"):
+			b = true
+		case sc.literal("false"):
+		default:
+			return false
+		}
+		if check && !c.allows(b) {
+			return false
+		}
+		v.SetBool(b)
+		return true
+	case reflect.String:
+		s, ok := sc.str()
+		if !ok || check && !c.checkString(s) {
+			return false
+		}
+		v.SetString(s)
+		return true
+	}
+	lit, integral, ok := sc.number()
+	if !ok || check && !c.checkNumber(lit) {
+		return false
+	}
+	switch c.kind {
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(lit, v.Type().Bits())
+		if err != nil {
+			return false
+		}
+		v.SetFloat(f)
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !integral {
+			return false
+		}
+		n, err := strconv.ParseInt(lit, 10, intBits(c.kind))
+		if err != nil {
+			return false
+		}
+		v.SetInt(n)
+		return true
+	default:
+		if !integral {
+			return false
+		}
+		n, err := strconv.ParseUint(lit, 10, intBits(c.kind))
+		if err != nil {
+			return false
+		}
+		v.SetUint(n)
+		return true
+	}
+}
+
+func intBits(k reflect.Kind) int {
+	switch k {
+	case reflect.Int8, reflect.Uint8:
+		return 8
+	case reflect.Int16, reflect.Uint16:
+		return 16
+	case reflect.Int32, reflect.Uint32:
+		return 32
+	case reflect.Int, reflect.Uint:
+		return strconv.IntSize
+	}
+	return 64
+}
+
+// allows reports whether v, normalized, is among the values of enum.
+func (c *fastScalar) allows(v interface{}) bool {
+	if c.enum == nil {
+		return true
+	}
+	for _, e := range c.enum {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fastScalar) checkString(s string) bool {
+	if !c.allows(s) {
+		return false
+	}
+	if c.minLength.ok || c.maxLength.ok {
+		n := float64(utf8.RuneCountInString(s))
+		if c.minLength.ok && n < c.minLength.v || c.maxLength.ok && n > c.maxLength.v {
+			return false
+		}
+	}
+	return c.pattern == nil || c.pattern.MatchString(s)
+}
+
+// checkNumber checks the number literal lit with the comparisons of
+// Validate, which works on its float64 value.
+func (c *fastScalar) checkNumber(lit string) bool {
+	f, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return false
+	}
+	switch {
+	case !c.allows(f),
+		c.minimum.ok && f < c.minimum.v,
+		c.maximum.ok && f > c.maximum.v,
+		c.exclusiveMinimum.ok && f <= c.exclusiveMinimum.v,
+		c.exclusiveMaximum.ok && f >= c.exclusiveMaximum.v:
+		return false
+	}
+	if m := c.multipleOf; m.ok && m.v > 0 {
+		if q := f / m.v; math.Abs(q-math.Round(q)) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// scanner reads JSON tokens from data for a Decoder. Its methods skip the
+// white space before the token and report false, without consuming
+// anything of use, when the next token is not the one asked for.
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+func (sc *scanner) skipSpace() {
+	for sc.pos < len(sc.data) {
+		switch sc.data[sc.pos] {
+		case ' ', '\t', '\n', '\r':
+			sc.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume consumes the delimiter c.
+func (sc *scanner) consume(c byte) bool {
+	sc.skipSpace()
+	if sc.pos < len(sc.data) && sc.data[sc.pos] == c {
+		sc.pos++
+		return true
+	}
+	return false
+}
+
+// literal consumes the literal lit, such as true or null.
+func (sc *scanner) literal(lit string) bool {
+	sc.skipSpace()
+	end := sc.pos + len(lit)
+	if end > len(sc.data) || string(sc.data[sc.pos:end]) != lit {
+		return false
+	}
+	if end < len(sc.data) && isLetter(sc.data[end]) {
+		return false
+	}
+	sc.pos = end
+	return true
+}
+
+func isLetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+// number consumes a number and returns its literal, reporting whether it
+// is written as an integer, without fraction or exponent.
+func (sc *scanner) number() (lit string, integral, ok bool) {
+	sc.skipSpace()
+	start, i := sc.pos, sc.pos
+	digits := func() int {
+		n := 0
+		for i < len(sc.data) && '0' <= sc.data[i] && sc.data[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+	if i < len(sc.data) && sc.data[i] == '-' {
+		i++
+	}
+	switch n := digits(); {
+	case n == 0, n > 1 && sc.data[i-n] == '0':
+		return "", false, false
+	}
+	integral = true
+	if i < len(sc.data) && sc.data[i] == '.' {
+		i++
+		if digits() == 0 {
+			return "", false, false
+		}
+		integral = false
+	}
+	if i < len(sc.data) && (sc.data[i] == 'e' || sc.data[i] == 'E') {
+		i++
+		if i < len(sc.data) && (sc.data[i] == '+' || sc.data[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return "", false, false
+		}
+		integral = false
+	}
+	sc.pos = i
+	return string(sc.data[start:i]), integral, true
+}
+
+// str consumes a string and returns its value.
+func (sc *scanner) str() (string, bool) {
+	sc.skipSpace()
+	if sc.pos >= len(sc.data) || sc.data[sc.pos] != '"' {
+		return "", false
+	}
+	start := sc.pos + 1
+	escaped, ascii := false, true
+	i := start
+	for ; i < len(sc.data); i++ {
+		c := sc.data[i]
+		switch {
+		case c == '"':
+			raw := sc.data[start:i]
+			if !ascii && !utf8.Valid(raw) {
+				// encoding/json replaces invalid bytes; leave that to it.
+				return "", false
+			}
+			sc.pos = i + 1
+			if escaped {
+				return unescape(raw)
+			}
+			return string(raw), true
+		case c == '\\':
+			escaped = true
+			i++
+		case c < 0x20:
+			return "", false
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return "", false
+}
+
+// unescape returns the value of the contents raw of a JSON string holding
+// escape sequences. Lone surrogates become U+FFFD, as with encoding/json.
+func unescape(raw []byte) (string, bool) {
+	var b strings.Builder
+	b.Grow(len(raw))
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(raw) {
+			return "", false
+		}
+		switch raw[i] {
+		case '"', '\\', '/':
+			b.WriteByte(raw[i])
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, ok := hex4(raw[i+1:])
+			if !ok {
+				return "", false
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				r2, ok := rune(-1), false
+				if i+2 < len(raw) && raw[i+1] == '\\' && raw[i+2] == 'u' {
+					r2, ok = hex4(raw[i+3:])
+				}
+				if dec := utf16.DecodeRune(r, r2); ok && dec != utf8.RuneError {
+					r = dec
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			b.WriteRune(r)
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(string(b[:4]), 16, 32)
+	return rune(n), err == nil
+}
+
+// skipValue consumes a value of any type, which must be valid JSON.
+func (sc *scanner) skipValue() bool {
+	sc.skipSpace()
+	start, depth := sc.pos, 0
+	i := start
+scan:
+	for ; i < len(sc.data); i++ {
+		switch sc.data[i] {
+		case '"':
+			for i++; i < len(sc.data) && sc.data[i] != '"'; i++ {
+				if sc.data[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				i++
+				break scan
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				// The end of the enclosing object.
+				break scan
+			}
+			if depth--; depth == 0 {
+				i++
+				break scan
+			}
+		case ',':
+			if depth == 0 {
+				break scan
+			}
+		}
+	}
+	if i == start || i > len(sc.data) || !json.Valid(sc.data[start:i]) {
+		return false
+	}
+	sc.pos = i
+	return true
+}
@@ -0,0 +1,235 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/schema"
+)
+
+type level string
+
+type searchRequest struct {
+	Query   string    `json:"query"`
+	Limit   int       `json:"limit" default:"10"`
+	Offset  uint16    `json:"offset,omitempty"`
+	Score   float64   `json:"score,omitempty"`
+	Ratio   float32   `json:"ratio,omitempty"`
+	Exact   bool      `json:"exact,omitempty"`
+	Level   level     `json:"level" default:"info"`
+	Tags    []string  `json:"tags,omitempty"`
+	Weights []float64 `json:"weights,omitempty"`
+	IDs     []int64   `json:"ids,omitempty"`
+	Levels  []level   `json:"levels,omitempty"`
+	Shards  []uint    `json:"shards,omitempty"`
+	Cursor  int8      `json:"cursor,omitempty"`
+}
+
+// searchSchema is the schema generated for searchRequest, tightened with
+// the keywords a Decoder checks.
+func searchSchema(t testing.TB) map[string]interface{} {
+	t.Helper()
+	s, err := schema.For[searchRequest]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := s["properties"].(map[string]interface{})
+	set := func(prop string, kv ...interface{}) {
+		p := props[prop].(map[string]interface{})
+		for i := 0; i < len(kv); i += 2 {
+			p[kv[i].(string)] = kv[i+1]
+		}
+	}
+	set("query", "minLength", 1, "maxLength", 64, "pattern", "^[^!]*$")
+	set("limit", "minimum", 1, "maximum", 100)
+	set("score", "exclusiveMinimum", 0, "exclusiveMaximum", 1)
+	set("ratio", "multipleOf", 0.25)
+	set("level", "enum", []interface{}{"debug", "info", "warn"})
+	set("tags", "maxItems", 4, "uniqueItems", true)
+	set("weights", "minItems", 1)
+	set("ids", "uniqueItems", true)
+	props["levels"].(map[string]interface{})["items"].(map[string]interface{})["enum"] = []interface{}{"debug", "info"}
+	set("cursor", "const", 3)
+	s["additionalProperties"] = false
+	return s
+}
+
+// decodeGeneric decodes data as tools without a fast path do: Validate,
+// then ApplyDefaults and encoding/json.
+func decodeGeneric(s map[string]interface{}, data []byte) (searchRequest, bool) {
+	var v searchRequest
+	if schema.Validate(data, s) != nil {
+		return v, false
+	}
+	filled, err := schema.ApplyDefaults(s, data)
+	if err != nil {
+		return v, false
+	}
+	if json.Unmarshal(filled, &v) != nil {
+		return v, false
+	}
+	return v, true
+}
+
+// accepted are arguments the fast path must decode itself.
+var accepted = []string{
+	`{"query":"go"}`,
+	`{"query":"go","limit":5,"offset":65535,"score":0.5,"ratio":0.75,"exact":true}`,
+	` { "query" : "café \"quoted\" 😀" , "level" : "warn" } `,
+	`{"query":"x","tags":["a","b"],"weights":[1,2.5e-3,-0],"ids":[1,-2,9007199254740993]}`,
+	`{"query":"x","levels":["debug"],"shards":[0,18446744073709551615],"cursor":3}`,
+	`{"query":"x","tags":[],"offset":null,"score":null}`,
+	`{"query":"x","limit":null}`,
+	`{"query":"x\u0000\ud800\ud83d\ude00\/"}`,
+}
+
+// declined are arguments the fast path hands to the generic path, valid
+// or not.
+var declined = []string{
+	``,
+	`null`,
+	`[]`,
+	`{"query":""}`,
+	`{"query":"a!"}`,
+	`{"limit":5}`,
+	`{"query":"x","limit":0}`,
+	`{"query":"x","limit":1.5}`,
+	`{"query":"x","limit":1e1}`,
+	`{"query":"x","offset":65536}`,
+	`{"query":"x","offset":-1}`,
+	`{"query":"x","score":1}`,
+	`{"query":"x","ratio":0.3}`,
+	`{"query":"x","level":"error"}`,
+	`{"query":"x","tags":["a","a"]}`,
+	`{"query":"x","tags":["a","b","c","d","e"]}`,
+	`{"query":"x","weights":[]}`,
+	`{"query":"x","ids":[1,1.0]}`,
+	`{"query":"x","levels":["warn"]}`,
+	`{"query":"x","cursor":4}`,
+	`{"query":"x","other":1}`,
+	`{"Query":"x"}`,
+	`{"query":"x","query":"y"}`,
+	`{"query":"x"} {}`,
+	`{"query":"x",}`,
+	"{\"query\":\"\xff\"}",
+	`{"query":"x","exact":tru}`,
+	`{"query":"x","limit":01}`,
+}
+
+func TestDecoderAccepts(t *testing.T) {
+	s := searchSchema(t)
+	dec, err := schema.NewDecoder[searchRequest](s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range accepted {
+		var got searchRequest
+		if !dec.Decode([]byte(data), &got) {
+			t.Errorf("Decode(%s) declined", data)
+			continue
+		}
+		want, ok := decodeGeneric(s, []byte(data))
+		if !ok {
+			t.Errorf("generic path rejects %s", data)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%s) = %+v, want %+v", data, got, want)
+		}
+	}
+	for _, data := range declined {
+		var got searchRequest
+		if dec.Decode([]byte(data), &got) {
+			t.Errorf("Decode(%s) accepted it", data)
+		}
+	}
+}
+
+func TestNewDecoderUnsupported(t *testing.T) {
+	type nested struct {
+		Inner struct {
+			A int `json:"a"`
+		} `json:"inner"`
+	}
+	type pointer struct {
+		A *int `json:"a"`
+	}
+	type raw struct {
+		A json.RawMessage `json:"a"`
+	}
+	type bytes struct {
+		A []byte `json:"a"`
+	}
+	type quoted struct {
+		A int `json:"a,string"`
+	}
+	tests := []struct {
+		name string
+		new  func() error
+	}{
+		{"nested struct", func() error { _, err := schema.NewDecoder[nested](mustSchema[nested](t)); return err }},
+		{"pointer", func() error { _, err := schema.NewDecoder[pointer](mustSchema[pointer](t)); return err }},
+		{"json.RawMessage", func() error { _, err := schema.NewDecoder[raw](mustSchema[raw](t)); return err }},
+		{"[]byte", func() error { _, err := schema.NewDecoder[bytes](mustSchema[bytes](t)); return err }},
+		{",string", func() error { _, err := schema.NewDecoder[quoted](mustSchema[quoted](t)); return err }},
+		{"not a struct", func() error { _, err := schema.NewDecoder[[]int](map[string]interface{}{"type": "array"}); return err }},
+		{"unknown keyword", func() error {
+			s := searchSchema(t)
+			s["properties"].(map[string]interface{})["query"].(map[string]interface{})["format"] = "uri"
+			s["properties"].(map[string]interface{})["query"].(map[string]interface{})["contentEncoding"] = "base64"
+			_, err := schema.NewDecoder[searchRequest](s)
+			return err
+		}},
+		{"property without a field", func() error {
+			s := searchSchema(t)
+			s["properties"].(map[string]interface{})["extra"] = map[string]interface{}{"type": "string"}
+			_, err := schema.NewDecoder[searchRequest](s)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		if err := tt.new(); err == nil {
+			t.Errorf("%s: NewDecoder succeeded", tt.name)
+		}
+	}
+}
+
+func mustSchema[T any](t testing.TB) map[string]interface{} {
+	t.Helper()
+	s, err := schema.For[T]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// FuzzDecode checks the fast path against the generic one: whatever the
+// input, the fast path either declines it or decodes exactly the value
+// the generic path decodes after Validate accepted it.
+func FuzzDecode(f *testing.F) {
+	for _, data := range accepted {
+		f.Add([]byte(data))
+	}
+	for _, data := range declined {
+		f.Add([]byte(data))
+	}
+	s := searchSchema(f)
+	dec, err := schema.NewDecoder[searchRequest](s)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var got searchRequest
+		if !dec.Decode(data, &got) {
+			return
+		}
+		want, ok := decodeGeneric(s, data)
+		if !ok {
+			t.Fatalf("fast path accepted %q, which the generic path rejects", data)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("fast path decoded %q as %+v, generic path as %+v", data, got, want)
+		}
+	})
+}
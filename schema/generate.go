@@ -0,0 +1,210 @@
+// Package schema derives JSON Schemas from Go types for typed tool
+// arguments.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeKeywords are the schema keywords that may be set from struct tags.
+var sizeKeywords = []string{"maxItems", "maxLength", "maxProperties"}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// For returns the JSON Schema describing T.
+func For[T any]() (map[string]interface{}, error) {
+	return Generate(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Generate returns the JSON Schema describing t.
+//
+// Struct fields are named after their json tag and are required unless the
+// tag contains omitempty, the field is a pointer or it has a default. A
+// `description` struct tag becomes the property description, and a
+// `default` tag the property default. Defaults are written as JSON, except
+// that string values may omit the quotes. maxItems, maxLength and
+// maxProperties tags set the keywords of the same name. Union fields become a oneOf over
+// their registered variants.
+//
+// Types implementing JSONSchemer or registered with RegisterSchema are
+// described by that schema instead, and a `schema` struct tag holding a
+// JSON object replaces the schema of a single field. The description,
+// default and size tags still apply on top of either.
+//
+// A named struct or union type used more than once is defined once under
+// $defs and referenced with $ref everywhere it appears. Recursive types,
+// such as linked lists and trees, are defined the same way, and a type
+// containing itself refers to the root with "#".
+func Generate(t reflect.Type) (map[string]interface{}, error) {
+	return GenerateWith(t, Options{})
+}
+
+// generate returns the schema of t where it is used: a reference when t is
+// defined in $defs or is the recursive root, its full schema otherwise.
+func (g *generator) generate(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name, ok := g.names[t]; ok {
+		return map[string]interface{}{"$ref": defsRef + name}, nil
+	}
+	if t == g.root {
+		return map[string]interface{}{"$ref": "#"}, nil
+	}
+	return g.body(t)
+}
+
+// body returns the full schema of t.
+func (g *generator) body(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if s, ok := override(t); ok {
+		return s, nil
+	}
+	switch {
+	case t.Implements(unionTypeType):
+		return g.generateUnion(reflect.Zero(t).Interface().(unionType).unionInterface())
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case t == rawMessageType:
+		return map[string]interface{}{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := g.generate(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("schema: unsupported map key type %s", t.Key())
+		}
+		values, err := g.generate(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.generateStruct(t)
+	default:
+		return nil, fmt.Errorf("schema: unsupported type %s", t)
+	}
+}
+
+func (g *generator) generateStruct(t reflect.Type) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	var required []string
+	if err := g.collectFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
+
+// collectFields adds the properties of t to properties, flattening
+// embedded structs the way encoding/json does.
+func (g *generator) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, skip := FieldName(f)
+		if skip {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.collectFields(ft, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop, ok, err := fieldOverride(f)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if !ok {
+			prop, err = g.generate(f.Type)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", f.Name, err)
+			}
+		}
+		if desc := f.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		for _, keyword := range sizeKeywords {
+			if tag, ok := f.Tag.Lookup(keyword); ok {
+				n, err := strconv.Atoi(tag)
+				if err != nil || n < 0 {
+					return fmt.Errorf("field %s: schema: invalid %s %q", f.Name, keyword, tag)
+				}
+				prop[keyword] = n
+			}
+		}
+		if tag, ok := f.Tag.Lookup("default"); ok {
+			def, err := parseDefault(f.Type, tag)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			prop["default"] = def
+		}
+		properties[name] = prop
+		_, hasDefault := prop["default"]
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer && !hasDefault {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// FieldName returns the JSON name and tag options of a struct field, and
+// whether encoding/json ignores it. An empty name means the tag did not set
+// one.
+func FieldName(f reflect.StructField) (name, opts string, skip bool) {
+	if !f.IsExported() && !f.Anonymous {
+		return "", "", true
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", "", true
+	}
+	name, opts, _ = strings.Cut(tag, ",")
+	return name, opts, false
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Limits bounds the shape of incoming arguments. A zero field selects the
+// default from DefaultLimits; a negative field disables that limit.
+//
+// maxItems, maxLength and maxProperties keywords in the schema take
+// precedence over MaxItems, MaxStringLength and MaxProperties for the
+// values they describe.
+type Limits struct {
+	// MaxDepth bounds the nesting of arrays and objects.
+	MaxDepth int
+	// MaxItems bounds the length of every array.
+	MaxItems int
+	// MaxProperties bounds the number of members of every object.
+	MaxProperties int
+	// MaxStringLength bounds the length of every string, in characters.
+	MaxStringLength int
+}
+
+// DefaultLimits are generous enough for any reasonable tool call while
+// keeping pathological inputs away from handlers.
+var DefaultLimits = Limits{
+	MaxDepth:        32,
+	MaxItems:        10000,
+	MaxProperties:   1000,
+	MaxStringLength: 1 << 20,
+}
+
+func (l Limits) withDefaults() Limits {
+	pick := func(v, def int) int {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	return Limits{
+		MaxDepth:        pick(l.MaxDepth, DefaultLimits.MaxDepth),
+		MaxItems:        pick(l.MaxItems, DefaultLimits.MaxItems),
+		MaxProperties:   pick(l.MaxProperties, DefaultLimits.MaxProperties),
+		MaxStringLength: pick(l.MaxStringLength, DefaultLimits.MaxStringLength),
+	}
+}
+
+// LimitError reports a value that exceeds a limit.
+type LimitError struct {
+	// Path is the JSON path of the offending value, such as "items[3]".
+	Path string `json:"path"`
+	// Limit names the exceeded limit: maxDepth, maxItems, maxProperties or
+	// maxLength.
+	Limit string `json:"limit"`
+	Max   int    `json:"max"`
+}
+
+func (e *LimitError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "arguments"
+	}
+	return fmt.Sprintf("%s exceeds %s of %d", path, e.Limit, e.Max)
+}
+
+// frame is an array or object being scanned by CheckLimits.
+type frame struct {
+	schema    map[string]interface{}
+	path      string
+	object    bool
+	n         int
+	expectKey bool
+	// value schema and path of the member whose key was just read
+	nextSchema map[string]interface{}
+	nextPath   string
+}
+
+// CheckLimits scans the JSON value raw token by token, without building it
+// in memory, and returns a *LimitError for the first value exceeding l or
+// the size keywords of s. s may be nil. References into the $defs of s are
+// followed. Malformed JSON is reported as is.
+func CheckLimits(raw json.RawMessage, s map[string]interface{}, l Limits) error {
+	l = l.withDefaults()
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var stack []*frame
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		// Object keys select the schema of the member that follows.
+		if top != nil && top.object && top.expectKey {
+			key := tok.(string)
+			top.n++
+			if max := limitFor(top.schema, "maxProperties", l.MaxProperties); max >= 0 && top.n > max {
+				return &LimitError{Path: top.path, Limit: "maxProperties", Max: max}
+			}
+			top.nextSchema = subschema(top.schema, "properties", key)
+			if top.nextSchema == nil {
+				top.nextSchema, _ = top.schema["additionalProperties"].(map[string]interface{})
+			}
+			top.nextPath = joinPath(top.path, key)
+			top.expectKey = false
+			continue
+		}
+
+		// Everything else is a value; find its schema and path.
+		vs, path := s, ""
+		switch {
+		case top == nil:
+		case top.object:
+			vs, path = top.nextSchema, top.nextPath
+			top.expectKey = true
+		default:
+			top.n++
+			if max := limitFor(top.schema, "maxItems", l.MaxItems); max >= 0 && top.n > max {
+				return &LimitError{Path: top.path, Limit: "maxItems", Max: max}
+			}
+			vs, _ = top.schema["items"].(map[string]interface{})
+			path = top.path + "[" + strconv.Itoa(top.n-1) + "]"
+		}
+		vs = deref(s, vs)
+
+		switch v := tok.(type) {
+		case json.Delim:
+			if l.MaxDepth >= 0 && len(stack) >= l.MaxDepth {
+				return &LimitError{Path: path, Limit: "maxDepth", Max: l.MaxDepth}
+			}
+			stack = append(stack, &frame{schema: vs, path: path, object: v == '{', expectKey: v == '{'})
+		case string:
+			if max := limitFor(vs, "maxLength", l.MaxStringLength); max >= 0 && utf8.RuneCountInString(v) > max {
+				return &LimitError{Path: path, Limit: "maxLength", Max: max}
+			}
+		}
+	}
+}
+
+// limitFor returns the integer keyword of s, or def when s does not set it.
+func limitFor(s map[string]interface{}, keyword string, def int) int {
+	switch v := s[keyword].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+	}
+	return def
+}
+
+func subschema(s map[string]interface{}, keyword, name string) map[string]interface{} {
+	props, _ := s[keyword].(map[string]interface{})
+	sub, _ := props[name].(map[string]interface{})
+	return sub
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
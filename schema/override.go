@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// JSONSchemer is implemented by types that describe their own schema, such
+// as UUIDs, decimals or geographic points whose Go representation says
+// little about their encoding.
+//
+//	type UUID [16]byte
+//
+//	func (UUID) JSONSchema() map[string]interface{} {
+//		return map[string]interface{}{"type": "string", "format": "uuid"}
+//	}
+type JSONSchemer interface {
+	JSONSchema() map[string]interface{}
+}
+
+var jsonSchemerType = reflect.TypeOf((*JSONSchemer)(nil)).Elem()
+
+var (
+	overridesMu sync.RWMutex
+	overrides   = make(map[reflect.Type]map[string]interface{})
+)
+
+// RegisterSchema makes the generator describe T with s, for types that
+// cannot implement JSONSchemer, such as those of other packages. It takes
+// precedence over a JSONSchema method. It is meant to be called from init
+// and panics if s is nil.
+func RegisterSchema[T any](s map[string]interface{}) {
+	if s == nil {
+		panic("schema: RegisterSchema with nil schema")
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides[t] = clone(s).(map[string]interface{})
+}
+
+// override returns the schema registered for t or returned by its
+// JSONSchema method, if any. The result is a copy the caller may modify.
+func override(t reflect.Type) (map[string]interface{}, bool) {
+	overridesMu.RLock()
+	s, ok := overrides[t]
+	overridesMu.RUnlock()
+	switch {
+	case ok:
+	case t.Implements(jsonSchemerType):
+		s = reflect.Zero(t).Interface().(JSONSchemer).JSONSchema()
+	case reflect.PointerTo(t).Implements(jsonSchemerType):
+		s = reflect.New(t).Interface().(JSONSchemer).JSONSchema()
+	default:
+		return nil, false
+	}
+	if s == nil {
+		return nil, false
+	}
+	return clone(s).(map[string]interface{}), true
+}
+
+// hasOverride reports whether override describes t.
+func hasOverride(t reflect.Type) bool {
+	overridesMu.RLock()
+	_, ok := overrides[t]
+	overridesMu.RUnlock()
+	return ok || t.Implements(jsonSchemerType) || reflect.PointerTo(t).Implements(jsonSchemerType)
+}
+
+// fieldOverride parses the schema tag of f, a JSON object that replaces
+// the schema generated for the field's type.
+func fieldOverride(f reflect.StructField) (map[string]interface{}, bool, error) {
+	tag, ok := f.Tag.Lookup("schema")
+	if !ok {
+		return nil, false, nil
+	}
+	var s map[string]interface{}
+	if err := json.Unmarshal([]byte(tag), &s); err != nil || s == nil {
+		return nil, false, fmt.Errorf("schema: invalid schema tag %q: want a JSON object", tag)
+	}
+	return s, true, nil
+}
+
+// clone returns a deep copy of a schema value, so that overrides handed
+// out by the generator can be annotated without changing the original.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[k] = clone(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = clone(e)
+		}
+		return c
+	case []string:
+		return append([]string(nil), v...)
+	}
+	return v
+}
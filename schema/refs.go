@@ -0,0 +1,286 @@
+package schema
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defsRef prefixes references to the definitions of the root schema.
+const defsRef = "#/$defs/"
+
+// Options tunes schema generation.
+type Options struct {
+	// DefineAll places every named struct type below the root in $defs,
+	// not only those used more than once. Schemas of different tools then
+	// define the types they have in common identically, which lets
+	// ShareDefs hoist the definitions out of a tools/list response.
+	DefineAll bool
+}
+
+// ForWith returns the JSON Schema describing T, generated with opts.
+func ForWith[T any](opts Options) (map[string]interface{}, error) {
+	return GenerateWith(reflect.TypeOf((*T)(nil)).Elem(), opts)
+}
+
+// GenerateWith returns the JSON Schema describing t, generated with opts.
+// See Generate for how types map to schemas.
+func GenerateWith(t reflect.Type, opts Options) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	c := &useCounter{
+		uses:      make(map[reflect.Type]int),
+		recursive: make(map[reflect.Type]bool),
+		visiting:  make(map[reflect.Type]bool),
+	}
+	c.visit(t)
+
+	var defined []reflect.Type
+	for typ, n := range c.uses {
+		if typ == t {
+			continue
+		}
+		shareable := typ.Kind() == reflect.Struct && (n > 1 || opts.DefineAll && !typ.Implements(unionTypeType))
+		if shareable || c.recursive[typ] {
+			defined = append(defined, typ)
+		}
+	}
+	// Sort so that colliding type names are resolved the same way every
+	// time.
+	sort.Slice(defined, func(i, j int) bool { return defined[i].String() < defined[j].String() })
+	g := &generator{root: t, names: make(map[reflect.Type]string)}
+	taken := make(map[string]bool)
+	for _, typ := range defined {
+		g.names[typ] = defName(typ, taken)
+	}
+
+	root, err := g.body(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(defined) > 0 {
+		defs := make(map[string]interface{}, len(defined))
+		for _, typ := range defined {
+			s, err := g.body(typ)
+			if err != nil {
+				return nil, err
+			}
+			defs[g.names[typ]] = s
+		}
+		root["$defs"] = defs
+	}
+	return root, nil
+}
+
+// generator holds the state of one schema generation.
+type generator struct {
+	// root is the type whose schema is generated; a type containing
+	// itself refers back to it with "#".
+	root reflect.Type
+	// names maps the types defined in $defs to their definition names.
+	names map[reflect.Type]string
+}
+
+// useCounter counts how often each named composite type is referenced
+// from the root and finds the ones that contain themselves.
+type useCounter struct {
+	uses      map[reflect.Type]int
+	recursive map[reflect.Type]bool
+	// visiting holds the named types on the path from the root to the
+	// type being visited.
+	visiting map[reflect.Type]bool
+}
+
+func (c *useCounter) visit(t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || t == rawMessageType || hasOverride(t) {
+		return
+	}
+	union := t.Implements(unionTypeType)
+	if t.Name() != "" && (union || isComposite(t.Kind())) {
+		if c.visiting[t] {
+			c.recursive[t] = true
+			return
+		}
+		c.uses[t]++
+		if c.uses[t] > 1 {
+			return
+		}
+		c.visiting[t] = true
+		defer delete(c.visiting, t)
+	}
+	if union {
+		spec, err := lookupUnion(reflect.Zero(t).Interface().(unionType).unionInterface())
+		if err != nil {
+			return
+		}
+		// Variants are inlined into the union and are not counted
+		// themselves, but a variant containing itself is recursive.
+		for _, name := range spec.names {
+			vt := spec.byName[name]
+			for vt.Kind() == reflect.Pointer {
+				vt = vt.Elem()
+			}
+			if vt.Kind() != reflect.Struct || c.visiting[vt] {
+				continue
+			}
+			c.visiting[vt] = true
+			c.fields(vt)
+			delete(c.visiting, vt)
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			c.visit(t.Elem())
+		}
+	case reflect.Map:
+		c.visit(t.Elem())
+	case reflect.Struct:
+		c.fields(t)
+	}
+}
+
+// fields visits the types referenced by the fields of struct t, looking
+// through embedded structs the way collectFields does.
+func (c *useCounter) fields(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, skip := FieldName(f)
+		if skip {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			c.fields(ft)
+			continue
+		}
+		if _, ok := f.Tag.Lookup("schema"); ok {
+			continue
+		}
+		c.visit(f.Type)
+	}
+}
+
+func isComposite(k reflect.Kind) bool {
+	return k == reflect.Struct || k == reflect.Slice || k == reflect.Array || k == reflect.Map
+}
+
+// defName returns an unused definition name for t: its type name, or its
+// package-qualified name when another type took that already.
+func defName(t reflect.Type, taken map[string]bool) string {
+	if t.Implements(unionTypeType) {
+		// Name unions after their interface rather than Union[...].
+		t = reflect.Zero(t).Interface().(unionType).unionInterface()
+	}
+	name := sanitizeDefName(t.Name())
+	if taken[name] {
+		name = sanitizeDefName(path.Base(t.PkgPath()) + "." + t.Name())
+	}
+	for base, i := name, 2; taken[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	taken[name] = true
+	return name
+}
+
+// sanitizeDefName replaces the characters of generic instantiations and
+// package paths that would need escaping in a JSON pointer.
+func sanitizeDefName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// deref returns the definition s refers to when it is a $ref into the
+// $defs of root or to root itself, and s itself otherwise.
+func deref(root, s map[string]interface{}) map[string]interface{} {
+	// Definitions never consist of a bare reference, but a bound keeps
+	// hand-written schemas from looping.
+	for i := 0; i < 8 && s != nil; i++ {
+		ref, ok := s["$ref"].(string)
+		if !ok {
+			return s
+		}
+		if ref == "#" {
+			return root
+		}
+		name, ok := strings.CutPrefix(ref, defsRef)
+		if !ok {
+			return s
+		}
+		defs, _ := root["$defs"].(map[string]interface{})
+		s, _ = defs[name].(map[string]interface{})
+	}
+	return s
+}
+
+// ShareDefs hoists the definitions that schemas have in common out of
+// their $defs. A definition is shared when every schema defining it under
+// its name defines it identically; conflicting definitions stay local. It
+// returns the shared definitions and copies of schemas without them.
+// Consumers must add the shared definitions back to a schema's $defs
+// before resolving its references.
+func ShareDefs(schemas []map[string]interface{}) (map[string]interface{}, []map[string]interface{}) {
+	encoded := make(map[string]string)
+	conflict := make(map[string]bool)
+	shared := make(map[string]interface{})
+	for _, s := range schemas {
+		defs, _ := s["$defs"].(map[string]interface{})
+		for name, def := range defs {
+			data, err := json.Marshal(def)
+			if err != nil {
+				conflict[name] = true
+				continue
+			}
+			if prev, ok := encoded[name]; ok && prev != string(data) {
+				conflict[name] = true
+			}
+			encoded[name] = string(data)
+			shared[name] = def
+		}
+	}
+	for name := range conflict {
+		delete(shared, name)
+	}
+
+	out := make([]map[string]interface{}, len(schemas))
+	for i, s := range schemas {
+		defs, ok := s["$defs"].(map[string]interface{})
+		if !ok {
+			out[i] = s
+			continue
+		}
+		c := make(map[string]interface{}, len(s))
+		for k, v := range s {
+			c[k] = v
+		}
+		local := make(map[string]interface{})
+		for name, def := range defs {
+			if _, ok := shared[name]; !ok {
+				local[name] = def
+			}
+		}
+		if len(local) > 0 {
+			c["$defs"] = local
+		} else {
+			delete(c, "$defs")
+		}
+		out[i] = c
+	}
+	return shared, out
+}
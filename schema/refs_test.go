@@ -0,0 +1,293 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/schema"
+)
+
+// listNode is a linked list: the root refers to itself through a pointer.
+type listNode struct {
+	Value int       `json:"value"`
+	Next  *listNode `json:"next,omitempty"`
+}
+
+// treeNode is a tree: the root refers to itself through a slice.
+type treeNode struct {
+	Name     string     `json:"name"`
+	Weight   int        `json:"weight" default:"1"`
+	Children []treeNode `json:"children,omitempty" maxItems:"3"`
+}
+
+// treeArgs holds recursive types below the root.
+type treeArgs struct {
+	Root treeNode  `json:"root"`
+	List *listNode `json:"list,omitempty"`
+}
+
+// expr and term refer to each other.
+type expr struct {
+	Op   string `json:"op" default:"and"`
+	Args []term `json:"args"`
+}
+
+type term struct {
+	Lit *float64 `json:"lit,omitempty"`
+	Sub *expr    `json:"sub,omitempty"`
+}
+
+type queryArgs struct {
+	Where expr `json:"where"`
+}
+
+// filterQueryArgs uses term outside the cycle too.
+type filterQueryArgs struct {
+	Where expr   `json:"where"`
+	Or    []term `json:"or,omitempty"`
+}
+
+func generate(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	s, err := schema.Generate(reflect.TypeOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Recursion must be expressed with references, leaving a tree of maps
+	// that encodes.
+	if _, err := json.Marshal(s); err != nil {
+		t.Fatalf("schema does not encode: %v", err)
+	}
+	checkRefs(t, s, s)
+	return s
+}
+
+// checkRefs fails t for any $ref in s that does not resolve within root.
+func checkRefs(t *testing.T, root map[string]interface{}, s interface{}) {
+	t.Helper()
+	switch s := s.(type) {
+	case map[string]interface{}:
+		if ref, ok := s["$ref"].(string); ok && ref != "#" {
+			name, ok := strings.CutPrefix(ref, "#/$defs/")
+			defs, _ := root["$defs"].(map[string]interface{})
+			if _, defined := defs[name]; !ok || !defined {
+				t.Errorf("$ref %q does not resolve", ref)
+			}
+		}
+		for _, v := range s {
+			checkRefs(t, root, v)
+		}
+	case []interface{}:
+		for _, v := range s {
+			checkRefs(t, root, v)
+		}
+	}
+}
+
+// at returns the value at the given path of map keys in s.
+func at(s map[string]interface{}, path ...string) interface{} {
+	var v interface{} = s
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+var rootRef = map[string]interface{}{"$ref": "#"}
+
+func TestRecursiveRoot(t *testing.T) {
+	list := generate(t, listNode{})
+	if got := at(list, "properties", "next"); !reflect.DeepEqual(got, rootRef) {
+		t.Errorf("list next = %v, want a reference to the root", got)
+	}
+	tree := generate(t, treeNode{})
+	if got := at(tree, "properties", "children", "items"); !reflect.DeepEqual(got, rootRef) {
+		t.Errorf("tree children items = %v, want a reference to the root", got)
+	}
+	for name, s := range map[string]map[string]interface{}{"list": list, "tree": tree} {
+		if _, ok := s["$defs"]; ok {
+			t.Errorf("%s: root recursion needs no $defs, got %v", name, s["$defs"])
+		}
+	}
+}
+
+func TestRecursiveBelowRoot(t *testing.T) {
+	s := generate(t, treeArgs{})
+	for prop, def := range map[string]string{"root": "treeNode", "list": "listNode"} {
+		want := map[string]interface{}{"$ref": "#/$defs/" + def}
+		if got := at(s, "properties", prop); !reflect.DeepEqual(got, want) {
+			t.Errorf("property %s = %v, want %v", prop, got, want)
+		}
+	}
+	want := map[string]interface{}{"$ref": "#/$defs/treeNode"}
+	if got := at(s, "$defs", "treeNode", "properties", "children", "items"); !reflect.DeepEqual(got, want) {
+		t.Errorf("treeNode children items = %v, want %v", got, want)
+	}
+}
+
+func TestMutuallyRecursive(t *testing.T) {
+	exprRef := map[string]interface{}{"$ref": "#/$defs/expr"}
+
+	// The cycle is broken at expr, the type entered first; term, used
+	// only inside it, is inlined and refers back to it.
+	s := generate(t, queryArgs{})
+	if got := at(s, "properties", "where"); !reflect.DeepEqual(got, exprRef) {
+		t.Errorf("where = %v, want %v", got, exprRef)
+	}
+	if got := at(s, "$defs", "expr", "properties", "args", "items", "properties", "sub"); !reflect.DeepEqual(got, exprRef) {
+		t.Errorf("expr args items sub = %v, want %v", got, exprRef)
+	}
+	if defs, _ := s["$defs"].(map[string]interface{}); len(defs) != 1 {
+		t.Errorf("$defs = %v, want only expr", defs)
+	}
+
+	// Used elsewhere as well, term gets a definition of its own.
+	s = generate(t, filterQueryArgs{})
+	termRef := map[string]interface{}{"$ref": "#/$defs/term"}
+	if got := at(s, "$defs", "expr", "properties", "args", "items"); !reflect.DeepEqual(got, termRef) {
+		t.Errorf("expr args items = %v, want %v", got, termRef)
+	}
+	if got := at(s, "$defs", "term", "properties", "sub"); !reflect.DeepEqual(got, exprRef) {
+		t.Errorf("term sub = %v, want %v", got, exprRef)
+	}
+
+	// With one of them as the root, the cycle closes on "#".
+	s = generate(t, expr{})
+	if got := at(s, "properties", "args", "items", "properties", "sub"); !reflect.DeepEqual(got, rootRef) {
+		t.Errorf("expr args items sub = %v, want a reference to the root", got)
+	}
+}
+
+// terminates fails t unless f returns within a few seconds.
+func terminates(t *testing.T, name string, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not terminate", name)
+	}
+}
+
+// deepTree returns a tree of the given depth, one child per node, with no
+// weights set.
+func deepTree(depth int) string {
+	var b strings.Builder
+	for i := 0; i < depth; i++ {
+		b.WriteString(`{"name":"n","children":[`)
+	}
+	b.WriteString(`{"name":"leaf"}`)
+	for i := 0; i < depth; i++ {
+		b.WriteString(`]}`)
+	}
+	return b.String()
+}
+
+func TestRecursiveDefaults(t *testing.T) {
+	s := generate(t, treeArgs{})
+	raw := json.RawMessage(`{"root":` + deepTree(20) + `}`)
+	var filled json.RawMessage
+	var err error
+	terminates(t, "ApplyDefaults", func() { filled, err = schema.ApplyDefaults(s, raw) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args treeArgs
+	if err := json.Unmarshal(filled, &args); err != nil {
+		t.Fatal(err)
+	}
+	depth := 0
+	for n := &args.Root; ; n = &n.Children[0] {
+		if n.Weight != 1 {
+			t.Fatalf("node at depth %d has weight %d, want the default 1", depth, n.Weight)
+		}
+		if len(n.Children) == 0 {
+			break
+		}
+		depth++
+	}
+	if depth != 20 {
+		t.Errorf("tree depth %d after defaults, want 20", depth)
+	}
+
+	s = generate(t, queryArgs{})
+	raw = json.RawMessage(`{"where":{"args":[{"sub":{"args":[{"sub":{"args":[]}}]}}]}}`)
+	terminates(t, "ApplyDefaults", func() { filled, err = schema.ApplyDefaults(s, raw) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q queryArgs
+	if err := json.Unmarshal(filled, &q); err != nil {
+		t.Fatal(err)
+	}
+	if op := q.Where.Args[0].Sub.Args[0].Sub.Op; op != "and" {
+		t.Errorf("innermost op = %q, want the default", op)
+	}
+}
+
+func TestRecursiveLimits(t *testing.T) {
+	s := generate(t, treeArgs{})
+	tests := []struct {
+		name   string
+		raw    string
+		limits schema.Limits
+		want   *schema.LimitError
+	}{
+		{"within limits", `{"root":` + deepTree(10) + `}`, schema.Limits{}, nil},
+		{"too deep", `{"root":` + deepTree(40) + `}`, schema.Limits{}, &schema.LimitError{Limit: "maxDepth", Max: 32}},
+		{"maxItems deep down", `{"root":{"name":"a","children":[{"name":"b","children":[{"name":"c"},{"name":"d"},{"name":"e"},{"name":"f"}]}]}}`,
+			schema.Limits{}, &schema.LimitError{Path: "root.children[0].children", Limit: "maxItems", Max: 3}},
+		{"list", `{"root":{"name":"r"},"list":{"value":1,"next":{"value":2,"next":{"value":3}}}}`, schema.Limits{MaxDepth: 3}, &schema.LimitError{Limit: "maxDepth", Max: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			terminates(t, "CheckLimits", func() { err = schema.CheckLimits(json.RawMessage(tt.raw), s, tt.limits) })
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("CheckLimits: %v", err)
+				}
+				return
+			}
+			var le *schema.LimitError
+			if !errors.As(err, &le) {
+				t.Fatalf("CheckLimits = %v, want a LimitError", err)
+			}
+			if le.Limit != tt.want.Limit || le.Max != tt.want.Max || tt.want.Path != "" && le.Path != tt.want.Path {
+				t.Errorf("CheckLimits = %+v, want %+v", le, tt.want)
+			}
+		})
+	}
+}
+
+// TestSelfReferentialDefinition checks that a hand-written schema whose
+// definition refers to nothing but itself does not hang the functions
+// following references.
+func TestSelfReferentialDefinition(t *testing.T) {
+	s := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"loop": map[string]interface{}{"$ref": "#/$defs/loop"},
+		},
+		"$defs": map[string]interface{}{
+			"loop": map[string]interface{}{"$ref": "#/$defs/loop"},
+		},
+	}
+	raw := json.RawMessage(`{"loop":{"loop":{}}}`)
+	terminates(t, "HasDefaults", func() { schema.HasDefaults(s) })
+	terminates(t, "ApplyDefaults", func() { schema.ApplyDefaults(s, raw) })
+	terminates(t, "CheckLimits", func() { schema.CheckLimits(raw, s, schema.Limits{}) })
+	terminates(t, "Validate", func() { schema.Validate(raw, s) })
+}
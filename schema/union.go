@@ -0,0 +1,205 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Union holds one of the concrete types registered for the interface I
+// with RegisterUnion. On the wire it is a JSON object whose discriminator
+// property names the variant; its schema is a oneOf over the variants.
+//
+//	type Shape interface{ Area() float64 }
+//
+//	func init() {
+//		schema.RegisterUnion[Shape]("kind",
+//			schema.Variant[Circle]("circle"),
+//			schema.Variant[Square]("square"))
+//	}
+//
+//	type DrawArgs struct {
+//		Shape schema.Union[Shape] `json:"shape"`
+//	}
+type Union[I any] struct {
+	Value I
+}
+
+// VariantSpec names one concrete type of a union.
+type VariantSpec struct {
+	name string
+	typ  reflect.Type
+}
+
+// Variant returns the variant spec for T, identified on the wire by name.
+func Variant[T any](name string) VariantSpec {
+	return VariantSpec{name: name, typ: reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+type unionSpec struct {
+	discriminator string
+	byName        map[string]reflect.Type
+	byType        map[reflect.Type]string
+	names         []string
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = make(map[reflect.Type]*unionSpec)
+)
+
+// RegisterUnion declares the variants of the interface I, discriminated by
+// the property named discriminator. It is meant to be called from init and
+// panics if I is not an interface, a variant does not implement I, or a
+// name is used twice.
+func RegisterUnion[I any](discriminator string, variants ...VariantSpec) {
+	it := reflect.TypeOf((*I)(nil)).Elem()
+	if it.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("schema: RegisterUnion of non-interface type %s", it))
+	}
+	spec := &unionSpec{
+		discriminator: discriminator,
+		byName:        make(map[string]reflect.Type),
+		byType:        make(map[reflect.Type]string),
+	}
+	for _, v := range variants {
+		if !v.typ.Implements(it) {
+			panic(fmt.Sprintf("schema: union variant %s does not implement %s", v.typ, it))
+		}
+		if _, dup := spec.byName[v.name]; dup {
+			panic(fmt.Sprintf("schema: union %s has duplicate variant %q", it, v.name))
+		}
+		spec.byName[v.name] = v.typ
+		spec.byType[v.typ] = v.name
+		spec.names = append(spec.names, v.name)
+	}
+	sort.Strings(spec.names)
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	unions[it] = spec
+}
+
+func lookupUnion(it reflect.Type) (*unionSpec, error) {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	spec, ok := unions[it]
+	if !ok {
+		return nil, fmt.Errorf("schema: no variants registered for %s", it)
+	}
+	return spec, nil
+}
+
+// unionType is implemented by every Union instantiation.
+type unionType interface {
+	unionInterface() reflect.Type
+}
+
+func (Union[I]) unionInterface() reflect.Type {
+	return reflect.TypeOf((*I)(nil)).Elem()
+}
+
+var unionTypeType = reflect.TypeOf((*unionType)(nil)).Elem()
+
+// generateUnion returns the oneOf schema of the union over interface it.
+func (g *generator) generateUnion(it reflect.Type) (map[string]interface{}, error) {
+	spec, err := lookupUnion(it)
+	if err != nil {
+		return nil, err
+	}
+	oneOf := make([]interface{}, 0, len(spec.names))
+	for _, name := range spec.names {
+		vt := spec.byName[name]
+		for vt.Kind() == reflect.Pointer {
+			vt = vt.Elem()
+		}
+		if vt.Kind() != reflect.Struct || vt == timeType {
+			return nil, fmt.Errorf("schema: union variant %q of %s is not a struct", name, it)
+		}
+		// Variants are always inlined: each gets its own discriminator.
+		s, err := g.generateStruct(vt)
+		if err != nil {
+			return nil, fmt.Errorf("variant %q: %w", name, err)
+		}
+		props, _ := s["properties"].(map[string]interface{})
+		if props == nil {
+			props = make(map[string]interface{})
+			s["properties"] = props
+		}
+		props[spec.discriminator] = map[string]interface{}{"type": "string", "const": name}
+		required, _ := s["required"].([]string)
+		s["required"] = append([]string{spec.discriminator}, required...)
+		oneOf = append(oneOf, s)
+	}
+	return map[string]interface{}{
+		"type":          "object",
+		"oneOf":         oneOf,
+		"discriminator": map[string]interface{}{"propertyName": spec.discriminator},
+	}, nil
+}
+
+// UnmarshalJSON decodes the variant named by the discriminator property.
+func (u *Union[I]) UnmarshalJSON(data []byte) error {
+	spec, err := lookupUnion(u.unionInterface())
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero I
+		u.Value = zero
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	raw, ok := fields[spec.discriminator]
+	if !ok {
+		return fmt.Errorf("missing %q property, want one of %q", spec.discriminator, spec.names)
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		return fmt.Errorf("%q property: %w", spec.discriminator, err)
+	}
+	t, ok := spec.byName[name]
+	if !ok {
+		return fmt.Errorf("unknown %s %q, want one of %q", spec.discriminator, name, spec.names)
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return err
+	}
+	u.Value = v.Elem().Interface().(I)
+	return nil
+}
+
+// MarshalJSON encodes the value with its discriminator property.
+func (u Union[I]) MarshalJSON() ([]byte, error) {
+	v := reflect.ValueOf(&u.Value).Elem()
+	if v.IsNil() {
+		return []byte("null"), nil
+	}
+	spec, err := lookupUnion(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	name, ok := spec.byType[v.Elem().Type()]
+	if !ok {
+		return nil, fmt.Errorf("schema: %s is not a registered variant of %s", v.Elem().Type(), v.Type())
+	}
+	data, err := json.Marshal(u.Value)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("schema: union variant %q must encode as an object: %w", name, err)
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+	fields[spec.discriminator], _ = json.Marshal(name)
+	return json.Marshal(fields)
+}
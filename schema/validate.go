@@ -0,0 +1,432 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxValidationErrors bounds the errors Validate reports for one value.
+const maxValidationErrors = 20
+
+// ValidationError reports a value that does not match its schema.
+type ValidationError struct {
+	// Path is the JSON path of the offending value, such as "items[3]",
+	// and empty for the arguments as a whole.
+	Path string `json:"path,omitempty"`
+	// Keyword is the schema keyword the value violates, such as
+	// "required" or "type".
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "arguments"
+	}
+	return path + ": " + e.Message
+}
+
+// ValidationErrors collects the errors found by Validate.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ve := range e {
+		msgs[i] = ve.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the JSON value raw against s and returns
+// ValidationErrors listing, up to a bound, the places it does not match.
+// It understands the keywords of the schemas Generate produces and the
+// common validation keywords of hand-written ones: type, enum, const, the
+// numeric, string, array and object bounds, pattern, properties, required,
+// additionalProperties, items, allOf, anyOf, oneOf, not and references
+// into $defs. Annotations such as format are not checked. A nil s accepts
+// any value.
+func Validate(raw json.RawMessage, s map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	vd := validator{root: s}
+	vd.check("", v, s)
+	if len(vd.errs) > 0 {
+		return vd.errs
+	}
+	return nil
+}
+
+type validator struct {
+	root map[string]interface{}
+	errs ValidationErrors
+}
+
+func (vd *validator) fail(path, keyword, format string, args ...interface{}) {
+	if len(vd.errs) < maxValidationErrors {
+		vd.errs = append(vd.errs, &ValidationError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// matches reports whether v matches s without recording errors.
+func (vd *validator) matches(path string, v interface{}, s map[string]interface{}) bool {
+	sub := validator{root: vd.root}
+	sub.check(path, v, s)
+	return len(sub.errs) == 0
+}
+
+func (vd *validator) check(path string, v interface{}, s map[string]interface{}) {
+	s = deref(vd.root, s)
+	if s == nil {
+		return
+	}
+	if t, ok := s["type"]; ok && !typeMatches(v, t) {
+		vd.fail(path, "type", "must be %s, not %s", typeList(t), jsonType(v))
+		return
+	}
+	if enum, ok := s["enum"]; ok {
+		found := false
+		for _, e := range toSlice(enum) {
+			if equalJSON(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vd.fail(path, "enum", "must be one of %s", encode(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !equalJSON(v, c) {
+		vd.fail(path, "const", "must be %s", encode(c))
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		vd.checkNumber(path, v, s)
+	case string:
+		vd.checkString(path, v, s)
+	case []interface{}:
+		vd.checkArray(path, v, s)
+	case map[string]interface{}:
+		vd.checkObject(path, v, s)
+	}
+
+	for _, sub := range schemas(s["allOf"]) {
+		vd.check(path, v, sub)
+	}
+	if anyOf := schemas(s["anyOf"]); len(anyOf) > 0 {
+		ok := false
+		for _, sub := range anyOf {
+			if vd.matches(path, v, sub) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			vd.fail(path, "anyOf", "must match at least one of the allowed schemas")
+		}
+	}
+	if oneOf := schemas(s["oneOf"]); len(oneOf) > 0 {
+		n := 0
+		for _, sub := range oneOf {
+			if vd.matches(path, v, sub) {
+				n++
+			}
+		}
+		if n != 1 {
+			vd.fail(path, "oneOf", "must match exactly one of the allowed schemas, matches %d", n)
+		}
+	}
+	if not, ok := s["not"].(map[string]interface{}); ok && vd.matches(path, v, not) {
+		vd.fail(path, "not", "must not match the excluded schema")
+	}
+}
+
+func (vd *validator) checkNumber(path string, n json.Number, s map[string]interface{}) {
+	f, err := n.Float64()
+	if err != nil {
+		return
+	}
+	if min, ok := number(s["minimum"]); ok && f < min {
+		vd.fail(path, "minimum", "must be at least %v", min)
+	}
+	if max, ok := number(s["maximum"]); ok && f > max {
+		vd.fail(path, "maximum", "must be at most %v", max)
+	}
+	if min, ok := number(s["exclusiveMinimum"]); ok && f <= min {
+		vd.fail(path, "exclusiveMinimum", "must be greater than %v", min)
+	}
+	if max, ok := number(s["exclusiveMaximum"]); ok && f >= max {
+		vd.fail(path, "exclusiveMaximum", "must be less than %v", max)
+	}
+	if m, ok := number(s["multipleOf"]); ok && m > 0 {
+		if q := f / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			vd.fail(path, "multipleOf", "must be a multiple of %v", m)
+		}
+	}
+}
+
+func (vd *validator) checkString(path, str string, s map[string]interface{}) {
+	n := utf8.RuneCountInString(str)
+	if min, ok := number(s["minLength"]); ok && float64(n) < min {
+		vd.fail(path, "minLength", "must be at least %v characters long", min)
+	}
+	if max, ok := number(s["maxLength"]); ok && float64(n) > max {
+		vd.fail(path, "maxLength", "must be at most %v characters long", max)
+	}
+	if p, ok := s["pattern"].(string); ok {
+		re, err := compilePattern(p)
+		if err == nil && !re.MatchString(str) {
+			vd.fail(path, "pattern", "must match the pattern %q", p)
+		}
+	}
+}
+
+func (vd *validator) checkArray(path string, a []interface{}, s map[string]interface{}) {
+	if min, ok := number(s["minItems"]); ok && float64(len(a)) < min {
+		vd.fail(path, "minItems", "must have at least %v items", min)
+	}
+	if max, ok := number(s["maxItems"]); ok && float64(len(a)) > max {
+		vd.fail(path, "maxItems", "must have at most %v items", max)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	outer:
+		for i := range a {
+			for j := 0; j < i; j++ {
+				if equalJSON(a[i], a[j]) {
+					vd.fail(path, "uniqueItems", "items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		for i, item := range a {
+			vd.check(path+"["+strconv.Itoa(i)+"]", item, items)
+		}
+	}
+}
+
+func (vd *validator) checkObject(path string, obj map[string]interface{}, s map[string]interface{}) {
+	required := stringList(s["required"])
+	for _, name := range required {
+		if _, ok := obj[name]; !ok {
+			vd.fail(joinPath(path, name), "required", "is required")
+		}
+	}
+	if min, ok := number(s["minProperties"]); ok && float64(len(obj)) < min {
+		vd.fail(path, "minProperties", "must have at least %v members", min)
+	}
+	if max, ok := number(s["maxProperties"]); ok && float64(len(obj)) > max {
+		vd.fail(path, "maxProperties", "must have at most %v members", max)
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // report errors in a stable order
+	for _, k := range keys {
+		// Decoding leaves a field alone when its value is null, so null is
+		// accepted for optional properties whatever their declared type.
+		if _, declared := props[k]; declared && obj[k] == nil && !contains(required, k) {
+			continue
+		}
+		if ps, ok := props[k].(map[string]interface{}); ok {
+			vd.check(joinPath(path, k), obj[k], ps)
+			continue
+		}
+		switch ap := s["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				vd.fail(joinPath(path, k), "additionalProperties", "is not allowed")
+			}
+		case map[string]interface{}:
+			vd.check(joinPath(path, k), obj[k], ap)
+		}
+	}
+}
+
+// typeMatches reports whether v has the JSON type, or one of the types,
+// named by t.
+func typeMatches(v interface{}, t interface{}) bool {
+	types := stringList(t)
+	if len(types) == 0 {
+		return true
+	}
+	actual := jsonType(v)
+	for _, want := range types {
+		switch {
+		case want == actual:
+			return true
+		case want == "number" && actual == "integer":
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value, distinguishing
+// integers from other numbers.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func typeList(t interface{}) string {
+	types := stringList(t)
+	if len(types) == 1 {
+		return types[0]
+	}
+	return "one of " + strings.Join(types, ", ")
+}
+
+// stringList returns the string or list of strings v holds.
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// schemas returns the list of schemas v holds, as in allOf.
+func schemas(v interface{}) []map[string]interface{} {
+	var out []map[string]interface{}
+	switch v := v.(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		for _, x := range v {
+			if m, ok := x.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+// toSlice returns the elements of the list v, whatever its element type.
+func toSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+// number returns the numeric keyword value v.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equalJSON reports whether a and b encode the same JSON value, comparing
+// numbers by value.
+func equalJSON(a, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// normalize round-trips v through JSON, so that values of different Go
+// types encoding the same JSON compare equal.
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if json.Unmarshal(data, &out) != nil {
+		return v
+	}
+	return out
+}
+
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// patterns caches compiled pattern keywords.
+var patterns sync.Map // string -> *regexp.Regexp
+
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(p, re)
+	return re, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
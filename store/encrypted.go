@@ -0,0 +1,229 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrUndecryptable is returned by encrypted stores for values they cannot
+// decrypt: values encrypted under a key they were not given, values
+// altered in storage or moved to another key, and plaintext values unless
+// EncryptionConfig.ReadPlaintext is set.
+var ErrUndecryptable = errors.New("store: cannot decrypt value")
+
+// EncryptionKey is an AES key identified by ID. The ID is stored with
+// every value so that the key can be found again after rotation; it must
+// not exceed 255 bytes.
+type EncryptionKey struct {
+	ID string
+	// Secret is the AES-128, AES-192 or AES-256 key: 16, 24 or 32 bytes.
+	Secret []byte
+}
+
+// EncryptionConfig configures NewEncrypted.
+type EncryptionConfig struct {
+	// Key encrypts every value written.
+	Key EncryptionKey
+	// Previous lists retired keys, still used to decrypt values written
+	// before a rotation. Reencrypt moves those values to Key, after which
+	// the retired keys can be dropped.
+	Previous []EncryptionKey
+	// ReadPlaintext returns values written before encryption was enabled
+	// as they are, instead of failing with ErrUndecryptable, so that an
+	// existing store can be encrypted in place with Reencrypt.
+	ReadPlaintext bool
+}
+
+// encryptedMagic starts every encrypted value. Plaintext values written
+// by zenmcp are JSON and never start with a NUL byte.
+var encryptedMagic = []byte("\x00zenc\x01")
+
+// NewEncrypted returns a Store encrypting the values of inner with
+// AES-GCM. Keys are stored in plaintext, so that List keeps working, and
+// each value is bound to its key: a value copied to another key fails to
+// decrypt. The returned store implements Transactional when inner does.
+func NewEncrypted(inner Store, cfg EncryptionConfig) (Store, error) {
+	e := &encrypted{inner: inner, readPlaintext: cfg.ReadPlaintext, aeads: make(map[string]cipher.AEAD)}
+	for i, k := range append([]EncryptionKey{cfg.Key}, cfg.Previous...) {
+		if k.ID == "" || len(k.ID) > 255 {
+			return nil, fmt.Errorf("store: encryption key ID %q must be 1 to 255 bytes", k.ID)
+		}
+		if _, dup := e.aeads[k.ID]; dup {
+			return nil, fmt.Errorf("store: duplicate encryption key ID %q", k.ID)
+		}
+		block, err := aes.NewCipher(k.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("store: encryption key %q: %w", k.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		e.aeads[k.ID] = aead
+		if i == 0 {
+			e.current = k.ID
+		}
+	}
+	if _, ok := inner.(Transactional); ok {
+		return &encryptedTx{e}, nil
+	}
+	return e, nil
+}
+
+// encrypted is the Store returned by NewEncrypted.
+type encrypted struct {
+	inner         Store
+	current       string
+	aeads         map[string]cipher.AEAD
+	readPlaintext bool
+}
+
+// Get implements Store.
+func (e *encrypted) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := e.inner.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	plain, _, err := e.open(key, v)
+	return plain, err
+}
+
+// Put implements Store.
+func (e *encrypted) Put(ctx context.Context, key string, value []byte) error {
+	sealed, err := e.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return e.inner.Put(ctx, key, sealed)
+}
+
+// Delete implements Store.
+func (e *encrypted) Delete(ctx context.Context, key string) error {
+	return e.inner.Delete(ctx, key)
+}
+
+// List implements Store.
+func (e *encrypted) List(ctx context.Context, prefix string) ([]string, error) {
+	return e.inner.List(ctx, prefix)
+}
+
+// seal encrypts value under the current key, authenticating key with it.
+// The result is the magic, the key ID's length and bytes, the nonce and
+// the ciphertext.
+func (e *encrypted) seal(key string, value []byte) ([]byte, error) {
+	aead := e.aeads[e.current]
+	out := make([]byte, 0, len(encryptedMagic)+1+len(e.current)+aead.NonceSize()+len(value)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, byte(len(e.current)))
+	out = append(out, e.current...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("store: generating nonce: %w", err)
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, value, []byte(key)), nil
+}
+
+// open decrypts a value stored under key and returns the ID of the key it
+// was encrypted with, or "" for plaintext.
+func (e *encrypted) open(key string, v []byte) ([]byte, string, error) {
+	rest, ok := bytes.CutPrefix(v, encryptedMagic)
+	if !ok {
+		if e.readPlaintext {
+			return v, "", nil
+		}
+		return nil, "", fmt.Errorf("%w %q: not encrypted", ErrUndecryptable, key)
+	}
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return nil, "", fmt.Errorf("%w %q: truncated", ErrUndecryptable, key)
+	}
+	id := string(rest[1 : 1+rest[0]])
+	rest = rest[1+rest[0]:]
+	aead, ok := e.aeads[id]
+	if !ok {
+		return nil, "", fmt.Errorf("%w %q: unknown key %q", ErrUndecryptable, key, id)
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, "", fmt.Errorf("%w %q: truncated", ErrUndecryptable, key)
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w %q: %v", ErrUndecryptable, key, err)
+	}
+	return plain, id, nil
+}
+
+// encryptedTx is an encrypted store over a Transactional one.
+type encryptedTx struct {
+	*encrypted
+}
+
+// Update implements Transactional. Values read and written through tx are
+// decrypted and encrypted like those of the store itself.
+func (e *encryptedTx) Update(ctx context.Context, fn func(tx Store) error) error {
+	return e.inner.(Transactional).Update(ctx, func(tx Store) error {
+		c := *e.encrypted
+		c.inner = tx
+		return fn(&c)
+	})
+}
+
+// Reencrypt rewrites the values under prefix that s, a store returned by
+// NewEncrypted, did not encrypt with its current key, including plaintext
+// values when it reads them. It returns how many values it rewrote. Run it
+// after a key rotation, before retiring the previous keys.
+func Reencrypt(ctx context.Context, s Store, prefix string) (int, error) {
+	var e *encrypted
+	switch s := s.(type) {
+	case *encrypted:
+		e = s
+	case *encryptedTx:
+		e = s.encrypted
+	default:
+		return 0, errors.New("store: Reencrypt needs a store returned by NewEncrypted")
+	}
+	keys, err := e.inner.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		var rewritten bool
+		rewrite := func(st Store) error {
+			c := *e
+			c.inner = st
+			v, err := st.Get(ctx, key)
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			plain, id, err := c.open(key, v)
+			if err != nil || id == c.current {
+				return err
+			}
+			rewritten = true
+			return c.Put(ctx, key, plain)
+		}
+		// Transactional stores keep concurrent writes from being
+		// overwritten with the value read here.
+		if t, ok := e.inner.(Transactional); ok {
+			err = t.Update(ctx, rewrite)
+		} else {
+			err = rewrite(e.inner)
+		}
+		if err != nil {
+			return n, err
+		}
+		if rewritten {
+			n++
+		}
+	}
+	return n, nil
+}
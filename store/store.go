@@ -0,0 +1,151 @@
+// Package store defines the key-value storage used for state that must
+// outlive a connection or a process, such as paused workflows.
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by Get for missing keys.
+var ErrNotFound = errors.New("store: not found")
+
+// Store is a key-value store. Implementations must be safe for concurrent
+// use. Keys are slash-separated paths such as "workflow/runs/42".
+type Store interface {
+	// Get returns the value stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores value under key, replacing any existing value.
+	Put(ctx context.Context, key string, value []byte) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys starting with prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// MemoryStore keeps values in memory. It is meant for tests and for
+// single-process deployments that can afford to lose state on restart.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+// Put implements Store.
+func (m *MemoryStore) Put(ctx context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+// List implements Store.
+func (m *MemoryStore) List(ctx context.Context, prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Transactional is implemented by stores that can apply several writes
+// atomically. Update calls fn with a Store whose writes take effect
+// together when fn returns nil and are discarded otherwise. Reads through
+// tx observe its own pending writes.
+type Transactional interface {
+	Update(ctx context.Context, fn func(tx Store) error) error
+}
+
+// Update implements Transactional. Other operations on m block until fn
+// returns, so fn must only use tx.
+func (m *MemoryStore) Update(ctx context.Context, fn func(tx Store) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx := &memoryTx{m: m, writes: make(map[string][]byte)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for k, v := range tx.writes {
+		if v == nil {
+			delete(m.data, k)
+		} else {
+			m.data[k] = v
+		}
+	}
+	return nil
+}
+
+// memoryTx stages writes to a locked MemoryStore. A nil value marks a
+// deletion.
+type memoryTx struct {
+	m      *MemoryStore
+	writes map[string][]byte
+}
+
+func (tx *memoryTx) Get(ctx context.Context, key string) ([]byte, error) {
+	v, staged := tx.writes[key]
+	if !staged {
+		v = tx.m.data[key]
+	}
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+func (tx *memoryTx) Put(ctx context.Context, key string, value []byte) error {
+	tx.writes[key] = append(make([]byte, 0, len(value)), value...)
+	return nil
+}
+
+func (tx *memoryTx) Delete(ctx context.Context, key string) error {
+	tx.writes[key] = nil
+	return nil
+}
+
+func (tx *memoryTx) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for k := range tx.m.data {
+		if _, staged := tx.writes[k]; !staged && strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range tx.writes {
+		if v != nil && strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,169 @@
+// Package tenant partitions one server between tenants. Each tenant is
+// served from its own registry, so tools, resources, prompts and the
+// caches attached to them are never shared between tenants.
+//
+// A Resolver names the tenant of every request, for example from a claim
+// of the authenticated principal or from the /mcp/{tenant} path segment
+// of the HTTP transport. A session is bound to the tenant of its first
+// request and cannot switch tenants afterwards.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ErrUnknownTenant is returned for requests addressing a tenant that has
+// no registry.
+var ErrUnknownTenant = errors.New("tenant: unknown tenant")
+
+// ErrConflict is returned by resolvers combined with Agree when they name
+// different tenants.
+var ErrConflict = errors.New("tenant: conflicting tenants")
+
+// Resolver returns the tenant of a request, or "" when the request names
+// none.
+type Resolver func(ctx context.Context) (string, error)
+
+// Path resolves the tenant chosen by the transport, such as the path
+// segment of the HTTP transport's WithTenantPaths.
+func Path(ctx context.Context) (string, error) {
+	p, _ := transport.PeerFromContext(ctx)
+	return p.Tenant, nil
+}
+
+// Claim resolves the tenant from the string claim name of the
+// authenticated principal.
+func Claim(name string) Resolver {
+	return func(ctx context.Context) (string, error) {
+		p, _ := transport.PeerFromContext(ctx)
+		return p.Principal.Claim(name), nil
+	}
+}
+
+// Agree combines resolvers that must not contradict each other. The
+// tenant is the one named by any of them; a request for which two of them
+// name different tenants fails with ErrConflict. Combining Path and Claim
+// this way keeps an authenticated client out of other tenants' paths.
+func Agree(rs ...Resolver) Resolver {
+	return func(ctx context.Context) (string, error) {
+		var tenant string
+		for _, r := range rs {
+			t, err := r(ctx)
+			if err != nil {
+				return "", err
+			}
+			if t == "" {
+				continue
+			}
+			if tenant != "" && t != tenant {
+				return "", fmt.Errorf("%w: %q and %q", ErrConflict, tenant, t)
+			}
+			tenant = t
+		}
+		return tenant, nil
+	}
+}
+
+// Set maps tenant names to the registries serving them.
+type Set struct {
+	resolve Resolver
+
+	mu   sync.RWMutex
+	regs map[string]*registry.Registry
+}
+
+// NewSet returns an empty set whose requests are assigned to tenants by
+// resolve.
+func NewSet(resolve Resolver) *Set {
+	return &Set{resolve: resolve, regs: make(map[string]*registry.Registry)}
+}
+
+// Add serves tenant from reg. Each tenant needs its own registry; adding
+// one registry for two tenants would let them share state.
+func (s *Set) Add(tenant string, reg *registry.Registry) error {
+	if tenant == "" {
+		return errors.New("tenant: name is required")
+	}
+	if reg == nil {
+		return fmt.Errorf("tenant: %q has no registry", tenant)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.regs[tenant]; ok {
+		return fmt.Errorf("tenant: %q already added", tenant)
+	}
+	for name, r := range s.regs {
+		if r == reg {
+			return fmt.Errorf("tenant: %q already uses this registry", name)
+		}
+	}
+	s.regs[tenant] = reg
+	return nil
+}
+
+// Registry returns the registry of tenant.
+func (s *Set) Registry(tenant string) (*registry.Registry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reg, ok := s.regs[tenant]
+	return reg, ok
+}
+
+// Names returns the tenants in the set, sorted.
+func (s *Set) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.regs))
+	for name := range s.regs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the tenant of the request ctx belongs to and its
+// registry. A request naming no tenant yields "" and a nil registry; one
+// naming a tenant outside the set fails with ErrUnknownTenant.
+func (s *Set) Resolve(ctx context.Context) (string, *registry.Registry, error) {
+	tenant, err := s.resolve(ctx)
+	if err != nil || tenant == "" {
+		return "", nil, err
+	}
+	reg, ok := s.Registry(tenant)
+	if !ok {
+		return "", nil, fmt.Errorf("%w %q", ErrUnknownTenant, tenant)
+	}
+	return tenant, reg, nil
+}
+
+// Init runs the Init hooks of every tenant's registry, in tenant name
+// order.
+func (s *Set) Init(ctx context.Context) error {
+	for _, name := range s.Names() {
+		reg, _ := s.Registry(name)
+		if err := reg.Init(ctx); err != nil {
+			return fmt.Errorf("tenant %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Shutdown runs the Shutdown hooks of every tenant's registry and joins
+// their errors.
+func (s *Set) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, name := range s.Names() {
+		reg, _ := s.Registry(name)
+		if err := reg.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
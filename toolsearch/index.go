@@ -0,0 +1,209 @@
+// Package toolsearch ranks the tools of a registry against free-text
+// queries, helping agents find the right tool on servers with large
+// catalogs.
+//
+// Ranking uses BM25 over tool names, descriptions and tags. An optional
+// Embedder adds semantic similarity on top.
+package toolsearch
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hyperleex/zenmcp/registry"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects their
+// semantic similarity.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Result is a ranked tool.
+type Result struct {
+	Tool  *registry.ToolDescriptor
+	Score float64
+}
+
+// Index is an immutable search index over a set of tools.
+type Index struct {
+	docs     []document
+	df       map[string]int
+	avgLen   float64
+	embedder Embedder
+	weight   float64
+}
+
+type document struct {
+	tool   *registry.ToolDescriptor
+	terms  map[string]int
+	length int
+	vector []float32
+}
+
+// Options configure an index.
+type Options struct {
+	// Embedder, when set, is used to embed tools and queries; the cosine
+	// similarity is blended with the BM25 score.
+	Embedder Embedder
+	// SemanticWeight is the share of the semantic score in the blended
+	// score, between 0 and 1. Defaults to 0.5 when an Embedder is set.
+	SemanticWeight float64
+}
+
+// Build indexes tools.
+func Build(ctx context.Context, tools []*registry.ToolDescriptor, opts Options) (*Index, error) {
+	idx := &Index{df: make(map[string]int), embedder: opts.Embedder, weight: opts.SemanticWeight}
+	if idx.weight <= 0 || idx.weight > 1 {
+		idx.weight = 0.5
+	}
+	texts := make([]string, 0, len(tools))
+	total := 0
+	for _, t := range tools {
+		// The name counts twice: it is the strongest signal of what a
+		// tool does.
+		text := t.Key() + " " + t.Name + " " + t.Description + " " + strings.Join(t.Tags, " ")
+		terms := make(map[string]int)
+		n := 0
+		for _, tok := range tokenize(text) {
+			terms[tok]++
+			n++
+		}
+		for term := range terms {
+			idx.df[term]++
+		}
+		idx.docs = append(idx.docs, document{tool: t, terms: terms, length: n})
+		texts = append(texts, t.Name+": "+t.Description)
+		total += n
+	}
+	if len(tools) > 0 {
+		idx.avgLen = float64(total) / float64(len(tools))
+	}
+	if idx.embedder != nil && len(texts) > 0 {
+		vectors, err := idx.embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range idx.docs {
+			if i < len(vectors) {
+				idx.docs[i].vector = vectors[i]
+			}
+		}
+	}
+	return idx, nil
+}
+
+// Search returns up to limit tools passing keep, best match first. Tools
+// with no relevance to the query are omitted. keep may be nil.
+func (idx *Index) Search(ctx context.Context, query string, limit int, keep func(*registry.ToolDescriptor) bool) ([]Result, error) {
+	terms := tokenize(query)
+	var qvec []float32
+	if idx.embedder != nil {
+		vs, err := idx.embedder.Embed(ctx, []string{query})
+		if err != nil {
+			return nil, err
+		}
+		if len(vs) > 0 {
+			qvec = vs[0]
+		}
+	}
+
+	lexical := make([]float64, len(idx.docs))
+	maxLexical := 0.0
+	for i, d := range idx.docs {
+		lexical[i] = idx.bm25(d, terms)
+		maxLexical = math.Max(maxLexical, lexical[i])
+	}
+
+	var results []Result
+	for i, d := range idx.docs {
+		if keep != nil && !keep(d.tool) {
+			continue
+		}
+		score := lexical[i]
+		if qvec != nil && d.vector != nil {
+			if maxLexical > 0 {
+				score /= maxLexical
+			}
+			score = (1-idx.weight)*score + idx.weight*cosine(qvec, d.vector)
+		}
+		if score <= 0 {
+			continue
+		}
+		results = append(results, Result{Tool: d.tool, Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (idx *Index) bm25(d document, terms []string) float64 {
+	n := float64(len(idx.docs))
+	score := 0.0
+	for _, term := range terms {
+		tf := float64(d.terms[term])
+		if tf == 0 {
+			continue
+		}
+		df := float64(idx.df[term])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := 1 - b + b*float64(d.length)/idx.avgLen
+		score += idf * tf * (k1 + 1) / (tf + k1*norm)
+	}
+	return score
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// tokenize lowercases text and splits it into words, breaking identifiers
+// such as "listOpenIssues" and "list_open_issues" into their parts.
+func tokenize(text string) []string {
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 1 || len(cur) == 1 && unicode.IsDigit(cur[0]) {
+			tokens = append(tokens, string(cur))
+		}
+		cur = cur[:0]
+	}
+	var prev rune
+	for _, r := range text {
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			cur = append(cur, unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur = append(cur, unicode.ToLower(r))
+		default:
+			flush()
+		}
+		prev = r
+	}
+	flush()
+	return tokens
+}
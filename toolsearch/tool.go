@@ -0,0 +1,73 @@
+package toolsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// ToolName is the name of the search meta-tool.
+const ToolName = "tools.search"
+
+// SearchArgs are the arguments of the search meta-tool.
+type SearchArgs struct {
+	Query string `json:"query" description:"What the tool should do, in plain words." validate:"nonzero"`
+	Limit int    `json:"limit" description:"Maximum number of tools to return." default:"5"`
+}
+
+// Match is a tool returned by the search meta-tool.
+type Match struct {
+	protocol.Tool
+	Score float64 `json:"score"`
+}
+
+// Register adds the tools.search meta-tool to s. The index is built from
+// the server's registry on the first search, so Register may be called
+// before the other tools are registered. Results respect the caller's
+// tool profile, and the meta-tool never returns itself.
+func Register(s *mcp.Server, opts Options) error {
+	var (
+		once  sync.Once
+		index *Index
+		err   error
+	)
+	desc := registry.ToolDescriptor{
+		Name:        ToolName,
+		Description: "Find the tools most relevant to a task. Returns tool names, descriptions and input schemas ranked by relevance.",
+		Tags:        []string{"meta"},
+	}
+	return mcp.RegisterToolTyped(s, desc, func(ctx *runtime.Context, args SearchArgs) (*protocol.ToolCallResult, error) {
+		once.Do(func() { index, err = Build(ctx, s.Registry().Tools(), opts) })
+		if err != nil {
+			return nil, fmt.Errorf("build tool index: %w", err)
+		}
+		var filter *protocol.ToolFilter
+		if sess := ctx.Session(); sess != nil {
+			filter = sess.ToolFilter()
+		}
+		results, err := index.Search(ctx, args.Query, args.Limit, func(d *registry.ToolDescriptor) bool {
+			return d.Name != ToolName && d.Matches(filter)
+		})
+		if err != nil {
+			return nil, err
+		}
+		locale := ctx.Locale()
+		matches := make([]Match, 0, len(results))
+		for _, r := range results {
+			t := r.Tool.Tool()
+			t.Name = r.Tool.Key()
+			t.Description = r.Tool.DescriptionFor(locale)
+			matches = append(matches, Match{Tool: t, Score: r.Score})
+		}
+		data, err := json.Marshal(map[string]interface{}{"tools": matches})
+		if err != nil {
+			return nil, err
+		}
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+	})
+}
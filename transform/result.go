@@ -0,0 +1,121 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Text returns a result transform replacing the text of every text item
+// with what fn returns for it. Error results are left alone.
+func Text(fn func(ctx *runtime.Context, text string) (string, error)) registry.ResultTransform {
+	return func(ctx context.Context, res *protocol.ToolCallResult) (*protocol.ToolCallResult, error) {
+		if res.IsError {
+			return res, nil
+		}
+		rc := runtime.FromContext(ctx)
+		for i, c := range res.Content {
+			if c.Type != "text" {
+				continue
+			}
+			text, err := fn(rc, c.Text)
+			if err != nil {
+				return nil, err
+			}
+			res.Content[i].Text = text
+		}
+		return res, nil
+	}
+}
+
+// JSON returns a result transform replacing the value of every text item
+// holding JSON with what fn returns for it, for instance to convert units.
+// Numbers reach fn as json.Number. Other text items and error results are
+// left alone.
+func JSON(fn func(ctx *runtime.Context, v interface{}) (interface{}, error)) registry.ResultTransform {
+	return Text(func(ctx *runtime.Context, text string) (string, error) {
+		var v interface{}
+		if err := runtime.DecodeJSON([]byte(text), &v, true); err != nil {
+			return text, nil
+		}
+		out, err := fn(ctx, v)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	})
+}
+
+// Table returns a result transform rendering text items that hold a JSON
+// array of objects as Markdown tables, with a column per member in order
+// of name. Other text items and error results are left alone.
+func Table() registry.ResultTransform {
+	return Text(func(ctx *runtime.Context, text string) (string, error) {
+		var rows []map[string]interface{}
+		if err := runtime.DecodeJSON([]byte(text), &rows, true); err != nil || len(rows) == 0 {
+			return text, nil
+		}
+		seen := map[string]bool{}
+		var cols []string
+		for _, row := range rows {
+			for k := range row {
+				if !seen[k] {
+					seen[k] = true
+					cols = append(cols, k)
+				}
+			}
+		}
+		sort.Strings(cols)
+		var b strings.Builder
+		writeRow(&b, cols)
+		b.WriteString("|")
+		b.WriteString(strings.Repeat(" --- |", len(cols)))
+		b.WriteString("\n")
+		cells := make([]string, len(cols))
+		for _, row := range rows {
+			for i, col := range cols {
+				cells[i] = cell(row[col])
+			}
+			writeRow(&b, cells)
+		}
+		return b.String(), nil
+	})
+}
+
+func writeRow(b *strings.Builder, cells []string) {
+	b.WriteString("|")
+	for _, c := range cells {
+		b.WriteString(" ")
+		b.WriteString(c)
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+}
+
+// cell renders v for a table cell, escaping what would break the row.
+func cell(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	case json.Number, bool:
+		s = fmt.Sprint(v)
+	default:
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
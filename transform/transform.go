@@ -0,0 +1,105 @@
+// Package transform builds the common kinds of registry.ArgumentTransform
+// and registry.ResultTransform, so that enrichment and formatting shared by
+// several tools are declared once instead of repeated in every handler:
+//
+//	registry.ToolDescriptor{
+//		Transforms: []registry.ArgumentTransform{
+//			transform.Set("tenant_id", func(ctx *runtime.Context) (interface{}, error) { return ctx.Tenant(), nil }),
+//			transform.Map("path", func(ctx *runtime.Context, v interface{}) (interface{}, error) { ... }),
+//		},
+//		ResultTransforms: []registry.ResultTransform{transform.Table()},
+//		...
+//	}
+//
+// The argument transforms address the top-level members of the argument
+// object. Numbers pass through them with every digit kept.
+package transform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Func returns a transform applying fn to the decoded argument object.
+// fn may change args in place.
+func Func(fn func(ctx *runtime.Context, args map[string]interface{}) error) registry.ArgumentTransform {
+	return func(ctx context.Context, raw json.RawMessage) (json.RawMessage, error) {
+		args := map[string]interface{}{}
+		if len(bytes.TrimSpace(raw)) > 0 && string(raw) != "null" {
+			if err := runtime.DecodeJSON(raw, &args, true); err != nil {
+				return nil, errors.New("arguments must be an object")
+			}
+		}
+		if err := fn(runtime.FromContext(ctx), args); err != nil {
+			return nil, err
+		}
+		return json.Marshal(args)
+	}
+}
+
+// Set returns a transform setting the member name to the value returned
+// by value, whatever the caller sent. It suits values the caller must not
+// choose, such as its tenant or user ID.
+func Set(name string, value func(ctx *runtime.Context) (interface{}, error)) registry.ArgumentTransform {
+	return Func(func(ctx *runtime.Context, args map[string]interface{}) error {
+		v, err := value(ctx)
+		if err != nil {
+			return err
+		}
+		args[name] = v
+		return nil
+	})
+}
+
+// Default returns a transform setting the member name to value when the
+// caller left it out.
+func Default(name string, value interface{}) registry.ArgumentTransform {
+	return Func(func(ctx *runtime.Context, args map[string]interface{}) error {
+		if _, ok := args[name]; !ok {
+			args[name] = value
+		}
+		return nil
+	})
+}
+
+// Map returns a transform replacing the member name, when present, with
+// what fn returns for it, for instance to resolve a friendly name into an
+// internal ID or to normalize a path. Errors fn returns are reported
+// against the member.
+func Map(name string, fn func(ctx *runtime.Context, v interface{}) (interface{}, error)) registry.ArgumentTransform {
+	return Func(func(ctx *runtime.Context, args map[string]interface{}) error {
+		v, ok := args[name]
+		if !ok {
+			return nil
+		}
+		out, err := fn(ctx, v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		args[name] = out
+		return nil
+	})
+}
+
+// Rename returns a transform moving the member from to the member to, so
+// that a tool keeps accepting an argument under its former name. A value
+// sent under the new name wins.
+func Rename(from, to string) registry.ArgumentTransform {
+	return Func(func(ctx *runtime.Context, args map[string]interface{}) error {
+		v, ok := args[from]
+		if !ok {
+			return nil
+		}
+		delete(args, from)
+		if _, exists := args[to]; !exists {
+			args[to] = v
+		}
+		return nil
+	})
+}
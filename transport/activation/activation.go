@@ -0,0 +1,87 @@
+// Package activation retrieves listening sockets handed to the process by
+// its supervisor, such as systemd socket activation or file descriptors
+// passed by a container runtime.
+package activation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// ErrNotActivated is returned when the process was not socket activated.
+var ErrNotActivated = errors.New("activation: no sockets passed by the service manager")
+
+// Listeners returns the listeners passed through systemd socket activation
+// (LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES), keyed by their FileDescriptorName.
+// Unnamed sockets are keyed "unknown" as systemd does, so callers expecting
+// several sockets should name them. The environment variables are unset so
+// child processes do not inherit them.
+func Listeners() (map[string][]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, ErrNotActivated
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, ErrNotActivated
+	}
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	out := make(map[string][]net.Listener, n)
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		ln, err := FileListener(uintptr(listenFDsStart+i), name)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = append(out[name], ln)
+	}
+	return out, nil
+}
+
+// Listener returns the single activated listener named name.
+func Listener(name string) (net.Listener, error) {
+	all, err := Listeners()
+	if err != nil {
+		return nil, err
+	}
+	lns := all[name]
+	if len(lns) != 1 {
+		return nil, fmt.Errorf("activation: expected one socket named %q, got %d", name, len(lns))
+	}
+	return lns[0], nil
+}
+
+// FileListener wraps an inherited listening file descriptor, for example
+// one whose number was passed in an environment variable or flag.
+func FileListener(fd uintptr, name string) (net.Listener, error) {
+	f := os.NewFile(fd, name)
+	if f == nil {
+		return nil, fmt.Errorf("activation: invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("activation: fd %d (%s): %w", fd, name, err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,225 @@
+// Package chaos injects faults into transports for testing.
+//
+// Wrap decorates any transport.Transport at the message level: frames are
+// delayed, dropped, delivered as malformed, or the connection is cut.
+// WrapListener does the same at the byte level for stream transports such
+// as tcp, where outgoing frames can also be truncated mid-write. Neither is
+// meant for production use.
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// Config selects the faults to inject. Rates are probabilities between 0
+// and 1, evaluated independently for every frame.
+type Config struct {
+	// Latency delays every frame in both directions.
+	Latency time.Duration
+	// Jitter adds a uniformly random delay of up to Jitter.
+	Jitter time.Duration
+	// DropRate is the probability that a frame is silently discarded.
+	DropRate float64
+	// TruncateRate is the probability that a frame is cut short. Incoming
+	// frames then fail to parse; with WrapListener, outgoing frames are
+	// written partially before the connection is closed.
+	TruncateRate float64
+	// DisconnectRate is the probability that the connection is closed
+	// instead of transferring a frame.
+	DisconnectRate float64
+	// Seed makes the injected faults reproducible. Zero seeds from the
+	// clock.
+	Seed int64
+	// Clock times latency and jitter. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// faults draws fault decisions from a shared random source.
+type faults struct {
+	cfg Config
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newFaults(cfg Config) *faults {
+	cfg.Clock = clock.Or(cfg.Clock)
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = cfg.Clock.Now().UnixNano()
+	}
+	return &faults{cfg: cfg, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// hit reports whether an event of probability p occurs.
+func (f *faults) hit(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Float64() < p
+}
+
+// intn returns a random integer in [0, n).
+func (f *faults) intn(n int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Intn(n)
+}
+
+// delay sleeps for the configured latency or until ctx is done.
+func (f *faults) delay(ctx context.Context) error {
+	d := f.cfg.Latency
+	if f.cfg.Jitter > 0 {
+		f.mu.Lock()
+		d += time.Duration(f.rnd.Int63n(int64(f.cfg.Jitter) + 1))
+		f.mu.Unlock()
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := f.cfg.Clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wrap returns a transport whose connections inject the faults in cfg.
+func Wrap(t transport.Transport, cfg Config) transport.Transport {
+	return &chaosTransport{Transport: t, faults: newFaults(cfg)}
+}
+
+type chaosTransport struct {
+	transport.Transport
+	faults *faults
+}
+
+// Accept implements transport.Transport.
+func (t *chaosTransport) Accept(ctx context.Context) (transport.Connection, error) {
+	c, err := t.Transport.Accept(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Connection: c, faults: t.faults}, nil
+}
+
+type conn struct {
+	transport.Connection
+	faults *faults
+}
+
+func (c *conn) Read(ctx context.Context) (*protocol.Message, error) {
+	for {
+		msg, err := c.Connection.Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.faults.delay(ctx); err != nil {
+			return nil, err
+		}
+		switch {
+		case c.faults.hit(c.faults.cfg.DisconnectRate):
+			c.Close()
+			return nil, transport.ErrClosed
+		case c.faults.hit(c.faults.cfg.DropRate):
+			continue
+		case c.faults.hit(c.faults.cfg.TruncateRate):
+			return nil, protocol.NewError(protocol.ParseError, "parse error: unexpected end of JSON input (injected)", nil)
+		}
+		return msg, nil
+	}
+}
+
+func (c *conn) Write(ctx context.Context, msg *protocol.Message) error {
+	if err := c.faults.delay(ctx); err != nil {
+		return err
+	}
+	switch {
+	case c.faults.hit(c.faults.cfg.DisconnectRate):
+		c.Close()
+		return transport.ErrClosed
+	case c.faults.hit(c.faults.cfg.DropRate):
+		return nil
+	}
+	return c.Connection.Write(ctx, msg)
+}
+
+// Bytes implements transport.ByteCounter when the wrapped connection does.
+func (c *conn) Bytes() (read, written int64) {
+	if bc, ok := c.Connection.(transport.ByteCounter); ok {
+		return bc.Bytes()
+	}
+	return 0, 0
+}
+
+// RequestScoped implements transport.RequestScoped.
+func (c *conn) RequestScoped() bool {
+	rs, ok := c.Connection.(transport.RequestScoped)
+	return ok && rs.RequestScoped()
+}
+
+// WrapListener returns a listener whose connections inject the faults in
+// cfg into the byte stream. Each Read and Write call counts as a frame.
+// Pass it to a stream transport, for example with tcp.WithListener.
+func WrapListener(ln net.Listener, cfg Config) net.Listener {
+	return &listener{Listener: ln, faults: newFaults(cfg)}
+}
+
+type listener struct {
+	net.Listener
+	faults *faults
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &netConn{Conn: c, faults: l.faults}, nil
+}
+
+type netConn struct {
+	net.Conn
+	faults *faults
+}
+
+func (c *netConn) Read(p []byte) (int, error) {
+	if err := c.faults.delay(context.Background()); err != nil {
+		return 0, err
+	}
+	if c.faults.hit(c.faults.cfg.DisconnectRate) {
+		c.Close()
+		return 0, net.ErrClosed
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *netConn) Write(p []byte) (int, error) {
+	if err := c.faults.delay(context.Background()); err != nil {
+		return 0, err
+	}
+	switch {
+	case c.faults.hit(c.faults.cfg.DisconnectRate):
+		c.Close()
+		return 0, net.ErrClosed
+	case c.faults.hit(c.faults.cfg.DropRate):
+		return len(p), nil
+	case len(p) > 1 && c.faults.hit(c.faults.cfg.TruncateRate):
+		n, _ := c.Conn.Write(p[:c.faults.intn(len(p)-1)+1])
+		c.Close()
+		return n, net.ErrClosed
+	}
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,54 @@
+package chaos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/chaos"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+)
+
+func TestLatency(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	in := inproc.New()
+	tr := chaos.Wrap(in, chaos.Config{Latency: time.Second, Clock: fake})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	accepted := make(chan transport.Connection, 1)
+	go func() {
+		c, _ := tr.Accept(ctx)
+		accepted <- c
+	}()
+	client, err := in.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.Fatal("accept failed")
+	}
+
+	msg, err := protocol.NewNotification(protocol.MethodInitialized, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan error, 1)
+	go func() { written <- server.Write(ctx, msg) }()
+	fake.BlockUntil(1)
+	select {
+	case err := <-written:
+		t.Fatalf("write done before the latency passed: %v", err)
+	default:
+	}
+	fake.Advance(time.Second)
+	if _, err := client.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+}
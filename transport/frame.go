@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"strconv"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// FrameError is returned by Read for a frame that was received but could
+// not be parsed. It unwraps to the ParseError sent back to the client and
+// keeps the frame for diagnostics.
+type FrameError struct {
+	// Frame holds the received bytes. Transports that do not buffer
+	// messages may keep only a prefix of the frame.
+	Frame []byte
+	// Size is the length of the whole frame.
+	Size int64
+	Err  *protocol.Error
+}
+
+func (e *FrameError) Error() string { return e.Err.Error() }
+
+func (e *FrameError) Unwrap() error { return e.Err }
+
+// FrameReporter is implemented by transports that answer malformed frames
+// themselves instead of passing them on through Read, such as HTTP, which
+// rejects a malformed request body before a connection exists.
+type FrameReporter interface {
+	// ReportMalformedFrames arranges for report to be called with every
+	// frame the transport rejects, keeping at least the first n bytes of
+	// each. It must be called before Listen.
+	ReportMalformedFrames(n int, report func(Peer, *FrameError))
+}
+
+// Preview renders at most the first n bytes of frame for a log record. The
+// bytes are quoted with Go escaping, so that binary data and invalid UTF-8
+// stay readable, and a cut-off frame ends in an ellipsis.
+func Preview(frame []byte, n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if len(frame) <= n {
+		return strconv.Quote(string(frame))
+	}
+	return strconv.Quote(string(frame[:n])) + "..."
+}
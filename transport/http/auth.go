@@ -0,0 +1,32 @@
+package http
+
+import (
+	nethttp "net/http"
+
+	"github.com/hyperleex/zenmcp/auth"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// WithAuthenticator authenticates every request with a. Requests it
+// rejects are answered with 401 Unauthorized; the principal of accepted
+// ones is attached to the connection's peer.
+func WithAuthenticator(a auth.Authenticator) Option {
+	return func(t *Transport) { t.authenticate = a }
+}
+
+// authenticateRequest runs the authenticator, if any, recording the
+// principal in peer. It writes a 401 response and returns false when the
+// request is rejected.
+func (t *Transport) authenticateRequest(w nethttp.ResponseWriter, r *nethttp.Request, peer *transport.Peer) bool {
+	if t.authenticate == nil {
+		return true
+	}
+	p, err := t.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, nethttp.StatusUnauthorized, "unauthorized")
+		return false
+	}
+	peer.Principal = p
+	return true
+}
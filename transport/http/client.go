@@ -0,0 +1,227 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	nethttp "net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// ClientOptions configures Dial.
+type ClientOptions struct {
+	// Client sends the HTTP requests. Defaults to http.DefaultClient.
+	Client *nethttp.Client
+	// Header is added to every request, for instance to carry an
+	// Authorization header.
+	Header nethttp.Header
+}
+
+// ClientConn is the client end of the HTTP transport, suitable for
+// mcp.NewClient. Every message is POSTed to the endpoint; the response
+// arrives as a JSON body or an SSE stream and is returned by Read.
+//
+// When the server assigns a session, the connection sends its ID with
+// every request, opens a GET stream for messages the server sends outside
+// any request and ends the session with DELETE on Close.
+type ClientConn struct {
+	endpoint string
+	client   *nethttp.Client
+	header   nethttp.Header
+
+	inbox  chan *protocol.Message
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	session string
+	once    sync.Once
+}
+
+// Dial returns a connection to the MCP endpoint at url. No request is
+// made until the first message is written.
+func Dial(url string, opts ClientOptions) *ClientConn {
+	if opts.Client == nil {
+		opts.Client = nethttp.DefaultClient
+	}
+	c := &ClientConn{
+		endpoint: url,
+		client:   opts.Client,
+		header:   opts.Header,
+		inbox:    make(chan *protocol.Message, 16),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+// SessionID returns the session assigned by the server, or "".
+func (c *ClientConn) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+// Read returns the next message received from the server.
+func (c *ClientConn) Read(ctx context.Context) (*protocol.Message, error) {
+	select {
+	case msg := <-c.inbox:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, io.EOF
+	}
+}
+
+// Write POSTs msg and waits for the response headers. A JSON response is
+// queued for Read before Write returns; an SSE stream is read in the
+// background for as long as ctx allows. Write is safe for concurrent use.
+func (c *ClientConn) Write(ctx context.Context, msg *protocol.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, nethttp.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaJSON)
+	req.Header.Set("Accept", mediaJSON+", "+mediaSSE)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http transport: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return responseError(resp)
+	}
+	c.setSession(resp.Header.Get(SessionHeader))
+	if resp.StatusCode == nethttp.StatusAccepted {
+		resp.Body.Close()
+		return nil
+	}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mt == mediaSSE {
+		go c.readEvents(resp.Body)
+		return nil
+	}
+	defer resp.Body.Close()
+	var reply protocol.Message
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("http transport: decoding response: %w", err)
+	}
+	c.deliver(&reply)
+	return nil
+}
+
+// Close ends the session, if any, and stops the connection.
+func (c *ClientConn) Close() error {
+	if id := c.SessionID(); id != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if req, err := c.newRequest(ctx, nethttp.MethodDelete, nil); err == nil {
+			if resp, err := c.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	c.cancel()
+	return nil
+}
+
+func (c *ClientConn) newRequest(ctx context.Context, method string, body io.Reader) (*nethttp.Request, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, method, c.endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if id := c.SessionID(); id != "" {
+		req.Header.Set(SessionHeader, id)
+	}
+	return req, nil
+}
+
+// setSession records the session ID from the first response carrying one
+// and opens the GET stream for it.
+func (c *ClientConn) setSession(id string) {
+	if id == "" {
+		return
+	}
+	c.mu.Lock()
+	if c.session == "" {
+		c.session = id
+	}
+	c.mu.Unlock()
+	c.once.Do(func() { go c.listen() })
+}
+
+// listen reads the session's GET stream until the connection closes. A
+// server that does not offer one is left alone.
+func (c *ClientConn) listen() {
+	req, err := c.newRequest(c.ctx, nethttp.MethodGet, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", mediaSSE)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != nethttp.StatusOK {
+		resp.Body.Close()
+		return
+	}
+	c.readEvents(resp.Body)
+}
+
+// readEvents queues the messages carried by an SSE stream.
+func (c *ClientConn) readEvents(body io.ReadCloser) {
+	defer body.Close()
+	r := bufio.NewReader(body)
+	var data []string
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && len(data) > 0:
+			var msg protocol.Message
+			if json.Unmarshal([]byte(strings.Join(data, "\n")), &msg) == nil {
+				c.deliver(&msg)
+			}
+			data = data[:0]
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *ClientConn) deliver(msg *protocol.Message) {
+	select {
+	case c.inbox <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+// responseError describes a failed HTTP exchange, keeping the JSON-RPC
+// error the transport puts in the body when there is one.
+func responseError(resp *nethttp.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var msg protocol.Message
+	if json.Unmarshal(body, &msg) == nil && msg.Error != nil {
+		return fmt.Errorf("http transport: %s: %w", resp.Status, msg.Error)
+	}
+	return fmt.Errorf("http transport: %s", resp.Status)
+}
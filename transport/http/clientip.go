@@ -0,0 +1,56 @@
+package http
+
+import (
+	nethttp "net/http"
+	"net/netip"
+
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// WithAllowedCIDRs admits only clients whose address is in one of
+// prefixes. Other clients receive 403 Forbidden.
+func WithAllowedCIDRs(prefixes ...netip.Prefix) Option {
+	return func(t *Transport) { t.ipFilter.Allow = append(t.ipFilter.Allow, prefixes...) }
+}
+
+// WithDeniedCIDRs rejects clients whose address is in one of prefixes with
+// 403 Forbidden. Denials take precedence over WithAllowedCIDRs.
+func WithDeniedCIDRs(prefixes ...netip.Prefix) Option {
+	return func(t *Transport) { t.ipFilter.Deny = append(t.ipFilter.Deny, prefixes...) }
+}
+
+// WithTrustedProxies trusts X-Forwarded-For headers set by reverse proxies
+// in prefixes, so that peers, IP filtering and logs use the originating
+// client address rather than the proxy's.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(t *Transport) { t.trustedProxies = append(t.trustedProxies, prefixes...) }
+}
+
+// peer describes the client of r, resolving its address through trusted
+// proxies.
+func (t *Transport) peer(r *nethttp.Request) (transport.Peer, netip.Addr) {
+	p := transport.Peer{
+		Transport:  "http",
+		RemoteAddr: r.RemoteAddr,
+		TLS:        r.TLS,
+		UserAgent:  r.UserAgent(),
+		Locale:     preferredLanguage(r.Header.Get("Accept-Language")),
+	}
+	remote := transport.AddrFromString(r.RemoteAddr)
+	client := transport.ClientIP(remote, r.Header.Values("X-Forwarded-For"), t.trustedProxies)
+	if client != remote {
+		p.Proxy = r.RemoteAddr
+		p.RemoteAddr = client.String()
+	}
+	return p, client
+}
+
+// checkClient reports whether the client of r passes the IP filter,
+// writing a 403 response when it does not.
+func (t *Transport) checkClient(w nethttp.ResponseWriter, client netip.Addr) bool {
+	if t.ipFilter.Allowed(client) {
+		return true
+	}
+	writeError(w, nethttp.StatusForbidden, "client address not allowed")
+	return false
+}
@@ -0,0 +1,318 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// CompressionOptions configures WithAdaptiveCompression.
+type CompressionOptions struct {
+	// SlowRTT is the round-trip time from which a session's link counts
+	// as slow, and its responses are compressed whatever their size.
+	// Defaults to 150ms.
+	SlowRTT time.Duration
+	// LargeBody is the size from which JSON response bodies are
+	// compressed whatever the link. Defaults to 64 KiB.
+	LargeBody int
+	// MinBody is the size below which JSON response bodies are never
+	// compressed. Defaults to codec.DefaultCompressThreshold.
+	MinBody int
+	// PingInterval is how often sessions measure their round-trip time
+	// by sending ping on their GET stream. Defaults to 30 seconds; a
+	// negative value turns the pings off, leaving the size rule only.
+	// Pings are scheduled and timed with SessionOptions.Clock.
+	PingInterval time.Duration
+	// Level is the gzip compression level. Defaults to
+	// gzip.DefaultCompression.
+	Level int
+	// Metrics receives the compression decisions and round-trip times.
+	// Defaults to metrics.Default.
+	Metrics *metrics.Registry
+}
+
+// WithAdaptiveCompression compresses responses with gzip, for clients
+// accepting it, where compression pays: JSON bodies from opts.LargeBody
+// bytes, and every response but the smallest on links slower than
+// opts.SlowRTT. SSE streams, whose size is not known in advance, are
+// compressed on slow links only.
+//
+// The round-trip time of a session is measured with pings the transport
+// sends on its GET stream and answers to which it keeps from the server.
+// Connections without sessions, or whose client has no GET stream open,
+// are never known to be slow.
+//
+// The decisions taken are counted in zenmcp_http_compression_total,
+// labelled with the decision and its reason.
+func WithAdaptiveCompression(opts CompressionOptions) Option {
+	if opts.SlowRTT <= 0 {
+		opts.SlowRTT = 150 * time.Millisecond
+	}
+	if opts.LargeBody <= 0 {
+		opts.LargeBody = 64 << 10
+	}
+	if opts.MinBody <= 0 {
+		opts.MinBody = codec.DefaultCompressThreshold
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.Default
+	}
+	return func(t *Transport) { t.compress = newCompressor(opts) }
+}
+
+// Reasons for a compression decision, as recorded in metrics.
+const (
+	reasonNotAccepted = "not_accepted"
+	reasonSmall       = "small_body"
+	reasonLarge       = "large_body"
+	reasonSlowLink    = "slow_link"
+	reasonFastLink    = "fast_link"
+	reasonUnmeasured  = "unmeasured"
+)
+
+// compressor decides which responses to compress and compresses them. A
+// nil compressor compresses nothing.
+type compressor struct {
+	opts CompressionOptions
+	pool sync.Pool
+
+	decisions metrics.CounterVec
+	saved     metrics.Counter
+	rtt       metrics.Histogram
+}
+
+func newCompressor(opts CompressionOptions) *compressor {
+	reg := opts.Metrics
+	return &compressor{
+		opts: opts,
+		decisions: reg.CounterVec("zenmcp_http_compression_total",
+			"Responses compressed or sent plain, by decision and reason.", "decision", "reason"),
+		saved: reg.Counter("zenmcp_http_compression_saved_bytes_total",
+			"Bytes saved by compressing JSON response bodies."),
+		rtt: reg.Histogram("zenmcp_http_rtt_seconds",
+			"Round-trip times of the pings measuring session links.", metrics.ExponentialBuckets(0.001, 2, 14)),
+	}
+}
+
+// decide reports whether to compress a response of size bytes, or of
+// unknown size when size is negative, to a client on a link with the
+// given round-trip time, zero when unmeasured.
+func (c *compressor) decide(accept acceptance, size int, rtt time.Duration) bool {
+	var reason string
+	compress := false
+	switch {
+	case !accept.gzip:
+		reason = reasonNotAccepted
+	case size >= 0 && size < c.opts.MinBody:
+		reason = reasonSmall
+	case size >= c.opts.LargeBody:
+		reason, compress = reasonLarge, true
+	case rtt >= c.opts.SlowRTT:
+		reason, compress = reasonSlowLink, true
+	case rtt > 0:
+		reason = reasonFastLink
+	default:
+		reason = reasonUnmeasured
+	}
+	decision := "plain"
+	if compress {
+		decision = "compressed"
+	}
+	c.decisions.With(decision, reason).Inc()
+	return compress
+}
+
+func (c *compressor) writer(w *bytes.Buffer) *gzip.Writer {
+	if gz, ok := c.pool.Get().(*gzip.Writer); ok {
+		gz.Reset(w)
+		return gz
+	}
+	gz, err := gzip.NewWriterLevel(w, c.opts.Level)
+	if err != nil {
+		gz = gzip.NewWriter(w)
+	}
+	return gz
+}
+
+// writeJSON writes msg as the whole response body, as the package's
+// writeJSON does, compressing it if c so decides.
+func (c *compressor) writeJSON(w nethttp.ResponseWriter, status int, msg *protocol.Message, accept acceptance, rtt time.Duration) (int, error) {
+	if c == nil {
+		return writeJSON(w, status, msg)
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	data = append(data, '\n')
+	h := w.Header()
+	h.Set("Content-Type", mediaJSON)
+	h.Add("Vary", "Accept-Encoding")
+	if c.decide(accept, len(data), rtt) {
+		var buf bytes.Buffer
+		gz := c.writer(&buf)
+		_, err := gz.Write(data)
+		if err == nil {
+			err = gz.Close()
+		}
+		c.pool.Put(gz)
+		if err == nil && buf.Len() < len(data) {
+			c.saved.Add(float64(len(data) - buf.Len()))
+			h.Set("Content-Encoding", "gzip")
+			data = buf.Bytes()
+		}
+	}
+	w.WriteHeader(status)
+	return w.Write(data)
+}
+
+// startSSE starts an SSE response on w, as the package's startSSE does,
+// and returns the writer to write its events to: w itself, or a
+// gzipResponse when c decides to compress the stream.
+func (c *compressor) startSSE(w nethttp.ResponseWriter, accept acceptance, rtt time.Duration) nethttp.ResponseWriter {
+	if c == nil {
+		startSSE(w)
+		return w
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !c.decide(accept, -1, rtt) {
+		startSSE(w)
+		return w
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	gz, err := gzip.NewWriterLevel(w, c.opts.Level)
+	if err != nil {
+		gz = gzip.NewWriter(w)
+	}
+	zw := &gzipResponse{ResponseWriter: w, gz: gz}
+	startSSE(zw)
+	return zw
+}
+
+// gzipResponse compresses an SSE stream. Flushing it flushes the gzip
+// stream, so that every event reaches the client as it is written.
+type gzipResponse struct {
+	nethttp.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (w *gzipResponse) Write(p []byte) (int, error) { return w.gz.Write(p) }
+
+func (w *gzipResponse) FlushError() error {
+	if err := w.gz.Flush(); err != nil {
+		return err
+	}
+	return nethttp.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// finishResponse ends the gzip stream of w, if it is one. It must be
+// called before the handler of the response returns.
+func finishResponse(w nethttp.ResponseWriter) {
+	if zw, ok := w.(*gzipResponse); ok {
+		zw.gz.Close()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header accepts gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "x-gzip" && coding != "*" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// probePrefix starts the IDs of the pings measuring round-trip times, as
+// they appear on the wire.
+const probePrefix = `"zenmcp-rtt-`
+
+// probe sends ping on the session's GET stream to measure its round-trip
+// time, unless a ping is already awaiting its answer or the client has no
+// GET stream open. s.mu must be held.
+func (s *session) probe() {
+	c := s.tab.compress
+	if c == nil || s.get.sink == nil || !s.probeSent.IsZero() && clock.Since(s.tab.opts.Clock, s.probeSent) < c.opts.PingInterval {
+		return
+	}
+	id := protocol.NewStringID("zenmcp-rtt-" + s.tab.opts.IDs.NewID())
+	msg, err := protocol.NewRequest(id, protocol.MethodPing, nil)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	// The ping is not logged for replay: an answer arriving after a
+	// resumption would tell nothing of the link.
+	n, err := writeEvent(s.get.sink.w, "", data)
+	s.written.Add(int64(n))
+	if err != nil {
+		s.get.sink.close()
+		s.get.sink = nil
+		return
+	}
+	s.probeID, s.probeSent = id, s.tab.opts.Clock.Now()
+}
+
+// probeAnswered reports whether msg answers a ping sent by probe, and
+// records the round-trip time it measures. Answers to earlier pings are
+// recognized but tell nothing.
+func (s *session) probeAnswered(msg *protocol.Message) bool {
+	if !msg.IsResponse() || !strings.HasPrefix(msg.ID.String(), probePrefix) {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if *msg.ID != s.probeID || s.probeSent.IsZero() {
+		return true
+	}
+	sample := clock.Since(s.tab.opts.Clock, s.probeSent)
+	s.probeSent = time.Time{}
+	s.tab.compress.rtt.Observe(sample.Seconds())
+	// Smoothed as TCP smooths its round-trip time.
+	if s.srtt == 0 {
+		s.srtt = sample
+	} else {
+		s.srtt += (sample - s.srtt) / 8
+	}
+	return true
+}
+
+// ping probes the session's link and schedules the next probe.
+func (s *session) ping() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	s.probe()
+	s.pinger.Reset(s.tab.compress.opts.PingInterval)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/metrics"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// TestProbeClock checks that the round-trip time of a session is measured
+// on the sessions' clock.
+func TestProbeClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	tr := New("",
+		WithSessions(SessionOptions{IdleTimeout: time.Hour, Clock: fake}),
+		WithAdaptiveCompression(CompressionOptions{PingInterval: 30 * time.Second, Metrics: metrics.NewRegistry()}),
+	)
+	srv := httptest.NewServer(tr)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	serveOne(ctx, tr)
+	id := initialize(t, srv.URL+"/mcp")
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, srv.URL+"/mcp", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionHeader, id)
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != nethttp.StatusOK {
+		t.Fatalf("GET: status %d", resp.StatusCode)
+	}
+
+	// Opening the GET stream sends a first ping, and the pinger sends the
+	// next one after PingInterval.
+	sc := bufio.NewScanner(resp.Body)
+	answer := func(after time.Duration) {
+		t.Helper()
+		var ping protocol.Message
+		for ping.Method == "" {
+			if !sc.Scan() {
+				t.Fatalf("GET stream ended: %v", sc.Err())
+			}
+			if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+				if err := json.Unmarshal([]byte(data), &ping); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if ping.Method != protocol.MethodPing {
+			t.Fatalf("GET stream sent %s, want a ping", ping.Method)
+		}
+		fake.Advance(after)
+		res, _ := protocol.NewResult(ping.ID, struct{}{})
+		data, _ := json.Marshal(res)
+		if resp := post(t, srv.URL+"/mcp", id, string(data)); resp.StatusCode != nethttp.StatusAccepted {
+			t.Fatalf("answer: status %d", resp.StatusCode)
+		}
+	}
+	answer(200 * time.Millisecond)
+	fake.Advance(30*time.Second - 200*time.Millisecond)
+	answer(100 * time.Millisecond)
+
+	tr.sessions.mu.Lock()
+	s := tr.sessions.m[id]
+	tr.sessions.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 200ms smoothed with 100ms.
+	if want := 200*time.Millisecond - 100*time.Millisecond/8; s.srtt != want {
+		t.Errorf("srtt = %s, want %s", s.srtt, want)
+	}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// ErrStreamNotAccepted is returned when a handler emits a message ahead of
+// its response to a client that accepts only application/json, so the
+// message cannot be delivered.
+var ErrStreamNotAccepted = errors.New("http transport: client does not accept text/event-stream")
+
+type responseMode int
+
+const (
+	modeNone responseMode = iota
+	modeJSON
+	modeSSE
+)
+
+// conn is the connection for a single POST: it yields one message and
+// accepts its response. The response is sent as a single JSON body unless
+// the handler emits other messages first and the client accepts
+// text/event-stream, in which case the exchange becomes an SSE stream that
+// ends after the response.
+type conn struct {
+	msg    *protocol.Message
+	size   int64
+	w      nethttp.ResponseWriter
+	closed chan struct{}
+	// gone is closed when the client abandons the request.
+	gone     <-chan struct{}
+	peer     transport.Peer
+	accept   acceptance
+	compress *compressor
+
+	mu        sync.Mutex
+	read      bool
+	mode      responseMode
+	responded bool
+	finished  bool
+	written   int64
+	once      sync.Once
+}
+
+func (c *conn) Read(ctx context.Context) (*protocol.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.read {
+		return nil, io.EOF
+	}
+	c.read = true
+	return c.msg, nil
+}
+
+func (c *conn) Write(ctx context.Context, msg *protocol.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.gone:
+		return transport.ErrDisconnected
+	default:
+	}
+	if c.finished {
+		return transport.ErrClosed
+	}
+	if c.responded {
+		return errors.New("http transport: response already written")
+	}
+	final := msg.IsResponse()
+	if c.mode == modeNone {
+		if final && (c.accept.json || !c.accept.sse) {
+			c.mode = modeJSON
+			c.responded = true
+			n, err := c.compress.writeJSON(c.w, nethttp.StatusOK, msg, c.accept, 0)
+			c.written += int64(n)
+			return disconnected(err)
+		}
+		if !c.accept.sse {
+			return ErrStreamNotAccepted
+		}
+		c.mode = modeSSE
+		c.w = c.compress.startSSE(c.w, c.accept, 0)
+	}
+	c.responded = final
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	n, err := writeEvent(c.w, "", data)
+	c.written += int64(n)
+	return disconnected(err)
+}
+
+// disconnected marks an error writing to a response as the client having
+// gone away: the server can do nothing else about a failed write.
+func disconnected(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", transport.ErrDisconnected, err)
+}
+
+func (c *conn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *conn) Peer() transport.Peer { return c.peer }
+
+// RequestScoped implements transport.RequestScoped.
+func (c *conn) RequestScoped() bool { return true }
+
+// Bytes implements transport.ByteCounter. The request body counts as read
+// once the message has been consumed.
+func (c *conn) Bytes() (read, written int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.read {
+		read = c.size
+	}
+	return read, c.written
+}
+
+// finish completes the HTTP exchange. A message that produced no response
+// (a notification) is acknowledged with 202 Accepted.
+func (c *conn) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode == modeNone && !c.finished {
+		c.w.WriteHeader(nethttp.StatusAccepted)
+	}
+	if !c.finished {
+		finishResponse(c.w)
+	}
+	c.finished = true
+}
+
+// writeEvent writes the encoded message data as a single SSE event with
+// the given ID, if any, and flushes it, returning the number of bytes
+// written.
+func writeEvent(w nethttp.ResponseWriter, id string, data []byte) (int, error) {
+	buf := make([]byte, 0, len(data)+64)
+	if id != "" {
+		buf = append(buf, "id: "...)
+		buf = append(buf, id...)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, "event: message\ndata: "...)
+	buf = append(buf, data...)
+	buf = append(buf, "\n\n"...)
+	n, err := w.Write(buf)
+	if err != nil {
+		return n, err
+	}
+	return n, nethttp.NewResponseController(w).Flush()
+}
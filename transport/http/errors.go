@@ -0,0 +1,47 @@
+package http
+
+import (
+	nethttp "net/http"
+
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// errorData is attached to the error envelopes written for failures that
+// happen before a message reaches the server, so generic HTTP clients can
+// still parse them as JSON-RPC responses.
+type errorData struct {
+	Status int   `json:"status"`
+	Limit  int64 `json:"limit,omitempty"`
+}
+
+// writeError writes a JSON-RPC error envelope with a null id and the given
+// HTTP status.
+func writeError(w nethttp.ResponseWriter, status int, message string) {
+	writeErrorData(w, status, message, errorData{Status: status})
+}
+
+func writeErrorData(w nethttp.ResponseWriter, status int, message string, data errorData) {
+	code := protocol.InvalidRequest
+	if status >= 500 {
+		code = protocol.InternalError
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	writeJSON(w, status, protocol.NewErrorResponse(nil, protocol.NewError(code, message, data)))
+}
+
+// writeTooLarge rejects a body exceeding limit bytes.
+func writeTooLarge(w nethttp.ResponseWriter, limit int64) {
+	writeErrorData(w, nethttp.StatusRequestEntityTooLarge, "request body too large",
+		errorData{Status: nethttp.StatusRequestEntityTooLarge, Limit: limit})
+}
+
+// methodNotAllowed rejects a request whose method is not in allowed.
+func methodNotAllowed(w nethttp.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, nethttp.StatusMethodNotAllowed, "method not allowed")
+}
+
+// notFound answers requests outside the transport's endpoints.
+func notFound(w nethttp.ResponseWriter, r *nethttp.Request) {
+	writeError(w, nethttp.StatusNotFound, "not found")
+}
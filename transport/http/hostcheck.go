@@ -0,0 +1,127 @@
+package http
+
+import (
+	"net"
+	nethttp "net/http"
+	"net/url"
+	"strings"
+)
+
+// loopbackHosts are accepted by default when the transport listens on a
+// loopback address.
+var loopbackHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// WithAllowedHosts enables Host header validation, accepting only requests
+// addressed to one of hosts. Entries are host names or IPs, optionally with
+// a port ("example.com", "example.com:8443"); an entry without a port
+// matches any port.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(t *Transport) { t.allowedHosts = append(t.allowedHosts, hosts...) }
+}
+
+// WithAllowedOrigins enables Origin header validation, accepting only
+// requests without an Origin or whose Origin host is one of origins.
+// Entries use the same form as WithAllowedHosts.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(t *Transport) {
+		all := append(t.origins(), origins...)
+		t.allowedOrigins.Store(&all)
+	}
+}
+
+// SetAllowedOrigins replaces the accepted origins while the transport is
+// serving. An empty list turns Origin validation off, except on loopback
+// listeners, which fall back to the loopback-only default. It has no
+// effect if host validation is disabled.
+func (t *Transport) SetAllowedOrigins(origins ...string) {
+	all := append([]string(nil), origins...)
+	if len(all) == 0 && t.loopback {
+		all = loopbackHosts
+	}
+	t.allowedOrigins.Store(&all)
+}
+
+// AllowedOrigins returns the origins currently accepted.
+func (t *Transport) AllowedOrigins() []string {
+	return append([]string(nil), t.origins()...)
+}
+
+func (t *Transport) origins() []string {
+	if p := t.allowedOrigins.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// WithoutHostValidation disables the Host and Origin checks that are
+// otherwise enabled automatically for loopback listeners.
+func WithoutHostValidation() Option {
+	return func(t *Transport) { t.noHostCheck = true }
+}
+
+// configureHostCheck enables loopback-only Host and Origin validation when
+// the transport listens on a loopback address and no explicit lists were
+// given. A transport mounted on another server with ServeHTTP is
+// configured by its first request, from the address it arrived on. Locally running MCP servers are a known DNS rebinding target: a
+// malicious page can resolve its own name to 127.0.0.1 and drive the server
+// from the victim's browser.
+func (t *Transport) configureHostCheck(addr net.Addr) {
+	if t.noHostCheck || addr == nil {
+		return
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsLoopback() {
+		return
+	}
+	if len(t.allowedHosts) == 0 {
+		t.allowedHosts = loopbackHosts
+	}
+	t.loopback = true
+	if len(t.origins()) == 0 {
+		t.allowedOrigins.Store(&loopbackHosts)
+	}
+}
+
+// checkOrigin reports whether r passes Host and Origin validation, writing
+// a 403 response when it does not.
+func (t *Transport) checkOrigin(w nethttp.ResponseWriter, r *nethttp.Request) bool {
+	if t.noHostCheck {
+		return true
+	}
+	t.hostCheckOnce.Do(func() {
+		addr, _ := r.Context().Value(nethttp.LocalAddrContextKey).(net.Addr)
+		t.configureHostCheck(addr)
+	})
+	if len(t.allowedHosts) > 0 && !hostAllowed(r.Host, t.allowedHosts) {
+		writeError(w, nethttp.StatusForbidden, "host not allowed")
+		return false
+	}
+	if origin, allowed := r.Header.Get("Origin"), t.origins(); origin != "" && len(allowed) > 0 {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" || !hostAllowed(u.Host, allowed) {
+			writeError(w, nethttp.StatusForbidden, "origin not allowed")
+			return false
+		}
+	}
+	return true
+}
+
+// hostAllowed matches a host[:port] value against allowed entries.
+func hostAllowed(hostport string, allowed []string) bool {
+	hostport = strings.ToLower(hostport)
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == hostport {
+			return true
+		}
+		if _, _, err := net.SplitHostPort(a); err != nil && strings.Trim(a, "[]") == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHostCheckMounted checks that the DNS-rebinding protection of a
+// loopback listener also applies when the transport is mounted on another
+// server instead of listening itself.
+func TestHostCheckMounted(t *testing.T) {
+	srv := httptest.NewServer(New(""))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		host, origin string
+		forbidden    bool
+	}{
+		{"loopback", "", "", false},
+		{"localhost", "localhost", "http://localhost:3000", false},
+		{"rebound host", "evil.example", "", true},
+		{"foreign origin", "", "http://evil.example", true},
+	}
+	for _, tt := range tests {
+		req, err := nethttp.NewRequest(nethttp.MethodGet, srv.URL+"/mcp", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.StatusCode == nethttp.StatusForbidden; got != tt.forbidden {
+			t.Errorf("%s: status %d, forbidden = %v, want %v", tt.name, resp.StatusCode, got, tt.forbidden)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+// Package http implements the MCP Streamable HTTP transport: every POST
+// carries one JSON-RPC message and receives its response in the HTTP
+// response body, or as an SSE stream when the server sends other messages
+// first. By default each POST is a connection of its own; WithSessions
+// adds sessions, a GET stream for server-initiated messages, stream
+// resumption and session termination.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	nethttp "net/http"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/auth"
+	"github.com/hyperleex/zenmcp/blob"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// DefaultMaxBodySize bounds the size of a request body.
+const DefaultMaxBodySize = 4 << 20
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithMaxBodySize limits the number of bytes read from a request body.
+// Larger bodies, whether announced by Content-Length or sent chunked, are
+// rejected with 413 Request Entity Too Large.
+func WithMaxBodySize(n int64) Option {
+	return func(t *Transport) { t.maxBodySize = n }
+}
+
+// WithPath sets the URL path the MCP endpoint is served on. It defaults to
+// "/mcp".
+func WithPath(path string) Option {
+	return func(t *Transport) { t.path = path }
+}
+
+// WithUploads enables the uploads endpoint at "<path>/uploads". Clients
+// POST a raw payload there and receive a blob reference to pass as a tool
+// argument in place of inline base64 data. The size limits are enforced
+// by store: a blob over its limit is refused with 413, and an upload the
+// store has no room for (blob.ErrFull) with 507.
+func WithUploads(store blob.Store) Option {
+	return func(t *Transport) { t.uploads = store }
+}
+
+// WithListener makes the transport serve on l instead of binding its
+// address. Use it with listeners inherited through systemd socket
+// activation or file descriptors passed by a supervisor.
+func WithListener(l net.Listener) Option {
+	return func(t *Transport) { t.ln = l }
+}
+
+// WithUI serves h, such as a ui.Console, under /ui/. The page at /ui/
+// itself is served to every client that passes the address, host and
+// origin checks, so that a browser can load it; everything below it also
+// requires the authentication the MCP endpoint does.
+func WithUI(h nethttp.Handler) Option {
+	return func(t *Transport) { t.ui = h }
+}
+
+// Transport serves MCP over HTTP.
+type Transport struct {
+	addr        string
+	path        string
+	maxBodySize int64
+	uploads     blob.Store
+	ui          nethttp.Handler
+
+	allowedHosts   []string
+	allowedOrigins atomic.Pointer[[]string]
+	loopback       bool
+	noHostCheck    bool
+	// hostCheckOnce runs configureHostCheck, from Listen or, for a
+	// transport mounted on another server, from the first request.
+	hostCheckOnce  sync.Once
+	ipFilter       transport.IPFilter
+	trustedProxies []netip.Prefix
+	authenticate   auth.Authenticator
+	tenantPaths    bool
+	sessions       *sessionTable
+	compress       *compressor
+
+	framePreview int
+	reportFrame  func(transport.Peer, *transport.FrameError)
+
+	server *nethttp.Server
+	ln     net.Listener
+	conns  chan transport.Connection
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New returns a transport listening on addr once Listen is called.
+func New(addr string, opts ...Option) *Transport {
+	t := &Transport{
+		addr:        addr,
+		path:        "/mcp",
+		maxBodySize: DefaultMaxBodySize,
+		conns:       make(chan transport.Connection),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.sessions != nil {
+		t.sessions.compress = t.compress
+	}
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("/", notFound)
+	mux.Handle(t.path, t)
+	if t.tenantPaths {
+		mux.Handle(t.path+"/", t)
+	}
+	if t.uploads != nil {
+		mux.HandleFunc(t.path+"/uploads", t.serveUpload)
+	}
+	if t.ui != nil {
+		mux.HandleFunc("/ui/", t.serveUI)
+	}
+	t.server = &nethttp.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return t
+}
+
+// Listen binds the listening socket, unless a listener was supplied, and
+// starts serving HTTP.
+func (t *Transport) Listen(ctx context.Context) error {
+	if t.ln == nil {
+		var lc net.ListenConfig
+		ln, err := lc.Listen(ctx, "tcp", t.addr)
+		if err != nil {
+			return fmt.Errorf("http transport: %w", err)
+		}
+		t.ln = ln
+	}
+	t.hostCheckOnce.Do(func() { t.configureHostCheck(t.ln.Addr()) })
+	go func() {
+		if err := t.server.Serve(t.ln); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			t.Close()
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the transport is listening on, or nil before
+// Listen.
+func (t *Transport) Addr() net.Addr {
+	if t.ln == nil {
+		return nil
+	}
+	return t.ln.Addr()
+}
+
+// Accept returns the connection for the next incoming POST.
+func (t *Transport) Accept(ctx context.Context) (transport.Connection, error) {
+	select {
+	case c := <-t.conns:
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, transport.ErrClosed
+	}
+}
+
+// Close stops the HTTP server.
+func (t *Transport) Close() error {
+	var err error
+	t.once.Do(func() {
+		close(t.done)
+		err = t.server.Close()
+	})
+	return err
+}
+
+// ServeHTTP handles a request to the MCP endpoint. It is exported so the
+// transport can be mounted on an existing mux instead of calling Listen;
+// the Host and Origin checks then apply as they would after Listen on the
+// address the requests arrive on.
+func (t *Transport) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	peer, client := t.peer(r)
+	if !t.checkClient(w, client) || !t.checkOrigin(w, r) {
+		return
+	}
+	tenant, ok := t.tenantFromPath(w, r)
+	if !ok {
+		return
+	}
+	peer.Tenant = tenant
+	switch {
+	case t.sessions != nil && (r.Method == nethttp.MethodGet || r.Method == nethttp.MethodDelete):
+		if !t.authenticateRequest(w, r, &peer) {
+			return
+		}
+		if r.Method == nethttp.MethodGet {
+			t.serveGet(w, r, peer)
+		} else {
+			t.serveDelete(w, r, peer)
+		}
+		return
+	case r.Method != nethttp.MethodPost && t.sessions != nil:
+		methodNotAllowed(w, "GET, POST, DELETE")
+		return
+	case r.Method != nethttp.MethodPost:
+		methodNotAllowed(w, nethttp.MethodPost)
+		return
+	}
+	if !t.authenticateRequest(w, r, &peer) {
+		return
+	}
+	accept := negotiate(r.Header.Get("Accept"))
+	if !accept.json && !accept.sse {
+		writeError(w, nethttp.StatusNotAcceptable, "client must accept application/json or text/event-stream")
+		return
+	}
+	accept.gzip = acceptsGzip(r.Header.Get("Accept-Encoding"))
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != mediaJSON {
+		writeError(w, nethttp.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+	if r.ContentLength > t.maxBodySize {
+		writeTooLarge(w, t.maxBodySize)
+		return
+	}
+
+	msg, n, err := t.decode(w, r)
+	if err != nil {
+		var tooLarge *nethttp.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeTooLarge(w, t.maxBodySize)
+			return
+		}
+		var fe *transport.FrameError
+		if errors.As(err, &fe) && t.reportFrame != nil {
+			t.reportFrame(peer, fe)
+		}
+		writeJSON(w, nethttp.StatusBadRequest, protocol.NewErrorResponse(nil, protocol.AsError(err)))
+		return
+	}
+	if t.sessions != nil {
+		t.servePostSession(w, r, peer, accept, msg, n)
+		return
+	}
+
+	c := &conn{msg: msg, size: n, w: w, closed: make(chan struct{}), gone: r.Context().Done(), peer: peer, accept: accept, compress: t.compress}
+	select {
+	case t.conns <- c:
+	case <-r.Context().Done():
+		return
+	case <-t.done:
+		writeError(w, nethttp.StatusServiceUnavailable, "server shutting down")
+		return
+	}
+
+	select {
+	case <-c.closed:
+	case <-r.Context().Done():
+	}
+	c.finish()
+}
+
+// serveUI passes requests under /ui/ to the UI handler.
+func (t *Transport) serveUI(w nethttp.ResponseWriter, r *nethttp.Request) {
+	peer, client := t.peer(r)
+	if !t.checkClient(w, client) || !t.checkOrigin(w, r) {
+		return
+	}
+	if r.URL.Path != "/ui/" && !t.authenticateRequest(w, r, &peer) {
+		return
+	}
+	nethttp.StripPrefix("/ui", t.ui).ServeHTTP(w, r)
+}
+
+// uploadResponse is returned from the uploads endpoint.
+type uploadResponse struct {
+	Ref blob.Ref `json:"ref"`
+	blob.Info
+}
+
+// serveUpload streams a raw request body into the blob store.
+func (t *Transport) serveUpload(w nethttp.ResponseWriter, r *nethttp.Request) {
+	peer, client := t.peer(r)
+	if !t.checkClient(w, client) || !t.checkOrigin(w, r) {
+		return
+	}
+	if r.Method != nethttp.MethodPost {
+		methodNotAllowed(w, nethttp.MethodPost)
+		return
+	}
+	if !t.authenticateRequest(w, r, &peer) {
+		return
+	}
+	mimeType := r.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	info, err := t.uploads.Put(r.Context(), r.Body, mimeType)
+	if err != nil {
+		if errors.Is(err, blob.ErrFull) {
+			writeError(w, nethttp.StatusInsufficientStorage, "upload store full")
+			return
+		}
+		if errors.Is(err, blob.ErrTooLarge) {
+			writeError(w, nethttp.StatusRequestEntityTooLarge, "upload too large")
+			return
+		}
+		writeError(w, nethttp.StatusInternalServerError, "upload failed")
+		return
+	}
+	w.Header().Set("Content-Type", mediaJSON)
+	w.WriteHeader(nethttp.StatusCreated)
+	json.NewEncoder(w).Encode(uploadResponse{Ref: info.Ref(), Info: info})
+}
+
+// ReportMalformedFrames implements transport.FrameReporter. Request bodies
+// are not buffered, so only the first n bytes of a malformed body are kept.
+func (t *Transport) ReportMalformedFrames(n int, report func(transport.Peer, *transport.FrameError)) {
+	t.framePreview, t.reportFrame = n, report
+}
+
+// decode stream-decodes a single message from the request body without
+// buffering it, enforcing the body size limit on chunked bodies too. It
+// returns the number of body bytes read.
+func (t *Transport) decode(w nethttp.ResponseWriter, r *nethttp.Request) (*protocol.Message, int64, error) {
+	body := &countingReader{r: nethttp.MaxBytesReader(w, r.Body, t.maxBodySize)}
+	if t.reportFrame != nil {
+		// One byte past the preview shows whether it was cut off.
+		body.keep = t.framePreview + 1
+	}
+	dec := json.NewDecoder(body)
+	var msg protocol.Message
+	if err := dec.Decode(&msg); err != nil {
+		return nil, body.n, parseError(err, body, r)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after message")
+		}
+		return nil, body.n, parseError(err, body, r)
+	}
+	return &msg, body.n, nil
+}
+
+// countingReader counts the bytes read from r and keeps the first keep of
+// them.
+type countingReader struct {
+	r      io.Reader
+	n      int64
+	keep   int
+	prefix []byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if room := c.keep - len(c.prefix); room > 0 {
+		c.prefix = append(c.prefix, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+func parseError(err error, body *countingReader, r *nethttp.Request) error {
+	var tooLarge *nethttp.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	return &transport.FrameError{
+		Frame: body.prefix,
+		Size:  max(body.n, r.ContentLength),
+		Err:   protocol.NewError(protocol.ParseError, "parse error: "+err.Error(), nil),
+	}
+}
+
+// writeJSON writes msg as the whole response body and returns the number
+// of body bytes written.
+func writeJSON(w nethttp.ResponseWriter, status int, msg *protocol.Message) (int, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	w.Header().Set("Content-Type", mediaJSON)
+	w.WriteHeader(status)
+	return w.Write(append(data, '\n'))
+}
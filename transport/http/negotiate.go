@@ -0,0 +1,74 @@
+package http
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+const (
+	mediaJSON = "application/json"
+	mediaSSE  = "text/event-stream"
+)
+
+// acceptance records which response media types a client accepts, and
+// whether it accepts gzip-compressed responses.
+type acceptance struct {
+	json bool
+	sse  bool
+	gzip bool
+}
+
+// negotiate parses an Accept header. A missing header accepts anything, as
+// RFC 9110 prescribes; media ranges with q=0 are treated as refused.
+func negotiate(header string) acceptance {
+	if strings.TrimSpace(header) == "" {
+		return acceptance{json: true, sse: true}
+	}
+	var a acceptance
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 {
+				continue
+			}
+		}
+		switch mt {
+		case "*/*":
+			a.json, a.sse = true, true
+		case "application/*", mediaJSON:
+			a.json = true
+		case "text/*", mediaSSE:
+			a.sse = true
+		}
+	}
+	return a
+}
+
+// preferredLanguage returns the language tag with the highest quality in
+// an Accept-Language header, ignoring the "*" wildcard.
+func preferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
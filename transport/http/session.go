@@ -0,0 +1,628 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/idgen"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// SessionHeader carries the session ID in both directions.
+const SessionHeader = "Mcp-Session-Id"
+
+// SessionOptions configures the sessions enabled by WithSessions.
+type SessionOptions struct {
+	// IdleTimeout ends sessions that have had no request in progress and
+	// no open stream for this long. Defaults to 30 minutes.
+	IdleTimeout time.Duration
+	// MaxEvents bounds the stream events kept per session for clients
+	// resuming a stream with Last-Event-ID. Defaults to 1000.
+	MaxEvents int
+	// MaxSessions bounds the number of concurrent sessions. Initialize
+	// requests beyond it are answered with 503 Service Unavailable.
+	// Defaults to 10000.
+	MaxSessions int
+	// IDs generates session IDs, and the IDs of the pings measuring
+	// round-trip times for WithAdaptiveCompression. Defaults to
+	// idgen.Default.
+	IDs idgen.Generator
+	// Clock times idle sessions, and the pings measuring round-trip
+	// times for WithAdaptiveCompression. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// WithSessions serves the endpoint as the Streamable HTTP transport with
+// sessions. An initialize request creates a session and its response
+// carries the session ID in the Mcp-Session-Id header; the client sends
+// it with every later request. All requests of a session share one
+// connection, and so one runtime session.
+//
+// Besides POST, the endpoint then accepts GET, which opens an SSE stream
+// for messages the server sends outside a request, and DELETE, which ends
+// the session. Events on SSE streams carry IDs, and a client that lost a
+// stream resumes it by sending GET with the Last-Event-ID header.
+//
+// Without WithSessions, every POST is a connection of its own.
+func WithSessions(opts SessionOptions) Option {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 30 * time.Minute
+	}
+	if opts.MaxEvents <= 0 {
+		opts.MaxEvents = 1000
+	}
+	if opts.MaxSessions <= 0 {
+		opts.MaxSessions = 10000
+	}
+	opts.IDs = idgen.Or(opts.IDs)
+	opts.Clock = clock.Or(opts.Clock)
+	return func(t *Transport) {
+		t.sessions = &sessionTable{opts: opts, m: make(map[string]*session)}
+	}
+}
+
+// sessionTable holds the live sessions of a transport.
+type sessionTable struct {
+	opts     SessionOptions
+	compress *compressor
+
+	mu sync.Mutex
+	m  map[string]*session
+}
+
+func (tab *sessionTable) get(id string) *session {
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+	return tab.m[id]
+}
+
+func (tab *sessionTable) remove(id string) {
+	tab.mu.Lock()
+	delete(tab.m, id)
+	tab.mu.Unlock()
+}
+
+// create registers a new session, or returns nil when the table is full.
+func (tab *sessionTable) create(peer transport.Peer) (*session, error) {
+	s := &session{
+		id:      tab.opts.IDs.NewID(),
+		tab:     tab,
+		peer:    peer,
+		inbox:   make(chan inbound),
+		done:    make(chan struct{}),
+		pending: make(map[string]*exchange),
+		streams: make(map[string]*stream),
+	}
+	s.get = &stream{id: "get"}
+	s.streams[s.get.id] = s.get
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+	if len(tab.m) >= tab.opts.MaxSessions {
+		return nil, nil
+	}
+	tab.m[s.id] = s
+	if c := tab.compress; c != nil && c.opts.PingInterval > 0 {
+		s.mu.Lock()
+		s.pinger = tab.opts.Clock.AfterFunc(c.opts.PingInterval, s.ping)
+		s.mu.Unlock()
+	}
+	return s, nil
+}
+
+type inbound struct {
+	msg  *protocol.Message
+	size int64
+}
+
+// session is the connection of one Streamable HTTP session. Messages of
+// all its POST requests are read from it in arrival order. Responses go
+// to the request that asked for them; other messages go to the SSE stream
+// of the request being handled, when its client accepts one, and to the
+// GET stream otherwise.
+type session struct {
+	id   string
+	tab  *sessionTable
+	peer transport.Peer
+
+	inbox chan inbound
+	done  chan struct{}
+	once  sync.Once
+
+	read    atomic.Int64
+	written atomic.Int64
+
+	mu sync.Mutex
+	// pending maps the IDs of requests awaiting a response to their
+	// exchanges.
+	pending map[string]*exchange
+	// handling holds the exchanges of the requests read but not yet
+	// answered, in the order they were read. Notifications naming a
+	// related request go with that request's exchange; others go with
+	// the first, the oldest request being handled.
+	handling []*exchange
+	streams  map[string]*stream
+	get      *stream
+	posts    int
+	seq      int64
+	log      []event
+	active   int
+	idle     clock.Timer
+
+	// The round-trip time of the link, measured by probe when compression
+	// is adaptive: srtt is its smoothed value, zero until measured, and
+	// probeSent is when the ping probeID awaiting its answer was sent.
+	srtt      time.Duration
+	probeID   protocol.ID
+	probeSent time.Time
+	pinger    clock.Timer
+}
+
+// exchange is a POST request awaiting its response.
+type exchange struct {
+	sink   *sink
+	accept acceptance
+	mode   responseMode
+	stream *stream
+	// gone is set once the POST handler has returned; the response
+	// writer must not be used after that.
+	gone bool
+}
+
+// stream is an SSE stream of a session, the GET stream or the stream a
+// POST response turned into. Its events outlive the HTTP response they
+// were written to so that the client can resume it.
+type stream struct {
+	id   string
+	sink *sink
+	// sent is the sequence number of the last event written to a sink.
+	sent int64
+	// ended is set once the response ending a POST stream was sent.
+	ended bool
+}
+
+// sink is an HTTP response events are written to.
+type sink struct {
+	w    nethttp.ResponseWriter
+	done chan struct{}
+	once sync.Once
+}
+
+func newSink(w nethttp.ResponseWriter) *sink {
+	return &sink{w: w, done: make(chan struct{})}
+}
+
+func (k *sink) close() { k.once.Do(func() { close(k.done) }) }
+
+// event is a message sent on a stream.
+type event struct {
+	seq    int64
+	stream string
+	data   []byte
+}
+
+func (e event) id() string { return e.stream + "-" + strconv.FormatInt(e.seq, 10) }
+
+func (s *session) Read(ctx context.Context) (*protocol.Message, error) {
+	select {
+	case in := <-s.inbox:
+		s.read.Add(in.size)
+		if in.msg.IsRequest() {
+			s.mu.Lock()
+			if x := s.pending[in.msg.ID.String()]; x != nil {
+				s.handling = append(s.handling, x)
+			}
+			s.mu.Unlock()
+		}
+		return in.msg, nil
+	case <-s.done:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *session) Write(ctx context.Context, msg *protocol.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return transport.ErrClosed
+	default:
+	}
+	if msg.IsResponse() {
+		key := msg.ID.String()
+		x := s.pending[key]
+		if x == nil {
+			return nil // the request was abandoned with its session
+		}
+		delete(s.pending, key)
+		for i, h := range s.handling {
+			if h == x {
+				s.handling = append(s.handling[:i], s.handling[i+1:]...)
+				break
+			}
+		}
+		return s.respond(x, msg)
+	}
+	x := s.current()
+	if id, ok := transport.RelatedRequestFromContext(ctx); ok {
+		x = s.pending[id.String()]
+	}
+	if x != nil && x.mode != modeJSON && x.accept.sse && (!x.gone || x.stream != nil) {
+		if x.mode == modeNone {
+			s.startStream(x)
+		}
+		return s.send(x.stream, msg)
+	}
+	return s.send(s.get, msg)
+}
+
+// current returns the exchange of the oldest request being handled, or
+// nil.
+func (s *session) current() *exchange {
+	if len(s.handling) == 0 {
+		return nil
+	}
+	return s.handling[0]
+}
+
+// respond completes x with msg, as a JSON body unless x is streaming or
+// its client accepts only SSE.
+func (s *session) respond(x *exchange, msg *protocol.Message) error {
+	if x.mode == modeNone {
+		if x.gone {
+			return nil
+		}
+		if x.accept.json || !x.accept.sse {
+			x.mode = modeJSON
+			n, err := s.tab.compress.writeJSON(x.sink.w, nethttp.StatusOK, msg, x.accept, s.srtt)
+			s.written.Add(int64(n))
+			x.sink.close()
+			return err
+		}
+		s.startStream(x)
+	}
+	err := s.send(x.stream, msg)
+	x.stream.ended = true
+	if x.stream.sink != nil {
+		x.stream.sink.close()
+		x.stream.sink = nil
+	}
+	return err
+}
+
+// startStream turns the response of x into an SSE stream.
+func (s *session) startStream(x *exchange) {
+	s.posts++
+	x.mode = modeSSE
+	x.stream = &stream{id: "p" + strconv.Itoa(s.posts)}
+	s.streams[x.stream.id] = x.stream
+	if !x.gone {
+		x.sink.w = s.tab.compress.startSSE(x.sink.w, x.accept, s.srtt)
+		x.stream.sink = x.sink
+	}
+}
+
+// send records msg as the next event of st and writes it to the stream's
+// sink, if any. A sink that fails is dropped; the client can resume.
+func (s *session) send(st *stream, msg *protocol.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.seq++
+	e := event{seq: s.seq, stream: st.id, data: data}
+	s.log = append(s.log, e)
+	if over := len(s.log) - s.tab.opts.MaxEvents; over > 0 {
+		s.log = append(s.log[:0], s.log[over:]...)
+		s.forgetStreams()
+	}
+	s.deliver(st, e)
+	return nil
+}
+
+func (s *session) deliver(st *stream, e event) {
+	if st.sink == nil {
+		return
+	}
+	n, err := writeEvent(st.sink.w, e.id(), e.data)
+	s.written.Add(int64(n))
+	if err != nil {
+		st.sink.close()
+		st.sink = nil
+		return
+	}
+	st.sent = e.seq
+}
+
+// forgetStreams drops the ended POST streams with no events left to
+// replay.
+func (s *session) forgetStreams() {
+	live := make(map[string]bool)
+	for _, e := range s.log {
+		live[e.stream] = true
+	}
+	for id, st := range s.streams {
+		if st.ended && !live[id] {
+			delete(s.streams, id)
+		}
+	}
+}
+
+// attach makes k the sink of st, replacing any previous one, and replays
+// the events of st after seq.
+func (s *session) attach(st *stream, k *sink, after int64) {
+	if st.sink != nil {
+		st.sink.close()
+	}
+	st.sink = k
+	for _, e := range s.log {
+		if e.stream == st.id && e.seq > after {
+			s.deliver(st, e)
+			if st.sink == nil {
+				return
+			}
+		}
+	}
+	if st.ended {
+		k.close()
+		st.sink = nil
+	}
+}
+
+func (s *session) Close() error {
+	s.terminate()
+	return nil
+}
+
+// terminate ends the session: reads return io.EOF, open streams end and
+// the session ID is no longer recognized.
+func (s *session) terminate() {
+	s.once.Do(func() {
+		close(s.done)
+		s.tab.remove(s.id)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, st := range s.streams {
+			if st.sink != nil {
+				st.sink.close()
+				st.sink = nil
+			}
+		}
+		for _, x := range s.pending {
+			x.sink.close()
+		}
+		if s.idle != nil {
+			s.idle.Stop()
+		}
+		if s.pinger != nil {
+			s.pinger.Stop()
+		}
+	})
+}
+
+func (s *session) Peer() transport.Peer { return s.peer }
+
+// SessionID implements transport.SessionIdentified.
+func (s *session) SessionID() string { return s.id }
+
+// Bytes implements transport.ByteCounter, counting message bodies and
+// stream events.
+func (s *session) Bytes() (read, written int64) {
+	return s.read.Load(), s.written.Load()
+}
+
+// begin records an HTTP request of the session as in progress, keeping
+// the session from expiring. The returned function ends it.
+func (s *session) begin() func() {
+	s.mu.Lock()
+	s.active++
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.active--
+		if s.active > 0 {
+			return
+		}
+		if s.idle == nil {
+			s.idle = s.tab.opts.Clock.AfterFunc(s.tab.opts.IdleTimeout, s.terminate)
+		} else {
+			s.idle.Reset(s.tab.opts.IdleTimeout)
+		}
+	}
+}
+
+// enqueue hands msg to the server, giving up if ctx is done or the
+// session ends first.
+func (s *session) enqueue(ctx context.Context, msg *protocol.Message, size int64) bool {
+	select {
+	case s.inbox <- inbound{msg: msg, size: size}:
+		return true
+	case <-ctx.Done():
+	case <-s.done:
+	}
+	return false
+}
+
+// sessionFor returns the session named by the request's session header,
+// writing an error response and returning nil when there is none. A
+// session is only found by requests from its own principal and tenant.
+func (t *Transport) sessionFor(w nethttp.ResponseWriter, r *nethttp.Request, peer transport.Peer) *session {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		writeError(w, nethttp.StatusBadRequest, "missing "+SessionHeader+" header")
+		return nil
+	}
+	s := t.sessions.get(id)
+	if s == nil || subjectOf(s.peer) != subjectOf(peer) || s.peer.Tenant != peer.Tenant {
+		writeError(w, nethttp.StatusNotFound, "session not found")
+		return nil
+	}
+	return s
+}
+
+func subjectOf(p transport.Peer) string {
+	if p.Principal == nil {
+		return ""
+	}
+	return p.Principal.Subject
+}
+
+// servePostSession handles a POST carrying msg when sessions are enabled.
+func (t *Transport) servePostSession(w nethttp.ResponseWriter, r *nethttp.Request, peer transport.Peer, accept acceptance, msg *protocol.Message, size int64) {
+	var s *session
+	if r.Header.Get(SessionHeader) == "" && msg.Method == protocol.MethodInitialize {
+		var err error
+		s, err = t.sessions.create(peer)
+		if err != nil {
+			writeError(w, nethttp.StatusInternalServerError, "creating session failed")
+			return
+		}
+		if s == nil {
+			writeError(w, nethttp.StatusServiceUnavailable, "too many sessions")
+			return
+		}
+		select {
+		case t.conns <- s:
+		case <-r.Context().Done():
+			s.terminate()
+			return
+		case <-t.done:
+			s.terminate()
+			writeError(w, nethttp.StatusServiceUnavailable, "server shutting down")
+			return
+		}
+	} else if s = t.sessionFor(w, r, peer); s == nil {
+		return
+	}
+	defer s.begin()()
+	w.Header().Set(SessionHeader, s.id)
+
+	if !msg.IsRequest() {
+		if s.probeAnswered(msg) {
+			w.WriteHeader(nethttp.StatusAccepted)
+			return
+		}
+		if !s.enqueue(r.Context(), msg, size) {
+			writeError(w, nethttp.StatusNotFound, "session not found")
+			return
+		}
+		w.WriteHeader(nethttp.StatusAccepted)
+		return
+	}
+	key := msg.ID.String()
+	x := &exchange{sink: newSink(w), accept: accept}
+	s.mu.Lock()
+	if _, dup := s.pending[key]; dup {
+		s.mu.Unlock()
+		writeJSON(w, nethttp.StatusBadRequest, protocol.NewErrorResponse(msg.ID,
+			protocol.NewError(protocol.InvalidRequest, "a request with this id is already in progress", nil)))
+		return
+	}
+	s.pending[key] = x
+	s.mu.Unlock()
+	if !s.enqueue(r.Context(), msg, size) {
+		s.mu.Lock()
+		delete(s.pending, key)
+		s.mu.Unlock()
+		writeError(w, nethttp.StatusNotFound, "session not found")
+		return
+	}
+	select {
+	case <-x.sink.done:
+	case <-r.Context().Done():
+	}
+	s.mu.Lock()
+	x.gone = true
+	if x.stream != nil && x.stream.sink == x.sink {
+		x.stream.sink = nil
+	}
+	finishResponse(x.sink.w)
+	if x.mode == modeNone {
+		select {
+		case <-s.done:
+			writeError(w, nethttp.StatusNotFound, "session terminated")
+		default:
+		}
+	}
+	s.mu.Unlock()
+}
+
+// serveGet opens the session's GET stream, or resumes the stream named by
+// the Last-Event-ID header.
+func (t *Transport) serveGet(w nethttp.ResponseWriter, r *nethttp.Request, peer transport.Peer) {
+	accept := negotiate(r.Header.Get("Accept"))
+	if !accept.sse {
+		writeError(w, nethttp.StatusNotAcceptable, "client must accept text/event-stream")
+		return
+	}
+	accept.gzip = acceptsGzip(r.Header.Get("Accept-Encoding"))
+	s := t.sessionFor(w, r, peer)
+	if s == nil {
+		return
+	}
+	defer s.begin()()
+	s.mu.Lock()
+	st, after := s.get, s.get.sent
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		id, seq, ok := strings.Cut(last, "-")
+		n, err := strconv.ParseInt(seq, 10, 64)
+		st = s.streams[id]
+		if !ok || err != nil || st == nil {
+			s.mu.Unlock()
+			writeError(w, nethttp.StatusNotFound, "stream not found")
+			return
+		}
+		after = n
+	}
+	w.Header().Set(SessionHeader, s.id)
+	k := newSink(s.tab.compress.startSSE(w, accept, s.srtt))
+	s.attach(st, k, after)
+	if st == s.get && s.srtt == 0 {
+		s.probe()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-k.done:
+	case <-r.Context().Done():
+	}
+	s.mu.Lock()
+	if st.sink == k {
+		st.sink = nil
+	}
+	finishResponse(k.w)
+	s.mu.Unlock()
+}
+
+// serveDelete ends a session at the client's request.
+func (t *Transport) serveDelete(w nethttp.ResponseWriter, r *nethttp.Request, peer transport.Peer) {
+	s := t.sessionFor(w, r, peer)
+	if s == nil {
+		return
+	}
+	s.terminate()
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+// startSSE writes the header of an SSE response.
+func startSSE(w nethttp.ResponseWriter) {
+	h := w.Header()
+	h.Set("Content-Type", mediaSSE)
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(nethttp.StatusOK)
+	nethttp.NewResponseController(w).Flush()
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// serveOne answers the initialize request of the first connection of tr,
+// then reads its messages until it ends and sends the error it ended with.
+func serveOne(ctx context.Context, tr *Transport) <-chan error {
+	ended := make(chan error, 1)
+	go func() {
+		conn, err := tr.Accept(ctx)
+		if err != nil {
+			ended <- err
+			return
+		}
+		msg, err := conn.Read(ctx)
+		if err != nil {
+			ended <- err
+			return
+		}
+		res, _ := protocol.NewResult(msg.ID, struct{}{})
+		if err := conn.Write(ctx, res); err != nil {
+			ended <- err
+			return
+		}
+		for {
+			if _, err := conn.Read(ctx); err != nil {
+				ended <- err
+				return
+			}
+		}
+	}()
+	return ended
+}
+
+// post sends body to the endpoint at url in the given session, if any, and
+// returns the response with its body closed.
+func post(t *testing.T, url, session, body string) *nethttp.Response {
+	t.Helper()
+	req, err := nethttp.NewRequest(nethttp.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set(SessionHeader, session)
+	}
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// initialize opens a session at url and returns its ID.
+func initialize(t *testing.T, url string) string {
+	t.Helper()
+	resp := post(t, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	id := resp.Header.Get(SessionHeader)
+	if resp.StatusCode != nethttp.StatusOK || id == "" {
+		t.Fatalf("initialize: status %d, session %q", resp.StatusCode, id)
+	}
+	return id
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	tr := New("", WithSessions(SessionOptions{IdleTimeout: time.Minute, Clock: fake}))
+	srv := httptest.NewServer(tr)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ended := serveOne(ctx, tr)
+	id := initialize(t, srv.URL+"/mcp")
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	select {
+	case <-ended:
+	case <-ctx.Done():
+		t.Fatal("session did not expire")
+	}
+	if resp := post(t, srv.URL+"/mcp", id, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != nethttp.StatusNotFound {
+		t.Errorf("request after expiry: status %d, want %d", resp.StatusCode, nethttp.StatusNotFound)
+	}
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	nethttp "net/http"
+	"strings"
+)
+
+// WithTenantPaths additionally serves the endpoint at "<path>/{tenant}"
+// and records the segment as the connection's tenant, for use with
+// tenant.Path. Requests to "<path>" itself carry no tenant. The segment
+// "uploads" is reserved for the uploads endpoint.
+func WithTenantPaths() Option {
+	return func(t *Transport) { t.tenantPaths = true }
+}
+
+// tenantFromPath returns the tenant addressed by r. It writes a 404
+// response and returns false when the path below the endpoint is not a
+// single segment.
+func (t *Transport) tenantFromPath(w nethttp.ResponseWriter, r *nethttp.Request) (string, bool) {
+	if !t.tenantPaths {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, t.path+"/")
+	if !ok {
+		return "", true
+	}
+	if rest == "" || strings.Contains(rest, "/") {
+		notFound(w, r)
+		return "", false
+	}
+	return rest, true
+}
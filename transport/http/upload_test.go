@@ -0,0 +1,39 @@
+package http
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/blob"
+)
+
+func TestUploadLimits(t *testing.T) {
+	store := blob.NewMemoryStore(blob.MemoryOptions{MaxSize: 10, MaxTotal: 15})
+	tr := New("", WithUploads(store))
+	srv := httptest.NewServer(tr.server.Handler)
+	defer srv.Close()
+
+	upload := func(n int) int {
+		t.Helper()
+		resp, err := nethttp.Post(srv.URL+"/mcp/uploads", "text/plain", strings.NewReader(strings.Repeat("x", n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tt := range []struct {
+		size, status int
+	}{
+		{10, nethttp.StatusCreated},
+		{11, nethttp.StatusRequestEntityTooLarge},
+		{10, nethttp.StatusInsufficientStorage},
+		{5, nethttp.StatusCreated},
+	} {
+		if got := upload(tt.size); got != tt.status {
+			t.Errorf("upload of %d bytes: status %d, want %d", tt.size, got, tt.status)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package inproc connects clients to a server within the same process,
+// without sockets or child processes, for tests and for programs embedding
+// a server:
+//
+//	t := inproc.New()
+//	s := mcp.NewServer(mcp.WithTransport(t))
+//	go s.Serve(ctx)
+//	conn, err := t.Dial(ctx)
+//	if err != nil { ... }
+//	client := mcp.NewClient(conn)
+//
+// Messages cross as JSON, as on any other transport, so handlers and
+// clients never share memory. The transport runs no goroutines of its
+// own: once the server and client are closed nothing is left running.
+package inproc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// Transport accepts the connections made with Dial.
+type Transport struct {
+	accept chan *Conn
+	once   sync.Once
+	done   chan struct{}
+}
+
+// New returns an in-process transport.
+func New() *Transport {
+	return &Transport{accept: make(chan *Conn), done: make(chan struct{})}
+}
+
+// Listen implements transport.Transport. There is nothing to bind.
+func (t *Transport) Listen(ctx context.Context) error { return nil }
+
+// Accept returns the server end of the next connection made with Dial.
+func (t *Transport) Accept(ctx context.Context) (transport.Connection, error) {
+	select {
+	case c := <-t.accept:
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, transport.ErrClosed
+	}
+}
+
+// Close stops accepting connections. Connections already made stay open.
+func (t *Transport) Close() error {
+	t.once.Do(func() { close(t.done) })
+	return nil
+}
+
+// Dial connects a client to the server serving t and returns the client
+// end of the connection, an mcp.ClientConn. It blocks until the server
+// accepts the connection, ctx is done or t is closed.
+func (t *Transport) Dial(ctx context.Context) (*Conn, error) {
+	return t.DialPeer(ctx, transport.Peer{})
+}
+
+// DialPeer is like Dial, with the server seeing the client as peer, for
+// instance to test tools with an authenticated principal. peer.Transport
+// defaults to "inproc".
+func (t *Transport) DialPeer(ctx context.Context, peer transport.Peer) (*Conn, error) {
+	if peer.Transport == "" {
+		peer.Transport = "inproc"
+	}
+	client, server := Pipe()
+	server.peer = peer
+	select {
+	case t.accept <- server:
+		return client, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, transport.ErrClosed
+	}
+}
+
+// Conn is one end of an in-process connection. Closing either end closes
+// both: the other end reads io.EOF once it has read what was sent before.
+type Conn struct {
+	in, out chan []byte
+	closed  chan struct{}
+	once    *sync.Once
+	peer    transport.Peer
+}
+
+// Pipe returns the two ends of a connection. Messages written to one are
+// read from the other, each write waiting for the matching read.
+func Pipe() (a, b *Conn) {
+	ab, ba := make(chan []byte), make(chan []byte)
+	closed := make(chan struct{})
+	once := new(sync.Once)
+	a = &Conn{in: ba, out: ab, closed: closed, once: once, peer: transport.Peer{Transport: "inproc"}}
+	b = &Conn{in: ab, out: ba, closed: closed, once: once, peer: transport.Peer{Transport: "inproc"}}
+	return a, b
+}
+
+// Read returns the next message written to the other end.
+func (c *Conn) Read(ctx context.Context) (*protocol.Message, error) {
+	var data []byte
+	select {
+	case data = <-c.in:
+	case <-c.closed:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var msg protocol.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, &transport.FrameError{
+			Frame: data,
+			Size:  int64(len(data)),
+			Err:   protocol.NewError(protocol.ParseError, "parse error: "+err.Error(), nil),
+		}
+	}
+	return &msg, nil
+}
+
+// Write sends msg to the other end, waiting until it is read. It is safe
+// for concurrent use.
+func (c *Conn) Write(ctx context.Context, msg *protocol.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	select {
+	case c.out <- data:
+		return nil
+	case <-c.closed:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes both ends of the connection.
+func (c *Conn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// Peer implements transport.Connection.
+func (c *Conn) Peer() transport.Peer { return c.peer }
@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IPFilter admits or rejects clients by address. Deny entries take
+// precedence; an empty Allow list admits every address not denied.
+type IPFilter struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// Enabled reports whether the filter has any rules.
+func (f IPFilter) Enabled() bool { return len(f.Allow) > 0 || len(f.Deny) > 0 }
+
+// Allowed reports whether addr passes the filter. Invalid addresses only
+// pass a filter without rules.
+func (f IPFilter) Allowed(addr netip.Addr) bool {
+	if !f.Enabled() {
+		return true
+	}
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	if containsAddr(f.Deny, addr) {
+		return false
+	}
+	return len(f.Allow) == 0 || containsAddr(f.Allow, addr)
+}
+
+// ClientIP returns the address of the client that originated a request
+// arriving from remote with the given X-Forwarded-For header values. The
+// forwarded chain is only consulted when remote is a trusted proxy; it is
+// then walked from the nearest hop outwards, skipping trusted proxies, and
+// the first untrusted address is the client. Spoofed entries to the left of
+// that address are ignored.
+func ClientIP(remote netip.Addr, forwardedFor []string, trusted []netip.Prefix) netip.Addr {
+	remote = remote.Unmap()
+	if !containsAddr(trusted, remote) {
+		return remote
+	}
+	var hops []string
+	for _, v := range forwardedFor {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hops = append(hops, h)
+			}
+		}
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !containsAddr(trusted, client) {
+			break
+		}
+	}
+	return client
+}
+
+// AddrFromString parses the IP of a "host:port" or bare IP string, as found
+// in net.Conn.RemoteAddr and http.Request.RemoteAddr.
+func AddrFromString(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, _ := netip.ParseAddr(s)
+	return addr.Unmap()
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
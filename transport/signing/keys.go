@@ -0,0 +1,80 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// Key signs messages and verifies their signatures. Keys are identified
+// by ID in the signatures they produce, so that a verifier holding several
+// keys knows which one to check.
+type Key interface {
+	// ID names the key in signatures.
+	ID() string
+	// Algorithm names the signature algorithm, such as "HS256" or
+	// "EdDSA".
+	Algorithm() string
+	// Sign returns the signature of data.
+	Sign(data []byte) ([]byte, error)
+	// Verify reports whether sig is a valid signature of data.
+	Verify(data, sig []byte) bool
+}
+
+// HMACKey returns a key signing with HMAC-SHA256 under secret. Both sides
+// of the connection need the secret.
+func HMACKey(id string, secret []byte) Key {
+	return hmacKey{id: id, secret: append([]byte(nil), secret...)}
+}
+
+type hmacKey struct {
+	id     string
+	secret []byte
+}
+
+func (k hmacKey) ID() string        { return k.id }
+func (k hmacKey) Algorithm() string { return "HS256" }
+
+func (k hmacKey) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (k hmacKey) Verify(data, sig []byte) bool {
+	want, _ := k.Sign(data)
+	return hmac.Equal(want, sig)
+}
+
+// Ed25519Key returns a key signing with priv. Give peers the matching
+// Ed25519PublicKey to verify with.
+func Ed25519Key(id string, priv ed25519.PrivateKey) Key {
+	return ed25519Key{id: id, priv: priv, pub: priv.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519PublicKey returns a key that verifies Ed25519 signatures made
+// with the private half of pub. It cannot sign.
+func Ed25519PublicKey(id string, pub ed25519.PublicKey) Key {
+	return ed25519Key{id: id, pub: pub}
+}
+
+type ed25519Key struct {
+	id   string
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+func (k ed25519Key) ID() string        { return k.id }
+func (k ed25519Key) Algorithm() string { return "EdDSA" }
+
+func (k ed25519Key) Sign(data []byte) ([]byte, error) {
+	if k.priv == nil {
+		return nil, errors.New("signing: public key cannot sign")
+	}
+	return ed25519.Sign(k.priv, data), nil
+}
+
+func (k ed25519Key) Verify(data, sig []byte) bool {
+	return len(k.pub) == ed25519.PublicKeySize && ed25519.Verify(k.pub, data, sig)
+}
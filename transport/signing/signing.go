@@ -0,0 +1,314 @@
+// Package signing authenticates individual MCP messages, for deployments
+// that tunnel MCP through intermediaries which terminate TLS and could
+// otherwise alter messages unnoticed.
+//
+// Wrap decorates a transport: messages written to clients are signed, and
+// messages read from them are verified against trusted keys. A signature
+// covers the canonical JSON encoding (see codec.Canonical) of the whole
+// message, envelope included, and travels in the _meta object of its
+// params, result or error data under "zenmcp/signature":
+//
+//	{"alg": "EdDSA", "kid": "server-1", "created": 1700000000, "sig": "..."}
+//
+// The signed bytes are those of the message with the signature object in
+// place but without its "sig" member. Sign and Verify are exported for
+// clients implementing the same scheme.
+package signing
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// MetaKey is the _meta member holding a message's signature.
+const MetaKey = "zenmcp/signature"
+
+var (
+	// ErrUnsigned is returned by Verify for messages without a signature.
+	ErrUnsigned = errors.New("signing: message is not signed")
+	// ErrUnknownKey is returned by Verify for signatures made with a key
+	// it was not given.
+	ErrUnknownKey = errors.New("signing: unknown key")
+	// ErrInvalidSignature is returned by Verify for signatures that do not
+	// match the message.
+	ErrInvalidSignature = errors.New("signing: invalid signature")
+	// ErrUnsignable is returned by Sign for messages whose payload is not
+	// a JSON object, such as errors with string data.
+	ErrUnsignable = errors.New("signing: payload is not an object")
+)
+
+// Signature is the signature object carried in _meta.
+type Signature struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// Created is the Unix time the signature was made at.
+	Created int64 `json:"created"`
+	// Value is the base64url-encoded signature, without padding.
+	Value string `json:"sig,omitempty"`
+}
+
+// Sign returns a copy of msg signed with key at time now. msg itself is
+// not modified, so one message may be signed for several connections.
+func Sign(msg *protocol.Message, key Key, now time.Time) (*protocol.Message, error) {
+	sig := Signature{Algorithm: key.Algorithm(), KeyID: key.ID(), Created: now.Unix()}
+	signed, err := withSignature(msg, &sig)
+	if err != nil {
+		return nil, err
+	}
+	data, err := codec.Canonical(signed)
+	if err != nil {
+		return nil, err
+	}
+	value, err := key.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	sig.Value = base64.RawURLEncoding.EncodeToString(value)
+	return withSignature(msg, &sig)
+}
+
+// Verify checks the signature of msg against keys and returns it.
+func Verify(msg *protocol.Message, keys ...Key) (*Signature, error) {
+	obj, err := payloadObject(payload(msg))
+	if err != nil {
+		return nil, ErrUnsigned
+	}
+	meta, err := payloadObject(obj["_meta"])
+	if err != nil || meta[MetaKey] == nil {
+		return nil, ErrUnsigned
+	}
+	var sig Signature
+	if err := json.Unmarshal(meta[MetaKey], &sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	var key Key
+	for _, k := range keys {
+		if k.ID() == sig.KeyID && k.Algorithm() == sig.Algorithm {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w %q (%s)", ErrUnknownKey, sig.KeyID, sig.Algorithm)
+	}
+	value, err := base64.RawURLEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	unsigned := sig
+	unsigned.Value = ""
+	stripped, err := withSignature(msg, &unsigned)
+	if err != nil {
+		return nil, err
+	}
+	data, err := codec.Canonical(stripped)
+	if err != nil {
+		return nil, err
+	}
+	if !key.Verify(data, value) {
+		return nil, ErrInvalidSignature
+	}
+	return &sig, nil
+}
+
+// payload returns the JSON object a signature is carried in: the params
+// of requests and notifications, the result of responses and the data of
+// errors.
+func payload(msg *protocol.Message) json.RawMessage {
+	switch {
+	case msg.Method != "":
+		return msg.Params
+	case msg.Error != nil:
+		if msg.Error.Data == nil {
+			return nil
+		}
+		data, err := json.Marshal(msg.Error.Data)
+		if err != nil {
+			return json.RawMessage("[]") // not an object
+		}
+		return data
+	}
+	return msg.Result
+}
+
+// withSignature returns a copy of msg whose payload's _meta holds sig.
+func withSignature(msg *protocol.Message, sig *Signature) (*protocol.Message, error) {
+	obj, err := payloadObject(payload(msg))
+	if err != nil {
+		return nil, err
+	}
+	meta, err := payloadObject(obj["_meta"])
+	if err != nil {
+		return nil, fmt.Errorf("signing: _meta: %w", err)
+	}
+	if meta[MetaKey], err = json.Marshal(sig); err != nil {
+		return nil, err
+	}
+	if obj["_meta"], err = json.Marshal(meta); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	c := *msg
+	switch {
+	case c.Method != "":
+		c.Params = raw
+	case c.Error != nil:
+		e := *c.Error
+		e.Data = json.RawMessage(raw)
+		c.Error = &e
+	default:
+		c.Result = raw
+	}
+	return &c, nil
+}
+
+// payloadObject decodes a JSON object, keeping its members verbatim.
+// Absent and null payloads yield an empty object.
+func payloadObject(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return make(map[string]json.RawMessage), nil
+	}
+	if raw[0] != '{' {
+		return nil, ErrUnsignable
+	}
+	obj := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Config configures Wrap.
+type Config struct {
+	// Key signs every message written to clients. Nil leaves outgoing
+	// messages unsigned.
+	Key Key
+	// Trusted lists the keys clients sign with. Incoming messages are
+	// verified only when it is not empty.
+	Trusted []Key
+	// AllowUnsigned accepts incoming messages that carry no signature at
+	// all, while still rejecting invalid ones. Use it while rolling out
+	// signing to clients.
+	AllowUnsigned bool
+	// MaxAge rejects incoming signatures created more than MaxAge before
+	// or after the current time, limiting how long a captured message can
+	// be replayed. Zero accepts signatures of any age.
+	MaxAge time.Duration
+	// Logger receives rejected messages. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Clock dates signatures and checks MaxAge. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// Wrap returns a transport whose connections sign and verify messages as
+// configured by cfg. Incoming requests failing verification are answered
+// with an InvalidRequest error; other rejected messages are dropped.
+func Wrap(t transport.Transport, cfg Config) transport.Transport {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &signingTransport{Transport: t, cfg: cfg}
+}
+
+type signingTransport struct {
+	transport.Transport
+	cfg Config
+}
+
+// Accept implements transport.Transport.
+func (t *signingTransport) Accept(ctx context.Context) (transport.Connection, error) {
+	c, err := t.Transport.Accept(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Connection: c, cfg: &t.cfg}, nil
+}
+
+type conn struct {
+	transport.Connection
+	cfg *Config
+}
+
+func (c *conn) Read(ctx context.Context) (*protocol.Message, error) {
+	for {
+		msg, err := c.Connection.Read(ctx)
+		if err != nil || len(c.cfg.Trusted) == 0 {
+			return msg, err
+		}
+		err = c.verify(msg)
+		if err == nil {
+			return msg, nil
+		}
+		c.cfg.Logger.Warn("signing: rejecting message", "method", msg.Method, "id", msg.ID, "peer", c.Peer(), "error", err)
+		if msg.IsRequest() {
+			resp := protocol.NewErrorResponse(msg.ID, protocol.NewError(protocol.InvalidRequest, err.Error(), nil))
+			if err := c.Write(ctx, resp); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (c *conn) verify(msg *protocol.Message) error {
+	sig, err := Verify(msg, c.cfg.Trusted...)
+	if errors.Is(err, ErrUnsigned) && c.cfg.AllowUnsigned {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if c.cfg.MaxAge > 0 {
+		age := clock.Since(c.cfg.Clock, time.Unix(sig.Created, 0))
+		if age > c.cfg.MaxAge || -age > c.cfg.MaxAge {
+			return fmt.Errorf("%w: created %s ago", ErrInvalidSignature, age.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+func (c *conn) Write(ctx context.Context, msg *protocol.Message) error {
+	if c.cfg.Key != nil {
+		signed, err := Sign(msg, c.cfg.Key, c.cfg.Clock.Now())
+		switch {
+		case errors.Is(err, ErrUnsignable):
+			// Verifying peers reject the message, which is as safe as
+			// not sending it and easier to diagnose.
+			c.cfg.Logger.Warn("signing: sending unsigned message", "method", msg.Method, "id", msg.ID, "error", err)
+		case err != nil:
+			return fmt.Errorf("signing: %w", err)
+		default:
+			msg = signed
+		}
+	}
+	return c.Connection.Write(ctx, msg)
+}
+
+// Bytes implements transport.ByteCounter when the wrapped connection does.
+func (c *conn) Bytes() (read, written int64) {
+	if bc, ok := c.Connection.(transport.ByteCounter); ok {
+		return bc.Bytes()
+	}
+	return 0, 0
+}
+
+// RequestScoped implements transport.RequestScoped.
+func (c *conn) RequestScoped() bool {
+	rs, ok := c.Connection.(transport.RequestScoped)
+	return ok && rs.RequestScoped()
+}
@@ -0,0 +1,227 @@
+// Package stdio implements the MCP stdio transport: a single connection over
+// the process's standard input and output.
+//
+// Messages are framed either as newline-delimited JSON, which most MCP
+// clients speak over stdio, or with LSP-style Content-Length headers. By
+// default the framing is detected from the first bytes the client sends
+// and used in both directions; WithFraming fixes it instead.
+package stdio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// Framing selects how messages are delimited on the streams.
+type Framing int
+
+const (
+	// Auto detects the framing from the first bytes read.
+	Auto Framing = iota
+	// NDJSON puts every message on a line of its own.
+	NDJSON
+	// ContentLength precedes every message with a Content-Length header.
+	ContentLength
+)
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithCompression lets the connection compress large frames with the
+// first of cs, in order of preference, that the client also supports.
+// Clients that do not negotiate compression get plain frames, as do
+// clients framing messages as NDJSON, which has no room for the header
+// marking compressed frames.
+func WithCompression(cs ...codec.Compression) Option {
+	return func(t *Transport) { t.compressions = cs }
+}
+
+// WithFraming fixes the framing instead of detecting it.
+func WithFraming(f Framing) Option {
+	return func(t *Transport) { t.framing = f }
+}
+
+// WithStreams serves the connection over r and w instead of the process's
+// standard input and output, as tests and embedding programs do.
+func WithStreams(r io.Reader, w io.Writer) Option {
+	return func(t *Transport) { t.in, t.out = r, w }
+}
+
+// Transport serves exactly one connection over stdin/stdout.
+type Transport struct {
+	compressions []codec.Compression
+	framing      Framing
+	in           io.Reader
+	out          io.Writer
+
+	once     sync.Once
+	accepted bool
+	done     chan struct{}
+}
+
+// New returns a stdio transport.
+func New(opts ...Option) *Transport {
+	t := &Transport{done: make(chan struct{}), in: os.Stdin, out: os.Stdout}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Listen implements transport.Transport. Stdio needs no preparation.
+func (t *Transport) Listen(ctx context.Context) error { return nil }
+
+// Accept returns the stdio connection on the first call and blocks on
+// subsequent calls until ctx is done or the transport is closed.
+func (t *Transport) Accept(ctx context.Context) (transport.Connection, error) {
+	if !t.accepted {
+		t.accepted = true
+		c := &conn{t: t, ready: make(chan struct{})}
+		if t.framing != Auto {
+			c.setCodec(t.framing, t.in)
+		}
+		return c, nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, transport.ErrClosed
+	}
+}
+
+// Close implements transport.Transport.
+func (t *Transport) Close() error {
+	t.once.Do(func() { close(t.done) })
+	return nil
+}
+
+type conn struct {
+	t *Transport
+	// codec is set once the framing is known, and ready closed then.
+	codec codec.Codec
+	ready chan struct{}
+	// writeMu serializes Encode calls, so that frames written from
+	// several goroutines never interleave on stdout.
+	writeMu sync.Mutex
+}
+
+// setCodec makes the connection use framing f, reading from r.
+func (c *conn) setCodec(f Framing, r io.Reader) {
+	if f == NDJSON {
+		c.codec = codec.NewNDJSON(r, c.t.out)
+	} else {
+		cl := codec.NewContentLength(r, c.t.out)
+		cl.SetCompressions(c.t.compressions...)
+		c.codec = cl
+	}
+	close(c.ready)
+}
+
+// detect reads up to the first byte other than white space and picks the
+// framing it starts. A stream that ends first gets Content-Length
+// framing, which then reports the end.
+func (c *conn) detect() {
+	br := bufio.NewReader(c.t.in)
+	f := ContentLength
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			break
+		}
+		if ndjson, ok := codec.IsNDJSON(b); ok {
+			if ndjson {
+				f = NDJSON
+			}
+			break
+		}
+		br.ReadByte()
+	}
+	c.setCodec(f, br)
+}
+
+func (c *conn) Read(ctx context.Context) (*protocol.Message, error) {
+	select {
+	case <-c.ready:
+	default:
+		c.detect()
+	}
+	var msg protocol.Message
+	if err := c.codec.Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Write is safe for concurrent use. Before the framing is detected it
+// waits for the client's first message.
+func (c *conn) Write(ctx context.Context, msg *protocol.Message) error {
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.t.done:
+		return transport.ErrClosed
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.codec.Encode(msg)
+}
+
+func (c *conn) Bytes() (read, written int64) {
+	select {
+	case <-c.ready:
+		return c.codec.Bytes()
+	default:
+		return 0, 0
+	}
+}
+
+// Compressions returns the algorithms the connection can use: none with
+// NDJSON framing, and those set with WithCompression otherwise, including
+// while the framing is not yet known.
+func (c *conn) Compressions() []string {
+	select {
+	case <-c.ready:
+		if cl, ok := c.codec.(*codec.ContentLength); ok {
+			return cl.Compressions()
+		}
+		return nil
+	default:
+	}
+	if c.t.framing == NDJSON {
+		return nil
+	}
+	names := make([]string, len(c.t.compressions))
+	for i, cmp := range c.t.compressions {
+		names[i] = cmp.Name()
+	}
+	return names
+}
+
+func (c *conn) EnableCompression(name string) error {
+	select {
+	case <-c.ready:
+	default:
+		return errors.New("stdio: framing not yet known")
+	}
+	cl, ok := c.codec.(*codec.ContentLength)
+	if !ok {
+		return errors.New("stdio: NDJSON framing cannot carry compressed frames")
+	}
+	return cl.EnableCompression(name)
+}
+
+// Close closes the transport as well: once the stdio session ends there is
+// nothing left to accept.
+func (c *conn) Close() error { return c.t.Close() }
+
+func (c *conn) Peer() transport.Peer { return transport.Peer{Transport: "stdio"} }
@@ -0,0 +1,176 @@
+package stdio_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/stdio"
+)
+
+// pipeWriter stands in for stdout on a pipe: it takes every Write in
+// small pieces, yielding between them, so that writes made concurrently
+// interleave unless the caller serializes them.
+type pipeWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	const chunk = 256
+	n := 0
+	for len(p) > 0 {
+		k := min(chunk, len(p))
+		w.mu.Lock()
+		w.buf.Write(p[:k])
+		w.mu.Unlock()
+		n += k
+		p = p[k:]
+		runtime.Gosched()
+	}
+	return n, nil
+}
+
+func (w *pipeWriter) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return bytes.Clone(w.buf.Bytes())
+}
+
+type payload struct {
+	Writer int    `json:"writer"`
+	Seq    int    `json:"seq"`
+	Fill   string `json:"fill"`
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	const writers, perWriter = 16, 40
+
+	tests := []struct {
+		name     string
+		framing  stdio.Framing
+		compress string
+		decoder  func(io.Reader) codec.Codec
+	}{
+		{"ndjson", stdio.NDJSON, "", func(r io.Reader) codec.Codec { return codec.NewNDJSON(r, io.Discard) }},
+		{"content-length", stdio.ContentLength, "", func(r io.Reader) codec.Codec { return codec.NewContentLength(r, io.Discard) }},
+		{"content-length/deflate", stdio.ContentLength, "deflate", func(r io.Reader) codec.Codec {
+			cl := codec.NewContentLength(r, io.Discard)
+			cl.SetCompressions(codec.Deflate)
+			return cl
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &pipeWriter{}
+			tr := stdio.New(stdio.WithStreams(strings.NewReader(""), out), stdio.WithFraming(tt.framing), stdio.WithCompression(codec.Deflate))
+			ctx := context.Background()
+			conn, err := tr.Accept(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.compress != "" {
+				if err := conn.(transport.Compressor).EnableCompression(tt.compress); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var wg sync.WaitGroup
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < perWriter; i++ {
+						// Sizes vary around the compression threshold
+						// and the pipe's chunk size.
+						fill := strings.Repeat(string(rune('a'+w%26)), 100+(w*perWriter+i)*37%3000)
+						msg, err := protocol.NewNotification("test/write", payload{Writer: w, Seq: i, Fill: fill})
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if err := conn.Write(ctx, msg); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			dec := tt.decoder(bytes.NewReader(out.Bytes()))
+			next := make([]int, writers)
+			for n := 0; ; n++ {
+				var msg protocol.Message
+				err := dec.Decode(&msg)
+				if err == io.EOF {
+					if n != writers*perWriter {
+						t.Fatalf("decoded %d frames, want %d", n, writers*perWriter)
+					}
+					break
+				}
+				if err != nil {
+					t.Fatalf("frame %d: %v", n, err)
+				}
+				var p payload
+				if err := json.Unmarshal(msg.Params, &p); err != nil {
+					t.Fatalf("frame %d: params: %v", n, err)
+				}
+				if p.Writer < 0 || p.Writer >= writers || p.Seq != next[p.Writer] {
+					t.Fatalf("frame %d: got writer %d seq %d out of order", n, p.Writer, p.Seq)
+				}
+				next[p.Writer]++
+				if want := strings.Repeat(string(rune('a'+p.Writer%26)), len(p.Fill)); p.Fill != want {
+					t.Fatalf("frame %d: fill of writer %d corrupted", n, p.Writer)
+				}
+			}
+		})
+	}
+}
+
+// TestWriteBeforeFraming checks that writes made before the framing is
+// detected wait for it, then go out framed like the client's messages.
+func TestWriteBeforeFraming(t *testing.T) {
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n")
+	out := &pipeWriter{}
+	tr := stdio.New(stdio.WithStreams(in, out))
+	ctx := context.Background()
+	conn, err := tr.Accept(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg, _ := protocol.NewNotification("test/write", payload{Seq: i})
+			if err := conn.Write(ctx, msg); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	if _, err := conn.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(string(out.Bytes()), "\n"), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("got %d NDJSON lines, want 8:\n%s", len(lines), out.Bytes())
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("line is not JSON: %s", line)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package tcp implements a raw TCP transport: every accepted socket is a
+// connection carrying Content-Length framed JSON-RPC messages.
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// tlsHandshakeTimeout bounds the TLS handshake of an accepted socket.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithListener makes the transport accept on l instead of binding its
+// address. Use it with listeners inherited through systemd socket
+// activation or file descriptors passed by a supervisor.
+func WithListener(l net.Listener) Option {
+	return func(t *Transport) { t.ln = l }
+}
+
+// WithAllowedCIDRs admits only clients whose address is in one of
+// prefixes. Other sockets are closed as soon as they are accepted.
+func WithAllowedCIDRs(prefixes ...netip.Prefix) Option {
+	return func(t *Transport) { t.ipFilter.Allow = append(t.ipFilter.Allow, prefixes...) }
+}
+
+// WithDeniedCIDRs closes sockets from clients whose address is in one of
+// prefixes. Denials take precedence over WithAllowedCIDRs.
+func WithDeniedCIDRs(prefixes ...netip.Prefix) Option {
+	return func(t *Transport) { t.ipFilter.Deny = append(t.ipFilter.Deny, prefixes...) }
+}
+
+// WithCanonicalJSON writes every message in its codec.Canonical encoding,
+// for peers that compare or hash the bytes they receive.
+func WithCanonicalJSON() Option {
+	return func(t *Transport) { t.canonical = true }
+}
+
+// WithCompression lets connections compress large frames with the first
+// of cs, in order of preference, that the client also supports. Clients
+// that do not negotiate compression get plain frames.
+func WithCompression(cs ...codec.Compression) Option {
+	return func(t *Transport) { t.compressions = cs }
+}
+
+// Transport accepts MCP connections on a TCP listener.
+type Transport struct {
+	addr         string
+	ln           net.Listener
+	ipFilter     transport.IPFilter
+	canonical    bool
+	compressions []codec.Compression
+
+	// accepted and acceptErr carry the results of acceptLoop, which
+	// starts with the first Accept.
+	accepted   chan *conn
+	acceptErr  chan error
+	acceptOnce sync.Once
+
+	// ctx ends the TLS handshakes in progress once the transport closes.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New returns a transport listening on addr once Listen is called.
+func New(addr string, opts ...Option) *Transport {
+	t := &Transport{
+		addr:      addr,
+		accepted:  make(chan *conn),
+		acceptErr: make(chan error),
+		done:      make(chan struct{}),
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Listen binds the listening socket unless a listener was supplied.
+func (t *Transport) Listen(ctx context.Context) error {
+	if t.ln != nil {
+		return nil
+	}
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", t.addr)
+	if err != nil {
+		return fmt.Errorf("tcp transport: %w", err)
+	}
+	t.ln = ln
+	return nil
+}
+
+// Addr returns the address the transport is listening on, or nil before
+// Listen.
+func (t *Transport) Addr() net.Addr {
+	if t.ln == nil {
+		return nil
+	}
+	return t.ln.Addr()
+}
+
+// Accept waits for the next client socket that passes the IP filter. On a
+// TLS listener, such as one made with tls.NewListener, it returns the
+// socket once its handshake has completed, so that the connection's peer
+// holds the negotiated state and the client's certificates; sockets
+// failing the handshake are closed.
+func (t *Transport) Accept(ctx context.Context) (transport.Connection, error) {
+	t.acceptOnce.Do(func() { go t.acceptLoop() })
+	select {
+	case c := <-t.accepted:
+		return c, nil
+	case err := <-t.acceptErr:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, transport.ErrClosed
+	}
+}
+
+// acceptLoop accepts sockets until the listener fails. TLS handshakes run
+// in their own goroutines, so that a slow client does not hold up the
+// others.
+func (t *Transport) acceptLoop() {
+	for {
+		nc, err := t.ln.Accept()
+		if err != nil {
+			select {
+			case <-t.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				err = transport.ErrClosed
+			}
+			select {
+			case t.acceptErr <- err:
+			case <-t.done:
+			}
+			return
+		}
+		if !t.ipFilter.Allowed(transport.AddrFromString(nc.RemoteAddr().String())) {
+			nc.Close()
+			continue
+		}
+		if tc, ok := nc.(*tls.Conn); ok {
+			go t.handshake(tc)
+			continue
+		}
+		t.deliver(t.newConn(nc))
+	}
+}
+
+func (t *Transport) handshake(tc *tls.Conn) {
+	ctx, cancel := context.WithTimeout(t.ctx, tlsHandshakeTimeout)
+	defer cancel()
+	if err := tc.HandshakeContext(ctx); err != nil {
+		tc.Close()
+		return
+	}
+	t.deliver(t.newConn(tc))
+}
+
+// deliver hands c to Accept, or closes it if the transport closes first.
+func (t *Transport) deliver(c *conn) {
+	select {
+	case t.accepted <- c:
+	case <-t.done:
+		c.Close()
+	}
+}
+
+func (t *Transport) newConn(nc net.Conn) *conn {
+	peer := transport.Peer{Transport: "tcp", RemoteAddr: nc.RemoteAddr().String()}
+	if tc, ok := nc.(*tls.Conn); ok {
+		state := tc.ConnectionState()
+		peer.TLS = &state
+	}
+	cl := codec.NewContentLength(nc, nc)
+	cl.SetCanonical(t.canonical)
+	cl.SetCompressions(t.compressions...)
+	return &conn{nc: nc, codec: cl, peer: peer}
+}
+
+// Close closes the listener.
+func (t *Transport) Close() error {
+	var err error
+	t.once.Do(func() {
+		close(t.done)
+		t.cancel()
+		if t.ln != nil {
+			err = t.ln.Close()
+		}
+	})
+	return err
+}
+
+type conn struct {
+	nc    net.Conn
+	codec *codec.ContentLength
+	peer  transport.Peer
+}
+
+func (c *conn) Read(ctx context.Context) (*protocol.Message, error) {
+	var msg protocol.Message
+	if err := c.codec.Decode(&msg); err != nil {
+		if errors.Is(err, net.ErrClosed) {
+			return nil, transport.ErrClosed
+		}
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (c *conn) Write(ctx context.Context, msg *protocol.Message) error {
+	return c.codec.Encode(msg)
+}
+
+func (c *conn) Bytes() (read, written int64) { return c.codec.Bytes() }
+
+func (c *conn) Compressions() []string { return c.codec.Compressions() }
+
+func (c *conn) EnableCompression(name string) error { return c.codec.EnableCompression(name) }
+
+func (c *conn) Close() error { return c.nc.Close() }
+
+func (c *conn) Peer() transport.Peer { return c.peer }
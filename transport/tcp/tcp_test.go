@@ -0,0 +1,102 @@
+package tcp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/tcp"
+)
+
+// certificate returns a self-signed certificate for name.
+func certificate(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestTLSPeer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln = tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{certificate(t, "server")},
+		ClientAuth:   tls.RequireAnyClientCert,
+	})
+	s := mcp.NewServer(mcp.WithTransport(tcp.New("", tcp.WithListener(ln))))
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{Name: "whoami"}, func(ctx *runtime.Context, _ struct{}) (*protocol.ToolCallResult, error) {
+		peer, _ := transport.PeerFromContext(ctx)
+		if peer.TLS == nil || len(peer.TLS.PeerCertificates) == 0 {
+			return nil, protocol.Errorf(protocol.InternalError, "no client certificate in %+v", peer)
+		}
+		name := peer.TLS.PeerCertificates[0].Subject.CommonName
+		return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(name)}}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx) }()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	// A client that never starts its handshake does not hold up the
+	// others.
+	stalled, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+
+	nc, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+		Certificates:       []tls.Certificate{certificate(t, "alice")},
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := mcp.NewClient(mcp.NewStreamConn(nc, nc, nc))
+	defer c.Close()
+	if _, err := c.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.CallTool(ctx, "whoami", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Content) != 1 || res.Content[0].Text != "alice" {
+		t.Errorf("whoami = %+v, want alice", res)
+	}
+}
@@ -0,0 +1,237 @@
+// Package trace records the traffic of transports to files for debugging.
+//
+// Wrap decorates any transport.Transport so that every connection writes
+// its messages, in both directions and with timestamps, to its own file.
+// Files hold one JSON-encoded Entry per line; read them back with ReadAll
+// or view them with zenmcp trace view. Traces contain tool arguments and
+// results verbatim, so treat them as sensitive.
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/idgen"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+)
+
+// Events recorded in a trace.
+const (
+	// EventOpen is the first entry of every trace and carries the peer.
+	EventOpen = "open"
+	// EventIn is a message read from the client. A frame that failed to
+	// parse is recorded with an error and no message.
+	EventIn = "in"
+	// EventOut is a message written to the client.
+	EventOut = "out"
+	// EventClose is the last entry of a trace.
+	EventClose = "close"
+)
+
+// Entry is one line of a trace file.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Event   string            `json:"event"`
+	Message *protocol.Message `json:"message,omitempty"`
+	// Error describes a failed read or write.
+	Error string `json:"error,omitempty"`
+	Peer  *Peer  `json:"peer,omitempty"`
+}
+
+// Peer is the part of a transport.Peer recorded when a connection opens.
+type Peer struct {
+	Transport  string `json:"transport"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	UserAgent  string `json:"userAgent,omitempty"`
+	Subject    string `json:"subject,omitempty"`
+	Tenant     string `json:"tenant,omitempty"`
+}
+
+// Config configures Wrap.
+type Config struct {
+	// Dir is the directory trace files are created in. It is created if
+	// missing. Files are named after the time the connection opened, a
+	// sequence number or trace ID and the transport, such as
+	// 20240501T120000.000-3-stdio.jsonl. Transports serving each request
+	// on its own connection, like plain HTTP, produce one file per request.
+	Dir string
+	// Filter, when set, selects the connections to trace.
+	Filter func(transport.Peer) bool
+	// Logger receives trace files that could not be written. Defaults to
+	// slog.Default().
+	Logger *slog.Logger
+	// IDs, when set, generates the trace IDs used in file names in place
+	// of the sequence number.
+	IDs idgen.Generator
+	// Clock stamps entries and names files. Defaults to clock.Real.
+	Clock clock.Clock
+}
+
+// Wrap returns a transport recording the traffic of its connections as
+// configured by cfg. Failing to create or write a trace file does not
+// affect the connection; the failure is logged and tracing of that
+// connection stops.
+func Wrap(t transport.Transport, cfg Config) transport.Transport {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	cfg.Clock = clock.Or(cfg.Clock)
+	return &traceTransport{Transport: t, cfg: cfg}
+}
+
+type traceTransport struct {
+	transport.Transport
+	cfg Config
+	seq atomic.Int64
+}
+
+// Accept implements transport.Transport.
+func (t *traceTransport) Accept(ctx context.Context) (transport.Connection, error) {
+	c, err := t.Transport.Accept(ctx)
+	if err != nil {
+		return nil, err
+	}
+	peer := c.Peer()
+	if t.cfg.Filter != nil && !t.cfg.Filter(peer) {
+		return c, nil
+	}
+	now := t.cfg.Clock.Now()
+	id := strconv.FormatInt(t.seq.Add(1), 10)
+	if t.cfg.IDs != nil {
+		id = t.cfg.IDs.NewID()
+	}
+	name := fmt.Sprintf("%s-%s-%s.jsonl", now.UTC().Format("20060102T150405.000"), id, peer.Transport)
+	f, err := create(t.cfg.Dir, name)
+	if err != nil {
+		t.cfg.Logger.Warn("trace: not tracing connection", "error", err)
+		return c, nil
+	}
+	tc := &conn{Connection: c, f: f, logger: t.cfg.Logger, clock: t.cfg.Clock}
+	p := &Peer{
+		Transport:  peer.Transport,
+		RemoteAddr: peer.RemoteAddr,
+		UserAgent:  peer.UserAgent,
+		Tenant:     peer.Tenant,
+	}
+	if peer.Principal != nil {
+		p.Subject = peer.Principal.Subject
+	}
+	tc.record(Entry{Time: now, Event: EventOpen, Peer: p})
+	return tc, nil
+}
+
+func create(dir, name string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+}
+
+type conn struct {
+	transport.Connection
+
+	logger *slog.Logger
+	clock  clock.Clock
+
+	mu sync.Mutex
+	f  *os.File // nil once closed or failed
+}
+
+// record appends e to the trace, one write per entry so that a trace cut
+// short by a crash is complete up to its last line.
+func (c *conn) record(e Entry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil {
+		return
+	}
+	if _, err := c.f.Write(line); err != nil {
+		c.logger.Warn("trace: stopped tracing connection", "file", c.f.Name(), "error", err)
+		c.f.Close()
+		c.f = nil
+	}
+}
+
+func (c *conn) Read(ctx context.Context) (*protocol.Message, error) {
+	msg, err := c.Connection.Read(ctx)
+	var rpcErr *protocol.Error
+	switch {
+	case err == nil:
+		c.record(Entry{Time: c.clock.Now(), Event: EventIn, Message: msg})
+	case errors.As(err, &rpcErr):
+		c.record(Entry{Time: c.clock.Now(), Event: EventIn, Error: rpcErr.Message})
+	}
+	return msg, err
+}
+
+func (c *conn) Write(ctx context.Context, msg *protocol.Message) error {
+	err := c.Connection.Write(ctx, msg)
+	e := Entry{Time: c.clock.Now(), Event: EventOut, Message: msg}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	c.record(e)
+	return err
+}
+
+func (c *conn) Close() error {
+	err := c.Connection.Close()
+	c.record(Entry{Time: c.clock.Now(), Event: EventClose})
+	c.mu.Lock()
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+	c.mu.Unlock()
+	return err
+}
+
+// Bytes implements transport.ByteCounter when the wrapped connection does.
+func (c *conn) Bytes() (read, written int64) {
+	if bc, ok := c.Connection.(transport.ByteCounter); ok {
+		return bc.Bytes()
+	}
+	return 0, 0
+}
+
+// RequestScoped implements transport.RequestScoped.
+func (c *conn) RequestScoped() bool {
+	rs, ok := c.Connection.(transport.RequestScoped)
+	return ok && rs.RequestScoped()
+}
+
+// ReadAll reads the entries of a trace file from r. A trace whose last
+// line was cut short, by a crash for instance, yields the complete
+// entries along with io.ErrUnexpectedEOF.
+func ReadAll(r io.Reader) ([]Entry, error) {
+	dec := json.NewDecoder(r)
+	var entries []Entry
+	for {
+		var e Entry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+}
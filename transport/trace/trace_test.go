@@ -0,0 +1,79 @@
+package trace_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/transport"
+	"github.com/hyperleex/zenmcp/transport/inproc"
+	"github.com/hyperleex/zenmcp/transport/trace"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	dir := t.TempDir()
+	in := inproc.New()
+	tr := trace.Wrap(in, trace.Config{Dir: dir, Clock: fake})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	accepted := make(chan transport.Connection, 1)
+	go func() {
+		c, _ := tr.Accept(ctx)
+		accepted <- c
+	}()
+	client, err := in.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.Fatal("accept failed")
+	}
+	msg, err := protocol.NewNotification(protocol.MethodInitialized, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(time.Second)
+	written := make(chan error, 1)
+	go func() { written <- client.Write(ctx, msg) }()
+	if _, err := server.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(time.Second)
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "20240501T120000.000-1-*.jsonl"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("trace files = %v, %v", files, err)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := trace.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		event string
+		at    time.Duration
+	}{{trace.EventOpen, 0}, {trace.EventIn, time.Second}, {trace.EventClose, 2 * time.Second}}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i, w := range want {
+		if e := entries[i]; e.Event != w.event || !e.Time.Equal(start.Add(w.at)) {
+			t.Errorf("entry %d = %s at %s, want %s at %s", i, e.Event, e.Time, w.event, start.Add(w.at))
+		}
+	}
+}
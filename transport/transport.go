@@ -0,0 +1,176 @@
+// Package transport defines the abstractions shared by all MCP transports.
+// Concrete implementations live in subpackages.
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"syscall"
+
+	"github.com/hyperleex/zenmcp/auth"
+	"github.com/hyperleex/zenmcp/protocol"
+)
+
+// ErrClosed is returned by Accept, Read and Write once a transport or
+// connection has been closed.
+var ErrClosed = errors.New("transport: closed")
+
+// ErrDisconnected is returned by Write when the client has gone away, such
+// as an HTTP client that abandoned its request. Transports wrap it around
+// the underlying error where there is one.
+var ErrDisconnected = errors.New("transport: client disconnected")
+
+// IsDisconnected reports whether err from Write means the client went away:
+// it abandoned the exchange, closed its end or reset the connection. It
+// does not match ErrClosed, which follows the server's own Close.
+func IsDisconnected(err error) bool {
+	return errors.Is(err, ErrDisconnected) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// Transport produces connections from clients.
+type Transport interface {
+	// Listen prepares the transport for accepting connections, binding any
+	// sockets it needs. It is called once before the first Accept.
+	Listen(ctx context.Context) error
+	// Accept blocks until a client connects, ctx is done or the transport
+	// is closed.
+	Accept(ctx context.Context) (Connection, error)
+	// Close stops accepting new connections.
+	Close() error
+}
+
+// Connection is a bidirectional message stream with a single client.
+type Connection interface {
+	// Read returns the next message from the client. It returns io.EOF when
+	// the client has finished sending. A *protocol.Error with code
+	// ParseError reports a malformed frame; the connection remains usable.
+	Read(ctx context.Context) (*protocol.Message, error)
+	// Write sends a message to the client.
+	Write(ctx context.Context, msg *protocol.Message) error
+	// Close releases the connection.
+	Close() error
+	// Peer describes the client on the other end of the connection.
+	Peer() Peer
+}
+
+// ByteCounter is implemented by connections that count the bytes they
+// transfer, framing included.
+type ByteCounter interface {
+	Bytes() (read, written int64)
+}
+
+// Compressor is implemented by connections that can compress the frames
+// they carry, with an algorithm negotiated during initialize through the
+// zenmcp/compression experimental capability.
+type Compressor interface {
+	// Compressions names the algorithms the connection can use, in order
+	// of preference. It is empty when compression is not configured.
+	Compressions() []string
+	// EnableCompression compresses the frames written from now on with
+	// the named algorithm, which the peer has agreed to.
+	EnableCompression(name string) error
+}
+
+// RequestScoped is implemented by connections that carry a single exchange
+// of an otherwise stateless transport, such as one plain HTTP request.
+// Per-session policies like the handshake timeout do not apply to them.
+type RequestScoped interface {
+	RequestScoped() bool
+}
+
+// SessionIdentified is implemented by connections whose transport assigns
+// them a session ID the client sees, such as Streamable HTTP sessions and
+// their Mcp-Session-Id header. The server identifies the runtime session
+// of such a connection by the same ID.
+type SessionIdentified interface {
+	SessionID() string
+}
+
+// Peer describes the client side of a connection. Fields a transport
+// cannot know are left empty.
+type Peer struct {
+	// Transport names the transport that accepted the connection, such as
+	// "stdio" or "http".
+	Transport string
+	// RemoteAddr is the client's network address. Behind a trusted reverse
+	// proxy it is the client address taken from X-Forwarded-For.
+	RemoteAddr string
+	// Proxy is the address of the trusted proxy the connection arrived
+	// through, when RemoteAddr was taken from forwarding headers.
+	Proxy string
+	// TLS is the TLS connection state for encrypted connections.
+	TLS *tls.ConnectionState
+	// UserAgent is the client's self-reported user agent.
+	UserAgent string
+	// Locale is the client's preferred locale as a BCP 47 tag, when the
+	// transport carries one, such as the HTTP Accept-Language header.
+	Locale string
+	// Principal is the authenticated client, or nil for anonymous
+	// connections and transports without authentication.
+	Principal *auth.Principal
+	// Tenant is the tenant the transport routed the connection to, such
+	// as the {tenant} path segment of the HTTP transport, or "".
+	Tenant string
+}
+
+// LogValue implements slog.LogValuer.
+func (p Peer) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("transport", p.Transport)}
+	if p.RemoteAddr != "" {
+		attrs = append(attrs, slog.String("remote_addr", p.RemoteAddr))
+	}
+	if p.Proxy != "" {
+		attrs = append(attrs, slog.String("proxy", p.Proxy))
+	}
+	if p.TLS != nil {
+		attrs = append(attrs,
+			slog.String("tls_version", tls.VersionName(p.TLS.Version)),
+			slog.String("tls_server_name", p.TLS.ServerName))
+	}
+	if p.UserAgent != "" {
+		attrs = append(attrs, slog.String("user_agent", p.UserAgent))
+	}
+	if p.Principal != nil {
+		attrs = append(attrs, slog.String("subject", p.Principal.Subject))
+	}
+	if p.Tenant != "" {
+		attrs = append(attrs, slog.String("tenant", p.Tenant))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type peerKey struct{}
+
+// ContextWithPeer returns a copy of ctx carrying p. The server attaches the
+// peer of every connection to the contexts handed to handlers.
+func ContextWithPeer(ctx context.Context, p Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// PeerFromContext returns the peer carried by ctx.
+func PeerFromContext(ctx context.Context) (Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(Peer)
+	return p, ok
+}
+
+type relatedRequestKey struct{}
+
+// ContextWithRelatedRequest returns a copy of ctx naming the request a
+// message is sent on behalf of. The server passes it to Write with the
+// notifications a handler sends, so that transports answering each request
+// on a stream of its own can deliver them on the request's stream.
+func ContextWithRelatedRequest(ctx context.Context, id protocol.ID) context.Context {
+	return context.WithValue(ctx, relatedRequestKey{}, id)
+}
+
+// RelatedRequestFromContext returns the request named by ctx.
+func RelatedRequestFromContext(ctx context.Context) (protocol.ID, bool) {
+	id, ok := ctx.Value(relatedRequestKey{}).(protocol.ID)
+	return id, ok
+}
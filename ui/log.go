@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logRecord is a log record as sent to the page.
+type logRecord struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// LogHandler returns a slog.Handler that keeps records for the console and
+// passes them on to next, which may be nil. Records below the console's
+// LogLevel are only passed on.
+func (c *Console) LogHandler(next slog.Handler) slog.Handler {
+	return &logHandler{console: c, next: next}
+}
+
+type logHandler struct {
+	console *Console
+	next    slog.Handler
+	// attrs are those added with WithAttrs, their keys qualified by the
+	// groups open at the time.
+	attrs []slog.Attr
+	group string
+}
+
+func (h *logHandler) keeps(level slog.Level) bool {
+	return level >= h.console.opts.LogLevel.Level()
+}
+
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.keeps(level) || h.next != nil && h.next.Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.keeps(r.Level) {
+		rec := logRecord{Time: r.Time, Level: r.Level.String(), Message: r.Message}
+		if len(h.attrs) > 0 || r.NumAttrs() > 0 {
+			rec.Attrs = make(map[string]interface{}, len(h.attrs)+r.NumAttrs())
+			for _, a := range h.attrs {
+				addAttr(rec.Attrs, "", a)
+			}
+			r.Attrs(func(a slog.Attr) bool {
+				addAttr(rec.Attrs, h.group, a)
+				return true
+			})
+		}
+		h.console.logs.add(rec)
+	}
+	if h.next != nil && h.next.Enabled(ctx, r.Level) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + a.Key
+		}
+		h2.attrs = append(h2.attrs, a)
+	}
+	if h.next != nil {
+		h2.next = h.next.WithAttrs(attrs)
+	}
+	return &h2
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	if h.next != nil {
+		h2.next = h.next.WithGroup(name)
+	}
+	return &h2
+}
+
+// addAttr stores a in m under its key prefixed with prefix, flattening
+// groups into dotted keys.
+func addAttr(m map[string]interface{}, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(m, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	switch v.Kind() {
+	case slog.KindDuration:
+		m[prefix+a.Key] = v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			m[prefix+a.Key] = err.Error()
+			return
+		}
+		m[prefix+a.Key] = v.String()
+	default:
+		m[prefix+a.Key] = v.Any()
+	}
+}
@@ -0,0 +1,193 @@
+// Package ui serves a small single-page console for inspecting a running
+// server from a browser: it lists the tools, resources and prompts, calls
+// tools and reads resources through the server's own MCP endpoint, tails
+// recent log records and shows the metrics.
+//
+// Mount a Console with the HTTP transport's WithUI option and route the
+// server's logs through LogHandler:
+//
+//	reg := metrics.NewRegistry()
+//	console := ui.New(ui.Options{Metrics: reg})
+//	logger := slog.New(console.LogHandler(slog.NewTextHandler(os.Stderr, nil)))
+//	s := mcp.NewServer(mcp.WithLogger(logger), mcp.WithMetrics(reg),
+//		mcp.WithTransport(http.New(":8080", http.WithUI(console))))
+package ui
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/metrics"
+)
+
+//go:embed index.html
+var page []byte
+
+// Options configures a Console.
+type Options struct {
+	// Endpoint is the URL path of the MCP endpoint the page talks to.
+	// Defaults to "/mcp".
+	Endpoint string
+	// Metrics is the registry shown in the metrics panel. The panel is
+	// hidden when it is nil.
+	Metrics *metrics.Registry
+	// MaxLogs bounds the log records kept for pages opened later.
+	// Defaults to 500.
+	MaxLogs int
+	// LogLevel is the lowest level of the records LogHandler keeps.
+	// Defaults to slog.LevelInfo.
+	LogLevel slog.Leveler
+}
+
+// Console is the web console. It serves:
+//
+//	GET /         the page
+//	GET /logs     recent and then live log records, as server-sent events
+//	GET /metrics  metrics in the Prometheus text format
+//
+// relative to where it is mounted.
+type Console struct {
+	opts Options
+	page []byte
+	logs *logBuffer
+}
+
+// New returns a console configured by opts.
+func New(opts Options) *Console {
+	if opts.Endpoint == "" {
+		opts.Endpoint = "/mcp"
+	}
+	if opts.MaxLogs <= 0 {
+		opts.MaxLogs = 500
+	}
+	if opts.LogLevel == nil {
+		opts.LogLevel = slog.LevelInfo
+	}
+	cfg, _ := json.Marshal(config{Endpoint: opts.Endpoint, Metrics: opts.Metrics != nil})
+	return &Console{
+		opts: opts,
+		page: bytes.Replace(page, []byte(`"__CONFIG__"`), cfg, 1),
+		logs: newLogBuffer(opts.MaxLogs),
+	}
+}
+
+// config holds the settings the page starts from. It is written into the
+// page, where json.Marshal's HTML escaping keeps it from closing the
+// script.
+type config struct {
+	Endpoint string `json:"endpoint"`
+	Metrics  bool   `json:"metrics"`
+}
+
+func (c *Console) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(c.page)
+	case "logs":
+		c.serveLogs(w, r)
+	case "metrics":
+		if c.opts.Metrics == nil {
+			http.NotFound(w, r)
+			return
+		}
+		c.opts.Metrics.Handler().ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveLogs streams the kept log records, then new ones as they are
+// logged, until the client goes away.
+func (c *Console) serveLogs(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	backlog, live, cancel := c.logs.subscribe()
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, rec := range backlog {
+		writeRecord(w, rec)
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case rec := <-live:
+			writeRecord(w, rec)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeRecord(w http.ResponseWriter, rec logRecord) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	w.Write([]byte("data: "))
+	w.Write(data)
+	w.Write([]byte("\n\n"))
+}
+
+// logBuffer keeps the most recent log records and hands new ones to the
+// pages streaming them.
+type logBuffer struct {
+	mu      sync.Mutex
+	records []logRecord
+	next    int // index of the oldest record once records is full
+	max     int
+	subs    map[chan logRecord]struct{}
+}
+
+func newLogBuffer(max int) *logBuffer {
+	return &logBuffer{max: max, subs: make(map[chan logRecord]struct{})}
+}
+
+func (b *logBuffer) add(rec logRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.records) < b.max {
+		b.records = append(b.records, rec)
+	} else {
+		b.records[b.next] = rec
+		b.next = (b.next + 1) % b.max
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- rec:
+		default: // a slow page misses records rather than slowing logging
+		}
+	}
+}
+
+// subscribe returns the kept records, oldest first, and a channel
+// receiving later ones until cancel is called.
+func (b *logBuffer) subscribe() (backlog []logRecord, live <-chan logRecord, cancel func()) {
+	ch := make(chan logRecord, 64)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	backlog = append(backlog, b.records[b.next:]...)
+	backlog = append(backlog, b.records[:b.next]...)
+	b.subs[ch] = struct{}{}
+	return backlog, ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,244 @@
+// Package validate checks decoded tool arguments before they reach a
+// handler.
+//
+// A value is checked in two ways. Argument types, and any struct nested in
+// them, may implement Validator. Struct fields may also name validators in
+// a `validate` tag, such as `validate:"nonzero,email"`, where each name
+// refers to a function installed with Register.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperleex/zenmcp/schema"
+)
+
+// Validator is implemented by argument types that check their own
+// invariants after decoding.
+type Validator interface {
+	Validate() error
+}
+
+// Func checks the value of a single field. It returns a plain error
+// describing the problem; the field path is added by the caller.
+type Func func(value interface{}) error
+
+var (
+	mu    sync.RWMutex
+	funcs = map[string]Func{
+		"nonzero": nonzero,
+	}
+)
+
+// Register installs fn as the field validator called name, replacing any
+// existing one. The "nonzero" validator is built in.
+func Register(name string, fn Func) {
+	mu.Lock()
+	defer mu.Unlock()
+	funcs[name] = fn
+}
+
+func lookup(name string) (Func, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fn, ok := funcs[name]
+	return fn, ok
+}
+
+func nonzero(v interface{}) error {
+	if v == nil || reflect.ValueOf(v).IsZero() {
+		return errors.New("must be set")
+	}
+	return nil
+}
+
+// FieldError reports an invalid field. Field is the JSON path of the
+// field, such as "items[2].name"; it is empty for errors about the value
+// as a whole.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Errors collects the field errors found in a value.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Value validates v, which is typically a pointer to decoded arguments. It
+// returns Errors when v is invalid, and any other error when a field names
+// an unregistered validator.
+func Value(v interface{}) error {
+	var errs Errors
+	if err := walk("", reflect.ValueOf(v), &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Checks reports whether Value has anything to check in values of type t:
+// whether t, or a type reachable from it through fields, elements and
+// pointers, implements Validator or has fields naming validators. Callers
+// decoding many values of a type skip Value when it does not.
+func Checks(t reflect.Type) bool {
+	return checks(t, make(map[reflect.Type]bool))
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+func checks(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if t.Implements(validatorType) || reflect.PointerTo(t).Implements(validatorType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		// The dynamic value may implement Validator.
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return checks(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if _, _, skip := schema.FieldName(f); skip || !f.IsExported() {
+				continue
+			}
+			if f.Tag.Get("validate") != "" || checks(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func walk(path string, v reflect.Value, errs *Errors) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.CanAddr() {
+		callValidator(path, v.Addr(), errs)
+	} else {
+		callValidator(path, v, errs)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return walkStruct(path, v, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walk(path+"["+strconv.Itoa(i)+"]", v.Index(i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := walk(join(path, fmt.Sprint(iter.Key().Interface())), iter.Value(), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkStruct(path string, v reflect.Value, errs *Errors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, skip := schema.FieldName(f)
+		if skip || !f.IsExported() {
+			continue
+		}
+		fieldPath := path
+		if !f.Anonymous || name != "" {
+			if name == "" {
+				name = f.Name
+			}
+			fieldPath = join(path, name)
+		}
+		fv := v.Field(i)
+		if tag := f.Tag.Get("validate"); tag != "" {
+			for _, vname := range strings.Split(tag, ",") {
+				vname = strings.TrimSpace(vname)
+				fn, ok := lookup(vname)
+				if !ok {
+					return fmt.Errorf("validate: field %s of %s uses unknown validator %q", f.Name, t, vname)
+				}
+				if err := fn(fv.Interface()); err != nil {
+					*errs = append(*errs, &FieldError{Field: fieldPath, Message: err.Error()})
+				}
+			}
+		}
+		if err := walk(fieldPath, fv, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callValidator calls v.Validate, if v implements Validator, and records
+// the errors it reports under path.
+func callValidator(path string, v reflect.Value, errs *Errors) {
+	if !v.CanInterface() {
+		return
+	}
+	val, ok := v.Interface().(Validator)
+	if !ok {
+		return
+	}
+	err := val.Validate()
+	if err == nil {
+		return
+	}
+	var list Errors
+	var fe *FieldError
+	switch {
+	case errors.As(err, &list):
+		for _, e := range list {
+			*errs = append(*errs, &FieldError{Field: join(path, e.Field), Message: e.Message})
+		}
+	case errors.As(err, &fe):
+		*errs = append(*errs, &FieldError{Field: join(path, fe.Field), Message: fe.Message})
+	default:
+		*errs = append(*errs, &FieldError{Field: path, Message: err.Error()})
+	}
+}
+
+func join(path, field string) string {
+	switch {
+	case path == "":
+		return field
+	case field == "":
+		return path
+	case strings.HasPrefix(field, "["):
+		return path + field
+	}
+	return path + "." + field
+}
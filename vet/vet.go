@@ -0,0 +1,405 @@
+// Package vet statically checks code that registers zenmcp tools,
+// resources and prompts for mistakes that otherwise only surface at run
+// time or as a surprising input schema:
+//
+//   - argument structs of typed tools with unexported fields, which are
+//     neither decoded nor described in the schema;
+//   - exported argument fields without a json tag, which are exposed under
+//     their Go name;
+//   - argument field types schema generation cannot describe, such as
+//     channels, functions or maps with non-string keys;
+//   - descriptors whose Name or URI differs from the key they are
+//     registered under.
+//
+// It is run by the zenmcp vet command.
+package vet
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	mcpPath      = "github.com/hyperleex/zenmcp/mcp"
+	registryPath = "github.com/hyperleex/zenmcp/registry"
+	schemaPath   = "github.com/hyperleex/zenmcp/schema"
+)
+
+// Diagnostic is a problem found by Check.
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// Dirs checks the Go packages in dirs. A directory ending in "/..." also
+// selects every package below it, skipping testdata, vendor and hidden
+// directories. Test files are not checked.
+func Dirs(dirs ...string) ([]Diagnostic, error) {
+	var expanded []string
+	for _, d := range dirs {
+		root, recursive := strings.CutSuffix(d, "/...")
+		if !recursive {
+			expanded = append(expanded, d)
+			continue
+		}
+		if root == "" || root == "." {
+			root = "."
+		}
+		err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !e.IsDir() {
+				return nil
+			}
+			name := e.Name()
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			expanded = append(expanded, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	var diags []Diagnostic
+	for _, dir := range expanded {
+		d, err := checkDir(fset, imp, dir)
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, d...)
+	}
+	return diags, nil
+}
+
+// checkDir type-checks the package in dir, if any, and checks it.
+func checkDir(fset *token.FileSet, imp types.Importer, dir string) ([]Diagnostic, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Instances: make(map[*ast.Ident]types.Instance),
+	}
+	var typeErrs []error
+	conf := types.Config{Importer: imp, Error: func(err error) { typeErrs = append(typeErrs, err) }}
+	pkg, _ := conf.Check(importPath(dir, bp.ImportPath), fset, files, info)
+	if len(typeErrs) > 0 {
+		return nil, fmt.Errorf("vet: %s: %w", dir, typeErrs[0])
+	}
+	return Check(fset, pkg, files, info), nil
+}
+
+// importPath returns the import path of the package in dir, derived from
+// the enclosing go.mod, or fallback when there is none.
+func importPath(dir, fallback string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fallback
+	}
+	for root := abs; ; {
+		if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if mod, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					rel, err := filepath.Rel(root, abs)
+					if err != nil {
+						return fallback
+					}
+					return path.Join(strings.Trim(mod, `"`), filepath.ToSlash(rel))
+				}
+			}
+			return fallback
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return fallback
+		}
+		root = parent
+	}
+}
+
+// Check inspects the type-checked files of pkg. info must record Types,
+// Uses and Instances.
+func Check(fset *token.FileSet, pkg *types.Package, files []*ast.File, info *types.Info) []Diagnostic {
+	c := &checker{fset: fset, pkg: pkg, info: info, checked: make(map[types.Type]bool)}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				c.call(call)
+			}
+			return true
+		})
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Pos, c.diags[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return c.diags
+}
+
+type checker struct {
+	fset    *token.FileSet
+	pkg     *types.Package
+	info    *types.Info
+	checked map[types.Type]bool
+	diags   []Diagnostic
+}
+
+func (c *checker) report(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{Pos: c.fset.Position(pos), Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) call(call *ast.CallExpr) {
+	var id *ast.Ident
+	switch fn := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	case *ast.IndexExpr:
+		id = identOf(fn.X)
+	case *ast.IndexListExpr:
+		id = identOf(fn.X)
+	}
+	if id == nil {
+		return
+	}
+	fn, ok := c.info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return
+	}
+	switch {
+	case fn.Pkg().Path() == mcpPath && fn.Name() == "RegisterToolTyped":
+		if inst, ok := c.info.Instances[id]; ok && inst.TypeArgs.Len() == 1 {
+			c.args(call.Args[len(call.Args)-1].Pos(), inst.TypeArgs.At(0))
+		}
+		if len(call.Args) >= 2 {
+			if _, ok := c.field(call.Args[1], "Name"); !ok && isLiteral(call.Args[1]) {
+				c.report(call.Args[1].Pos(), "tool descriptor has no Name")
+			}
+		}
+	case fn.Pkg().Path() == registryPath && isRegistryMethod(fn):
+		if len(call.Args) != 2 {
+			return
+		}
+		keyField := "Name"
+		if fn.Name() == "RegisterResource" {
+			keyField = "URI"
+		}
+		key, ok := c.stringConst(call.Args[0])
+		if !ok {
+			return
+		}
+		if fn.Name() == "RegisterTool" {
+			key, _, _ = strings.Cut(key, "@")
+		}
+		if v, ok := c.field(call.Args[1], keyField); ok && v != key {
+			c.report(call.Args[1].Pos(), "descriptor %s %q does not match registration key %q", keyField, v, key)
+		}
+	}
+}
+
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+func identOf(e ast.Expr) *ast.Ident {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		return e.Sel
+	}
+	return nil
+}
+
+func isRegistryMethod(fn *types.Func) bool {
+	switch fn.Name() {
+	case "RegisterTool", "RegisterResource", "RegisterPrompt":
+	default:
+		return false
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	return ok && sig.Recv() != nil
+}
+
+func isLiteral(e ast.Expr) bool {
+	e = unparen(e)
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		e = u.X
+	}
+	_, ok := e.(*ast.CompositeLit)
+	return ok
+}
+
+// field returns the constant string value of the named field in a
+// composite literal.
+func (c *checker) field(e ast.Expr, name string) (string, bool) {
+	e = unparen(e)
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		e = u.X
+	}
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return "", false
+	}
+	for _, el := range lit.Elts {
+		kv, ok := el.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if k, ok := kv.Key.(*ast.Ident); ok && k.Name == name {
+			return c.stringConst(kv.Value)
+		}
+	}
+	return "", false
+}
+
+func (c *checker) stringConst(e ast.Expr) (string, bool) {
+	tv, ok := c.info.Types[e]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// args checks the argument type of a typed tool, reporting at pos the
+// problems of types declared outside the checked package.
+func (c *checker) args(pos token.Pos, t types.Type) {
+	c.walk(pos, t, types.TypeString(t, types.RelativeTo(c.pkg)))
+}
+
+// walk checks t, reached through path, for types schema generation
+// cannot describe and for struct fields that are not decoded as intended.
+func (c *checker) walk(pos token.Pos, t types.Type, path string) {
+	if c.checked[t] {
+		return
+	}
+	c.checked[t] = true
+	if special(t) {
+		return
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		c.walk(pos, u.Elem(), path)
+	case *types.Slice:
+		c.walk(pos, u.Elem(), path+"[]")
+	case *types.Array:
+		c.walk(pos, u.Elem(), path+"[]")
+	case *types.Map:
+		if b, ok := u.Key().Underlying().(*types.Basic); !ok || b.Info()&types.IsString == 0 {
+			c.report(pos, "%s: map key type %s is not supported by schema generation; use string keys", path, u.Key())
+		}
+		c.walk(pos, u.Elem(), path+"[]")
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsComplex != 0, u.Kind() == types.Uintptr, u.Kind() == types.UnsafePointer:
+			c.report(pos, "%s: type %s is not supported by schema generation", path, t)
+		}
+	case *types.Chan, *types.Signature:
+		c.report(pos, "%s: type %s is not supported by schema generation", path, t)
+	case *types.Struct:
+		c.structFields(pos, t, u, path)
+	}
+}
+
+func (c *checker) structFields(pos token.Pos, t types.Type, s *types.Struct, path string) {
+	local := false
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() == c.pkg {
+		local = true
+	}
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		tag := reflect.StructTag(s.Tag(i))
+		jsonTag, hasJSON := tag.Lookup("json")
+		if jsonTag == "-" {
+			continue
+		}
+		at := pos
+		if local {
+			at = f.Pos()
+		}
+		name := path + "." + f.Name()
+		switch {
+		case f.Embedded():
+			if !f.Exported() && !isStruct(f.Type()) {
+				continue
+			}
+		case !f.Exported():
+			if local || !hasJSON {
+				c.report(at, "%s: unexported field is ignored when decoding arguments and in the input schema", name)
+			}
+			continue
+		case !hasJSON:
+			c.report(at, "%s: exported field has no json tag; it is exposed as %q", name, f.Name())
+		}
+		c.walk(at, f.Type(), name)
+	}
+}
+
+func isStruct(t types.Type) bool {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+// special reports whether schema generation handles t itself.
+func special(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	switch n.Obj().Pkg().Path() + "." + n.Obj().Name() {
+	case "time.Time", "encoding/json.RawMessage", schemaPath + ".Union":
+		return true
+	}
+	return false
+}
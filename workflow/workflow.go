@@ -0,0 +1,395 @@
+// Package workflow runs multi-step tool executions that pause for human
+// approval.
+//
+// A workflow is registered as a tool. Calling it starts a run that executes
+// steps like a compose pipeline until it reaches a step marked
+// RequireApproval. The run is then persisted in a store.Store and the call
+// returns a description of the pending step. Approval is deliberately not
+// a tool, so the model cannot approve its own actions: the client
+// application asks the user and calls the workflow/approve method, or the
+// operator calls Engine.Approve. Because runs live in the store, approval
+// may arrive on a different connection, after a reconnect.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/compose"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+)
+
+// Method names of the approval RPCs registered on the router.
+const (
+	MethodApprove = "workflow/approve"
+	MethodResume  = "workflow/resume"
+)
+
+// StatusToolName is the read-only tool reporting the state of a run.
+const StatusToolName = "workflow.status"
+
+// ErrRunNotFound is returned for unknown run IDs.
+var ErrRunNotFound = errors.New("workflow: run not found")
+
+// Step is one tool invocation of a workflow. Args is a compose template.
+type Step struct {
+	Name string
+	Tool string
+	Args map[string]interface{}
+	// RequireApproval pauses the run before this step until it is
+	// approved.
+	RequireApproval bool
+	// ApprovalMessage explains to the user what is being approved.
+	ApprovalMessage string
+}
+
+// Workflow describes a workflow tool.
+type Workflow struct {
+	Name        string
+	Description string
+	InputSchema map[string]interface{}
+	Tags        []string
+	Steps       []Step
+	// Output is a compose template selecting the result; when nil the
+	// last step's result is returned.
+	Output interface{}
+}
+
+// Status is the state of a run.
+type Status string
+
+// Run states.
+const (
+	StatusRunning          Status = "running"
+	StatusAwaitingApproval Status = "awaiting_approval"
+	StatusCompleted        Status = "completed"
+	StatusFailed           Status = "failed"
+	StatusRejected         Status = "rejected"
+)
+
+// Decision records an approval or rejection.
+type Decision struct {
+	Step     int       `json:"step"`
+	Approved bool      `json:"approved"`
+	Comment  string    `json:"comment,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Run is the persisted state of one workflow execution.
+type Run struct {
+	ID        string                   `json:"id"`
+	Workflow  string                   `json:"workflow"`
+	Status    Status                   `json:"status"`
+	Next      int                      `json:"next"`
+	State     *compose.State           `json:"state"`
+	Decisions []Decision               `json:"decisions,omitempty"`
+	Result    *protocol.ToolCallResult `json:"result,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+	CreatedAt time.Time                `json:"createdAt"`
+	UpdatedAt time.Time                `json:"updatedAt"`
+}
+
+// Engine executes workflows registered on a server.
+type Engine struct {
+	server *mcp.Server
+	store  store.Store
+
+	mu        sync.Mutex
+	workflows map[string]*Workflow
+	locks     map[string]*sync.Mutex
+}
+
+// NewEngine returns an engine persisting runs in st. It registers the
+// workflow.status tool and the workflow/approve and workflow/resume
+// methods on s. Runs are timestamped with the server's clock.
+func NewEngine(s *mcp.Server, st store.Store) (*Engine, error) {
+	e := &Engine{
+		server:    s,
+		store:     st,
+		workflows: make(map[string]*Workflow),
+		locks:     make(map[string]*sync.Mutex),
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        StatusToolName,
+		Description: "Report the status of a workflow run, including the step awaiting approval.",
+		Tags:        []string{"meta"},
+	}, e.statusTool); err != nil {
+		return nil, err
+	}
+	if err := s.Router().Handle(MethodApprove, e.handleApprove); err != nil {
+		return nil, err
+	}
+	if err := s.Router().Handle(MethodResume, e.handleResume); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Register validates w and registers it as a tool that starts a run.
+func (e *Engine) Register(w Workflow) error {
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("workflow: %q has no steps", w.Name)
+	}
+	for i, st := range w.Steps {
+		if st.Tool == "" {
+			return fmt.Errorf("workflow: %q: step %d has no tool", w.Name, i)
+		}
+	}
+	e.mu.Lock()
+	e.workflows[w.Name] = &w
+	e.mu.Unlock()
+	return e.server.Registry().RegisterTool(w.Name, registry.ToolDescriptor{
+		Description: w.Description,
+		InputSchema: w.InputSchema,
+		Tags:        w.Tags,
+		Handler: func(ctx context.Context, args json.RawMessage) (*protocol.ToolCallResult, error) {
+			return e.start(ctx, &w, args)
+		},
+	})
+}
+
+// Get returns the run with the given ID.
+func (e *Engine) Get(ctx context.Context, id string) (*Run, error) {
+	data, err := e.store.Get(ctx, runKey(id))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrRunNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("workflow: decode run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// Approve records a decision on the step run id is waiting on. An approved
+// run continues until the next approval step or completion; a rejected run
+// ends. The returned result describes the run's new state.
+func (e *Engine) Approve(ctx context.Context, id string, approved bool, comment string) (*protocol.ToolCallResult, error) {
+	unlock := e.lock(id)
+	defer unlock()
+	run, err := e.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != StatusAwaitingApproval {
+		return nil, fmt.Errorf("workflow: run %s is %s, not awaiting approval", id, run.Status)
+	}
+	run.Decisions = append(run.Decisions, Decision{Step: run.Next, Approved: approved, Comment: comment, At: e.server.Clock().Now()})
+	if !approved {
+		run.Status = StatusRejected
+		run.Result = &protocol.ToolCallResult{
+			Content: []protocol.Content{protocol.TextContent(fmt.Sprintf("Workflow run %s was rejected at step %d.", id, run.Next))},
+			IsError: true,
+		}
+		return run.Result, e.save(ctx, run)
+	}
+	w, err := e.workflow(run.Workflow)
+	if err != nil {
+		return nil, err
+	}
+	run.Status = StatusRunning
+	return e.advance(ctx, w, run, true)
+}
+
+// Resume continues a run left in the running state, for example because
+// the process stopped while it was executing.
+func (e *Engine) Resume(ctx context.Context, id string) (*protocol.ToolCallResult, error) {
+	unlock := e.lock(id)
+	defer unlock()
+	run, err := e.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != StatusRunning {
+		return nil, fmt.Errorf("workflow: run %s is %s, not running", id, run.Status)
+	}
+	w, err := e.workflow(run.Workflow)
+	if err != nil {
+		return nil, err
+	}
+	return e.advance(ctx, w, run, false)
+}
+
+func (e *Engine) start(ctx context.Context, w *Workflow, args json.RawMessage) (*protocol.ToolCallResult, error) {
+	state, err := compose.NewState(args)
+	if err != nil {
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: "+err.Error(), nil)
+	}
+	id := e.server.IDGenerator().NewID()
+	now := e.server.Clock().Now()
+	run := &Run{ID: id, Workflow: w.Name, Status: StatusRunning, State: state, CreatedAt: now, UpdatedAt: now}
+	unlock := e.lock(id)
+	defer unlock()
+	return e.advance(ctx, w, run, false)
+}
+
+// advance executes steps from run.Next, saving the run after each one.
+// approved reports whether the step at run.Next has just been approved.
+func (e *Engine) advance(ctx context.Context, w *Workflow, run *Run, approved bool) (*protocol.ToolCallResult, error) {
+	router := e.server.Router()
+	var last *protocol.ToolCallResult
+	for ; run.Next < len(w.Steps); run.Next++ {
+		st := w.Steps[run.Next]
+		args, err := run.State.Expand(st.Args)
+		if err != nil {
+			return e.fail(ctx, run, fmt.Errorf("step %d (%s): %w", run.Next, st.Tool, err))
+		}
+		if st.RequireApproval && !approved {
+			run.Status = StatusAwaitingApproval
+			if err := e.save(ctx, run); err != nil {
+				return nil, err
+			}
+			return pendingResult(run, st, args), nil
+		}
+		approved = false
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return e.fail(ctx, run, fmt.Errorf("step %d (%s): encode arguments: %w", run.Next, st.Tool, err))
+		}
+		res, err := router.CallTool(ctx, st.Tool, raw)
+		if err != nil {
+			return e.fail(ctx, run, fmt.Errorf("step %d (%s): %w", run.Next, st.Tool, err))
+		}
+		if res.IsError {
+			run.Status = StatusFailed
+			run.Result = compose.StepFailed(run.Next, st.Tool, res)
+			return run.Result, e.save(ctx, run)
+		}
+		run.State.Record(st.Name, compose.StepOutput(res))
+		last = res
+		if err := e.save(ctx, run); err != nil {
+			return nil, err
+		}
+	}
+
+	result := last
+	if w.Output != nil || last == nil {
+		var err error
+		if result, err = run.State.Result(w.Output); err != nil {
+			return e.fail(ctx, run, err)
+		}
+	}
+	run.Status = StatusCompleted
+	run.Result = result
+	return result, e.save(ctx, run)
+}
+
+func (e *Engine) fail(ctx context.Context, run *Run, err error) (*protocol.ToolCallResult, error) {
+	run.Status = StatusFailed
+	run.Error = err.Error()
+	if serr := e.save(ctx, run); serr != nil {
+		return nil, errors.Join(err, serr)
+	}
+	return nil, err
+}
+
+func (e *Engine) save(ctx context.Context, run *Run) error {
+	run.UpdatedAt = e.server.Clock().Now()
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("workflow: encode run %s: %w", run.ID, err)
+	}
+	return e.store.Put(ctx, runKey(run.ID), data)
+}
+
+func (e *Engine) workflow(name string) (*Workflow, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	w, ok := e.workflows[name]
+	if !ok {
+		return nil, fmt.Errorf("workflow: %q is not registered", name)
+	}
+	return w, nil
+}
+
+// lock serializes work on one run within this process.
+func (e *Engine) lock(id string) func() {
+	e.mu.Lock()
+	l, ok := e.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		e.locks[id] = l
+	}
+	e.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// pendingResult tells the caller that run is waiting for approval of st.
+func pendingResult(run *Run, st Step, args interface{}) *protocol.ToolCallResult {
+	msg := st.ApprovalMessage
+	if msg == "" {
+		msg = fmt.Sprintf("Step %d calls %s.", run.Next, st.Tool)
+	}
+	details, _ := json.Marshal(map[string]interface{}{
+		"runId":     run.ID,
+		"status":    run.Status,
+		"step":      run.Next,
+		"tool":      st.Tool,
+		"message":   msg,
+		"arguments": args,
+	})
+	return &protocol.ToolCallResult{Content: []protocol.Content{
+		protocol.TextContent(fmt.Sprintf("Workflow run %s is waiting for user approval: %s", run.ID, msg)),
+		protocol.TextContent(string(details)),
+	}}
+}
+
+type approveParams struct {
+	RunID   string `json:"runId"`
+	Approve bool   `json:"approve"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func (e *Engine) handleApprove(ctx *runtime.Context, params json.RawMessage) (interface{}, error) {
+	var p approveParams
+	if err := json.Unmarshal(params, &p); err != nil || p.RunID == "" {
+		return nil, protocol.NewError(protocol.InvalidParams, "runId is required", nil)
+	}
+	return rpcResult(e.Approve(ctx, p.RunID, p.Approve, p.Comment))
+}
+
+func (e *Engine) handleResume(ctx *runtime.Context, params json.RawMessage) (interface{}, error) {
+	var p approveParams
+	if err := json.Unmarshal(params, &p); err != nil || p.RunID == "" {
+		return nil, protocol.NewError(protocol.InvalidParams, "runId is required", nil)
+	}
+	return rpcResult(e.Resume(ctx, p.RunID))
+}
+
+// rpcResult maps engine errors onto JSON-RPC errors.
+func rpcResult(res *protocol.ToolCallResult, err error) (interface{}, error) {
+	if errors.Is(err, ErrRunNotFound) {
+		return nil, protocol.NewError(protocol.InvalidParams, err.Error(), nil)
+	}
+	return res, err
+}
+
+type statusArgs struct {
+	RunID string `json:"runId" description:"ID of the workflow run." validate:"nonzero"`
+}
+
+func (e *Engine) statusTool(ctx *runtime.Context, args statusArgs) (*protocol.ToolCallResult, error) {
+	run, err := e.Get(ctx, args.RunID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+func runKey(id string) string { return "workflow/runs/" + id }
@@ -0,0 +1,204 @@
+// Package zenmcptest provides helpers for testing zenmcp servers.
+//
+// Golden runs a sequence of requests against a server, in one session, and
+// compares each response with a JSON file checked in next to the tests.
+// Any change a client could observe then shows up as a golden file diff in
+// code review. Set ZENMCP_UPDATE_GOLDEN=1 to rewrite the files after an
+// intended change.
+package zenmcptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/codec"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// UpdateEnv is the environment variable that makes Golden rewrite golden
+// files instead of comparing against them.
+const UpdateEnv = "ZENMCP_UPDATE_GOLDEN"
+
+// Case is one request of a golden run.
+type Case struct {
+	// Name names the golden file, <Name>.json. It must be unique within a
+	// run.
+	Name   string
+	Method string
+	Params interface{}
+}
+
+// Initialize returns the case performing the initialize handshake with an
+// empty set of client capabilities.
+func Initialize() Case {
+	return Case{
+		Name:   "initialize",
+		Method: protocol.MethodInitialize,
+		Params: protocol.InitializeParams{
+			ProtocolVersion: protocol.LatestProtocolVersion,
+			ClientInfo:      protocol.Implementation{Name: "zenmcptest", Version: "1"},
+		},
+	}
+}
+
+// ToolCall returns a case calling tool name with args, named
+// "call-<name>-<label>".
+func ToolCall(name, label string, args interface{}) Case {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		panic(fmt.Sprintf("zenmcptest: encode arguments of %s: %v", name, err))
+	}
+	return Case{
+		Name:   "call-" + name + "-" + label,
+		Method: protocol.MethodToolsCall,
+		Params: protocol.ToolCallParams{Name: name, Arguments: raw},
+	}
+}
+
+// Canonical returns the requests every server should snapshot: the
+// handshake and the listings of its tools and resources.
+func Canonical() []Case {
+	return []Case{
+		Initialize(),
+		{Name: "tools-list", Method: protocol.MethodToolsList},
+		{Name: "resources-list", Method: protocol.MethodResourcesList},
+	}
+}
+
+// Golden sends the cases to s in order, within a single session, and
+// compares every response with dir/<case>.json. Each file holds the
+// request and the response, indented and with object keys sorted so that
+// diffs stay readable.
+func Golden(t testing.TB, s *mcp.Server, dir string, cases ...Case) {
+	t.Helper()
+	update := os.Getenv(UpdateEnv) != ""
+	if update {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("zenmcptest: %v", err)
+		}
+	}
+	ctx := runtime.WithSession(context.Background(), runtime.NewSession())
+	seen := make(map[string]bool)
+	for i, c := range cases {
+		if seen[c.Name] {
+			t.Fatalf("zenmcptest: duplicate case name %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		req, err := protocol.NewRequest(protocol.NewNumberID(int64(i+1)), c.Method, c.Params)
+		if err != nil {
+			t.Fatalf("zenmcptest: %s: %v", c.Name, err)
+		}
+		resp := s.Router().Dispatch(ctx, req)
+		got, err := snapshot(req, resp)
+		if err != nil {
+			t.Fatalf("zenmcptest: %s: %v", c.Name, err)
+		}
+
+		path := filepath.Join(dir, c.Name+".json")
+		if update {
+			if err := os.WriteFile(path, got, 0o644); err != nil {
+				t.Fatalf("zenmcptest: %v", err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("zenmcptest: %s: %v (run with %s=1 to create it)", c.Name, err, UpdateEnv)
+			continue
+		}
+		if !bytes.Equal(want, got) {
+			t.Errorf("zenmcptest: %s differs from %s (run with %s=1 to accept):\n%s",
+				c.Name, path, UpdateEnv, diff(string(want), string(got)))
+		}
+	}
+}
+
+// snapshot renders a request and its response as the content of a golden
+// file: their canonical encoding, indented, so that files only change
+// when the messages do.
+func snapshot(req, resp *protocol.Message) ([]byte, error) {
+	doc := struct {
+		Request  *protocol.Message `json:"request"`
+		Response *protocol.Message `json:"response"`
+	}{req, resp}
+	data, err := codec.Canonical(doc)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// diffContext is the number of unchanged lines shown around a change.
+const diffContext = 3
+
+// diff returns a line diff of want and got, marking removed lines with "-"
+// and added lines with "+". Unchanged lines far from any change are
+// elided.
+func diff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+
+	keep := make([]bool, len(lines))
+	for n, l := range lines {
+		if l[0] == ' ' {
+			continue
+		}
+		for k := max(0, n-diffContext); k <= min(len(lines)-1, n+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+	var sb strings.Builder
+	for n, l := range lines {
+		if keep[n] {
+			sb.WriteString(l + "\n")
+		} else if n == 0 || keep[n-1] {
+			sb.WriteString("  ...\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,426 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Change is a file that differs between two snapshots.
+type Change struct {
+	Path string `json:"path"`
+	// Status is "added", "modified" or "deleted".
+	Status string `json:"status"`
+	// Additions and Deletions count changed lines when a patch was
+	// computed.
+	Additions int  `json:"additions,omitempty"`
+	Deletions int  `json:"deletions,omitempty"`
+	Binary    bool `json:"binary,omitempty"`
+
+	old, new File
+}
+
+// diffTrees appends to out the files that differ between trees a and b,
+// which lie at dir. A zero hash stands for an empty tree.
+func (r *Repository) diffTrees(a, b Hash, dir string, out *[]Change) error {
+	if a == b {
+		return nil
+	}
+	ea, err := r.treeOrEmpty(a)
+	if err != nil {
+		return err
+	}
+	eb, err := r.treeOrEmpty(b)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string][2]*TreeEntry)
+	for i := range ea {
+		pair := byName[ea[i].Name]
+		pair[0] = &ea[i]
+		byName[ea[i].Name] = pair
+	}
+	for i := range eb {
+		pair := byName[eb[i].Name]
+		pair[1] = &eb[i]
+		byName[eb[i].Name] = pair
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		x, y := byName[name][0], byName[name][1]
+		p := path.Join(dir, name)
+		var subA, subB Hash
+		var fileA, fileB *TreeEntry
+		if x != nil && x.IsDir() {
+			subA = x.Hash
+		} else if x != nil && !x.IsSubmodule() {
+			fileA = x
+		}
+		if y != nil && y.IsDir() {
+			subB = y.Hash
+		} else if y != nil && !y.IsSubmodule() {
+			fileB = y
+		}
+		if err := r.diffTrees(subA, subB, p, out); err != nil {
+			return err
+		}
+		switch {
+		case fileA == nil && fileB == nil:
+		case fileA == nil:
+			*out = append(*out, Change{Path: p, Status: "added", new: File{p, fileB.Mode, fileB.Hash}})
+		case fileB == nil:
+			*out = append(*out, Change{Path: p, Status: "deleted", old: File{p, fileA.Mode, fileA.Hash}})
+		case fileA.Hash != fileB.Hash || fileA.Mode != fileB.Mode:
+			*out = append(*out, Change{Path: p, Status: "modified", old: File{p, fileA.Mode, fileA.Hash}, new: File{p, fileB.Mode, fileB.Hash}})
+		}
+	}
+	return nil
+}
+
+func (r *Repository) treeOrEmpty(h Hash) ([]TreeEntry, error) {
+	if h.IsZero() {
+		return nil, nil
+	}
+	return r.tree(h)
+}
+
+// diffSnapshots returns the files that differ between two flat
+// snapshots, in path order.
+func diffSnapshots(a, b map[string]File) []Change {
+	var out []Change
+	for p, x := range a {
+		y, ok := b[p]
+		switch {
+		case !ok:
+			out = append(out, Change{Path: p, Status: "deleted", old: x})
+		case x.Hash != y.Hash || x.Mode != y.Mode:
+			out = append(out, Change{Path: p, Status: "modified", old: x, new: y})
+		}
+	}
+	for p, y := range b {
+		if _, ok := a[p]; !ok {
+			out = append(out, Change{Path: p, Status: "added", new: y})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// isBinary reports whether data looks like a binary file, the way git
+// decides: by a NUL byte in its first 8000 bytes.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// splitLines splits data into lines, each keeping its newline.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		lines = append(lines, string(data[:i]))
+		data = data[i:]
+	}
+	return lines
+}
+
+// edit is a step of an edit script turning lines a into lines b: keep
+// (' '), delete ('-') or insert ('+'). a and b are the positions in
+// either sequence when the step is taken.
+type edit struct {
+	kind byte
+	a, b int
+}
+
+// maxEditDistance bounds the work spent finding a minimal diff; beyond
+// it the differing middle of the files is replaced wholesale.
+const maxEditDistance = 1000
+
+// diffLines returns an edit script turning a into b.
+func diffLines(a, b []string) []edit {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	var edits []edit
+	for i := 0; i < pre; i++ {
+		edits = append(edits, edit{' ', i, i})
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	middle, ok := myers(ma, mb)
+	if !ok {
+		middle = middle[:0]
+		for i := range ma {
+			middle = append(middle, edit{'-', i, 0})
+		}
+		for j := range mb {
+			middle = append(middle, edit{'+', len(ma), j})
+		}
+	}
+	for _, e := range middle {
+		edits = append(edits, edit{e.kind, e.a + pre, e.b + pre})
+	}
+	for i := 0; i < suf; i++ {
+		edits = append(edits, edit{' ', len(a) - suf + i, len(b) - suf + i})
+	}
+	return compact(a, b, edits)
+}
+
+// compact slides each group of changed lines as far down as lines equal
+// to its first allow, as git does, so that ambiguous changes such as an
+// added function followed by a blank line are placed consistently.
+func compact(a, b []string, edits []edit) []edit {
+	delA, addB := make([]bool, len(a)), make([]bool, len(b))
+	for _, e := range edits {
+		switch e.kind {
+		case '-':
+			delA[e.a] = true
+		case '+':
+			addB[e.b] = true
+		}
+	}
+	slide(a, delA)
+	slide(b, addB)
+	out := edits[:0]
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && delA[i]:
+			out = append(out, edit{'-', i, j})
+			i++
+		case j < len(b) && addB[j]:
+			out = append(out, edit{'+', i, j})
+			j++
+		default:
+			out = append(out, edit{' ', i, j})
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func slide(lines []string, changed []bool) {
+	for s := 0; s < len(lines); {
+		if !changed[s] {
+			s++
+			continue
+		}
+		e := s
+		for e < len(lines) && changed[e] {
+			e++
+		}
+		for e < len(lines) && !changed[e] && lines[s] == lines[e] {
+			changed[s], changed[e] = false, true
+			s++
+			e++
+			for e < len(lines) && changed[e] {
+				e++
+			}
+		}
+		s = e
+	}
+}
+
+// myers finds a shortest edit script with Myers' algorithm. It gives up,
+// returning false, when the edit distance exceeds maxEditDistance.
+func myers(a, b []string) ([]edit, bool) {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+	limit := min(n+m, maxEditDistance)
+	off := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] holds v[k] for k in [-d-1, d+1] as it was before round d.
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(trace [][]int, x, y int) []edit {
+	var rev []edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, edit{' ', x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				rev = append(rev, edit{'+', x, y})
+			} else {
+				x--
+				rev = append(rev, edit{'-', x, y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev
+}
+
+// contextLines is the number of unchanged lines shown around changes.
+const contextLines = 3
+
+// writePatch writes the unified diff of c, whose contents are a and b,
+// and fills in its line counts.
+func writePatch(w *strings.Builder, c *Change, a, b []byte) {
+	fmt.Fprintf(w, "diff --git a/%s b/%s\n", c.Path, c.Path)
+	switch c.Status {
+	case "added":
+		fmt.Fprintf(w, "new file mode %o\n", c.new.Mode)
+	case "deleted":
+		fmt.Fprintf(w, "deleted file mode %o\n", c.old.Mode)
+	default:
+		if c.old.Mode != c.new.Mode {
+			fmt.Fprintf(w, "old mode %o\nnew mode %o\n", c.old.Mode, c.new.Mode)
+		}
+	}
+	if bytes.Equal(a, b) {
+		return
+	}
+	fmt.Fprintf(w, "index %s..%s", abbrev(c.old.Hash), abbrev(c.new.Hash))
+	if c.Status == "modified" && c.old.Mode == c.new.Mode {
+		fmt.Fprintf(w, " %o", c.new.Mode)
+	}
+	w.WriteByte('\n')
+	from, to := "a/"+c.Path, "b/"+c.Path
+	if c.Status == "added" {
+		from = "/dev/null"
+	}
+	if c.Status == "deleted" {
+		to = "/dev/null"
+	}
+	if isBinary(a) || isBinary(b) {
+		c.Binary = true
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", from, to)
+		return
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+	la, lb := splitLines(a), splitLines(b)
+	edits := diffLines(la, lb)
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-contextLines)
+		last := i
+		for j := i; j < len(edits) && j-last <= 2*contextLines+1; j++ {
+			if edits[j].kind != ' ' {
+				last = j
+			}
+		}
+		stop := min(len(edits), last+contextLines+1)
+		hunk := edits[start:stop]
+		var na, nb int
+		for _, e := range hunk {
+			if e.kind != '+' {
+				na++
+			}
+			if e.kind != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%s +%s @@%s\n", hunkRange(hunk[0].a, na), hunkRange(hunk[0].b, nb), funcContext(la, hunk[0].a))
+		for _, e := range hunk {
+			line := ""
+			switch e.kind {
+			case '-':
+				line = la[e.a]
+				c.Deletions++
+			case '+':
+				line = lb[e.b]
+				c.Additions++
+			default:
+				line = la[e.a]
+			}
+			w.WriteByte(e.kind)
+			w.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+}
+
+// abbrev returns h abbreviated as git abbreviates the hashes of index
+// lines; the zero hash of a missing side is all zeros.
+func abbrev(h Hash) string {
+	return h.String()[:7]
+}
+
+// funcContext returns the hunk header suffix naming the enclosing
+// definition of line at: with git's default rule, the last line before it
+// that starts with a letter, '_' or '$'.
+func funcContext(lines []string, at int) string {
+	for i := min(at, len(lines)) - 1; i >= 0; i-- {
+		line := lines[i]
+		if c := line[0]; c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+			if len(line) > 80 {
+				line = line[:80]
+			}
+			return " " + strings.TrimRight(line, " \t\r\n")
+		}
+	}
+	return ""
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
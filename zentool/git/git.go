@@ -0,0 +1,389 @@
+// Package git gives agents read-only access to git repositories: tools
+// to inspect the status, history, diffs, blame and content of
+// repositories allow-listed by the server.
+//
+// Repositories are read directly from their files, without running the
+// git command, so that no argument can reach a shell or a git
+// configuration hook. The reader covers what the tools need: loose and
+// packed objects, references, the index and gitignore rules. SHA-256
+// repositories are not supported, and renames are reported as a deletion
+// and an addition.
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Names of the tools registered by Install.
+const (
+	StatusToolName = "git.status"
+	LogToolName    = "git.log"
+	DiffToolName   = "git.diff"
+	ShowToolName   = "git.show"
+	BlameToolName  = "git.blame"
+	GrepToolName   = "git.grep"
+)
+
+// Config configures the git tools.
+type Config struct {
+	// Repositories maps the names callers use to the paths of the
+	// repositories they may read. Required.
+	Repositories map[string]string
+	// MaxPatchBytes bounds the size of the patches returned by git.diff
+	// and git.show. Defaults to 256 KiB.
+	MaxPatchBytes int
+	// MaxResults caps the number of commits, matches and untracked files
+	// a call returns. Defaults to 200.
+	MaxResults int
+	// MaxBlameCommits bounds the number of commits git.blame examines.
+	// Defaults to 1000.
+	MaxBlameCommits int
+}
+
+// Git serves the git tools.
+type Git struct {
+	cfg   Config
+	repos map[string]*Repository
+}
+
+// Install opens the configured repositories and registers the git tools
+// on s.
+func Install(s *mcp.Server, cfg Config) (*Git, error) {
+	if len(cfg.Repositories) == 0 {
+		return nil, errors.New("git: at least one repository is required")
+	}
+	if cfg.MaxPatchBytes <= 0 {
+		cfg.MaxPatchBytes = 256 << 10
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = 200
+	}
+	if cfg.MaxBlameCommits <= 0 {
+		cfg.MaxBlameCommits = 1000
+	}
+	g := &Git{cfg: cfg, repos: make(map[string]*Repository)}
+	for name, dir := range cfg.Repositories {
+		r, err := Open(dir)
+		if err != nil {
+			return nil, err
+		}
+		g.repos[name] = r
+	}
+	tags := []string{"git"}
+	yes := true
+	readOnly := &protocol.ToolAnnotations{ReadOnlyHint: &yes}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        StatusToolName,
+		Description: "Show the current branch, the changes staged for commit, the changes not staged and the untracked files of a repository.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, g.statusTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        LogToolName,
+		Description: "List the commits of a repository, newest first, optionally only those changing a path.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, g.logTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        DiffToolName,
+		Description: "Compare two revisions, a revision and the working tree, or the index and the working tree, as a unified diff.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, g.diffTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ShowToolName,
+		Description: "Show a commit: its author, message and changes.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, g.showTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        BlameToolName,
+		Description: "Show which commit last changed each line of a file.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, g.blameTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        GrepToolName,
+		Description: "Search the files of a revision for lines matching a regular expression.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, g.grepTool); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// repo returns the repository a call names. The name may be omitted when
+// only one repository is configured.
+func (g *Git) repo(name string) (*Repository, error) {
+	if name == "" && len(g.repos) == 1 {
+		for _, r := range g.repos {
+			return r, nil
+		}
+	}
+	if r, ok := g.repos[name]; ok {
+		return r, nil
+	}
+	names := make([]string, 0, len(g.repos))
+	for n := range g.repos {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, protocol.NewError(protocol.InvalidParams,
+		fmt.Sprintf("invalid arguments: unknown repository %q; available: %s", name, strings.Join(names, ", ")), nil)
+}
+
+// cleanPath checks that p is a path inside the repository and returns it
+// in canonical form, "" standing for the root.
+func cleanPath(p string) (string, error) {
+	if p == "" {
+		return "", nil
+	}
+	clean := path.Clean(strings.ReplaceAll(p, `\`, "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", protocol.NewError(protocol.InvalidParams, fmt.Sprintf("invalid arguments: path %q is outside the repository", p), nil)
+	}
+	if clean == "." {
+		return "", nil
+	}
+	return clean, nil
+}
+
+// limit returns the number of results to return for a requested n, def
+// when none is requested.
+func (g *Git) limit(n, def int) int {
+	if n <= 0 {
+		n = def
+	}
+	return min(n, g.cfg.MaxResults)
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+type statusArgs struct {
+	Repo string `json:"repo,omitempty" description:"Name of the repository; optional when only one is available."`
+}
+
+func (g *Git) statusTool(ctx *runtime.Context, args statusArgs) (*protocol.ToolCallResult, error) {
+	r, err := g.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	st, err := r.Status(g.cfg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(st)
+}
+
+type logArgs struct {
+	Repo  string `json:"repo,omitempty" description:"Name of the repository; optional when only one is available."`
+	Rev   string `json:"rev,omitempty" description:"Branch, tag or commit to start from." default:"HEAD"`
+	Path  string `json:"path,omitempty" description:"Only list commits changing this file or directory."`
+	Since string `json:"since,omitempty" description:"Only list commits made since this RFC 3339 time."`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of commits to list." default:"20"`
+	Skip  int    `json:"skip,omitempty" description:"Number of commits to skip, for paging."`
+}
+
+func (g *Git) logTool(ctx *runtime.Context, args logArgs) (*protocol.ToolCallResult, error) {
+	r, err := g.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cleanPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	opts := LogOptions{Rev: args.Rev, Path: p, Max: g.limit(args.Limit, 20), Skip: args.Skip}
+	if args.Since != "" {
+		if opts.Since, err = time.Parse(time.RFC3339, args.Since); err != nil {
+			return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: since: "+err.Error(), nil)
+		}
+	}
+	commits, err := r.Log(opts)
+	if err != nil {
+		return nil, err
+	}
+	if commits == nil {
+		commits = []*Commit{}
+	}
+	return jsonResult(map[string]interface{}{"commits": commits})
+}
+
+type diffArgs struct {
+	Repo   string `json:"repo,omitempty" description:"Name of the repository; optional when only one is available."`
+	From   string `json:"from,omitempty" description:"Revision to compare from. Without it, the working tree is compared with the index."`
+	To     string `json:"to,omitempty" description:"Revision to compare to. Without it, from is compared with the working tree."`
+	Staged bool   `json:"staged,omitempty" description:"Compare the changes staged for commit with from, or HEAD."`
+	Path   string `json:"path,omitempty" description:"Only compare this file or directory."`
+	Stat   bool   `json:"stat,omitempty" description:"Only list the changed files, without the patch."`
+}
+
+func (g *Git) diffTool(ctx *runtime.Context, args diffArgs) (*protocol.ToolCallResult, error) {
+	r, err := g.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cleanPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if args.To != "" && args.From == "" {
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: to requires from", nil)
+	}
+	limit := g.cfg.MaxPatchBytes
+	if args.Stat {
+		limit = 0
+	}
+	patch, err := r.Diff(DiffOptions{From: args.From, To: args.To, Staged: args.Staged, Path: p}, limit)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(patch)
+}
+
+type showArgs struct {
+	Repo string `json:"repo,omitempty" description:"Name of the repository; optional when only one is available."`
+	Rev  string `json:"rev,omitempty" description:"Commit to show." default:"HEAD"`
+	Path string `json:"path,omitempty" description:"Only show the changes to this file or directory."`
+	Stat bool   `json:"stat,omitempty" description:"Only list the changed files, without the patch."`
+}
+
+func (g *Git) showTool(ctx *runtime.Context, args showArgs) (*protocol.ToolCallResult, error) {
+	r, err := g.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cleanPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	limit := g.cfg.MaxPatchBytes
+	if args.Stat {
+		limit = 0
+	}
+	c, patch, err := r.Show(args.Rev, p, limit)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(struct {
+		Commit *Commit `json:"commit"`
+		*Patch
+	}{c, patch})
+}
+
+type blameArgs struct {
+	Repo      string `json:"repo,omitempty" description:"Name of the repository; optional when only one is available."`
+	Path      string `json:"path" description:"File to blame." validate:"nonzero"`
+	Rev       string `json:"rev,omitempty" description:"Revision to blame the file at." default:"HEAD"`
+	StartLine int    `json:"startLine,omitempty" description:"First line to return, counting from 1."`
+	EndLine   int    `json:"endLine,omitempty" description:"Last line to return."`
+}
+
+// blameCommit summarizes a commit named by blamed lines.
+type blameCommit struct {
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	When    time.Time `json:"when"`
+	Subject string    `json:"subject"`
+}
+
+func (g *Git) blameTool(ctx *runtime.Context, args blameArgs) (*protocol.ToolCallResult, error) {
+	r, err := g.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cleanPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	lines, commits, err := r.Blame(args.Rev, p, g.cfg.MaxBlameCommits)
+	if err != nil {
+		return nil, err
+	}
+	if args.EndLine > 0 && args.EndLine < len(lines) {
+		lines = lines[:args.EndLine]
+	}
+	if args.StartLine > 1 {
+		lines = lines[min(args.StartLine-1, len(lines)):]
+	}
+	named := make(map[string]blameCommit)
+	for _, l := range lines {
+		if _, ok := named[l.Commit]; !ok {
+			c := commits[l.Commit]
+			named[l.Commit] = blameCommit{Author: c.Author.Name, Email: c.Author.Email, When: c.Author.When, Subject: c.Subject()}
+		}
+	}
+	if lines == nil {
+		lines = []BlameLine{}
+	}
+	return jsonResult(map[string]interface{}{"lines": lines, "commits": named})
+}
+
+type grepArgs struct {
+	Repo       string `json:"repo,omitempty" description:"Name of the repository; optional when only one is available."`
+	Pattern    string `json:"pattern" description:"Regular expression (RE2 syntax) to search for." validate:"nonzero"`
+	Fixed      bool   `json:"fixed,omitempty" description:"Search for the pattern as literal text."`
+	IgnoreCase bool   `json:"ignoreCase,omitempty" description:"Ignore case when matching."`
+	Rev        string `json:"rev,omitempty" description:"Revision to search." default:"HEAD"`
+	Path       string `json:"path,omitempty" description:"Only search this file or directory."`
+	Limit      int    `json:"limit,omitempty" description:"Maximum number of matching lines to return." default:"50"`
+}
+
+func (g *Git) grepTool(ctx *runtime.Context, args grepArgs) (*protocol.ToolCallResult, error) {
+	r, err := g.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cleanPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	pattern := args.Pattern
+	if args.Fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if args.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: pattern: "+err.Error(), nil)
+	}
+	matches, truncated, err := r.Grep(args.Rev, re, p, g.limit(args.Limit, 50))
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = []GrepMatch{}
+	}
+	return jsonResult(map[string]interface{}{"matches": matches, "truncated": truncated})
+}
@@ -0,0 +1,73 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/zenmcptest"
+	"github.com/hyperleex/zenmcp/zentool/git"
+)
+
+// fixture copies testdata/fixture, built by testdata/mkfixture.sh, into a
+// temporary directory and returns the path of the repository.
+func fixture(t *testing.T) string {
+	t.Helper()
+	dst := t.TempDir()
+	err := filepath.WalkDir("testdata/fixture", func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel("testdata/fixture", p)
+		if err != nil {
+			return err
+		}
+		if rel == "dotgit" || strings.HasPrefix(rel, "dotgit"+string(filepath.Separator)) {
+			rel = ".git" + strings.TrimPrefix(rel, "dotgit")
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+func TestGolden(t *testing.T) {
+	s := mcp.NewServer()
+	if _, err := git.Install(s, git.Config{Repositories: map[string]string{"fixture": fixture(t)}}); err != nil {
+		t.Fatal(err)
+	}
+	type args = map[string]interface{}
+	zenmcptest.Golden(t, s, "testdata/golden",
+		zenmcptest.ToolCall(git.LogToolName, "head", args{}),
+		zenmcptest.ToolCall(git.LogToolName, "path", args{"path": "src/main.go"}),
+		zenmcptest.ToolCall(git.LogToolName, "branch", args{"rev": "feature", "limit": 2}),
+		zenmcptest.ToolCall(git.ShowToolName, "head", args{}),
+		zenmcptest.ToolCall(git.ShowToolName, "merge", args{"rev": "HEAD~1"}),
+		zenmcptest.ToolCall(git.ShowToolName, "delete-binary", args{"rev": "ebf3f0d"}),
+		zenmcptest.ToolCall(git.ShowToolName, "root", args{"rev": "609773b"}),
+		zenmcptest.ToolCall(git.ShowToolName, "stat", args{"rev": "3f2c0fc", "stat": true}),
+		zenmcptest.ToolCall(git.DiffToolName, "revisions", args{"from": "609773b", "to": "main"}),
+		zenmcptest.ToolCall(git.DiffToolName, "path", args{"from": "609773b", "to": "feature", "path": "src"}),
+		zenmcptest.ToolCall(git.DiffToolName, "worktree", args{}),
+		zenmcptest.ToolCall(git.DiffToolName, "staged", args{"staged": true}),
+		zenmcptest.ToolCall(git.DiffToolName, "head-worktree", args{"from": "HEAD"}),
+		zenmcptest.ToolCall(git.BlameToolName, "main", args{"path": "src/main.go"}),
+		zenmcptest.ToolCall(git.BlameToolName, "range", args{"path": "README.md", "rev": "HEAD~2", "startLine": 2, "endLine": 3}),
+	)
+}
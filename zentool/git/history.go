@@ -0,0 +1,455 @@
+package git
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogOptions selects the commits Log returns.
+type LogOptions struct {
+	// Rev is the revision to start from. Defaults to HEAD.
+	Rev string
+	// Path, when set, keeps only the commits that changed the file or
+	// directory at this path.
+	Path string
+	// Since, when set, stops at commits older than this.
+	Since time.Time
+	// Max is the maximum number of commits to return.
+	Max int
+	// Skip is the number of matching commits to skip first.
+	Skip int
+}
+
+// Log returns the commits reachable from a revision, newest first by
+// commit date.
+func (r *Repository) Log(opts LogOptions) ([]*Commit, error) {
+	start, err := r.Resolve(opts.Rev)
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.Commit(start)
+	if err != nil {
+		return nil, err
+	}
+	queue := &commitQueue{first}
+	seen := map[Hash]bool{start: true}
+	var out []*Commit
+	skip := opts.Skip
+	for queue.Len() > 0 && len(out) < opts.Max {
+		c := heap.Pop(queue).(*Commit)
+		if !opts.Since.IsZero() && c.Committer.When.Before(opts.Since) {
+			continue
+		}
+		var parents []*Commit
+		for _, h := range c.Parents {
+			p, err := r.Commit(h)
+			if err != nil {
+				return nil, err
+			}
+			parents = append(parents, p)
+			if !seen[h] {
+				seen[h] = true
+				heap.Push(queue, p)
+			}
+		}
+		if opts.Path != "" {
+			touched, err := r.touches(c, parents, opts.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !touched {
+				continue
+			}
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// touches reports whether c changed the entry at p relative to every one
+// of its parents.
+func (r *Repository) touches(c *Commit, parents []*Commit, p string) (bool, error) {
+	mine, err := r.entryHash(c, p)
+	if err != nil {
+		return false, err
+	}
+	if len(parents) == 0 {
+		return !mine.IsZero(), nil
+	}
+	for _, parent := range parents {
+		theirs, err := r.entryHash(parent, p)
+		if err != nil {
+			return false, err
+		}
+		if theirs == mine {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// entryHash returns the hash of the entry at p in c, or a zero hash when
+// there is none.
+func (r *Repository) entryHash(c *Commit, p string) (Hash, error) {
+	e, err := r.entry(c.Tree, p)
+	if errors.Is(err, ErrNotFound) {
+		return Hash{}, nil
+	}
+	return e.Hash, err
+}
+
+// commitQueue orders commits newest first.
+type commitQueue []*Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// BlameLine is a line of a file and the commit that last changed it.
+type BlameLine struct {
+	// Line is the 1-based line number.
+	Line   int    `json:"line"`
+	Commit string `json:"commit"`
+	Text   string `json:"text"`
+	// Boundary reports that the history search stopped before reaching
+	// the commit that introduced the line; Commit is then the oldest
+	// commit examined.
+	Boundary bool `json:"boundary,omitempty"`
+}
+
+// Blame attributes each line of the file at p in revision rev to the
+// commit that last changed it, following first parents only: lines
+// brought in by a merge are attributed to the merge. At most maxCommits
+// commits are examined. The returned map describes the commits named by
+// the lines.
+func (r *Repository) Blame(rev, p string, maxCommits int) ([]BlameLine, map[string]*Commit, error) {
+	h, err := r.Resolve(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := r.Commit(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	blobHash, data, err := r.blobAt(c, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isBinary(data) {
+		return nil, nil, fmt.Errorf("git: %s is a binary file", p)
+	}
+	lines := splitLines(data)
+	out := make([]BlameLine, len(lines))
+	commits := make(map[string]*Commit)
+	// pending maps unattributed lines of the result to their line in the
+	// version of the file at c.
+	type pendingLine struct{ final, cur int }
+	pending := make([]pendingLine, len(lines))
+	for i := range lines {
+		pending[i] = pendingLine{i, i}
+	}
+	cur := lines
+	assign := func(c *Commit, ps []pendingLine, boundary bool) {
+		for _, pl := range ps {
+			out[pl.final] = BlameLine{Commit: c.Hash.String(), Boundary: boundary}
+		}
+		if len(ps) > 0 {
+			commits[c.Hash.String()] = c
+		}
+	}
+	for steps := 0; len(pending) > 0; {
+		if len(c.Parents) == 0 {
+			assign(c, pending, false)
+			break
+		}
+		if steps == maxCommits {
+			assign(c, pending, true)
+			break
+		}
+		parent, err := r.Commit(c.Parents[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		e, err := r.entry(parent.Tree, p)
+		if errors.Is(err, ErrNotFound) || (err == nil && (e.IsDir() || e.IsSubmodule())) {
+			assign(c, pending, false)
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if e.Hash == blobHash {
+			c = parent
+			continue
+		}
+		steps++
+		prevData, err := r.typedObject(e.Hash, typeBlob)
+		if err != nil {
+			return nil, nil, err
+		}
+		prev := splitLines(prevData)
+		kept := make(map[int]int)
+		for _, ed := range diffLines(prev, cur) {
+			if ed.kind == ' ' {
+				kept[ed.b] = ed.a
+			}
+		}
+		var next, mine []pendingLine
+		for _, pl := range pending {
+			if at, ok := kept[pl.cur]; ok {
+				next = append(next, pendingLine{pl.final, at})
+			} else {
+				mine = append(mine, pl)
+			}
+		}
+		assign(c, mine, false)
+		pending, cur, blobHash, c = next, prev, e.Hash, parent
+	}
+	for i := range out {
+		out[i].Line = i + 1
+		out[i].Text = strings.TrimSuffix(lines[i], "\n")
+	}
+	return out, commits, nil
+}
+
+// GrepMatch is a line matching a search.
+type GrepMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// Grep searches the text files below dir in revision rev for lines
+// matching re. It returns at most limit matches and reports whether
+// there were more.
+func (r *Repository) Grep(rev string, re *regexp.Regexp, dir string, limit int) ([]GrepMatch, bool, error) {
+	h, err := r.Resolve(rev)
+	if err != nil {
+		return nil, false, err
+	}
+	c, err := r.Commit(h)
+	if err != nil {
+		return nil, false, err
+	}
+	root, err := r.entry(c.Tree, dir)
+	if err != nil {
+		return nil, false, err
+	}
+	var out []GrepMatch
+	search := func(f File) error {
+		data, err := r.typedObject(f.Hash, typeBlob)
+		if err != nil {
+			return err
+		}
+		if isBinary(data) {
+			return nil
+		}
+		for i, line := range splitLines(data) {
+			line = strings.TrimSuffix(line, "\n")
+			if !re.MatchString(line) {
+				continue
+			}
+			if len(out) == limit {
+				return errTruncated
+			}
+			out = append(out, GrepMatch{Path: f.Path, Line: i + 1, Text: line})
+		}
+		return nil
+	}
+	if !root.IsDir() {
+		err = search(File{Path: dir, Mode: root.Mode, Hash: root.Hash})
+	} else {
+		err = r.files(root.Hash, strings.Trim(dir, "/"), search)
+	}
+	if err == errTruncated {
+		return out, true, nil
+	}
+	return out, false, err
+}
+
+// Patch is a set of changes and their unified diff.
+type Patch struct {
+	Changes []Change `json:"changes"`
+	// Text is the unified diff, empty unless requested.
+	Text string `json:"patch,omitempty"`
+	// Truncated reports that the diff was cut short at the size limit;
+	// changes past that point have no line counts.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Show returns the commit a revision names and its changes against its
+// first parent.
+func (r *Repository) Show(rev, dir string, patchLimit int) (*Commit, *Patch, error) {
+	h, err := r.Resolve(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := r.Commit(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	var parentTree Hash
+	if len(c.Parents) > 0 {
+		parent, err := r.Commit(c.Parents[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		parentTree = parent.Tree
+	}
+	var changes []Change
+	if err := r.diffTrees(parentTree, c.Tree, "", &changes); err != nil {
+		return nil, nil, err
+	}
+	p, err := r.patch(filterChanges(changes, dir), r.blob, r.blob, patchLimit)
+	return c, p, err
+}
+
+// DiffOptions selects what Diff compares. With neither From nor Staged,
+// the working tree is compared with the index.
+type DiffOptions struct {
+	// From is the revision to compare from.
+	From string
+	// To is the revision to compare to. When empty, From is compared with
+	// the working tree, or with the index when Staged is set.
+	To string
+	// Staged compares the index with From, or HEAD.
+	Staged bool
+	// Path restricts the comparison to a file or directory.
+	Path string
+}
+
+// Diff compares two revisions, the index or the working tree. The patch
+// text stops growing past patchLimit bytes; a zero limit omits it.
+func (r *Repository) Diff(opts DiffOptions, patchLimit int) (*Patch, error) {
+	if opts.To != "" {
+		if opts.Staged {
+			return nil, errors.New("git: cannot compare the index with two revisions")
+		}
+		a, err := r.commitTree(opts.From)
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.commitTree(opts.To)
+		if err != nil {
+			return nil, err
+		}
+		var changes []Change
+		if err := r.diffTrees(a, b, "", &changes); err != nil {
+			return nil, err
+		}
+		return r.patch(filterChanges(changes, opts.Path), r.blob, r.blob, patchLimit)
+	}
+	if r.Bare() {
+		return nil, errors.New("git: a bare repository has no working tree")
+	}
+	idx, err := r.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	var from map[string]File
+	loadFrom := r.blob
+	if opts.From != "" || opts.Staged {
+		tree, err := r.commitTree(opts.From)
+		if err != nil {
+			return nil, err
+		}
+		if from, err = r.treeSnapshot(tree); err != nil {
+			return nil, err
+		}
+	} else {
+		from = idx.snapshot()
+	}
+	to, loadTo := idx.snapshot(), r.blob
+	if !opts.Staged {
+		if to, err = r.worktreeSnapshot(idx); err != nil {
+			return nil, err
+		}
+		loadTo = func(f File) ([]byte, error) { return r.worktreeFile(f.Path) }
+	}
+	return r.patch(filterChanges(diffSnapshots(from, to), opts.Path), loadFrom, loadTo, patchLimit)
+}
+
+// commitTree returns the tree of the commit rev names.
+func (r *Repository) commitTree(rev string) (Hash, error) {
+	h, err := r.Resolve(rev)
+	if err != nil {
+		return Hash{}, err
+	}
+	c, err := r.Commit(h)
+	if err != nil {
+		return Hash{}, err
+	}
+	return c.Tree, nil
+}
+
+// blob returns the content of a file stored in the repository.
+func (r *Repository) blob(f File) ([]byte, error) {
+	return r.typedObject(f.Hash, typeBlob)
+}
+
+// patch computes the unified diff of changes, loading the old and new
+// contents of files with loadOld and loadNew.
+func (r *Repository) patch(changes []Change, loadOld, loadNew func(File) ([]byte, error), limit int) (*Patch, error) {
+	p := &Patch{Changes: orEmpty(changes)}
+	if limit <= 0 {
+		return p, nil
+	}
+	var w strings.Builder
+	for i := range p.Changes {
+		if w.Len() >= limit {
+			p.Truncated = true
+			break
+		}
+		c := &p.Changes[i]
+		var a, b []byte
+		var err error
+		if c.Status != "added" {
+			if a, err = loadOld(c.old); err != nil {
+				return nil, err
+			}
+		}
+		if c.Status != "deleted" {
+			if b, err = loadNew(c.new); err != nil {
+				return nil, err
+			}
+		}
+		writePatch(&w, c, a, b)
+	}
+	p.Text = w.String()
+	if len(p.Text) > limit {
+		p.Text, p.Truncated = p.Text[:limit], true
+	}
+	return p, nil
+}
+
+// filterChanges keeps the changes at or below dir.
+func filterChanges(changes []Change, dir string) []Change {
+	dir = strings.Trim(dir, "/")
+	if dir == "" || dir == "." {
+		return changes
+	}
+	var out []Change
+	for _, c := range changes {
+		if c.Path == dir || strings.HasPrefix(c.Path, dir+"/") {
+			out = append(out, c)
+		}
+	}
+	return out
+}
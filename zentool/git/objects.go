@@ -0,0 +1,197 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature identifies the author or committer of a commit.
+type Signature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	When  time.Time `json:"when"`
+}
+
+// Commit is a parsed commit object.
+type Commit struct {
+	Hash      Hash      `json:"hash"`
+	Tree      Hash      `json:"-"`
+	Parents   []Hash    `json:"parents"`
+	Author    Signature `json:"author"`
+	Committer Signature `json:"committer"`
+	Message   string    `json:"message"`
+}
+
+// Subject returns the first line of the commit message.
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return subject
+}
+
+// Commit reads the commit named h.
+func (r *Repository) Commit(h Hash) (*Commit, error) {
+	data, err := r.typedObject(h, typeCommit)
+	if err != nil {
+		return nil, err
+	}
+	c := &Commit{Hash: h}
+	header, message, _ := bytes.Cut(data, []byte("\n\n"))
+	c.Message = string(message)
+	for _, line := range strings.Split(string(header), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			c.Tree, _ = parseHash(value)
+		case "parent":
+			if p, ok := parseHash(value); ok {
+				c.Parents = append(c.Parents, p)
+			}
+		case "author":
+			c.Author = parseSignature(value)
+		case "committer":
+			c.Committer = parseSignature(value)
+		}
+	}
+	if c.Tree.IsZero() {
+		return nil, fmt.Errorf("git: malformed commit %s", h)
+	}
+	return c, nil
+}
+
+// parseSignature parses "Name <email> seconds zone".
+func parseSignature(s string) Signature {
+	var sig Signature
+	lt, gt := strings.IndexByte(s, '<'), strings.LastIndexByte(s, '>')
+	if lt < 0 || gt < lt {
+		sig.Name = s
+		return sig
+	}
+	sig.Name = strings.TrimSpace(s[:lt])
+	sig.Email = s[lt+1 : gt]
+	fields := strings.Fields(s[gt+1:])
+	if len(fields) == 2 {
+		secs, err1 := strconv.ParseInt(fields[0], 10, 64)
+		zone, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil {
+			offset := (zone/100*60 + zone%100) * 60
+			sig.When = time.Unix(secs, 0).In(time.FixedZone("", offset))
+		}
+	}
+	return sig
+}
+
+// TreeEntry is an entry of a tree object.
+type TreeEntry struct {
+	Name string
+	Mode uint32
+	Hash Hash
+}
+
+// IsDir reports whether the entry is a subtree.
+func (e TreeEntry) IsDir() bool { return e.Mode == 0o40000 }
+
+// IsSubmodule reports whether the entry is a gitlink to a submodule
+// commit.
+func (e TreeEntry) IsSubmodule() bool { return e.Mode == 0o160000 }
+
+// tree reads the tree named h.
+func (r *Repository) tree(h Hash) ([]TreeEntry, error) {
+	data, err := r.typedObject(h, typeTree)
+	if err != nil {
+		return nil, err
+	}
+	var entries []TreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			return nil, fmt.Errorf("git: malformed tree %s", h)
+		}
+		mode, err := strconv.ParseUint(string(data[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("git: malformed tree %s", h)
+		}
+		e := TreeEntry{Name: string(data[sp+1 : nul]), Mode: uint32(mode)}
+		copy(e.Hash[:], data[nul+1:])
+		entries = append(entries, e)
+		data = data[nul+21:]
+	}
+	return entries, nil
+}
+
+// entry returns the tree entry at the slash-separated path p below tree
+// root.
+func (r *Repository) entry(root Hash, p string) (TreeEntry, error) {
+	cur := TreeEntry{Mode: 0o40000, Hash: root}
+	if p == "" || p == "." {
+		return cur, nil
+	}
+	for _, name := range strings.Split(p, "/") {
+		if !cur.IsDir() {
+			return TreeEntry{}, fmt.Errorf("%w: path %s", ErrNotFound, p)
+		}
+		entries, err := r.tree(cur.Hash)
+		if err != nil {
+			return TreeEntry{}, err
+		}
+		found := false
+		for _, e := range entries {
+			if e.Name == name {
+				cur, found = e, true
+				break
+			}
+		}
+		if !found {
+			return TreeEntry{}, fmt.Errorf("%w: path %s", ErrNotFound, p)
+		}
+	}
+	return cur, nil
+}
+
+// File is a file of a tree, listed by files.
+type File struct {
+	Path string
+	Mode uint32
+	Hash Hash
+}
+
+// files calls fn for the files below tree h, which lies at dir, in tree
+// order. Submodules are skipped.
+func (r *Repository) files(h Hash, dir string, fn func(File) error) error {
+	entries, err := r.tree(h)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name)
+		switch {
+		case e.IsDir():
+			err = r.files(e.Hash, p, fn)
+		case e.IsSubmodule():
+		default:
+			err = fn(File{Path: p, Mode: e.Mode, Hash: e.Hash})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blobAt returns the content of the file at path p in commit c, or
+// ErrNotFound.
+func (r *Repository) blobAt(c *Commit, p string) (Hash, []byte, error) {
+	e, err := r.entry(c.Tree, p)
+	if err != nil {
+		return Hash{}, nil, err
+	}
+	if e.IsDir() || e.IsSubmodule() {
+		return Hash{}, nil, fmt.Errorf("git: %s is not a file", p)
+	}
+	data, err := r.typedObject(e.Hash, typeBlob)
+	return e.Hash, data, err
+}
@@ -0,0 +1,286 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// pack is a pack file and its version 2 index.
+type pack struct {
+	f    *os.File
+	size int64
+	// hashes, sorted, and the offsets of their objects in the pack.
+	hashes  []Hash
+	offsets []int64
+
+	mu sync.Mutex
+	// cache keeps recently inflated objects by offset, chiefly the bases
+	// of delta chains.
+	cache map[int64]cachedObject
+}
+
+type cachedObject struct {
+	typ  objectType
+	data []byte
+}
+
+// maxCached bounds the number of objects a pack caches.
+const maxCached = 512
+
+const (
+	// packHeaderSize is the size of the header preceding the first
+	// object of a pack.
+	packHeaderSize = 12
+	// maxInflation is the largest ratio deflate achieves between the
+	// data it inflates to and its compressed size.
+	maxInflation = 1032
+	// maxDeltaDepth bounds the chains of deltas resolved for an object.
+	// git itself does not write longer ones.
+	maxDeltaDepth = 4095
+)
+
+func openPack(base string) (*pack, error) {
+	idx, err := os.ReadFile(base + ".idx")
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:]) != 2 {
+		return nil, fmt.Errorf("git: %s.idx: unsupported pack index", base)
+	}
+	n := int(binary.BigEndian.Uint32(idx[8+255*4:]))
+	hashStart := 8 + 256*4
+	offStart := hashStart + n*20 + n*4
+	largeStart := offStart + n*4
+	if len(idx) < largeStart {
+		return nil, fmt.Errorf("git: %s.idx: truncated", base)
+	}
+	p := &pack{hashes: make([]Hash, n), offsets: make([]int64, n), cache: make(map[int64]cachedObject)}
+	for i := 0; i < n; i++ {
+		copy(p.hashes[i][:], idx[hashStart+i*20:])
+		off := binary.BigEndian.Uint32(idx[offStart+i*4:])
+		if off&0x80000000 != 0 {
+			at := largeStart + int(off&0x7fffffff)*8
+			if len(idx) < at+8 {
+				return nil, fmt.Errorf("git: %s.idx: truncated", base)
+			}
+			p.offsets[i] = int64(binary.BigEndian.Uint64(idx[at:]))
+		} else {
+			p.offsets[i] = int64(off)
+		}
+	}
+	if p.f, err = os.Open(base + ".pack"); err != nil {
+		return nil, err
+	}
+	fi, err := p.f.Stat()
+	if err != nil {
+		p.f.Close()
+		return nil, err
+	}
+	p.size = fi.Size()
+	return p, nil
+}
+
+// find returns the offset of object h, if the pack holds it.
+func (p *pack) find(h Hash) (int64, bool) {
+	i := sort.Search(len(p.hashes), func(i int) bool { return bytes.Compare(p.hashes[i][:], h[:]) >= 0 })
+	if i < len(p.hashes) && p.hashes[i] == h {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// withPrefix returns the hashes starting with the hexadecimal prefix.
+func (p *pack) withPrefix(prefix string) []Hash {
+	var out []Hash
+	i := sort.Search(len(p.hashes), func(i int) bool { return p.hashes[i].String() >= prefix })
+	for ; i < len(p.hashes) && strings.HasPrefix(p.hashes[i].String(), prefix); i++ {
+		out = append(out, p.hashes[i])
+	}
+	return out
+}
+
+// objectAt returns the object at offset off, resolving deltas. Deltas
+// against objects outside the pack are looked up through r. depth counts
+// the deltas already being resolved to reach off.
+func (p *pack) objectAt(r *Repository, off int64, depth int) (objectType, []byte, error) {
+	if depth > maxDeltaDepth {
+		return 0, nil, fmt.Errorf("git: pack object at %d: delta chain longer than %d", off, maxDeltaDepth)
+	}
+	if off < packHeaderSize || off >= p.size {
+		return 0, nil, fmt.Errorf("git: pack object offset %d out of range", off)
+	}
+	p.mu.Lock()
+	c, ok := p.cache[off]
+	p.mu.Unlock()
+	if ok {
+		return c.typ, c.data, nil
+	}
+	br := bufio.NewReader(io.NewSectionReader(p.f, off, 1<<62))
+	b, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ := objectType(b >> 4 & 7)
+	size := int64(b & 0x0f)
+	for shift := 4; b&0x80 != 0; shift += 7 {
+		if b, err = br.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+		if shift > 56 {
+			return 0, nil, fmt.Errorf("git: pack object at %d: malformed size", off)
+		}
+		size |= int64(b&0x7f) << shift
+	}
+	// Inflating what remains of the pack could not produce more.
+	if size > (p.size-off)*maxInflation {
+		return 0, nil, fmt.Errorf("git: pack object at %d: size %d exceeds what the pack holds", off, size)
+	}
+	var baseType objectType
+	var base []byte
+	switch typ {
+	case typeOfs:
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		rel := int64(b & 0x7f)
+		for b&0x80 != 0 && rel < off {
+			if b, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			rel = (rel+1)<<7 | int64(b&0x7f)
+		}
+		// The base comes before the delta, after the pack header.
+		if rel <= 0 || rel > off-packHeaderSize {
+			return 0, nil, fmt.Errorf("git: pack object at %d: delta base %d bytes back is outside the pack", off, rel)
+		}
+		if baseType, base, err = p.objectAt(r, off-rel, depth+1); err != nil {
+			return 0, nil, err
+		}
+	case typeRef:
+		var h Hash
+		if _, err := io.ReadFull(br, h[:]); err != nil {
+			return 0, nil, err
+		}
+		if baseType, base, err = r.objectDepth(h, depth+1); err != nil {
+			return 0, nil, err
+		}
+	case typeCommit, typeTree, typeBlob, typeTag:
+	default:
+		return 0, nil, fmt.Errorf("git: pack object at %d has unknown type %d", off, typ)
+	}
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return 0, nil, fmt.Errorf("git: pack object at %d: %w", off, err)
+	}
+	data, err := readInflated(zr, size, p.size-off)
+	zr.Close()
+	if err != nil {
+		return 0, nil, fmt.Errorf("git: pack object at %d: %w", off, err)
+	}
+	if base != nil {
+		typ = baseType
+		if data, err = applyDelta(base, data); err != nil {
+			return 0, nil, fmt.Errorf("git: pack object at %d: %w", off, err)
+		}
+	}
+	p.mu.Lock()
+	if len(p.cache) >= maxCached {
+		clear(p.cache)
+	}
+	p.cache[off] = cachedObject{typ, data}
+	p.mu.Unlock()
+	return typ, data, nil
+}
+
+// readInflated reads the size bytes of an object from zr. Its buffer
+// starts at no more than hint bytes and grows with the data actually
+// inflated, so that a forged size does not allocate up front.
+func readInflated(zr io.Reader, size, hint int64) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(int(min(size, hint)))
+	n, err := buf.ReadFrom(io.LimitReader(zr, size))
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), nil
+}
+
+var errBadDelta = errors.New("malformed delta")
+
+// applyDelta rebuilds an object from its base and a delta.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	varint := func() (int, bool) {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			b := delta[0]
+			delta = delta[1:]
+			n |= int(b&0x7f) << shift
+			shift += 7
+			if b&0x80 == 0 {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	srcSize, ok1 := varint()
+	dstSize, ok2 := varint()
+	if !ok1 || !ok2 || srcSize != len(base) {
+		return nil, errBadDelta
+	}
+	if dstSize > len(base)+len(delta)*0x10000 {
+		return nil, errBadDelta
+	}
+	out := make([]byte, 0, min(dstSize, len(base)+len(delta)))
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		if op&0x80 == 0 {
+			n := int(op)
+			if n == 0 || n > len(delta) || len(out)+n > dstSize {
+				return nil, errBadDelta
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+			continue
+		}
+		var off, n int
+		for i := 0; i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, errBadDelta
+			}
+			if i < 4 {
+				off |= int(delta[0]) << (8 * i)
+			} else {
+				n |= int(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if n == 0 {
+			n = 0x10000
+		}
+		if off+n > len(base) || len(out)+n > dstSize {
+			return nil, errBadDelta
+		}
+		out = append(out, base[off:off+n]...)
+	}
+	if len(out) != dstSize {
+		return nil, errBadDelta
+	}
+	return out, nil
+}
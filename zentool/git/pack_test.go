@@ -0,0 +1,176 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// packBuilder writes pack files object by object, for tests that need
+// packs git would not write.
+type packBuilder struct {
+	buf bytes.Buffer
+}
+
+func newPackBuilder() *packBuilder {
+	b := &packBuilder{}
+	b.buf.WriteString("PACK\x00\x00\x00\x02\x00\x00\x00\x00")
+	return b
+}
+
+// header appends an object header of type typ and size, and returns the
+// object's offset.
+func (b *packBuilder) header(typ objectType, size int64) int64 {
+	off := int64(b.buf.Len())
+	c := byte(typ)<<4 | byte(size&0x0f)
+	size >>= 4
+	for size > 0 {
+		b.buf.WriteByte(c | 0x80)
+		c = byte(size & 0x7f)
+		size >>= 7
+	}
+	b.buf.WriteByte(c)
+	return off
+}
+
+// ofsDelta appends the distance to the base of an OFS delta.
+func (b *packBuilder) ofsDelta(rel int64) {
+	enc := []byte{byte(rel & 0x7f)}
+	for rel >>= 7; rel > 0; rel >>= 7 {
+		rel--
+		enc = append([]byte{byte(rel&0x7f) | 0x80}, enc...)
+	}
+	b.buf.Write(enc)
+}
+
+func (b *packBuilder) deflate(data []byte) {
+	zw := zlib.NewWriter(&b.buf)
+	zw.Write(data)
+	zw.Close()
+}
+
+// blob appends a whole blob and returns its offset.
+func (b *packBuilder) blob(data string) int64 {
+	off := b.header(typeBlob, int64(len(data)))
+	b.deflate([]byte(data))
+	return off
+}
+
+// copyDelta appends a delta copying the whole of a one-byte base at
+// offset base, and returns its offset.
+func (b *packBuilder) copyDelta(base int64) int64 {
+	delta := []byte{1, 1, 0x90, 1}
+	off := b.header(typeOfs, int64(len(delta)))
+	b.ofsDelta(off - base)
+	b.deflate(delta)
+	return off
+}
+
+func (b *packBuilder) open(t *testing.T) *pack {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.pack")
+	if err := os.WriteFile(path, b.buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return &pack{f: f, size: int64(b.buf.Len()), cache: make(map[int64]cachedObject)}
+}
+
+func TestPackObjects(t *testing.T) {
+	b := newPackBuilder()
+	base := b.blob("x")
+	off := base
+	for i := 0; i < 10; i++ {
+		off = b.copyDelta(off)
+	}
+	p := b.open(t)
+	typ, data, err := p.objectAt(nil, off, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != typeBlob || string(data) != "x" {
+		t.Errorf("objectAt = %s %q, want blob \"x\"", typ, data)
+	}
+}
+
+func TestPackCorrupt(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *packBuilder) int64
+		want  string
+	}{
+		{"delta on itself", func(b *packBuilder) int64 {
+			off := b.header(typeOfs, 4)
+			b.ofsDelta(0)
+			b.deflate([]byte{1, 1, 0x90, 1})
+			return off
+		}, "outside the pack"},
+		{"delta before the pack", func(b *packBuilder) int64 {
+			off := b.header(typeOfs, 4)
+			b.ofsDelta(off + 100)
+			b.deflate([]byte{1, 1, 0x90, 1})
+			return off
+		}, "outside the pack"},
+		{"delta into the header", func(b *packBuilder) int64 {
+			off := b.header(typeOfs, 4)
+			b.ofsDelta(off - 4)
+			b.deflate([]byte{1, 1, 0x90, 1})
+			return off
+		}, "outside the pack"},
+		{"endless delta distance", func(b *packBuilder) int64 {
+			b.blob("x")
+			off := b.header(typeOfs, 4)
+			b.buf.Write(bytes.Repeat([]byte{0xff}, 32))
+			return off
+		}, "outside the pack"},
+		{"long delta chain", func(b *packBuilder) int64 {
+			off := b.blob("x")
+			for i := 0; i <= maxDeltaDepth; i++ {
+				off = b.copyDelta(off)
+			}
+			return off
+		}, "delta chain longer than"},
+		{"forged size", func(b *packBuilder) int64 {
+			off := b.header(typeBlob, 1<<40)
+			b.deflate([]byte("x"))
+			return off
+		}, "exceeds what the pack holds"},
+		{"overlong size", func(b *packBuilder) int64 {
+			off := int64(b.buf.Len())
+			b.buf.WriteByte(byte(typeBlob)<<4 | 0x8f)
+			b.buf.Write(bytes.Repeat([]byte{0xff}, 10))
+			return off
+		}, "malformed size"},
+		{"short data", func(b *packBuilder) int64 {
+			off := b.header(typeBlob, 10)
+			b.deflate([]byte("x"))
+			return off
+		}, "unexpected EOF"},
+		{"delta larger than its base allows", func(b *packBuilder) int64 {
+			base := b.blob("x")
+			delta := []byte{1, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x90, 1}
+			off := b.header(typeOfs, int64(len(delta)))
+			b.ofsDelta(off - base)
+			b.deflate(delta)
+			return off
+		}, "malformed delta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newPackBuilder()
+			off := tt.build(b)
+			p := b.open(t)
+			_, _, err := p.objectAt(nil, off, 0)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("objectAt = %v, want an error about %q", err, tt.want)
+			}
+		})
+	}
+}
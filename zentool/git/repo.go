@@ -0,0 +1,417 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Hash is a SHA-1 object name.
+type Hash [20]byte
+
+// String returns the hash in hexadecimal.
+func (h Hash) String() string { return hex.EncodeToString(h[:]) }
+
+// IsZero reports whether h is the all-zero hash.
+func (h Hash) IsZero() bool { return h == Hash{} }
+
+// MarshalText implements encoding.TextMarshaler.
+func (h Hash) MarshalText() ([]byte, error) { return []byte(h.String()), nil }
+
+func parseHash(s string) (Hash, bool) {
+	var h Hash
+	if len(s) != 40 {
+		return h, false
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, false
+	}
+	return h, true
+}
+
+// Object types, numbered as in pack files.
+type objectType int
+
+const (
+	typeCommit objectType = 1
+	typeTree   objectType = 2
+	typeBlob   objectType = 3
+	typeTag    objectType = 4
+	typeOfs    objectType = 6
+	typeRef    objectType = 7
+)
+
+var typeNames = map[string]objectType{"commit": typeCommit, "tree": typeTree, "blob": typeBlob, "tag": typeTag}
+
+func (t objectType) String() string {
+	for name, typ := range typeNames {
+		if typ == t {
+			return name
+		}
+	}
+	return "object type " + strconv.Itoa(int(t))
+}
+
+// ErrNotFound is returned for objects, references and paths that do not
+// exist.
+var ErrNotFound = errors.New("git: not found")
+
+// Repository reads a git repository directly from its files.
+type Repository struct {
+	// worktree is the root of the working tree, or "" for bare
+	// repositories.
+	worktree string
+	gitDir   string
+	// commonDir holds objects and shared references; it differs from
+	// gitDir in linked worktrees.
+	commonDir string
+
+	// packs are the pack files found so far, by path.
+	packsMu sync.Mutex
+	packs   map[string]*pack
+}
+
+// Open opens the repository at path: a working tree containing .git, or
+// a bare repository.
+func Open(path string) (*Repository, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Repository{}
+	dotGit := filepath.Join(path, ".git")
+	fi, err := os.Stat(dotGit)
+	switch {
+	case err == nil && fi.IsDir():
+		r.worktree, r.gitDir = path, dotGit
+	case err == nil:
+		// A linked worktree or submodule: .git names the git directory.
+		data, err := os.ReadFile(dotGit)
+		if err != nil {
+			return nil, err
+		}
+		dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+		if !ok {
+			return nil, fmt.Errorf("git: %s: malformed .git file", path)
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(path, dir)
+		}
+		r.worktree, r.gitDir = path, dir
+	default:
+		if _, err := os.Stat(filepath.Join(path, "objects")); err != nil {
+			return nil, fmt.Errorf("git: %s is not a repository", path)
+		}
+		r.gitDir = path
+	}
+	r.commonDir = r.gitDir
+	if data, err := os.ReadFile(filepath.Join(r.gitDir, "commondir")); err == nil {
+		dir := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.gitDir, dir)
+		}
+		r.commonDir = dir
+	}
+	if cfg, err := os.ReadFile(filepath.Join(r.commonDir, "config")); err == nil && bytes.Contains(cfg, []byte("objectformat = sha256")) {
+		return nil, errors.New("git: SHA-256 repositories are not supported")
+	}
+	return r, nil
+}
+
+// Bare reports whether the repository has no working tree.
+func (r *Repository) Bare() bool { return r.worktree == "" }
+
+// object returns the type and content of the object named h.
+func (r *Repository) object(h Hash) (objectType, []byte, error) {
+	return r.objectDepth(h, 0)
+}
+
+// objectDepth returns object h, depth deltas down a chain being resolved.
+func (r *Repository) objectDepth(h Hash, depth int) (objectType, []byte, error) {
+	s := h.String()
+	f, err := os.Open(filepath.Join(r.commonDir, "objects", s[:2], s[2:]))
+	if err == nil {
+		defer f.Close()
+		return readLoose(f)
+	}
+	// A repack may have moved the object into a new pack; rescan once.
+	for _, rescan := range []bool{false, true} {
+		packs, err := r.loadPacks(rescan)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, p := range packs {
+			if off, ok := p.find(h); ok {
+				return p.objectAt(r, off, depth)
+			}
+		}
+	}
+	return 0, nil, fmt.Errorf("%w: object %s", ErrNotFound, s)
+}
+
+// typedObject returns the content of object h, which must be of type want.
+func (r *Repository) typedObject(h Hash, want objectType) ([]byte, error) {
+	typ, data, err := r.object(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != want {
+		return nil, fmt.Errorf("git: object %s is a %s, not a %s", h, typ, want)
+	}
+	return data, nil
+}
+
+func readLoose(f io.Reader) (objectType, []byte, error) {
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("git: loose object: %w", err)
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+	header, err := br.ReadString(0)
+	if err != nil {
+		return 0, nil, fmt.Errorf("git: loose object header: %w", err)
+	}
+	name, sizeStr, _ := strings.Cut(strings.TrimSuffix(header, "\x00"), " ")
+	typ, ok := typeNames[name]
+	size, err := strconv.Atoi(sizeStr)
+	if !ok || err != nil || size < 0 {
+		return 0, nil, fmt.Errorf("git: malformed loose object header %q", header)
+	}
+	data, err := readInflated(br, int64(size), 64<<10)
+	if err != nil {
+		return 0, nil, fmt.Errorf("git: loose object: %w", err)
+	}
+	return typ, data, nil
+}
+
+// loadPacks returns the pack files of the repository, looking for new
+// ones on first use or when rescan is set.
+func (r *Repository) loadPacks(rescan bool) ([]*pack, error) {
+	r.packsMu.Lock()
+	defer r.packsMu.Unlock()
+	if r.packs == nil || rescan {
+		if r.packs == nil {
+			r.packs = make(map[string]*pack)
+		}
+		idxs, err := filepath.Glob(filepath.Join(r.commonDir, "objects", "pack", "*.idx"))
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range idxs {
+			base := strings.TrimSuffix(idx, ".idx")
+			if r.packs[base] != nil {
+				continue
+			}
+			p, err := openPack(base)
+			if err != nil {
+				return nil, err
+			}
+			r.packs[base] = p
+		}
+	}
+	packs := make([]*pack, 0, len(r.packs))
+	for _, p := range r.packs {
+		packs = append(packs, p)
+	}
+	return packs, nil
+}
+
+// ref returns the hash a reference points to, following symbolic
+// references.
+func (r *Repository) ref(name string) (Hash, error) {
+	for depth := 0; depth < 10; depth++ {
+		target, err := r.readRef(name)
+		if err != nil {
+			return Hash{}, err
+		}
+		sym, ok := strings.CutPrefix(target, "ref: ")
+		if !ok {
+			h, ok := parseHash(target)
+			if !ok {
+				return Hash{}, fmt.Errorf("git: malformed reference %s", name)
+			}
+			return h, nil
+		}
+		name = sym
+	}
+	return Hash{}, fmt.Errorf("git: reference %s is too deeply nested", name)
+}
+
+// readRef returns the raw content of a loose or packed reference.
+func (r *Repository) readRef(name string) (string, error) {
+	dirs := []string{r.gitDir}
+	if r.commonDir != r.gitDir && name != "HEAD" {
+		dirs = append(dirs, r.commonDir)
+	}
+	for _, dir := range dirs {
+		if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(r.commonDir, "packed-refs"))
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			hash, ref, ok := strings.Cut(strings.TrimSpace(line), " ")
+			if ok && ref == name {
+				return hash, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: reference %s", ErrNotFound, name)
+}
+
+// Head returns the name of the branch HEAD points to, or "" when HEAD is
+// detached.
+func (r *Repository) Head() string {
+	target, err := r.readRef("HEAD")
+	if err != nil {
+		return ""
+	}
+	branch, _ := strings.CutPrefix(target, "ref: refs/heads/")
+	if branch == target {
+		return ""
+	}
+	return branch
+}
+
+// Resolve returns the commit a revision names. It understands reference
+// and branch names, full and abbreviated hashes, and the suffixes ~n, ^
+// and ^n.
+func (r *Repository) Resolve(rev string) (Hash, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	base, suffix := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i > 0 {
+		base, suffix = rev[:i], rev[i:]
+	}
+	h, err := r.resolveBase(base)
+	if err != nil {
+		return Hash{}, err
+	}
+	if h, err = r.peel(h); err != nil {
+		return Hash{}, err
+	}
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		n := 1
+		digits := len(suffix) - len(strings.TrimLeft(suffix, "0123456789"))
+		if digits > 0 {
+			n, _ = strconv.Atoi(suffix[:digits])
+			suffix = suffix[digits:]
+		}
+		c, err := r.Commit(h)
+		if err != nil {
+			return Hash{}, err
+		}
+		switch {
+		case op == '^' && n == 0:
+		case op == '^':
+			if n > len(c.Parents) {
+				return Hash{}, fmt.Errorf("%w: %s has no parent %d", ErrNotFound, h, n)
+			}
+			h = c.Parents[n-1]
+		default:
+			for i := 0; i < n; i++ {
+				if len(c.Parents) == 0 {
+					return Hash{}, fmt.Errorf("%w: %s goes back beyond the root commit", ErrNotFound, rev)
+				}
+				h = c.Parents[0]
+				if i < n-1 {
+					if c, err = r.Commit(h); err != nil {
+						return Hash{}, err
+					}
+				}
+			}
+		}
+	}
+	return h, nil
+}
+
+func (r *Repository) resolveBase(name string) (Hash, error) {
+	for _, ref := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name, "refs/remotes/" + name, "refs/remotes/" + name + "/HEAD"} {
+		if strings.Contains(ref, "..") {
+			break
+		}
+		if h, err := r.ref(ref); err == nil {
+			return h, nil
+		}
+	}
+	if h, ok := parseHash(name); ok {
+		return h, nil
+	}
+	if len(name) >= 4 && len(name) < 40 && strings.Trim(strings.ToLower(name), "0123456789abcdef") == "" {
+		return r.expand(strings.ToLower(name))
+	}
+	return Hash{}, fmt.Errorf("%w: revision %s", ErrNotFound, name)
+}
+
+// expand finds the unique object whose name starts with prefix.
+func (r *Repository) expand(prefix string) (Hash, error) {
+	var found []Hash
+	dir := filepath.Join(r.commonDir, "objects", prefix[:2])
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if h, ok := parseHash(prefix[:2] + e.Name()); ok && strings.HasPrefix(h.String(), prefix) {
+				found = append(found, h)
+			}
+		}
+	}
+	packs, err := r.loadPacks(false)
+	if err != nil {
+		return Hash{}, err
+	}
+	for _, p := range packs {
+		found = append(found, p.withPrefix(prefix)...)
+	}
+	sort.Slice(found, func(i, j int) bool { return bytes.Compare(found[i][:], found[j][:]) < 0 })
+	uniq := found[:0]
+	for i, h := range found {
+		if i == 0 || h != found[i-1] {
+			uniq = append(uniq, h)
+		}
+	}
+	switch len(uniq) {
+	case 0:
+		return Hash{}, fmt.Errorf("%w: revision %s", ErrNotFound, prefix)
+	case 1:
+		return uniq[0], nil
+	}
+	return Hash{}, fmt.Errorf("git: abbreviated hash %s is ambiguous", prefix)
+}
+
+// peel follows annotated tags to the commit they point to.
+func (r *Repository) peel(h Hash) (Hash, error) {
+	for depth := 0; depth < 10; depth++ {
+		typ, data, err := r.object(h)
+		if err != nil {
+			return Hash{}, err
+		}
+		if typ != typeTag {
+			if typ != typeCommit {
+				return Hash{}, fmt.Errorf("git: %s is a %s, not a commit", h, typ)
+			}
+			return h, nil
+		}
+		target, ok := strings.CutPrefix(string(data), "object ")
+		if !ok || len(target) < 40 {
+			return Hash{}, fmt.Errorf("git: malformed tag %s", h)
+		}
+		if h, ok = parseHash(target[:40]); !ok {
+			return Hash{}, fmt.Errorf("git: malformed tag %s", h)
+		}
+	}
+	return Hash{}, fmt.Errorf("git: tag %s is too deeply nested", h)
+}
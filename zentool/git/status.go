@@ -0,0 +1,460 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// indexEntry is a stage 0 entry of the index.
+type indexEntry struct {
+	File
+	size      uint32
+	mtimeSec  uint32
+	mtimeNsec uint32
+}
+
+// index is the parsed index: the files staged for the next commit.
+type index struct {
+	entries map[string]indexEntry
+	// conflicted lists the paths with unmerged entries.
+	conflicted []string
+}
+
+// readIndex parses the index. A missing index is empty.
+func (r *Repository) readIndex() (*index, error) {
+	idx := &index{entries: make(map[string]indexEntry)}
+	data, err := os.ReadFile(filepath.Join(r.gitDir, "index"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, errors.New("git: malformed index")
+	}
+	version := binary.BigEndian.Uint32(data[4:])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("git: unsupported index version %d", version)
+	}
+	n := int(binary.BigEndian.Uint32(data[8:]))
+	rest := data[12:]
+	prev := ""
+	conflicts := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		if len(rest) < 62 {
+			return nil, errors.New("git: truncated index")
+		}
+		e := indexEntry{
+			mtimeSec:  binary.BigEndian.Uint32(rest[8:]),
+			mtimeNsec: binary.BigEndian.Uint32(rest[12:]),
+			size:      binary.BigEndian.Uint32(rest[36:]),
+		}
+		e.Mode = binary.BigEndian.Uint32(rest[24:])
+		copy(e.Hash[:], rest[40:60])
+		flags := binary.BigEndian.Uint16(rest[60:])
+		fixed := 62
+		if version >= 3 && flags&0x4000 != 0 {
+			fixed += 2
+		}
+		if len(rest) < fixed {
+			return nil, errors.New("git: truncated index")
+		}
+		rest = rest[fixed:]
+		if version == 4 {
+			// The path is a count of bytes to drop from the previous path
+			// followed by the suffix to append.
+			strip, used := 0, 0
+			for {
+				if used >= len(rest) {
+					return nil, errors.New("git: truncated index")
+				}
+				b := rest[used]
+				used++
+				strip = strip<<7 | int(b&0x7f)
+				if b&0x80 == 0 {
+					break
+				}
+				strip++
+			}
+			rest = rest[used:]
+			nul := bytes.IndexByte(rest, 0)
+			if nul < 0 || strip > len(prev) {
+				return nil, errors.New("git: malformed index")
+			}
+			e.Path = prev[:len(prev)-strip] + string(rest[:nul])
+			rest = rest[nul+1:]
+		} else {
+			nul := bytes.IndexByte(rest, 0)
+			if nul < 0 {
+				return nil, errors.New("git: malformed index")
+			}
+			e.Path = string(rest[:nul])
+			// Entries are padded with 1 to 8 NULs to a multiple of 8 bytes.
+			pad := 8 - (fixed+nul)%8
+			if len(rest) < nul+pad {
+				return nil, errors.New("git: truncated index")
+			}
+			rest = rest[nul+pad:]
+		}
+		prev = e.Path
+		if stage := flags >> 12 & 3; stage != 0 {
+			conflicts[e.Path] = true
+			continue
+		}
+		idx.entries[e.Path] = e
+	}
+	for p := range conflicts {
+		idx.conflicted = append(idx.conflicted, p)
+	}
+	sort.Strings(idx.conflicted)
+	return idx, nil
+}
+
+// tracked reports whether the index has an entry for p.
+func (idx *index) tracked(p string) bool {
+	if _, ok := idx.entries[p]; ok {
+		return true
+	}
+	i := sort.SearchStrings(idx.conflicted, p)
+	return i < len(idx.conflicted) && idx.conflicted[i] == p
+}
+
+// snapshot returns the index as a flat snapshot.
+func (idx *index) snapshot() map[string]File {
+	out := make(map[string]File, len(idx.entries))
+	for p, e := range idx.entries {
+		out[p] = e.File
+	}
+	return out
+}
+
+// treeSnapshot returns the files of tree h as a flat snapshot. A zero
+// hash is an empty tree.
+func (r *Repository) treeSnapshot(h Hash) (map[string]File, error) {
+	out := make(map[string]File)
+	if h.IsZero() {
+		return out, nil
+	}
+	err := r.files(h, "", func(f File) error {
+		out[f.Path] = f
+		return nil
+	})
+	return out, err
+}
+
+// worktreeSnapshot returns the working tree copies of the files in the
+// index. Files whose size and modification time match the index are
+// assumed unchanged; the others are hashed.
+func (r *Repository) worktreeSnapshot(idx *index) (map[string]File, error) {
+	out := make(map[string]File, len(idx.entries))
+	for p, e := range idx.entries {
+		if e.Mode == 0o160000 {
+			continue
+		}
+		fi, err := os.Lstat(r.worktreePath(p))
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) || (err == nil && fi.IsDir()) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		mode := fileMode(fi)
+		mtime := fi.ModTime()
+		if mode == e.Mode && uint32(fi.Size()) == e.size && uint32(mtime.Unix()) == e.mtimeSec && uint32(mtime.Nanosecond()) == e.mtimeNsec {
+			out[p] = e.File
+			continue
+		}
+		data, err := r.worktreeFile(p)
+		if err != nil {
+			return nil, err
+		}
+		out[p] = File{Path: p, Mode: mode, Hash: blobHash(data)}
+	}
+	return out, nil
+}
+
+func (r *Repository) worktreePath(p string) string {
+	return filepath.Join(r.worktree, filepath.FromSlash(p))
+}
+
+// worktreeFile returns the content of the working tree file at p: the
+// file itself or, for a symbolic link, its target.
+func (r *Repository) worktreeFile(p string) ([]byte, error) {
+	full := r.worktreePath(p)
+	fi, err := os.Lstat(full)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		return []byte(target), err
+	}
+	return os.ReadFile(full)
+}
+
+// fileMode returns the git mode of a working tree file.
+func fileMode(fi fs.FileInfo) uint32 {
+	switch {
+	case fi.Mode()&fs.ModeSymlink != 0:
+		return 0o120000
+	case fi.Mode()&0o100 != 0:
+		return 0o100755
+	}
+	return 0o100644
+}
+
+func blobHash(data []byte) Hash {
+	h := sha1.New()
+	h.Write([]byte("blob " + strconv.Itoa(len(data)) + "\x00"))
+	h.Write(data)
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+// untracked lists up to limit working tree files that are neither in the
+// index nor ignored, and reports whether there were more.
+func (r *Repository) untracked(idx *index, limit int) ([]string, bool, error) {
+	var out []string
+	ign := r.loadExcludes()
+	err := filepath.WalkDir(r.worktree, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(r.worktree, full)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			ign.load(full, "")
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || ign.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(full, ".git")); err == nil {
+				// A nested repository or submodule.
+				return filepath.SkipDir
+			}
+			ign.load(full, rel)
+			return nil
+		}
+		if idx.tracked(rel) || ign.ignored(rel, false) {
+			return nil
+		}
+		if len(out) == limit {
+			return errTruncated
+		}
+		out = append(out, rel)
+		return nil
+	})
+	if err == errTruncated {
+		return out, true, nil
+	}
+	return out, false, err
+}
+
+var errTruncated = errors.New("git: truncated")
+
+func (r *Repository) loadExcludes() *ignorer {
+	ign := &ignorer{}
+	if data, err := os.ReadFile(filepath.Join(r.commonDir, "info", "exclude")); err == nil {
+		ign.parse(data, "")
+	}
+	return ign
+}
+
+// ignorer applies gitignore patterns.
+type ignorer struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	// base is the directory of the file holding the pattern.
+	base     string
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// load reads the .gitignore of the directory full, found at rel.
+func (ign *ignorer) load(full, rel string) {
+	if data, err := os.ReadFile(filepath.Join(full, ".gitignore")); err == nil {
+		ign.parse(data, rel)
+	}
+}
+
+func (ign *ignorer) parse(data []byte, base string) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate, line = true, line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored, line = true, strings.TrimPrefix(line, "/")
+		}
+		re, err := regexp.Compile("^" + globRegexp(line) + "$")
+		if err != nil || line == "" {
+			continue
+		}
+		rule.re = re
+		ign.rules = append(ign.rules, rule)
+	}
+}
+
+// ignored reports whether the path rel is ignored. As in git, the last
+// matching pattern decides.
+func (ign *ignorer) ignored(rel string, dir bool) bool {
+	ignored := false
+	for _, rule := range ign.rules {
+		sub := rel
+		if rule.base != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if rule.dirOnly && !dir {
+			continue
+		}
+		if !rule.anchored {
+			sub = path.Base(sub)
+		}
+		if rule.re.MatchString(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globRegexp translates a gitignore glob into a regular expression.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Status describes the working tree: changes staged in the index, changes
+// not yet staged, and untracked files.
+type Status struct {
+	// Branch is the current branch, empty when HEAD is detached.
+	Branch string `json:"branch,omitempty"`
+	// Head is the commit HEAD points to, empty in a repository without
+	// commits.
+	Head       string   `json:"head,omitempty"`
+	Staged     []Change `json:"staged"`
+	Unstaged   []Change `json:"unstaged"`
+	Untracked  []string `json:"untracked"`
+	Conflicted []string `json:"conflicted,omitempty"`
+	// Truncated reports that more untracked files exist than were listed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Status compares HEAD, the index and the working tree. At most
+// maxUntracked untracked files are listed.
+func (r *Repository) Status(maxUntracked int) (*Status, error) {
+	if r.Bare() {
+		return nil, errors.New("git: a bare repository has no working tree")
+	}
+	st := &Status{Branch: r.Head()}
+	var headTree Hash
+	if h, err := r.Resolve("HEAD"); err == nil {
+		c, err := r.Commit(h)
+		if err != nil {
+			return nil, err
+		}
+		st.Head, headTree = h.String(), c.Tree
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	idx, err := r.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.treeSnapshot(headTree)
+	if err != nil {
+		return nil, err
+	}
+	// Unmerged paths are reported apart rather than as deleted.
+	for _, p := range idx.conflicted {
+		delete(head, p)
+	}
+	staged := idx.snapshot()
+	work, err := r.worktreeSnapshot(idx)
+	if err != nil {
+		return nil, err
+	}
+	st.Staged = orEmpty(diffSnapshots(head, staged))
+	st.Unstaged = orEmpty(diffSnapshots(staged, work))
+	st.Conflicted = idx.conflicted
+	st.Untracked, st.Truncated, err = r.untracked(idx, maxUntracked)
+	if err != nil {
+		return nil, err
+	}
+	if st.Untracked == nil {
+		st.Untracked = []string{}
+	}
+	return st, nil
+}
+
+func orEmpty(c []Change) []Change {
+	if c == nil {
+		return []Change{}
+	}
+	return c
+}
@@ -0,0 +1,145 @@
+#!/bin/sh
+# mkfixture.sh rebuilds testdata/fixture, the repository the golden tests
+# read. Its git directory is stored as dotgit, since git does not track
+# nested .git directories; the tests copy the fixture and rename it.
+#
+# Run from zentool/git: sh testdata/mkfixture.sh
+set -eu
+
+dir=testdata/fixture
+rm -rf "$dir"
+mkdir -p "$dir"
+cd "$dir"
+
+export GIT_CONFIG_NOSYSTEM=1 HOME=/nonexistent TZ=UTC
+export GIT_AUTHOR_NAME="Ada Lovelace" GIT_AUTHOR_EMAIL=ada@example.com
+export GIT_COMMITTER_NAME="Ada Lovelace" GIT_COMMITTER_EMAIL=ada@example.com
+
+n=0
+commit() {
+	n=$((n + 1))
+	export GIT_AUTHOR_DATE="2024-03-0${n}T10:00:00+01:00" GIT_COMMITTER_DATE="2024-03-0${n}T10:00:00+01:00"
+	git add -A
+	git commit -q -m "$1"
+}
+
+git init -q -b main
+git config core.fileMode true
+
+mkdir src
+cat >README.md <<'END'
+# fixture
+
+A small repository for the git tool tests.
+END
+cat >src/main.go <<'END'
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(greeting("world"))
+}
+
+func greeting(name string) string {
+	return "hello, " + name
+}
+END
+cat >src/util.go <<'END'
+package main
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+END
+printf '#!/bin/sh\necho build\n' >build.sh
+commit "Initial commit"
+
+cat >src/main.go <<'END'
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	name := "world"
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+	}
+	fmt.Println(greeting(name))
+}
+
+func greeting(name string) string {
+	return "hello, " + name
+}
+END
+mkdir docs
+cat >docs/guide.md <<'END'
+# Guide
+
+Run the program with a name.
+END
+commit "Take the name from the command line
+
+The first argument replaces the default name."
+
+git checkout -q -b feature
+cat >src/main.go <<'END'
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	name := "world"
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+	}
+	fmt.Println(greeting(name))
+}
+
+func greeting(name string) string {
+	return "Hello, " + name + "!"
+}
+END
+commit "Capitalize the greeting"
+
+git checkout -q main
+git rm -q src/util.go
+printf '\0\1\2\3binary' >logo.bin
+cat >>README.md <<'END'
+
+See docs/guide.md.
+END
+commit "Drop clamp and link the guide"
+
+n=$((n + 1))
+export GIT_AUTHOR_DATE="2024-03-0${n}T10:00:00+01:00" GIT_COMMITTER_DATE="2024-03-0${n}T10:00:00+01:00"
+git merge -q --no-ff -m "Merge branch 'feature'" feature
+
+# Pack what exists so far, with deltas, and leave later objects loose.
+git repack -q -a -d -f --depth=10 --window=10
+rm -f .git/packed-refs.lock
+git prune-packed
+
+chmod +x build.sh
+printf 'no newline at the end' >notes.txt
+commit "Make build.sh executable and add notes"
+
+# Uncommitted changes: one staged, one only in the working tree.
+printf '# Guide\n\nRun the program with a name, or none.\n' >docs/guide.md
+git add docs/guide.md
+printf 'no newline at the end, still' >notes.txt
+
+rm -rf .git/hooks .git/logs .git/info .git/description .git/COMMIT_EDITMSG .git/ORIG_HEAD
+mv .git dotgit
@@ -0,0 +1,255 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIError is an error returned by the API server, such as a resource
+// that does not exist or a request RBAC forbids.
+type APIError struct {
+	Code    int
+	Reason  string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Reason
+	}
+	return fmt.Sprintf("k8s: %d %s: %s", e.Code, http.StatusText(e.Code), msg)
+}
+
+// Client makes read-only requests to the API server of a cluster.
+type Client struct {
+	cluster *Cluster
+	http    *http.Client
+
+	// mu guards the credential obtained from the exec plugin.
+	mu         sync.Mutex
+	execToken  string
+	execCert   *tls.Certificate
+	execExpiry time.Time
+}
+
+// NewClient returns a client for cluster.
+func NewClient(cluster *Cluster) (*Client, error) {
+	if cluster.Server == "" {
+		return nil, errors.New("k8s: cluster has no server")
+	}
+	c := &Client{cluster: cluster}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cluster.InsecureSkipTLSVerify,
+		ServerName:         cluster.TLSServerName,
+	}
+	if len(cluster.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cluster.CAData) {
+			return nil, errors.New("k8s: no valid certificate in the cluster CA data")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(cluster.ClientCertData) > 0 {
+		cert, err := tls.X509KeyPair(cluster.ClientCertData, cluster.ClientKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if cluster.Exec != nil {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			_, cert, err := c.execCredential(context.Background())
+			if err != nil || cert == nil {
+				return &tls.Certificate{}, err
+			}
+			return cert, nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.http = &http.Client{Transport: transport}
+	return c, nil
+}
+
+// Namespace returns the default namespace of the cluster.
+func (c *Client) Namespace() string {
+	if c.cluster.Namespace != "" {
+		return c.cluster.Namespace
+	}
+	return "default"
+}
+
+// Get fetches the API path with query parameters and decodes the JSON
+// response into out.
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("k8s: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// GetText fetches the API path and returns up to limit bytes of the
+// response, reporting whether there were more.
+func (c *Client) GetText(ctx context.Context, path string, query url.Values, limit int) (string, bool, error) {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return "", false, fmt.Errorf("k8s: reading %s: %w", path, err)
+	}
+	if len(data) > limit {
+		return string(data[:limit]), true, nil
+	}
+	return string(data), false, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := strings.TrimSuffix(c.cluster.Server, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json, */*")
+		if err := c.authorize(req); err != nil {
+			return nil, err
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: %w", err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && c.cluster.Exec != nil && attempt == 0 {
+			// The plugin's credential may have been revoked early; get a
+			// fresh one and retry once.
+			resp.Body.Close()
+			c.mu.Lock()
+			c.execToken, c.execCert = "", nil
+			c.mu.Unlock()
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			err := apiError(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+func apiError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var status struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &status) != nil {
+		status.Message = strings.TrimSpace(string(data))
+	}
+	return &APIError{Code: resp.StatusCode, Reason: status.Reason, Message: status.Message}
+}
+
+// authorize adds the cluster credentials to req.
+func (c *Client) authorize(req *http.Request) error {
+	cl := c.cluster
+	switch {
+	case cl.Token != "":
+		req.Header.Set("Authorization", "Bearer "+cl.Token)
+	case cl.TokenFile != "":
+		token, err := os.ReadFile(cl.TokenFile)
+		if err != nil {
+			return fmt.Errorf("k8s: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	case cl.Username != "":
+		req.SetBasicAuth(cl.Username, cl.Password)
+	case cl.Exec != nil:
+		token, _, err := c.execCredential(req.Context())
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return nil
+}
+
+// execCredential returns the credential of the exec plugin, running it
+// when there is none or it has expired.
+func (c *Client) execCredential(ctx context.Context) (string, *tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if (c.execToken != "" || c.execCert != nil) && (c.execExpiry.IsZero() || time.Until(c.execExpiry) > 10*time.Second) {
+		return c.execToken, c.execCert, nil
+	}
+	e := c.cluster.Exec
+	apiVersion := e.APIVersion
+	if apiVersion == "" {
+		apiVersion = "client.authentication.k8s.io/v1"
+	}
+	info, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]interface{}{"interactive": false},
+	})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(info))
+	for k, v := range e.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("k8s: credential plugin %s: %w: %s", e.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	var cred struct {
+		Status struct {
+			Token                 string    `json:"token"`
+			ExpirationTimestamp   time.Time `json:"expirationTimestamp"`
+			ClientCertificateData string    `json:"clientCertificateData"`
+			ClientKeyData         string    `json:"clientKeyData"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &cred); err != nil {
+		return "", nil, fmt.Errorf("k8s: credential plugin %s: %w", e.Command, err)
+	}
+	c.execToken, c.execCert, c.execExpiry = cred.Status.Token, nil, cred.Status.ExpirationTimestamp
+	if cred.Status.ClientCertificateData != "" {
+		cert, err := tls.X509KeyPair([]byte(cred.Status.ClientCertificateData), []byte(cred.Status.ClientKeyData))
+		if err != nil {
+			return "", nil, fmt.Errorf("k8s: credential plugin %s: %w", e.Command, err)
+		}
+		c.execCert = &cert
+	}
+	if c.execToken == "" && c.execCert == nil {
+		return "", nil, fmt.Errorf("k8s: credential plugin %s returned no credential", e.Command)
+	}
+	return c.execToken, c.execCert, nil
+}
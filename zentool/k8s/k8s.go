@@ -0,0 +1,575 @@
+// Package k8s gives agents read-only introspection of a Kubernetes
+// cluster: tools to list pods and deployments, read container logs and
+// describe resources with their events.
+//
+// The tools talk to the API server's REST API with the credentials of a
+// kubeconfig context or of the pod's service account, so the cluster's
+// RBAC rules decide what they can read. A namespace allow-list narrows
+// that further, and secrets are never returned.
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Names of the tools registered by Install.
+const (
+	ListPodsToolName        = "k8s.list_pods"
+	ListDeploymentsToolName = "k8s.list_deployments"
+	LogsToolName            = "k8s.logs"
+	DescribeToolName        = "k8s.describe"
+)
+
+// Config configures the Kubernetes tools.
+type Config struct {
+	// Cluster is the cluster to read. When nil, it is loaded with
+	// DefaultCluster from Kubeconfig and Context.
+	Cluster *Cluster
+	// Kubeconfig is the path of the kubeconfig file; see LoadKubeconfig.
+	Kubeconfig string
+	// Context selects a kubeconfig context. Defaults to the current one.
+	Context string
+	// Namespaces lists the namespaces callers may read. When empty, every
+	// namespace the credentials can read is available, as are
+	// cluster-scoped resources such as nodes.
+	Namespaces []string
+	// MaxResults caps the number of resources a list returns. Defaults to
+	// 200.
+	MaxResults int
+	// MaxLogBytes bounds the size of the logs returned. Defaults to 256
+	// KiB.
+	MaxLogBytes int
+}
+
+// K8s serves the Kubernetes tools.
+type K8s struct {
+	cfg     Config
+	client  *Client
+	allowed map[string]bool
+}
+
+// Install connects to the configured cluster and registers the
+// Kubernetes tools on s.
+func Install(s *mcp.Server, cfg Config) (*K8s, error) {
+	if cfg.Cluster == nil {
+		c, err := DefaultCluster(cfg.Kubeconfig, cfg.Context)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Cluster = c
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = 200
+	}
+	if cfg.MaxLogBytes <= 0 {
+		cfg.MaxLogBytes = 256 << 10
+	}
+	client, err := NewClient(cfg.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	k := &K8s{cfg: cfg, client: client}
+	if len(cfg.Namespaces) > 0 {
+		k.allowed = make(map[string]bool)
+		for _, ns := range cfg.Namespaces {
+			k.allowed[ns] = true
+		}
+	}
+	tags := []string{"kubernetes"}
+	yes := true
+	readOnly := &protocol.ToolAnnotations{ReadOnlyHint: &yes}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ListPodsToolName,
+		Description: "List pods with their phase, readiness, restarts and node.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, k.listPodsTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ListDeploymentsToolName,
+		Description: "List deployments with their desired, ready, updated and available replicas and images.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, k.listDeploymentsTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        LogsToolName,
+		Description: "Read the logs of a pod's container, most recent lines last.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, k.logsTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        DescribeToolName,
+		Description: "Describe a resource: its specification, status and recent events. Secrets cannot be described.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, k.describeTool); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// namespace returns the namespace a call names, or the default one, after
+// checking it is allowed.
+func (k *K8s) namespace(ns string) (string, error) {
+	if ns == "" {
+		ns = k.client.Namespace()
+		if k.allowed != nil && !k.allowed[ns] && len(k.cfg.Namespaces) == 1 {
+			ns = k.cfg.Namespaces[0]
+		}
+	}
+	if k.allowed != nil && !k.allowed[ns] {
+		return "", protocol.NewError(protocol.InvalidParams,
+			fmt.Sprintf("invalid arguments: namespace %q is not available; available: %s", ns, strings.Join(k.cfg.Namespaces, ", ")), nil)
+	}
+	return ns, nil
+}
+
+// namespaces returns the namespaces a list covers: the one named, or
+// with all set, every allowed namespace, nil standing for the whole
+// cluster.
+func (k *K8s) namespaces(ns string, all bool) ([]string, error) {
+	if !all {
+		ns, err := k.namespace(ns)
+		if err != nil {
+			return nil, err
+		}
+		return []string{ns}, nil
+	}
+	if k.allowed == nil {
+		return nil, nil
+	}
+	return k.cfg.Namespaces, nil
+}
+
+// list fetches the items of a namespaced resource matching a label
+// selector in the given namespaces, nil meaning all of them, decoding
+// each into a T.
+func list[T any](k *K8s, ctx *runtime.Context, group, resource string, namespaces []string, labels string) ([]T, bool, error) {
+	query := url.Values{}
+	if labels != "" {
+		query.Set("labelSelector", labels)
+	}
+	query.Set("limit", strconv.Itoa(k.cfg.MaxResults+1))
+	paths := []string{group + "/" + resource}
+	if namespaces != nil {
+		paths = paths[:0]
+		for _, ns := range namespaces {
+			paths = append(paths, group+"/namespaces/"+url.PathEscape(ns)+"/"+resource)
+		}
+	}
+	var out []T
+	for _, p := range paths {
+		var page struct {
+			Items []T `json:"items"`
+		}
+		if err := k.client.Get(ctx, p, query, &page); err != nil {
+			return nil, false, err
+		}
+		out = append(out, page.Items...)
+		if len(out) > k.cfg.MaxResults {
+			return out[:k.cfg.MaxResults], true, nil
+		}
+	}
+	return out, false, nil
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+// objectMeta is the part of resource metadata the summaries use.
+type objectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+}
+
+type listArgs struct {
+	Namespace     string `json:"namespace,omitempty" description:"Namespace to list; defaults to the configured namespace."`
+	AllNamespaces bool   `json:"allNamespaces,omitempty" description:"List every available namespace."`
+	LabelSelector string `json:"labelSelector,omitempty" description:"Only list resources matching this label selector, such as app=web."`
+}
+
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		NodeName string `json:"nodeName"`
+	} `json:"spec"`
+	Status struct {
+		Phase             string     `json:"phase"`
+		Reason            string     `json:"reason"`
+		PodIP             string     `json:"podIP"`
+		StartTime         *time.Time `json:"startTime"`
+		ContainerStatuses []struct {
+			Name         string `json:"name"`
+			Ready        bool   `json:"ready"`
+			RestartCount int    `json:"restartCount"`
+			State        map[string]struct {
+				Reason string `json:"reason"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// podSummary is a pod as listed by k8s.list_pods.
+type podSummary struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Status is the phase, or the reason a container is not running, such
+	// as CrashLoopBackOff, as kubectl shows it.
+	Status    string     `json:"status"`
+	Ready     string     `json:"ready"`
+	Restarts  int        `json:"restarts"`
+	Node      string     `json:"node,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+}
+
+func (k *K8s) listPodsTool(ctx *runtime.Context, args listArgs) (*protocol.ToolCallResult, error) {
+	namespaces, err := k.namespaces(args.Namespace, args.AllNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	pods, truncated, err := list[pod](k, ctx, "/api/v1", "pods", namespaces, args.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]podSummary, 0, len(pods))
+	for _, p := range pods {
+		s := podSummary{
+			Name:      p.Metadata.Name,
+			Namespace: p.Metadata.Namespace,
+			Status:    p.Status.Phase,
+			Node:      p.Spec.NodeName,
+			IP:        p.Status.PodIP,
+			StartTime: p.Status.StartTime,
+		}
+		if p.Status.Reason != "" {
+			s.Status = p.Status.Reason
+		}
+		ready := 0
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			s.Restarts += cs.RestartCount
+			for state, detail := range cs.State {
+				if state != "running" && detail.Reason != "" {
+					s.Status = detail.Reason
+				}
+			}
+		}
+		s.Ready = fmt.Sprintf("%d/%d", ready, len(p.Status.ContainerStatuses))
+		out = append(out, s)
+	}
+	return jsonResult(map[string]interface{}{"pods": out, "truncated": truncated})
+}
+
+type deployment struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int `json:"replicas"`
+		Paused   bool `json:"paused"`
+		Template struct {
+			Spec struct {
+				Containers []struct {
+					Image string `json:"image"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		ReadyReplicas     int `json:"readyReplicas"`
+		UpdatedReplicas   int `json:"updatedReplicas"`
+		AvailableReplicas int `json:"availableReplicas"`
+	} `json:"status"`
+}
+
+// deploymentSummary is a deployment as listed by k8s.list_deployments.
+type deploymentSummary struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Replicas  int       `json:"replicas"`
+	Ready     int       `json:"ready"`
+	Updated   int       `json:"updated"`
+	Available int       `json:"available"`
+	Paused    bool      `json:"paused,omitempty"`
+	Images    []string  `json:"images"`
+	Created   time.Time `json:"created"`
+}
+
+func (k *K8s) listDeploymentsTool(ctx *runtime.Context, args listArgs) (*protocol.ToolCallResult, error) {
+	namespaces, err := k.namespaces(args.Namespace, args.AllNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	deployments, truncated, err := list[deployment](k, ctx, "/apis/apps/v1", "deployments", namespaces, args.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]deploymentSummary, 0, len(deployments))
+	for _, d := range deployments {
+		s := deploymentSummary{
+			Name:      d.Metadata.Name,
+			Namespace: d.Metadata.Namespace,
+			Replicas:  1,
+			Ready:     d.Status.ReadyReplicas,
+			Updated:   d.Status.UpdatedReplicas,
+			Available: d.Status.AvailableReplicas,
+			Paused:    d.Spec.Paused,
+			Images:    []string{},
+			Created:   d.Metadata.CreationTimestamp,
+		}
+		if d.Spec.Replicas != nil {
+			s.Replicas = *d.Spec.Replicas
+		}
+		for _, c := range d.Spec.Template.Spec.Containers {
+			s.Images = append(s.Images, c.Image)
+		}
+		out = append(out, s)
+	}
+	return jsonResult(map[string]interface{}{"deployments": out, "truncated": truncated})
+}
+
+type logsArgs struct {
+	Namespace    string `json:"namespace,omitempty" description:"Namespace of the pod; defaults to the configured namespace."`
+	Pod          string `json:"pod" description:"Name of the pod." validate:"nonzero"`
+	Container    string `json:"container,omitempty" description:"Container to read; required when the pod has several."`
+	TailLines    int    `json:"tailLines,omitempty" description:"Number of most recent lines to read." default:"200"`
+	SinceSeconds int    `json:"sinceSeconds,omitempty" description:"Only read lines logged in the last this many seconds."`
+	Previous     bool   `json:"previous,omitempty" description:"Read the logs of the previous, terminated instance of the container, as after a crash."`
+	Timestamps   bool   `json:"timestamps,omitempty" description:"Prefix each line with its timestamp."`
+}
+
+func (k *K8s) logsTool(ctx *runtime.Context, args logsArgs) (*protocol.ToolCallResult, error) {
+	ns, err := k.namespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	tail := args.TailLines
+	if tail <= 0 {
+		tail = 200
+	}
+	query.Set("tailLines", strconv.Itoa(tail))
+	query.Set("limitBytes", strconv.Itoa(k.cfg.MaxLogBytes))
+	if args.Container != "" {
+		query.Set("container", args.Container)
+	}
+	if args.SinceSeconds > 0 {
+		query.Set("sinceSeconds", strconv.Itoa(args.SinceSeconds))
+	}
+	if args.Previous {
+		query.Set("previous", "true")
+	}
+	if args.Timestamps {
+		query.Set("timestamps", "true")
+	}
+	logs, truncated, err := k.client.GetText(ctx, "/api/v1/namespaces/"+url.PathEscape(ns)+"/pods/"+url.PathEscape(args.Pod)+"/log", query, k.cfg.MaxLogBytes)
+	if err != nil {
+		return nil, err
+	}
+	if truncated || len(logs) == k.cfg.MaxLogBytes {
+		logs += fmt.Sprintf("\n[logs cut at %s bytes; ask for fewer lines]", ctx.FormatNumber(float64(k.cfg.MaxLogBytes), 0))
+	}
+	if logs == "" {
+		logs = "(no log lines)"
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(logs)}}, nil
+}
+
+// resourceKind locates a kind of resource in the API.
+type resourceKind struct {
+	Kind       string
+	Group      string
+	Resource   string
+	Namespaced bool
+}
+
+// kinds lists the kinds k8s.describe accepts, by lowercase name. Secrets
+// are deliberately absent.
+var kinds = map[string]resourceKind{
+	"pod":                     {"Pod", "/api/v1", "pods", true},
+	"service":                 {"Service", "/api/v1", "services", true},
+	"configmap":               {"ConfigMap", "/api/v1", "configmaps", true},
+	"persistentvolumeclaim":   {"PersistentVolumeClaim", "/api/v1", "persistentvolumeclaims", true},
+	"serviceaccount":          {"ServiceAccount", "/api/v1", "serviceaccounts", true},
+	"endpoints":               {"Endpoints", "/api/v1", "endpoints", true},
+	"node":                    {"Node", "/api/v1", "nodes", false},
+	"namespace":               {"Namespace", "/api/v1", "namespaces", false},
+	"persistentvolume":        {"PersistentVolume", "/api/v1", "persistentvolumes", false},
+	"deployment":              {"Deployment", "/apis/apps/v1", "deployments", true},
+	"replicaset":              {"ReplicaSet", "/apis/apps/v1", "replicasets", true},
+	"statefulset":             {"StatefulSet", "/apis/apps/v1", "statefulsets", true},
+	"daemonset":               {"DaemonSet", "/apis/apps/v1", "daemonsets", true},
+	"job":                     {"Job", "/apis/batch/v1", "jobs", true},
+	"cronjob":                 {"CronJob", "/apis/batch/v1", "cronjobs", true},
+	"ingress":                 {"Ingress", "/apis/networking.k8s.io/v1", "ingresses", true},
+	"horizontalpodautoscaler": {"HorizontalPodAutoscaler", "/apis/autoscaling/v2", "horizontalpodautoscalers", true},
+}
+
+// kindAliases maps kubectl's plural and short names to kinds.
+var kindAliases = map[string]string{
+	"po": "pod", "svc": "service", "cm": "configmap", "pvc": "persistentvolumeclaim",
+	"sa": "serviceaccount", "ep": "endpoints", "no": "node", "ns": "namespace",
+	"pv": "persistentvolume", "deploy": "deployment", "rs": "replicaset",
+	"sts": "statefulset", "ds": "daemonset", "cj": "cronjob", "ing": "ingress",
+	"hpa": "horizontalpodautoscaler", "ingresses": "ingress",
+}
+
+// lookupKind finds a kind by name, plural or short name.
+func lookupKind(name string) (resourceKind, bool) {
+	name = strings.ToLower(name)
+	if alias, ok := kindAliases[name]; ok {
+		name = alias
+	}
+	if rk, ok := kinds[name]; ok {
+		return rk, true
+	}
+	rk, ok := kinds[strings.TrimSuffix(name, "s")]
+	return rk, ok
+}
+
+func kindNames() string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+type describeArgs struct {
+	Kind      string `json:"kind" description:"Kind of resource, such as pod, deployment or node; kubectl short names work too." validate:"nonzero"`
+	Name      string `json:"name" description:"Name of the resource." validate:"nonzero"`
+	Namespace string `json:"namespace,omitempty" description:"Namespace of the resource; defaults to the configured namespace."`
+}
+
+// event is an event as listed by k8s.describe.
+type event struct {
+	Type     string    `json:"type"`
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message"`
+	Count    int       `json:"count,omitempty"`
+	LastSeen time.Time `json:"lastSeen"`
+	Source   string    `json:"source,omitempty"`
+}
+
+// maxEvents bounds the events returned with a description.
+const maxEvents = 20
+
+func (k *K8s) describeTool(ctx *runtime.Context, args describeArgs) (*protocol.ToolCallResult, error) {
+	if strings.HasPrefix(strings.ToLower(args.Kind), "secret") {
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: secrets cannot be described", nil)
+	}
+	rk, ok := lookupKind(args.Kind)
+	if !ok {
+		return nil, protocol.NewError(protocol.InvalidParams,
+			fmt.Sprintf("invalid arguments: unknown kind %q; known kinds: %s", args.Kind, kindNames()), nil)
+	}
+	var path, ns string
+	if rk.Namespaced {
+		var err error
+		if ns, err = k.namespace(args.Namespace); err != nil {
+			return nil, err
+		}
+		path = rk.Group + "/namespaces/" + url.PathEscape(ns) + "/" + rk.Resource + "/" + url.PathEscape(args.Name)
+	} else {
+		if k.allowed != nil {
+			return nil, protocol.NewError(protocol.InvalidParams,
+				fmt.Sprintf("invalid arguments: %s is cluster-scoped and only namespaced resources are available", rk.Kind), nil)
+		}
+		path = rk.Group + "/" + rk.Resource + "/" + url.PathEscape(args.Name)
+	}
+	var obj map[string]interface{}
+	if err := k.client.Get(ctx, path, nil, &obj); err != nil {
+		return nil, err
+	}
+	if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+		// Bookkeeping that only clutters the description.
+		delete(meta, "managedFields")
+		if ann, ok := meta["annotations"].(map[string]interface{}); ok {
+			delete(ann, "kubectl.kubernetes.io/last-applied-configuration")
+		}
+	}
+	events, err := k.events(ctx, rk, ns, args.Name)
+	if err != nil {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			return nil, err
+		}
+		// Reading events may be forbidden where the object is not;
+		// describe the object anyway.
+		events = []event{}
+	}
+	return jsonResult(map[string]interface{}{"object": obj, "events": events})
+}
+
+// events returns the most recent events about an object.
+func (k *K8s) events(ctx *runtime.Context, rk resourceKind, ns, name string) ([]event, error) {
+	query := url.Values{}
+	query.Set("fieldSelector", "involvedObject.kind="+rk.Kind+",involvedObject.name="+name)
+	path := "/api/v1/events"
+	if ns != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(ns) + "/events"
+	}
+	var page struct {
+		Items []struct {
+			Type           string    `json:"type"`
+			Reason         string    `json:"reason"`
+			Message        string    `json:"message"`
+			Count          int       `json:"count"`
+			FirstTimestamp time.Time `json:"firstTimestamp"`
+			LastTimestamp  time.Time `json:"lastTimestamp"`
+			EventTime      time.Time `json:"eventTime"`
+			Source         struct {
+				Component string `json:"component"`
+			} `json:"source"`
+			ReportingComponent string `json:"reportingComponent"`
+		} `json:"items"`
+	}
+	if err := k.client.Get(ctx, path, query, &page); err != nil {
+		return nil, err
+	}
+	out := make([]event, 0, len(page.Items))
+	for _, e := range page.Items {
+		ev := event{Type: e.Type, Reason: e.Reason, Message: e.Message, Count: e.Count, LastSeen: e.LastTimestamp, Source: e.Source.Component}
+		if ev.LastSeen.IsZero() {
+			ev.LastSeen = e.EventTime
+		}
+		if ev.LastSeen.IsZero() {
+			ev.LastSeen = e.FirstTimestamp
+		}
+		if ev.Source == "" {
+			ev.Source = e.ReportingComponent
+		}
+		out = append(out, ev)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	if len(out) > maxEvents {
+		out = out[:maxEvents]
+	}
+	return out, nil
+}
@@ -0,0 +1,238 @@
+package k8s_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/zentool/k8s"
+)
+
+// secretValue is the data of every secret the fake API server holds; it
+// must never reach a tool result.
+const secretValue = "c3VwZXItc2VjcmV0"
+
+// apiServer is a fake API server recording the paths it is asked for.
+type apiServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	paths []string
+}
+
+func newAPIServer(t *testing.T) *apiServer {
+	a := &apiServer{}
+	a.Server = httptest.NewServer(http.HandlerFunc(a.serve))
+	t.Cleanup(a.Close)
+	return a
+}
+
+func (a *apiServer) serve(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.EscapedPath()
+	a.mu.Lock()
+	a.paths = append(a.paths, path)
+	a.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	ns := ""
+	for i, p := range parts {
+		if p == "namespaces" && i+1 < len(parts) {
+			ns = parts[i+1]
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch last := parts[len(parts)-1]; {
+	case strings.Contains(path, "/secrets"):
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"kind":     "Secret",
+			"metadata": map[string]interface{}{"name": last, "namespace": ns},
+			"data":     map[string]string{"password": secretValue},
+		})
+	case last == "pods":
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "api-0", "namespace": ns},
+				"status":   map[string]interface{}{"phase": "Running"},
+			},
+		}})
+	case last == "deployments":
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "api", "namespace": ns}},
+		}})
+	case last == "events":
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{}})
+	case last == "log":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("started\n"))
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"kind":     "Object",
+			"metadata": map[string]interface{}{"name": last, "namespace": ns},
+		})
+	}
+}
+
+// requested returns the paths asked for since the last call.
+func (a *apiServer) requested() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	paths := a.paths
+	a.paths = nil
+	return paths
+}
+
+func install(t *testing.T, a *apiServer, namespaces ...string) *mcp.Server {
+	t.Helper()
+	s := mcp.NewServer()
+	_, err := k8s.Install(s, k8s.Config{
+		Cluster:    &k8s.Cluster{Server: a.URL, Token: "test-token", Namespace: "default"},
+		Namespaces: namespaces,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// call calls a tool and returns the text of its result, or its error.
+func call(t *testing.T, s *mcp.Server, tool string, args map[string]interface{}) (string, *protocol.Error) {
+	t.Helper()
+	raw, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := protocol.NewRequest(protocol.NewNumberID(1), protocol.MethodToolsCall, protocol.ToolCallParams{Name: tool, Arguments: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runtime.WithSession(context.Background(), runtime.NewSession())
+	resp := s.Router().Dispatch(ctx, req)
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	var res protocol.ToolCallResult
+	if err := json.Unmarshal(resp.Result, &res); err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	for _, c := range res.Content {
+		text.WriteString(c.Text)
+	}
+	if res.IsError {
+		return "", &protocol.Error{Message: text.String()}
+	}
+	return text.String(), nil
+}
+
+func TestNamespaceAllowList(t *testing.T) {
+	a := newAPIServer(t)
+	s := install(t, a, "web", "jobs")
+	type args = map[string]interface{}
+
+	tests := []struct {
+		name  string
+		tool  string
+		args  args
+		paths []string // nil when the call must fail without a request
+	}{
+		{"list allowed", k8s.ListPodsToolName, args{"namespace": "web"}, []string{"/api/v1/namespaces/web/pods"}},
+		{"list all allowed", k8s.ListDeploymentsToolName, args{"allNamespaces": true}, []string{
+			"/apis/apps/v1/namespaces/web/deployments",
+			"/apis/apps/v1/namespaces/jobs/deployments",
+		}},
+		{"list other", k8s.ListPodsToolName, args{"namespace": "kube-system"}, nil},
+		{"list default, not allowed", k8s.ListPodsToolName, args{}, nil},
+		{"logs allowed", k8s.LogsToolName, args{"namespace": "jobs", "pod": "api-0"}, []string{"/api/v1/namespaces/jobs/pods/api-0/log"}},
+		{"logs other", k8s.LogsToolName, args{"namespace": "kube-system", "pod": "etcd-0"}, nil},
+		{"describe allowed", k8s.DescribeToolName, args{"kind": "po", "name": "api-0", "namespace": "web"}, []string{
+			"/api/v1/namespaces/web/pods/api-0",
+			"/api/v1/namespaces/web/events",
+		}},
+		{"describe other", k8s.DescribeToolName, args{"kind": "deployment", "name": "coredns", "namespace": "kube-system"}, nil},
+		{"describe cluster-scoped", k8s.DescribeToolName, args{"kind": "node", "name": "node-1"}, nil},
+		{"escaped name", k8s.DescribeToolName, args{"kind": "pod", "name": "../../kube-system/pods/etcd-0", "namespace": "web"}, []string{
+			"/api/v1/namespaces/web/pods/..%2F..%2Fkube-system%2Fpods%2Fetcd-0",
+			"/api/v1/namespaces/web/events",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, callErr := call(t, s, tt.tool, tt.args)
+			paths := a.requested()
+			if tt.paths == nil {
+				if callErr == nil || callErr.Code != protocol.InvalidParams {
+					t.Errorf("call error = %v, want InvalidParams", callErr)
+				}
+				if len(paths) > 0 {
+					t.Errorf("requested %v, want nothing", paths)
+				}
+				return
+			}
+			if callErr != nil {
+				t.Fatalf("call: %v", callErr)
+			}
+			if strings.Join(paths, " ") != strings.Join(tt.paths, " ") {
+				t.Errorf("requested %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestNamespaceDefault(t *testing.T) {
+	a := newAPIServer(t)
+
+	// A single allowed namespace stands in for a default that is not
+	// allowed.
+	s := install(t, a, "web")
+	if _, err := call(t, s, k8s.ListPodsToolName, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.requested(); len(got) != 1 || got[0] != "/api/v1/namespaces/web/pods" {
+		t.Errorf("requested %v, want the pods of web", got)
+	}
+
+	// Without an allow-list, lists may span the cluster.
+	s = install(t, a)
+	if _, err := call(t, s, k8s.ListPodsToolName, map[string]interface{}{"allNamespaces": true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.requested(); len(got) != 1 || got[0] != "/api/v1/pods" {
+		t.Errorf("requested %v, want the pods of the cluster", got)
+	}
+}
+
+func TestSecretsNeverDescribed(t *testing.T) {
+	a := newAPIServer(t)
+	for _, namespaces := range [][]string{nil, {"web"}} {
+		s := install(t, a, namespaces...)
+		for _, kind := range []string{"secret", "secrets", "Secret", "SECRETS", "secret.v1"} {
+			text, err := call(t, s, k8s.DescribeToolName, map[string]interface{}{"kind": kind, "name": "db", "namespace": "web"})
+			if err == nil || err.Code != protocol.InvalidParams {
+				t.Errorf("describe %s: error = %v, want InvalidParams", kind, err)
+			}
+			if strings.Contains(text, secretValue) {
+				t.Errorf("describe %s returned the secret", kind)
+			}
+		}
+		// Nor can a secret be reached through the name of another kind.
+		text, err := call(t, s, k8s.DescribeToolName, map[string]interface{}{"kind": "configmap", "name": "../secrets/db", "namespace": "web"})
+		if err == nil && strings.Contains(text, secretValue) {
+			t.Errorf("describe through a crafted name returned the secret")
+		}
+		for _, p := range a.requested() {
+			if strings.Contains(p, "/secrets") {
+				t.Errorf("requested %s", p)
+			}
+		}
+	}
+}
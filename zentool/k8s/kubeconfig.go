@@ -0,0 +1,322 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cluster describes how to reach a cluster and whom to authenticate as.
+// It is usually loaded from a kubeconfig file or from the service account
+// of the pod the server runs in. Requests are made with these
+// credentials, so the cluster's RBAC rules decide what the tools can see.
+type Cluster struct {
+	// Server is the base URL of the API server.
+	Server string
+	// CAData holds the PEM certificates that verify the server. When
+	// empty, the system roots are used.
+	CAData                []byte
+	InsecureSkipTLSVerify bool
+	TLSServerName         string
+
+	// Token is a bearer token. TokenFile names a file holding one, read
+	// on every request so that rotated tokens are picked up.
+	Token     string
+	TokenFile string
+	// ClientCertData and ClientKeyData are a PEM client certificate and
+	// key.
+	ClientCertData []byte
+	ClientKeyData  []byte
+	Username       string
+	Password       string
+	// Exec runs a credential plugin to obtain a token or certificate.
+	Exec *ExecConfig
+
+	// Namespace is the default namespace.
+	Namespace string
+}
+
+// ExecConfig is a client-go credential plugin: a command printing an
+// ExecCredential object on its standard output.
+type ExecConfig struct {
+	Command    string
+	Args       []string
+	Env        map[string]string
+	APIVersion string
+}
+
+// kubeconfig is the part of a kubeconfig file the loader understands.
+type kubeconfig struct {
+	CurrentContext string `json:"current-context"`
+	Clusters       []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server                   string `json:"server"`
+			CertificateAuthority     string `json:"certificate-authority"`
+			CertificateAuthorityData string `json:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+			TLSServerName            string `json:"tls-server-name"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			Token                 string          `json:"token"`
+			TokenFile             string          `json:"tokenFile"`
+			ClientCertificate     string          `json:"client-certificate"`
+			ClientCertificateData string          `json:"client-certificate-data"`
+			ClientKey             string          `json:"client-key"`
+			ClientKeyData         string          `json:"client-key-data"`
+			Username              string          `json:"username"`
+			Password              string          `json:"password"`
+			AuthProvider          json.RawMessage `json:"auth-provider"`
+			Exec                  *struct {
+				Command    string   `json:"command"`
+				Args       []string `json:"args"`
+				APIVersion string   `json:"apiVersion"`
+				Env        []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"env"`
+			} `json:"exec"`
+		} `json:"user"`
+	} `json:"users"`
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster   string `json:"cluster"`
+			User      string `json:"user"`
+			Namespace string `json:"namespace"`
+		} `json:"context"`
+	} `json:"contexts"`
+}
+
+var errNoKubeconfig = errors.New("k8s: no kubeconfig found")
+
+// LoadKubeconfig loads the cluster of a kubeconfig context; an empty
+// context selects the current one. path may list several files separated
+// by the OS path list separator, which are merged as kubectl does: the
+// first definition of a name wins. When path is empty, $KUBECONFIG is
+// used, then ~/.kube/config.
+func LoadKubeconfig(path, context string) (*Cluster, error) {
+	if path == "" {
+		path = os.Getenv("KUBECONFIG")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("k8s: no kubeconfig: %w", err)
+		}
+		path = filepath.Join(home, ".kube", "config")
+	}
+	var merged kubeconfig
+	// dirs records the directory of the file defining each cluster and
+	// user, against which relative file names are resolved.
+	dirs := make(map[string]string)
+	found := false
+	for _, file := range filepath.SplitList(path) {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("k8s: %w", err)
+		}
+		found = true
+		kc, err := parseKubeconfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: %s: %w", file, err)
+		}
+		dir := filepath.Dir(file)
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = kc.CurrentContext
+		}
+		for _, c := range kc.Clusters {
+			if _, ok := dirs["cluster/"+c.Name]; !ok {
+				dirs["cluster/"+c.Name] = dir
+				merged.Clusters = append(merged.Clusters, c)
+			}
+		}
+		for _, u := range kc.Users {
+			if _, ok := dirs["user/"+u.Name]; !ok {
+				dirs["user/"+u.Name] = dir
+				merged.Users = append(merged.Users, u)
+			}
+		}
+		seen := make(map[string]bool)
+		for _, c := range merged.Contexts {
+			seen[c.Name] = true
+		}
+		for _, c := range kc.Contexts {
+			if !seen[c.Name] {
+				merged.Contexts = append(merged.Contexts, c)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w at %s", errNoKubeconfig, path)
+	}
+	return merged.cluster(context, dirs)
+}
+
+func parseKubeconfig(data []byte) (*kubeconfig, error) {
+	v, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	// Decode through JSON to fill the typed structure.
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var kc kubeconfig
+	if err := json.Unmarshal(js, &kc); err != nil {
+		return nil, err
+	}
+	return &kc, nil
+}
+
+// cluster builds the Cluster of the named context.
+func (kc *kubeconfig) cluster(name string, dirs map[string]string) (*Cluster, error) {
+	if name == "" {
+		name = kc.CurrentContext
+	}
+	if name == "" {
+		return nil, errors.New("k8s: kubeconfig has no current context")
+	}
+	var out Cluster
+	ctxFound, clusterFound := false, false
+	for _, c := range kc.Contexts {
+		if c.Name != name {
+			continue
+		}
+		ctxFound = true
+		out.Namespace = c.Context.Namespace
+		for _, cl := range kc.Clusters {
+			if cl.Name != c.Context.Cluster {
+				continue
+			}
+			clusterFound = true
+			dir := dirs["cluster/"+cl.Name]
+			out.Server = cl.Cluster.Server
+			out.InsecureSkipTLSVerify = cl.Cluster.InsecureSkipTLSVerify
+			out.TLSServerName = cl.Cluster.TLSServerName
+			var err error
+			if out.CAData, err = dataOrFile(cl.Cluster.CertificateAuthorityData, cl.Cluster.CertificateAuthority, dir); err != nil {
+				return nil, err
+			}
+			break
+		}
+		for _, u := range kc.Users {
+			if u.Name != c.Context.User {
+				continue
+			}
+			dir := dirs["user/"+u.Name]
+			if len(u.User.AuthProvider) > 0 && string(u.User.AuthProvider) != "null" {
+				return nil, fmt.Errorf("k8s: user %s uses an auth provider, which is not supported; use an exec credential plugin", u.Name)
+			}
+			out.Token = u.User.Token
+			out.TokenFile = resolvePath(u.User.TokenFile, dir)
+			out.Username, out.Password = u.User.Username, u.User.Password
+			var err error
+			if out.ClientCertData, err = dataOrFile(u.User.ClientCertificateData, u.User.ClientCertificate, dir); err != nil {
+				return nil, err
+			}
+			if out.ClientKeyData, err = dataOrFile(u.User.ClientKeyData, u.User.ClientKey, dir); err != nil {
+				return nil, err
+			}
+			if e := u.User.Exec; e != nil {
+				out.Exec = &ExecConfig{Command: e.Command, Args: e.Args, APIVersion: e.APIVersion, Env: make(map[string]string)}
+				if strings.ContainsRune(e.Command, filepath.Separator) {
+					out.Exec.Command = resolvePath(e.Command, dir)
+				}
+				for _, kv := range e.Env {
+					out.Exec.Env[kv.Name] = kv.Value
+				}
+			}
+			break
+		}
+		break
+	}
+	// A context naming no known user connects anonymously.
+	switch {
+	case !ctxFound:
+		return nil, fmt.Errorf("k8s: kubeconfig has no context %q", name)
+	case !clusterFound:
+		return nil, fmt.Errorf("k8s: context %q names an unknown cluster", name)
+	}
+	if out.Server == "" {
+		return nil, fmt.Errorf("k8s: context %q has no server", name)
+	}
+	return &out, nil
+}
+
+// dataOrFile returns base64 data, or else the content of the named file.
+func dataOrFile(data, file, dir string) ([]byte, error) {
+	if data != "" {
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: kubeconfig: %w", err)
+		}
+		return b, nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(resolvePath(file, dir))
+	if err != nil {
+		return nil, fmt.Errorf("k8s: %w", err)
+	}
+	return b, nil
+}
+
+func resolvePath(p, dir string) string {
+	if p == "" || filepath.IsAbs(p) || dir == "" {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// serviceAccountDir holds the credentials mounted in every pod.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// InClusterConfig returns the cluster a pod runs in, authenticated as
+// the pod's service account.
+func InClusterConfig() (*Cluster, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("k8s: not running in a cluster")
+	}
+	tokenFile := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(tokenFile); err != nil {
+		return nil, fmt.Errorf("k8s: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("k8s: %w", err)
+	}
+	c := &Cluster{Server: "https://" + net.JoinHostPort(host, port), CAData: ca, TokenFile: tokenFile}
+	if ns, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		c.Namespace = strings.TrimSpace(string(ns))
+	}
+	return c, nil
+}
+
+// DefaultCluster loads the cluster the way kubectl and client-go do: from
+// the kubeconfig at path, $KUBECONFIG or ~/.kube/config, or when none
+// exists, from the in-cluster service account.
+func DefaultCluster(path, context string) (*Cluster, error) {
+	c, err := LoadKubeconfig(path, context)
+	if errors.Is(err, errNoKubeconfig) {
+		if in, inErr := InClusterConfig(); inErr == nil {
+			return in, nil
+		}
+	}
+	return c, err
+}
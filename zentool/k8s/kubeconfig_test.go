@@ -0,0 +1,339 @@
+package k8s_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperleex/zenmcp/zentool/k8s"
+)
+
+func b64(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+// kind is the kubeconfig kind writes: inline certificate data.
+var kind = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: ` + b64("kind-ca") + `
+    server: https://127.0.0.1:40283
+  name: kind-kind
+contexts:
+- context:
+    cluster: kind-kind
+    user: kind-kind
+  name: kind-kind
+current-context: kind-kind
+kind: Config
+preferences: {}
+users:
+- name: kind-kind
+  user:
+    client-certificate-data: ` + b64("kind-cert") + `
+    client-key-data: ` + b64("kind-key") + `
+`
+
+// gke is the kubeconfig gcloud writes, with the exec plugin's install
+// hint spanning two lines.
+const gke = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Z2tlLWNh
+    server: https://34.1.2.3
+  name: gke_acme_europe-west1_prod
+contexts:
+- context:
+    cluster: gke_acme_europe-west1_prod
+    user: gke_acme_europe-west1_prod
+  name: gke_acme_europe-west1_prod
+current-context: gke_acme_europe-west1_prod
+kind: Config
+preferences: {}
+users:
+- name: gke_acme_europe-west1_prod
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: gke-gcloud-auth-plugin
+      installHint: Install gke-gcloud-auth-plugin for use with kubectl by following
+        https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-access-for-kubectl#install_plugin
+      provideClusterInfo: true
+`
+
+// eks is the kubeconfig aws eks update-kubeconfig writes: names are ARNs
+// and the plugin takes arguments and environment variables.
+const eks = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: ZWtzLWNh
+    server: https://ABCDEF.gr7.us-west-2.eks.amazonaws.com
+  name: arn:aws:eks:us-west-2:123456789012:cluster/demo
+contexts:
+- context:
+    cluster: arn:aws:eks:us-west-2:123456789012:cluster/demo
+    namespace: payments
+    user: arn:aws:eks:us-west-2:123456789012:cluster/demo
+  name: arn:aws:eks:us-west-2:123456789012:cluster/demo
+current-context: arn:aws:eks:us-west-2:123456789012:cluster/demo
+kind: Config
+preferences: {}
+users:
+- name: arn:aws:eks:us-west-2:123456789012:cluster/demo
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      args:
+      - --region
+      - us-west-2
+      - eks
+      - get-token
+      - --cluster-name
+      - demo
+      command: aws
+      env:
+      - name: AWS_PROFILE
+        value: prod
+      interactiveMode: IfAvailable
+      provideClusterInfo: false
+`
+
+// minikube is the kubeconfig minikube writes: certificates are files,
+// here relative to the kubeconfig, and clusters carry extensions.
+const minikube = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority: certs/ca.crt
+    extensions:
+    - extension:
+        last-update: Mon, 01 Jan 2024 10:00:00 UTC
+        provider: minikube.sigs.k8s.io
+        version: v1.32.0
+      name: cluster_info
+    server: https://192.168.49.2:8443
+  name: minikube
+contexts:
+- context:
+    cluster: minikube
+    extensions:
+    - extension:
+        provider: minikube.sigs.k8s.io
+      name: context_info
+    namespace: default
+    user: minikube
+  name: minikube
+current-context: minikube
+kind: Config
+preferences: {}
+users:
+- name: minikube
+  user:
+    client-certificate: certs/client.crt
+    client-key: certs/client.key
+`
+
+// handWritten exercises quoting, comments, flow collections and a token
+// file, with Windows line endings.
+var handWritten = strings.ReplaceAll(`# Written by hand.
+current-context: "staging"   # the default
+clusters:
+  - name: 'staging'
+    cluster:
+      server: "https://staging.example.com:6443"
+      insecure-skip-tls-verify: true
+      tls-server-name: api.staging.internal
+contexts:
+  - name: staging
+    context: {cluster: staging, user: robot, namespace: web}
+  - name: ops
+    context: {cluster: staging, user: "ops-user"}
+users:
+  - name: robot
+    user:
+      tokenFile: robot.token
+  - name: ops-user
+    user:
+      username: ops
+      password: "s3cr:t #1"
+  - name: bot
+    user:
+      exec:
+        command: ./bin/get-token
+        args: ["--audience", "k8s"]
+`, "\n", "\r\n")
+
+func TestLoadKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("certs/ca.crt", "minikube-ca")
+	write("certs/client.crt", "minikube-cert")
+	write("certs/client.key", "minikube-key")
+	list := func(paths ...string) string { return strings.Join(paths, string(os.PathListSeparator)) }
+
+	tests := []struct {
+		name    string
+		path    string
+		context string
+		want    *k8s.Cluster
+	}{
+		{"kind", write("kind", kind), "", &k8s.Cluster{
+			Server:         "https://127.0.0.1:40283",
+			CAData:         []byte("kind-ca"),
+			ClientCertData: []byte("kind-cert"),
+			ClientKeyData:  []byte("kind-key"),
+		}},
+		{"gke", write("gke", gke), "", &k8s.Cluster{
+			Server: "https://34.1.2.3",
+			CAData: []byte("gke-ca"),
+			Exec: &k8s.ExecConfig{
+				Command:    "gke-gcloud-auth-plugin",
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+				Env:        map[string]string{},
+			},
+		}},
+		{"eks", write("eks", eks), "", &k8s.Cluster{
+			Server:    "https://ABCDEF.gr7.us-west-2.eks.amazonaws.com",
+			CAData:    []byte("eks-ca"),
+			Namespace: "payments",
+			Exec: &k8s.ExecConfig{
+				Command:    "aws",
+				Args:       []string{"--region", "us-west-2", "eks", "get-token", "--cluster-name", "demo"},
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+				Env:        map[string]string{"AWS_PROFILE": "prod"},
+			},
+		}},
+		{"minikube", write("minikube", minikube), "", &k8s.Cluster{
+			Server:         "https://192.168.49.2:8443",
+			CAData:         []byte("minikube-ca"),
+			ClientCertData: []byte("minikube-cert"),
+			ClientKeyData:  []byte("minikube-key"),
+			Namespace:      "default",
+		}},
+		{"hand-written", write("hand/config", handWritten), "", &k8s.Cluster{
+			Server:                "https://staging.example.com:6443",
+			InsecureSkipTLSVerify: true,
+			TLSServerName:         "api.staging.internal",
+			TokenFile:             filepath.Join(dir, "hand", "robot.token"),
+			Namespace:             "web",
+		}},
+		{"hand-written basic auth", filepath.Join(dir, "hand/config"), "ops", &k8s.Cluster{
+			Server:                "https://staging.example.com:6443",
+			InsecureSkipTLSVerify: true,
+			TLSServerName:         "api.staging.internal",
+			Username:              "ops",
+			Password:              "s3cr:t #1",
+		}},
+		// Merged files: the current context comes from the first file
+		// setting one, and the first definition of a name wins.
+		{"merged", list(filepath.Join(dir, "missing"), filepath.Join(dir, "kind"), filepath.Join(dir, "eks")), "", &k8s.Cluster{
+			Server:         "https://127.0.0.1:40283",
+			CAData:         []byte("kind-ca"),
+			ClientCertData: []byte("kind-cert"),
+			ClientKeyData:  []byte("kind-key"),
+		}},
+		{"merged, other context", list(filepath.Join(dir, "kind"), filepath.Join(dir, "minikube")), "minikube", &k8s.Cluster{
+			Server:         "https://192.168.49.2:8443",
+			CAData:         []byte("minikube-ca"),
+			ClientCertData: []byte("minikube-cert"),
+			ClientKeyData:  []byte("minikube-key"),
+			Namespace:      "default",
+		}},
+		{"merged, shadowed cluster", list(write("override", `clusters:
+- name: kind-kind
+  cluster:
+    server: https://kind.example.com
+current-context: kind-kind
+`), filepath.Join(dir, "kind")), "", &k8s.Cluster{
+			Server:         "https://kind.example.com",
+			ClientCertData: []byte("kind-cert"),
+			ClientKeyData:  []byte("kind-key"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k8s.LoadKubeconfig(tt.path, tt.context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The exec command of a user is resolved against the directory of
+	// the file defining it when it is a path.
+	bot := write("bot/config", handWritten+"contexts:\r\n- name: bot\r\n  context: {cluster: staging, user: bot}\r\n")
+	got, err := k8s.LoadKubeconfig(bot, "bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &k8s.ExecConfig{Command: filepath.Join(dir, "bot", "bin", "get-token"), Args: []string{"--audience", "k8s"}, Env: map[string]string{}}
+	if !reflect.DeepEqual(got.Exec, want) {
+		t.Errorf("bot exec = %+v, want %+v", got.Exec, want)
+	}
+}
+
+func TestLoadKubeconfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	kindPath := write("kind", kind)
+	tests := []struct {
+		name    string
+		path    string
+		context string
+		want    string
+	}{
+		{"no file", filepath.Join(dir, "missing"), "", "no kubeconfig found"},
+		{"unknown context", kindPath, "prod", `no context "prod"`},
+		{"no current context", write("nocurrent", strings.Replace(kind, "current-context: kind-kind\n", "", 1)), "", "no current context"},
+		{"unknown cluster", write("nocluster", strings.Replace(kind, "    cluster: kind-kind\n", "    cluster: other\n", 1)), "", "unknown cluster"},
+		{"auth provider", write("gcp", `current-context: old
+clusters:
+- name: old
+  cluster:
+    server: https://old.example.com
+contexts:
+- name: old
+  context:
+    cluster: old
+    user: old
+users:
+- name: old
+  user:
+    auth-provider:
+      name: gcp
+      config:
+        cmd-path: /usr/bin/gcloud
+`), "", "auth provider"},
+		{"bad certificate data", write("badca", strings.Replace(kind, b64("kind-ca"), "not base64!", 1)), "", "illegal base64"},
+		{"missing certificate file", write("nocert", strings.Replace(minikube, "certs/ca.crt", "nowhere/ca.crt", 1)), "", "no such file"},
+		{"bad indentation", write("indent", "clusters:\n  - name: a\n     cluster: {}\n"), "", "line 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := k8s.LoadKubeconfig(tt.path, tt.context)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadKubeconfig = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
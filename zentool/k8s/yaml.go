@@ -0,0 +1,363 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML found in kubeconfig files: block
+// mappings and sequences, plain and quoted scalars, possibly spanning
+// lines, literal and folded block scalars, and flow sequences and mappings
+// of scalars. Mappings decode to map[string]interface{}, sequences to
+// []interface{}, true and false to bool, null to nil and every other
+// scalar to a string.
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := strings.TrimRight(raw, " \t")
+		content := strings.TrimLeft(text, " ")
+		if content == "---" || content == "..." || strings.HasPrefix(content, "%") {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{no: i + 1, indent: len(text) - len(content), text: content, raw: raw})
+	}
+	p.skipBlank()
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	v, err := p.node(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected content")
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+	raw    string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	no := 0
+	if p.pos < len(p.lines) {
+		no = p.lines[p.pos].no
+	}
+	return fmt.Errorf("k8s: kubeconfig line %d: %s", no, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips empty and comment lines.
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && (p.lines[p.pos].text == "" || p.lines[p.pos].text[0] == '#') {
+		p.pos++
+	}
+}
+
+// node parses the block node starting at the current line, indented by
+// indent.
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	l := p.lines[p.pos]
+	if l.text == "-" || strings.HasPrefix(l.text, "- ") {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.mapping(indent)
+	}
+	p.pos++
+	return scalar(stripComment(l.text))
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	out := []interface{}{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent != indent || !(l.text == "-" || strings.HasPrefix(l.text, "- ")) {
+			if l.indent > indent {
+				return nil, p.errorf("bad indentation")
+			}
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" || rest[0] == '#' {
+			p.pos++
+			v, err := p.child(indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		// The item starts on the dash line: reparse that line as if the
+		// item text were indented on its own.
+		p.lines[p.pos] = yamlLine{no: l.no, indent: l.indent + len(l.text) - len(rest), text: rest, raw: l.raw}
+		v, err := p.node(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	out := map[string]interface{}{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent != indent {
+			if l.indent > indent {
+				return nil, p.errorf("bad indentation")
+			}
+			break
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf("expected a key")
+		}
+		rest = stripComment(rest)
+		p.pos++
+		var v interface{}
+		var err error
+		switch {
+		case rest == "":
+			v, err = p.child(indent)
+			if err == nil && v == nil && p.pos < len(p.lines) {
+				// A sequence may sit at the indentation of its key.
+				if next := p.lines[p.pos]; next.indent == indent && (next.text == "-" || strings.HasPrefix(next.text, "- ")) {
+					v, err = p.sequence(indent)
+				}
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			v = p.blockScalar(indent, rest)
+		default:
+			v, err = scalar(p.continuation(indent, rest))
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// child parses the node nested below a line indented by indent, or
+// returns nil when there is none.
+func (p *yamlParser) child(indent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos == len(p.lines) || p.lines[p.pos].indent <= indent {
+		return nil, nil
+	}
+	return p.node(p.lines[p.pos].indent)
+}
+
+// continuation returns the value s of a key indented by indent with the
+// lines continuing it, which are indented further, folded into it: long
+// plain scalars, such as the installHint kubectl writes for exec plugins,
+// and quoted scalars and flow collections span lines that way.
+func (p *yamlParser) continuation(indent int, s string) string {
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if l.text == "" || l.text[0] == '#' || l.indent <= indent {
+			break
+		}
+		if _, _, ok := splitKey(l.text); ok || l.text == "-" || strings.HasPrefix(l.text, "- ") {
+			// Not a continuation but a misplaced node.
+			break
+		}
+		s += " " + stripComment(l.text)
+	}
+	return s
+}
+
+// blockScalar reads the lines of a literal (|) or folded (>) scalar.
+func (p *yamlParser) blockScalar(indent int, header string) string {
+	var lines []string
+	inner := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if l.text != "" && l.indent <= indent {
+			break
+		}
+		if inner < 0 && l.text != "" {
+			inner = l.indent
+		}
+		if l.text == "" || inner < 0 {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, l.raw[min(inner, len(l.raw)):])
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	sep := "\n"
+	if header[0] == '>' {
+		sep = " "
+	}
+	s := strings.Join(lines, sep)
+	if !strings.HasSuffix(header, "-") {
+		s += "\n"
+	}
+	return s
+}
+
+// splitKey splits "key: value" or "key:".
+func splitKey(text string) (key, rest string, ok bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 || !strings.HasPrefix(text[end+1:], ":") {
+			return "", "", false
+		}
+		k, err := scalar(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		rest = text[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		s, _ := k.(string)
+		return s, strings.TrimSpace(rest), true
+	}
+	if text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return text[:i], strings.TrimSpace(text[i+1:]), true
+		}
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+	}
+	return "", "", false
+}
+
+// closingQuote returns the index of the quote closing the string text
+// starts with, or -1.
+func closingQuote(text string) int {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case text[i] == q && q == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a trailing comment from a value.
+func stripComment(s string) string {
+	if s == "" {
+		return s
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		if end := closingQuote(s); end >= 0 {
+			return s[:end+1]
+		}
+		return s
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// scalar decodes a scalar, quoted or plain, or a flow collection of
+// scalars.
+func scalar(s string) (interface{}, error) {
+	switch {
+	case s == "":
+		return nil, nil
+	case s[0] == '"':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: kubeconfig: bad string %s", s)
+		}
+		return v, nil
+	case s[0] == '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return nil, fmt.Errorf("k8s: kubeconfig: bad string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s[0] == '[' || s[0] == '{':
+		return flow(s)
+	}
+	switch s {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	return s, nil
+}
+
+// flow decodes a flow sequence or mapping of scalars.
+func flow(s string) (interface{}, error) {
+	open, close := s[0], byte(']')
+	if open == '{' {
+		close = '}'
+	}
+	if s[len(s)-1] != close {
+		return nil, fmt.Errorf("k8s: kubeconfig: unsupported flow collection %s", s)
+	}
+	body := strings.TrimSpace(s[1 : len(s)-1])
+	var items []string
+	for body != "" {
+		end := strings.IndexByte(body, ',')
+		if body[0] == '"' || body[0] == '\'' {
+			q := closingQuote(body)
+			if q < 0 {
+				return nil, fmt.Errorf("k8s: kubeconfig: bad string in %s", s)
+			}
+			end = strings.IndexByte(body[q:], ',')
+			if end >= 0 {
+				end += q
+			}
+		}
+		if end < 0 {
+			end = len(body)
+		}
+		items = append(items, strings.TrimSpace(body[:end]))
+		body = strings.TrimSpace(strings.TrimPrefix(body[end:], ","))
+	}
+	if open == '[' {
+		out := []interface{}{}
+		for _, item := range items {
+			v, err := scalar(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	out := map[string]interface{}{}
+	for _, item := range items {
+		key, rest, ok := splitKey(item)
+		if !ok {
+			return nil, fmt.Errorf("k8s: kubeconfig: bad flow mapping %s", s)
+		}
+		v, err := scalar(rest)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
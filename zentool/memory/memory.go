@@ -0,0 +1,354 @@
+// Package memory gives agents a scratchpad: tools to set, get, delete and
+// list small values in a key-value space of their own, kept in a
+// store.Store.
+//
+// Each caller sees a separate namespace, by default its authenticated
+// principal or, for anonymous callers, its session. Entries may expire,
+// and the size of values and the number of keys per namespace are
+// bounded.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+)
+
+// Names of the tools registered by Install.
+const (
+	SetToolName    = "memory.set"
+	GetToolName    = "memory.get"
+	DeleteToolName = "memory.delete"
+	ListToolName   = "memory.list"
+)
+
+// keyPrefix prefixes the store keys of all namespaces.
+const keyPrefix = "memory/"
+
+// ErrNotFound is returned for keys that are not set or have expired.
+var ErrNotFound = errors.New("memory: key not found")
+
+// Scope selects whose namespace a call reads and writes.
+type Scope int
+
+const (
+	// ScopeAuto uses the principal's namespace for authenticated callers
+	// and the session's otherwise.
+	ScopeAuto Scope = iota
+	// ScopeSession gives every session its own namespace, even when
+	// several sessions belong to one principal. Sessions of request-scoped
+	// transports such as plain HTTP last a single request.
+	ScopeSession
+	// ScopePrincipal shares one namespace between the sessions of a
+	// principal. Anonymous callers cannot use the tools.
+	ScopePrincipal
+)
+
+// Config configures the memory tools.
+type Config struct {
+	// Store holds the entries. Required.
+	Store store.Store
+	// Scope selects the namespace of each call. Namespaces are qualified
+	// by the tenant, if any.
+	Scope Scope
+	// DefaultTTL is the lifetime of entries set without a TTL. Zero keeps
+	// them until deleted.
+	DefaultTTL time.Duration
+	// MaxTTL caps the TTL callers may request. Zero leaves it uncapped.
+	MaxTTL time.Duration
+	// MaxValueBytes bounds the encoded size of a value. Defaults to 64 KiB.
+	MaxValueBytes int
+	// MaxKeys bounds the number of keys in a namespace. Defaults to 1000.
+	MaxKeys int
+	// Clock timestamps and expires entries. Defaults to the server's
+	// clock.
+	Clock clock.Clock
+}
+
+// Entry is a value in a namespace.
+type Entry struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+}
+
+// expired reports whether e has expired at now.
+func (e *Entry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// Memory serves the memory tools.
+type Memory struct {
+	cfg Config
+	// mu serializes the key count check with the write it guards.
+	mu sync.Mutex
+}
+
+// Install registers the memory tools on s.
+func Install(s *mcp.Server, cfg Config) (*Memory, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("memory: a store is required")
+	}
+	if cfg.MaxValueBytes <= 0 {
+		cfg.MaxValueBytes = 64 << 10
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = 1000
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = s.Clock()
+	}
+	m := &Memory{cfg: cfg}
+	tags := []string{"memory"}
+	yes := true
+	readOnly := &protocol.ToolAnnotations{ReadOnlyHint: &yes}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        SetToolName,
+		Description: "Remember a JSON value under a key, replacing any previous value. Use it to keep notes across turns.",
+		Tags:        tags,
+	}, m.setTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        GetToolName,
+		Description: "Recall the value remembered under a key.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, m.getTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        DeleteToolName,
+		Description: "Forget the value remembered under a key.",
+		Tags:        tags,
+	}, m.deleteTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ListToolName,
+		Description: "List the keys you have remembered values under, optionally only those starting with a prefix.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, m.listTool); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Namespace returns the namespace of the caller of ctx, or an error when
+// the scope admits no namespace for it.
+func (m *Memory) Namespace(ctx *runtime.Context) (string, error) {
+	var ns string
+	p := ctx.Principal()
+	s := ctx.Session()
+	switch {
+	case m.cfg.Scope != ScopeSession && p != nil && p.Subject != "":
+		ns = "principal:" + p.Subject
+	case m.cfg.Scope != ScopePrincipal && s != nil:
+		ns = "session:" + s.ID()
+	default:
+		return "", errors.New("memory: not available to anonymous callers")
+	}
+	if t := ctx.Tenant(); t != "" {
+		ns = "tenant:" + t + "/" + ns
+	}
+	return ns, nil
+}
+
+// Set stores value under key in namespace ns. A positive ttl makes the
+// entry expire.
+func (m *Memory) Set(ctx context.Context, ns, key string, value json.RawMessage, ttl time.Duration) (*Entry, error) {
+	if key == "" {
+		return nil, errors.New("memory: key is required")
+	}
+	if len(value) > m.cfg.MaxValueBytes {
+		return nil, fmt.Errorf("memory: value of %d bytes exceeds the limit of %d", len(value), m.cfg.MaxValueBytes)
+	}
+	now := m.cfg.Clock.Now()
+	e := &Entry{Key: key, Value: value, UpdatedAt: now}
+	if ttl > 0 {
+		at := now.Add(ttl)
+		e.ExpiresAt = &at
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.Get(ctx, ns, key); errors.Is(err, ErrNotFound) {
+		entries, err := m.List(ctx, ns, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) >= m.cfg.MaxKeys {
+			return nil, fmt.Errorf("memory: the limit of %d keys is reached; delete some first", m.cfg.MaxKeys)
+		}
+	}
+	if err := m.cfg.Store.Put(ctx, storeKey(ns, key), data); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Get returns the entry under key in namespace ns.
+func (m *Memory) Get(ctx context.Context, ns, key string) (*Entry, error) {
+	data, err := m.cfg.Store.Get(ctx, storeKey(ns, key))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("memory: decoding %s: %w", key, err)
+	}
+	if e.expired(m.cfg.Clock.Now()) {
+		m.cfg.Store.Delete(ctx, storeKey(ns, key))
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return &e, nil
+}
+
+// Delete removes key from namespace ns.
+func (m *Memory) Delete(ctx context.Context, ns, key string) error {
+	return m.cfg.Store.Delete(ctx, storeKey(ns, key))
+}
+
+// List returns the live entries of namespace ns whose keys start with
+// prefix, sorted by key. Expired entries are deleted on the way.
+func (m *Memory) List(ctx context.Context, ns, prefix string) ([]*Entry, error) {
+	keys, err := m.cfg.Store.List(ctx, nsPrefix(ns))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, k := range keys {
+		key, err := url.PathUnescape(strings.TrimPrefix(k, nsPrefix(ns)))
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		e, err := m.Get(ctx, ns, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	// Escaping can reorder keys; sort by the keys themselves.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+func nsPrefix(ns string) string {
+	return keyPrefix + url.PathEscape(ns) + "/"
+}
+
+func storeKey(ns, key string) string {
+	return nsPrefix(ns) + url.PathEscape(key)
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+type setArgs struct {
+	Key        string          `json:"key" description:"Key to remember the value under." validate:"nonzero"`
+	Value      json.RawMessage `json:"value" description:"Any JSON value."`
+	TTLSeconds int             `json:"ttlSeconds,omitempty" description:"Forget the value after this many seconds."`
+}
+
+func (m *Memory) setTool(ctx *runtime.Context, args setArgs) (*protocol.ToolCallResult, error) {
+	ns, err := m.Namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(args.Value) == 0 {
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: value is required", nil)
+	}
+	ttl := m.cfg.DefaultTTL
+	if args.TTLSeconds > 0 {
+		ttl = time.Duration(args.TTLSeconds) * time.Second
+	}
+	if m.cfg.MaxTTL > 0 && (ttl <= 0 || ttl > m.cfg.MaxTTL) {
+		ttl = m.cfg.MaxTTL
+	}
+	e, err := m.Set(ctx, ns, args.Key, args.Value, ttl)
+	if err != nil {
+		return nil, err
+	}
+	e.Value = nil
+	return jsonResult(e)
+}
+
+type keyArgs struct {
+	Key string `json:"key" description:"Key the value is remembered under." validate:"nonzero"`
+}
+
+func (m *Memory) getTool(ctx *runtime.Context, args keyArgs) (*protocol.ToolCallResult, error) {
+	ns, err := m.Namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e, err := m.Get(ctx, ns, args.Key)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(e)
+}
+
+func (m *Memory) deleteTool(ctx *runtime.Context, args keyArgs) (*protocol.ToolCallResult, error) {
+	ns, err := m.Namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Delete(ctx, ns, args.Key); err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{
+		protocol.TextContent(fmt.Sprintf("Forgot %s.", args.Key)),
+	}}, nil
+}
+
+type listArgs struct {
+	Prefix string `json:"prefix,omitempty" description:"Only list keys starting with this prefix."`
+}
+
+func (m *Memory) listTool(ctx *runtime.Context, args listArgs) (*protocol.ToolCallResult, error) {
+	ns, err := m.Namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := m.List(ctx, ns, args.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		summary := *e
+		summary.Value = nil
+		out = append(out, summary)
+	}
+	return jsonResult(map[string]interface{}{"entries": out})
+}
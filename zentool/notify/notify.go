@@ -0,0 +1,497 @@
+// Package notify gives agents tools to send notifications by email,
+// webhook or Slack, with every send held for human approval and audited.
+//
+// A send is a side effect on the world the model cannot take back, so
+// notify.send is annotated as destructive and, by default, only records
+// the message and returns its ID. As in package workflow, approval is
+// deliberately not a tool: the client application shows the message to
+// the user and calls the notify/approve method, or the operator calls
+// Notifier.Approve. A Config.Approver can instead decide synchronously,
+// for instance by asking the user through the host application.
+//
+// Every request, decision and delivery is reported to Config.Audit along
+// with the tenant and principal behind it, and the record of each send
+// is kept in a store.Store.
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/store"
+)
+
+// Names of the tools registered by Install.
+const (
+	SendToolName     = "notify.send"
+	StatusToolName   = "notify.status"
+	ChannelsToolName = "notify.channels"
+)
+
+// MethodApprove is the approval RPC registered on the router.
+const MethodApprove = "notify/approve"
+
+// ErrSendNotFound is returned for unknown send IDs.
+var ErrSendNotFound = errors.New("notify: send not found")
+
+// Message is a notification.
+type Message struct {
+	// To lists the recipients, for channels that address them.
+	To      []string `json:"to,omitempty"`
+	Subject string   `json:"subject,omitempty"`
+	Body    string   `json:"body"`
+}
+
+// Sender delivers messages over one channel. Implementations must be
+// safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// A Validator is a Sender that can reject a message before it is held for
+// approval, such as an email without recipients.
+type Validator interface {
+	Validate(m Message) error
+}
+
+// Channel is a named way to send notifications.
+type Channel struct {
+	Sender Sender
+	// Description tells the model what the channel reaches, such as "the
+	// on-call engineers' Slack channel".
+	Description string
+}
+
+// Status is the state of a send.
+type Status string
+
+// Send states.
+const (
+	StatusAwaitingApproval Status = "awaiting_approval"
+	StatusSent             Status = "sent"
+	StatusFailed           Status = "failed"
+	StatusRejected         Status = "rejected"
+	StatusExpired          Status = "expired"
+)
+
+// Decision records an approval or rejection.
+type Decision struct {
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment,omitempty"`
+	// By is the subject of the principal who decided, when known.
+	By string    `json:"by,omitempty"`
+	At time.Time `json:"at"`
+}
+
+// Send is the persisted record of one notification.
+type Send struct {
+	ID        string    `json:"id"`
+	Channel   string    `json:"channel"`
+	Message   Message   `json:"message"`
+	Status    Status    `json:"status"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	Decision  *Decision `json:"decision,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AuditEvent reports a step in the life of a send.
+type AuditEvent struct {
+	Time time.Time
+	// Action is "requested", "approved", "rejected", "sent", "failed" or
+	// "expired".
+	Action  string
+	SendID  string
+	Channel string
+	Tenant  string
+	// Principal is the subject of the principal who acted, when known.
+	Principal  string
+	Recipients []string
+	Subject    string
+	Detail     string
+}
+
+// Config configures the notification tools.
+type Config struct {
+	// Channels maps channel names to channels. At least one is required.
+	Channels map[string]Channel
+	// Store keeps the sends. Defaults to a new store.MemoryStore.
+	Store store.Store
+	// Approver, when set, decides on each send as it is requested instead
+	// of holding it for the notify/approve method.
+	Approver func(ctx *runtime.Context, s *Send) (approved bool, err error)
+	// AllowRecipient, when set, rejects recipients it returns false for
+	// before they reach approval.
+	AllowRecipient func(channel, recipient string) bool
+	// Audit receives every audit event. Defaults to logging them with the
+	// request logger.
+	Audit func(ctx context.Context, e AuditEvent)
+	// ApprovalTTL is how long a send waits for approval before it
+	// expires. Defaults to 24 hours.
+	ApprovalTTL time.Duration
+	// MaxBodyBytes bounds the size of a message body. Defaults to 16 KiB.
+	MaxBodyBytes int
+	// MaxRecipients bounds the number of recipients. Defaults to 10.
+	MaxRecipients int
+	// Clock timestamps sends and expires pending approvals. Defaults to
+	// the server's clock.
+	Clock clock.Clock
+}
+
+// Notifier serves the notification tools.
+type Notifier struct {
+	cfg Config
+	// mu serializes decisions so that a send is delivered at most once
+	// by this process.
+	mu sync.Mutex
+}
+
+// Install registers the notification tools and the notify/approve method
+// on s.
+func Install(s *mcp.Server, cfg Config) (*Notifier, error) {
+	if len(cfg.Channels) == 0 {
+		return nil, errors.New("notify: at least one channel is required")
+	}
+	for name, c := range cfg.Channels {
+		if c.Sender == nil {
+			return nil, fmt.Errorf("notify: channel %q has no sender", name)
+		}
+	}
+	if cfg.Store == nil {
+		cfg.Store = store.NewMemoryStore()
+	}
+	if cfg.Audit == nil {
+		cfg.Audit = logAudit
+	}
+	if cfg.ApprovalTTL <= 0 {
+		cfg.ApprovalTTL = 24 * time.Hour
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 16 << 10
+	}
+	if cfg.MaxRecipients <= 0 {
+		cfg.MaxRecipients = 10
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = s.Clock()
+	}
+	n := &Notifier{cfg: cfg}
+	tags := []string{"notify"}
+	yes, no := true, false
+	readOnly := &protocol.ToolAnnotations{ReadOnlyHint: &yes}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        SendToolName,
+		Description: "Send a notification over a configured channel. The message is held until a person approves it; the result says whether it was sent or is awaiting approval.",
+		Tags:        tags,
+		Annotations: &protocol.ToolAnnotations{ReadOnlyHint: &no, DestructiveHint: &yes, IdempotentHint: &no, OpenWorldHint: &yes},
+	}, n.sendTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        StatusToolName,
+		Description: "Report whether a notification was approved and sent.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, n.statusTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ChannelsToolName,
+		Description: "List the channels notifications can be sent over.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, n.channelsTool); err != nil {
+		return nil, err
+	}
+	if err := s.Router().Handle(MethodApprove, n.handleApprove); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Get returns the send with the given ID.
+func (n *Notifier) Get(ctx context.Context, id string) (*Send, error) {
+	data, err := n.cfg.Store.Get(ctx, sendKey(id))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrSendNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Send
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("notify: decode send %s: %w", id, err)
+	}
+	return &s, nil
+}
+
+// Approve records a decision on a send awaiting approval and, when
+// approved, delivers it. The returned send reflects the outcome.
+func (n *Notifier) Approve(ctx context.Context, id string, approved bool, comment string) (*Send, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, err := n.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != StatusAwaitingApproval {
+		return nil, fmt.Errorf("notify: send %s is %s, not awaiting approval", id, s.Status)
+	}
+	if clock.Since(n.cfg.Clock, s.CreatedAt) > n.cfg.ApprovalTTL {
+		s.Status = StatusExpired
+		n.audit(ctx, s, "expired", "")
+		if err := n.save(ctx, s); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("notify: send %s expired before it was approved", id)
+	}
+	return s, n.decide(ctx, s, approved, comment)
+}
+
+// decide records a decision on s, delivers it when approved and saves it.
+func (n *Notifier) decide(ctx context.Context, s *Send, approved bool, comment string) error {
+	d := &Decision{Approved: approved, Comment: comment, By: subject(ctx), At: n.cfg.Clock.Now()}
+	s.Decision = d
+	if !approved {
+		s.Status = StatusRejected
+		n.audit(ctx, s, "rejected", comment)
+		return n.save(ctx, s)
+	}
+	n.audit(ctx, s, "approved", comment)
+	// Save the decision first, so that a crash during delivery does not
+	// leave the send open to a second approval.
+	s.Status = StatusFailed
+	s.Error = "delivery did not complete"
+	if err := n.save(ctx, s); err != nil {
+		return err
+	}
+	if err := n.cfg.Channels[s.Channel].Sender.Send(ctx, s.Message); err != nil {
+		s.Error = err.Error()
+		n.audit(ctx, s, "failed", s.Error)
+	} else {
+		s.Status, s.Error = StatusSent, ""
+		n.audit(ctx, s, "sent", "")
+	}
+	return n.save(ctx, s)
+}
+
+func (n *Notifier) save(ctx context.Context, s *Send) error {
+	s.UpdatedAt = n.cfg.Clock.Now()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("notify: encode send %s: %w", s.ID, err)
+	}
+	return n.cfg.Store.Put(ctx, sendKey(s.ID), data)
+}
+
+func (n *Notifier) audit(ctx context.Context, s *Send, action, detail string) {
+	rctx := runtime.FromContext(ctx)
+	n.cfg.Audit(ctx, AuditEvent{
+		Time:       n.cfg.Clock.Now(),
+		Action:     action,
+		SendID:     s.ID,
+		Channel:    s.Channel,
+		Tenant:     s.Tenant,
+		Principal:  subject(rctx),
+		Recipients: s.Message.To,
+		Subject:    s.Message.Subject,
+		Detail:     detail,
+	})
+}
+
+// logAudit is the default audit sink.
+func logAudit(ctx context.Context, e AuditEvent) {
+	runtime.FromContext(ctx).Logger().LogAttrs(ctx, slog.LevelInfo, "notify audit",
+		slog.String("action", e.Action),
+		slog.String("send", e.SendID),
+		slog.String("channel", e.Channel),
+		slog.String("tenant", e.Tenant),
+		slog.String("principal", e.Principal),
+		slog.Any("to", e.Recipients),
+		slog.String("subject", e.Subject),
+		slog.String("detail", e.Detail),
+	)
+}
+
+// subject returns the subject of the principal behind ctx, if any.
+func subject(ctx context.Context) string {
+	if p := runtime.FromContext(ctx).Principal(); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+// visible reports whether the caller requested s.
+func visible(ctx *runtime.Context, s *Send) bool {
+	return s.Tenant == ctx.Tenant() && s.Owner == subject(ctx)
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+func invalidArgs(msg string) error {
+	return protocol.NewError(protocol.InvalidParams, "invalid arguments: "+msg, nil)
+}
+
+type sendArgs struct {
+	Channel string   `json:"channel" description:"Name of the channel, as listed by notify.channels." validate:"nonzero"`
+	To      []string `json:"to,omitempty" description:"Recipients, for channels that address them such as email."`
+	Subject string   `json:"subject,omitempty" description:"Subject line."`
+	Body    string   `json:"body" description:"Text of the notification." validate:"nonzero"`
+}
+
+func (n *Notifier) sendTool(ctx *runtime.Context, args sendArgs) (*protocol.ToolCallResult, error) {
+	ch, ok := n.cfg.Channels[args.Channel]
+	if !ok {
+		return nil, invalidArgs(fmt.Sprintf("unknown channel %q", args.Channel))
+	}
+	if len(args.Body) > n.cfg.MaxBodyBytes {
+		return nil, invalidArgs(fmt.Sprintf("body exceeds %d bytes", n.cfg.MaxBodyBytes))
+	}
+	if len(args.To) > n.cfg.MaxRecipients {
+		return nil, invalidArgs(fmt.Sprintf("more than %d recipients", n.cfg.MaxRecipients))
+	}
+	if n.cfg.AllowRecipient != nil {
+		for _, to := range args.To {
+			if !n.cfg.AllowRecipient(args.Channel, to) {
+				return nil, invalidArgs(fmt.Sprintf("recipient %s is not allowed on channel %s", to, args.Channel))
+			}
+		}
+	}
+	msg := Message{To: args.To, Subject: args.Subject, Body: args.Body}
+	if v, ok := ch.Sender.(Validator); ok {
+		if err := v.Validate(msg); err != nil {
+			return nil, invalidArgs(err.Error())
+		}
+	}
+	id, err := newSendID()
+	if err != nil {
+		return nil, err
+	}
+	now := n.cfg.Clock.Now()
+	s := &Send{
+		ID:        id,
+		Channel:   args.Channel,
+		Message:   msg,
+		Status:    StatusAwaitingApproval,
+		Tenant:    ctx.Tenant(),
+		Owner:     subject(ctx),
+		CreatedAt: now,
+	}
+	if err := n.save(ctx, s); err != nil {
+		return nil, err
+	}
+	n.audit(ctx, s, "requested", "")
+	if n.cfg.Approver == nil {
+		return jsonResult(map[string]interface{}{
+			"sendId":  s.ID,
+			"status":  s.Status,
+			"message": "The notification is waiting for a person to approve it. Check notify.status later; do not send it again.",
+		})
+	}
+	approved, err := n.cfg.Approver(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("notify: approval: %w", err)
+	}
+	// The send may meanwhile have been decided through notify/approve.
+	if s, err = n.Approve(ctx, s.ID, approved, ""); err != nil {
+		return nil, err
+	}
+	return sendResult(s)
+}
+
+// sendResult reports the outcome of a decided send.
+func sendResult(s *Send) (*protocol.ToolCallResult, error) {
+	res, err := jsonResult(struct {
+		SendID string `json:"sendId"`
+		Status Status `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}{s.ID, s.Status, s.Error})
+	if err != nil {
+		return nil, err
+	}
+	res.IsError = s.Status != StatusSent
+	return res, nil
+}
+
+type statusArgs struct {
+	SendID string `json:"sendId" description:"ID returned by notify.send." validate:"nonzero"`
+}
+
+func (n *Notifier) statusTool(ctx *runtime.Context, args statusArgs) (*protocol.ToolCallResult, error) {
+	s, err := n.Get(ctx, args.SendID)
+	if errors.Is(err, ErrSendNotFound) || (err == nil && !visible(ctx, s)) {
+		return nil, invalidArgs("unknown send " + args.SendID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.Status == StatusAwaitingApproval && clock.Since(n.cfg.Clock, s.CreatedAt) > n.cfg.ApprovalTTL {
+		s.Status = StatusExpired
+	}
+	return jsonResult(s)
+}
+
+func (n *Notifier) channelsTool(ctx *runtime.Context, _ struct{}) (*protocol.ToolCallResult, error) {
+	type channel struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}
+	out := make([]channel, 0, len(n.cfg.Channels))
+	for name, c := range n.cfg.Channels {
+		out = append(out, channel{Name: name, Description: c.Description})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return jsonResult(map[string]interface{}{"channels": out})
+}
+
+type approveParams struct {
+	SendID  string `json:"sendId"`
+	Approve bool   `json:"approve"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func (n *Notifier) handleApprove(ctx *runtime.Context, params json.RawMessage) (interface{}, error) {
+	var p approveParams
+	if err := json.Unmarshal(params, &p); err != nil || p.SendID == "" {
+		return nil, protocol.NewError(protocol.InvalidParams, "sendId is required", nil)
+	}
+	s, err := n.Approve(ctx, p.SendID, p.Approve, p.Comment)
+	if errors.Is(err, ErrSendNotFound) {
+		return nil, protocol.NewError(protocol.InvalidParams, err.Error(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func sendKey(id string) string { return "notify/sends/" + id }
+
+func newSendID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
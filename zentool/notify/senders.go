@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SMTP sends email through an SMTP server, upgrading the connection with
+// STARTTLS when the server offers it.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTP returns a sender delivering through the server at addr, such as
+// "smtp.example.com:587", from the address from. auth may be nil for
+// servers that relay without authentication.
+func NewSMTP(addr, from string, auth smtp.Auth) *SMTP {
+	return &SMTP{addr: addr, from: from, auth: auth}
+}
+
+// Validate implements Validator.
+func (s *SMTP) Validate(m Message) error {
+	if len(m.To) == 0 {
+		return errors.New("email needs at least one recipient")
+	}
+	for _, to := range m.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("bad recipient %q", to)
+		}
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return errors.New("subject must be a single line")
+	}
+	return nil
+}
+
+// Send implements Sender. net/smtp has no context support, so ctx is
+// only checked before the message is sent.
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	if err := s.Validate(m); err != nil {
+		return fmt.Errorf("notify: smtp: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var to, header []string
+	for _, addr := range m.To {
+		a, _ := mail.ParseAddress(addr)
+		to, header = append(to, a.Address), append(header, a.String())
+	}
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("notify: smtp: bad sender %q", s.from)
+	}
+	var id [12]byte
+	rand.Read(id[:])
+	domain := from.Address[strings.LastIndexByte(from.Address, '@')+1:]
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(header, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id[:]), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	if err := smtp.SendMail(s.addr, s.auth, from.Address, to, b.Bytes()); err != nil {
+		return fmt.Errorf("notify: smtp: %w", err)
+	}
+	return nil
+}
+
+// Webhook posts messages as JSON objects with to, subject and body
+// fields to a URL.
+type Webhook struct {
+	url    string
+	header http.Header
+	client *http.Client
+}
+
+// NewWebhook returns a sender posting to target with the extra request
+// headers header, which may be nil. client defaults to http.DefaultClient.
+func NewWebhook(target string, header http.Header, client *http.Client) *Webhook {
+	return &Webhook{url: target, header: header, client: orDefault(client)}
+}
+
+// Send implements Sender.
+func (w *Webhook) Send(ctx context.Context, m Message) error {
+	return post(ctx, w.client, w.url, w.header, m, "webhook")
+}
+
+// Slack posts messages to a Slack channel through an incoming webhook.
+// The channel is fixed by the webhook, so recipients are ignored.
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack returns a sender posting to the Slack incoming webhook URL.
+// client defaults to http.DefaultClient.
+func NewSlack(webhookURL string, client *http.Client) *Slack {
+	return &Slack{url: webhookURL, client: orDefault(client)}
+}
+
+// Send implements Sender.
+func (s *Slack) Send(ctx context.Context, m Message) error {
+	text := m.Body
+	if m.Subject != "" {
+		text = "*" + m.Subject + "*\n" + text
+	}
+	return post(ctx, s.client, s.url, nil, map[string]string{"text": text}, "slack")
+}
+
+func orDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
+
+// post sends body as JSON to target.
+func post(ctx context.Context, client *http.Client, target string, header http.Header, body interface{}, kind string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("notify: %s: bad URL", kind)
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL is left out: webhook URLs are credentials.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("notify: %s: %w", kind, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notify: %s: %d %s: %s", kind, resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
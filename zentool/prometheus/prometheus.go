@@ -0,0 +1,558 @@
+// Package prometheus gives agents PromQL query tools against a
+// Prometheus-compatible HTTP API: Prometheus itself, Thanos, Mimir,
+// VictoriaMetrics, or a Prometheus data source proxied by Grafana.
+//
+// Queries are guarded: ranges are bounded in length and resolution, and
+// results in series and samples. Every result carries a short text
+// summary for the model, followed by the series as JSON.
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// Names of the tools registered by Install.
+const (
+	QueryToolName      = "prometheus.query"
+	QueryRangeToolName = "prometheus.query_range"
+)
+
+// Config configures the query tools.
+type Config struct {
+	// URL is the base URL of the API, such as "http://prometheus:9090".
+	// For a Grafana data source, use its proxy URL, such as
+	// "https://grafana/api/datasources/proxy/uid/<uid>". Required.
+	URL string
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request, for instance to authenticate
+	// with an Authorization header.
+	Header http.Header
+	// Timeout bounds the evaluation of a query. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxRange bounds the length of range queries. Defaults to 7 days.
+	MaxRange time.Duration
+	// MaxPoints bounds the number of points per series of a range query;
+	// the step is raised to stay below it when none is given. Defaults to
+	// 500.
+	MaxPoints int
+	// MaxSeries caps the number of series returned. Defaults to 50.
+	MaxSeries int
+	// MaxSamples caps the total number of samples returned. Defaults to
+	// 10000.
+	MaxSamples int
+}
+
+// Prometheus serves the query tools.
+type Prometheus struct {
+	cfg   Config
+	clock clock.Clock
+}
+
+// Install registers the query tools on s.
+func Install(s *mcp.Server, cfg Config) (*Prometheus, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("prometheus: a URL is required")
+	}
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("prometheus: %w", err)
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxRange <= 0 {
+		cfg.MaxRange = 7 * 24 * time.Hour
+	}
+	if cfg.MaxPoints <= 0 {
+		cfg.MaxPoints = 500
+	}
+	if cfg.MaxSeries <= 0 {
+		cfg.MaxSeries = 50
+	}
+	if cfg.MaxSamples <= 0 {
+		cfg.MaxSamples = 10000
+	}
+	p := &Prometheus{cfg: cfg, clock: s.Clock()}
+	tags := []string{"observability"}
+	yes := true
+	readOnly := &protocol.ToolAnnotations{ReadOnlyHint: &yes}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        QueryToolName,
+		Description: "Evaluate a PromQL expression at a single instant, by default now.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, p.queryTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        QueryRangeToolName,
+		Description: "Evaluate a PromQL expression over a time range, returning each series' values with min, max, average and last value.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, p.queryRangeTool); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Sample is a value at a point in time. Values are kept as Prometheus
+// formats them, so that NaN and infinities survive JSON.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value string    `json:"value"`
+}
+
+// UnmarshalJSON decodes the [timestamp, "value"] pairs of the API.
+func (s *Sample) UnmarshalJSON(data []byte) error {
+	var pair [2]json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	var ts float64
+	if err := json.Unmarshal(pair[0], &ts); err != nil {
+		return err
+	}
+	sec, frac := math.Modf(ts)
+	s.Time = time.Unix(int64(sec), int64(math.Round(frac*1e3))*int64(time.Millisecond)).UTC()
+	return json.Unmarshal(pair[1], &s.Value)
+}
+
+func (s Sample) float() float64 {
+	f, err := strconv.ParseFloat(s.Value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+// Series is a labelled series with one value, for instant queries, or
+// several, for range queries.
+type Series struct {
+	Metric map[string]string `json:"metric"`
+	Value  *Sample           `json:"value,omitempty"`
+	Values []Sample          `json:"values,omitempty"`
+}
+
+// Result is the outcome of a query.
+type Result struct {
+	// Type is "vector", "matrix", "scalar" or "string".
+	Type string `json:"resultType"`
+	// Series holds vectors and matrices.
+	Series []Series `json:"series,omitempty"`
+	// Scalar holds scalars and strings.
+	Scalar *Sample `json:"scalar,omitempty"`
+	// TotalSeries is the number of series before truncation.
+	TotalSeries int      `json:"totalSeries,omitempty"`
+	Truncated   bool     `json:"truncated,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// Query evaluates expr at instant t.
+func (p *Prometheus) Query(ctx context.Context, expr string, t time.Time) (*Result, error) {
+	form := url.Values{"query": {expr}, "time": {formatTime(t)}}
+	return p.do(ctx, "/api/v1/query", form)
+}
+
+// QueryRange evaluates expr from start to end every step.
+func (p *Prometheus) QueryRange(ctx context.Context, expr string, start, end time.Time, step time.Duration) (*Result, error) {
+	if err := p.checkRange(start, end, step); err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"query": {expr},
+		"start": {formatTime(start)},
+		"end":   {formatTime(end)},
+		"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	}
+	return p.do(ctx, "/api/v1/query_range", form)
+}
+
+// checkRange enforces the range and resolution limits.
+func (p *Prometheus) checkRange(start, end time.Time, step time.Duration) error {
+	if !end.After(start) {
+		return errors.New("prometheus: the end of the range must be after its start")
+	}
+	if end.Sub(start) > p.cfg.MaxRange {
+		return fmt.Errorf("prometheus: range of %s exceeds the limit of %s", end.Sub(start), p.cfg.MaxRange)
+	}
+	if points := int(end.Sub(start)/step) + 1; points > p.cfg.MaxPoints {
+		return fmt.Errorf("prometheus: a step of %s gives %d points per series, over the limit of %d; use a step of at least %s",
+			step, points, p.cfg.MaxPoints, minStep(end.Sub(start), p.cfg.MaxPoints))
+	}
+	return nil
+}
+
+// minStep returns the smallest whole-second step giving at most points
+// points over a range.
+func minStep(r time.Duration, points int) time.Duration {
+	if points <= 1 {
+		return r
+	}
+	step := time.Duration(math.Ceil(r.Seconds()/float64(points-1))) * time.Second
+	return max(step, time.Second)
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
+
+// apiError is an error reported by the API.
+type apiError struct {
+	typ, msg string
+}
+
+func (e *apiError) Error() string { return "prometheus: " + e.typ + ": " + e.msg }
+
+func (p *Prometheus) do(ctx context.Context, path string, form url.Values) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout+5*time.Second)
+	defer cancel()
+	form.Set("timeout", p.cfg.Timeout.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range p.cfg.Header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status    string   `json:"status"`
+		ErrorType string   `json:"errorType"`
+		Error     string   `json:"error"`
+		Warnings  []string `json:"warnings"`
+		Data      struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus: %w", err)
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		if len(data) > 200 {
+			data = data[:200]
+		}
+		return nil, fmt.Errorf("prometheus: %s: unexpected response %q", resp.Status, data)
+	}
+	if body.Status != "success" {
+		return nil, &apiError{typ: body.ErrorType, msg: body.Error}
+	}
+	res := &Result{Type: body.Data.ResultType, Warnings: body.Warnings}
+	switch res.Type {
+	case "vector", "matrix":
+		if err := json.Unmarshal(body.Data.Result, &res.Series); err != nil {
+			return nil, fmt.Errorf("prometheus: decoding result: %w", err)
+		}
+		p.limit(res)
+	case "scalar", "string":
+		res.Scalar = new(Sample)
+		if err := json.Unmarshal(body.Data.Result, res.Scalar); err != nil {
+			return nil, fmt.Errorf("prometheus: decoding result: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("prometheus: unknown result type %q", res.Type)
+	}
+	return res, nil
+}
+
+// limit sorts the series of res by metric and drops those past the
+// series and sample limits.
+func (p *Prometheus) limit(res *Result) {
+	sort.Slice(res.Series, func(i, j int) bool {
+		return metricName(res.Series[i].Metric) < metricName(res.Series[j].Metric)
+	})
+	res.TotalSeries = len(res.Series)
+	samples := 0
+	for i, s := range res.Series {
+		n := len(s.Values)
+		if s.Value != nil {
+			n++
+		}
+		if i == p.cfg.MaxSeries || samples+n > p.cfg.MaxSamples {
+			res.Series, res.Truncated = res.Series[:i], true
+			return
+		}
+		samples += n
+	}
+}
+
+// metricName formats labels the way PromQL writes them:
+// name{label="value",...}.
+func metricName(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(labels["__name__"])
+	if len(keys) > 0 || b.Len() == 0 {
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s=%q", k, labels[k])
+		}
+		b.WriteByte('}')
+	}
+	return b.String()
+}
+
+// Summary describes res in a few lines of text.
+func (res *Result) Summary() string { return res.SummaryFor("") }
+
+// SummaryFor is like Summary, with numbers and times written the way
+// people speaking locale expect, as runtime.FormatNumber and
+// runtime.FormatTime do.
+func (res *Result) SummaryFor(locale string) string {
+	var b strings.Builder
+	switch res.Type {
+	case "scalar", "string":
+		value := res.Scalar.Value
+		if res.Type == "scalar" {
+			value = formatValue(locale, value)
+		}
+		fmt.Fprintf(&b, "%s %s at %s\n", res.Type, value, runtime.FormatTime(locale, res.Scalar.Time))
+	case "vector":
+		fmt.Fprintf(&b, "%s series\n", formatCount(locale, res.TotalSeries))
+		for _, s := range res.Series {
+			fmt.Fprintf(&b, "%s %s\n", metricName(s.Metric), formatValue(locale, s.Value.Value))
+		}
+	case "matrix":
+		fmt.Fprintf(&b, "%s series\n", formatCount(locale, res.TotalSeries))
+		for _, s := range res.Series {
+			if len(s.Values) == 0 {
+				continue
+			}
+			lo, hi, sum, n := math.Inf(1), math.Inf(-1), 0.0, 0
+			for _, v := range s.Values {
+				f := v.float()
+				if math.IsNaN(f) {
+					continue
+				}
+				lo, hi, sum, n = math.Min(lo, f), math.Max(hi, f), sum+f, n+1
+			}
+			first, last := s.Values[0], s.Values[len(s.Values)-1]
+			fmt.Fprintf(&b, "%s: %s points from %s to %s", metricName(s.Metric), formatCount(locale, len(s.Values)),
+				runtime.FormatTime(locale, first.Time), runtime.FormatTime(locale, last.Time))
+			if n > 0 {
+				fmt.Fprintf(&b, ", min %s, max %s, avg %s", formatFloat(locale, lo), formatFloat(locale, hi), formatFloat(locale, sum/float64(n)))
+			}
+			fmt.Fprintf(&b, ", last %s\n", formatValue(locale, last.Value))
+		}
+	}
+	if res.Truncated {
+		fmt.Fprintf(&b, "(showing %s of %s series; narrow the query to see the rest)\n",
+			formatCount(locale, len(res.Series)), formatCount(locale, res.TotalSeries))
+	}
+	for _, w := range res.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatFloat rounds f to six significant digits. Without a locale it
+// keeps the exponent form strconv uses for very large and small values.
+func formatFloat(locale string, f float64) string {
+	s := strconv.FormatFloat(f, 'g', 6, 64)
+	if locale == "" {
+		return s
+	}
+	rounded, _ := strconv.ParseFloat(s, 64)
+	return runtime.FormatNumber(locale, rounded, -1)
+}
+
+// formatValue formats a sample value as Prometheus sent it. Without a
+// locale the value is left as sent.
+func formatValue(locale, v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if locale == "" || err != nil {
+		return v
+	}
+	return runtime.FormatNumber(locale, f, -1)
+}
+
+func formatCount(locale string, n int) string {
+	return runtime.FormatNumber(locale, float64(n), 0)
+}
+
+// result renders res as a summary, for the client's locale, followed by
+// its JSON.
+func result(ctx *runtime.Context, res *Result) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{
+		protocol.TextContent(res.SummaryFor(ctx.Locale())),
+		protocol.TextContent(string(data)),
+	}}, nil
+}
+
+// ParseDuration parses a Prometheus duration such as "90s", "5m", "1h30m",
+// "2d" or "1w", or a number of seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		if secs < 0 || secs > math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("prometheus: duration %q out of range", s)
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"ms", time.Millisecond}, {"s", time.Second}, {"m", time.Minute}, {"h", time.Hour},
+		{"d", 24 * time.Hour}, {"w", 7 * 24 * time.Hour}, {"y", 365 * 24 * time.Hour},
+	}
+	if s == "" {
+		return 0, errors.New("prometheus: empty duration")
+	}
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("prometheus: bad duration %q", s)
+		}
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("prometheus: bad duration %q", s)
+		}
+		rest = rest[i:]
+		matched := false
+		for _, u := range units {
+			// "ms" is listed before "m", so it is tried first.
+			if strings.HasPrefix(rest, u.suffix) {
+				total += time.Duration(n) * u.unit
+				rest = rest[len(u.suffix):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, fmt.Errorf("prometheus: bad duration %q", s)
+		}
+	}
+	return total, nil
+}
+
+// parseTime parses an RFC 3339 time, a Unix timestamp, "now", or a time
+// relative to now such as "now-1h".
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" || s == "now" {
+		return now, nil
+	}
+	if rel, ok := strings.CutPrefix(s, "now-"); ok {
+		d, err := ParseDuration(rel)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(secs)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("prometheus: bad time %q; use RFC 3339, a Unix timestamp or now-<duration>", s)
+}
+
+func invalidArgs(err error) error {
+	return protocol.NewError(protocol.InvalidParams, "invalid arguments: "+strings.TrimPrefix(err.Error(), "prometheus: "), nil)
+}
+
+// toolError reports queries Prometheus rejects as bad arguments, so that
+// the model corrects its PromQL rather than retrying.
+func toolError(err error) error {
+	var ae *apiError
+	if errors.As(err, &ae) && ae.typ == "bad_data" {
+		return invalidArgs(err)
+	}
+	return err
+}
+
+type queryArgs struct {
+	Query string `json:"query" description:"PromQL expression." validate:"nonzero"`
+	Time  string `json:"time,omitempty" description:"Instant to evaluate at: RFC 3339, Unix seconds, or relative such as now-1h." default:"now"`
+}
+
+func (p *Prometheus) queryTool(ctx *runtime.Context, args queryArgs) (*protocol.ToolCallResult, error) {
+	t, err := parseTime(args.Time, p.clock.Now())
+	if err != nil {
+		return nil, invalidArgs(err)
+	}
+	res, err := p.Query(ctx, args.Query, t)
+	if err != nil {
+		return nil, toolError(err)
+	}
+	return result(ctx, res)
+}
+
+type queryRangeArgs struct {
+	Query string `json:"query" description:"PromQL expression." validate:"nonzero"`
+	Start string `json:"start" description:"Start of the range: RFC 3339, Unix seconds, or relative such as now-6h." validate:"nonzero"`
+	End   string `json:"end,omitempty" description:"End of the range, in the same forms." default:"now"`
+	Step  string `json:"step,omitempty" description:"Resolution, such as 30s or 5m. Chosen from the range when omitted."`
+}
+
+func (p *Prometheus) queryRangeTool(ctx *runtime.Context, args queryRangeArgs) (*protocol.ToolCallResult, error) {
+	now := p.clock.Now()
+	start, err := parseTime(args.Start, now)
+	if err != nil {
+		return nil, invalidArgs(err)
+	}
+	end, err := parseTime(args.End, now)
+	if err != nil {
+		return nil, invalidArgs(err)
+	}
+	step := minStep(end.Sub(start), min(p.cfg.MaxPoints, 250))
+	if args.Step != "" {
+		if step, err = ParseDuration(args.Step); err != nil || step <= 0 {
+			return nil, protocol.NewError(protocol.InvalidParams, fmt.Sprintf("invalid arguments: bad step %q", args.Step), nil)
+		}
+	}
+	if err := p.checkRange(start, end, step); err != nil {
+		return nil, invalidArgs(err)
+	}
+	res, err := p.QueryRange(ctx, args.Query, start, end, step)
+	if err != nil {
+		return nil, toolError(err)
+	}
+	return result(ctx, res)
+}
@@ -0,0 +1,238 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Brave searches with the Brave Search API.
+type Brave struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewBrave returns a provider using the Brave Search API subscription
+// token apiKey. client defaults to http.DefaultClient.
+func NewBrave(apiKey string, client *http.Client) *Brave {
+	return &Brave{apiKey: apiKey, client: orDefault(client)}
+}
+
+// Search implements SearchProvider.
+func (b *Brave) Search(ctx context.Context, q Query) ([]Result, error) {
+	params := url.Values{"q": {q.Text}, "count": {strconv.Itoa(min(q.Count, 20))}}
+	if q.Language != "" {
+		params.Set("search_lang", q.Language)
+	}
+	if f := map[string]string{"day": "pd", "week": "pw", "month": "pm", "year": "py"}[q.Freshness]; f != "" {
+		params.Set("freshness", f)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.search.brave.com/res/v1/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				PageAge     string `json:"page_age"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := do(b.client, req, "brave", &resp); err != nil {
+		return nil, err
+	}
+	out := make([]Result, 0, len(resp.Web.Results))
+	for _, r := range resp.Web.Results {
+		out = append(out, Result{Title: r.Title, URL: r.URL, Snippet: r.Description, Date: r.PageAge})
+	}
+	return out, nil
+}
+
+// SearXNG searches with a SearXNG metasearch instance. The instance must
+// have the JSON output format enabled.
+type SearXNG struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewSearXNG returns a provider using the SearXNG instance at baseURL,
+// such as "http://localhost:8080". client defaults to http.DefaultClient.
+func NewSearXNG(baseURL string, client *http.Client) *SearXNG {
+	return &SearXNG{baseURL: strings.TrimSuffix(baseURL, "/"), client: orDefault(client)}
+}
+
+// Search implements SearchProvider.
+func (s *SearXNG) Search(ctx context.Context, q Query) ([]Result, error) {
+	params := url.Values{"q": {q.Text}, "format": {"json"}}
+	if q.Language != "" {
+		params.Set("language", q.Language)
+	}
+	if q.Freshness != "" {
+		params.Set("time_range", q.Freshness)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"publishedDate"`
+		} `json:"results"`
+	}
+	if err := do(s.client, req, "searxng", &resp); err != nil {
+		return nil, err
+	}
+	// SearXNG has no result count parameter; a page holds some twenty.
+	out := make([]Result, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		out = append(out, Result{Title: r.Title, URL: r.URL, Snippet: r.Content, Date: r.PublishedDate})
+	}
+	return out, nil
+}
+
+// Google searches with the Google Programmable Search Engine JSON API.
+type Google struct {
+	apiKey   string
+	engineID string
+	client   *http.Client
+}
+
+// NewGoogle returns a provider using the API key apiKey and the search
+// engine engineID (the "cx" parameter). client defaults to
+// http.DefaultClient.
+func NewGoogle(apiKey, engineID string, client *http.Client) *Google {
+	return &Google{apiKey: apiKey, engineID: engineID, client: orDefault(client)}
+}
+
+// Search implements SearchProvider.
+func (g *Google) Search(ctx context.Context, q Query) ([]Result, error) {
+	params := url.Values{
+		"key": {g.apiKey},
+		"cx":  {g.engineID},
+		"q":   {q.Text},
+		"num": {strconv.Itoa(min(q.Count, 10))},
+	}
+	if q.Language != "" {
+		params.Set("lr", "lang_"+q.Language)
+	}
+	if f := map[string]string{"day": "d1", "week": "w1", "month": "m1", "year": "y1"}[q.Freshness]; f != "" {
+		params.Set("dateRestrict", f)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.googleapis.com/customsearch/v1?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Items []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := do(g.client, req, "google", &resp); err != nil {
+		return nil, err
+	}
+	out := make([]Result, 0, len(resp.Items))
+	for _, r := range resp.Items {
+		out = append(out, Result{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return out, nil
+}
+
+// Tavily searches with the Tavily search API.
+type Tavily struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewTavily returns a provider using the Tavily API key apiKey. client
+// defaults to http.DefaultClient.
+func NewTavily(apiKey string, client *http.Client) *Tavily {
+	return &Tavily{apiKey: apiKey, client: orDefault(client)}
+}
+
+// Search implements SearchProvider. Tavily has no language filter, so
+// q.Language is ignored.
+func (t *Tavily) Search(ctx context.Context, q Query) ([]Result, error) {
+	body := map[string]interface{}{"query": q.Text, "max_results": min(q.Count, 20)}
+	if q.Freshness != "" {
+		body["time_range"] = q.Freshness
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.tavily.com/search", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	var resp struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"published_date"`
+		} `json:"results"`
+	}
+	if err := do(t.client, req, "tavily", &resp); err != nil {
+		return nil, err
+	}
+	out := make([]Result, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		out = append(out, Result{Title: r.Title, URL: r.URL, Snippet: r.Content, Date: r.PublishedDate})
+	}
+	return out, nil
+}
+
+func orDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
+
+// ErrRateLimited is returned when a provider refuses a search because
+// too many were made.
+var ErrRateLimited = errors.New("search: rate limited")
+
+// do sends req and decodes the JSON response into out.
+func do(client *http.Client, req *http.Request, provider string, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// Drop the URL, which may carry the API key.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("search: %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w by %s", ErrRateLimited, provider)
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("search: %s: %d %s: %s", provider, resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("search: %s: decoding response: %w", provider, err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package search gives agents web search through a single web.search
+// tool, whatever the search API behind it.
+//
+// The API is a SearchProvider: Brave, SearXNG, Google Programmable Search
+// and Tavily are provided, and other services plug in by implementing the
+// interface. Results come back in the same shape from every provider,
+// with snippets reduced to plain text.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+)
+
+// ToolName is the name of the tool registered by Install.
+const ToolName = "web.search"
+
+// Query is a search request.
+type Query struct {
+	Text string
+	// Count is the number of results wanted. Providers may return fewer.
+	Count int
+	// Language, when set, is an ISO 639-1 code such as "en" restricting
+	// results to pages in that language.
+	Language string
+	// Freshness, when set, is one of "day", "week", "month" or "year",
+	// restricting results to pages published within that period.
+	Freshness string
+}
+
+// Result is a page found by a search.
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+	// Date is the publication date as YYYY-MM-DD, when the provider knows
+	// it.
+	Date string `json:"date,omitempty"`
+}
+
+// SearchProvider searches the web. Implementations must be safe for
+// concurrent use.
+type SearchProvider interface {
+	Search(ctx context.Context, q Query) ([]Result, error)
+}
+
+// Config configures the search tool.
+type Config struct {
+	// Provider runs the searches. Required.
+	Provider SearchProvider
+	// MaxResults caps the number of results of a search. Defaults to 10.
+	MaxResults int
+	// Timeout bounds a search. Defaults to 15 seconds.
+	Timeout time.Duration
+}
+
+// Search serves the search tool.
+type Search struct {
+	cfg Config
+}
+
+// Install registers the search tool on s.
+func Install(s *mcp.Server, cfg Config) (*Search, error) {
+	if cfg.Provider == nil {
+		return nil, errors.New("search: a provider is required")
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+	x := &Search{cfg: cfg}
+	yes := true
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        ToolName,
+		Description: "Search the web. Returns the title, URL and a text snippet of each page found, best match first.",
+		Tags:        []string{"web"},
+		Annotations: &protocol.ToolAnnotations{ReadOnlyHint: &yes, OpenWorldHint: &yes},
+	}, x.searchTool); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Search runs q on the provider and cleans up its results: snippets and
+// titles become plain text, duplicate URLs are dropped and at most
+// MaxResults are kept.
+func (x *Search) Search(ctx context.Context, q Query) ([]Result, error) {
+	if q.Count <= 0 || q.Count > x.cfg.MaxResults {
+		q.Count = x.cfg.MaxResults
+	}
+	ctx, cancel := context.WithTimeout(ctx, x.cfg.Timeout)
+	defer cancel()
+	results, err := x.cfg.Provider.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Result, 0, len(results))
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if r.URL == "" || seen[r.URL] {
+			continue
+		}
+		seen[r.URL] = true
+		r.Title, r.Snippet = plainText(r.Title), plainText(r.Snippet)
+		r.Date = date(r.Date)
+		out = append(out, r)
+		if len(out) == q.Count {
+			break
+		}
+	}
+	return out, nil
+}
+
+var (
+	tagRE   = regexp.MustCompile(`<[^>]*>`)
+	spaceRE = regexp.MustCompile(`\s+`)
+)
+
+// plainText strips the markup some providers use to highlight matches.
+func plainText(s string) string {
+	s = html.UnescapeString(tagRE.ReplaceAllString(s, ""))
+	return strings.TrimSpace(spaceRE.ReplaceAllString(s, " "))
+}
+
+// date reduces a timestamp to its date, or drops it when it is not one.
+func date(s string) string {
+	if len(s) < 10 {
+		return ""
+	}
+	if _, err := time.Parse("2006-01-02", s[:10]); err != nil {
+		return ""
+	}
+	return s[:10]
+}
+
+type searchArgs struct {
+	Query     string `json:"query" description:"Search terms." validate:"nonzero"`
+	Count     int    `json:"count,omitempty" description:"Number of results wanted. Defaults to the most allowed."`
+	Language  string `json:"language,omitempty" description:"ISO 639-1 code of the language of the pages, such as en."`
+	Freshness string `json:"freshness,omitempty" description:"Only pages published within this period: day, week, month or year."`
+}
+
+func (x *Search) searchTool(ctx *runtime.Context, args searchArgs) (*protocol.ToolCallResult, error) {
+	switch args.Freshness {
+	case "", "day", "week", "month", "year":
+	default:
+		return nil, protocol.NewError(protocol.InvalidParams, "invalid arguments: freshness must be day, week, month or year", nil)
+	}
+	results, err := x.Search(ctx, Query{
+		Text:      args.Query,
+		Count:     args.Count,
+		Language:  strings.ToLower(args.Language),
+		Freshness: args.Freshness,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(map[string]interface{}{"query": args.Query, "results": results})
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
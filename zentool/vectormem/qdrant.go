@@ -0,0 +1,207 @@
+package vectormem
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Qdrant is a VectorStore backed by a Qdrant server, accessed through its
+// REST API. Collections are created on first use with cosine distance and
+// the dimension of the first vector stored.
+//
+// Qdrant only accepts integers and UUIDs as point IDs, so document IDs
+// are mapped to name-based UUIDs and kept in the point payload alongside
+// the text, creation time and metadata.
+type Qdrant struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+// NewQdrant returns a store talking to the Qdrant server at baseURL, such
+// as "http://localhost:6333". apiKey may be empty for servers without
+// authentication; client defaults to http.DefaultClient.
+func NewQdrant(baseURL, apiKey string, client *http.Client) *Qdrant {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Qdrant{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  client,
+		created: make(map[string]bool),
+	}
+}
+
+// qdrantPayload is the payload stored with every point.
+type qdrantPayload struct {
+	ID        string            `json:"id"`
+	Text      string            `json:"text"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// Upsert implements VectorStore.
+func (q *Qdrant) Upsert(ctx context.Context, collection string, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := q.ensureCollection(ctx, collection, len(docs[0].Vector)); err != nil {
+		return err
+	}
+	type point struct {
+		ID      string        `json:"id"`
+		Vector  []float32     `json:"vector"`
+		Payload qdrantPayload `json:"payload"`
+	}
+	points := make([]point, 0, len(docs))
+	for _, d := range docs {
+		points = append(points, point{
+			ID:      pointID(d.ID),
+			Vector:  d.Vector,
+			Payload: qdrantPayload{ID: d.ID, Text: d.Text, CreatedAt: d.CreatedAt, Metadata: d.Metadata},
+		})
+	}
+	return q.do(ctx, http.MethodPut, collectionPath(collection)+"/points?wait=true", map[string]interface{}{"points": points}, nil)
+}
+
+// Search implements VectorStore.
+func (q *Qdrant) Search(ctx context.Context, collection string, vector []float32, k int, filter map[string]string) ([]Hit, error) {
+	req := map[string]interface{}{"vector": vector, "limit": k, "with_payload": true}
+	if len(filter) > 0 {
+		var must []interface{}
+		for key, v := range filter {
+			must = append(must, map[string]interface{}{"key": "metadata." + key, "match": map[string]string{"value": v}})
+		}
+		req["filter"] = map[string]interface{}{"must": must}
+	}
+	var resp struct {
+		Result []struct {
+			Score   float64       `json:"score"`
+			Payload qdrantPayload `json:"payload"`
+		} `json:"result"`
+	}
+	err := q.do(ctx, http.MethodPost, collectionPath(collection)+"/points/search", req, &resp)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	hits := make([]Hit, 0, len(resp.Result))
+	for _, r := range resp.Result {
+		hits = append(hits, Hit{
+			Document: Document{ID: r.Payload.ID, Text: r.Payload.Text, CreatedAt: r.Payload.CreatedAt, Metadata: r.Payload.Metadata},
+			Score:    r.Score,
+		})
+	}
+	return hits, nil
+}
+
+// Delete implements VectorStore.
+func (q *Qdrant) Delete(ctx context.Context, collection string, ids []string) error {
+	points := make([]string, 0, len(ids))
+	for _, id := range ids {
+		points = append(points, pointID(id))
+	}
+	err := q.do(ctx, http.MethodPost, collectionPath(collection)+"/points/delete?wait=true", map[string]interface{}{"points": points}, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ensureCollection creates collection unless it exists.
+func (q *Qdrant) ensureCollection(ctx context.Context, collection string, dim int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.created[collection] {
+		return nil
+	}
+	err := q.do(ctx, http.MethodGet, collectionPath(collection), nil, nil)
+	if isNotFound(err) {
+		err = q.do(ctx, http.MethodPut, collectionPath(collection), map[string]interface{}{
+			"vectors": map[string]interface{}{"size": dim, "distance": "Cosine"},
+		}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	q.created[collection] = true
+	return nil
+}
+
+// qdrantError is a failed Qdrant request.
+type qdrantError struct {
+	status int
+	body   string
+}
+
+func (e *qdrantError) Error() string {
+	return fmt.Sprintf("vectormem: qdrant: %d %s: %s", e.status, http.StatusText(e.status), e.body)
+}
+
+func isNotFound(err error) bool {
+	qe, ok := err.(*qdrantError)
+	return ok && qe.status == http.StatusNotFound
+}
+
+// do sends a request with body encoded as JSON and decodes the response
+// into out, when not nil.
+func (q *Qdrant) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, q.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vectormem: qdrant: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &qdrantError{status: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func collectionPath(collection string) string {
+	return "/collections/" + url.PathEscape(collection)
+}
+
+// pointID maps a document ID to a name-based (version 5 style) UUID.
+func pointID(id string) string {
+	sum := sha1.Sum([]byte(id))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,128 @@
+package vectormem
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Document is a text and its embedding.
+type Document struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Vector   []float32         `json:"-"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// CreatedAt is when the document was stored.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Hit is a document found by a search.
+type Hit struct {
+	Document
+	// Score is the cosine similarity of the document to the query, at most
+	// 1.
+	Score float64 `json:"score"`
+}
+
+// VectorStore keeps documents in named collections and finds those
+// nearest to a vector. Implementations must be safe for concurrent use.
+type VectorStore interface {
+	// Upsert adds docs to collection, replacing documents with the same
+	// IDs.
+	Upsert(ctx context.Context, collection string, docs []Document) error
+	// Search returns the k documents of collection most similar to vector
+	// whose metadata contains every pair of filter, best first.
+	Search(ctx context.Context, collection string, vector []float32, k int, filter map[string]string) ([]Hit, error)
+	// Delete removes the documents with the given IDs. Deleting a missing
+	// document is not an error.
+	Delete(ctx context.Context, collection string, ids []string) error
+}
+
+// MemoryStore is a VectorStore searching exhaustively in memory. It suits
+// tests and collections of up to some ten thousand documents.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]Document
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{collections: make(map[string]map[string]Document)}
+}
+
+// Upsert implements VectorStore.
+func (m *MemoryStore) Upsert(ctx context.Context, collection string, docs []Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.collections[collection]
+	if c == nil {
+		c = make(map[string]Document)
+		m.collections[collection] = c
+	}
+	for _, d := range docs {
+		d.Vector = append([]float32(nil), d.Vector...)
+		c[d.ID] = d
+	}
+	return nil
+}
+
+// Search implements VectorStore.
+func (m *MemoryStore) Search(ctx context.Context, collection string, vector []float32, k int, filter map[string]string) ([]Hit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var hits []Hit
+	for _, d := range m.collections[collection] {
+		if !matches(d.Metadata, filter) {
+			continue
+		}
+		hits = append(hits, Hit{Document: d, Score: cosine(vector, d.Vector)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// Delete implements VectorStore.
+func (m *MemoryStore) Delete(ctx context.Context, collection string, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.collections[collection], id)
+	}
+	return nil
+}
+
+// matches reports whether metadata contains every pair of filter.
+func matches(metadata, filter map[string]string) bool {
+	for k, v := range filter {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,214 @@
+// Package vectormem gives agents a semantic memory: tools to store texts,
+// search them by meaning and forget them, on top of an embedding model
+// and a vector store.
+//
+// The embedding model is any toolsearch.Embedder. Documents are kept in a
+// VectorStore: MemoryStore searches in process, and Qdrant adapts an
+// external Qdrant server. Other stores plug in by implementing the
+// interface.
+package vectormem
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperleex/zenmcp/clock"
+	"github.com/hyperleex/zenmcp/mcp"
+	"github.com/hyperleex/zenmcp/protocol"
+	"github.com/hyperleex/zenmcp/registry"
+	"github.com/hyperleex/zenmcp/runtime"
+	"github.com/hyperleex/zenmcp/toolsearch"
+)
+
+// Names of the tools registered by Install.
+const (
+	EmbedToolName  = "vectormem.embed"
+	StoreToolName  = "vectormem.store"
+	SearchToolName = "vectormem.search"
+	DeleteToolName = "vectormem.delete"
+)
+
+// Config configures the semantic memory tools.
+type Config struct {
+	// Embedder turns texts into vectors. Required.
+	Embedder toolsearch.Embedder
+	// Store keeps the documents. Defaults to a new MemoryStore.
+	Store VectorStore
+	// Collection names the collection a call reads and writes. The
+	// default gives every tenant a collection of its own, shared by its
+	// callers.
+	Collection func(ctx *runtime.Context) string
+	// MaxTextBytes bounds the size of a stored text. Defaults to 16 KiB.
+	MaxTextBytes int
+	// MaxResults caps the number of search results. Defaults to 50.
+	MaxResults int
+}
+
+// Memory serves the semantic memory tools.
+type Memory struct {
+	cfg   Config
+	clock clock.Clock
+}
+
+// Install registers the semantic memory tools on s.
+func Install(s *mcp.Server, cfg Config) (*Memory, error) {
+	if cfg.Embedder == nil {
+		return nil, errors.New("vectormem: an embedder is required")
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.Collection == nil {
+		cfg.Collection = defaultCollection
+	}
+	if cfg.MaxTextBytes <= 0 {
+		cfg.MaxTextBytes = 16 << 10
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = 50
+	}
+	m := &Memory{cfg: cfg, clock: s.Clock()}
+	tags := []string{"memory"}
+	yes := true
+	readOnly := &protocol.ToolAnnotations{ReadOnlyHint: &yes}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        EmbedToolName,
+		Description: "Compute the embedding vectors of texts.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, m.embedTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        StoreToolName,
+		Description: "Remember a text so that it can later be found by searching for its meaning.",
+		Tags:        tags,
+	}, m.storeTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        SearchToolName,
+		Description: "Find the remembered texts closest in meaning to a query, most similar first.",
+		Tags:        tags,
+		Annotations: readOnly,
+	}, m.searchTool); err != nil {
+		return nil, err
+	}
+	if err := mcp.RegisterToolTyped(s, registry.ToolDescriptor{
+		Name:        DeleteToolName,
+		Description: "Forget a remembered text.",
+		Tags:        tags,
+	}, m.deleteTool); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func defaultCollection(ctx *runtime.Context) string {
+	if t := ctx.Tenant(); t != "" {
+		return "memory-" + t
+	}
+	return "memory"
+}
+
+// embed returns the vector of one text.
+func (m *Memory) embed(ctx *runtime.Context, text string) ([]float32, error) {
+	vectors, err := m.cfg.Embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("vectormem: embedding: %w", err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return nil, errors.New("vectormem: embedder returned no vector")
+	}
+	return vectors[0], nil
+}
+
+func jsonResult(v interface{}) (*protocol.ToolCallResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{protocol.TextContent(string(data))}}, nil
+}
+
+type embedArgs struct {
+	Texts []string `json:"texts" description:"Texts to embed." maxItems:"32"`
+}
+
+func (m *Memory) embedTool(ctx *runtime.Context, args embedArgs) (*protocol.ToolCallResult, error) {
+	vectors, err := m.cfg.Embedder.Embed(ctx, args.Texts)
+	if err != nil {
+		return nil, fmt.Errorf("vectormem: embedding: %w", err)
+	}
+	return jsonResult(map[string]interface{}{"vectors": vectors})
+}
+
+type storeArgs struct {
+	Text     string            `json:"text" description:"Text to remember." validate:"nonzero"`
+	ID       string            `json:"id,omitempty" description:"ID of the text; storing under an existing ID replaces that text. Generated when omitted."`
+	Metadata map[string]string `json:"metadata,omitempty" description:"Labels to filter searches by."`
+}
+
+func (m *Memory) storeTool(ctx *runtime.Context, args storeArgs) (*protocol.ToolCallResult, error) {
+	if len(args.Text) > m.cfg.MaxTextBytes {
+		return nil, fmt.Errorf("vectormem: text of %d bytes exceeds the limit of %d", len(args.Text), m.cfg.MaxTextBytes)
+	}
+	vector, err := m.embed(ctx, args.Text)
+	if err != nil {
+		return nil, err
+	}
+	id := args.ID
+	if id == "" {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		id = hex.EncodeToString(b[:])
+	}
+	doc := Document{ID: id, Text: args.Text, Vector: vector, Metadata: args.Metadata, CreatedAt: m.clock.Now()}
+	if err := m.cfg.Store.Upsert(ctx, m.cfg.Collection(ctx), []Document{doc}); err != nil {
+		return nil, err
+	}
+	return jsonResult(map[string]string{"id": id})
+}
+
+type searchArgs struct {
+	Query  string            `json:"query" description:"What to look for, in plain words." validate:"nonzero"`
+	Limit  int               `json:"limit" description:"Maximum number of texts to return." default:"5"`
+	Filter map[string]string `json:"filter,omitempty" description:"Only return texts with all of these metadata labels."`
+}
+
+func (m *Memory) searchTool(ctx *runtime.Context, args searchArgs) (*protocol.ToolCallResult, error) {
+	vector, err := m.embed(ctx, args.Query)
+	if err != nil {
+		return nil, err
+	}
+	limit := args.Limit
+	if limit <= 0 || limit > m.cfg.MaxResults {
+		limit = m.cfg.MaxResults
+	}
+	hits, err := m.cfg.Store.Search(ctx, m.cfg.Collection(ctx), vector, limit, args.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if hits == nil {
+		hits = []Hit{}
+	}
+	return jsonResult(map[string]interface{}{"results": hits})
+}
+
+type deleteArgs struct {
+	ID string `json:"id" description:"ID of the text to forget." validate:"nonzero"`
+}
+
+func (m *Memory) deleteTool(ctx *runtime.Context, args deleteArgs) (*protocol.ToolCallResult, error) {
+	if err := m.cfg.Store.Delete(ctx, m.cfg.Collection(ctx), []string{args.ID}); err != nil {
+		return nil, err
+	}
+	return &protocol.ToolCallResult{Content: []protocol.Content{
+		protocol.TextContent(fmt.Sprintf("Forgot %s.", args.ID)),
+	}}, nil
+}